@@ -16,39 +16,31 @@ const docTemplate = `{
             "email": "support@swagger.io"
         },
         "license": {
-            "name": "Apache 2.0",
-            "url": "http://www.apache.org/licenses/LICENSE-2.0.html"
+            "name": "MIT",
+            "url": "https://opensource.org/licenses/MIT"
         },
         "version": "{{.Version}}"
     },
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/clustermachines": {
+        "/auth/service-accounts": {
             "get": {
-                "description": "Get a list of all cluster machines in Omni",
+                "description": "Get a list of all service accounts",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "clustermachines"
-                ],
-                "summary": "List all cluster machines",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Filter by cluster ID",
-                        "name": "cluster",
-                        "in": "query"
-                    }
+                    "auth"
                 ],
+                "summary": "List service accounts",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/handlers.ClusterMachineResponse"
+                                "$ref": "#/definitions/handlers.ServiceAccountResponse"
                             }
                         }
                     },
@@ -62,36 +54,45 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/clustermachines/{id}": {
-            "get": {
-                "description": "Get detailed information about a specific cluster machine",
+            },
+            "post": {
+                "description": "Create a new service account",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "clustermachines"
+                    "auth"
                 ],
-                "summary": "Get a single cluster machine",
+                "summary": "Create a service account",
                 "parameters": [
+                    {
+                        "description": "Service account creation request",
+                        "name": "account",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateServiceAccountRequest"
+                        }
+                    },
                     {
                         "type": "string",
-                        "description": "Cluster Machine ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ClusterMachineResponse"
+                            "$ref": "#/definitions/handlers.ServiceAccountResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -111,20 +112,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/clustermachines/{id}/config": {
+        "/auth/service-accounts/{id}": {
             "get": {
-                "description": "Get machine configuration for a specific cluster machine",
+                "description": "Get details of a specific service account",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "clustermachines"
+                    "auth"
                 ],
-                "summary": "Get cluster machine config",
+                "summary": "Get a service account",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Cluster Machine ID",
+                        "description": "Service account ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -134,7 +135,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ClusterMachineConfigResponse"
+                            "$ref": "#/definitions/handlers.ServiceAccountResponse"
                         }
                     },
                     "404": {
@@ -156,32 +157,33 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/clustermachines/{id}/config-status": {
-            "get": {
-                "description": "Get configuration status information for a specific cluster machine",
+            },
+            "delete": {
+                "description": "Delete a service account",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "clustermachines"
+                    "auth"
                 ],
-                "summary": "Get cluster machine config status",
+                "summary": "Delete a service account",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Cluster Machine ID",
+                        "description": "Service account ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ClusterMachineConfigStatusResponse"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "404": {
@@ -205,34 +207,67 @@ const docTemplate = `{
                 }
             }
         },
-        "/clustermachines/{id}/status": {
-            "get": {
-                "description": "Get detailed status information about a specific cluster machine",
+        "/bulk": {
+            "post": {
+                "description": "Run a list of operations, or one operation against every target matching a label selector, with bounded concurrency.\nActions: reboot machines, patch-labels of machines, delete-config-patch, delete-machine-set, delete-machine-class and delete-cluster.\nIn continue mode every item runs; in fail_fast mode items that did not start yet are skipped after the first failure.\nThe items run in the background; the operation's result holds the status of every item and the operation fails if any item failed.\nEach item is checked against the route groups and admission policies of its equivalent single request, e.g. POST /machines/{id}/actions/reboot; items denied by a policy fail.\nWith dryRun the items are validated synchronously and the report is returned instead.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "clustermachines"
+                    "bulk"
                 ],
-                "summary": "Get cluster machine status",
+                "summary": "Run many operations",
                 "parameters": [
+                    {
+                        "description": "Operations to run",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BulkRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate every item without applying it",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
-                        "description": "Cluster Machine ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Returned instead when dryRun is set",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ClusterMachineStatusResponse"
+                            "$ref": "#/definitions/handlers.BulkDryRunResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "202": {
+                        "description": "Location header points to the operation running the items",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "An action belongs to a disabled route group",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -252,34 +287,84 @@ const docTemplate = `{
                 }
             }
         },
-        "/clustermachines/{id}/talos-version": {
+        "/campaigns": {
             "get": {
-                "description": "Get Talos OS version and schematic information for a specific cluster machine",
+                "description": "List fleet upgrade campaigns, newest first",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "clustermachines"
+                    "campaigns"
                 ],
-                "summary": "Get cluster machine Talos version",
+                "summary": "List upgrade campaigns",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CampaignListResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Upgrade the clusters matching a label selector to a target Talos and/or Kubernetes version in waves: the canary cluster alone, then each share of the fleet in waves (10% by default), then the rest.\nEvery cluster follows its upgrade plan one step at a time and must be healthy (ClusterStatus ready, control plane conditions and Kubernetes nodes ready) before each step and after the last one.\nA failed step or health gate pauses the campaign. With dryRun=true the waves are returned without starting the campaign.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "campaigns"
+                ],
+                "summary": "Start an upgrade campaign",
                 "parameters": [
+                    {
+                        "description": "Target versions, cluster selector, canary and waves",
+                        "name": "campaign",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/campaign.Spec"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return the planned waves without starting the campaign",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
-                        "description": "Cluster Machine ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ClusterMachineTalosVersionResponse"
+                            "$ref": "#/definitions/handlers.CampaignResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CampaignResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid spec, or a selected cluster has no upgrade path to the target versions",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Campaigns are not enabled, OMNI_API_CAMPAIGN_FILE is not set",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -299,28 +384,34 @@ const docTemplate = `{
                 }
             }
         },
-        "/clusters": {
+        "/campaigns/{id}": {
             "get": {
-                "description": "Get a list of all clusters in Omni",
+                "description": "Get the status of an upgrade campaign with the wave, step and health gate progress of each cluster",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "clusters"
+                    "campaigns"
+                ],
+                "summary": "Get an upgrade campaign",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Campaign ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "List all clusters",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/handlers.ClusterResponse"
-                            }
+                            "$ref": "#/definitions/handlers.CampaignResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -331,30 +422,36 @@ const docTemplate = `{
                 }
             }
         },
-        "/clusters/{id}": {
-            "get": {
-                "description": "Get detailed information about a specific cluster",
+        "/campaigns/{id}/actions/abort": {
+            "post": {
+                "description": "Stop a running or paused campaign for good. Upgraded clusters are not rolled back.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "clusters"
+                    "campaigns"
                 ],
-                "summary": "Get a single cluster",
+                "summary": "Abort an upgrade campaign",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Cluster ID",
+                        "description": "Campaign ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ClusterResponse"
+                            "$ref": "#/definitions/handlers.CampaignResponse"
                         }
                     },
                     "404": {
@@ -366,8 +463,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -378,30 +475,36 @@ const docTemplate = `{
                 }
             }
         },
-        "/clusters/{id}/bootstrap": {
-            "get": {
-                "description": "Get the bootstrap status for a specific cluster",
+        "/campaigns/{id}/actions/pause": {
+            "post": {
+                "description": "Stop starting further upgrade steps. Steps already sent to Omni complete; the interrupted clusters continue when the campaign is resumed.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "clusters"
+                    "campaigns"
                 ],
-                "summary": "Get cluster bootstrap status",
+                "summary": "Pause an upgrade campaign",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Cluster ID",
+                        "description": "Campaign ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ClusterBootstrapResponse"
+                            "$ref": "#/definitions/handlers.CampaignResponse"
                         }
                     },
                     "404": {
@@ -413,8 +516,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -425,30 +528,36 @@ const docTemplate = `{
                 }
             }
         },
-        "/clusters/{id}/controlplane-status": {
-            "get": {
-                "description": "Get control plane health status for a cluster",
+        "/campaigns/{id}/actions/resume": {
+            "post": {
+                "description": "Continue a paused campaign; failed clusters retry their failed step",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "clusters"
+                    "campaigns"
                 ],
-                "summary": "Get control plane status",
+                "summary": "Resume an upgrade campaign",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Cluster ID",
+                        "description": "Campaign ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ControlPlaneStatusResponse"
+                            "$ref": "#/definitions/handlers.CampaignResponse"
                         }
                     },
                     "404": {
@@ -460,8 +569,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -472,30 +581,68 @@ const docTemplate = `{
                 }
             }
         },
-        "/clusters/{id}/destroy-status": {
-            "get": {
-                "description": "Get the status of cluster destruction operation",
+        "/cluster-templates:apply": {
+            "post": {
+                "description": "Reconcile the cluster, machine sets, machine set nodes, config patches and labels in Omni with a cluster template, like omnictl cluster template sync.\nResources are created and updated right away. Resources of the cluster missing from the template are then torn down in the background (unless prune=false) and the response is 202 with a Location header pointing at the operation.",
+                "consumes": [
+                    "application/yaml"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "clusters"
+                    "cluster-templates"
                 ],
-                "summary": "Get cluster destroy status",
+                "summary": "Apply a cluster template",
                 "parameters": [
+                    {
+                        "description": "Cluster template YAML",
+                        "name": "template",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Destroy resources of the cluster that are missing from the template (default true)",
+                        "name": "prune",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request and return the changes without applying them",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
-                        "description": "Cluster ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Applied, or the planned changes when dryRun is set",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ClusterDestroyStatusResponse"
+                            "$ref": "#/definitions/handlers.TemplatePlanResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Applied; Location header points to the operation pruning removed resources",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TemplatePlanResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "404": {
@@ -507,6 +654,24 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -519,34 +684,45 @@ const docTemplate = `{
                 }
             }
         },
-        "/clusters/{id}/diagnostics": {
-            "get": {
-                "description": "Get diagnostic information for a cluster",
+        "/cluster-templates:diff": {
+            "post": {
+                "description": "Compare a cluster template with the resources in Omni and return the changes applying it would make, including the resources it would prune.",
+                "consumes": [
+                    "application/yaml"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "clusters"
+                    "cluster-templates"
                 ],
-                "summary": "Get cluster diagnostics",
+                "summary": "Diff a cluster template",
                 "parameters": [
+                    {
+                        "description": "Cluster template YAML",
+                        "name": "template",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     {
                         "type": "string",
-                        "description": "Cluster ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ClusterDiagnosticsResponse"
+                            "$ref": "#/definitions/handlers.TemplatePlanResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -554,46 +730,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    }
-                }
-            }
-        },
-        "/clusters/{id}/endpoints": {
-            "get": {
-                "description": "Get management endpoints for a specific cluster",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "clusters"
-                ],
-                "summary": "Get cluster endpoints",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Cluster ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.ClusterEndpointResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
+                    "412": {
+                        "description": "Precondition Failed",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -613,43 +751,45 @@ const docTemplate = `{
                 }
             }
         },
-        "/clusters/{id}/kubeconfig": {
-            "get": {
-                "description": "Get the Kubernetes kubeconfig for a cluster. WARNING: Contains sensitive credentials.",
+        "/cluster-templates:validate": {
+            "post": {
+                "description": "Validate an omnictl cluster template (Cluster, ControlPlane, Workers and Machine documents) without comparing it to Omni.\nPatches must be inline; patch files are not supported.",
+                "consumes": [
+                    "application/yaml"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "kubeconfigs"
+                    "cluster-templates"
                 ],
-                "summary": "Get cluster kubeconfig",
+                "summary": "Validate a cluster template",
                 "parameters": [
+                    {
+                        "description": "Cluster template YAML",
+                        "name": "template",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     {
                         "type": "string",
-                        "description": "Cluster ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.KubeconfigResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/handlers.TemplateValidationResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -660,23 +800,22 @@ const docTemplate = `{
                 }
             }
         },
-        "/clusters/{id}/kubernetes-nodes": {
+        "/clustermachines": {
             "get": {
-                "description": "Get a list of all Kubernetes nodes in a cluster",
+                "description": "Get a list of all cluster machines in Omni",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "clusters"
+                    "clustermachines"
                 ],
-                "summary": "List cluster Kubernetes nodes",
+                "summary": "List all cluster machines",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Cluster ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Filter by cluster ID",
+                        "name": "cluster",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -685,7 +824,7 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/handlers.ClusterKubernetesNodeResponse"
+                                "$ref": "#/definitions/handlers.ClusterMachineResponse"
                             }
                         }
                     },
@@ -701,37 +840,30 @@ const docTemplate = `{
                 }
             }
         },
-        "/clusters/{id}/kubernetes-nodes/{node}": {
+        "/clustermachines/{id}": {
             "get": {
-                "description": "Get detailed information about a specific Kubernetes node in a cluster",
+                "description": "Get detailed information about a specific cluster machine",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "clusters"
+                    "clustermachines"
                 ],
-                "summary": "Get a cluster Kubernetes node",
+                "summary": "Get a single cluster machine",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Cluster ID",
+                        "description": "Cluster Machine ID",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Node name",
-                        "name": "node",
-                        "in": "path",
-                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ClusterKubernetesNodeResponse"
+                            "$ref": "#/definitions/handlers.ClusterMachineResponse"
                         }
                     },
                     "404": {
@@ -755,20 +887,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/clusters/{id}/kubernetes-status": {
+        "/clustermachines/{id}/config": {
             "get": {
-                "description": "Get Kubernetes cluster status including nodes and static pods",
+                "description": "Get machine configuration for a specific cluster machine",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "clusters"
+                    "clustermachines"
                 ],
-                "summary": "Get Kubernetes status",
+                "summary": "Get cluster machine config",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Cluster ID",
+                        "description": "Cluster Machine ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -778,7 +910,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.KubernetesStatusResponse"
+                            "$ref": "#/definitions/handlers.ClusterMachineConfigResponse"
                         }
                     },
                     "404": {
@@ -802,20 +934,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/clusters/{id}/kubernetes-upgrade": {
+        "/clustermachines/{id}/config-status": {
             "get": {
-                "description": "Get the status of Kubernetes version upgrades for a cluster",
+                "description": "Get configuration status information for a specific cluster machine",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "clusters"
+                    "clustermachines"
                 ],
-                "summary": "Get Kubernetes upgrade status",
+                "summary": "Get cluster machine config status",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Cluster ID",
+                        "description": "Cluster Machine ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -825,7 +957,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.KubernetesUpgradeStatusResponse"
+                            "$ref": "#/definitions/handlers.ClusterMachineConfigStatusResponse"
                         }
                     },
                     "404": {
@@ -849,20 +981,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/clusters/{id}/metrics": {
+        "/clustermachines/{id}/status": {
             "get": {
-                "description": "Get real-time metrics for a specific cluster",
+                "description": "Get detailed status information about a specific cluster machine",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "clusters"
+                    "clustermachines"
                 ],
-                "summary": "Get cluster metrics",
+                "summary": "Get cluster machine status",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Cluster ID",
+                        "description": "Cluster Machine ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -872,7 +1004,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ClusterMetricsResponse"
+                            "$ref": "#/definitions/handlers.ClusterMachineStatusResponse"
                         }
                     },
                     "404": {
@@ -896,20 +1028,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/clusters/{id}/status": {
+        "/clustermachines/{id}/talos-version": {
             "get": {
-                "description": "Get health and phase information for a specific cluster",
+                "description": "Get Talos OS version and schematic information for a specific cluster machine",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "clusters"
+                    "clustermachines"
                 ],
-                "summary": "Get cluster status",
+                "summary": "Get cluster machine Talos version",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Cluster ID",
+                        "description": "Cluster Machine ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -919,7 +1051,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ClusterStatusResponse"
+                            "$ref": "#/definitions/handlers.ClusterMachineTalosVersionResponse"
                         }
                     },
                     "404": {
@@ -943,38 +1075,23 @@ const docTemplate = `{
                 }
             }
         },
-        "/clusters/{id}/talos-upgrade": {
+        "/clusters": {
             "get": {
-                "description": "Get the status of Talos OS upgrades for a cluster",
+                "description": "Get a list of all clusters in Omni",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "clusters"
                 ],
-                "summary": "Get Talos upgrade status",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Cluster ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
+                "summary": "List all clusters",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.TalosUpgradeStatusResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.ClusterResponse"
                             }
                         }
                     },
@@ -988,36 +1105,57 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/clusters/{id}/workload-proxy-status": {
-            "get": {
-                "description": "Get the status of workload proxy for a cluster",
+            },
+            "post": {
+                "description": "Create a new cluster in Omni",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "clusters"
                 ],
-                "summary": "Get cluster workload proxy status",
+                "summary": "Create a new cluster",
                 "parameters": [
+                    {
+                        "description": "Cluster creation request",
+                        "name": "cluster",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ClusterCreateRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request and return the change without applying it",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
-                        "description": "Cluster ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Returned instead when dryRun is set",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ClusterWorkloadProxyStatusResponse"
+                            "$ref": "#/definitions/handlers.DryRunResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ClusterResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1025,37 +1163,14 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "412": {
+                        "description": "Backups are enabled but Omni has no usable etcd backup store",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
                         }
-                    }
-                }
-            }
-        },
-        "/configpatches": {
-            "get": {
-                "description": "Get a list of all config patches in Omni",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "configpatches"
-                ],
-                "summary": "List all config patches",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/handlers.ConfigPatchResponse"
-                            }
-                        }
                     },
                     "500": {
                         "description": "Internal Server Error",
@@ -1069,20 +1184,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/configpatches/{id}": {
+        "/clusters/{id}": {
             "get": {
-                "description": "Get detailed information about a specific config patch",
+                "description": "Get detailed information about a specific cluster",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "configpatches"
+                    "clusters"
                 ],
-                "summary": "Get a single config patch",
+                "summary": "Get a single cluster",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Config Patch ID",
+                        "description": "Cluster ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1092,7 +1207,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ConfigPatchResponse"
+                            "$ref": "#/definitions/handlers.ClusterResponse"
                         }
                     },
                     "404": {
@@ -1114,33 +1229,65 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/etcd-manual-backups": {
-            "get": {
-                "description": "Get a list of all etcd manual backup requests",
+            },
+            "put": {
+                "description": "Update an existing cluster in Omni",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "etcdbackups"
+                    "clusters"
                 ],
-                "summary": "List etcd manual backups",
+                "summary": "Update a cluster",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Filter by cluster ID",
+                        "description": "Cluster ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Cluster update request",
                         "name": "cluster",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ClusterUpdateRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request and return the change without applying it",
+                        "name": "dryRun",
                         "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "A DryRunResponse when dryRun is set",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/handlers.EtcdManualBackupResponse"
+                            "$ref": "#/definitions/handlers.ClusterResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
@@ -1154,32 +1301,45 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/etcd-manual-backups/{id}": {
-            "get": {
-                "description": "Get detailed information about a specific etcd manual backup request",
+            },
+            "delete": {
+                "description": "Delete a cluster from Omni",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "etcdbackups"
+                    "clusters"
                 ],
-                "summary": "Get an etcd manual backup",
+                "summary": "Delete a cluster",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Etcd Manual Backup ID",
+                        "description": "Cluster ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request and return the change without applying it",
+                        "name": "dryRun",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Returned instead when dryRun is set",
                         "schema": {
-                            "$ref": "#/definitions/handlers.EtcdManualBackupResponse"
+                            "$ref": "#/definitions/handlers.DryRunResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Location header points to the operation tracking the deletion",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "404": {
@@ -1203,31 +1363,59 @@ const docTemplate = `{
                 }
             }
         },
-        "/etcdbackups": {
-            "get": {
-                "description": "Get a list of all etcd backups in Omni",
+        "/clusters/{id}/actions/bootstrap": {
+            "post": {
+                "description": "Trigger bootstrap for a cluster",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "etcdbackups"
+                    "clusters"
                 ],
-                "summary": "List all etcd backups",
+                "summary": "Trigger cluster bootstrap",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Filter by cluster ID",
-                        "name": "cluster",
+                        "description": "Cluster ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request and return the change without applying it",
+                        "name": "dryRun",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Returned instead when dryRun is set",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/handlers.EtcdBackupResponse"
+                            "$ref": "#/definitions/handlers.DryRunResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Location header points to the operation tracking the bootstrap",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
@@ -1243,30 +1431,51 @@ const docTemplate = `{
                 }
             }
         },
-        "/etcdbackups/{id}": {
-            "get": {
-                "description": "Get detailed information about a specific etcd backup",
+        "/clusters/{id}/actions/destroy": {
+            "post": {
+                "description": "Trigger destruction/teardown of a cluster",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "etcdbackups"
+                    "clusters"
                 ],
-                "summary": "Get a single etcd backup",
+                "summary": "Trigger cluster destruction",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Etcd Backup ID",
+                        "description": "Cluster ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request and return the change without applying it",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Returned instead when dryRun is set",
                         "schema": {
-                            "$ref": "#/definitions/handlers.EtcdBackupResponse"
+                            "$ref": "#/definitions/handlers.DryRunResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Location header points to the operation tracking the destruction",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "404": {
@@ -1290,34 +1499,58 @@ const docTemplate = `{
                 }
             }
         },
-        "/etcdbackups/{id}/status": {
-            "get": {
-                "description": "Get status of an etcd backup operation",
+        "/clusters/{id}/actions/kubernetes-upgrade": {
+            "post": {
+                "description": "Trigger a Kubernetes version upgrade for a cluster",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "etcdbackups"
+                    "clusters"
                 ],
-                "summary": "Get etcd backup status",
+                "summary": "Trigger Kubernetes upgrade",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Etcd Backup ID",
+                        "description": "Cluster ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Upgrade request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ClusterActionRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request and return the change without applying it",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Returned instead when dryRun is set",
                         "schema": {
-                            "$ref": "#/definitions/handlers.EtcdBackupStatusResponse"
+                            "$ref": "#/definitions/handlers.DryRunResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "202": {
+                        "description": "Location header points to the operation tracking the upgrade",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1325,35 +1558,21 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
                         }
-                    }
-                }
-            }
-        },
-        "/exposed-services": {
-            "get": {
-                "description": "Get a list of all exposed services",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "infrastructure"
-                ],
-                "summary": "List exposed services",
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/handlers.ExposedServiceResponse"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
@@ -1369,30 +1588,72 @@ const docTemplate = `{
                 }
             }
         },
-        "/exposed-services/{id}": {
-            "get": {
-                "description": "Get detailed information about a specific exposed service",
+        "/clusters/{id}/actions/talos-upgrade": {
+            "post": {
+                "description": "Trigger a Talos OS version upgrade for a cluster",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "infrastructure"
+                    "clusters"
                 ],
-                "summary": "Get an exposed service",
+                "summary": "Trigger Talos upgrade",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Exposed Service ID",
+                        "description": "Cluster ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Upgrade request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ClusterActionRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request and return the change without applying it",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Returned instead when dryRun is set",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ExposedServiceResponse"
+                            "$ref": "#/definitions/handlers.DryRunResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Location header points to the operation tracking the upgrade",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "404": {
@@ -1416,23 +1677,38 @@ const docTemplate = `{
                 }
             }
         },
-        "/extensions-configurations": {
+        "/clusters/{id}/backup": {
             "get": {
-                "description": "Get a list of all extensions configurations",
+                "description": "Get whether Omni takes etcd backups of a cluster, how often, and the retention policy this API evaluates them against",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "machines"
+                    "clusters"
+                ],
+                "summary": "Get the etcd backup configuration of a cluster",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cluster ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "List extensions configurations",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/handlers.ExtensionsConfigurationResponse"
+                            "$ref": "#/definitions/handlers.ClusterBackupResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
@@ -1446,32 +1722,57 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/extensions-configurations/{id}": {
-            "get": {
-                "description": "Get detailed information about a specific extensions configuration",
+            },
+            "put": {
+                "description": "Enable or disable scheduled etcd backups of a cluster and set their interval, written to the cluster spec.\nretention is kept in the omni-api/backup-retention annotation of the cluster; Omni itself keeps every backup.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "machines"
+                    "clusters"
                 ],
-                "summary": "Get an extensions configuration",
+                "summary": "Set the etcd backup configuration of a cluster",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Extensions Configuration ID",
+                        "description": "Cluster ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Backup configuration",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ClusterBackupRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request and return the change without applying it",
+                        "name": "dryRun",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "A DryRunResponse when dryRun is set",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ExtensionsConfigurationResponse"
+                            "$ref": "#/definitions/handlers.ClusterBackupResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "404": {
@@ -1483,6 +1784,15 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "412": {
+                        "description": "Omni has no usable etcd backup store",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1495,43 +1805,65 @@ const docTemplate = `{
                 }
             }
         },
-        "/health": {
+        "/clusters/{id}/backup/retention": {
             "get": {
-                "description": "Get the health status of the API server and Omni connection",
+                "description": "Report for every etcd backup of a cluster whether the retention policy keeps it, and by which rules.\nBackups no rule keeps violate the policy and can be pruned; gaps are days and weeks the policy covers without a backup.\nThe policy is the one configured on the cluster; keep_last, keep_daily and keep_weekly override it.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "health"
+                    "clusters"
                 ],
-                "summary": "Get API health status",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.HealthResponse"
-                        }
+                "summary": "Evaluate the etcd backups of a cluster against a retention policy",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cluster ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Keep the newest N backups",
+                        "name": "keep_last",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Keep the newest backup of the last N days with backups",
+                        "name": "keep_daily",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Keep the newest backup of the last N weeks with backups",
+                        "name": "keep_weekly",
+                        "in": "query"
                     }
-                }
-            }
-        },
-        "/image-pull-requests": {
-            "get": {
-                "description": "Get a list of all image pull requests in Omni",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "imagepullrequests"
                 ],
-                "summary": "List all image pull requests",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/handlers.ImagePullRequestResponse"
+                            "$ref": "#/definitions/handlers.ClusterBackupRetentionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
@@ -1547,20 +1879,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/image-pull-requests/{id}": {
+        "/clusters/{id}/bootstrap": {
             "get": {
-                "description": "Get detailed information about a specific image pull request",
+                "description": "Get the bootstrap status for a specific cluster",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "imagepullrequests"
+                    "clusters"
                 ],
-                "summary": "Get a single image pull request",
+                "summary": "Get cluster bootstrap status",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Image Pull Request ID",
+                        "description": "Cluster ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1570,7 +1902,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ImagePullRequestResponse"
+                            "$ref": "#/definitions/handlers.ClusterBootstrapResponse"
                         }
                     },
                     "404": {
@@ -1594,20 +1926,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/image-pull-requests/{id}/status": {
+        "/clusters/{id}/controlplane-status": {
             "get": {
-                "description": "Get the status of an image pull operation",
+                "description": "Get control plane health status for a cluster",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "imagepullrequests"
+                    "clusters"
                 ],
-                "summary": "Get image pull status",
+                "summary": "Get control plane status",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Image Pull Request ID",
+                        "description": "Cluster ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1617,7 +1949,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ImagePullStatusResponse"
+                            "$ref": "#/definitions/handlers.ControlPlaneStatusResponse"
                         }
                     },
                     "404": {
@@ -1641,31 +1973,38 @@ const docTemplate = `{
                 }
             }
         },
-        "/infra-machine-configs": {
+        "/clusters/{id}/destroy-status": {
             "get": {
-                "description": "Get a list of all infrastructure machine configs in Omni",
+                "description": "Get the status of cluster destruction operation",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "inframachineconfigs"
+                    "clusters"
                 ],
-                "summary": "List all infrastructure machine configs",
+                "summary": "Get cluster destroy status",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Filter by machine ID",
-                        "name": "machine",
-                        "in": "query"
+                        "description": "Cluster ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/handlers.InfraMachineConfigResponse"
+                            "$ref": "#/definitions/handlers.ClusterDestroyStatusResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
@@ -1681,20 +2020,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/infra-machine-configs/{id}": {
+        "/clusters/{id}/diagnostics": {
             "get": {
-                "description": "Get detailed information about a specific infrastructure machine config",
+                "description": "Get diagnostic information for a cluster",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "inframachineconfigs"
+                    "clusters"
                 ],
-                "summary": "Get a single infrastructure machine config",
+                "summary": "Get cluster diagnostics",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Infrastructure Machine Config ID",
+                        "description": "Cluster ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1704,7 +2043,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.InfraMachineConfigResponse"
+                            "$ref": "#/definitions/handlers.ClusterDiagnosticsResponse"
                         }
                     },
                     "404": {
@@ -1728,23 +2067,38 @@ const docTemplate = `{
                 }
             }
         },
-        "/installation-medias": {
+        "/clusters/{id}/endpoints": {
             "get": {
-                "description": "Get a list of all installation medias in Omni",
+                "description": "Get management endpoints for a specific cluster",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "installationmedias"
+                    "clusters"
+                ],
+                "summary": "Get cluster endpoints",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cluster ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "List all installation medias",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/handlers.InstallationMediaResponse"
+                            "$ref": "#/definitions/handlers.ClusterEndpointResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
@@ -1760,30 +2114,90 @@ const docTemplate = `{
                 }
             }
         },
-        "/installation-medias/{id}": {
+        "/clusters/{id}/kubeconfig": {
             "get": {
-                "description": "Get detailed information about a specific installation media",
+                "description": "Generate a Kubernetes kubeconfig for a cluster through the Omni Management API.\nBy default the kubeconfig makes each user sign in to Omni (OIDC); mode=service-account embeds a token for the given user and groups that expires after ttl.\nService-account kubeconfigs are only available to users listed in OMNI_API_SERVICE_ACCOUNT_KUBECONFIG_USERS, authenticated by a proxy in OMNI_API_TRUSTED_PROXIES, and only for the groups listed in OMNI_API_SERVICE_ACCOUNT_GROUPS.\nEvery generated kubeconfig is recorded in the audit log.",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/yaml"
                 ],
                 "tags": [
-                    "installationmedias"
+                    "kubeconfigs"
                 ],
-                "summary": "Get a single installation media",
+                "summary": "Generate cluster kubeconfig",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Installation Media ID",
+                        "description": "Cluster ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "enum": [
+                            "oidc",
+                            "service-account"
+                        ],
+                        "type": "string",
+                        "default": "oidc",
+                        "description": "Kubeconfig mode",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User the service account authenticates as (service-account mode)",
+                        "name": "user",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated Kubernetes groups of the service account (service-account mode)",
+                        "name": "groups",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "How long a service-account kubeconfig is valid, e.g. 8h (default 24h)",
+                        "name": "ttl",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "OIDC grant type, e.g. authcode-keyboard (oidc mode)",
+                        "name": "grant_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return the raw kubeconfig file instead of JSON; also selected by Accept: application/yaml",
+                        "name": "download",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.InstallationMediaResponse"
+                            "$ref": "#/definitions/handlers.KubeconfigResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "404": {
@@ -1807,23 +2221,32 @@ const docTemplate = `{
                 }
             }
         },
-        "/kernel-args": {
+        "/clusters/{id}/kubernetes-nodes": {
             "get": {
-                "description": "Get a list of all kernel args configurations",
+                "description": "Get a list of all Kubernetes nodes in a cluster",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "machines"
+                    "clusters"
+                ],
+                "summary": "List cluster Kubernetes nodes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cluster ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "List kernel args",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/handlers.KernelArgsResponse"
+                                "$ref": "#/definitions/handlers.ClusterKubernetesNodeResponse"
                             }
                         }
                     },
@@ -1839,30 +2262,37 @@ const docTemplate = `{
                 }
             }
         },
-        "/kernel-args/{id}": {
+        "/clusters/{id}/kubernetes-nodes/{node}": {
             "get": {
-                "description": "Get detailed information about a specific kernel args configuration",
+                "description": "Get detailed information about a specific Kubernetes node in a cluster",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "machines"
+                    "clusters"
                 ],
-                "summary": "Get kernel args",
+                "summary": "Get a cluster Kubernetes node",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Kernel Args ID",
+                        "description": "Cluster ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Node name",
+                        "name": "node",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.KernelArgsResponse"
+                            "$ref": "#/definitions/handlers.ClusterKubernetesNodeResponse"
                         }
                     },
                     "404": {
@@ -1886,28 +2316,43 @@ const docTemplate = `{
                 }
             }
         },
-        "/kubernetes-versions": {
+        "/clusters/{id}/kubernetes-status": {
             "get": {
-                "description": "Get a list of all available Kubernetes versions",
+                "description": "Get Kubernetes cluster status including nodes and static pods",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "kubernetes"
+                    "clusters"
+                ],
+                "summary": "Get Kubernetes status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cluster ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "List all Kubernetes versions",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/handlers.KubernetesVersionResponse"
-                            }
+                            "$ref": "#/definitions/handlers.KubernetesStatusResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1918,20 +2363,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/kubernetes-versions/{id}": {
+        "/clusters/{id}/kubernetes-upgrade": {
             "get": {
-                "description": "Get detailed information about a specific Kubernetes version",
+                "description": "Get the status of Kubernetes version upgrades for a cluster",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "kubernetes"
+                    "clusters"
                 ],
-                "summary": "Get a Kubernetes version",
+                "summary": "Get Kubernetes upgrade status",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Kubernetes Version ID",
+                        "description": "Cluster ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1941,7 +2386,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.KubernetesVersionResponse"
+                            "$ref": "#/definitions/handlers.KubernetesUpgradeStatusResponse"
                         }
                     },
                     "404": {
@@ -1965,23 +2410,38 @@ const docTemplate = `{
                 }
             }
         },
-        "/loadbalancer-configs": {
+        "/clusters/{id}/metrics": {
             "get": {
-                "description": "Get a list of all load balancer configurations",
+                "description": "Get real-time metrics for a specific cluster",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "infrastructure"
+                    "clusters"
+                ],
+                "summary": "Get cluster metrics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cluster ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "List load balancer configs",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/handlers.LoadBalancerConfigResponse"
+                            "$ref": "#/definitions/handlers.ClusterMetricsResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
@@ -1997,20 +2457,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/loadbalancer-configs/{id}": {
+        "/clusters/{id}/status": {
             "get": {
-                "description": "Get detailed information about a specific load balancer configuration",
+                "description": "Get health and phase information for a specific cluster",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "infrastructure"
+                    "clusters"
                 ],
-                "summary": "Get a load balancer config",
+                "summary": "Get cluster status",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Load Balancer Config ID",
+                        "description": "Cluster ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -2020,7 +2480,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.LoadBalancerConfigResponse"
+                            "$ref": "#/definitions/handlers.ClusterStatusResponse"
                         }
                     },
                     "404": {
@@ -2044,30 +2504,36 @@ const docTemplate = `{
                 }
             }
         },
-        "/loadbalancers/{id}/status": {
-            "get": {
-                "description": "Get status of a load balancer",
+        "/clusters/{id}/support-bundle": {
+            "post": {
+                "description": "Start collecting Talos and Kubernetes diagnostics from every machine of a cluster through the Omni Management API, like omnictl support.\nFollow per-node progress with the events link (Server-Sent Events) and download the zip once the bundle is ready; it is deleted after OMNI_API_SUPPORT_BUNDLE_TTL.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "infrastructure"
+                    "clusters"
                 ],
-                "summary": "Get load balancer status",
+                "summary": "Generate a cluster support bundle",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Load Balancer ID",
+                        "description": "Cluster ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "$ref": "#/definitions/handlers.LoadBalancerStatusResponse"
+                            "$ref": "#/definitions/handlers.SupportBundleResponse"
                         }
                     },
                     "404": {
@@ -2091,23 +2557,38 @@ const docTemplate = `{
                 }
             }
         },
-        "/machine-request-sets": {
+        "/clusters/{id}/talos-upgrade": {
             "get": {
-                "description": "Get a list of all machine request sets",
+                "description": "Get the status of Talos OS upgrades for a cluster",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "machines"
+                    "clusters"
+                ],
+                "summary": "Get Talos upgrade status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cluster ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "List machine request sets",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/handlers.MachineRequestSetResponse"
+                            "$ref": "#/definitions/handlers.TalosUpgradeStatusResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
@@ -2123,34 +2604,47 @@ const docTemplate = `{
                 }
             }
         },
-        "/machine-request-sets/{id}": {
+        "/clusters/{id}/talosconfig": {
             "get": {
-                "description": "Get detailed information about a specific machine request set",
+                "description": "Generate a talosconfig for a cluster through the Omni Management API. The talosconfig reaches the machines through Omni and authenticates each user against Omni.\nbreak_glass=true returns an operator talosconfig that talks to the machines directly; it is only available to users listed in OMNI_API_BREAK_GLASS_USERS, authenticated by a proxy in OMNI_API_TRUSTED_PROXIES.\nEvery generated talosconfig is recorded in the audit log.",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/yaml"
                 ],
                 "tags": [
-                    "machines"
+                    "talosconfigs"
                 ],
-                "summary": "Get a machine request set",
+                "summary": "Generate cluster talosconfig",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Machine Request Set ID",
+                        "description": "Cluster ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Generate an operator talosconfig that bypasses Omni",
+                        "name": "break_glass",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return the raw talosconfig file instead of JSON; also selected by Accept: application/yaml",
+                        "name": "download",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.MachineRequestSetResponse"
+                            "$ref": "#/definitions/handlers.TalosconfigResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2158,35 +2652,21 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
                         }
-                    }
-                }
-            }
-        },
-        "/machineclasses": {
-            "get": {
-                "description": "Get a list of all machine classes in Omni",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "machineclasses"
-                ],
-                "summary": "List all machine classes",
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/handlers.MachineClassResponse"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
@@ -2202,30 +2682,36 @@ const docTemplate = `{
                 }
             }
         },
-        "/machineclasses/{id}": {
+        "/clusters/{id}/template": {
             "get": {
-                "description": "Get detailed information about a specific machine class",
+                "description": "Render an existing cluster, its machine sets, machines, config patches and extensions as an omnictl cluster template",
                 "produces": [
-                    "application/json"
+                    "application/yaml"
                 ],
                 "tags": [
-                    "machineclasses"
+                    "cluster-templates"
                 ],
-                "summary": "Get a single machine class",
+                "summary": "Export a cluster as a template",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Machine Class ID",
+                        "description": "Cluster ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Send the template as a file attachment",
+                        "name": "download",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.MachineClassResponse"
+                            "type": "string"
                         }
                     },
                     "404": {
@@ -2249,62 +2735,51 @@ const docTemplate = `{
                 }
             }
         },
-        "/machines": {
-            "get": {
-                "description": "Get a list of all machines in Omni",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "machines"
-                ],
-                "summary": "List all machines",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/handlers.MachineResponse"
-                            }
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    }
-                }
-            }
-        },
-        "/machines/{id}": {
+        "/clusters/{id}/upgrade-plan": {
             "get": {
-                "description": "Get detailed information about a specific machine",
+                "description": "Compute the ordered upgrade steps that move a cluster to the target versions, using the Talos versions Omni knows with their supported Kubernetes versions.\nTalos moves to the newest patch of each following minor version and Kubernetes one minor version at a time; after every step Talos supports the running Kubernetes version.\nThe targets default to the newest Talos version that is not deprecated and the newest Kubernetes version it supports.\nBlockers list what has to be resolved first: upgrades in progress or failed, version skew, unhealthy nodes and failed Kubernetes upgrade pre-checks such as removed APIs still in use.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "machines"
+                    "clusters"
                 ],
-                "summary": "Get a single machine",
+                "summary": "Plan the Talos and Kubernetes upgrades of a cluster",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Machine ID",
+                        "description": "Cluster ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Target Talos version",
+                        "name": "talos_version",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Target Kubernetes version",
+                        "name": "kubernetes_version",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.MachineResponse"
+                            "$ref": "#/definitions/handlers.ClusterUpgradePlanResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "404": {
@@ -2328,20 +2803,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/machines/{id}/config-diff": {
+        "/clusters/{id}/workload-proxy-status": {
             "get": {
-                "description": "Get the configuration difference for a machine",
+                "description": "Get the status of workload proxy for a cluster",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "machines"
+                    "clusters"
                 ],
-                "summary": "Get machine config diff",
+                "summary": "Get cluster workload proxy status",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Machine ID",
+                        "description": "Cluster ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -2351,7 +2826,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.MachineConfigDiffResponse"
+                            "$ref": "#/definitions/handlers.ClusterWorkloadProxyStatusResponse"
                         }
                     },
                     "404": {
@@ -2375,38 +2850,23 @@ const docTemplate = `{
                 }
             }
         },
-        "/machines/{id}/extensions": {
+        "/configpatches": {
             "get": {
-                "description": "Get the list of Talos extensions installed on a specific machine",
+                "description": "Get a list of all config patches in Omni",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "machines"
-                ],
-                "summary": "Get machine extensions",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Machine ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "configpatches"
                 ],
+                "summary": "List all config patches",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.MachineExtensionsResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.ConfigPatchResponse"
                             }
                         }
                     },
@@ -2420,32 +2880,62 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/machines/{id}/labels": {
-            "get": {
-                "description": "Get user-defined labels for a specific machine",
+            },
+            "post": {
+                "description": "Create a new config patch for a cluster. The patch is validated as Talos machine configuration first.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "machines"
+                    "configpatches"
                 ],
-                "summary": "Get machine labels",
+                "summary": "Create a new config patch",
                 "parameters": [
+                    {
+                        "description": "Config patch creation request",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ConfigPatchCreateRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request and return the change without applying it",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
-                        "description": "Machine ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Returned instead when dryRun is set",
                         "schema": {
-                            "$ref": "#/definitions/handlers.MachineLabelsResponse"
+                            "$ref": "#/definitions/handlers.DryRunResponse"
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ConfigPatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "404": {
@@ -2457,6 +2947,15 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -2469,20 +2968,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/machines/{id}/metrics": {
+        "/configpatches/{id}": {
             "get": {
-                "description": "Get aggregated metrics for all machines in Omni",
+                "description": "Get detailed information about a specific config patch",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "machines"
+                    "configpatches"
                 ],
-                "summary": "Get machine status metrics",
+                "summary": "Get a single config patch",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Machine ID (used for link generation, metrics are global)",
+                        "description": "Config Patch ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -2492,7 +2991,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.MachineStatusMetricsResponse"
+                            "$ref": "#/definitions/handlers.ConfigPatchResponse"
                         }
                     },
                     "404": {
@@ -2514,33 +3013,57 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/machines/{id}/status": {
-            "get": {
-                "description": "Get detailed status information about a specific machine. This endpoint is deprecated - status information is now included in the main machine endpoint.",
+            },
+            "put": {
+                "description": "Update an existing config patch. The patch is validated as Talos machine configuration first.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "machines"
+                    "configpatches"
                 ],
-                "summary": "Get machine status (deprecated: use GET /machines/{id} instead)",
-                "deprecated": true,
+                "summary": "Update a config patch",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Machine ID",
+                        "description": "Config patch ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Config patch update request",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ConfigPatchUpdateRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request and return the change without applying it",
+                        "name": "dryRun",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "A DryRunResponse when dryRun is set",
                         "schema": {
-                            "$ref": "#/definitions/handlers.MachineResponse"
+                            "$ref": "#/definitions/handlers.ConfigPatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "404": {
@@ -2562,32 +3085,45 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/machines/{id}/upgrade-status": {
-            "get": {
-                "description": "Get the upgrade status and progress for a specific machine",
+            },
+            "delete": {
+                "description": "Delete a config patch from Omni",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "machines"
+                    "configpatches"
                 ],
-                "summary": "Get machine upgrade status",
+                "summary": "Delete a config patch",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Machine ID",
+                        "description": "Config patch ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request and return the change without applying it",
+                        "name": "dryRun",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Returned instead when dryRun is set",
                         "schema": {
-                            "$ref": "#/definitions/handlers.MachineUpgradeStatusResponse"
+                            "$ref": "#/definitions/handlers.DryRunResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "404": {
@@ -2611,36 +3147,34 @@ const docTemplate = `{
                 }
             }
         },
-        "/machinesetnodes": {
+        "/configpatches/{id}/history": {
             "get": {
-                "description": "Get a list of all machine set nodes in Omni",
+                "description": "List the versions of a config patch written through this API, newest first.\nThe content Omni had before the API first changed a patch is recorded as an \"observed\" revision.\nThe author is the user a trusted authenticating proxy passed with the write, empty for unauthenticated writes; the API does not verify it.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "machinesetnodes"
+                    "configpatches"
                 ],
-                "summary": "List all machine set nodes",
+                "summary": "Get config patch history",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Filter by machine set ID",
-                        "name": "machineset",
-                        "in": "query"
+                        "description": "Config patch ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/handlers.MachineSetNodeResponse"
-                            }
+                            "$ref": "#/definitions/handlers.ConfigPatchHistoryResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2651,34 +3185,46 @@ const docTemplate = `{
                 }
             }
         },
-        "/machinesetnodes/{id}": {
+        "/configpatches/{id}/history/diff": {
             "get": {
-                "description": "Get detailed information about a specific machine set node",
+                "description": "Compare two recorded versions of a config patch",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "machinesetnodes"
+                    "configpatches"
                 ],
-                "summary": "Get a single machine set node",
+                "summary": "Diff config patch revisions",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Machine Set Node ID",
+                        "description": "Config patch ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Revision to compare from (default: the revision before to)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Revision to compare to (default: the latest revision)",
+                        "name": "to",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.MachineSetNodeResponse"
+                            "$ref": "#/definitions/handlers.ConfigPatchRevisionDiffResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2686,8 +3232,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2698,23 +3244,66 @@ const docTemplate = `{
                 }
             }
         },
-        "/machinesets": {
-            "get": {
-                "description": "Get a list of all machine sets in Omni",
+        "/configpatches/{id}/rollback": {
+            "post": {
+                "description": "Restore the content of a config patch from a recorded revision. Deleted patches are recreated in the cluster they belonged to.\nThe rollback is recorded as a new revision.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "machinesets"
+                    "configpatches"
+                ],
+                "summary": "Roll back a config patch",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Config patch ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Revision to restore",
+                        "name": "revision",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request and return the change without applying it",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
                 ],
-                "summary": "List all machine sets",
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "The recorded rollback, or a DryRunResponse when dryRun is set",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/handlers.MachineSetResponse"
+                            "$ref": "#/definitions/handlers.ConfigPatchRevisionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
@@ -2730,38 +3319,31 @@ const docTemplate = `{
                 }
             }
         },
-        "/machinesets/{id}": {
+        "/etcd-manual-backups": {
             "get": {
-                "description": "Get detailed information about a specific machine set",
+                "description": "Get a list of all etcd manual backup requests",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "machinesets"
+                    "etcdbackups"
                 ],
-                "summary": "Get a single machine set",
+                "summary": "List etcd manual backups",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Machine Set ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Filter by cluster ID",
+                        "name": "cluster",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.MachineSetResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.EtcdManualBackupResponse"
                             }
                         }
                     },
@@ -2777,20 +3359,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/machinesets/{id}/destroy-status": {
+        "/etcd-manual-backups/{id}": {
             "get": {
-                "description": "Get the status of machine set destruction operation",
+                "description": "Get detailed information about a specific etcd manual backup request",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "machinesets"
+                    "etcdbackups"
                 ],
-                "summary": "Get machine set destroy status",
+                "summary": "Get an etcd manual backup",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Machine Set ID",
+                        "description": "Etcd Manual Backup ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -2800,7 +3382,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.MachineSetDestroyStatusResponse"
+                            "$ref": "#/definitions/handlers.EtcdManualBackupResponse"
                         }
                     },
                     "404": {
@@ -2824,38 +3406,31 @@ const docTemplate = `{
                 }
             }
         },
-        "/machinesets/{id}/status": {
+        "/etcdbackups": {
             "get": {
-                "description": "Get status information for a specific machine set",
+                "description": "Get a list of all etcd backups in Omni",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "machinesets"
+                    "etcdbackups"
                 ],
-                "summary": "Get machine set status",
+                "summary": "List all etcd backups",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Machine Set ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Filter by cluster ID",
+                        "name": "cluster",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.MachineSetStatusResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.EtcdBackupResponse"
                             }
                         }
                     },
@@ -2869,53 +3444,158 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/metrics": {
-            "get": {
-                "description": "Get Prometheus-style metrics for the API server",
+            },
+            "post": {
+                "description": "Trigger a manual etcd backup for a cluster",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "metrics"
+                    "etcdbackups"
                 ],
-                "summary": "Get API metrics",
+                "summary": "Trigger manual etcd backup",
+                "parameters": [
+                    {
+                        "description": "Backup request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.EtcdBackupCreateRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request and return the change without applying it",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returned instead when dryRun is set",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DryRunResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Location header points to the operation tracking the backup",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/etcdbackups/store-status": {
+            "get": {
+                "description": "Get the configured etcd backup store of Omni, whether it is usable, and the outcome of the most recent backup of any cluster.\nCombines the EtcdBackupStoreStatus and EtcdBackupOverallStatus resources.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "etcdbackups"
+                ],
+                "summary": "Get the etcd backup store status",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.MetricsResponse"
+                            "$ref": "#/definitions/handlers.EtcdBackupStoreStatusResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
         },
-        "/ongoingtasks": {
+        "/etcdbackups/{id}": {
             "get": {
-                "description": "Get a list of all currently running tasks in Omni",
+                "description": "Get detailed information about a specific etcd backup",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "ongoingtasks"
+                    "etcdbackups"
                 ],
-                "summary": "List all ongoing tasks",
+                "summary": "Get a single etcd backup",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Filter by resource ID",
-                        "name": "resource",
-                        "in": "query"
+                        "description": "Etcd Backup ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/handlers.OngoingTaskResponse"
+                            "$ref": "#/definitions/handlers.EtcdBackupResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
@@ -2931,20 +3611,124 @@ const docTemplate = `{
                 }
             }
         },
-        "/ongoingtasks/{id}": {
+        "/etcdbackups/{id}/actions/restore": {
+            "post": {
+                "description": "Create a new cluster whose control plane Omni bootstraps from the etcd snapshot of a backup, using the secrets of the backed up cluster.\nThe control plane allocates machine_count machines from machine_class, or uses the listed machines.\nThe versions default to those of the backed up cluster; Kubernetes may move one minor version ahead and Talos must not be older.\nBoth versions are required if the backed up cluster no longer exists or changed since the backup, as Omni does not record its versions.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "etcdbackups"
+                ],
+                "summary": "Restore an etcd backup into a new cluster",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Etcd Backup ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Restore request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.EtcdBackupRestoreRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request without creating the cluster",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returned instead when dryRun is set",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.EtcdBackupRestoreResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Location header points to the operation tracking the bootstrap of the restored cluster",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.EtcdBackupRestoreResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "The cluster or one of the machines already exists or is allocated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "412": {
+                        "description": "The versions are not compatible with the backup, or are required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/etcdbackups/{id}/status": {
             "get": {
-                "description": "Get detailed information about a specific ongoing task",
+                "description": "Get status of an etcd backup operation",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "ongoingtasks"
+                    "etcdbackups"
                 ],
-                "summary": "Get a single ongoing task",
+                "summary": "Get etcd backup status",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Ongoing Task ID",
+                        "description": "Etcd Backup ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -2954,7 +3738,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.OngoingTaskResponse"
+                            "$ref": "#/definitions/handlers.EtcdBackupStatusResponse"
                         }
                     },
                     "404": {
@@ -2978,23 +3762,23 @@ const docTemplate = `{
                 }
             }
         },
-        "/schematic-configurations": {
+        "/exposed-services": {
             "get": {
-                "description": "Get a list of all schematic configurations",
+                "description": "Get a list of all exposed services",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "schematics"
+                    "infrastructure"
                 ],
-                "summary": "List schematic configurations",
+                "summary": "List exposed services",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/handlers.SchematicConfigurationResponse"
+                                "$ref": "#/definitions/handlers.ExposedServiceResponse"
                             }
                         }
                     },
@@ -3010,20 +3794,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/schematic-configurations/{id}": {
+        "/exposed-services/{id}": {
             "get": {
-                "description": "Get detailed information about a specific schematic configuration",
+                "description": "Get detailed information about a specific exposed service",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "schematics"
+                    "infrastructure"
                 ],
-                "summary": "Get a schematic configuration",
+                "summary": "Get an exposed service",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Schematic Configuration ID",
+                        "description": "Exposed Service ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -3033,7 +3817,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.SchematicConfigurationResponse"
+                            "$ref": "#/definitions/handlers.ExposedServiceResponse"
                         }
                     },
                     "404": {
@@ -3057,23 +3841,23 @@ const docTemplate = `{
                 }
             }
         },
-        "/schematics": {
+        "/extensions-configurations": {
             "get": {
-                "description": "Get a list of all Talos image schematics in Omni",
+                "description": "Get a list of all extensions configurations",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "schematics"
+                    "machines"
                 ],
-                "summary": "List all schematics",
+                "summary": "List extensions configurations",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/handlers.SchematicResponse"
+                                "$ref": "#/definitions/handlers.ExtensionsConfigurationResponse"
                             }
                         }
                     },
@@ -3089,20 +3873,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/schematics/{id}": {
+        "/extensions-configurations/{id}": {
             "get": {
-                "description": "Get detailed information about a specific schematic",
+                "description": "Get detailed information about a specific extensions configuration",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "schematics"
+                    "machines"
                 ],
-                "summary": "Get a single schematic",
+                "summary": "Get an extensions configuration",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Schematic ID",
+                        "description": "Extensions Configuration ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -3112,7 +3896,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.SchematicResponse"
+                            "$ref": "#/definitions/handlers.ExtensionsConfigurationResponse"
                         }
                     },
                     "404": {
@@ -3135,19 +3919,5510 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "handlers.ClusterBootstrapResponse": {
-            "type": "object",
-            "properties": {
-                "bootstrapped": {
-                    "type": "boolean"
-                }
-            }
         },
-        "handlers.ClusterDestroyStatusResponse": {
-            "type": "object",
+        "/gitops/status": {
+            "get": {
+                "description": "Get the result of the latest sync of the OMNI_API_GITOPS_DIR directory: errors per file and the changes it applied, or would apply in observe mode.\nUnapplied changes are drift between the directory and Omni.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "gitops"
+                ],
+                "summary": "Get GitOps sync status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.GitOpsStatusResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/gitops/sync": {
+            "post": {
+                "description": "Sync the GitOps directory now instead of waiting for OMNI_API_GITOPS_INTERVAL, for example from a git hook after pulling.\nThe sync runs in the background; follow it with the status endpoint.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "gitops"
+                ],
+                "summary": "Trigger a GitOps sync",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Get the health status of the API server and Omni connection",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Get API health status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.HealthResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/image-pull-requests": {
+            "get": {
+                "description": "Get a list of all image pull requests in Omni",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "imagepullrequests"
+                ],
+                "summary": "List all image pull requests",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.ImagePullRequestResponse"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/image-pull-requests/{id}": {
+            "get": {
+                "description": "Get detailed information about a specific image pull request",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "imagepullrequests"
+                ],
+                "summary": "Get a single image pull request",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Image Pull Request ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ImagePullRequestResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/image-pull-requests/{id}/status": {
+            "get": {
+                "description": "Get the status of an image pull operation",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "imagepullrequests"
+                ],
+                "summary": "Get image pull status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Image Pull Request ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ImagePullStatusResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/infra-machine-configs": {
+            "get": {
+                "description": "Get a list of all infrastructure machine configs in Omni",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "inframachineconfigs"
+                ],
+                "summary": "List all infrastructure machine configs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by machine ID",
+                        "name": "machine",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.InfraMachineConfigResponse"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/infra-machine-configs/{id}": {
+            "get": {
+                "description": "Get detailed information about a specific infrastructure machine config",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "inframachineconfigs"
+                ],
+                "summary": "Get a single infrastructure machine config",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Infrastructure Machine Config ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.InfraMachineConfigResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/installation-medias": {
+            "get": {
+                "description": "Get a list of all installation medias in Omni",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "installationmedias"
+                ],
+                "summary": "List all installation medias",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.InstallationMediaResponse"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/installation-medias/{id}": {
+            "get": {
+                "description": "Get detailed information about a specific installation media",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "installationmedias"
+                ],
+                "summary": "Get a single installation media",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Installation Media ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.InstallationMediaResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/kernel-args": {
+            "get": {
+                "description": "Get a list of all kernel args configurations",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machines"
+                ],
+                "summary": "List kernel args",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.KernelArgsResponse"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/kernel-args/{id}": {
+            "get": {
+                "description": "Get detailed information about a specific kernel args configuration",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machines"
+                ],
+                "summary": "Get kernel args",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Kernel Args ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.KernelArgsResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/kubernetes-versions": {
+            "get": {
+                "description": "Get a list of all available Kubernetes versions",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "kubernetes"
+                ],
+                "summary": "List all Kubernetes versions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.KubernetesVersionResponse"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/kubernetes-versions/{id}": {
+            "get": {
+                "description": "Get detailed information about a specific Kubernetes version",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "kubernetes"
+                ],
+                "summary": "Get a Kubernetes version",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Kubernetes Version ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.KubernetesVersionResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/loadbalancer-configs": {
+            "get": {
+                "description": "Get a list of all load balancer configurations",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "infrastructure"
+                ],
+                "summary": "List load balancer configs",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.LoadBalancerConfigResponse"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/loadbalancer-configs/{id}": {
+            "get": {
+                "description": "Get detailed information about a specific load balancer configuration",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "infrastructure"
+                ],
+                "summary": "Get a load balancer config",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Load Balancer Config ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LoadBalancerConfigResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/loadbalancers/{id}/status": {
+            "get": {
+                "description": "Get status of a load balancer",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "infrastructure"
+                ],
+                "summary": "Get load balancer status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Load Balancer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LoadBalancerStatusResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machine-request-sets": {
+            "get": {
+                "description": "Get a list of all machine request sets",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machines"
+                ],
+                "summary": "List machine request sets",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.MachineRequestSetResponse"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machine-request-sets/{id}": {
+            "get": {
+                "description": "Get detailed information about a specific machine request set",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machines"
+                ],
+                "summary": "Get a machine request set",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine Request Set ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineRequestSetResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machineclasses": {
+            "get": {
+                "description": "Get a list of all machine classes in Omni",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machineclasses"
+                ],
+                "summary": "List all machine classes",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.MachineClassResponse"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a machine class that either selects existing machines by label (match_labels) or has an infrastructure provider create them (provision).\nUse GET /machineclasses/{id}/matches to check which machines a label-match class selects.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machineclasses"
+                ],
+                "summary": "Create a machine class",
+                "parameters": [
+                    {
+                        "description": "Machine class creation request",
+                        "name": "machineclass",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineClassCreateRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request and return the change without applying it",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returned instead when dryRun is set",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DryRunResponse"
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machineclasses/{id}": {
+            "get": {
+                "description": "Get detailed information about a specific machine class",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machineclasses"
+                ],
+                "summary": "Get a single machine class",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine Class ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineClassResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace the selectors or provisioning settings of a machine class. Machine sets using the class pick up the change.\nProvisioning meta values and the gRPC tunnel mode set outside the API are kept.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machineclasses"
+                ],
+                "summary": "Update a machine class",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine class ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Machine class update request",
+                        "name": "machineclass",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineClassUpdateRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request and return the change without applying it",
+                        "name": "dryRun",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "A DryRunResponse when dryRun is set",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a machine class that no machine set allocates machines from",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machineclasses"
+                ],
+                "summary": "Delete a machine class",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine class ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request and return the change without applying it",
+                        "name": "dryRun",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "A DryRunResponse when dryRun is set",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "412": {
+                        "description": "The machine class is used by machine sets",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machineclasses/{id}/matches": {
+            "get": {
+                "description": "Evaluate the selectors of a machine class against all machines and list the matching machines, with how many are available and how many are already allocated.\nMachine classes that provision their machines have no selectors to evaluate.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machineclasses"
+                ],
+                "summary": "Preview the machines of a machine class",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine Class ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineClassMatchesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "The machine class provisions its machines",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machines": {
+            "get": {
+                "description": "Get a list of all machines in Omni",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machines"
+                ],
+                "summary": "List all machines",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.MachineResponse"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machines/labels": {
+            "patch": {
+                "description": "Merge labels into the user labels of every machine matching an Omni label selector, e.g. \"rack=r1\" or \"rack in (r1, r2), !owner\". A null value deletes the label.\nMachines are changed one by one; the response lists the outcome per machine and a machine that fails does not stop the others.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machines"
+                ],
+                "summary": "Change the labels of many machines",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Label selector matched against the machine labels",
+                        "name": "selector",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Labels to merge",
+                        "name": "labels",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineLabelsPatchRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Report the changes without applying them",
+                        "name": "dryRun",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineLabelsPatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machines/{id}": {
+            "get": {
+                "description": "Get detailed information about a specific machine",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machines"
+                ],
+                "summary": "Get a single machine",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Update machine labels, extensions, or maintenance mode.\nLabels are merged into the user labels like a JSON merge patch: a null value deletes the label. Labels starting with omni.sidero.dev/ cannot be changed.\nAll changes are checked before any is applied, so a request that fails changes nothing. Changing extensions is not supported yet and fails with 501.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machines"
+                ],
+                "summary": "Update a machine",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Machine update request",
+                        "name": "machine",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineUpdateRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request and return the change without applying it",
+                        "name": "dryRun",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "A DryRunResponse when dryRun is set, or a DryRunChangesResponse if more than one of labels, extensions and maintenance is set",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machines/{id}/actions/maintenance": {
+            "post": {
+                "description": "Move a machine into maintenance mode by taking it out of its machine set, which makes Omni remove it from the cluster and reset it,\nor out of maintenance mode by adding it back to the machine set it was taken out of, or to machine_set.\nMachines of machine sets that allocate from a machine class cannot be moved; scale the machine set instead.\nControl plane machines are refused with 412 if they are the last of their machine set, or if the other healthy control plane machines would not make up an etcd quorum.\nmaintenance is the current MachineStatus state; the operation completes once it matches enabled.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machines"
+                ],
+                "summary": "Toggle machine maintenance mode",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Maintenance mode request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineMaintenanceRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request and return the change without applying it",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The machine already is in the requested state; a DryRunResponse when dryRun is set",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "202": {
+                        "description": "Location header points to the operation tracking the maintenance state",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machines/{id}/actions/maintenance-upgrade": {
+            "post": {
+                "description": "Install a Talos version on a machine that runs in maintenance mode and is not part of a cluster, e.g. to bring new machines to a baseline before allocating them.\nThe machine keeps its image factory schematic unless schematic is set. The operation completes once the machine reports the new version.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machines"
+                ],
+                "summary": "Upgrade a machine in maintenance mode",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Talos version and schematic",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineMaintenanceUpgradeRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request without upgrading",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returned instead when dryRun is set",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DryRunResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Location header points to the operation tracking the upgrade",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "412": {
+                        "description": "The machine is not in maintenance mode or its schematic is unknown",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machines/{id}/actions/reboot": {
+            "post": {
+                "description": "Trigger a reboot of a machine via Talos API",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machines"
+                ],
+                "summary": "Reboot a machine",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request and return the change without applying it",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returned instead when dryRun is set",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DryRunResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Location header points to the operation tracking the reboot",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machines/{id}/actions/reset": {
+            "post": {
+                "description": "Trigger a reset of a machine via Talos API",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machines"
+                ],
+                "summary": "Reset a machine",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request and return the change without applying it",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returned instead when dryRun is set",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DryRunResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "501": {
+                        "description": "Resetting machines is not supported yet",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machines/{id}/actions/shutdown": {
+            "post": {
+                "description": "Trigger a shutdown of a machine via Talos API",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machines"
+                ],
+                "summary": "Shutdown a machine",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request and return the change without applying it",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returned instead when dryRun is set",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DryRunResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "501": {
+                        "description": "Shutting down machines is not supported yet",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machines/{id}/config-diff": {
+            "get": {
+                "description": "Get the configuration difference for a machine",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machines"
+                ],
+                "summary": "Get machine config diff",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineConfigDiffResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machines/{id}/config-preview": {
+            "post": {
+                "description": "Render the Talos machine config of a cluster machine with a proposed config patch applied, without changing anything.\nThe config is rendered like Omni does: the base config generated from the cluster secrets gets every patch Omni applies to the machine, in Omni's order (cluster, then machine set, then machine, by ID within a level).\nThe proposed patch replaces the existing patch with its ID, or is inserted where Omni would apply it.\nSecrets are redacted and the response includes a unified diff against the config rendered without the proposed patch.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machines"
+                ],
+                "summary": "Preview a machine config",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Proposed config patch",
+                        "name": "preview",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineConfigPreviewRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineConfigPreviewResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machines/{id}/extensions": {
+            "get": {
+                "description": "Get the list of Talos extensions installed on a specific machine",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machines"
+                ],
+                "summary": "Get machine extensions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineExtensionsResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machines/{id}/labels": {
+            "get": {
+                "description": "Get user-defined labels for a specific machine",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machines"
+                ],
+                "summary": "Get machine labels",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineLabelsResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace all user labels of a machine. Labels starting with omni.sidero.dev/ are managed by Omni; they cannot be set and are kept.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machines"
+                ],
+                "summary": "Replace machine labels",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "User labels",
+                        "name": "labels",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineLabelsReplaceRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request and return the change without applying it",
+                        "name": "dryRun",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "A DryRunResponse when dryRun is set",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineLabelsResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machines/{id}/logs": {
+            "get": {
+                "description": "Stream the logs of a machine as chunked plain text, or as Server-Sent Events (\"log\" events) with format=sse or Accept: text/event-stream.\nWithout a service the machine console logs collected by Omni are returned; \"kernel\" reads the kernel ring buffer and any other name reads that Talos service (kubelet, etcd, apid, ...) through the Talos proxy.\nThe stream ends when the client disconnects.",
+                "produces": [
+                    "text/plain",
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "machines"
+                ],
+                "summary": "Stream machine logs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Log source: console (default), kernel or a Talos service such as kubelet, etcd or apid",
+                        "name": "service",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Keep streaming new log lines",
+                        "name": "follow",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Start this many lines from the end (default: all lines)",
+                        "name": "tail_lines",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "text",
+                            "sse"
+                        ],
+                        "type": "string",
+                        "default": "text",
+                        "description": "Output format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machines/{id}/metrics": {
+            "get": {
+                "description": "Get aggregated metrics for all machines in Omni",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machines"
+                ],
+                "summary": "Get machine status metrics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine ID (used for link generation, metrics are global)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineStatusMetricsResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machines/{id}/status": {
+            "get": {
+                "description": "Get detailed status information about a specific machine. This endpoint is deprecated - status information is now included in the main machine endpoint.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machines"
+                ],
+                "summary": "Get machine status (deprecated: use GET /machines/{id} instead)",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machines/{id}/upgrade-status": {
+            "get": {
+                "description": "Get the upgrade status and progress for a specific machine",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machines"
+                ],
+                "summary": "Get machine upgrade status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineUpgradeStatusResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machinesetnodes": {
+            "get": {
+                "description": "Get a list of all machine set nodes in Omni",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machinesetnodes"
+                ],
+                "summary": "List all machine set nodes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by machine set ID",
+                        "name": "machineset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.MachineSetNodeResponse"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machinesetnodes/{id}": {
+            "get": {
+                "description": "Get detailed information about a specific machine set node",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machinesetnodes"
+                ],
+                "summary": "Get a single machine set node",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine Set Node ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineSetNodeResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machinesets": {
+            "get": {
+                "description": "Get a list of all machine sets in Omni",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machinesets"
+                ],
+                "summary": "List all machine sets",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.MachineSetResponse"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new machine set in Omni",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machinesets"
+                ],
+                "summary": "Create a new machine set",
+                "parameters": [
+                    {
+                        "description": "Machine set creation request",
+                        "name": "machineset",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineSetCreateRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request and return the change without applying it",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returned instead when dryRun is set",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DryRunResponse"
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machinesets/{id}": {
+            "get": {
+                "description": "Get detailed information about a specific machine set",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machinesets"
+                ],
+                "summary": "Get a single machine set",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine Set ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineSetResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update an existing machine set in Omni",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machinesets"
+                ],
+                "summary": "Update a machine set",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine set ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Machine set update request",
+                        "name": "machineset",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineSetUpdateRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request and return the change without applying it",
+                        "name": "dryRun",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "A DryRunResponse when dryRun is set",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a machine set from Omni",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machinesets"
+                ],
+                "summary": "Delete a machine set",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine set ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request and return the change without applying it",
+                        "name": "dryRun",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returned instead when dryRun is set",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DryRunResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Location header points to the operation tracking the deletion",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machinesets/{id}/actions/destroy": {
+            "post": {
+                "description": "Trigger destruction/teardown of a machine set",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machinesets"
+                ],
+                "summary": "Trigger machine set destruction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine set ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request and return the change without applying it",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returned instead when dryRun is set",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DryRunResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Location header points to the operation tracking the destruction",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machinesets/{id}/allocate": {
+            "post": {
+                "description": "Choose free machines and add them to a machine set that is not backed by a machine class, creating a MachineSetNode for each.\nMachines are eligible if they are connected, not already in a machine set, match the selector or machine class and the arch and platform.\nEligible machines are ranked by \"lru\" (least recently allocated first) or \"spread\" (balance the machine set over the values of spread_label, e.g. racks or zones).\nEither all chosen machines are allocated or none; the response lists every other machine with the reason it was not chosen.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machinesets"
+                ],
+                "summary": "Allocate machines to a machine set",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine set ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Allocation request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineAllocationRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Choose the machines without allocating them",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returned when dryRun is set",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineAllocationResponse"
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineAllocationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Too few eligible machines; rejected lists the reasons",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machinesets/{id}/destroy-status": {
+            "get": {
+                "description": "Get the status of machine set destruction operation",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machinesets"
+                ],
+                "summary": "Get machine set destroy status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine Set ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineSetDestroyStatusResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machinesets/{id}/scale": {
+            "get": {
+                "description": "Get the desired machine count of a machine set and the actual counts from its status.\nFor machine sets with manually allocated machines the desired count is the number of machine set nodes.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machinesets"
+                ],
+                "summary": "Get the scale of a machine set",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine Set ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineSetScale"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Set the number of machines a machine set takes from its machine class. Omni then adds or removes machines following the update and delete strategies.\nIf resource_version is set and the machine set changed since, nothing is written and 409 is returned.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machinesets"
+                ],
+                "summary": "Scale a machine set",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine Set ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Desired machine count",
+                        "name": "scale",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineSetScaleRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the request and return the change without applying it",
+                        "name": "dryRun",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "A DryRunResponse when dryRun is set",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineSetScale"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "The machine set changed since resource_version",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "412": {
+                        "description": "The machine set is not backed by a machine class with a fixed machine count",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/machinesets/{id}/status": {
+            "get": {
+                "description": "Get status information for a specific machine set",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "machinesets"
+                ],
+                "summary": "Get machine set status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Machine Set ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MachineSetStatusResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics": {
+            "get": {
+                "description": "Get Prometheus-style metrics for the API server",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "metrics"
+                ],
+                "summary": "Get API metrics",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MetricsResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/oidc/providers": {
+            "get": {
+                "description": "Get a list of all configured OIDC providers",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "List OIDC providers",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.OIDCProviderResponse"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new OIDC provider configuration",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "Create an OIDC provider",
+                "parameters": [
+                    {
+                        "description": "OIDC provider creation request",
+                        "name": "provider",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateOIDCProviderRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OIDCProviderResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/oidc/providers/{id}": {
+            "get": {
+                "description": "Get details of a specific OIDC provider",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "Get an OIDC provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "OIDC provider ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OIDCProviderResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update an existing OIDC provider configuration",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "Update an OIDC provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "OIDC provider ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "OIDC provider update request",
+                        "name": "provider",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateOIDCProviderRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OIDCProviderResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete an OIDC provider configuration",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "Delete an OIDC provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "OIDC provider ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/omni-audit-log": {
+            "get": {
+                "description": "Stream Omni's audit log between two dates (inclusive, UTC) as newline-delimited JSON, or as CSV with format=csv or Accept: text/csv.\nEvery record has the same shape whatever its source; data holds the original entry.\nsource=all appends the entries this API recorded itself (credential downloads and the like) after Omni's, so a single request covers both.\nThis API's entries are served from memory and only cover the most recent OMNI_API_AUDIT_LOG_MAX_ENTRIES since the last restart.",
+                "produces": [
+                    "application/x-ndjson",
+                    "text/csv"
+                ],
+                "tags": [
+                    "audit"
+                ],
+                "summary": "Export the audit log",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "First day to export, YYYY-MM-DD (default: today)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last day to export, YYYY-MM-DD (default: from)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated actors (Omni user e-mail or ID, or this API's actor) to include",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated resource types to include, e.g. Clusters.omni.sidero.dev or kubeconfig",
+                        "name": "resource_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated event types to include, e.g. create, update, destroy or kubeconfig.generate",
+                        "name": "event_type",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "omni",
+                            "omni-api",
+                            "all"
+                        ],
+                        "type": "string",
+                        "default": "omni",
+                        "description": "Audit logs to read",
+                        "name": "source",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "ndjson",
+                            "csv"
+                        ],
+                        "type": "string",
+                        "default": "ndjson",
+                        "description": "Output format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "One record per line",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AuditRecord"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/omniconfig": {
+            "get": {
+                "description": "Generate an omniconfig for omnictl through the Omni Management API. Users authenticate against Omni when they first use it.",
+                "produces": [
+                    "application/json",
+                    "application/yaml"
+                ],
+                "tags": [
+                    "omniconfig"
+                ],
+                "summary": "Generate omniconfig",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Return the raw omniconfig file instead of JSON; also selected by Accept: application/yaml",
+                        "name": "download",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OmniconfigResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/ongoingtasks": {
+            "get": {
+                "description": "Get a list of all currently running tasks in Omni",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ongoingtasks"
+                ],
+                "summary": "List all ongoing tasks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by resource ID",
+                        "name": "resource",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.OngoingTaskResponse"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/ongoingtasks/{id}": {
+            "get": {
+                "description": "Get detailed information about a specific ongoing task",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ongoingtasks"
+                ],
+                "summary": "Get a single ongoing task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Ongoing Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OngoingTaskResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operations": {
+            "get": {
+                "description": "Get a list of long-running operations started by asynchronous actions, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "operations"
+                ],
+                "summary": "List operations",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.OperationResponse"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operations/{id}": {
+            "get": {
+                "description": "Get the progress of a long-running operation. Use wait (e.g. 30s, at most 5m) to long-poll until the operation completes.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "operations"
+                ],
+                "summary": "Get an operation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Operation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Maximum time to wait for completion, e.g. 30s",
+                        "name": "wait",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OperationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operations/{id}/cancel": {
+            "post": {
+                "description": "Cancel a running operation, e.g. by reverting an upgrade to the previous version. Not all operations can be canceled.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "operations"
+                ],
+                "summary": "Cancel an operation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Operation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OperationResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Report whether the API can currently reach Omni, including the circuit breaker state. Returns 503 while Omni is unavailable.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Get API readiness",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReadinessResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReadinessResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/schematic-configurations": {
+            "get": {
+                "description": "Get a list of all schematic configurations",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schematics"
+                ],
+                "summary": "List schematic configurations",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.SchematicConfigurationResponse"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/schematic-configurations/{id}": {
+            "get": {
+                "description": "Get detailed information about a specific schematic configuration",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schematics"
+                ],
+                "summary": "Get a schematic configuration",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Schematic Configuration ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SchematicConfigurationResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/schematics": {
+            "get": {
+                "description": "Get a list of all Talos image schematics in Omni",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schematics"
+                ],
+                "summary": "List all schematics",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.SchematicResponse"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/schematics/{id}": {
+            "get": {
+                "description": "Get detailed information about a specific schematic",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schematics"
+                ],
+                "summary": "Get a single schematic",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Schematic ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SchematicResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/support-bundles/{id}": {
+            "get": {
+                "description": "Get the status and per-node progress of a support bundle",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "support-bundles"
+                ],
+                "summary": "Get a support bundle",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Support bundle ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SupportBundleResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/support-bundles/{id}/download": {
+            "get": {
+                "description": "Download the zip archive of a ready support bundle",
+                "produces": [
+                    "application/zip"
+                ],
+                "tags": [
+                    "support-bundles"
+                ],
+                "summary": "Download a support bundle",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Support bundle ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/support-bundles/{id}/events": {
+            "get": {
+                "description": "Stream the progress of a support bundle as Server-Sent Events.\nA \"progress\" event with the source (node), state and value/total is sent whenever the progress of a node changes, followed by a final \"done\" event carrying the SupportBundleResponse.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "support-bundles"
+                ],
+                "summary": "Stream support bundle progress",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Support bundle ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "allocation.Choice": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "allocation.Rejection": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "bulk.ItemResult": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "result": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/bulk.ItemStatus"
+                },
+                "target": {
+                    "type": "string"
+                }
+            }
+        },
+        "bulk.ItemStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "running",
+                "succeeded",
+                "failed",
+                "skipped"
+            ],
+            "x-enum-varnames": [
+                "ItemPending",
+                "ItemRunning",
+                "ItemSucceeded",
+                "ItemFailed",
+                "ItemSkipped"
+            ]
+        },
+        "bulk.Mode": {
+            "type": "string",
+            "enum": [
+                "continue",
+                "fail_fast"
+            ],
+            "x-enum-varnames": [
+                "ModeContinue",
+                "ModeFailFast"
+            ]
+        },
+        "bulk.Report": {
+            "type": "object",
+            "properties": {
+                "concurrency": {
+                    "type": "integer"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/bulk.ItemResult"
+                    }
+                },
+                "mode": {
+                    "$ref": "#/definitions/bulk.Mode"
+                },
+                "skipped": {
+                    "type": "integer"
+                },
+                "succeeded": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "campaign.Cluster": {
+            "type": "object",
+            "properties": {
+                "completed": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/upgradeplan.Step"
+                    }
+                },
+                "completed_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "message": {
+                    "description": "Progress of the current step or health gate",
+                    "type": "string"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/campaign.ClusterStatus"
+                },
+                "step": {
+                    "description": "Step being run; kept when the campaign is paused or the step failed, and run again on resume",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/upgradeplan.Step"
+                        }
+                    ]
+                },
+                "wave": {
+                    "type": "integer"
+                }
+            }
+        },
+        "campaign.ClusterStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "upgrading",
+                "succeeded",
+                "failed"
+            ],
+            "x-enum-varnames": [
+                "ClusterPending",
+                "ClusterUpgrading",
+                "ClusterSucceeded",
+                "ClusterFailed"
+            ]
+        },
+        "campaign.Spec": {
+            "type": "object",
+            "properties": {
+                "canary": {
+                    "description": "Cluster upgraded first, alone; defaults to the first selected cluster by ID",
+                    "type": "string"
+                },
+                "kubernetes_version": {
+                    "description": "Target Kubernetes version; unset keeps the clusters' versions",
+                    "type": "string"
+                },
+                "selector": {
+                    "description": "Omni label selector over cluster labels, e.g. env=prod; empty selects every cluster",
+                    "type": "string"
+                },
+                "talos_version": {
+                    "description": "Target Talos version; unset keeps the clusters' versions",
+                    "type": "string"
+                },
+                "waves": {
+                    "description": "Share of the selected clusters upgraded in each wave after the canary, in percent; the rest follow in a last wave",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "campaign.Status": {
+            "type": "string",
+            "enum": [
+                "running",
+                "paused",
+                "succeeded",
+                "aborted"
+            ],
+            "x-enum-varnames": [
+                "StatusRunning",
+                "StatusPaused",
+                "StatusSucceeded",
+                "StatusAborted"
+            ]
+        },
+        "client.SupportBundleProgress": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "source": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "value": {
+                    "type": "integer"
+                }
+            }
+        },
+        "gitops.Change": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "applied": {
+                    "type": "boolean"
+                },
+                "diff": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "gitops.SourceStatus": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/gitops.Change"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "file": {
+                    "description": "Relative to the directory",
+                    "type": "string"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Cluster of a template, or ID of a config patch",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.AuditRecord": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "type": "string"
+                },
+                "data": {
+                    "description": "The original entry",
+                    "type": "object"
+                },
+                "event_type": {
+                    "type": "string"
+                },
+                "resource_id": {
+                    "type": "string"
+                },
+                "resource_type": {
+                    "type": "string"
+                },
+                "source": {
+                    "type": "string"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.BulkDryRunResponse": {
+            "type": "object",
+            "properties": {
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "report": {
+                    "$ref": "#/definitions/bulk.Report"
+                }
+            }
+        },
+        "handlers.BulkOperation": {
+            "type": "object",
+            "required": [
+                "action"
+            ],
+            "properties": {
+                "action": {
+                    "description": "reboot, patch-labels, delete-config-patch, delete-machine-set, delete-machine-class or delete-cluster",
+                    "type": "string"
+                },
+                "labels": {
+                    "description": "Labels merged by patch-labels; a null value deletes the label",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "target": {
+                    "description": "ID of the machine, config patch, machine set, machine class or cluster; filled in from the selector if set",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.BulkRequest": {
+            "type": "object",
+            "properties": {
+                "concurrency": {
+                    "description": "Items run at the same time, default 4, at most 32",
+                    "type": "integer"
+                },
+                "mode": {
+                    "description": "continue (default) or fail_fast",
+                    "type": "string"
+                },
+                "operation": {
+                    "description": "Run against every target matching selector",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/handlers.BulkOperation"
+                        }
+                    ]
+                },
+                "operations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.BulkOperation"
+                    }
+                },
+                "selector": {
+                    "description": "Omni label selector, e.g. \"rack=a, role=worker\"",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.CampaignListResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.CampaignResponse"
+                    }
+                }
+            }
+        },
+        "handlers.CampaignResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "clusters": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/campaign.Cluster"
+                    }
+                },
+                "completed_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "error": {
+                    "description": "Why the campaign paused",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "spec": {
+                    "$ref": "#/definitions/campaign.Spec"
+                },
+                "status": {
+                    "$ref": "#/definitions/campaign.Status"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "wave": {
+                    "description": "Current wave, starting with the canary at 0",
+                    "type": "integer"
+                },
+                "waves": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.CircuitBreakerStatus": {
+            "type": "object",
+            "properties": {
+                "consecutive_failures": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "opened_at": {
+                    "type": "string"
+                },
+                "retry_after_seconds": {
+                    "type": "integer"
+                },
+                "state": {
+                    "type": "string"
+                },
+                "trips": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.ClusterActionRequest": {
+            "type": "object",
+            "properties": {
+                "version": {
+                    "description": "For upgrade actions",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ClusterBackupRequest": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "interval": {
+                    "description": "Go duration, e.g. 6h; required when enabled",
+                    "type": "string"
+                },
+                "retention": {
+                    "description": "Left unchanged when omitted; {} removes it",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/retention.Policy"
+                        }
+                    ]
+                }
+            }
+        },
+        "handlers.ClusterBackupResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "cluster": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "interval": {
+                    "type": "string"
+                },
+                "retention": {
+                    "$ref": "#/definitions/retention.Policy"
+                }
+            }
+        },
+        "handlers.ClusterBackupRetentionResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "backups": {
+                    "description": "Newest first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/retention.Decision"
+                    }
+                },
+                "cluster": {
+                    "type": "string"
+                },
+                "gaps": {
+                    "description": "Days and weeks covered by the policy without a backup",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "kept": {
+                    "type": "integer"
+                },
+                "policy": {
+                    "$ref": "#/definitions/retention.Policy"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "violations": {
+                    "description": "Backups no rule keeps",
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.ClusterBootstrapResponse": {
+            "type": "object",
+            "properties": {
+                "bootstrapped": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "handlers.ClusterCreateRequest": {
+            "type": "object",
+            "required": [
+                "id",
+                "kubernetes_version"
+            ],
+            "properties": {
+                "backup_configuration": {
+                    "description": "Etcd backups are disabled when omitted",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/handlers.ClusterBackupRequest"
+                        }
+                    ]
+                },
+                "features": {
+                    "$ref": "#/definitions/handlers.ClusterFeaturesRequest"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "kubernetes_version": {
+                    "type": "string"
+                },
+                "talos_version": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ClusterDestroyStatusResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "namespace": {
+                    "type": "string"
+                },
+                "phase": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ClusterDiagnosticsNode": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "num_diagnostics": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.ClusterDiagnosticsResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "namespace": {
+                    "type": "string"
+                },
+                "nodes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.ClusterDiagnosticsNode"
+                    }
+                }
+            }
+        },
+        "handlers.ClusterEndpointResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "management_addresses": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "namespace": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ClusterFeaturesRequest": {
+            "type": "object",
+            "properties": {
+                "disk_encryption": {
+                    "type": "boolean"
+                },
+                "workload_proxy": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "handlers.ClusterKubernetesNodeResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "labels": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "namespace": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ClusterMachineConfigResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "cluster_machine_version": {
+                    "type": "string"
+                },
+                "config_size": {
+                    "type": "integer"
+                },
+                "generation_error": {
+                    "type": "string"
+                },
+                "grub_use_uki_cmdline": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "namespace": {
+                    "type": "string"
+                },
+                "without_comments": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "handlers.ClusterMachineConfigStatusResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "cluster_machine_config_sha256": {
+                    "type": "string"
+                },
+                "cluster_machine_config_version": {
+                    "type": "string"
+                },
+                "cluster_machine_version": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_config_error": {
+                    "type": "string"
+                },
+                "namespace": {
+                    "type": "string"
+                },
+                "schematic_id": {
+                    "type": "string"
+                },
+                "talos_version": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ClusterMachineResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "kubernetes_version": {
+                    "type": "string"
+                },
+                "labels": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "machine_id": {
+                    "type": "string"
+                },
+                "namespace": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ClusterMachineStatusResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "apid_available": {
+                    "type": "boolean"
+                },
+                "config_apply_status": {
+                    "type": "string"
+                },
+                "config_up_to_date": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "is_removed": {
+                    "type": "boolean"
+                },
+                "last_config_error": {
+                    "type": "string"
+                },
+                "management_address": {
+                    "type": "string"
+                },
+                "namespace": {
+                    "type": "string"
+                },
+                "provision_status": {
+                    "$ref": "#/definitions/handlers.ProvisionStatus"
+                },
+                "ready": {
+                    "type": "boolean"
+                },
+                "stage": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ClusterMachineTalosVersionResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "namespace": {
+                    "type": "string"
+                },
+                "schematic_id": {
+                    "type": "string"
+                },
+                "talos_version": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ClusterMetricsResponse": {
+            "type": "object",
+            "properties": {
+                "features": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer",
+                        "format": "int32"
+                    }
+                }
+            }
+        },
+        "handlers.ClusterResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "features": {
+                    "type": "object",
+                    "properties": {
+                        "disk_encryption": {
+                            "type": "boolean"
+                        },
+                        "workload_proxy": {
+                            "type": "boolean"
+                        }
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "kubernetes_version": {
+                    "type": "string"
+                },
+                "namespace": {
+                    "type": "string"
+                },
+                "talos_version": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ClusterStatusResponse": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "type": "boolean"
+                },
+                "controlplane_ready": {
+                    "type": "boolean"
+                },
+                "has_connected_control_planes": {
+                    "type": "boolean"
+                },
+                "kubernetes_api_ready": {
+                    "type": "boolean"
+                },
+                "machines": {
+                    "type": "object",
+                    "properties": {
+                        "connected": {
+                            "type": "integer"
+                        },
+                        "healthy": {
+                            "type": "integer"
+                        },
+                        "requested": {
+                            "type": "integer"
+                        },
+                        "total": {
+                            "type": "integer"
+                        }
+                    }
+                },
+                "phase": {
+                    "type": "string"
+                },
+                "ready": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "handlers.ClusterUpdateRequest": {
+            "type": "object",
+            "properties": {
+                "features": {
+                    "description": "Left unchanged when omitted",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/handlers.ClusterFeaturesRequest"
+                        }
+                    ]
+                },
+                "kubernetes_version": {
+                    "type": "string"
+                },
+                "talos_version": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ClusterUpgradePlanResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "blockers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.UpgradeBlocker"
+                    }
+                },
+                "cluster": {
+                    "type": "string"
+                },
+                "current": {
+                    "$ref": "#/definitions/handlers.UpgradePlanVersions"
+                },
+                "ready": {
+                    "description": "The first step can run now",
+                    "type": "boolean"
+                },
+                "steps": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.UpgradePlanStep"
+                    }
+                },
+                "target": {
+                    "$ref": "#/definitions/handlers.UpgradePlanVersions"
+                }
+            }
+        },
+        "handlers.ClusterWorkloadProxyStatusResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "namespace": {
+                    "type": "string"
+                },
+                "num_exposed_services": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.Condition": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ConfigPatchCreateRequest": {
+            "type": "object",
+            "required": [
+                "cluster",
+                "data",
+                "id"
+            ],
+            "properties": {
+                "cluster": {
+                    "type": "string"
+                },
+                "data": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ConfigPatchHistoryResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "revisions": {
+                    "description": "Newest first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.ConfigPatchRevisionResponse"
+                    }
+                }
+            }
+        },
+        "handlers.ConfigPatchResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "data": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "namespace": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ConfigPatchRevisionDiffResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "diff": {
+                    "description": "Unified diff, empty if the revisions are equal",
+                    "type": "string"
+                },
+                "from": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.ConfigPatchRevisionResponse": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "author": {
+                    "description": "User reported by the authenticating proxy; informational only",
+                    "type": "string"
+                },
+                "cluster": {
+                    "type": "string"
+                },
+                "data": {
+                    "type": "string"
+                },
+                "resource_version": {
+                    "type": "string"
+                },
+                "revision": {
+                    "type": "integer"
+                },
+                "rollback_of": {
+                    "description": "Revision restored by a rollback",
+                    "type": "integer"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ConfigPatchUpdateRequest": {
+            "type": "object",
+            "required": [
+                "data"
+            ],
+            "properties": {
+                "data": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ControlPlaneStatusResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "conditions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.Condition"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "namespace": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.CreateOIDCProviderRequest": {
+            "type": "object",
+            "required": [
+                "client_id",
+                "issuer_url",
+                "name"
+            ],
+            "properties": {
+                "client_id": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "issuer_url": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "handlers.CreateServiceAccountRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "handlers.DryRunResponse": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "cascade": {
+                    "description": "Other resources that would be destroyed",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "diff": {
+                    "description": "Unified diff against the current state",
+                    "type": "string"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "resource": {
+                    "description": "The resource that would be written",
+                    "type": "object",
+                    "additionalProperties": true
+                }
+            }
+        },
+        "handlers.EtcdBackupCreateRequest": {
+            "type": "object",
+            "required": [
+                "cluster"
+            ],
+            "properties": {
+                "cluster": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.EtcdBackupLastStatusResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "last_backup_attempt": {
+                    "type": "string"
+                },
+                "last_backup_time": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.EtcdBackupResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "namespace": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "snapshot": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.EtcdBackupRestoreControlPlane": {
+            "type": "object",
+            "properties": {
+                "machine_class": {
+                    "type": "string"
+                },
+                "machine_count": {
+                    "type": "integer"
+                },
+                "machines": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "handlers.EtcdBackupRestoreRequest": {
+            "type": "object",
+            "required": [
+                "cluster"
+            ],
+            "properties": {
+                "cluster": {
+                    "description": "ID of the new cluster",
+                    "type": "string"
+                },
+                "control_plane": {
+                    "$ref": "#/definitions/handlers.EtcdBackupRestoreControlPlane"
+                },
+                "features": {
+                    "$ref": "#/definitions/handlers.ClusterFeaturesRequest"
+                },
+                "kubernetes_version": {
+                    "description": "Defaults to the version of the backed up cluster, required if it is not known",
+                    "type": "string"
+                },
+                "talos_version": {
+                    "description": "Defaults to the version of the backed up cluster, required if it is not known",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.EtcdBackupRestoreResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "backup": {
+                    "type": "string"
+                },
+                "cluster": {
+                    "type": "string"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "kubernetes_version": {
+                    "type": "string"
+                },
+                "operation_id": {
+                    "type": "string"
+                },
+                "resources": {
+                    "description": "Resources created for the restore",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "snapshot": {
+                    "type": "string"
+                },
+                "source_cluster_uuid": {
+                    "type": "string"
+                },
+                "talos_version": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.EtcdBackupStatusResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "namespace": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.EtcdBackupStoreStatusResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "healthy": {
+                    "type": "boolean"
+                },
+                "last_backup": {
+                    "description": "Most recent backup of any cluster",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/handlers.EtcdBackupLastStatusResponse"
+                        }
+                    ]
+                },
+                "store": {
+                    "description": "Configured store: disabled, local or s3",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.EtcdManualBackupResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "backup_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "namespace": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ExposedServiceResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "has_explicit_alias": {
+                    "type": "boolean"
+                },
+                "icon_base64": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "namespace": {
+                    "type": "string"
+                },
+                "port": {
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ExtensionsConfigurationResponse": {
+            "type": "object",
             "properties": {
                 "_links": {
                     "type": "object",
@@ -3155,29 +9430,296 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
+                "extensions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "id": {
                     "type": "string"
                 },
                 "namespace": {
                     "type": "string"
+                }
+            }
+        },
+        "handlers.GitOpsStatusResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
                 },
-                "phase": {
+                "dir": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "error": {
                     "type": "string"
+                },
+                "in_sync": {
+                    "description": "The latest sync found no errors and left no changes unapplied",
+                    "type": "boolean"
+                },
+                "last_success": {
+                    "type": "string"
+                },
+                "last_sync": {
+                    "type": "string"
+                },
+                "mode": {
+                    "type": "string"
+                },
+                "prune": {
+                    "type": "boolean"
+                },
+                "pruned": {
+                    "description": "Config patches removed from the directory",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/gitops.Change"
+                    }
+                },
+                "revision": {
+                    "description": "Commit checked out in the directory, if it is a git checkout",
+                    "type": "string"
+                },
+                "sources": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/gitops.SourceStatus"
+                    }
+                },
+                "syncing": {
+                    "type": "boolean"
                 }
             }
         },
-        "handlers.ClusterDiagnosticsNode": {
+        "handlers.HealthResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "omni": {
+                    "$ref": "#/definitions/handlers.OmniHealthStatus"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ImagePullRequestResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "namespace": {
+                    "type": "string"
+                },
+                "node_image_list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.NodeImageList"
+                    }
+                }
+            }
+        },
+        "handlers.ImagePullStatusResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_processed_error": {
+                    "type": "string"
+                },
+                "last_processed_image": {
+                    "type": "string"
+                },
+                "last_processed_node": {
+                    "type": "string"
+                },
+                "namespace": {
+                    "type": "string"
+                },
+                "processed_count": {
+                    "type": "integer"
+                },
+                "request_version": {
+                    "type": "string"
+                },
+                "total_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.InfraMachineConfigResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "acceptance_status": {
+                    "type": "string"
+                },
+                "cordoned": {
+                    "type": "boolean"
+                },
+                "extra_kernel_args": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "namespace": {
+                    "type": "string"
+                },
+                "power_state": {
+                    "type": "string"
+                },
+                "requested_reboot_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.InstallationMediaResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "architecture": {
+                    "type": "string"
+                },
+                "content_type": {
+                    "type": "string"
+                },
+                "dest_file_prefix": {
+                    "type": "string"
+                },
+                "extension": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "min_talos_version": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "namespace": {
+                    "type": "string"
+                },
+                "no_secure_boot": {
+                    "type": "boolean"
+                },
+                "overlay": {
+                    "type": "string"
+                },
+                "profile": {
+                    "type": "string"
+                },
+                "src_file_prefix": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.KernelArgsResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "args": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "namespace": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.KubeconfigResponse": {
             "type": "object",
             "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "data": {
+                    "description": "Base64 encoded kubeconfig",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "id": {
                     "type": "string"
                 },
-                "num_diagnostics": {
-                    "type": "integer"
+                "mode": {
+                    "type": "string"
+                },
+                "namespace": {
+                    "type": "string"
+                },
+                "user": {
+                    "type": "string"
                 }
             }
         },
-        "handlers.ClusterDiagnosticsResponse": {
+        "handlers.KubernetesStatusResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -3195,12 +9737,18 @@ const docTemplate = `{
                 "nodes": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/handlers.ClusterDiagnosticsNode"
+                        "$ref": "#/definitions/handlers.NodeStatus"
+                    }
+                },
+                "static_pods": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.NodeStaticPods"
                     }
                 }
             }
         },
-        "handlers.ClusterEndpointResponse": {
+        "handlers.KubernetesUpgradeStatusResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -3209,21 +9757,39 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
+                "current_upgrade_version": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "string"
                 },
-                "management_addresses": {
+                "last_upgrade_version": {
+                    "type": "string"
+                },
+                "namespace": {
+                    "type": "string"
+                },
+                "phase": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "step": {
+                    "type": "string"
+                },
+                "upgrade_versions": {
                     "type": "array",
                     "items": {
                         "type": "string"
                     }
-                },
-                "namespace": {
-                    "type": "string"
                 }
             }
         },
-        "handlers.ClusterKubernetesNodeResponse": {
+        "handlers.KubernetesVersionResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -3235,21 +9801,15 @@ const docTemplate = `{
                 "id": {
                     "type": "string"
                 },
-                "labels": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "string"
-                    }
-                },
-                "name": {
+                "namespace": {
                     "type": "string"
                 },
-                "namespace": {
+                "version": {
                     "type": "string"
                 }
             }
         },
-        "handlers.ClusterMachineConfigResponse": {
+        "handlers.LoadBalancerConfigResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -3258,17 +9818,14 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
-                "cluster_machine_version": {
-                    "type": "string"
-                },
-                "config_size": {
-                    "type": "integer"
-                },
-                "generation_error": {
+                "bind_port": {
                     "type": "string"
                 },
-                "grub_use_uki_cmdline": {
-                    "type": "boolean"
+                "endpoints": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
                 "id": {
                     "type": "string"
@@ -3276,12 +9833,12 @@ const docTemplate = `{
                 "namespace": {
                     "type": "string"
                 },
-                "without_comments": {
-                    "type": "boolean"
+                "siderolink_endpoint": {
+                    "type": "string"
                 }
             }
         },
-        "handlers.ClusterMachineConfigStatusResponse": {
+        "handlers.LoadBalancerStatusResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -3290,33 +9847,57 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
-                "cluster_machine_config_sha256": {
+                "healthy": {
+                    "type": "boolean"
+                },
+                "id": {
                     "type": "string"
                 },
-                "cluster_machine_config_version": {
+                "namespace": {
                     "type": "string"
                 },
-                "cluster_machine_version": {
+                "stopped": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "handlers.MachineAllocationRequest": {
+            "type": "object",
+            "required": [
+                "count"
+            ],
+            "properties": {
+                "arch": {
+                    "description": "e.g. amd64",
                     "type": "string"
                 },
-                "id": {
+                "count": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "machine_class": {
+                    "description": "Choose machines matching the selectors of this machine class",
                     "type": "string"
                 },
-                "last_config_error": {
+                "platform": {
+                    "description": "e.g. metal",
                     "type": "string"
                 },
-                "namespace": {
+                "ranking": {
+                    "description": "lru (default) or spread",
                     "type": "string"
                 },
-                "schematic_id": {
+                "selector": {
+                    "description": "Omni label selector, e.g. \"rack=r1, !gpu\"",
                     "type": "string"
                 },
-                "talos_version": {
+                "spread_label": {
+                    "description": "Label the spread ranking balances, default topology.kubernetes.io/zone",
                     "type": "string"
                 }
             }
         },
-        "handlers.ClusterMachineResponse": {
+        "handlers.MachineAllocationResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -3325,71 +9906,79 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
-                "id": {
-                    "type": "string"
+                "chosen": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/allocation.Choice"
+                    }
                 },
-                "kubernetes_version": {
+                "cluster": {
                     "type": "string"
                 },
-                "labels": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "string"
-                    }
+                "dry_run": {
+                    "type": "boolean"
                 },
-                "machine_id": {
+                "machine_set": {
                     "type": "string"
                 },
-                "namespace": {
+                "ranking": {
                     "type": "string"
+                },
+                "rejected": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/allocation.Rejection"
+                    }
                 }
             }
         },
-        "handlers.ClusterMachineStatusResponse": {
+        "handlers.MachineClassCreateRequest": {
             "type": "object",
+            "required": [
+                "id"
+            ],
             "properties": {
-                "_links": {
-                    "type": "object",
-                    "additionalProperties": {
+                "id": {
+                    "type": "string"
+                },
+                "match_labels": {
+                    "description": "Omni label selectors, e.g. \"rack=a, !gpu\"; a machine matching any of them belongs to the class",
+                    "type": "array",
+                    "items": {
                         "type": "string"
                     }
                 },
-                "apid_available": {
-                    "type": "boolean"
-                },
-                "config_apply_status": {
-                    "type": "string"
-                },
-                "config_up_to_date": {
+                "provision": {
+                    "$ref": "#/definitions/handlers.MachineClassProvision"
+                }
+            }
+        },
+        "handlers.MachineClassMatch": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "description": "Connected and not part of a cluster or machine set",
                     "type": "boolean"
                 },
-                "id": {
+                "cluster": {
                     "type": "string"
                 },
-                "is_removed": {
+                "connected": {
                     "type": "boolean"
                 },
-                "last_config_error": {
-                    "type": "string"
-                },
-                "management_address": {
+                "hostname": {
                     "type": "string"
                 },
-                "namespace": {
+                "id": {
                     "type": "string"
                 },
-                "provision_status": {
-                    "$ref": "#/definitions/handlers.ProvisionStatus"
-                },
-                "ready": {
-                    "type": "boolean"
-                },
-                "stage": {
+                "machine_set": {
+                    "description": "Machine set the machine is allocated to",
                     "type": "string"
                 }
             }
         },
-        "handlers.ClusterMachineTalosVersionResponse": {
+        "handlers.MachineClassMatchesResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -3398,107 +9987,97 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
-                "id": {
-                    "type": "string"
+                "allocated": {
+                    "type": "integer"
                 },
-                "namespace": {
-                    "type": "string"
+                "available": {
+                    "type": "integer"
                 },
-                "schematic_id": {
+                "id": {
                     "type": "string"
                 },
-                "talos_version": {
-                    "type": "string"
-                }
-            }
-        },
-        "handlers.ClusterMetricsResponse": {
-            "type": "object",
-            "properties": {
-                "features": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "integer",
-                        "format": "int32"
+                "machines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.MachineClassMatch"
+                    }
+                },
+                "match_labels": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
                     }
+                },
+                "matching": {
+                    "type": "integer"
                 }
             }
         },
-        "handlers.ClusterResponse": {
+        "handlers.MachineClassProvision": {
             "type": "object",
+            "required": [
+                "provider_id"
+            ],
             "properties": {
-                "_links": {
-                    "type": "object",
-                    "additionalProperties": {
+                "kernel_args": {
+                    "type": "array",
+                    "items": {
                         "type": "string"
                     }
                 },
-                "features": {
-                    "type": "object",
-                    "properties": {
-                        "disk_encryption": {
-                            "type": "boolean"
-                        },
-                        "workload_proxy": {
-                            "type": "boolean"
-                        }
-                    }
-                },
-                "id": {
-                    "type": "string"
-                },
-                "kubernetes_version": {
-                    "type": "string"
-                },
-                "namespace": {
+                "provider_data": {
+                    "description": "Provider specific YAML, e.g. \"size: t3.large\"",
                     "type": "string"
                 },
-                "talos_version": {
+                "provider_id": {
                     "type": "string"
                 }
             }
         },
-        "handlers.ClusterStatusResponse": {
+        "handlers.MachineClassResponse": {
             "type": "object",
             "properties": {
-                "available": {
-                    "type": "boolean"
-                },
-                "controlplane_ready": {
-                    "type": "boolean"
-                },
-                "has_connected_control_planes": {
-                    "type": "boolean"
-                },
-                "kubernetes_api_ready": {
-                    "type": "boolean"
-                },
-                "machines": {
+                "_links": {
                     "type": "object",
-                    "properties": {
-                        "connected": {
-                            "type": "integer"
-                        },
-                        "healthy": {
-                            "type": "integer"
-                        },
-                        "requested": {
-                            "type": "integer"
-                        },
-                        "total": {
-                            "type": "integer"
-                        }
+                    "additionalProperties": {
+                        "type": "string"
                     }
                 },
-                "phase": {
+                "auto_provision": {
+                    "type": "boolean"
+                },
+                "id": {
                     "type": "string"
                 },
-                "ready": {
-                    "type": "boolean"
+                "match_labels": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "namespace": {
+                    "type": "string"
+                },
+                "provision": {
+                    "$ref": "#/definitions/handlers.MachineClassProvision"
                 }
             }
         },
-        "handlers.ClusterWorkloadProxyStatusResponse": {
+        "handlers.MachineClassUpdateRequest": {
+            "type": "object",
+            "properties": {
+                "match_labels": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "provision": {
+                    "$ref": "#/definitions/handlers.MachineClassProvision"
+                }
+            }
+        },
+        "handlers.MachineConfigDiffResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -3507,35 +10086,39 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
+                "diff": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "string"
                 },
                 "namespace": {
                     "type": "string"
-                },
-                "num_exposed_services": {
-                    "type": "integer"
                 }
             }
         },
-        "handlers.Condition": {
+        "handlers.MachineConfigPreviewRequest": {
             "type": "object",
             "properties": {
-                "message": {
+                "cluster": {
+                    "description": "Expected cluster of the machine, checked if set",
                     "type": "string"
                 },
-                "reason": {
+                "id": {
+                    "description": "Config patch ID, which orders the patch within its level",
                     "type": "string"
                 },
-                "status": {
+                "level": {
+                    "description": "cluster, machineset or machine (default)",
                     "type": "string"
                 },
-                "type": {
+                "patch": {
+                    "description": "Proposed config patch; the current config is returned if empty",
                     "type": "string"
                 }
             }
         },
-        "handlers.ConfigPatchResponse": {
+        "handlers.MachineConfigPreviewResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -3544,18 +10127,33 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
-                "data": {
+                "applied": {
+                    "description": "Config patches applied to the base config, in order",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "cluster": {
+                    "type": "string"
+                },
+                "config": {
+                    "description": "Merged config with secrets redacted",
+                    "type": "string"
+                },
+                "diff": {
+                    "description": "Unified diff against the config without the proposed patch",
                     "type": "string"
                 },
                 "id": {
                     "type": "string"
                 },
-                "namespace": {
+                "machine_set": {
                     "type": "string"
                 }
             }
         },
-        "handlers.ControlPlaneStatusResponse": {
+        "handlers.MachineExtensionsResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -3564,10 +10162,10 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
-                "conditions": {
+                "extensions": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/handlers.Condition"
+                        "type": "string"
                     }
                 },
                 "id": {
@@ -3578,33 +10176,62 @@ const docTemplate = `{
                 }
             }
         },
-        "handlers.EtcdBackupResponse": {
+        "handlers.MachineLabelsPatchRequest": {
             "type": "object",
+            "required": [
+                "labels"
+            ],
             "properties": {
-                "_links": {
+                "labels": {
+                    "description": "Merged into the user labels; null deletes a label",
                     "type": "object",
                     "additionalProperties": {
                         "type": "string"
                     }
+                }
+            }
+        },
+        "handlers.MachineLabelsPatchResponse": {
+            "type": "object",
+            "properties": {
+                "changed": {
+                    "type": "integer"
                 },
-                "created_at": {
-                    "type": "string"
+                "dry_run": {
+                    "type": "boolean"
                 },
-                "id": {
-                    "type": "string"
+                "failed": {
+                    "type": "integer"
                 },
-                "namespace": {
-                    "type": "string"
+                "machines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.MachineLabelsResult"
+                    }
                 },
-                "size": {
+                "matched": {
                     "type": "integer"
                 },
-                "snapshot": {
+                "selector": {
                     "type": "string"
                 }
             }
         },
-        "handlers.EtcdBackupStatusResponse": {
+        "handlers.MachineLabelsReplaceRequest": {
+            "type": "object",
+            "required": [
+                "labels"
+            ],
+            "properties": {
+                "labels": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "handlers.MachineLabelsResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -3613,41 +10240,74 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
-                "error": {
-                    "type": "string"
-                },
                 "id": {
                     "type": "string"
                 },
-                "namespace": {
-                    "type": "string"
+                "labels": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
                 },
-                "status": {
+                "namespace": {
                     "type": "string"
                 }
             }
         },
-        "handlers.EtcdManualBackupResponse": {
+        "handlers.MachineLabelsResult": {
             "type": "object",
             "properties": {
-                "_links": {
+                "action": {
+                    "description": "create, update or none",
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "labels": {
+                    "description": "User labels after the change",
                     "type": "object",
                     "additionalProperties": {
                         "type": "string"
                     }
+                }
+            }
+        },
+        "handlers.MachineMaintenanceRequest": {
+            "type": "object",
+            "required": [
+                "enabled"
+            ],
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
                 },
-                "backup_at": {
+                "machine_set": {
+                    "description": "Machine set to add the machine to when leaving maintenance mode, by default the one it was taken out of",
                     "type": "string"
-                },
-                "id": {
+                }
+            }
+        },
+        "handlers.MachineMaintenanceUpgradeRequest": {
+            "type": "object",
+            "required": [
+                "version"
+            ],
+            "properties": {
+                "schematic": {
+                    "description": "Image factory schematic ID, by default the machine's current schematic",
                     "type": "string"
                 },
-                "namespace": {
+                "version": {
+                    "description": "Talos version, e.g. 1.9.5",
                     "type": "string"
                 }
             }
         },
-        "handlers.ExposedServiceResponse": {
+        "handlers.MachineRequestSetResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -3656,33 +10316,42 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
-                "error": {
-                    "type": "string"
-                },
-                "has_explicit_alias": {
-                    "type": "boolean"
+                "extensions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "icon_base64": {
+                "grpc_tunnel": {
                     "type": "string"
                 },
                 "id": {
                     "type": "string"
                 },
-                "label": {
-                    "type": "string"
+                "kernel_args": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "machine_count": {
+                    "type": "integer"
                 },
                 "namespace": {
                     "type": "string"
                 },
-                "port": {
-                    "type": "integer"
+                "provider_data": {
+                    "type": "string"
                 },
-                "url": {
+                "provider_id": {
+                    "type": "string"
+                },
+                "talos_version": {
                     "type": "string"
                 }
             }
         },
-        "handlers.ExtensionsConfigurationResponse": {
+        "handlers.MachineResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -3691,44 +10360,99 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
-                "extensions": {
-                    "type": "array",
-                    "items": {
+                "arch": {
+                    "type": "string"
+                },
+                "connected": {
+                    "type": "boolean"
+                },
+                "hostname": {
+                    "description": "Status fields (from MachineStatus resource)",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "labels": {
+                    "type": "object",
+                    "additionalProperties": {
                         "type": "string"
                     }
                 },
-                "id": {
+                "last_error": {
+                    "type": "string"
+                },
+                "maintenance": {
+                    "type": "boolean"
+                },
+                "management_address": {
                     "type": "string"
                 },
                 "namespace": {
                     "type": "string"
+                },
+                "platform": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "talos_version": {
+                    "type": "string"
+                },
+                "use_grpc_tunnel": {
+                    "type": "boolean"
                 }
             }
         },
-        "handlers.HealthResponse": {
+        "handlers.MachineSetCreateRequest": {
             "type": "object",
+            "required": [
+                "cluster",
+                "id",
+                "machine_class"
+            ],
             "properties": {
-                "_links": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "string"
-                    }
+                "bootstrap_spec": {
+                    "type": "string"
                 },
-                "omni": {
-                    "$ref": "#/definitions/handlers.OmniHealthStatus"
+                "cluster": {
+                    "type": "string"
                 },
-                "status": {
+                "delete_strategy": {
                     "type": "string"
                 },
-                "timestamp": {
+                "delete_strategy_config": {
+                    "description": "Requires the Rolling delete strategy",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/handlers.MachineSetStrategyConfig"
+                        }
+                    ]
+                },
+                "id": {
                     "type": "string"
                 },
-                "version": {
+                "machine_class": {
+                    "type": "string"
+                },
+                "machine_count": {
+                    "type": "integer"
+                },
+                "update_strategy": {
                     "type": "string"
+                },
+                "update_strategy_config": {
+                    "description": "Requires the Rolling update strategy",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/handlers.MachineSetStrategyConfig"
+                        }
+                    ]
                 }
             }
         },
-        "handlers.ImagePullRequestResponse": {
+        "handlers.MachineSetDestroyStatusResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -3743,50 +10467,29 @@ const docTemplate = `{
                 "namespace": {
                     "type": "string"
                 },
-                "node_image_list": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/handlers.NodeImageList"
-                    }
+                "phase": {
+                    "type": "string"
                 }
             }
         },
-        "handlers.ImagePullStatusResponse": {
+        "handlers.MachineSetNodeResponse": {
             "type": "object",
             "properties": {
                 "_links": {
                     "type": "object",
                     "additionalProperties": {
                         "type": "string"
-                    }
-                },
-                "id": {
-                    "type": "string"
-                },
-                "last_processed_error": {
-                    "type": "string"
-                },
-                "last_processed_image": {
-                    "type": "string"
+                    }
                 },
-                "last_processed_node": {
+                "id": {
                     "type": "string"
                 },
                 "namespace": {
                     "type": "string"
-                },
-                "processed_count": {
-                    "type": "integer"
-                },
-                "request_version": {
-                    "type": "string"
-                },
-                "total_count": {
-                    "type": "integer"
                 }
             }
         },
-        "handlers.InfraMachineConfigResponse": {
+        "handlers.MachineSetResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -3795,30 +10498,30 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
-                "acceptance_status": {
+                "delete_strategy": {
                     "type": "string"
                 },
-                "cordoned": {
-                    "type": "boolean"
+                "delete_strategy_config": {
+                    "$ref": "#/definitions/handlers.MachineSetStrategyConfig"
                 },
-                "extra_kernel_args": {
+                "id": {
                     "type": "string"
                 },
-                "id": {
+                "machine_class": {
                     "type": "string"
                 },
                 "namespace": {
                     "type": "string"
                 },
-                "power_state": {
+                "update_strategy": {
                     "type": "string"
                 },
-                "requested_reboot_id": {
-                    "type": "string"
+                "update_strategy_config": {
+                    "$ref": "#/definitions/handlers.MachineSetStrategyConfig"
                 }
             }
         },
-        "handlers.InstallationMediaResponse": {
+        "handlers.MachineSetScale": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -3827,45 +10530,86 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
-                "architecture": {
+                "id": {
                     "type": "string"
                 },
-                "content_type": {
+                "machine_class": {
                     "type": "string"
                 },
-                "dest_file_prefix": {
+                "resource_version": {
+                    "description": "Pass back in a PUT to only scale if the machine set did not change",
                     "type": "string"
                 },
-                "extension": {
-                    "type": "string"
+                "scalable": {
+                    "description": "Only machine sets with a fixed number of machines from a machine class can be scaled",
+                    "type": "boolean"
                 },
-                "id": {
-                    "type": "string"
+                "spec": {
+                    "$ref": "#/definitions/handlers.MachineSetScaleSpec"
                 },
-                "min_talos_version": {
+                "status": {
+                    "$ref": "#/definitions/handlers.MachineSetScaleStatus"
+                }
+            }
+        },
+        "handlers.MachineSetScaleRequest": {
+            "type": "object",
+            "properties": {
+                "resource_version": {
                     "type": "string"
                 },
-                "name": {
-                    "type": "string"
+                "spec": {
+                    "$ref": "#/definitions/handlers.MachineSetScaleRequestSpec"
+                }
+            }
+        },
+        "handlers.MachineSetScaleRequestSpec": {
+            "type": "object",
+            "required": [
+                "replicas"
+            ],
+            "properties": {
+                "replicas": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.MachineSetScaleSpec": {
+            "type": "object",
+            "properties": {
+                "replicas": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.MachineSetScaleStatus": {
+            "type": "object",
+            "properties": {
+                "connected_replicas": {
+                    "description": "Machines connected to Omni",
+                    "type": "integer"
                 },
-                "namespace": {
+                "phase": {
                     "type": "string"
                 },
-                "no_secure_boot": {
+                "ready": {
                     "type": "boolean"
                 },
-                "overlay": {
-                    "type": "string"
+                "ready_replicas": {
+                    "description": "Healthy machines",
+                    "type": "integer"
                 },
-                "profile": {
-                    "type": "string"
+                "replicas": {
+                    "description": "Machines allocated to the machine set",
+                    "type": "integer"
                 },
-                "src_file_prefix": {
-                    "type": "string"
+                "requested_replicas": {
+                    "description": "Machines Omni is working towards",
+                    "type": "integer"
                 }
             }
         },
-        "handlers.KernelArgsResponse": {
+        "handlers.MachineSetStatusResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -3874,42 +10618,79 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
-                "args": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
+                "config_hash": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
                 },
                 "id": {
                     "type": "string"
                 },
+                "locked_updates": {
+                    "type": "integer"
+                },
+                "machines": {
+                    "type": "object",
+                    "properties": {
+                        "connected": {
+                            "type": "integer"
+                        },
+                        "healthy": {
+                            "type": "integer"
+                        },
+                        "requested": {
+                            "type": "integer"
+                        },
+                        "total": {
+                            "type": "integer"
+                        }
+                    }
+                },
                 "namespace": {
                     "type": "string"
+                },
+                "phase": {
+                    "type": "string"
+                },
+                "ready": {
+                    "type": "boolean"
                 }
             }
         },
-        "handlers.KubeconfigResponse": {
+        "handlers.MachineSetStrategyConfig": {
             "type": "object",
             "properties": {
-                "_links": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "string"
-                    }
-                },
-                "data": {
-                    "description": "Base64 encoded kubeconfig",
+                "max_parallelism": {
+                    "description": "Machines processed at once; 0 means 1",
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.MachineSetUpdateRequest": {
+            "type": "object",
+            "properties": {
+                "delete_strategy": {
                     "type": "string"
                 },
-                "id": {
+                "delete_strategy_config": {
+                    "$ref": "#/definitions/handlers.MachineSetStrategyConfig"
+                },
+                "machine_class": {
                     "type": "string"
                 },
-                "namespace": {
+                "machine_count": {
+                    "type": "integer"
+                },
+                "update_strategy": {
                     "type": "string"
+                },
+                "update_strategy_config": {
+                    "$ref": "#/definitions/handlers.MachineSetStrategyConfig"
                 }
             }
         },
-        "handlers.KubernetesStatusResponse": {
+        "handlers.MachineStatusMetricsResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -3918,27 +10699,69 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
+                "allocated_machines_count": {
+                    "type": "integer"
+                },
+                "connected_machines_count": {
+                    "type": "integer"
+                },
                 "id": {
                     "type": "string"
                 },
                 "namespace": {
                     "type": "string"
                 },
-                "nodes": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/handlers.NodeStatus"
+                "pending_machines_count": {
+                    "type": "integer"
+                },
+                "platforms": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer",
+                        "format": "int32"
                     }
                 },
-                "static_pods": {
+                "registered_machines_count": {
+                    "type": "integer"
+                },
+                "secure_boot_status": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer",
+                        "format": "int32"
+                    }
+                },
+                "uki_status": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer",
+                        "format": "int32"
+                    }
+                }
+            }
+        },
+        "handlers.MachineUpdateRequest": {
+            "type": "object",
+            "properties": {
+                "extensions": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/handlers.NodeStaticPods"
+                        "type": "string"
+                    }
+                },
+                "labels": {
+                    "description": "Merged into the user labels; null deletes a label",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
                     }
+                },
+                "maintenance": {
+                    "type": "boolean"
                 }
             }
         },
-        "handlers.KubernetesUpgradeStatusResponse": {
+        "handlers.MachineUpgradeStatusResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -3947,7 +10770,10 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
-                "current_upgrade_version": {
+                "current_schematic_id": {
+                    "type": "string"
+                },
+                "current_talos_version": {
                     "type": "string"
                 },
                 "error": {
@@ -3956,8 +10782,8 @@ const docTemplate = `{
                 "id": {
                     "type": "string"
                 },
-                "last_upgrade_version": {
-                    "type": "string"
+                "is_maintenance": {
+                    "type": "boolean"
                 },
                 "namespace": {
                     "type": "string"
@@ -3965,21 +10791,18 @@ const docTemplate = `{
                 "phase": {
                     "type": "string"
                 },
-                "status": {
+                "schematic_id": {
                     "type": "string"
                 },
-                "step": {
+                "status": {
                     "type": "string"
                 },
-                "upgrade_versions": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
+                "talos_version": {
+                    "type": "string"
                 }
             }
         },
-        "handlers.KubernetesVersionResponse": {
+        "handlers.MetricsResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -3988,70 +10811,81 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
-                "id": {
-                    "type": "string"
+                "average_response_time_seconds": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number",
+                        "format": "float64"
+                    }
                 },
-                "namespace": {
-                    "type": "string"
+                "circuit_breaker": {
+                    "$ref": "#/definitions/handlers.CircuitBreakerStatus"
+                },
+                "error_counts": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer",
+                        "format": "int64"
+                    }
+                },
+                "request_counts": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer",
+                        "format": "int64"
+                    }
+                },
+                "server_uptime_seconds": {
+                    "type": "number"
+                }
+            }
+        },
+        "handlers.NodeImageList": {
+            "type": "object",
+            "properties": {
+                "images": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "version": {
+                "node": {
                     "type": "string"
                 }
             }
         },
-        "handlers.LoadBalancerConfigResponse": {
+        "handlers.NodeStaticPods": {
             "type": "object",
             "properties": {
-                "_links": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "string"
-                    }
-                },
-                "bind_port": {
+                "nodename": {
                     "type": "string"
                 },
-                "endpoints": {
+                "pods": {
                     "type": "array",
                     "items": {
-                        "type": "string"
+                        "$ref": "#/definitions/handlers.PodStatus"
                     }
-                },
-                "id": {
-                    "type": "string"
-                },
-                "namespace": {
-                    "type": "string"
-                },
-                "siderolink_endpoint": {
-                    "type": "string"
                 }
             }
         },
-        "handlers.LoadBalancerStatusResponse": {
+        "handlers.NodeStatus": {
             "type": "object",
             "properties": {
-                "_links": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "string"
-                    }
-                },
-                "healthy": {
+                "api_server": {
                     "type": "boolean"
                 },
-                "id": {
-                    "type": "string"
+                "kubelet": {
+                    "type": "boolean"
                 },
-                "namespace": {
+                "nodename": {
                     "type": "string"
                 },
-                "stopped": {
+                "ready": {
                     "type": "boolean"
                 }
             }
         },
-        "handlers.MachineClassResponse": {
+        "handlers.OIDCProviderResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -4060,44 +10894,41 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
-                "auto_provision": {
+                "client_id": {
+                    "type": "string"
+                },
+                "enabled": {
                     "type": "boolean"
                 },
                 "id": {
                     "type": "string"
                 },
-                "match_labels": {
+                "issuer_url": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "scopes": {
                     "type": "array",
                     "items": {
                         "type": "string"
                     }
-                },
-                "namespace": {
-                    "type": "string"
                 }
             }
         },
-        "handlers.MachineConfigDiffResponse": {
+        "handlers.OmniHealthStatus": {
             "type": "object",
             "properties": {
-                "_links": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "string"
-                    }
-                },
-                "diff": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "string"
+                "connected": {
+                    "type": "boolean"
                 },
-                "namespace": {
+                "error": {
                     "type": "string"
                 }
             }
         },
-        "handlers.MachineExtensionsResponse": {
+        "handlers.OmniconfigResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -4106,21 +10937,13 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
-                "extensions": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
-                },
-                "id": {
-                    "type": "string"
-                },
-                "namespace": {
+                "data": {
+                    "description": "Base64 encoded omniconfig",
                     "type": "string"
                 }
             }
         },
-        "handlers.MachineLabelsResponse": {
+        "handlers.OngoingTaskResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -4132,18 +10955,21 @@ const docTemplate = `{
                 "id": {
                     "type": "string"
                 },
-                "labels": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "string"
-                    }
-                },
                 "namespace": {
                     "type": "string"
+                },
+                "resource_id": {
+                    "type": "string"
+                },
+                "task_type": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
                 }
             }
         },
-        "handlers.MachineRequestSetResponse": {
+        "handlers.OperationResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -4152,96 +10978,73 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
-                "extensions": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
+                "cancelable": {
+                    "type": "boolean"
                 },
-                "grpc_tunnel": {
+                "completed_at": {
                     "type": "string"
                 },
-                "id": {
+                "created_at": {
                     "type": "string"
                 },
-                "kernel_args": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
-                },
-                "machine_count": {
-                    "type": "integer"
+                "done": {
+                    "type": "boolean"
                 },
-                "namespace": {
+                "error": {
                     "type": "string"
                 },
-                "provider_data": {
+                "id": {
                     "type": "string"
                 },
-                "provider_id": {
+                "kind": {
                     "type": "string"
                 },
-                "talos_version": {
+                "message": {
                     "type": "string"
-                }
-            }
-        },
-        "handlers.MachineResponse": {
-            "type": "object",
-            "properties": {
-                "_links": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "string"
-                    }
                 },
-                "arch": {
+                "phase": {
                     "type": "string"
                 },
-                "connected": {
-                    "type": "boolean"
+                "result": {
+                    "description": "Kind specific details, e.g. the per-item report of a bulk operation"
                 },
-                "hostname": {
-                    "description": "Status fields (from MachineStatus resource)",
+                "status": {
                     "type": "string"
                 },
-                "id": {
+                "target": {
                     "type": "string"
                 },
-                "labels": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "string"
-                    }
-                },
-                "last_error": {
+                "updated_at": {
                     "type": "string"
-                },
-                "maintenance": {
-                    "type": "boolean"
-                },
-                "management_address": {
+                }
+            }
+        },
+        "handlers.PodStatus": {
+            "type": "object",
+            "properties": {
+                "app": {
                     "type": "string"
                 },
-                "namespace": {
-                    "type": "string"
+                "ready": {
+                    "type": "boolean"
                 },
-                "platform": {
+                "version": {
                     "type": "string"
-                },
-                "role": {
+                }
+            }
+        },
+        "handlers.ProvisionStatus": {
+            "type": "object",
+            "properties": {
+                "provider_id": {
                     "type": "string"
                 },
-                "talos_version": {
+                "request_id": {
                     "type": "string"
-                },
-                "use_grpc_tunnel": {
-                    "type": "boolean"
                 }
             }
         },
-        "handlers.MachineSetDestroyStatusResponse": {
+        "handlers.ReadinessResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -4250,18 +11053,21 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
-                "id": {
-                    "type": "string"
+                "circuit_breaker": {
+                    "$ref": "#/definitions/handlers.CircuitBreakerStatus"
                 },
-                "namespace": {
+                "error": {
                     "type": "string"
                 },
-                "phase": {
+                "ready": {
+                    "type": "boolean"
+                },
+                "timestamp": {
                     "type": "string"
                 }
             }
         },
-        "handlers.MachineSetNodeResponse": {
+        "handlers.SchematicConfigurationResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -4273,12 +11079,24 @@ const docTemplate = `{
                 "id": {
                     "type": "string"
                 },
+                "kernel_args": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "namespace": {
                     "type": "string"
+                },
+                "schematic_id": {
+                    "type": "string"
+                },
+                "talos_version": {
+                    "type": "string"
                 }
             }
         },
-        "handlers.MachineSetResponse": {
+        "handlers.SchematicResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -4287,24 +11105,15 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
-                "delete_strategy": {
-                    "type": "string"
-                },
                 "id": {
                     "type": "string"
                 },
-                "machine_class": {
-                    "type": "string"
-                },
                 "namespace": {
                     "type": "string"
-                },
-                "update_strategy": {
-                    "type": "string"
                 }
             }
         },
-        "handlers.MachineSetStatusResponse": {
+        "handlers.ServiceAccountResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -4313,47 +11122,27 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
-                "config_hash": {
+                "created_at": {
                     "type": "string"
                 },
-                "error": {
+                "description": {
                     "type": "string"
                 },
                 "id": {
                     "type": "string"
                 },
-                "locked_updates": {
-                    "type": "integer"
-                },
-                "machines": {
-                    "type": "object",
-                    "properties": {
-                        "connected": {
-                            "type": "integer"
-                        },
-                        "healthy": {
-                            "type": "integer"
-                        },
-                        "requested": {
-                            "type": "integer"
-                        },
-                        "total": {
-                            "type": "integer"
-                        }
-                    }
-                },
-                "namespace": {
-                    "type": "string"
-                },
-                "phase": {
+                "name": {
                     "type": "string"
                 },
-                "ready": {
-                    "type": "boolean"
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
-        "handlers.MachineStatusMetricsResponse": {
+        "handlers.SupportBundleResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -4362,48 +11151,42 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
-                "allocated_machines_count": {
-                    "type": "integer"
+                "cluster": {
+                    "type": "string"
                 },
-                "connected_machines_count": {
-                    "type": "integer"
+                "completed_at": {
+                    "type": "string"
                 },
-                "id": {
+                "created_at": {
                     "type": "string"
                 },
-                "namespace": {
+                "done": {
+                    "type": "boolean"
+                },
+                "error": {
                     "type": "string"
                 },
-                "pending_machines_count": {
-                    "type": "integer"
+                "expires_at": {
+                    "type": "string"
                 },
-                "platforms": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "integer",
-                        "format": "int32"
+                "id": {
+                    "type": "string"
+                },
+                "progress": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/client.SupportBundleProgress"
                     }
                 },
-                "registered_machines_count": {
+                "size": {
                     "type": "integer"
                 },
-                "secure_boot_status": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "integer",
-                        "format": "int32"
-                    }
-                },
-                "uki_status": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "integer",
-                        "format": "int32"
-                    }
+                "status": {
+                    "type": "string"
                 }
             }
         },
-        "handlers.MachineUpgradeStatusResponse": {
+        "handlers.TalosUpgradeStatusResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -4412,10 +11195,7 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
-                "current_schematic_id": {
-                    "type": "string"
-                },
-                "current_talos_version": {
+                "current_upgrade_version": {
                     "type": "string"
                 },
                 "error": {
@@ -4424,8 +11204,8 @@ const docTemplate = `{
                 "id": {
                     "type": "string"
                 },
-                "is_maintenance": {
-                    "type": "boolean"
+                "last_upgrade_version": {
+                    "type": "string"
                 },
                 "namespace": {
                     "type": "string"
@@ -4433,18 +11213,21 @@ const docTemplate = `{
                 "phase": {
                     "type": "string"
                 },
-                "schematic_id": {
-                    "type": "string"
-                },
                 "status": {
                     "type": "string"
                 },
-                "talos_version": {
+                "step": {
                     "type": "string"
+                },
+                "upgrade_versions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
-        "handlers.MetricsResponse": {
+        "handlers.TalosconfigResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -4453,223 +11236,224 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
-                "average_response_time_seconds": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "number",
-                        "format": "float64"
-                    }
+                "break_glass": {
+                    "type": "boolean"
                 },
-                "error_counts": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "integer",
-                        "format": "int64"
-                    }
+                "data": {
+                    "description": "Base64 encoded talosconfig",
+                    "type": "string"
                 },
-                "request_counts": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "integer",
-                        "format": "int64"
-                    }
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.TemplateChangeResponse": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
                 },
-                "server_uptime_seconds": {
-                    "type": "number"
+                "diff": {
+                    "description": "Unified diff against the current state",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "phase": {
+                    "description": "Prune phase of destructions, starting at 1",
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
                 }
             }
         },
-        "handlers.NodeImageList": {
+        "handlers.TemplatePlanResponse": {
             "type": "object",
             "properties": {
-                "images": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "changes": {
                     "type": "array",
                     "items": {
-                        "type": "string"
+                        "$ref": "#/definitions/handlers.TemplateChangeResponse"
                     }
                 },
-                "node": {
+                "cluster": {
+                    "type": "string"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "operation_id": {
+                    "description": "Operation tracking the pruning",
                     "type": "string"
+                },
+                "summary": {
+                    "description": "Number of changes per action",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
                 }
             }
         },
-        "handlers.NodeStaticPods": {
+        "handlers.TemplateValidationResponse": {
             "type": "object",
             "properties": {
-                "nodename": {
+                "cluster": {
                     "type": "string"
                 },
-                "pods": {
+                "errors": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/handlers.PodStatus"
+                        "type": "string"
                     }
+                },
+                "valid": {
+                    "type": "boolean"
                 }
             }
         },
-        "handlers.NodeStatus": {
+        "handlers.UpdateOIDCProviderRequest": {
             "type": "object",
             "properties": {
-                "api_server": {
-                    "type": "boolean"
+                "client_id": {
+                    "type": "string"
                 },
-                "kubelet": {
+                "enabled": {
                     "type": "boolean"
                 },
-                "nodename": {
+                "issuer_url": {
                     "type": "string"
                 },
-                "ready": {
-                    "type": "boolean"
+                "name": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
-        "handlers.OmniHealthStatus": {
+        "handlers.UpgradeBlocker": {
             "type": "object",
             "properties": {
-                "connected": {
-                    "type": "boolean"
+                "kind": {
+                    "type": "string"
                 },
-                "error": {
+                "message": {
                     "type": "string"
+                },
+                "nodes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "step": {
+                    "description": "Index of the step the blocker applies to; unset if it blocks every step",
+                    "type": "integer"
                 }
             }
         },
-        "handlers.OngoingTaskResponse": {
+        "handlers.UpgradePlanStep": {
             "type": "object",
             "properties": {
-                "_links": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "string"
-                    }
-                },
-                "id": {
+                "action": {
+                    "description": "Endpoint that runs the step",
                     "type": "string"
                 },
-                "namespace": {
+                "component": {
                     "type": "string"
                 },
-                "resource_id": {
+                "from": {
                     "type": "string"
                 },
-                "task_type": {
+                "kubernetes_version": {
                     "type": "string"
                 },
-                "title": {
-                    "type": "string"
-                }
-            }
-        },
-        "handlers.PodStatus": {
-            "type": "object",
-            "properties": {
-                "app": {
+                "talos_version": {
+                    "description": "Versions the cluster runs once the step is done",
                     "type": "string"
                 },
-                "ready": {
-                    "type": "boolean"
-                },
-                "version": {
+                "to": {
                     "type": "string"
                 }
             }
         },
-        "handlers.ProvisionStatus": {
+        "handlers.UpgradePlanVersions": {
             "type": "object",
             "properties": {
-                "provider_id": {
+                "kubernetes_version": {
                     "type": "string"
                 },
-                "request_id": {
+                "talos_version": {
                     "type": "string"
                 }
             }
         },
-        "handlers.SchematicConfigurationResponse": {
+        "retention.Decision": {
             "type": "object",
             "properties": {
-                "_links": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "string"
-                    }
+                "created_at": {
+                    "type": "string"
                 },
                 "id": {
                     "type": "string"
                 },
-                "kernel_args": {
+                "keep": {
+                    "type": "boolean"
+                },
+                "rules": {
+                    "description": "Rules keeping the backup",
                     "type": "array",
                     "items": {
                         "type": "string"
                     }
-                },
-                "namespace": {
-                    "type": "string"
-                },
-                "schematic_id": {
-                    "type": "string"
-                },
-                "talos_version": {
-                    "type": "string"
                 }
             }
         },
-        "handlers.SchematicResponse": {
+        "retention.Policy": {
             "type": "object",
             "properties": {
-                "_links": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "string"
-                    }
+                "keep_daily": {
+                    "type": "integer"
                 },
-                "id": {
-                    "type": "string"
+                "keep_last": {
+                    "type": "integer"
                 },
-                "namespace": {
-                    "type": "string"
+                "keep_weekly": {
+                    "type": "integer"
                 }
             }
         },
-        "handlers.TalosUpgradeStatusResponse": {
+        "upgradeplan.Step": {
             "type": "object",
             "properties": {
-                "_links": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "string"
-                    }
-                },
-                "current_upgrade_version": {
-                    "type": "string"
-                },
-                "error": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "string"
-                },
-                "last_upgrade_version": {
+                "component": {
                     "type": "string"
                 },
-                "namespace": {
+                "from": {
                     "type": "string"
                 },
-                "phase": {
+                "kubernetes_version": {
                     "type": "string"
                 },
-                "status": {
+                "talos_version": {
+                    "description": "Versions the cluster runs once the step is done",
                     "type": "string"
                 },
-                "step": {
+                "to": {
                     "type": "string"
-                },
-                "upgrade_versions": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
                 }
             }
         }
@@ -4678,7 +11462,7 @@ const docTemplate = `{
 
 // SwaggerInfo holds exported Swagger Info so clients can modify it
 var SwaggerInfo = &swag.Spec{
-	Version:          "0.0.1",
+	Version:          "0.0.11",
 	Host:             "localhost:8080",
 	BasePath:         "/api/v1",
 	Schemes:          []string{},
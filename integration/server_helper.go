@@ -57,7 +57,12 @@ func StartTestServer(ctx context.Context) (*ServerInstance, error) {
 	clusterMachineConfigStatusHandler := handlers.NewClusterMachineConfigStatusHandler(client.Omni().State())
 	clusterMachineTalosVersionHandler := handlers.NewClusterMachineTalosVersionHandler(client.Omni().State())
 	clusterMachineConfigHandler := handlers.NewClusterMachineConfigHandler(client.Omni().State())
-	kubeconfigHandler := handlers.NewKubeconfigHandler(client.Omni().State())
+	configService := omniclient.NewConfigService(omniclient.StaticSource{C: client})
+	kubeconfigHandler := handlers.NewKubeconfigHandler(client.Omni().State(), configService, nil, nil, nil)
+	talosconfigHandler := handlers.NewTalosconfigHandler(client.Omni().State(), configService, nil, nil)
+	omniconfigHandler := handlers.NewOmniconfigHandler(configService, nil)
+	omniAuditLogHandler := handlers.NewOmniAuditLogHandler(omniclient.NewAuditLogService(omniclient.StaticSource{C: client}), nil)
+	machineLogsHandler := handlers.NewMachineLogsHandler(client.Omni().State(), omniclient.NewTalosService(omniclient.StaticSource{C: client}))
 	kubernetesUpgradeHandler := handlers.NewKubernetesUpgradeHandler(client.Omni().State())
 	talosUpgradeHandler := handlers.NewTalosUpgradeHandler(client.Omni().State())
 	clusterEndpointHandler := handlers.NewClusterEndpointHandler(client.Omni().State())
@@ -96,6 +101,7 @@ func StartTestServer(ctx context.Context) (*ServerInstance, error) {
 		v1.GET("/clusters/:id/metrics", clusterHandler.GetClusterMetrics)
 		v1.GET("/clusters/:id/bootstrap", clusterHandler.GetClusterBootstrap)
 		v1.GET("/clusters/:id/kubeconfig", kubeconfigHandler.GetKubeconfig)
+		v1.GET("/clusters/:id/talosconfig", talosconfigHandler.GetTalosconfig)
 		v1.GET("/clusters/:id/kubernetes-upgrade", kubernetesUpgradeHandler.GetKubernetesUpgradeStatus)
 		v1.GET("/clusters/:id/talos-upgrade", talosUpgradeHandler.GetTalosUpgradeStatus)
 		v1.GET("/clusters/:id/endpoints", clusterEndpointHandler.GetClusterEndpoints)
@@ -116,6 +122,7 @@ func StartTestServer(ctx context.Context) (*ServerInstance, error) {
 		v1.GET("/machines/:id/upgrade-status", machineUpgradeStatusHandler.GetMachineUpgradeStatus)
 		v1.GET("/machines/:id/metrics", machineStatusMetricsHandler.GetMachineStatusMetrics)
 		v1.GET("/machines/:id/config-diff", machineConfigDiffHandler.GetMachineConfigDiff)
+		v1.GET("/machines/:id/logs", machineLogsHandler.StreamMachineLogs)
 
 		// MachineSet routes
 		v1.GET("/machinesets", machineSetHandler.ListMachineSets)
@@ -198,6 +205,12 @@ func StartTestServer(ctx context.Context) (*ServerInstance, error) {
 		// Infrastructure Machine Config routes
 		v1.GET("/infra-machine-configs", infraMachineConfigHandler.ListInfraMachineConfigs)
 		v1.GET("/infra-machine-configs/:id", infraMachineConfigHandler.GetInfraMachineConfig)
+
+		// Client configuration
+		v1.GET("/omniconfig", omniconfigHandler.GetOmniconfig)
+
+		// Audit log routes
+		v1.GET("/omni-audit-log", omniAuditLogHandler.ExportAuditLog)
 	}
 
 	// Redirect root to Swagger UI
@@ -0,0 +1,312 @@
+// Package admission evaluates CEL policies against write requests before they reach the handlers,
+// so operators can add guardrails such as limiting upgrades or forbidding actions at certain times.
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"go.yaml.in/yaml/v4"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// mutatingMethods are the methods policies apply to
+var mutatingMethods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// objectTypes maps the collection of a route to the resource its :id parameter names
+var objectTypes = map[string]resource.Type{
+	"clusters":       omni.ClusterType,
+	"machines":       omni.MachineStatusType,
+	"machinesets":    omni.MachineSetType,
+	"machineclasses": omni.MachineClassType,
+	"configpatches":  omni.ConfigPatchType,
+}
+
+// Policy admits a request when its expression evaluates to true
+type Policy struct {
+	Name       string   `yaml:"name" json:"name"`
+	Message    string   `yaml:"message" json:"message"`                     // Returned when the policy denies a request
+	Methods    []string `yaml:"methods,omitempty" json:"methods,omitempty"` // Empty matches every mutating method
+	Routes     []string `yaml:"routes,omitempty" json:"routes,omitempty"`   // Relative to /api/v1; a trailing * matches a prefix. Empty matches every route
+	Expression string   `yaml:"expression" json:"expression"`
+}
+
+// Config configures admission
+type Config struct {
+	File string // YAML file with a "policies" list; empty disables admission
+}
+
+// ConfigFromEnv builds a Config from environment variables
+func ConfigFromEnv() Config {
+	return Config{File: os.Getenv("OMNI_API_ADMISSION_POLICY_FILE")}
+}
+
+// Request is a write request as seen by policies
+type Request struct {
+	Method string
+	Route  string            // Route pattern relative to /api/v1, e.g. /clusters/:id
+	Path   string            // Request path relative to /api/v1
+	Params map[string]string // Route parameters
+	Query  map[string]string
+	User   string
+	Body   []byte
+}
+
+// Denial names the policy that rejected a request
+type Denial struct {
+	Policy  string `json:"policy"`
+	Message string `json:"message"`
+}
+
+type compiledPolicy struct {
+	Policy
+	program cel.Program
+}
+
+// Controller evaluates policies
+type Controller struct {
+	state    state.State
+	policies []compiledPolicy
+}
+
+// Load reads the policies in cfg.File and compiles them
+func Load(cfg Config, st state.State) (*Controller, error) {
+	if cfg.File == "" {
+		return New(nil, st)
+	}
+
+	data, err := os.ReadFile(cfg.File)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read admission policies: %w", err)
+	}
+
+	var file struct {
+		Policies []Policy `yaml:"policies"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse admission policies: %w", err)
+	}
+	return New(file.Policies, st)
+}
+
+// New compiles policies. Current resources are read from st.
+func New(policies []Policy, st state.State) (*Controller, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("request", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("object", cel.DynType),
+		cel.Variable("now", cel.TimestampType),
+		cel.CrossTypeNumericComparisons(true),
+		ext.Strings(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+
+	c := &Controller{state: st}
+	names := map[string]bool{}
+	for i, p := range policies {
+		if p.Name == "" {
+			return nil, fmt.Errorf("policy %d has no name", i+1)
+		}
+		if names[p.Name] {
+			return nil, fmt.Errorf("policy %s is defined twice", p.Name)
+		}
+		names[p.Name] = true
+
+		for j, method := range p.Methods {
+			p.Methods[j] = strings.ToUpper(method)
+			if !slices.Contains(mutatingMethods, p.Methods[j]) {
+				return nil, fmt.Errorf("policy %s: method %s is not a write method", p.Name, method)
+			}
+		}
+		for j, route := range p.Routes {
+			p.Routes[j] = normalizeRoute(route)
+		}
+
+		ast, issues := env.Compile(p.Expression)
+		if issues.Err() != nil {
+			return nil, fmt.Errorf("policy %s: %w", p.Name, issues.Err())
+		}
+		if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+			return nil, fmt.Errorf("policy %s: expression must evaluate to a bool, not %s", p.Name, ast.OutputType())
+		}
+		program, err := env.Program(ast)
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: %w", p.Name, err)
+		}
+
+		if p.Message == "" {
+			p.Message = "request denied by policy " + p.Name
+		}
+		c.policies = append(c.policies, compiledPolicy{Policy: p, program: program})
+	}
+
+	return c, nil
+}
+
+// Policies returns the loaded policies
+func (c *Controller) Policies() []Policy {
+	policies := make([]Policy, 0, len(c.policies))
+	for _, p := range c.policies {
+		policies = append(policies, p.Policy)
+	}
+	return policies
+}
+
+// Admit evaluates the policies matching a request in order and returns the first denial, or nil if the request is admitted.
+// A policy that fails to evaluate denies the request.
+func (c *Controller) Admit(ctx context.Context, req Request) (*Denial, error) {
+	var matching []compiledPolicy
+	for _, p := range c.policies {
+		if p.matches(req) {
+			matching = append(matching, p)
+		}
+	}
+	if len(matching) == 0 {
+		return nil, nil
+	}
+
+	object, err := c.object(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	vars := map[string]any{
+		"request": requestValue(req),
+		"object":  object,
+		"now":     time.Now().UTC(),
+	}
+
+	for _, p := range matching {
+		out, _, err := p.program.ContextEval(ctx, vars)
+		if err != nil {
+			return &Denial{Policy: p.Name, Message: fmt.Sprintf("%s (policy could not be evaluated: %v)", p.Message, err)}, nil
+		}
+		admitted, ok := out.Value().(bool)
+		if !ok {
+			return &Denial{Policy: p.Name, Message: fmt.Sprintf("%s (policy evaluated to %v instead of a bool)", p.Message, out.Value())}, nil
+		}
+		if !admitted {
+			return &Denial{Policy: p.Name, Message: p.Message}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (p compiledPolicy) matches(req Request) bool {
+	if len(p.Methods) > 0 && !slices.Contains(p.Methods, req.Method) {
+		return false
+	}
+	if len(p.Routes) == 0 {
+		return true
+	}
+	for _, route := range p.Routes {
+		if prefix, ok := strings.CutSuffix(route, "*"); ok && strings.HasPrefix(req.Route, prefix) {
+			return true
+		}
+		if route == req.Route {
+			return true
+		}
+	}
+	return false
+}
+
+// object returns the resource named by the route's :id parameter, or nil if there is none
+func (c *Controller) object(ctx context.Context, req Request) (any, error) {
+	id := req.Params["id"]
+	collection, _, _ := strings.Cut(strings.TrimPrefix(req.Route, "/"), "/")
+	resourceType, ok := objectTypes[collection]
+	if id == "" || !ok || c.state == nil {
+		return nil, nil
+	}
+
+	r, err := c.state.Get(ctx, resource.NewMetadata(omniresources.DefaultNamespace, resourceType, id, resource.VersionUndefined))
+	if err != nil {
+		if state.IsNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get %s %s: %w", resourceType, id, err)
+	}
+	return objectValue(r)
+}
+
+// objectValue converts a resource to a map with its metadata and its spec, using the protobuf field names
+func objectValue(r resource.Resource) (map[string]any, error) {
+	md := r.Metadata()
+	object := map[string]any{
+		"metadata": map[string]any{
+			"namespace":   md.Namespace(),
+			"type":        md.Type(),
+			"id":          md.ID(),
+			"version":     md.Version().String(),
+			"phase":       md.Phase().String(),
+			"labels":      stringMap(md.Labels().Raw()),
+			"annotations": stringMap(md.Annotations().Raw()),
+		},
+		"spec": map[string]any{},
+	}
+
+	spec, ok := r.Spec().(interface{ GetValue() proto.Message })
+	if !ok {
+		return object, nil
+	}
+	data, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(spec.GetValue())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s: %w", resource.String(r), err)
+	}
+	var value map[string]any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("failed to marshal %s: %w", resource.String(r), err)
+	}
+	object["spec"] = value
+	return object, nil
+}
+
+// requestValue converts a request to a map. Bodies that are not JSON objects or arrays are null.
+func requestValue(req Request) map[string]any {
+	var body any
+	if len(req.Body) > 0 && json.Unmarshal(req.Body, &body) != nil {
+		body = nil
+	}
+
+	return map[string]any{
+		"method": req.Method,
+		"route":  req.Route,
+		"path":   req.Path,
+		"params": stringMap(req.Params),
+		"query":  stringMap(req.Query),
+		"user":   req.User,
+		"body":   body,
+	}
+}
+
+func stringMap(m map[string]string) map[string]any {
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// normalizeRoute accepts Swagger style {id} parameters
+func normalizeRoute(route string) string {
+	segments := strings.Split("/"+strings.Trim(route, "/"), "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[i] = ":" + strings.TrimSuffix(strings.TrimPrefix(segment, "{"), "}")
+		}
+	}
+	return strings.Join(segments, "/")
+}
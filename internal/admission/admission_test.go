@@ -0,0 +1,171 @@
+package admission
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/cosi-project/runtime/pkg/state/impl/inmem"
+	"github.com/cosi-project/runtime/pkg/state/impl/namespaced"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const minorStepExpression = `!has(request.body.kubernetes_version) || object == null ||
+  int(request.body.kubernetes_version.split('.')[1]) - int(object.spec.kubernetes_version.split('.')[1]) <= 1`
+
+func newTestState(t *testing.T) state.State {
+	st := state.WrapCore(namespaced.NewState(inmem.Build))
+
+	cluster := omni.NewCluster("default", "prod")
+	cluster.TypedSpec().Value.KubernetesVersion = "1.30.2"
+	require.NoError(t, st.Create(context.Background(), cluster))
+
+	machine := omni.NewMachineStatus("default", "cp-1")
+	machine.Metadata().Labels().Set(omni.LabelControlPlaneRole, "")
+	require.NoError(t, st.Create(context.Background(), machine))
+
+	return st
+}
+
+func TestController_Admit(t *testing.T) {
+	c, err := New([]Policy{
+		{
+			Name:       "kubernetes-minor-step",
+			Message:    "Kubernetes can only be upgraded one minor version at a time",
+			Methods:    []string{"put"},
+			Routes:     []string{"/clusters/{id}"},
+			Expression: minorStepExpression,
+		},
+		{
+			Name:       "no-control-plane-reset",
+			Routes:     []string{"/machines/:id/actions/*"},
+			Expression: `request.route != '/machines/:id/actions/reset' || object == null || !('omni.sidero.dev/role-controlplane' in object.metadata.labels)`,
+		},
+		{
+			Name:       "require-user",
+			Routes:     []string{"/machinesets"},
+			Expression: `request.user != 'anonymous' && request.body.machine_count <= 5`,
+		},
+	}, newTestState(t))
+	require.NoError(t, err)
+	assert.Len(t, c.Policies(), 3)
+
+	tests := []struct {
+		name   string
+		req    Request
+		policy string
+	}{
+		{
+			name: "one minor step",
+			req:  Request{Method: "PUT", Route: "/clusters/:id", Params: map[string]string{"id": "prod"}, Body: []byte(`{"kubernetes_version":"1.31.0"}`)},
+		},
+		{
+			name:   "two minor steps",
+			req:    Request{Method: "PUT", Route: "/clusters/:id", Params: map[string]string{"id": "prod"}, Body: []byte(`{"kubernetes_version":"1.32.0"}`)},
+			policy: "kubernetes-minor-step",
+		},
+		{
+			name: "no version",
+			req:  Request{Method: "PUT", Route: "/clusters/:id", Params: map[string]string{"id": "prod"}, Body: []byte(`{"talos_version":"1.9.0"}`)},
+		},
+		{
+			name: "unknown cluster",
+			req:  Request{Method: "PUT", Route: "/clusters/:id", Params: map[string]string{"id": "missing"}, Body: []byte(`{"kubernetes_version":"1.40.0"}`)},
+		},
+		{
+			name: "other method",
+			req:  Request{Method: "DELETE", Route: "/clusters/:id", Params: map[string]string{"id": "prod"}},
+		},
+		{
+			name:   "control plane reset",
+			req:    Request{Method: "POST", Route: "/machines/:id/actions/reset", Params: map[string]string{"id": "cp-1"}},
+			policy: "no-control-plane-reset",
+		},
+		{
+			name: "control plane reboot",
+			req:  Request{Method: "POST", Route: "/machines/:id/actions/reboot", Params: map[string]string{"id": "cp-1"}},
+		},
+		{
+			name:   "anonymous user",
+			req:    Request{Method: "POST", Route: "/machinesets", User: "anonymous", Body: []byte(`{"machine_count":1}`)},
+			policy: "require-user",
+		},
+		{
+			name: "known user",
+			req:  Request{Method: "POST", Route: "/machinesets", User: "alice", Body: []byte(`{"machine_count":3}`)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			denial, err := c.Admit(context.Background(), tt.req)
+			require.NoError(t, err)
+			if tt.policy == "" {
+				assert.Nil(t, denial)
+				return
+			}
+			require.NotNil(t, denial)
+			assert.Equal(t, tt.policy, denial.Policy)
+			assert.NotEmpty(t, denial.Message)
+		})
+	}
+}
+
+func TestController_AdmitEvaluationError(t *testing.T) {
+	c, err := New([]Policy{{Name: "count", Message: "too many machines", Expression: "request.body.machine_count <= 5"}}, nil)
+	require.NoError(t, err)
+
+	// Missing fields fail closed
+	denial, err := c.Admit(context.Background(), Request{Method: "POST", Route: "/machinesets", Body: []byte(`{}`)})
+	require.NoError(t, err)
+	require.NotNil(t, denial)
+	assert.Contains(t, denial.Message, "too many machines (policy could not be evaluated")
+}
+
+func TestNew_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+	}{
+		{name: "no name", policy: Policy{Expression: "true"}},
+		{name: "syntax error", policy: Policy{Name: "p", Expression: "request.method =="}},
+		{name: "not a bool", policy: Policy{Name: "p", Expression: "size(request)"}},
+		{name: "read method", policy: Policy{Name: "p", Methods: []string{"GET"}, Expression: "true"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New([]Policy{tt.policy}, nil)
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := New([]Policy{{Name: "p", Expression: "true"}, {Name: "p", Expression: "false"}}, nil)
+	assert.ErrorContains(t, err, "defined twice")
+}
+
+func TestLoad(t *testing.T) {
+	c, err := Load(Config{}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, c.Policies())
+
+	file := filepath.Join(t.TempDir(), "policies.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`policies:
+  - name: business-hours
+    message: Resets are not allowed during business hours
+    routes: ["/machines/{id}/actions/reset"]
+    expression: now.getHours('Europe/Berlin') < 8 || now.getHours('Europe/Berlin') >= 18
+`), 0o600))
+
+	c, err = Load(Config{File: file}, nil)
+	require.NoError(t, err)
+	require.Len(t, c.Policies(), 1)
+	assert.Equal(t, []string{"/machines/:id/actions/reset"}, c.Policies()[0].Routes)
+
+	_, err = Load(Config{File: filepath.Join(t.TempDir(), "missing.yaml")}, nil)
+	assert.Error(t, err)
+}
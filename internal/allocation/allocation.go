@@ -0,0 +1,287 @@
+// Package allocation chooses free machines to add to a machine set.
+// Machines are filtered by availability and labels, then ordered by a pluggable Ranker.
+package allocation
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+// Candidate is a machine known to Omni
+type Candidate struct {
+	ID          string
+	Labels      map[string]string // MachineStatus labels, including arch and platform
+	Connected   bool
+	AllocatedTo string    // Machine set the machine belongs to, empty if it is free
+	LastUsed    time.Time // Last time the machine was seen allocated, zero if never
+}
+
+// Choice is a machine chosen for allocation
+type Choice struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// Rejection is a machine that was not chosen
+type Rejection struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// Filter restricts the machines that can be chosen
+type Filter struct {
+	Selectors resource.LabelQueries // A machine must match one of them; empty matches every machine
+	Arch      string
+	Platform  string
+}
+
+// Result is the outcome of a selection
+type Result struct {
+	Chosen   []Choice
+	Rejected []Rejection
+}
+
+// InsufficientError is returned when fewer machines are eligible than requested
+type InsufficientError struct {
+	Requested int
+	Eligible  int
+}
+
+func (e *InsufficientError) Error() string {
+	return fmt.Sprintf("%d machines requested, but only %d are eligible", e.Requested, e.Eligible)
+}
+
+// Ranker orders eligible machines by preference
+type Ranker interface {
+	// Choose picks count machines from eligible, taking the machines already in the machine set into account
+	Choose(eligible, members []Candidate, count int) []Choice
+}
+
+// RankerFactory creates a Ranker; label is the label a ranker groups machines by, if it uses one
+type RankerFactory func(label string) (Ranker, error)
+
+// DefaultRanking is used when no ranking is requested
+const DefaultRanking = "lru"
+
+// DefaultSpreadLabel is the label machines are spread by when none is given
+const DefaultSpreadLabel = "topology.kubernetes.io/zone"
+
+var (
+	rankersMu sync.RWMutex
+	rankers   = map[string]RankerFactory{
+		"lru": func(string) (Ranker, error) { return lruRanker{}, nil },
+		"spread": func(label string) (Ranker, error) {
+			if label == "" {
+				label = DefaultSpreadLabel
+			}
+			return spreadRanker{label: label}, nil
+		},
+	}
+)
+
+// RegisterRanker makes a ranking available by name
+func RegisterRanker(name string, factory RankerFactory) {
+	rankersMu.Lock()
+	defer rankersMu.Unlock()
+
+	rankers[name] = factory
+}
+
+// Rankings returns the names of the available rankings
+func Rankings() []string {
+	rankersMu.RLock()
+	defer rankersMu.RUnlock()
+
+	names := make([]string, 0, len(rankers))
+	for name := range rankers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// NewRanker creates the ranking registered under name, or the default ranking if name is empty
+func NewRanker(name, label string) (Ranker, error) {
+	if name == "" {
+		name = DefaultRanking
+	}
+
+	rankersMu.RLock()
+	factory, ok := rankers[name]
+	rankersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown ranking %q, expected one of %s", name, strings.Join(Rankings(), ", "))
+	}
+	return factory(label)
+}
+
+// Select picks count free, connected machines matching filter, in the order of ranker.
+// members are the machines already in the machine set. Every other candidate is reported as rejected with a reason.
+// If fewer machines are eligible than requested, nothing is chosen and an *InsufficientError is returned with the rejections.
+func Select(candidates, members []Candidate, filter Filter, count int, ranker Ranker) (Result, error) {
+	var (
+		result   Result
+		eligible []Candidate
+	)
+	for _, c := range sortedByID(candidates) {
+		if reason := ineligible(c, filter); reason != "" {
+			result.Rejected = append(result.Rejected, Rejection{ID: c.ID, Reason: reason})
+			continue
+		}
+		eligible = append(eligible, c)
+	}
+
+	if len(eligible) < count {
+		for _, c := range eligible {
+			result.Rejected = append(result.Rejected, Rejection{ID: c.ID, Reason: "eligible, but too few machines are eligible"})
+		}
+		return result, &InsufficientError{Requested: count, Eligible: len(eligible)}
+	}
+
+	result.Chosen = ranker.Choose(eligible, members, count)
+	chosen := map[string]bool{}
+	for _, choice := range result.Chosen {
+		chosen[choice.ID] = true
+	}
+	for _, c := range eligible {
+		if !chosen[c.ID] {
+			result.Rejected = append(result.Rejected, Rejection{ID: c.ID, Reason: "eligible, but ranked below the chosen machines"})
+		}
+	}
+	return result, nil
+}
+
+// ineligible returns why a machine cannot be allocated, or an empty string if it can
+func ineligible(c Candidate, filter Filter) string {
+	arch, platform := c.Labels[omni.MachineStatusLabelArch], c.Labels[omni.MachineStatusLabelPlatform]
+	switch {
+	case c.AllocatedTo != "":
+		return "already allocated to machine set " + c.AllocatedTo
+	case !c.Connected:
+		return "not connected"
+	case filter.Arch != "" && arch != filter.Arch:
+		return fmt.Sprintf("architecture %q is not %q", arch, filter.Arch)
+	case filter.Platform != "" && platform != filter.Platform:
+		return fmt.Sprintf("platform %q is not %q", platform, filter.Platform)
+	case !filter.Selectors.Matches(labelsOf(c)):
+		return "labels do not match the selector"
+	}
+	return ""
+}
+
+func labelsOf(c Candidate) resource.Labels {
+	var labels resource.Labels
+	for k, v := range c.Labels {
+		labels.Set(k, v)
+	}
+	return labels
+}
+
+func sortedByID(candidates []Candidate) []Candidate {
+	sorted := slices.Clone(candidates)
+	slices.SortFunc(sorted, func(a, b Candidate) int { return strings.Compare(a.ID, b.ID) })
+	return sorted
+}
+
+// byLastUsed orders machines that were never used first, then the least recently used, then by ID
+func byLastUsed(candidates []Candidate) []Candidate {
+	sorted := slices.Clone(candidates)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].LastUsed.Equal(sorted[j].LastUsed) {
+			return sorted[i].LastUsed.Before(sorted[j].LastUsed)
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+	return sorted
+}
+
+func lastUsedReason(c Candidate) string {
+	if c.LastUsed.IsZero() {
+		return "not used since the server started"
+	}
+	return "last used " + c.LastUsed.UTC().Format(time.RFC3339)
+}
+
+// lruRanker prefers the machines that were allocated least recently
+type lruRanker struct{}
+
+func (lruRanker) Choose(eligible, _ []Candidate, count int) []Choice {
+	choices := make([]Choice, 0, count)
+	for _, c := range byLastUsed(eligible)[:count] {
+		choices = append(choices, Choice{ID: c.ID, Reason: lastUsedReason(c)})
+	}
+	return choices
+}
+
+// spreadRanker spreads the machine set evenly over the values of a label, such as racks or zones.
+// Ties are broken by the least recently used machine.
+type spreadRanker struct {
+	label string
+}
+
+func (r spreadRanker) Choose(eligible, members []Candidate, count int) []Choice {
+	counts := map[string]int{}
+	for _, m := range members {
+		counts[m.Labels[r.label]]++
+	}
+
+	remaining := byLastUsed(eligible)
+	choices := make([]Choice, 0, count)
+	for range count {
+		best := 0
+		for i, c := range remaining {
+			if counts[c.Labels[r.label]] < counts[remaining[best].Labels[r.label]] {
+				best = i
+			}
+		}
+
+		c := remaining[best]
+		value := c.Labels[r.label]
+		reason := fmt.Sprintf("%s=%s had %d of the machine set's machines", r.label, value, counts[value])
+		if value == "" {
+			reason = fmt.Sprintf("no %s label, %d unlabeled machines in the machine set", r.label, counts[value])
+		}
+		choices = append(choices, Choice{ID: c.ID, Reason: reason + ", " + lastUsedReason(c)})
+
+		counts[value]++
+		remaining = slices.Delete(remaining, best, best+1)
+	}
+	return choices
+}
+
+// Usage remembers when machines were last seen allocated, for least recently used ranking.
+// It is kept in memory and starts empty when the server restarts.
+type Usage struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+// NewUsage creates an empty Usage
+func NewUsage() *Usage {
+	return &Usage{last: map[string]time.Time{}}
+}
+
+// Observe records that machines are allocated at t
+func (u *Usage) Observe(t time.Time, ids ...string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for _, id := range ids {
+		u.last[id] = t
+	}
+}
+
+// LastUsed returns when a machine was last seen allocated, or the zero time
+func (u *Usage) LastUsed(id string) time.Time {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	return u.last[id]
+}
@@ -0,0 +1,114 @@
+package allocation
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/siderolabs/omni/client/pkg/cosi/labels"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func candidate(id, zone string, lastUsed time.Time) Candidate {
+	return Candidate{
+		ID:        id,
+		Labels:    map[string]string{DefaultSpreadLabel: zone, omni.MachineStatusLabelArch: "amd64"},
+		Connected: true,
+		LastUsed:  lastUsed,
+	}
+}
+
+func chosenIDs(result Result) []string {
+	ids := make([]string, 0, len(result.Chosen))
+	for _, choice := range result.Chosen {
+		ids = append(ids, choice.ID)
+	}
+	return ids
+}
+
+func TestSelect_Rejections(t *testing.T) {
+	selectors, err := labels.ParseSelectors([]string{"rack=a"})
+	require.NoError(t, err)
+
+	arm := Candidate{ID: "arm", Labels: map[string]string{"rack": "a", omni.MachineStatusLabelArch: "arm64"}, Connected: true}
+	candidates := []Candidate{
+		{ID: "free", Labels: map[string]string{"rack": "a", omni.MachineStatusLabelArch: "amd64"}, Connected: true},
+		{ID: "used", Labels: map[string]string{"rack": "a"}, Connected: true, AllocatedTo: "other"},
+		{ID: "offline", Labels: map[string]string{"rack": "a"}},
+		{ID: "rack-b", Labels: map[string]string{"rack": "b", omni.MachineStatusLabelArch: "amd64"}, Connected: true},
+		arm,
+	}
+
+	result, err := Select(candidates, nil, Filter{Selectors: selectors, Arch: "amd64"}, 1, lruRanker{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"free"}, chosenIDs(result))
+	assert.Equal(t, []Rejection{
+		{ID: "arm", Reason: `architecture "arm64" is not "amd64"`},
+		{ID: "offline", Reason: "not connected"},
+		{ID: "rack-b", Reason: "labels do not match the selector"},
+		{ID: "used", Reason: "already allocated to machine set other"},
+	}, result.Rejected)
+
+	result, err = Select(candidates, nil, Filter{Selectors: selectors}, 3, lruRanker{})
+	var insufficient *InsufficientError
+	require.True(t, errors.As(err, &insufficient))
+	assert.Equal(t, 2, insufficient.Eligible)
+	assert.Empty(t, result.Chosen)
+	assert.Contains(t, result.Rejected, Rejection{ID: "free", Reason: "eligible, but too few machines are eligible"})
+}
+
+func TestSelect_LeastRecentlyUsed(t *testing.T) {
+	now := time.Now()
+	candidates := []Candidate{
+		candidate("a", "z1", now),
+		candidate("b", "z1", now.Add(-time.Hour)),
+		candidate("c", "z1", time.Time{}),
+		candidate("d", "z1", time.Time{}),
+	}
+
+	result, err := Select(candidates, nil, Filter{}, 3, lruRanker{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c", "d", "b"}, chosenIDs(result))
+	assert.Equal(t, []Rejection{{ID: "a", Reason: "eligible, but ranked below the chosen machines"}}, result.Rejected)
+}
+
+func TestSelect_Spread(t *testing.T) {
+	ranker, err := NewRanker("spread", "")
+	require.NoError(t, err)
+
+	members := []Candidate{candidate("member-1", "z1", time.Time{}), candidate("member-2", "z1", time.Time{}), candidate("member-3", "z2", time.Time{})}
+	candidates := []Candidate{
+		candidate("a", "z1", time.Time{}),
+		candidate("b", "z2", time.Time{}),
+		candidate("c", "z3", time.Time{}),
+		candidate("d", "z3", time.Time{}),
+	}
+
+	result, err := Select(candidates, members, Filter{}, 3, ranker)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c", "b", "d"}, chosenIDs(result))
+	assert.Contains(t, result.Chosen[0].Reason, DefaultSpreadLabel+"=z3 had 0")
+}
+
+func TestNewRanker(t *testing.T) {
+	ranker, err := NewRanker("", "")
+	require.NoError(t, err)
+	assert.IsType(t, lruRanker{}, ranker)
+
+	_, err = NewRanker("random", "")
+	assert.ErrorContains(t, err, "lru, spread")
+
+	RegisterRanker("first", func(string) (Ranker, error) { return lruRanker{}, nil })
+	assert.Contains(t, Rankings(), "first")
+}
+
+func TestUsage(t *testing.T) {
+	usage := NewUsage()
+	assert.True(t, usage.LastUsed("m1").IsZero())
+
+	now := time.Now()
+	usage.Observe(now, "m1", "m2")
+	assert.Equal(t, now, usage.LastUsed("m2"))
+}
@@ -84,6 +84,7 @@ type CreateServiceAccountRequest struct {
 // @Accept       json
 // @Produce      json
 // @Param        account  body      CreateServiceAccountRequest  true  "Service account creation request"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
 // @Success      201      {object}  ServiceAccountResponse
 // @Failure      400      {object}  map[string]string
 // @Failure      500      {object}  map[string]string
@@ -0,0 +1,349 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/admission"
+	"github.com/jubblin/omni-api/internal/api/middleware"
+	"github.com/jubblin/omni-api/internal/audit"
+	"github.com/jubblin/omni-api/internal/bulk"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/jubblin/omni-api/internal/operations"
+	"github.com/jubblin/omni-api/internal/patchhistory"
+	"github.com/siderolabs/omni/client/pkg/cosi/labels"
+	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+// maxBulkItems caps the number of items of a bulk request
+const maxBulkItems = 1000
+
+// BulkOperation represents a single action against a single target
+type BulkOperation struct {
+	Action string             `json:"action" binding:"required"` // reboot, patch-labels, delete-config-patch, delete-machine-set, delete-machine-class or delete-cluster
+	Target string             `json:"target,omitempty"`          // ID of the machine, config patch, machine set, machine class or cluster; filled in from the selector if set
+	Labels map[string]*string `json:"labels,omitempty"`          // Labels merged by patch-labels; a null value deletes the label
+}
+
+// BulkRequest represents a request to run many operations.
+// Either operations, or operation and selector are required.
+type BulkRequest struct {
+	Operations  []BulkOperation `json:"operations,omitempty"`
+	Operation   *BulkOperation  `json:"operation,omitempty"`   // Run against every target matching selector
+	Selector    string          `json:"selector,omitempty"`    // Omni label selector, e.g. "rack=a, role=worker"
+	Mode        string          `json:"mode,omitempty"`        // continue (default) or fail_fast
+	Concurrency int             `json:"concurrency,omitempty"` // Items run at the same time, default 4, at most 32
+}
+
+// BulkDryRunResponse represents the report of a bulk request run with dryRun
+type BulkDryRunResponse struct {
+	DryRun bool        `json:"dry_run"`
+	Report bulk.Report `json:"report"`
+}
+
+// bulkAction runs an action against a target
+type bulkAction struct {
+	kind   resource.Type // Type of the targets, matched by selectors
+	labels bool          // Whether the action needs labels
+	method string        // Method and route of the equivalent single request, checked against route groups and admission policies
+	route  string
+	run    func(ctx context.Context, h *BulkHandler, op BulkOperation, author string) (string, error) // author is recorded in the config patch history
+}
+
+var bulkActions = map[string]bulkAction{
+	"reboot": {kind: omni.MachineStatusType, method: http.MethodPost, route: "/machines/:id/actions/reboot", run: func(ctx context.Context, h *BulkHandler, op BulkOperation, _ string) (string, error) {
+		return "initiated", h.talos.RebootMachine(ctx, op.Target)
+	}},
+	"patch-labels": {kind: omni.MachineStatusType, labels: true, method: http.MethodPatch, route: "/machines/:id", run: func(ctx context.Context, h *BulkHandler, op BulkOperation, _ string) (string, error) {
+		return changeResult(h.management.PatchMachineLabels(ctx, op.Target, op.Labels))
+	}},
+	"delete-config-patch": {kind: omni.ConfigPatchType, method: http.MethodDelete, route: "/configpatches/:id", run: func(ctx context.Context, h *BulkHandler, op BulkOperation, author string) (string, error) {
+		change, err := h.management.DeleteConfigPatch(ctx, op.Target)
+		if err == nil && !client.IsDryRun(ctx) {
+			h.recordPatchDelete(change, author)
+		}
+		return changeResult(change, err)
+	}},
+	"delete-machine-set": {kind: omni.MachineSetType, method: http.MethodDelete, route: "/machinesets/:id", run: func(ctx context.Context, h *BulkHandler, op BulkOperation, _ string) (string, error) {
+		return changeResult(h.management.DeleteMachineSet(ctx, op.Target))
+	}},
+	"delete-machine-class": {kind: omni.MachineClassType, method: http.MethodDelete, route: "/machineclasses/:id", run: func(ctx context.Context, h *BulkHandler, op BulkOperation, _ string) (string, error) {
+		return changeResult(h.management.DeleteMachineClass(ctx, op.Target))
+	}},
+	"delete-cluster": {kind: omni.ClusterType, method: http.MethodDelete, route: "/clusters/:id", run: func(ctx context.Context, h *BulkHandler, op BulkOperation, _ string) (string, error) {
+		return changeResult(h.management.DeleteCluster(ctx, op.Target))
+	}},
+}
+
+func changeResult(change *client.Change, err error) (string, error) {
+	if err != nil || change == nil {
+		return "", err
+	}
+	return string(change.Action), nil
+}
+
+func bulkActionNames() string {
+	names := make([]string, 0, len(bulkActions))
+	for name := range bulkActions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// BulkHandler handles bulk operations
+type BulkHandler struct {
+	state      state.State
+	management client.ManagementService
+	talos      client.TalosService
+	operations *operations.Manager
+	access     middleware.AccessConfig // Items of disabled route groups are rejected
+	admission  *admission.Controller   // Every item must pass the policies of its equivalent single request
+	history    *patchhistory.History   // Records deleted config patches, may be nil
+}
+
+// NewBulkHandler creates a new BulkHandler
+func NewBulkHandler(s state.State, mgmt client.ManagementService, talos client.TalosService, ops *operations.Manager, access middleware.AccessConfig, admissionController *admission.Controller, history *patchhistory.History) *BulkHandler {
+	return &BulkHandler{
+		state:      s,
+		management: mgmt,
+		talos:      talos,
+		operations: ops,
+		access:     access,
+		admission:  admissionController,
+		history:    history,
+	}
+}
+
+// RunBulk godoc
+// @Summary      Run many operations
+// @Description  Run a list of operations, or one operation against every target matching a label selector, with bounded concurrency.
+// @Description  Actions: reboot machines, patch-labels of machines, delete-config-patch, delete-machine-set, delete-machine-class and delete-cluster.
+// @Description  In continue mode every item runs; in fail_fast mode items that did not start yet are skipped after the first failure.
+// @Description  The items run in the background; the operation's result holds the status of every item and the operation fails if any item failed.
+// @Description  Each item is checked against the route groups and admission policies of its equivalent single request, e.g. POST /machines/{id}/actions/reboot; items denied by a policy fail.
+// @Description  With dryRun the items are validated synchronously and the report is returned instead.
+// @Tags         bulk
+// @Accept       json
+// @Produce      json
+// @Param        request  body      BulkRequest  true   "Operations to run"
+// @Param        dryRun   query     bool         false  "Validate every item without applying it"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200  {object}  BulkDryRunResponse  "Returned instead when dryRun is set"
+// @Success      202  {object}  map[string]interface{}  "Location header points to the operation running the items"
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string  "An action belongs to a disabled route group"
+// @Failure      500  {object}  map[string]string
+// @Router       /bulk [post]
+func (h *BulkHandler) RunBulk(c *gin.Context) {
+	var req BulkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	mode, err := bulk.ParseMode(req.Mode)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Concurrency < 0 || req.Concurrency > bulk.MaxConcurrency {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("concurrency must be between 1 and %d", bulk.MaxConcurrency)})
+		return
+	}
+
+	ops, target, status, err := h.expand(c, req)
+	if err != nil {
+		if status == http.StatusInternalServerError {
+			log.Printf("Error expanding bulk request: %v", err)
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, op := range ops {
+		action := bulkActions[op.Action]
+		if ok, reason := h.access.Allowed(action.method, action.route); !ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("%s: %s", op.Action, reason)})
+			return
+		}
+	}
+
+	user := audit.ActorFromRequest(c.Request)
+	author := patchAuthor(c)
+	dryRun := isDryRun(c)
+	items := make([]bulk.Item, 0, len(ops))
+	for _, op := range ops {
+		action := bulkActions[op.Action]
+		items = append(items, bulk.Item{
+			Action: op.Action,
+			Target: op.Target,
+			Run: func(ctx context.Context) (string, error) {
+				if err := h.admit(ctx, user, dryRun, op); err != nil {
+					return "", err
+				}
+				return action.run(ctx, h, op, author)
+			},
+		})
+	}
+	opts := bulk.Options{Mode: mode, Concurrency: req.Concurrency}
+
+	if isDryRun(c) {
+		report, _ := bulk.Run(writeContext(c), items, opts, nil)
+		c.JSON(http.StatusOK, BulkDryRunResponse{DryRun: true, Report: report})
+		return
+	}
+
+	resp := gin.H{
+		"message": "Bulk operation started",
+		"total":   len(items),
+	}
+	startOperation(c, h.operations, operations.Spec{
+		Kind:   "bulk",
+		Target: target,
+		Track: func(ctx context.Context, report func(operations.Progress)) error {
+			_, err := bulk.Run(ctx, items, opts, func(r bulk.Report) {
+				report(operations.Progress{
+					Phase:   "Running",
+					Message: fmt.Sprintf("%d of %d items done", r.Done(), r.Total),
+					Result:  r,
+				})
+			})
+			return err
+		},
+		// Stopping the tracker skips the items that did not start yet
+		Cancel: func(context.Context) error { return nil },
+	}, resp)
+
+	c.JSON(http.StatusAccepted, resp)
+}
+
+// expand validates a bulk request and returns its operations and a description of the targets,
+// resolving the selector if set. The status is the HTTP status to respond with on error.
+func (h *BulkHandler) expand(c *gin.Context, req BulkRequest) ([]BulkOperation, string, int, error) {
+	switch {
+	case len(req.Operations) > 0 && (req.Operation != nil || req.Selector != ""):
+		return nil, "", http.StatusBadRequest, fmt.Errorf("operations cannot be combined with operation and selector")
+	case len(req.Operations) == 0 && (req.Operation == nil || req.Selector == ""):
+		return nil, "", http.StatusBadRequest, fmt.Errorf("either operations, or operation and selector are required")
+	}
+
+	if len(req.Operations) > 0 {
+		if len(req.Operations) > maxBulkItems {
+			return nil, "", http.StatusBadRequest, fmt.Errorf("at most %d operations are allowed", maxBulkItems)
+		}
+		for i, op := range req.Operations {
+			if err := validateBulkOperation(op); err != nil {
+				return nil, "", http.StatusBadRequest, fmt.Errorf("operations[%d]: %w", i, err)
+			}
+			if op.Target == "" {
+				return nil, "", http.StatusBadRequest, fmt.Errorf("operations[%d]: target is required", i)
+			}
+		}
+		return req.Operations, fmt.Sprintf("%d operations", len(req.Operations)), 0, nil
+	}
+
+	op := *req.Operation
+	if err := validateBulkOperation(op); err != nil {
+		return nil, "", http.StatusBadRequest, fmt.Errorf("operation: %w", err)
+	}
+	if op.Target != "" {
+		return nil, "", http.StatusBadRequest, fmt.Errorf("operation: target cannot be set together with selector")
+	}
+	query, err := labels.ParseSelectors([]string{req.Selector})
+	if err != nil {
+		return nil, "", http.StatusBadRequest, fmt.Errorf("invalid selector: %w", err)
+	}
+
+	kind := bulkActions[op.Action].kind
+	list, err := h.state.List(c.Request.Context(), resource.NewMetadata(omniresources.DefaultNamespace, kind, "", resource.VersionUndefined))
+	if err != nil {
+		return nil, "", http.StatusInternalServerError, err
+	}
+
+	var ops []BulkOperation
+	for _, item := range list.Items {
+		if !query.Matches(*item.Metadata().Labels()) {
+			continue
+		}
+		matched := op
+		matched.Target = item.Metadata().ID()
+		ops = append(ops, matched)
+	}
+	if len(ops) > maxBulkItems {
+		return nil, "", http.StatusBadRequest, fmt.Errorf("selector matches %d targets, at most %d are allowed", len(ops), maxBulkItems)
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].Target < ops[j].Target })
+
+	return ops, op.Action + " " + req.Selector, 0, nil
+}
+
+// admit evaluates the admission policies against the single request equivalent to op.
+// Policies are evaluated when the item runs, so they see the current time and resources.
+func (h *BulkHandler) admit(ctx context.Context, user string, dryRun bool, op BulkOperation) error {
+	if h.admission == nil {
+		return nil
+	}
+
+	action := bulkActions[op.Action]
+	req := admission.Request{
+		Method: action.method,
+		Route:  action.route,
+		Path:   strings.Replace(action.route, ":id", op.Target, 1),
+		Params: map[string]string{"id": op.Target},
+		Query:  map[string]string{},
+		User:   user,
+	}
+	if dryRun {
+		req.Query["dryRun"] = "true"
+	}
+	if action.labels {
+		body, err := json.Marshal(MachineUpdateRequest{Labels: op.Labels})
+		if err != nil {
+			return err
+		}
+		req.Body = body
+	}
+
+	denial, err := h.admission.Admit(ctx, req)
+	if err != nil {
+		return err
+	}
+	if denial != nil {
+		return fmt.Errorf("denied by admission policy %s: %s", denial.Policy, denial.Message)
+	}
+	return nil
+}
+
+// recordPatchDelete records a config patch deleted by a bulk item in the history, so it can be rolled back.
+// Failures are logged, as the config patch is already gone.
+func (h *BulkHandler) recordPatchDelete(change *client.Change, author string) {
+	if h.history == nil || change == nil {
+		return
+	}
+	if _, err := h.history.RecordChange(change, patchhistory.ActionDelete, author, 0); err != nil {
+		log.Printf("Error recording config patch history: %v", err)
+	}
+}
+
+func validateBulkOperation(op BulkOperation) error {
+	action, ok := bulkActions[op.Action]
+	if !ok {
+		return fmt.Errorf("unknown action %q, expected one of %s", op.Action, bulkActionNames())
+	}
+	if action.labels && len(op.Labels) == 0 {
+		return fmt.Errorf("labels are required for %s", op.Action)
+	}
+	if !action.labels && len(op.Labels) > 0 {
+		return fmt.Errorf("labels are not supported by %s", op.Action)
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/admission"
+	"github.com/jubblin/omni-api/internal/api/middleware"
+	"github.com/jubblin/omni-api/internal/bulk"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/jubblin/omni-api/internal/operations"
+	"github.com/jubblin/omni-api/internal/patchhistory"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkHandler_RunBulk(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockMgmt := new(MockManagementService)
+	mockMgmt.On("DeleteConfigPatch", mock.Anything, "stale").Return(nil, errors.New("config patch not found"))
+	mockTalos := new(MockTalosService)
+	mockTalos.On("RebootMachine", mock.Anything, "m1").Return(nil)
+
+	ops := operations.NewManager(operations.Config{})
+	handler := NewBulkHandler(new(MockState), mockMgmt, mockTalos, ops, middleware.AccessConfig{}, nil, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/bulk", strings.NewReader(`{"operations":[{"action":"reboot","target":"m1"},{"action":"delete-config-patch","target":"stale"}]}`))
+
+	handler.RunBulk(c)
+
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, float64(2), resp["total"])
+	assert.NotEmpty(t, w.Header().Get("Location"))
+
+	op, ok := ops.Wait(context.Background(), resp["operation_id"].(string))
+	require.True(t, ok)
+	assert.Equal(t, "bulk", op.Kind)
+	assert.Equal(t, "2 operations", op.Target)
+	assert.Equal(t, operations.StatusFailed, op.Status)
+	assert.Equal(t, "1 of 2 items failed", op.Error)
+
+	report, ok := op.Result.(bulk.Report)
+	require.True(t, ok)
+	assert.Equal(t, 1, report.Succeeded)
+	assert.Equal(t, bulk.ItemSucceeded, report.Items[0].Status)
+	assert.Equal(t, "config patch not found", report.Items[1].Error)
+	mockTalos.AssertExpectations(t)
+}
+
+func TestBulkHandler_RunBulkSelectorDryRun(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockMgmt := new(MockManagementService)
+	mockMgmt.On("PatchMachineLabels", mock.MatchedBy(client.IsDryRun), mock.Anything, mock.Anything).Return(&client.Change{Action: client.ChangeUpdate}, nil)
+
+	handler := NewBulkHandler(newAllocationState(), mockMgmt, new(MockTalosService), operations.NewManager(operations.Config{}), middleware.AccessConfig{}, nil, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/bulk?dryRun=true", strings.NewReader(`{"operation":{"action":"patch-labels","labels":{"owner":"team-a"}},"selector":"rack=a","mode":"fail_fast"}`))
+
+	handler.RunBulk(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp BulkDryRunResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.DryRun)
+	assert.Equal(t, bulk.ModeFailFast, resp.Report.Mode)
+	require.Len(t, resp.Report.Items, 4)
+
+	targets := make([]string, 0, len(resp.Report.Items))
+	for _, item := range resp.Report.Items {
+		targets = append(targets, item.Target)
+		assert.Equal(t, "update", item.Result)
+	}
+	assert.Equal(t, []string{"m1", "m2", "m4", "m5"}, targets)
+	mockMgmt.AssertNumberOfCalls(t, "PatchMachineLabels", 4)
+}
+
+func TestBulkHandler_RunBulkErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"empty", `{}`},
+		{"operations and selector", `{"operations":[{"action":"reboot","target":"m1"}],"operation":{"action":"reboot"},"selector":"rack=a"}`},
+		{"operation without selector", `{"operation":{"action":"reboot"}}`},
+		{"unknown action", `{"operations":[{"action":"explode","target":"m1"}]}`},
+		{"unimplemented action", `{"operations":[{"action":"shutdown","target":"m1"}]}`},
+		{"missing target", `{"operations":[{"action":"reboot"}]}`},
+		{"missing labels", `{"operations":[{"action":"patch-labels","target":"m1"}]}`},
+		{"unexpected labels", `{"operations":[{"action":"reboot","target":"m1","labels":{"a":"b"}}]}`},
+		{"target with selector", `{"operation":{"action":"reboot","target":"m1"},"selector":"rack=a"}`},
+		{"invalid selector", `{"operation":{"action":"reboot"},"selector":"rack in"}`},
+		{"unknown mode", `{"operations":[{"action":"reboot","target":"m1"}],"mode":"stop"}`},
+		{"concurrency too high", `{"operations":[{"action":"reboot","target":"m1"}],"concurrency":100}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ops := operations.NewManager(operations.Config{})
+			handler := NewBulkHandler(newAllocationState(), new(MockManagementService), new(MockTalosService), ops, middleware.AccessConfig{}, nil, nil)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest("POST", "/bulk", strings.NewReader(tt.body))
+
+			handler.RunBulk(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+			assert.Empty(t, ops.List())
+		})
+	}
+}
+
+func TestBulkHandler_RunBulkGuards(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("disabled route group", func(t *testing.T) {
+		ops := operations.NewManager(operations.Config{})
+		access := middleware.AccessConfig{DisabledGroups: map[string]bool{middleware.GroupActions: true}}
+		handler := NewBulkHandler(newAllocationState(), new(MockManagementService), new(MockTalosService), ops, access, nil, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("POST", "/bulk", strings.NewReader(`{"operations":[{"action":"reboot","target":"m1"}]}`))
+
+		handler.RunBulk(c)
+
+		assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+		assert.Empty(t, ops.List())
+	})
+
+	t.Run("admission policy", func(t *testing.T) {
+		controller, err := admission.New([]admission.Policy{{
+			Name:       "keep-m1",
+			Message:    "m1 cannot be relabeled",
+			Routes:     []string{"/machines/{id}"},
+			Expression: `request.params.id != "m1" && request.body.labels.owner == "team-a"`,
+		}}, nil)
+		require.NoError(t, err)
+
+		mockMgmt := new(MockManagementService)
+		mockMgmt.On("PatchMachineLabels", mock.Anything, "m2", mock.Anything).Return(&client.Change{Action: client.ChangeUpdate}, nil)
+		handler := NewBulkHandler(newAllocationState(), mockMgmt, new(MockTalosService), operations.NewManager(operations.Config{}), middleware.AccessConfig{}, controller, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("POST", "/bulk?dryRun=true", strings.NewReader(`{"operations":[{"action":"patch-labels","target":"m1","labels":{"owner":"team-a"}},{"action":"patch-labels","target":"m2","labels":{"owner":"team-a"}}]}`))
+
+		handler.RunBulk(c)
+
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp BulkDryRunResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, bulk.ItemFailed, resp.Report.Items[0].Status)
+		assert.Equal(t, "denied by admission policy keep-m1: m1 cannot be relabeled", resp.Report.Items[0].Error)
+		assert.Equal(t, bulk.ItemSucceeded, resp.Report.Items[1].Status)
+		mockMgmt.AssertNotCalled(t, "PatchMachineLabels", mock.Anything, "m1", mock.Anything)
+	})
+}
+
+func TestBulkHandler_RunBulkRecordsDeletedConfigPatches(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	patch := newConfigPatch(t, "400-stale", "machine:\n  network:\n    hostname: worker-1\n", map[string]string{omni.LabelCluster: "prod"})
+	mockMgmt := new(MockManagementService)
+	mockMgmt.On("DeleteConfigPatch", mock.Anything, "400-stale").Return(&client.Change{Action: client.ChangeDestroy, Current: patch}, nil)
+
+	history, err := patchhistory.New(patchhistory.Config{})
+	require.NoError(t, err)
+	ops := operations.NewManager(operations.Config{})
+	handler := NewBulkHandler(new(MockState), mockMgmt, new(MockTalosService), ops, middleware.AccessConfig{}, nil, history)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/bulk", strings.NewReader(`{"operations":[{"action":"delete-config-patch","target":"400-stale"}]}`))
+
+	handler.RunBulk(c)
+
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	op, ok := ops.Wait(context.Background(), resp["operation_id"].(string))
+	require.True(t, ok)
+	require.Equal(t, operations.StatusSucceeded, op.Status, op.Error)
+
+	latest, ok := history.Latest("400-stale")
+	require.True(t, ok)
+	assert.Equal(t, patchhistory.ActionDelete, latest.Action)
+	assert.Equal(t, "prod", latest.Cluster)
+}
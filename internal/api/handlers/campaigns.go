@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/campaign"
+)
+
+// CampaignResponse represents an upgrade campaign returned by the API
+type CampaignResponse struct {
+	campaign.Campaign
+	DryRun bool              `json:"dry_run,omitempty"`
+	Links  map[string]string `json:"_links,omitempty"`
+}
+
+// CampaignListResponse represents the list of upgrade campaigns
+type CampaignListResponse struct {
+	Items []CampaignResponse `json:"items"`
+}
+
+// CampaignHandler handles fleet upgrade campaign requests
+type CampaignHandler struct {
+	campaigns *campaign.Manager
+}
+
+// NewCampaignHandler creates a new CampaignHandler
+func NewCampaignHandler(campaigns *campaign.Manager) *CampaignHandler {
+	return &CampaignHandler{campaigns: campaigns}
+}
+
+// ListCampaigns godoc
+// @Summary      List upgrade campaigns
+// @Description  List fleet upgrade campaigns, newest first
+// @Tags         campaigns
+// @Produce      json
+// @Success      200  {object}  CampaignListResponse
+// @Router       /campaigns [get]
+func (h *CampaignHandler) ListCampaigns(c *gin.Context) {
+	resp := CampaignListResponse{Items: []CampaignResponse{}}
+	for _, item := range h.campaigns.List() {
+		resp.Items = append(resp.Items, campaignResponse(c, item))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// CreateCampaign godoc
+// @Summary      Start an upgrade campaign
+// @Description  Upgrade the clusters matching a label selector to a target Talos and/or Kubernetes version in waves: the canary cluster alone, then each share of the fleet in waves (10% by default), then the rest.
+// @Description  Every cluster follows its upgrade plan one step at a time and must be healthy (ClusterStatus ready, control plane conditions and Kubernetes nodes ready) before each step and after the last one.
+// @Description  A failed step or health gate pauses the campaign. With dryRun=true the waves are returned without starting the campaign.
+// @Tags         campaigns
+// @Accept       json
+// @Produce      json
+// @Param        campaign  body      campaign.Spec  true   "Target versions, cluster selector, canary and waves"
+// @Param        dryRun    query     bool           false  "Return the planned waves without starting the campaign"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200  {object}  CampaignResponse
+// @Success      202  {object}  CampaignResponse
+// @Failure      400  {object}  map[string]string  "Invalid spec, or a selected cluster has no upgrade path to the target versions"
+// @Failure      409  {object}  map[string]string  "Campaigns are not enabled, OMNI_API_CAMPAIGN_FILE is not set"
+// @Failure      500  {object}  map[string]string
+// @Router       /campaigns [post]
+func (h *CampaignHandler) CreateCampaign(c *gin.Context) {
+	var spec campaign.Spec
+	if err := c.ShouldBindJSON(&spec); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if isDryRun(c) {
+		planned, err := h.campaigns.Plan(c.Request.Context(), spec)
+		if err != nil {
+			handleCampaignError(c, err)
+			return
+		}
+		resp := campaignResponse(c, planned)
+		resp.DryRun = true
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	created, err := h.campaigns.Create(c.Request.Context(), spec)
+	if err != nil {
+		handleCampaignError(c, err)
+		return
+	}
+
+	resp := campaignResponse(c, created)
+	c.Header("Location", resp.Links["self"])
+	c.JSON(http.StatusAccepted, resp)
+}
+
+// GetCampaign godoc
+// @Summary      Get an upgrade campaign
+// @Description  Get the status of an upgrade campaign with the wave, step and health gate progress of each cluster
+// @Tags         campaigns
+// @Produce      json
+// @Param        id   path      string  true  "Campaign ID"
+// @Success      200  {object}  CampaignResponse
+// @Failure      404  {object}  map[string]string
+// @Router       /campaigns/{id} [get]
+func (h *CampaignHandler) GetCampaign(c *gin.Context) {
+	item, ok := h.campaigns.Get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": campaign.ErrNotFound.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, campaignResponse(c, item))
+}
+
+// PauseCampaign godoc
+// @Summary      Pause an upgrade campaign
+// @Description  Stop starting further upgrade steps. Steps already sent to Omni complete; the interrupted clusters continue when the campaign is resumed.
+// @Tags         campaigns
+// @Produce      json
+// @Param        id   path      string  true  "Campaign ID"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200  {object}  CampaignResponse
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /campaigns/{id}/actions/pause [post]
+func (h *CampaignHandler) PauseCampaign(c *gin.Context) {
+	h.respond(c, h.campaigns.Pause)
+}
+
+// ResumeCampaign godoc
+// @Summary      Resume an upgrade campaign
+// @Description  Continue a paused campaign; failed clusters retry their failed step
+// @Tags         campaigns
+// @Produce      json
+// @Param        id   path      string  true  "Campaign ID"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200  {object}  CampaignResponse
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /campaigns/{id}/actions/resume [post]
+func (h *CampaignHandler) ResumeCampaign(c *gin.Context) {
+	h.respond(c, h.campaigns.Resume)
+}
+
+// AbortCampaign godoc
+// @Summary      Abort an upgrade campaign
+// @Description  Stop a running or paused campaign for good. Upgraded clusters are not rolled back.
+// @Tags         campaigns
+// @Produce      json
+// @Param        id   path      string  true  "Campaign ID"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200  {object}  CampaignResponse
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /campaigns/{id}/actions/abort [post]
+func (h *CampaignHandler) AbortCampaign(c *gin.Context) {
+	h.respond(c, h.campaigns.Abort)
+}
+
+// respond changes the status of the campaign in the path and writes the result
+func (h *CampaignHandler) respond(c *gin.Context, change func(id string) (campaign.Campaign, error)) {
+	item, err := change(c.Param("id"))
+	if err != nil {
+		handleCampaignError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, campaignResponse(c, item))
+}
+
+// handleCampaignError maps campaign errors to HTTP status codes
+func handleCampaignError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, campaign.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, campaign.ErrInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, campaign.ErrConflict), errors.Is(err, campaign.ErrDisabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Error handling upgrade campaign: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func campaignResponse(c *gin.Context, item campaign.Campaign) CampaignResponse {
+	resp := CampaignResponse{Campaign: item}
+	if item.ID == "" {
+		return resp
+	}
+
+	self := "/api/v1/campaigns/" + item.ID
+	resp.Links = map[string]string{"self": buildURL(c, self)}
+	switch item.Status {
+	case campaign.StatusRunning:
+		resp.Links["pause"] = buildURL(c, self+"/actions/pause")
+		resp.Links["abort"] = buildURL(c, self+"/actions/abort")
+	case campaign.StatusPaused:
+		resp.Links["resume"] = buildURL(c, self+"/actions/resume")
+		resp.Links["abort"] = buildURL(c, self+"/actions/abort")
+	}
+	return resp
+}
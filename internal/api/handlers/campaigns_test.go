@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/campaign"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newCampaignRouter serves campaigns over the prod-1, prod-2 and staging clusters, running Talos 1.8.0 and Kubernetes 1.30.1.
+// The manager is started with a canceled context, so campaigns never upgrade anything.
+func newCampaignRouter(t *testing.T) *gin.Engine {
+	return newCampaignRouterWithFile(t, filepath.Join(t.TempDir(), "campaigns.json"))
+}
+
+func newCampaignRouterWithFile(t *testing.T, file string) *gin.Engine {
+	clusters := resource.List{}
+	for id, env := range map[string]string{"prod-1": "prod", "prod-2": "prod", "staging": "staging"} {
+		cluster := omni.NewCluster("default", id)
+		cluster.Metadata().Labels().Set("env", env)
+		cluster.TypedSpec().Value.TalosVersion = "1.8.0"
+		cluster.TypedSpec().Value.KubernetesVersion = "1.30.1"
+		clusters.Items = append(clusters.Items, cluster)
+	}
+	talosVersions := resource.List{}
+	for _, version := range []string{"1.8.0", "1.9.1"} {
+		tv := omni.NewTalosVersion("default", version)
+		tv.TypedSpec().Value.CompatibleKubernetesVersions = []string{"1.30.1", "1.31.0"}
+		talosVersions.Items = append(talosVersions.Items, tv)
+	}
+	kubernetesVersions := resource.List{}
+	for _, version := range []string{"1.30.1", "1.31.0"} {
+		kv := omni.NewKubernetesVersion("default", version)
+		kv.TypedSpec().Value.Version = version
+		kubernetesVersions.Items = append(kubernetesVersions.Items, kv)
+	}
+
+	mockState := new(MockState)
+	mockState.On("List", mock.Anything, ofType(omni.ClusterType), mock.Anything).Return(clusters, nil)
+	mockState.On("List", mock.Anything, ofType(omni.TalosVersionType), mock.Anything).Return(talosVersions, nil)
+	mockState.On("List", mock.Anything, ofType(omni.KubernetesVersionType), mock.Anything).Return(kubernetesVersions, nil)
+	mockState.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(nil, context.Canceled)
+
+	campaigns, err := campaign.NewManager(campaign.Config{File: file, HealthTimeout: time.Minute, PollInterval: time.Minute}, mockState, new(MockManagementService))
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	campaigns.Start(ctx)
+
+	handler := NewCampaignHandler(campaigns)
+	r := gin.New()
+	r.GET("/campaigns", handler.ListCampaigns)
+	r.POST("/campaigns", handler.CreateCampaign)
+	r.GET("/campaigns/:id", handler.GetCampaign)
+	r.POST("/campaigns/:id/actions/pause", handler.PauseCampaign)
+	r.POST("/campaigns/:id/actions/resume", handler.ResumeCampaign)
+	r.POST("/campaigns/:id/actions/abort", handler.AbortCampaign)
+	return r
+}
+
+func serveCampaign(t *testing.T, r *gin.Engine, method, path, body string) (*httptest.ResponseRecorder, CampaignResponse) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	r.ServeHTTP(w, req)
+
+	var resp CampaignResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+// waitStopped waits until the runner of a campaign gave up on its canary
+func waitStopped(t *testing.T, r *gin.Engine, id string) {
+	require.Eventually(t, func() bool {
+		_, resp := serveCampaign(t, r, "GET", "/campaigns/"+id, "")
+		return resp.Clusters[0].Status == campaign.ClusterPending && !resp.Clusters[0].StartedAt.IsZero()
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestCampaignHandler_DryRun(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := newCampaignRouter(t)
+
+	w, resp := serveCampaign(t, r, "POST", "/campaigns?dryRun=true", `{"talos_version":"v1.9.1","selector":"env=prod","canary":"prod-2"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, resp.DryRun)
+	assert.Empty(t, resp.ID)
+	assert.Empty(t, resp.Links)
+	assert.Equal(t, "1.9.1", resp.Spec.TalosVersion)
+	assert.Equal(t, 2, resp.Waves)
+	require.Len(t, resp.Clusters, 2)
+	assert.Equal(t, "prod-2", resp.Clusters[0].ID)
+	assert.Equal(t, "prod-1", resp.Clusters[1].ID)
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/campaigns", nil)
+	r.ServeHTTP(w, req)
+	assert.JSONEq(t, `{"items":[]}`, w.Body.String(), "dry runs are not stored")
+}
+
+func TestCampaignHandler_Lifecycle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := newCampaignRouter(t)
+
+	w, created := serveCampaign(t, r, "POST", "/campaigns", `{"kubernetes_version":"1.31.0"}`)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, campaign.StatusRunning, created.Status)
+	assert.Equal(t, "http://localhost:8080/api/v1/campaigns/"+created.ID, w.Header().Get("Location"))
+	assert.Equal(t, "http://localhost:8080/api/v1/campaigns/"+created.ID+"/actions/pause", created.Links["pause"])
+	assert.Len(t, created.Clusters, 3)
+	waitStopped(t, r, created.ID)
+
+	w, resp := serveCampaign(t, r, "POST", "/campaigns/"+created.ID+"/actions/resume", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w, resp = serveCampaign(t, r, "POST", "/campaigns/"+created.ID+"/actions/pause", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, campaign.StatusPaused, resp.Status)
+	assert.Contains(t, resp.Links, "resume")
+	assert.NotContains(t, resp.Links, "pause")
+
+	w, resp = serveCampaign(t, r, "POST", "/campaigns/"+created.ID+"/actions/abort", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, campaign.StatusAborted, resp.Status)
+	assert.Equal(t, map[string]string{"self": "http://localhost:8080/api/v1/campaigns/" + created.ID}, resp.Links)
+
+	w, _ = serveCampaign(t, r, "POST", "/campaigns/"+created.ID+"/actions/pause", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/campaigns", nil)
+	r.ServeHTTP(w, req)
+	var list CampaignListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.Items, 1)
+	assert.Equal(t, created.ID, list.Items[0].ID)
+}
+
+func TestCampaignHandler_Errors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := newCampaignRouter(t)
+
+	for name, tc := range map[string]struct {
+		method, path, body string
+		code               int
+	}{
+		"invalid body":         {"POST", "/campaigns", `{"waves":"10"}`, http.StatusBadRequest},
+		"no target version":    {"POST", "/campaigns", `{"selector":"env=prod"}`, http.StatusBadRequest},
+		"no matching clusters": {"POST", "/campaigns", `{"talos_version":"1.9.1","selector":"env=dev"}`, http.StatusBadRequest},
+		"no upgrade path":      {"POST", "/campaigns", `{"talos_version":"1.7.0"}`, http.StatusBadRequest},
+		"unknown campaign":     {"GET", "/campaigns/uc-unknown", "", http.StatusNotFound},
+		"abort unknown":        {"POST", "/campaigns/uc-unknown/actions/abort", "", http.StatusNotFound},
+	} {
+		w, _ := serveCampaign(t, r, tc.method, tc.path, tc.body)
+		assert.Equal(t, tc.code, w.Code, name)
+		assert.Contains(t, w.Body.String(), `"error"`, name)
+	}
+}
+
+func TestCampaignHandler_Disabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := newCampaignRouterWithFile(t, "")
+
+	w, _ := serveCampaign(t, r, "POST", "/campaigns", `{"talos_version":"1.9.1"}`)
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "OMNI_API_CAMPAIGN_FILE")
+
+	w, _ = serveCampaign(t, r, "POST", "/campaigns?dryRun=true", `{"talos_version":"1.9.1"}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
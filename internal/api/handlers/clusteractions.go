@@ -1,10 +1,16 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/state"
 	"github.com/gin-gonic/gin"
 	"github.com/jubblin/omni-api/internal/client"
+	"github.com/jubblin/omni-api/internal/operations"
+	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
 )
 
 // ClusterActionRequest represents a request for cluster actions
@@ -14,20 +20,39 @@ type ClusterActionRequest struct {
 
 // ClusterActionsHandler handles cluster action operations
 type ClusterActionsHandler struct {
-	state      interface{} // State interface
+	state      state.State
 	management client.ManagementService // Management service
 	talos      client.TalosService      // Talos service
+	operations *operations.Manager      // Tracks asynchronous actions
 }
 
 // NewClusterActionsHandler creates a new ClusterActionsHandler
-func NewClusterActionsHandler(state interface{}, mgmt client.ManagementService, talos client.TalosService) *ClusterActionsHandler {
+func NewClusterActionsHandler(s state.State, mgmt client.ManagementService, talos client.TalosService, ops *operations.Manager) *ClusterActionsHandler {
 	return &ClusterActionsHandler{
-		state:      state,
+		state:      s,
 		management: mgmt,
 		talos:      talos,
+		operations: ops,
 	}
 }
 
+// getCluster fetches a cluster, writing a 404 response if it does not exist
+func (h *ClusterActionsHandler) getCluster(c *gin.Context, id string) (*omni.Cluster, bool) {
+	md := resource.NewMetadata(omniresources.DefaultNamespace, omni.ClusterType, id, resource.VersionUndefined)
+	res, err := h.state.Get(c.Request.Context(), md)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "cluster not found"})
+		return nil, false
+	}
+
+	cl, ok := res.(*omni.Cluster)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error: unexpected resource type"})
+		return nil, false
+	}
+	return cl, true
+}
+
 // TriggerKubernetesUpgrade godoc
 // @Summary      Trigger Kubernetes upgrade
 // @Description  Trigger a Kubernetes version upgrade for a cluster
@@ -36,7 +61,10 @@ func NewClusterActionsHandler(state interface{}, mgmt client.ManagementService,
 // @Produce      json
 // @Param        id       path      string                true  "Cluster ID"
 // @Param        request  body      ClusterActionRequest  true  "Upgrade request"
-// @Success      202      {object}  map[string]string
+// @Param        dryRun   query     bool                  false  "Validate the request and return the change without applying it"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200      {object}  DryRunResponse  "Returned instead when dryRun is set"
+// @Success      202      {object}  map[string]string  "Location header points to the operation tracking the upgrade"
 // @Failure      400      {object}  map[string]string
 // @Failure      404      {object}  map[string]string
 // @Failure      500      {object}  map[string]string
@@ -49,18 +77,41 @@ func (h *ClusterActionsHandler) TriggerKubernetesUpgrade(c *gin.Context) {
 		return
 	}
 
+	cl, ok := h.getCluster(c, id)
+	if !ok {
+		return
+	}
+	previousVersion := cl.TypedSpec().Value.KubernetesVersion
+
 	// Trigger Kubernetes upgrade using Management service
-	err := h.management.UpgradeKubernetes(c.Request.Context(), id, req.Version)
+	change, err := h.management.UpgradeKubernetes(writeContext(c), id, req.Version)
 	if err != nil {
 		handleManagementError(c, err)
 		return
 	}
+	if isDryRun(c) {
+		respondDryRun(c, change)
+		return
+	}
 	
-	c.JSON(http.StatusAccepted, gin.H{
+	resp := gin.H{
 		"message": "Kubernetes upgrade initiated",
 		"cluster_id": id,
 		"version": req.Version,
-	})
+	}
+	startOperation(c, h.operations, operations.Spec{
+		Kind:       "kubernetes-upgrade",
+		Target:     id,
+		TargetPath: "/api/v1/clusters/" + id + "/kubernetes-upgrade",
+		Track:      operations.KubernetesUpgradeTracker(h.state, id, req.Version),
+		// Canceling reverts the cluster to the version it ran before
+		Cancel: func(ctx context.Context) error {
+			_, err := h.management.RevertKubernetesUpgrade(ctx, id, previousVersion)
+			return err
+		},
+	}, resp)
+
+	c.JSON(http.StatusAccepted, resp)
 }
 
 // TriggerTalosUpgrade godoc
@@ -71,7 +122,10 @@ func (h *ClusterActionsHandler) TriggerKubernetesUpgrade(c *gin.Context) {
 // @Produce      json
 // @Param        id       path      string                true  "Cluster ID"
 // @Param        request  body      ClusterActionRequest  true  "Upgrade request"
-// @Success      202      {object}  map[string]string
+// @Param        dryRun   query     bool                  false  "Validate the request and return the change without applying it"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200      {object}  DryRunResponse  "Returned instead when dryRun is set"
+// @Success      202      {object}  map[string]string  "Location header points to the operation tracking the upgrade"
 // @Failure      400      {object}  map[string]string
 // @Failure      404      {object}  map[string]string
 // @Failure      500      {object}  map[string]string
@@ -84,18 +138,41 @@ func (h *ClusterActionsHandler) TriggerTalosUpgrade(c *gin.Context) {
 		return
 	}
 
+	cl, ok := h.getCluster(c, id)
+	if !ok {
+		return
+	}
+	previousVersion := cl.TypedSpec().Value.TalosVersion
+
 	// Trigger Talos upgrade using Management service
-	err := h.management.UpgradeTalos(c.Request.Context(), id, req.Version)
+	change, err := h.management.UpgradeTalos(writeContext(c), id, req.Version)
 	if err != nil {
 		handleManagementError(c, err)
 		return
 	}
+	if isDryRun(c) {
+		respondDryRun(c, change)
+		return
+	}
 	
-	c.JSON(http.StatusAccepted, gin.H{
+	resp := gin.H{
 		"message": "Talos upgrade initiated",
 		"cluster_id": id,
 		"version": req.Version,
-	})
+	}
+	startOperation(c, h.operations, operations.Spec{
+		Kind:       "talos-upgrade",
+		Target:     id,
+		TargetPath: "/api/v1/clusters/" + id + "/talos-upgrade",
+		Track:      operations.TalosUpgradeTracker(h.state, id, req.Version),
+		// Canceling reverts the cluster to the version it ran before
+		Cancel: func(ctx context.Context) error {
+			_, err := h.management.RevertTalosUpgrade(ctx, id, previousVersion)
+			return err
+		},
+	}, resp)
+
+	c.JSON(http.StatusAccepted, resp)
 }
 
 // TriggerBootstrap godoc
@@ -103,8 +180,11 @@ func (h *ClusterActionsHandler) TriggerTalosUpgrade(c *gin.Context) {
 // @Description  Trigger bootstrap for a cluster
 // @Tags         clusters
 // @Produce      json
-// @Param        id   path      string  true  "Cluster ID"
-// @Success      202  {object}  map[string]string
+// @Param        id      path      string  true   "Cluster ID"
+// @Param        dryRun  query     bool    false  "Validate the request and return the change without applying it"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200      {object}  DryRunResponse  "Returned instead when dryRun is set"
+// @Success      202  {object}  map[string]string  "Location header points to the operation tracking the bootstrap"
 // @Failure      404  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /clusters/{id}/actions/bootstrap [post]
@@ -112,16 +192,28 @@ func (h *ClusterActionsHandler) TriggerBootstrap(c *gin.Context) {
 	id := c.Param("id")
 
 	// Trigger bootstrap using Management service
-	err := h.management.BootstrapCluster(c.Request.Context(), id)
+	change, err := h.management.BootstrapCluster(writeContext(c), id)
 	if err != nil {
 		handleManagementError(c, err)
 		return
 	}
+	if isDryRun(c) {
+		respondDryRun(c, change)
+		return
+	}
 	
-	c.JSON(http.StatusAccepted, gin.H{
+	resp := gin.H{
 		"message": "Bootstrap initiated",
 		"cluster_id": id,
-	})
+	}
+	startOperation(c, h.operations, operations.Spec{
+		Kind:       "bootstrap",
+		Target:     id,
+		TargetPath: "/api/v1/clusters/" + id + "/bootstrap",
+		Track:      operations.ClusterBootstrapTracker(h.state, id),
+	}, resp)
+
+	c.JSON(http.StatusAccepted, resp)
 }
 
 // TriggerDestroy godoc
@@ -129,8 +221,11 @@ func (h *ClusterActionsHandler) TriggerBootstrap(c *gin.Context) {
 // @Description  Trigger destruction/teardown of a cluster
 // @Tags         clusters
 // @Produce      json
-// @Param        id   path      string  true  "Cluster ID"
-// @Success      202  {object}  map[string]string
+// @Param        id      path      string  true   "Cluster ID"
+// @Param        dryRun  query     bool    false  "Validate the request and return the change without applying it"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200      {object}  DryRunResponse  "Returned instead when dryRun is set"
+// @Success      202  {object}  map[string]string  "Location header points to the operation tracking the destruction"
 // @Failure      404  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /clusters/{id}/actions/destroy [post]
@@ -138,14 +233,26 @@ func (h *ClusterActionsHandler) TriggerDestroy(c *gin.Context) {
 	id := c.Param("id")
 
 	// Trigger cluster destruction using Management service
-	err := h.management.DeleteCluster(c.Request.Context(), id)
+	change, err := h.management.DeleteCluster(writeContext(c), id)
 	if err != nil {
 		handleManagementError(c, err)
 		return
 	}
+	if isDryRun(c) {
+		respondDryRun(c, change)
+		return
+	}
 	
-	c.JSON(http.StatusAccepted, gin.H{
+	resp := gin.H{
 		"message": "Cluster destruction initiated",
 		"cluster_id": id,
-	})
+	}
+	startOperation(c, h.operations, operations.Spec{
+		Kind:       "cluster-destroy",
+		Target:     id,
+		TargetPath: "/api/v1/clusters/" + id + "/destroy-status",
+		Track:      operations.ClusterDestroyTracker(h.state, id),
+	}, resp)
+
+	c.JSON(http.StatusAccepted, resp)
 }
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/jubblin/omni-api/internal/operations"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterActionsHandler_CancelUpgrade(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cluster := omni.NewCluster("default", "prod")
+	cluster.TypedSpec().Value.TalosVersion = "1.8.0"
+	cluster.TypedSpec().Value.KubernetesVersion = "1.30.1"
+
+	tests := []struct {
+		name    string
+		path    string
+		upgrade string
+		revert  string
+		version string
+	}{
+		{"Talos", "/clusters/prod/actions/talos-upgrade", "UpgradeTalos", "RevertTalosUpgrade", "1.8.0"},
+		{"Kubernetes", "/clusters/prod/actions/kubernetes-upgrade", "UpgradeKubernetes", "RevertKubernetesUpgrade", "1.30.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockState := new(MockState)
+			mockState.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(cluster, nil)
+			// The upgrade never reports progress, so the operation runs until it is canceled
+			mockState.On("Watch", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+			mockMgmt := new(MockManagementService)
+			mockMgmt.On(tt.upgrade, mock.Anything, "prod", "1.99.0").Return(&client.Change{Action: client.ChangeUpdate}, nil)
+			mockMgmt.On(tt.revert, mock.Anything, "prod", tt.version).Return(&client.Change{Action: client.ChangeUpdate}, nil)
+
+			ops := operations.NewManager(operations.Config{})
+			handler := NewClusterActionsHandler(mockState, mockMgmt, new(MockTalosService), ops)
+			r := gin.New()
+			r.POST("/clusters/:id/actions/talos-upgrade", handler.TriggerTalosUpgrade)
+			r.POST("/clusters/:id/actions/kubernetes-upgrade", handler.TriggerKubernetesUpgrade)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", tt.path, bytes.NewBufferString(`{"version":"1.99.0"}`))
+			req.Header.Set("Content-Type", "application/json")
+			r.ServeHTTP(w, req)
+			require.Equal(t, http.StatusAccepted, w.Code)
+
+			var resp map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			op, err := ops.Cancel(context.Background(), resp["operation_id"].(string))
+			require.NoError(t, err)
+			assert.Equal(t, operations.StatusCanceled, op.Status)
+
+			// Canceling sets the version the cluster ran before the upgrade back
+			mockMgmt.AssertCalled(t, tt.revert, mock.Anything, "prod", tt.version)
+		})
+	}
+}
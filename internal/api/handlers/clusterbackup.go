@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/safe"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/jubblin/omni-api/internal/retention"
+	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+// ClusterBackupRequest represents the etcd backup configuration of a cluster in a request
+type ClusterBackupRequest struct {
+	Enabled   bool              `json:"enabled"`
+	Interval  string            `json:"interval,omitempty"`  // Go duration, e.g. 6h; required when enabled
+	Retention *retention.Policy `json:"retention,omitempty"` // Left unchanged when omitted; {} removes it
+}
+
+// ClusterBackupResponse represents the etcd backup configuration of a cluster
+type ClusterBackupResponse struct {
+	Cluster   string            `json:"cluster"`
+	Enabled   bool              `json:"enabled"`
+	Interval  string            `json:"interval,omitempty"`
+	Retention *retention.Policy `json:"retention,omitempty"`
+	Links     map[string]string `json:"_links,omitempty"`
+}
+
+// ClusterBackupRetentionResponse reports which etcd backups of a cluster its retention policy keeps
+type ClusterBackupRetentionResponse struct {
+	Cluster string `json:"cluster"`
+	retention.Report
+	Links map[string]string `json:"_links,omitempty"`
+}
+
+// ClusterBackupHandler handles the etcd backup configuration and retention of clusters
+type ClusterBackupHandler struct {
+	state      state.State
+	management client.ManagementService
+}
+
+// NewClusterBackupHandler creates a new ClusterBackupHandler
+func NewClusterBackupHandler(s state.State, mgmt client.ManagementService) *ClusterBackupHandler {
+	return &ClusterBackupHandler{
+		state:      s,
+		management: mgmt,
+	}
+}
+
+// toClusterBackup converts a request into the backup configuration written by the Management service
+func (req *ClusterBackupRequest) toClusterBackup() (*client.ClusterBackup, error) {
+	backup := &client.ClusterBackup{Enabled: req.Enabled, Retention: req.Retention}
+	if req.Interval != "" {
+		interval, err := time.ParseDuration(req.Interval)
+		if err != nil {
+			return nil, err
+		}
+		backup.Interval = interval
+	}
+	return backup, nil
+}
+
+// GetClusterBackup godoc
+// @Summary      Get the etcd backup configuration of a cluster
+// @Description  Get whether Omni takes etcd backups of a cluster, how often, and the retention policy this API evaluates them against
+// @Tags         clusters
+// @Produce      json
+// @Param        id   path      string  true  "Cluster ID"
+// @Success      200  {object}  ClusterBackupResponse
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /clusters/{id}/backup [get]
+func (h *ClusterBackupHandler) GetClusterBackup(c *gin.Context) {
+	id := c.Param("id")
+
+	cluster, ok := h.cluster(c, id)
+	if !ok {
+		return
+	}
+
+	resp, err := clusterBackupResponse(c, cluster)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// SetClusterBackup godoc
+// @Summary      Set the etcd backup configuration of a cluster
+// @Description  Enable or disable scheduled etcd backups of a cluster and set their interval, written to the cluster spec.
+// @Description  retention is kept in the omni-api/backup-retention annotation of the cluster; Omni itself keeps every backup.
+// @Tags         clusters
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                true   "Cluster ID"
+// @Param        request  body      ClusterBackupRequest  true   "Backup configuration"
+// @Param        dryRun   query     bool                  false  "Validate the request and return the change without applying it"
+// @Success      200      {object}  ClusterBackupResponse  "A DryRunResponse when dryRun is set"
+// @Failure      400      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      412      {object}  map[string]string  "Omni has no usable etcd backup store"
+// @Failure      500      {object}  map[string]string
+// @Router       /clusters/{id}/backup [put]
+func (h *ClusterBackupHandler) SetClusterBackup(c *gin.Context) {
+	id := c.Param("id")
+	var req ClusterBackupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	backup, err := req.toClusterBackup()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid backup interval: " + err.Error()})
+		return
+	}
+
+	change, err := h.management.SetClusterBackup(writeContext(c), id, backup)
+	if err != nil {
+		handleManagementError(c, err)
+		return
+	}
+	if isDryRun(c) {
+		respondDryRun(c, change)
+		return
+	}
+
+	cluster, ok := change.Desired.(*omni.Cluster)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error: unexpected resource type"})
+		return
+	}
+	resp, err := clusterBackupResponse(c, cluster)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetClusterBackupRetention godoc
+// @Summary      Evaluate the etcd backups of a cluster against a retention policy
+// @Description  Report for every etcd backup of a cluster whether the retention policy keeps it, and by which rules.
+// @Description  Backups no rule keeps violate the policy and can be pruned; gaps are days and weeks the policy covers without a backup.
+// @Description  The policy is the one configured on the cluster; keep_last, keep_daily and keep_weekly override it.
+// @Tags         clusters
+// @Produce      json
+// @Param        id           path      string  true   "Cluster ID"
+// @Param        keep_last    query     int     false  "Keep the newest N backups"
+// @Param        keep_daily   query     int     false  "Keep the newest backup of the last N days with backups"
+// @Param        keep_weekly  query     int     false  "Keep the newest backup of the last N weeks with backups"
+// @Success      200  {object}  ClusterBackupRetentionResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /clusters/{id}/backup/retention [get]
+func (h *ClusterBackupHandler) GetClusterBackupRetention(c *gin.Context) {
+	id := c.Param("id")
+
+	cluster, ok := h.cluster(c, id)
+	if !ok {
+		return
+	}
+
+	policy, _, err := client.ClusterBackupRetention(cluster)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for param, count := range map[string]*int{"keep_last": &policy.KeepLast, "keep_daily": &policy.KeepDaily, "keep_weekly": &policy.KeepWeekly} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + param + ": " + value})
+			return
+		}
+		*count = n
+	}
+	if err := policy.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	items, err := h.state.List(c.Request.Context(),
+		resource.NewMetadata(omniresources.ExternalNamespace, omni.EtcdBackupType, "", resource.VersionUndefined),
+		state.WithLabelQuery(resource.LabelEqual(omni.LabelCluster, id)),
+	)
+	if err != nil {
+		log.Printf("Error listing etcd backups of cluster %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	backups := make([]retention.Backup, 0, len(items.Items))
+	for _, item := range items.Items {
+		eb, ok := item.(*omni.EtcdBackup)
+		if !ok {
+			continue
+		}
+		backup := retention.Backup{ID: eb.Metadata().ID()}
+		if createdAt := eb.TypedSpec().Value.CreatedAt; createdAt != nil {
+			backup.CreatedAt = createdAt.AsTime()
+		}
+		backups = append(backups, backup)
+	}
+
+	c.JSON(http.StatusOK, ClusterBackupRetentionResponse{
+		Cluster: id,
+		Report:  policy.Evaluate(backups, time.Now()),
+		Links: map[string]string{
+			"self":    buildURL(c, "/api/v1/clusters/"+id+"/backup/retention"),
+			"backup":  buildURL(c, "/api/v1/clusters/"+id+"/backup"),
+			"backups": buildURL(c, "/api/v1/etcdbackups?cluster="+id),
+		},
+	})
+}
+
+// cluster gets a cluster, writing the error response if that fails
+func (h *ClusterBackupHandler) cluster(c *gin.Context, id string) (*omni.Cluster, bool) {
+	cluster, err := safe.StateGet[*omni.Cluster](c.Request.Context(), h.state, omni.NewCluster(omniresources.DefaultNamespace, id).Metadata())
+	if err != nil {
+		if state.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "cluster not found"})
+			return nil, false
+		}
+		log.Printf("Error getting cluster %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return cluster, true
+}
+
+func clusterBackupResponse(c *gin.Context, cluster *omni.Cluster) (ClusterBackupResponse, error) {
+	id := cluster.Metadata().ID()
+	resp := ClusterBackupResponse{
+		Cluster: id,
+		Links: map[string]string{
+			"self":      buildURL(c, "/api/v1/clusters/"+id+"/backup"),
+			"cluster":   buildURL(c, "/api/v1/clusters/"+id),
+			"retention": buildURL(c, "/api/v1/clusters/"+id+"/backup/retention"),
+			"status":    buildURL(c, "/api/v1/etcdbackups/"+id+"/status"),
+		},
+	}
+
+	if conf := cluster.TypedSpec().Value.GetBackupConfiguration(); conf != nil {
+		resp.Enabled = conf.Enabled
+		if conf.Interval != nil {
+			resp.Interval = conf.Interval.AsDuration().String()
+		}
+	}
+
+	policy, ok, err := client.ClusterBackupRetention(cluster)
+	if err != nil {
+		return resp, err
+	}
+	if ok {
+		resp.Retention = &policy
+	}
+	return resp, nil
+}
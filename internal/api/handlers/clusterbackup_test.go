@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/state/impl/inmem"
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/jubblin/omni-api/internal/retention"
+	"github.com/siderolabs/omni/client/api/omni/specs"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func backupCluster(retentionPolicy string) *omni.Cluster {
+	cluster := omni.NewCluster("default", "prod")
+	cluster.TypedSpec().Value.BackupConfiguration = &specs.EtcdBackupConf{Enabled: true, Interval: durationpb.New(6 * time.Hour)}
+	if retentionPolicy != "" {
+		cluster.Metadata().Annotations().Set(client.BackupRetentionAnnotation, retentionPolicy)
+	}
+	return cluster
+}
+
+func TestClusterBackupHandler_GetClusterBackup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockState := new(MockState)
+	mockState.On("Get", mock.Anything, ofType(omni.ClusterType), mock.Anything).Return(backupCluster("last=3,weekly=4"), nil)
+
+	handler := NewClusterBackupHandler(mockState, new(MockManagementService))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: "prod"}}
+	c.Request, _ = http.NewRequest("GET", "/clusters/prod/backup", nil)
+
+	handler.GetClusterBackup(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp ClusterBackupResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Enabled)
+	assert.Equal(t, "6h0m0s", resp.Interval)
+	assert.Equal(t, &retention.Policy{KeepLast: 3, KeepWeekly: 4}, resp.Retention)
+}
+
+func TestClusterBackupHandler_GetClusterBackupNotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockState := new(MockState)
+	mockState.On("Get", mock.Anything, ofType(omni.ClusterType), mock.Anything).Return(nil, inmem.ErrNotFound(omni.NewCluster("default", "prod").Metadata()))
+
+	handler := NewClusterBackupHandler(mockState, new(MockManagementService))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: "prod"}}
+	c.Request, _ = http.NewRequest("GET", "/clusters/prod/backup", nil)
+
+	handler.GetClusterBackup(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestClusterBackupHandler_SetClusterBackup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockMgmt := new(MockManagementService)
+	mockMgmt.On("SetClusterBackup", mock.Anything, "prod", &client.ClusterBackup{
+		Enabled:   true,
+		Interval:  6 * time.Hour,
+		Retention: &retention.Policy{KeepDaily: 7},
+	}).Return(&client.Change{Action: client.ChangeUpdate, Desired: backupCluster("daily=7")}, nil)
+
+	handler := NewClusterBackupHandler(new(MockState), mockMgmt)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: "prod"}}
+	c.Request, _ = http.NewRequest("PUT", "/clusters/prod/backup", strings.NewReader(`{"enabled":true,"interval":"6h","retention":{"keep_daily":7}}`))
+
+	handler.SetClusterBackup(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp ClusterBackupResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Enabled)
+	assert.Equal(t, &retention.Policy{KeepDaily: 7}, resp.Retention)
+	mockMgmt.AssertExpectations(t)
+}
+
+func TestClusterBackupHandler_SetClusterBackupErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		body       string
+		err        error
+		wantStatus int
+	}{
+		{"invalid interval", `{"enabled":true,"interval":"daily"}`, nil, http.StatusBadRequest},
+		{"missing interval", `{"enabled":true}`, status.Error(codes.InvalidArgument, "backup interval is required when backups are enabled"), http.StatusBadRequest},
+		{"no backup store", `{"enabled":true,"interval":"1h"}`, status.Error(codes.FailedPrecondition, "etcd backups are disabled in Omni, no backup store is configured"), http.StatusPreconditionFailed},
+		{"unknown cluster", `{"enabled":false}`, status.Error(codes.NotFound, "cluster prod not found"), http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockMgmt := new(MockManagementService)
+			mockMgmt.On("SetClusterBackup", mock.Anything, "prod", mock.Anything).Return(nil, tt.err)
+			handler := NewClusterBackupHandler(new(MockState), mockMgmt)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = gin.Params{{Key: "id", Value: "prod"}}
+			c.Request, _ = http.NewRequest("PUT", "/clusters/prod/backup", strings.NewReader(tt.body))
+
+			handler.SetClusterBackup(c)
+
+			assert.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestClusterBackupHandler_GetClusterBackupRetention(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	now := time.Now().UTC()
+	var backups []resource.Resource
+	for i := range 5 {
+		createdAt := now.Add(-time.Duration(i) * time.Hour)
+		backup := omni.NewEtcdBackup("prod", createdAt)
+		backup.TypedSpec().Value.CreatedAt = timestamppb.New(createdAt)
+		backups = append(backups, backup)
+	}
+
+	mockState := new(MockState)
+	mockState.On("Get", mock.Anything, ofType(omni.ClusterType), mock.Anything).Return(backupCluster("last=3"), nil)
+	mockState.On("List", mock.Anything, ofType(omni.EtcdBackupType), mock.Anything).Return(resource.List{Items: backups}, nil)
+
+	handler := NewClusterBackupHandler(mockState, new(MockManagementService))
+
+	t.Run("cluster policy", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "id", Value: "prod"}}
+		c.Request, _ = http.NewRequest("GET", "/clusters/prod/backup/retention", nil)
+
+		handler.GetClusterBackupRetention(c)
+
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp ClusterBackupRetentionResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "prod", resp.Cluster)
+		assert.Equal(t, retention.Policy{KeepLast: 3}, resp.Policy)
+		assert.Equal(t, 5, resp.Total)
+		assert.Equal(t, 3, resp.Kept)
+		assert.Equal(t, 2, resp.Violations)
+	})
+
+	t.Run("query overrides", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "id", Value: "prod"}}
+		c.Request, _ = http.NewRequest("GET", "/clusters/prod/backup/retention?keep_last=5", nil)
+
+		handler.GetClusterBackupRetention(c)
+
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp ClusterBackupRetentionResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Zero(t, resp.Violations)
+	})
+
+	for _, query := range []string{"keep_last=x", "keep_last=0"} {
+		t.Run(query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = gin.Params{{Key: "id", Value: "prod"}}
+			c.Request, _ = http.NewRequest("GET", "/clusters/prod/backup/retention?"+query, nil)
+
+			handler.GetClusterBackupRetention(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		})
+	}
+}
@@ -100,6 +100,7 @@ func (h *ClusterHandler) ListClusters(c *gin.Context) {
 				"status":    buildURL(c, "/api/v1/clusters/"+clusterID+"/status"),
 				"metrics":   buildURL(c, "/api/v1/clusters/"+clusterID+"/metrics"),
 				"bootstrap": buildURL(c, "/api/v1/clusters/"+clusterID+"/bootstrap"),
+				"backup":    buildURL(c, "/api/v1/clusters/"+clusterID+"/backup"),
 				"machines":  buildURL(c, "/api/v1/machines?cluster="+clusterID),
 			},
 		}
@@ -155,6 +156,7 @@ func (h *ClusterHandler) GetCluster(c *gin.Context) {
 			"status":    buildURL(c, "/api/v1/clusters/"+clusterID+"/status"),
 			"metrics":   buildURL(c, "/api/v1/clusters/"+clusterID+"/metrics"),
 			"bootstrap": buildURL(c, "/api/v1/clusters/"+clusterID+"/bootstrap"),
+			"backup":    buildURL(c, "/api/v1/clusters/"+clusterID+"/backup"),
 			"machines":  buildURL(c, "/api/v1/machines?cluster="+clusterID),
 		},
 	}
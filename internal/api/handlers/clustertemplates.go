@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/jubblin/omni-api/internal/diff"
+	"github.com/jubblin/omni-api/internal/operations"
+	"github.com/jubblin/omni-api/internal/patchhistory"
+)
+
+// maxTemplateSize bounds the size of cluster template request bodies
+const maxTemplateSize = 1 << 20
+
+// TemplateValidationResponse represents the result of validating a cluster template
+type TemplateValidationResponse struct {
+	Valid   bool     `json:"valid"`
+	Cluster string   `json:"cluster,omitempty"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// TemplateChangeResponse represents a single change of a cluster template plan
+type TemplateChangeResponse struct {
+	Action string `json:"action"`
+	Type   string `json:"type"`
+	ID     string `json:"id"`
+	Phase  int    `json:"phase,omitempty"` // Prune phase of destructions, starting at 1
+	Diff   string `json:"diff,omitempty"`  // Unified diff against the current state
+}
+
+// TemplatePlanResponse represents the changes that reconcile a cluster with a template
+type TemplatePlanResponse struct {
+	Cluster     string                   `json:"cluster"`
+	DryRun      bool                     `json:"dry_run,omitempty"`
+	Summary     map[string]int           `json:"summary"` // Number of changes per action
+	Changes     []TemplateChangeResponse `json:"changes"`
+	OperationID string                   `json:"operation_id,omitempty"` // Operation tracking the pruning
+	Links       map[string]string        `json:"_links,omitempty"`
+}
+
+// ClusterTemplateHandler handles cluster template requests
+type ClusterTemplateHandler struct {
+	templates  client.TemplateService
+	operations *operations.Manager
+	history    *patchhistory.History // Records the config patches templates write
+}
+
+// NewClusterTemplateHandler creates a new ClusterTemplateHandler
+func NewClusterTemplateHandler(templates client.TemplateService, ops *operations.Manager, history *patchhistory.History) *ClusterTemplateHandler {
+	return &ClusterTemplateHandler{templates: templates, operations: ops, history: history}
+}
+
+// ValidateTemplate godoc
+// @Summary      Validate a cluster template
+// @Description  Validate an omnictl cluster template (Cluster, ControlPlane, Workers and Machine documents) without comparing it to Omni.
+// @Description  Patches must be inline; patch files are not supported.
+// @Tags         cluster-templates
+// @Accept       application/yaml
+// @Produce      json
+// @Param        template  body      string  true  "Cluster template YAML"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200  {object}  TemplateValidationResponse
+// @Failure      400  {object}  map[string]string
+// @Router       /cluster-templates:validate [post]
+func (h *ClusterTemplateHandler) ValidateTemplate(c *gin.Context) {
+	tmpl, ok := readTemplate(c)
+	if !ok {
+		return
+	}
+
+	cluster, err := h.templates.Validate(tmpl)
+	var templateErr *client.TemplateError
+	switch {
+	case errors.As(err, &templateErr):
+		c.JSON(http.StatusOK, TemplateValidationResponse{Errors: templateErr.Errors})
+	case err != nil:
+		handleManagementError(c, err)
+	default:
+		c.JSON(http.StatusOK, TemplateValidationResponse{Valid: true, Cluster: cluster})
+	}
+}
+
+// DiffTemplate godoc
+// @Summary      Diff a cluster template
+// @Description  Compare a cluster template with the resources in Omni and return the changes applying it would make, including the resources it would prune.
+// @Tags         cluster-templates
+// @Accept       application/yaml
+// @Produce      json
+// @Param        template  body      string  true  "Cluster template YAML"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200  {object}  TemplatePlanResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      412  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /cluster-templates:diff [post]
+func (h *ClusterTemplateHandler) DiffTemplate(c *gin.Context) {
+	tmpl, ok := readTemplate(c)
+	if !ok {
+		return
+	}
+
+	plan, err := h.templates.Plan(c.Request.Context(), tmpl)
+	if err != nil {
+		handleTemplateError(c, err)
+		return
+	}
+
+	resp, err := templatePlanResponse(plan)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ApplyTemplate godoc
+// @Summary      Apply a cluster template
+// @Description  Reconcile the cluster, machine sets, machine set nodes, config patches and labels in Omni with a cluster template, like omnictl cluster template sync.
+// @Description  Resources are created and updated right away. Resources of the cluster missing from the template are then torn down in the background (unless prune=false) and the response is 202 with a Location header pointing at the operation.
+// @Tags         cluster-templates
+// @Accept       application/yaml
+// @Produce      json
+// @Param        template  body      string  true   "Cluster template YAML"
+// @Param        prune     query     bool    false  "Destroy resources of the cluster that are missing from the template (default true)"
+// @Param        dryRun    query     bool    false  "Validate the request and return the changes without applying them"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200  {object}  TemplatePlanResponse  "Applied, or the planned changes when dryRun is set"
+// @Success      202  {object}  TemplatePlanResponse  "Applied; Location header points to the operation pruning removed resources"
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      412  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /cluster-templates:apply [post]
+func (h *ClusterTemplateHandler) ApplyTemplate(c *gin.Context) {
+	prune := true
+	if value := c.Query("prune"); value != "" {
+		var err error
+		if prune, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid prune value"})
+			return
+		}
+	}
+
+	tmpl, ok := readTemplate(c)
+	if !ok {
+		return
+	}
+
+	plan, err := h.templates.Plan(c.Request.Context(), tmpl)
+	if err != nil {
+		handleTemplateError(c, err)
+		return
+	}
+	if !prune {
+		plan.Prune = nil
+	}
+
+	resp, err := templatePlanResponse(plan)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if isDryRun(c) {
+		resp.DryRun = true
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	author := patchAuthor(c)
+	if err := h.templates.Apply(c.Request.Context(), plan); err != nil {
+		handleManagementError(c, err)
+		return
+	}
+	h.recordPatches(plan.Changes, author)
+
+	resp.Links = map[string]string{"cluster": buildURL(c, "/api/v1/clusters/"+plan.Cluster)}
+	if len(plan.Prune) == 0 {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	op := h.operations.Start(operations.Spec{
+		Kind:       "cluster-template-prune",
+		Target:     plan.Cluster,
+		TargetPath: "/api/v1/clusters/" + plan.Cluster,
+		Track: func(ctx context.Context, report func(operations.Progress)) error {
+			var started int
+			err := h.templates.Prune(ctx, plan, func(phase, total int) {
+				started = phase
+				report(operations.Progress{Phase: "Pruning", Message: fmt.Sprintf("phase %d of %d", phase, total)})
+			})
+
+			// Pruning stops at the first failing phase; only the phases before it are recorded
+			done := started
+			if err != nil && done > 0 {
+				done--
+			}
+			for _, phase := range plan.Prune[:done] {
+				h.recordPatches(phase, author)
+			}
+			return err
+		},
+	})
+	location := buildURL(c, "/api/v1/operations/"+op.ID)
+	c.Header("Location", location)
+	resp.OperationID = op.ID
+	resp.Links["operation"] = location
+
+	c.JSON(http.StatusAccepted, resp)
+}
+
+// ExportTemplate godoc
+// @Summary      Export a cluster as a template
+// @Description  Render an existing cluster, its machine sets, machines, config patches and extensions as an omnictl cluster template
+// @Tags         cluster-templates
+// @Produce      application/yaml
+// @Param        id        path      string  true   "Cluster ID"
+// @Param        download  query     bool    false  "Send the template as a file attachment"
+// @Success      200  {string}  string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /clusters/{id}/template [get]
+func (h *ClusterTemplateHandler) ExportTemplate(c *gin.Context) {
+	id := c.Param("id")
+
+	data, err := h.templates.Export(c.Request.Context(), id)
+	if err != nil {
+		handleManagementError(c, err)
+		return
+	}
+
+	if download, _ := strconv.ParseBool(c.Query("download")); download {
+		respondYAMLFile(c, id+"-template.yaml", data)
+		return
+	}
+	c.Data(http.StatusOK, gin.MIMEYAML2, data)
+}
+
+// recordPatches records the config patches a template wrote in the patch history
+func (h *ClusterTemplateHandler) recordPatches(changes []*client.Change, author string) {
+	if h.history == nil {
+		return
+	}
+	if err := h.history.RecordChanges(changes, author); err != nil {
+		log.Printf("Error recording config patch history: %v", err)
+	}
+}
+
+// readTemplate reads the template from the request body
+func readTemplate(c *gin.Context) ([]byte, bool) {
+	tmpl, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxTemplateSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("template exceeds %d bytes", maxTemplateSize)})
+			return nil, false
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if len(tmpl) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "template is required"})
+		return nil, false
+	}
+	return tmpl, true
+}
+
+// handleTemplateError reports template validation errors with their messages, and other errors like Management errors
+func handleTemplateError(c *gin.Context, err error) {
+	var templateErr *client.TemplateError
+	if errors.As(err, &templateErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "errors": templateErr.Errors})
+		return
+	}
+	handleManagementError(c, err)
+}
+
+func templatePlanResponse(plan *client.TemplatePlan) (TemplatePlanResponse, error) {
+	resp := TemplatePlanResponse{
+		Cluster: plan.Cluster,
+		Summary: map[string]int{
+			string(client.ChangeCreate):  0,
+			string(client.ChangeUpdate):  0,
+			string(client.ChangeDestroy): 0,
+		},
+		Changes: []TemplateChangeResponse{},
+	}
+
+	add := func(change *client.Change, phase int) error {
+		r := change.Desired
+		if r == nil {
+			r = change.Current
+		}
+		changeDiff, err := diff.Resources(change.Current, change.Desired)
+		if err != nil {
+			return err
+		}
+
+		resp.Summary[string(change.Action)]++
+		resp.Changes = append(resp.Changes, TemplateChangeResponse{
+			Action: string(change.Action),
+			Type:   r.Metadata().Type(),
+			ID:     r.Metadata().ID(),
+			Phase:  phase,
+			Diff:   changeDiff,
+		})
+		return nil
+	}
+
+	for _, change := range plan.Changes {
+		if err := add(change, 0); err != nil {
+			return resp, err
+		}
+	}
+	for i, phase := range plan.Prune {
+		for _, change := range phase {
+			if err := add(change, i+1); err != nil {
+				return resp, err
+			}
+		}
+	}
+	return resp, nil
+}
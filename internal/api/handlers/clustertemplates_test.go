@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/jubblin/omni-api/internal/operations"
+	"github.com/jubblin/omni-api/internal/patchhistory"
+	"github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const clusterTemplate = "kind: Cluster\nname: prod\n"
+
+func newClusterTemplateRouter(templates *MockTemplateService, ops *operations.Manager, history *patchhistory.History) *gin.Engine {
+	handler := NewClusterTemplateHandler(templates, ops, history)
+	r := gin.New()
+	// Routes are registered like in main; without Run, gin matches their escaped colon literally
+	r.POST("/cluster-templates\\:validate", handler.ValidateTemplate)
+	r.POST("/cluster-templates\\:diff", handler.DiffTemplate)
+	r.POST("/cluster-templates\\:apply", handler.ApplyTemplate)
+	r.GET("/clusters/:id/template", handler.ExportTemplate)
+	return r
+}
+
+func postTemplate(r *gin.Engine, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", strings.Replace(path, ":", "\\:", 1), strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/yaml")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// testTemplatePlan creates a machine set, updates the cluster and prunes a config patch
+func testTemplatePlan() *client.TemplatePlan {
+	current := omni.NewCluster(resources.DefaultNamespace, "prod")
+	current.TypedSpec().Value.KubernetesVersion = "1.30.1"
+	desired := omni.NewCluster(resources.DefaultNamespace, "prod")
+	desired.TypedSpec().Value.KubernetesVersion = "1.31.0"
+
+	return &client.TemplatePlan{
+		Cluster: "prod",
+		Changes: []*client.Change{
+			{Action: client.ChangeCreate, Desired: omni.NewMachineSet(resources.DefaultNamespace, "prod-workers")},
+			{Action: client.ChangeUpdate, Current: current, Desired: desired},
+		},
+		Prune: [][]*client.Change{
+			{{Action: client.ChangeDestroy, Current: omni.NewConfigPatch(resources.DefaultNamespace, "400-prod-old")}},
+		},
+	}
+}
+
+func TestClusterTemplateHandler_ValidateTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	templates := new(MockTemplateService)
+	templates.On("Validate", clusterTemplate).Return("prod", nil)
+	templates.On("Validate", "kind: Nodes\n").Return("", &client.TemplateError{Errors: []string{"unknown kind"}})
+	r := newClusterTemplateRouter(templates, nil, nil)
+
+	w := postTemplate(r, "/cluster-templates:validate", clusterTemplate)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"valid":true,"cluster":"prod"}`, w.Body.String())
+
+	w = postTemplate(r, "/cluster-templates:validate", "kind: Nodes\n")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"valid":false,"errors":["unknown kind"]}`, w.Body.String())
+
+	w = postTemplate(r, "/cluster-templates:validate", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = postTemplate(r, "/cluster-templates:sync", clusterTemplate)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cluster-templatesvalidate", strings.NewReader(clusterTemplate))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestClusterTemplateHandler_DiffTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	templates := new(MockTemplateService)
+	templates.On("Plan", mock.Anything, clusterTemplate).Return(testTemplatePlan(), nil)
+	r := newClusterTemplateRouter(templates, nil, nil)
+
+	w := postTemplate(r, "/cluster-templates:diff", clusterTemplate)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp TemplatePlanResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, map[string]int{"create": 1, "update": 1, "destroy": 1}, resp.Summary)
+	require.Len(t, resp.Changes, 3)
+	assert.Equal(t, "prod-workers", resp.Changes[0].ID)
+	assert.Contains(t, resp.Changes[1].Diff, "+    kubernetesversion: 1.31.0")
+	assert.Equal(t, 1, resp.Changes[2].Phase)
+	templates.AssertNotCalled(t, "Apply", mock.Anything, mock.Anything)
+}
+
+func TestClusterTemplateHandler_DiffTemplate_Errors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	templates := new(MockTemplateService)
+	templates.On("Plan", mock.Anything, "invalid").Return(nil, &client.TemplateError{Errors: []string{"a", "b"}})
+	templates.On("Plan", mock.Anything, "other-cluster").Return(nil, status.Error(codes.FailedPrecondition, "resource belongs to cluster \"dev\""))
+	r := newClusterTemplateRouter(templates, nil, nil)
+
+	w := postTemplate(r, "/cluster-templates:diff", "invalid")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"errors":["a","b"]`)
+
+	w = postTemplate(r, "/cluster-templates:diff", "other-cluster")
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+}
+
+func TestClusterTemplateHandler_ApplyTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("prunes in an operation", func(t *testing.T) {
+		ops := operations.NewManager(operations.Config{})
+		templates := new(MockTemplateService)
+		templates.On("Plan", mock.Anything, clusterTemplate).Return(testTemplatePlan(), nil)
+		templates.On("Apply", mock.Anything, mock.Anything).Return(nil)
+		templates.On("Prune", mock.Anything, mock.Anything).Return(nil)
+		history, err := patchhistory.New(patchhistory.Config{})
+		require.NoError(t, err)
+		r := newClusterTemplateRouter(templates, ops, history)
+
+		w := postTemplate(r, "/cluster-templates:apply", clusterTemplate)
+		require.Equal(t, http.StatusAccepted, w.Code)
+
+		var resp TemplatePlanResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.NotEmpty(t, resp.OperationID)
+		assert.Equal(t, "http://localhost:8080/api/v1/operations/"+resp.OperationID, w.Header().Get("Location"))
+
+		op, ok := ops.Wait(context.Background(), resp.OperationID)
+		require.True(t, ok)
+		assert.Equal(t, operations.StatusSucceeded, op.Status)
+		assert.Equal(t, "phase 1 of 1", op.Message)
+		templates.AssertCalled(t, "Apply", mock.Anything, mock.Anything)
+
+		// The pruned config patch is recorded in the patch history
+		revisions := history.Revisions("400-prod-old")
+		require.Len(t, revisions, 2)
+		assert.Equal(t, patchhistory.ActionObserved, revisions[0].Action)
+		assert.Equal(t, patchhistory.ActionDelete, revisions[1].Action)
+	})
+
+	t.Run("without pruning", func(t *testing.T) {
+		templates := new(MockTemplateService)
+		templates.On("Plan", mock.Anything, clusterTemplate).Return(testTemplatePlan(), nil)
+		templates.On("Apply", mock.Anything, mock.Anything).Return(nil)
+		r := newClusterTemplateRouter(templates, nil, nil)
+
+		w := postTemplate(r, "/cluster-templates:apply?prune=false", clusterTemplate)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp TemplatePlanResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, 0, resp.Summary["destroy"])
+		assert.Empty(t, resp.OperationID)
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		templates := new(MockTemplateService)
+		templates.On("Plan", mock.Anything, clusterTemplate).Return(testTemplatePlan(), nil)
+		r := newClusterTemplateRouter(templates, nil, nil)
+
+		w := postTemplate(r, "/cluster-templates:apply?dryRun=true", clusterTemplate)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"dry_run":true`)
+		templates.AssertNotCalled(t, "Apply", mock.Anything, mock.Anything)
+	})
+}
+
+func TestClusterTemplateHandler_ExportTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	templates := new(MockTemplateService)
+	templates.On("Export", mock.Anything, "prod").Return([]byte(clusterTemplate), nil)
+	templates.On("Export", mock.Anything, "missing").Return(nil, status.Error(codes.NotFound, "cluster not found"))
+	r := newClusterTemplateRouter(templates, nil, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/clusters/prod/template?download=true", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, gin.MIMEYAML2, w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="prod-template.yaml"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, clusterTemplate, w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/clusters/missing/template", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/safe"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/jubblin/omni-api/internal/upgradeplan"
+	"github.com/siderolabs/omni/client/api/omni/specs"
+	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+// Kinds of upgrade blockers
+const (
+	blockerNoPath             = "no_path"
+	blockerUpgradeInProgress  = "upgrade_in_progress"
+	blockerUpgradeFailed      = "upgrade_failed"
+	blockerVersionUnavailable = "version_unavailable"
+	blockerVersionSkew        = "version_skew"
+	blockerUnhealthyNodes     = "unhealthy_nodes"
+	blockerDeprecatedAPIs     = "deprecated_apis"
+	blockerPreChecksFailed    = "pre_checks_failed"
+)
+
+// UpgradePlanVersions represents the Talos and Kubernetes versions of a cluster
+type UpgradePlanVersions struct {
+	TalosVersion      string `json:"talos_version"`
+	KubernetesVersion string `json:"kubernetes_version"`
+}
+
+// UpgradePlanStep represents a single upgrade of an upgrade plan
+type UpgradePlanStep struct {
+	upgradeplan.Step
+	Action string `json:"action"` // Endpoint that runs the step
+}
+
+// UpgradeBlocker represents something that has to be resolved before an upgrade plan can be followed
+type UpgradeBlocker struct {
+	Kind    string   `json:"kind"`
+	Message string   `json:"message"`
+	Step    *int     `json:"step,omitempty"` // Index of the step the blocker applies to; unset if it blocks every step
+	Nodes   []string `json:"nodes,omitempty"`
+}
+
+// ClusterUpgradePlanResponse represents the upgrade plan of a cluster
+type ClusterUpgradePlanResponse struct {
+	Cluster  string              `json:"cluster"`
+	Current  UpgradePlanVersions `json:"current"`
+	Target   UpgradePlanVersions `json:"target"`
+	Steps    []UpgradePlanStep   `json:"steps"`
+	Blockers []UpgradeBlocker    `json:"blockers"`
+	Ready    bool                `json:"ready"` // The first step can run now
+	Links    map[string]string   `json:"_links,omitempty"`
+}
+
+// ClusterUpgradePlanHandler plans Talos and Kubernetes upgrades of clusters
+type ClusterUpgradePlanHandler struct {
+	state      state.State
+	management client.ManagementService
+}
+
+// NewClusterUpgradePlanHandler creates a new ClusterUpgradePlanHandler
+func NewClusterUpgradePlanHandler(s state.State, mgmt client.ManagementService) *ClusterUpgradePlanHandler {
+	return &ClusterUpgradePlanHandler{
+		state:      s,
+		management: mgmt,
+	}
+}
+
+// GetClusterUpgradePlan godoc
+// @Summary      Plan the Talos and Kubernetes upgrades of a cluster
+// @Description  Compute the ordered upgrade steps that move a cluster to the target versions, using the Talos versions Omni knows with their supported Kubernetes versions.
+// @Description  Talos moves to the newest patch of each following minor version and Kubernetes one minor version at a time; after every step Talos supports the running Kubernetes version.
+// @Description  The targets default to the newest Talos version that is not deprecated and the newest Kubernetes version it supports.
+// @Description  Blockers list what has to be resolved first: upgrades in progress or failed, version skew, unhealthy nodes and failed Kubernetes upgrade pre-checks such as removed APIs still in use.
+// @Tags         clusters
+// @Produce      json
+// @Param        id                  path      string  true   "Cluster ID"
+// @Param        talos_version       query     string  false  "Target Talos version"
+// @Param        kubernetes_version  query     string  false  "Target Kubernetes version"
+// @Success      200  {object}  ClusterUpgradePlanResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /clusters/{id}/upgrade-plan [get]
+func (h *ClusterUpgradePlanHandler) GetClusterUpgradePlan(c *gin.Context) {
+	id := c.Param("id")
+	ctx := c.Request.Context()
+
+	cluster, err := safe.StateGet[*omni.Cluster](ctx, h.state, omni.NewCluster(omniresources.DefaultNamespace, id).Metadata())
+	if err != nil {
+		if state.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "cluster not found"})
+			return
+		}
+		log.Printf("Error getting cluster %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	catalog, err := upgradeplan.LoadCatalog(ctx, h.state)
+	if err != nil {
+		log.Printf("Error listing Talos and Kubernetes versions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	spec := cluster.TypedSpec().Value
+	current := UpgradePlanVersions{TalosVersion: spec.TalosVersion, KubernetesVersion: spec.KubernetesVersion}
+	target := UpgradePlanVersions{
+		TalosVersion:      strings.TrimPrefix(c.Query("talos_version"), "v"),
+		KubernetesVersion: strings.TrimPrefix(c.Query("kubernetes_version"), "v"),
+	}
+	if target.TalosVersion == "" {
+		target.TalosVersion, _ = catalog.Latest()
+		if target.TalosVersion == "" || upgradeplan.Compare(current.TalosVersion, target.TalosVersion) > 0 {
+			target.TalosVersion = current.TalosVersion
+		}
+	}
+	if target.KubernetesVersion == "" {
+		target.KubernetesVersion = catalog.LatestKubernetes(target.TalosVersion)
+		if target.KubernetesVersion == "" || upgradeplan.Compare(current.KubernetesVersion, target.KubernetesVersion) > 0 {
+			target.KubernetesVersion = current.KubernetesVersion
+		}
+	}
+
+	resp := ClusterUpgradePlanResponse{
+		Cluster:  id,
+		Current:  current,
+		Target:   target,
+		Steps:    []UpgradePlanStep{},
+		Blockers: []UpgradeBlocker{},
+		Links: map[string]string{
+			"self":               buildURL(c, "/api/v1/clusters/"+id+"/upgrade-plan"),
+			"cluster":            buildURL(c, "/api/v1/clusters/"+id),
+			"talos-upgrade":      buildURL(c, "/api/v1/clusters/"+id+"/talos-upgrade"),
+			"kubernetes-upgrade": buildURL(c, "/api/v1/clusters/"+id+"/kubernetes-upgrade"),
+		},
+	}
+
+	steps, err := catalog.Plan(current.TalosVersion, current.KubernetesVersion, target.TalosVersion, target.KubernetesVersion)
+	if err != nil {
+		if !errors.Is(err, upgradeplan.ErrNoPath) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		resp.Blockers = append(resp.Blockers, UpgradeBlocker{Kind: blockerNoPath, Message: err.Error()})
+	}
+	for _, step := range steps {
+		resp.Steps = append(resp.Steps, UpgradePlanStep{
+			Step:   step,
+			Action: buildURL(c, "/api/v1/clusters/"+id+"/actions/"+step.Component+"-upgrade"),
+		})
+	}
+
+	blockers, err := h.blockers(ctx, cluster, steps)
+	if err != nil {
+		log.Printf("Error checking upgrade blockers of cluster %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	resp.Blockers = append(resp.Blockers, blockers...)
+	resp.Ready = len(resp.Steps) > 0 && !slices.ContainsFunc(resp.Blockers, func(blocker UpgradeBlocker) bool {
+		return blocker.Step == nil || *blocker.Step == 0
+	})
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// blockers checks the cluster for anything that keeps the steps from running
+func (h *ClusterUpgradePlanHandler) blockers(ctx context.Context, cluster *omni.Cluster, steps []upgradeplan.Step) ([]UpgradeBlocker, error) {
+	id := cluster.Metadata().ID()
+	spec := cluster.TypedSpec().Value
+	var blockers []UpgradeBlocker
+
+	talosUpgrade, err := safe.StateGet[*omni.TalosUpgradeStatus](ctx, h.state, omni.NewTalosUpgradeStatus(omniresources.DefaultNamespace, id).Metadata())
+	if err != nil && !state.IsNotFoundError(err) {
+		return nil, err
+	}
+	if talosUpgrade != nil {
+		status := talosUpgrade.TypedSpec().Value
+		switch status.Phase {
+		case specs.TalosUpgradeStatusSpec_Upgrading, specs.TalosUpgradeStatusSpec_Reverting, specs.TalosUpgradeStatusSpec_UpdatingMachineSchematics:
+			blockers = append(blockers, UpgradeBlocker{Kind: blockerUpgradeInProgress, Message: fmt.Sprintf("Talos upgrade to %s is %s", status.CurrentUpgradeVersion, strings.ToLower(status.Phase.String()))})
+		case specs.TalosUpgradeStatusSpec_Failed:
+			blockers = append(blockers, UpgradeBlocker{Kind: blockerUpgradeFailed, Message: "Talos upgrade failed: " + status.Error})
+		}
+	}
+
+	kubernetesUpgrade, err := safe.StateGet[*omni.KubernetesUpgradeStatus](ctx, h.state, omni.NewKubernetesUpgradeStatus(omniresources.DefaultNamespace, id).Metadata())
+	if err != nil && !state.IsNotFoundError(err) {
+		return nil, err
+	}
+	if kubernetesUpgrade != nil {
+		status := kubernetesUpgrade.TypedSpec().Value
+		switch status.Phase {
+		case specs.KubernetesUpgradeStatusSpec_Upgrading, specs.KubernetesUpgradeStatusSpec_Reverting:
+			blockers = append(blockers, UpgradeBlocker{Kind: blockerUpgradeInProgress, Message: fmt.Sprintf("Kubernetes upgrade to %s is %s", status.CurrentUpgradeVersion, strings.ToLower(status.Phase.String()))})
+		case specs.KubernetesUpgradeStatusSpec_Failed:
+			blockers = append(blockers, UpgradeBlocker{Kind: blockerUpgradeFailed, Message: "Kubernetes upgrade failed: " + status.Error})
+		}
+	}
+
+	// Omni only accepts the versions it offers for the cluster as it runs now, which decides the first step
+	if len(steps) > 0 {
+		var offered []string
+		switch first := steps[0]; {
+		case first.Component == upgradeplan.ComponentTalos && talosUpgrade != nil:
+			offered = talosUpgrade.TypedSpec().Value.UpgradeVersions
+		case first.Component == upgradeplan.ComponentKubernetes && kubernetesUpgrade != nil:
+			offered = kubernetesUpgrade.TypedSpec().Value.UpgradeVersions
+		}
+		if offered != nil && !slices.Contains(offered, steps[0].To) {
+			blockers = append(blockers, UpgradeBlocker{
+				Kind:    blockerVersionUnavailable,
+				Message: fmt.Sprintf("Omni does not offer %s %s for this cluster, available versions: %s", steps[0].Component, steps[0].To, strings.Join(offered, ", ")),
+				Step:    stepIndex(0),
+			})
+		}
+	}
+
+	nodes, err := h.nodeBlockers(ctx, id, spec.TalosVersion, spec.KubernetesVersion)
+	if err != nil {
+		return nil, err
+	}
+	blockers = append(blockers, nodes...)
+
+	// Pre-checks compare the cluster as it runs now with the next Kubernetes version;
+	// later Kubernetes steps are checked once the cluster got there
+	if i := slices.IndexFunc(steps, func(step upgradeplan.Step) bool { return step.Component == upgradeplan.ComponentKubernetes }); i >= 0 {
+		reason, err := h.management.KubernetesUpgradePreChecks(ctx, id, steps[i].To)
+		switch {
+		case err != nil:
+			blockers = append(blockers, UpgradeBlocker{Kind: blockerPreChecksFailed, Message: "Kubernetes upgrade pre-checks could not run: " + err.Error(), Step: stepIndex(i)})
+		case reason != "":
+			blockers = append(blockers, UpgradeBlocker{Kind: blockerDeprecatedAPIs, Message: reason, Step: stepIndex(i)})
+		}
+	}
+
+	return blockers, nil
+}
+
+// nodeBlockers reports machines and nodes that are not ready or run other versions than the cluster
+func (h *ClusterUpgradePlanHandler) nodeBlockers(ctx context.Context, id, talosVersion, kubernetesVersion string) ([]UpgradeBlocker, error) {
+	query := state.WithLabelQuery(resource.LabelEqual(omni.LabelCluster, id))
+	var unhealthy, talosSkew, kubeletSkew []string
+
+	machines, err := safe.StateListAll[*omni.ClusterMachineStatus](ctx, h.state, query)
+	if err != nil {
+		return nil, err
+	}
+	for machine := range machines.All() {
+		if spec := machine.TypedSpec().Value; !spec.Ready || spec.Stage != specs.ClusterMachineStatusSpec_RUNNING {
+			unhealthy = append(unhealthy, machine.Metadata().ID())
+		}
+	}
+
+	machineStatuses, err := safe.StateListAll[*omni.MachineStatus](ctx, h.state, query)
+	if err != nil {
+		return nil, err
+	}
+	for machine := range machineStatuses.All() {
+		if version := strings.TrimPrefix(machine.TypedSpec().Value.TalosVersion, "v"); version != "" && version != talosVersion {
+			talosSkew = append(talosSkew, machine.Metadata().ID())
+		}
+	}
+
+	kubernetesStatus, err := safe.StateGet[*omni.KubernetesStatus](ctx, h.state, omni.NewKubernetesStatus(omniresources.DefaultNamespace, id).Metadata())
+	if err != nil && !state.IsNotFoundError(err) {
+		return nil, err
+	}
+	if kubernetesStatus != nil {
+		for _, node := range kubernetesStatus.TypedSpec().Value.Nodes {
+			if !node.Ready {
+				unhealthy = append(unhealthy, node.Nodename)
+			}
+			if strings.TrimPrefix(node.KubeletVersion, "v") != kubernetesVersion {
+				kubeletSkew = append(kubeletSkew, node.Nodename)
+			}
+		}
+	}
+
+	var blockers []UpgradeBlocker
+	if len(unhealthy) > 0 {
+		blockers = append(blockers, UpgradeBlocker{Kind: blockerUnhealthyNodes, Message: "machines or nodes are not ready", Nodes: unhealthy})
+	}
+	if len(talosSkew) > 0 {
+		blockers = append(blockers, UpgradeBlocker{Kind: blockerVersionSkew, Message: "machines do not run Talos " + talosVersion, Nodes: talosSkew})
+	}
+	if len(kubeletSkew) > 0 {
+		blockers = append(blockers, UpgradeBlocker{Kind: blockerVersionSkew, Message: "nodes do not run kubelet " + kubernetesVersion, Nodes: kubeletSkew})
+	}
+	return blockers, nil
+}
+
+func stepIndex(i int) *int {
+	return &i
+}
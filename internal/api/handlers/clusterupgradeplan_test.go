@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/state/impl/inmem"
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/upgradeplan"
+	"github.com/siderolabs/omni/client/api/omni/specs"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// upgradePlanState returns a state with a cluster running Talos 1.8.0 and Kubernetes 1.30.1 on the given machines
+func upgradePlanState(clusterMachines, machineStatuses []resource.Resource, kubernetesStatus *omni.KubernetesStatus) *MockState {
+	cluster := omni.NewCluster("default", "prod")
+	cluster.TypedSpec().Value.TalosVersion = "1.8.0"
+	cluster.TypedSpec().Value.KubernetesVersion = "1.30.1"
+
+	var talosVersions []resource.Resource
+	for version, compatible := range map[string][]string{
+		"1.8.0": {"1.30.1", "1.31.0"},
+		"1.9.1": {"1.30.1", "1.31.0", "1.32.0"},
+	} {
+		tv := omni.NewTalosVersion("default", version)
+		tv.TypedSpec().Value.CompatibleKubernetesVersions = compatible
+		talosVersions = append(talosVersions, tv)
+	}
+	deprecated := omni.NewTalosVersion("default", "1.7.6")
+	deprecated.TypedSpec().Value.CompatibleKubernetesVersions = []string{"1.30.1"}
+	deprecated.TypedSpec().Value.Deprecated = true
+	talosVersions = append(talosVersions, deprecated)
+
+	var kubernetesVersions []resource.Resource
+	for _, version := range []string{"1.30.1", "1.31.0", "1.32.0"} {
+		kv := omni.NewKubernetesVersion("default", version)
+		kv.TypedSpec().Value.Version = version
+		kubernetesVersions = append(kubernetesVersions, kv)
+	}
+
+	talosUpgrade := omni.NewTalosUpgradeStatus("default", "prod")
+	talosUpgrade.TypedSpec().Value.Phase = specs.TalosUpgradeStatusSpec_Done
+	talosUpgrade.TypedSpec().Value.UpgradeVersions = []string{"1.9.1"}
+
+	mockState := new(MockState)
+	mockState.On("Get", mock.Anything, ofType(omni.ClusterType), mock.Anything).Return(cluster, nil)
+	mockState.On("Get", mock.Anything, ofType(omni.TalosUpgradeStatusType), mock.Anything).Return(talosUpgrade, nil)
+	mockState.On("Get", mock.Anything, ofType(omni.KubernetesUpgradeStatusType), mock.Anything).Return(nil, inmem.ErrNotFound(omni.NewKubernetesUpgradeStatus("default", "prod").Metadata()))
+	if kubernetesStatus != nil {
+		mockState.On("Get", mock.Anything, ofType(omni.KubernetesStatusType), mock.Anything).Return(kubernetesStatus, nil)
+	} else {
+		mockState.On("Get", mock.Anything, ofType(omni.KubernetesStatusType), mock.Anything).Return(nil, inmem.ErrNotFound(omni.NewKubernetesStatus("default", "prod").Metadata()))
+	}
+	mockState.On("List", mock.Anything, ofType(omni.TalosVersionType), mock.Anything).Return(resource.List{Items: talosVersions}, nil)
+	mockState.On("List", mock.Anything, ofType(omni.KubernetesVersionType), mock.Anything).Return(resource.List{Items: kubernetesVersions}, nil)
+	mockState.On("List", mock.Anything, ofType(omni.ClusterMachineStatusType), mock.Anything).Return(resource.List{Items: clusterMachines}, nil)
+	mockState.On("List", mock.Anything, ofType(omni.MachineStatusType), mock.Anything).Return(resource.List{Items: machineStatuses}, nil)
+	return mockState
+}
+
+// healthyUpgradePlanState returns upgradePlanState with a single ready machine running the cluster's Talos version
+func healthyUpgradePlanState() *MockState {
+	machine := omni.NewClusterMachineStatus("default", "m1")
+	machine.TypedSpec().Value.Ready = true
+	machine.TypedSpec().Value.Stage = specs.ClusterMachineStatusSpec_RUNNING
+	machineStatus := omni.NewMachineStatus("default", "m1")
+	machineStatus.TypedSpec().Value.TalosVersion = "v1.8.0"
+
+	return upgradePlanState([]resource.Resource{machine}, []resource.Resource{machineStatus}, nil)
+}
+
+func getUpgradePlan(t *testing.T, handler *ClusterUpgradePlanHandler, query string) (int, ClusterUpgradePlanResponse) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: "prod"}}
+	c.Request, _ = http.NewRequest("GET", "/clusters/prod/upgrade-plan"+query, nil)
+
+	handler.GetClusterUpgradePlan(c)
+
+	var resp ClusterUpgradePlanResponse
+	if w.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	}
+	return w.Code, resp
+}
+
+func TestClusterUpgradePlanHandler_GetClusterUpgradePlan(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockMgmt := new(MockManagementService)
+	mockMgmt.On("KubernetesUpgradePreChecks", mock.Anything, "prod", "1.31.0").Return("", nil)
+
+	handler := NewClusterUpgradePlanHandler(healthyUpgradePlanState(), mockMgmt)
+	code, resp := getUpgradePlan(t, handler, "")
+
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, UpgradePlanVersions{TalosVersion: "1.8.0", KubernetesVersion: "1.30.1"}, resp.Current)
+	assert.Equal(t, UpgradePlanVersions{TalosVersion: "1.9.1", KubernetesVersion: "1.32.0"}, resp.Target)
+	require.Len(t, resp.Steps, 3)
+	assert.Equal(t, upgradeplan.Step{Component: upgradeplan.ComponentTalos, From: "1.8.0", To: "1.9.1", TalosVersion: "1.9.1", KubernetesVersion: "1.30.1"}, resp.Steps[0].Step)
+	assert.Equal(t, "http://localhost:8080/api/v1/clusters/prod/actions/talos-upgrade", resp.Steps[0].Action)
+	assert.Equal(t, []string{"1.31.0", "1.32.0"}, []string{resp.Steps[1].To, resp.Steps[2].To})
+	assert.Empty(t, resp.Blockers)
+	assert.True(t, resp.Ready)
+	mockMgmt.AssertExpectations(t)
+}
+
+func TestClusterUpgradePlanHandler_GetClusterUpgradePlanBlockers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	unhealthy := omni.NewClusterMachineStatus("default", "m2")
+	unhealthy.TypedSpec().Value.Stage = specs.ClusterMachineStatusSpec_BOOTING
+	skewed := omni.NewMachineStatus("default", "m2")
+	skewed.TypedSpec().Value.TalosVersion = "v1.7.6"
+	kubernetesStatus := omni.NewKubernetesStatus("default", "prod")
+	kubernetesStatus.TypedSpec().Value.Nodes = []*specs.KubernetesStatusSpec_NodeStatus{
+		{Nodename: "node-1", KubeletVersion: "v1.30.1", Ready: true},
+		{Nodename: "node-2", KubeletVersion: "v1.29.3", Ready: true},
+	}
+	mockState := upgradePlanState([]resource.Resource{unhealthy}, []resource.Resource{skewed}, kubernetesStatus)
+
+	mockMgmt := new(MockManagementService)
+	mockMgmt.On("KubernetesUpgradePreChecks", mock.Anything, "prod", "1.31.0").Return("policy/v1beta1 PodSecurityPolicy is removed in 1.31", nil)
+
+	handler := NewClusterUpgradePlanHandler(mockState, mockMgmt)
+	code, resp := getUpgradePlan(t, handler, "?talos_version=v1.9.1&kubernetes_version=1.31.0")
+
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, resp.Steps, 2)
+	assert.False(t, resp.Ready)
+
+	kinds := map[string][]string{}
+	for _, blocker := range resp.Blockers {
+		kinds[blocker.Kind] = append(kinds[blocker.Kind], blocker.Nodes...)
+		if blocker.Kind == blockerDeprecatedAPIs {
+			assert.Equal(t, 1, *blocker.Step)
+			assert.Contains(t, blocker.Message, "PodSecurityPolicy")
+		}
+	}
+	assert.Equal(t, map[string][]string{
+		blockerUnhealthyNodes: {"m2"},
+		blockerVersionSkew:    {"m2", "node-2"},
+		blockerDeprecatedAPIs: nil,
+	}, kinds)
+}
+
+func TestClusterUpgradePlanHandler_GetClusterUpgradePlanTargets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockMgmt := new(MockManagementService)
+	mockMgmt.On("KubernetesUpgradePreChecks", mock.Anything, "prod", mock.Anything).Return("", errors.New("cluster unreachable"))
+	handler := NewClusterUpgradePlanHandler(healthyUpgradePlanState(), mockMgmt)
+
+	code, resp := getUpgradePlan(t, handler, "?talos_version=1.8.0&kubernetes_version=1.31.0")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, resp.Blockers, 1)
+	assert.Equal(t, blockerPreChecksFailed, resp.Blockers[0].Kind)
+	assert.False(t, resp.Ready)
+
+	code, resp = getUpgradePlan(t, handler, "?talos_version=1.8.0&kubernetes_version=1.32.0")
+	require.Equal(t, http.StatusOK, code)
+	assert.Empty(t, resp.Steps)
+	require.Len(t, resp.Blockers, 1)
+	assert.Equal(t, blockerNoPath, resp.Blockers[0].Kind)
+
+	code, _ = getUpgradePlan(t, handler, "?talos_version=1.7.6")
+	assert.Equal(t, http.StatusBadRequest, code, "downgrades are rejected")
+}
+
+func TestClusterUpgradePlanHandler_GetClusterUpgradePlanNotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockState := new(MockState)
+	mockState.On("Get", mock.Anything, ofType(omni.ClusterType), mock.Anything).Return(nil, inmem.ErrNotFound(omni.NewCluster("default", "prod").Metadata()))
+
+	code, _ := getUpgradePlan(t, NewClusterUpgradePlanHandler(mockState, new(MockManagementService)), "")
+	assert.Equal(t, http.StatusNotFound, code)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/cosi-project/runtime/pkg/state"
 	"github.com/gin-gonic/gin"
 	"github.com/jubblin/omni-api/internal/client"
+	"github.com/jubblin/omni-api/internal/operations"
 	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
 	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
 )
@@ -16,33 +17,36 @@ type ClusterCreateRequest struct {
 	ID                string `json:"id" binding:"required"`
 	KubernetesVersion string `json:"kubernetes_version" binding:"required"`
 	TalosVersion      string `json:"talos_version,omitempty"`
-	Features          struct {
-		WorkloadProxy bool `json:"workload_proxy,omitempty"`
-		DiskEncryption bool `json:"disk_encryption,omitempty"`
-	} `json:"features,omitempty"`
+	Features          ClusterFeaturesRequest `json:"features,omitempty"`
+	BackupConfiguration *ClusterBackupRequest `json:"backup_configuration,omitempty"` // Etcd backups are disabled when omitted
+}
+
+// ClusterFeaturesRequest represents cluster feature flags in a request
+type ClusterFeaturesRequest struct {
+	WorkloadProxy bool `json:"workload_proxy,omitempty"`
+	DiskEncryption bool `json:"disk_encryption,omitempty"`
 }
 
 // ClusterUpdateRequest represents a request to update a cluster
 type ClusterUpdateRequest struct {
 	KubernetesVersion string `json:"kubernetes_version,omitempty"`
 	TalosVersion      string `json:"talos_version,omitempty"`
-	Features          struct {
-		WorkloadProxy bool `json:"workload_proxy,omitempty"`
-		DiskEncryption bool `json:"disk_encryption,omitempty"`
-	} `json:"features,omitempty"`
+	Features          *ClusterFeaturesRequest `json:"features,omitempty"` // Left unchanged when omitted
 }
 
 // ClusterWriteHandler handles cluster write operations
 type ClusterWriteHandler struct {
 	state      state.State
 	management client.ManagementService // Management service interface
+	operations *operations.Manager      // Tracks cluster deletion
 }
 
 // NewClusterWriteHandler creates a new ClusterWriteHandler
-func NewClusterWriteHandler(s state.State, mgmt client.ManagementService) *ClusterWriteHandler {
+func NewClusterWriteHandler(s state.State, mgmt client.ManagementService, ops *operations.Manager) *ClusterWriteHandler {
 	return &ClusterWriteHandler{
 		state:      s,
 		management: mgmt,
+		operations: ops,
 	}
 }
 
@@ -53,8 +57,12 @@ func NewClusterWriteHandler(s state.State, mgmt client.ManagementService) *Clust
 // @Accept       json
 // @Produce      json
 // @Param        cluster  body      ClusterCreateRequest  true  "Cluster creation request"
+// @Param        dryRun   query     bool                  false  "Validate the request and return the change without applying it"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200      {object}  DryRunResponse  "Returned instead when dryRun is set"
 // @Success      201      {object}  ClusterResponse
 // @Failure      400      {object}  map[string]string
+// @Failure      412      {object}  map[string]string  "Backups are enabled but Omni has no usable etcd backup store"
 // @Failure      500      {object}  map[string]string
 // @Router       /clusters [post]
 func (h *ClusterWriteHandler) CreateCluster(c *gin.Context) {
@@ -69,19 +77,33 @@ func (h *ClusterWriteHandler) CreateCluster(c *gin.Context) {
 		WorkloadProxy: req.Features.WorkloadProxy,
 		DiskEncryption: req.Features.DiskEncryption,
 	}
+
+	var backup *client.ClusterBackup
+	if req.BackupConfiguration != nil {
+		var err error
+		if backup, err = req.BackupConfiguration.toClusterBackup(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid backup interval: " + err.Error()})
+			return
+		}
+	}
 	
-	err := h.management.CreateCluster(
-		c.Request.Context(),
+	change, err := h.management.CreateCluster(
+		writeContext(c),
 		req.ID,
 		req.KubernetesVersion,
 		req.TalosVersion,
 		features,
+		backup,
 	)
 	
 	if err != nil {
 		handleManagementError(c, err)
 		return
 	}
+	if isDryRun(c) {
+		respondDryRun(c, change)
+		return
+	}
 	
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Cluster created successfully",
@@ -97,7 +119,8 @@ func (h *ClusterWriteHandler) CreateCluster(c *gin.Context) {
 // @Produce      json
 // @Param        id       path      string                true  "Cluster ID"
 // @Param        cluster  body      ClusterUpdateRequest  true  "Cluster update request"
-// @Success      200      {object}  ClusterResponse
+// @Param        dryRun   query     bool                  false  "Validate the request and return the change without applying it"
+// @Success      200      {object}  ClusterResponse  "A DryRunResponse when dryRun is set"
 // @Failure      400      {object}  map[string]string
 // @Failure      404      {object}  map[string]string
 // @Failure      500      {object}  map[string]string
@@ -126,13 +149,16 @@ func (h *ClusterWriteHandler) UpdateCluster(c *gin.Context) {
 	}
 
 	// Update cluster using Management service
-	features := &client.ClusterFeatures{
-		WorkloadProxy: req.Features.WorkloadProxy,
-		DiskEncryption: req.Features.DiskEncryption,
+	var features *client.ClusterFeatures
+	if req.Features != nil {
+		features = &client.ClusterFeatures{
+			WorkloadProxy: req.Features.WorkloadProxy,
+			DiskEncryption: req.Features.DiskEncryption,
+		}
 	}
 	
-	err = h.management.UpdateCluster(
-		c.Request.Context(),
+	change, err := h.management.UpdateCluster(
+		writeContext(c),
 		id,
 		req.KubernetesVersion,
 		req.TalosVersion,
@@ -143,6 +169,10 @@ func (h *ClusterWriteHandler) UpdateCluster(c *gin.Context) {
 		handleManagementError(c, err)
 		return
 	}
+	if isDryRun(c) {
+		respondDryRun(c, change)
+		return
+	}
 	
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Cluster updated successfully",
@@ -155,8 +185,10 @@ func (h *ClusterWriteHandler) UpdateCluster(c *gin.Context) {
 // @Description  Delete a cluster from Omni
 // @Tags         clusters
 // @Produce      json
-// @Param        id   path      string  true  "Cluster ID"
-// @Success      202  {object}  map[string]string
+// @Param        id      path      string  true   "Cluster ID"
+// @Param        dryRun  query     bool    false  "Validate the request and return the change without applying it"
+// @Success      200  {object}  DryRunResponse  "Returned instead when dryRun is set"
+// @Success      202  {object}  map[string]string  "Location header points to the operation tracking the deletion"
 // @Failure      404  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /clusters/{id} [delete]
@@ -172,14 +204,26 @@ func (h *ClusterWriteHandler) DeleteCluster(c *gin.Context) {
 	}
 
 	// Delete cluster using Management service
-	err = h.management.DeleteCluster(c.Request.Context(), id)
+	change, err := h.management.DeleteCluster(writeContext(c), id)
 	if err != nil {
 		handleManagementError(c, err)
 		return
 	}
+	if isDryRun(c) {
+		respondDryRun(c, change)
+		return
+	}
 	
-	c.JSON(http.StatusAccepted, gin.H{
+	resp := gin.H{
 		"message": "Cluster deletion initiated",
 		"id": id,
-	})
+	}
+	startOperation(c, h.operations, operations.Spec{
+		Kind:       "cluster-destroy",
+		Target:     id,
+		TargetPath: "/api/v1/clusters/" + id + "/destroy-status",
+		Track:      operations.ClusterDestroyTracker(h.state, id),
+	}, resp)
+
+	c.JSON(http.StatusAccepted, resp)
 }
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/api/middleware"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/jubblin/omni-api/internal/diff"
+	"github.com/jubblin/omni-api/internal/patchhistory"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ConfigPatchRevisionResponse represents a recorded version of a config patch
+type ConfigPatchRevisionResponse struct {
+	Revision        int    `json:"revision"`
+	Action          string `json:"action"`
+	Cluster         string `json:"cluster,omitempty"`
+	Data            string `json:"data"`
+	Author          string `json:"author,omitempty"` // User reported by the authenticating proxy; informational only
+	Time            string `json:"time"`
+	ResourceVersion string `json:"resource_version,omitempty"`
+	RollbackOf      int    `json:"rollback_of,omitempty"` // Revision restored by a rollback
+}
+
+// ConfigPatchHistoryResponse represents the recorded versions of a config patch
+type ConfigPatchHistoryResponse struct {
+	ID        string                        `json:"id"`
+	Revisions []ConfigPatchRevisionResponse `json:"revisions"` // Newest first
+	Links     map[string]string             `json:"_links,omitempty"`
+}
+
+// ConfigPatchRevisionDiffResponse represents the difference between two versions of a config patch
+type ConfigPatchRevisionDiffResponse struct {
+	ID    string            `json:"id"`
+	From  int               `json:"from"`
+	To    int               `json:"to"`
+	Diff  string            `json:"diff"` // Unified diff, empty if the revisions are equal
+	Links map[string]string `json:"_links,omitempty"`
+}
+
+// ConfigPatchHistoryHandler handles config patch history requests
+type ConfigPatchHistoryHandler struct {
+	management client.ManagementService
+	history    *patchhistory.History
+}
+
+// NewConfigPatchHistoryHandler creates a new ConfigPatchHistoryHandler
+func NewConfigPatchHistoryHandler(mgmt client.ManagementService, history *patchhistory.History) *ConfigPatchHistoryHandler {
+	return &ConfigPatchHistoryHandler{management: mgmt, history: history}
+}
+
+// GetConfigPatchHistory godoc
+// @Summary      Get config patch history
+// @Description  List the versions of a config patch written through this API, newest first.
+// @Description  The content Omni had before the API first changed a patch is recorded as an "observed" revision.
+// @Description  The author is the user a trusted authenticating proxy passed with the write, empty for unauthenticated writes; the API does not verify it.
+// @Tags         configpatches
+// @Produce      json
+// @Param        id   path      string  true  "Config patch ID"
+// @Success      200  {object}  ConfigPatchHistoryResponse
+// @Failure      404  {object}  map[string]string
+// @Router       /configpatches/{id}/history [get]
+func (h *ConfigPatchHistoryHandler) GetConfigPatchHistory(c *gin.Context) {
+	id := c.Param("id")
+
+	revisions := h.history.Revisions(id)
+	if len(revisions) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no history for config patch " + id})
+		return
+	}
+
+	resp := ConfigPatchHistoryResponse{
+		ID:        id,
+		Revisions: make([]ConfigPatchRevisionResponse, 0, len(revisions)),
+		Links: map[string]string{
+			"self":        buildURL(c, "/api/v1/configpatches/"+id+"/history"),
+			"configpatch": buildURL(c, "/api/v1/configpatches/"+id),
+		},
+	}
+	for _, rev := range slices.Backward(revisions) {
+		resp.Revisions = append(resp.Revisions, configPatchRevisionResponse(rev))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// DiffConfigPatchRevisions godoc
+// @Summary      Diff config patch revisions
+// @Description  Compare two recorded versions of a config patch
+// @Tags         configpatches
+// @Produce      json
+// @Param        id    path      string  true   "Config patch ID"
+// @Param        from  query     int     false  "Revision to compare from (default: the revision before to)"
+// @Param        to    query     int     false  "Revision to compare to (default: the latest revision)"
+// @Success      200  {object}  ConfigPatchRevisionDiffResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /configpatches/{id}/history/diff [get]
+func (h *ConfigPatchHistoryHandler) DiffConfigPatchRevisions(c *gin.Context) {
+	id := c.Param("id")
+
+	revisions := h.history.Revisions(id)
+	if len(revisions) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no history for config patch " + id})
+		return
+	}
+
+	to, ok := h.revisionParam(c, id, "to", revisions[len(revisions)-1].Revision)
+	if !ok {
+		return
+	}
+	from, ok := h.revisionParam(c, id, "from", previousRevision(revisions, to.Revision))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, ConfigPatchRevisionDiffResponse{
+		ID:   id,
+		From: from.Revision,
+		To:   to.Revision,
+		Diff: diff.Unified(from.Data, to.Data, fmt.Sprintf("%s@%d", id, from.Revision), fmt.Sprintf("%s@%d", id, to.Revision)),
+		Links: map[string]string{
+			"history": buildURL(c, "/api/v1/configpatches/"+id+"/history"),
+		},
+	})
+}
+
+// RollbackConfigPatch godoc
+// @Summary      Roll back a config patch
+// @Description  Restore the content of a config patch from a recorded revision. Deleted patches are recreated in the cluster they belonged to.
+// @Description  The rollback is recorded as a new revision.
+// @Tags         configpatches
+// @Produce      json
+// @Param        id        path      string  true   "Config patch ID"
+// @Param        revision  query     int     true   "Revision to restore"
+// @Param        dryRun    query     bool    false  "Validate the request and return the change without applying it"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200  {object}  ConfigPatchRevisionResponse  "The recorded rollback, or a DryRunResponse when dryRun is set"
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /configpatches/{id}/rollback [post]
+func (h *ConfigPatchHistoryHandler) RollbackConfigPatch(c *gin.Context) {
+	id := c.Param("id")
+
+	if c.Query("revision") == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "revision is required"})
+		return
+	}
+	target, ok := h.revisionParam(c, id, "revision", 0)
+	if !ok {
+		return
+	}
+	if target.Action == patchhistory.ActionDelete {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("revision %d deleted the config patch, roll back to an earlier revision", target.Revision)})
+		return
+	}
+
+	change, err := h.management.UpdateConfigPatch(writeContext(c), id, target.Data)
+	if status.Code(err) == codes.NotFound && target.Cluster != "" {
+		// The patch was deleted since, recreate it
+		change, err = h.management.CreateConfigPatch(writeContext(c), id, target.Cluster, target.Data)
+	}
+	if err != nil {
+		handleManagementError(c, err)
+		return
+	}
+	if isDryRun(c) {
+		respondDryRun(c, change)
+		return
+	}
+
+	rev, ok := recordPatchRevision(c, h.history, change, patchhistory.ActionRollback, target.Revision)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "config patch was rolled back, but the revision could not be recorded"})
+		return
+	}
+	c.JSON(http.StatusOK, configPatchRevisionResponse(rev))
+}
+
+// revisionParam returns the revision named by a query parameter, or def if the parameter is not set.
+// It responds with an error if the revision is invalid or unknown.
+func (h *ConfigPatchHistoryHandler) revisionParam(c *gin.Context, id, name string, def int) (patchhistory.Revision, bool) {
+	n := def
+	if value := c.Query(name); value != "" {
+		var err error
+		if n, err = strconv.Atoi(value); err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid %s value", name)})
+			return patchhistory.Revision{}, false
+		}
+	}
+
+	rev, ok := h.history.Get(id, n)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("revision %d of config patch %s not found", n, id)})
+		return patchhistory.Revision{}, false
+	}
+	return rev, true
+}
+
+// previousRevision returns the revision kept before n, or n itself if it is the oldest
+func previousRevision(revisions []patchhistory.Revision, n int) int {
+	previous := n
+	for _, rev := range revisions {
+		if rev.Revision >= n {
+			break
+		}
+		previous = rev.Revision
+	}
+	return previous
+}
+
+// recordPatchRevision records the config patch a change wrote in the history.
+// Failures are logged, as the change has already been written.
+func recordPatchRevision(c *gin.Context, history *patchhistory.History, change *client.Change, action patchhistory.Action, rollbackOf int) (patchhistory.Revision, bool) {
+	if history == nil || change == nil {
+		return patchhistory.Revision{}, false
+	}
+
+	rev, err := history.RecordChange(change, action, patchAuthor(c), rollbackOf)
+	if err != nil {
+		log.Printf("Error recording config patch history: %v", err)
+		return patchhistory.Revision{}, false
+	}
+	return rev, true
+}
+
+// patchAuthor returns the author recorded for a config patch write: the user a trusted proxy authenticated, or ""
+func patchAuthor(c *gin.Context) string {
+	user, _ := middleware.AuthenticatedUser(c)
+	return user
+}
+
+func configPatchRevisionResponse(rev patchhistory.Revision) ConfigPatchRevisionResponse {
+	return ConfigPatchRevisionResponse{
+		Revision:        rev.Revision,
+		Action:          string(rev.Action),
+		Cluster:         rev.Cluster,
+		Data:            rev.Data,
+		Author:          rev.Author,
+		Time:            rev.Time.UTC().Format(time.RFC3339),
+		ResourceVersion: rev.ResourceVersion,
+		RollbackOf:      rev.RollbackOf,
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/jubblin/omni-api/internal/patchhistory"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newConfigPatchHistoryRouter(t *testing.T, mgmt *MockManagementService) (*gin.Engine, *patchhistory.History) {
+	history, err := patchhistory.New(patchhistory.Config{})
+	require.NoError(t, err)
+
+	writeHandler := NewConfigPatchWriteHandler(new(MockState), mgmt, history)
+	historyHandler := NewConfigPatchHistoryHandler(mgmt, history)
+
+	r := gin.New()
+	r.Use(authenticate)
+	r.PUT("/configpatches/:id", writeHandler.UpdateConfigPatch)
+	r.DELETE("/configpatches/:id", writeHandler.DeleteConfigPatch)
+	r.GET("/configpatches/:id/history", historyHandler.GetConfigPatchHistory)
+	r.GET("/configpatches/:id/history/diff", historyHandler.DiffConfigPatchRevisions)
+	r.POST("/configpatches/:id/rollback", historyHandler.RollbackConfigPatch)
+	return r, history
+}
+
+func serveConfigPatch(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = "10.0.0.1:40000"
+	req.Header.Set("X-Remote-User", "alice@example.com")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestConfigPatchHistoryHandler_RecordAndRollback(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const (
+		original = "machine:\n  network:\n    hostname: worker-1\n"
+		broken   = "machine:\n  network:\n    interfaces: []\n"
+	)
+	labels := map[string]string{omni.LabelCluster: "prod"}
+	current := newConfigPatch(t, "400-network", original, labels)
+	updated := newConfigPatch(t, "400-network", broken, labels)
+	restored := newConfigPatch(t, "400-network", original, labels)
+
+	mgmt := new(MockManagementService)
+	mgmt.On("UpdateConfigPatch", mock.Anything, "400-network", broken).Return(&client.Change{Action: client.ChangeUpdate, Current: current, Desired: updated}, nil)
+	mgmt.On("DeleteConfigPatch", mock.Anything, "400-network").Return(&client.Change{Action: client.ChangeDestroy, Current: updated}, nil)
+	mgmt.On("UpdateConfigPatch", mock.Anything, "400-network", original).Return(nil, status.Error(codes.NotFound, "config patch 400-network not found"))
+	mgmt.On("CreateConfigPatch", mock.Anything, "400-network", "prod", original).Return(&client.Change{Action: client.ChangeCreate, Desired: restored}, nil)
+	r, history := newConfigPatchHistoryRouter(t, mgmt)
+
+	// The first update records the content Omni had before
+	w := serveConfigPatch(r, "PUT", "/configpatches/400-network", `{"data":"machine:\n  network:\n    interfaces: []\n"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = serveConfigPatch(r, "DELETE", "/configpatches/400-network", "")
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+
+	w = serveConfigPatch(r, "GET", "/configpatches/400-network/history", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp ConfigPatchHistoryResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Revisions, 3)
+	assert.Equal(t, "delete", resp.Revisions[0].Action)
+	assert.Equal(t, "update", resp.Revisions[1].Action)
+	assert.Equal(t, "alice@example.com", resp.Revisions[1].Author)
+	assert.Equal(t, broken, resp.Revisions[1].Data)
+	assert.Equal(t, "observed", resp.Revisions[2].Action)
+	assert.Equal(t, original, resp.Revisions[2].Data)
+
+	w = serveConfigPatch(r, "GET", "/configpatches/400-network/history/diff?to=2", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var diffResp ConfigPatchRevisionDiffResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &diffResp))
+	assert.Equal(t, 1, diffResp.From)
+	assert.Contains(t, diffResp.Diff, "-    hostname: worker-1\n+    interfaces: []")
+
+	// Revisions that deleted the patch cannot be restored
+	w = serveConfigPatch(r, "POST", "/configpatches/400-network/rollback?revision=3", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Dry runs are not recorded
+	w = serveConfigPatch(r, "POST", "/configpatches/400-network/rollback?revision=1&dryRun=true", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, history.Revisions("400-network"), 3)
+
+	// The deleted patch is recreated
+	w = serveConfigPatch(r, "POST", "/configpatches/400-network/rollback?revision=1", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var rev ConfigPatchRevisionResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rev))
+	assert.Equal(t, 4, rev.Revision)
+	assert.Equal(t, "rollback", rev.Action)
+	assert.Equal(t, 1, rev.RollbackOf)
+	assert.Equal(t, original, rev.Data)
+	mgmt.AssertCalled(t, "CreateConfigPatch", mock.Anything, "400-network", "prod", original)
+}
+
+func TestConfigPatchHistoryHandler_Errors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r, history := newConfigPatchHistoryRouter(t, new(MockManagementService))
+	_, err := history.Record(patchhistory.Revision{Patch: "400-network", Action: patchhistory.ActionCreate, Data: "machine: {}"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{name: "no history", method: "GET", path: "/configpatches/missing/history", wantStatus: http.StatusNotFound},
+		{name: "no history to diff", method: "GET", path: "/configpatches/missing/history/diff", wantStatus: http.StatusNotFound},
+		{name: "invalid revision", method: "GET", path: "/configpatches/400-network/history/diff?from=abc", wantStatus: http.StatusBadRequest},
+		{name: "unknown revision", method: "GET", path: "/configpatches/400-network/history/diff?from=7", wantStatus: http.StatusNotFound},
+		{name: "rollback without revision", method: "POST", path: "/configpatches/400-network/rollback", wantStatus: http.StatusBadRequest},
+		{name: "rollback to unknown revision", method: "POST", path: "/configpatches/400-network/rollback?revision=9", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveConfigPatch(r, tt.method, tt.path, "")
+			assert.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+		})
+	}
+}
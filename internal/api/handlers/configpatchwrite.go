@@ -1,13 +1,11 @@
 package handlers
 
 import (
-	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
-	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
-	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/jubblin/omni-api/internal/patchhistory"
 	"github.com/cosi-project/runtime/pkg/state"
 )
 
@@ -26,26 +24,33 @@ type ConfigPatchUpdateRequest struct {
 // ConfigPatchWriteHandler handles config patch write operations
 type ConfigPatchWriteHandler struct {
 	state      state.State
-	management interface{} // Management service client
+	management client.ManagementService // Management service
+	history    *patchhistory.History     // Records every version written
 }
 
 // NewConfigPatchWriteHandler creates a new ConfigPatchWriteHandler
-func NewConfigPatchWriteHandler(s state.State, mgmt interface{}) *ConfigPatchWriteHandler {
+func NewConfigPatchWriteHandler(s state.State, mgmt client.ManagementService, history *patchhistory.History) *ConfigPatchWriteHandler {
 	return &ConfigPatchWriteHandler{
 		state:      s,
 		management: mgmt,
+		history:    history,
 	}
 }
 
 // CreateConfigPatch godoc
 // @Summary      Create a new config patch
-// @Description  Create a new config patch for a cluster
+// @Description  Create a new config patch for a cluster. The patch is validated as Talos machine configuration first.
 // @Tags         configpatches
 // @Accept       json
 // @Produce      json
-// @Param        patch  body      ConfigPatchCreateRequest  true  "Config patch creation request"
+// @Param        patch   body      ConfigPatchCreateRequest  true   "Config patch creation request"
+// @Param        dryRun  query     bool                      false  "Validate the request and return the change without applying it"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200    {object}  DryRunResponse  "Returned instead when dryRun is set"
 // @Success      201    {object}  ConfigPatchResponse
 // @Failure      400    {object}  map[string]string
+// @Failure      404    {object}  map[string]string
+// @Failure      409    {object}  map[string]string
 // @Failure      500    {object}  map[string]string
 // @Router       /configpatches [post]
 func (h *ConfigPatchWriteHandler) CreateConfigPatch(c *gin.Context) {
@@ -55,26 +60,35 @@ func (h *ConfigPatchWriteHandler) CreateConfigPatch(c *gin.Context) {
 		return
 	}
 
-	// Use Management service to create config patch
-	log.Printf("Creating config patch %s for cluster %s (Management service integration needed)", req.ID, req.Cluster)
+	// Create config patch using Management service
+	change, err := h.management.CreateConfigPatch(writeContext(c), req.ID, req.Cluster, req.Data)
+	if err != nil {
+		handleManagementError(c, err)
+		return
+	}
+	if isDryRun(c) {
+		respondDryRun(c, change)
+		return
+	}
+	recordPatchRevision(c, h.history, change, patchhistory.ActionCreate, 0)
 	
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "Config patch creation initiated",
+		"message": "Config patch created successfully",
 		"id": req.ID,
 		"cluster": req.Cluster,
-		"note": "Management service integration required for actual creation",
 	})
 }
 
 // UpdateConfigPatch godoc
 // @Summary      Update a config patch
-// @Description  Update an existing config patch
+// @Description  Update an existing config patch. The patch is validated as Talos machine configuration first.
 // @Tags         configpatches
 // @Accept       json
 // @Produce      json
-// @Param        id     path      string                  true  "Config patch ID"
-// @Param        patch  body      ConfigPatchUpdateRequest  true  "Config patch update request"
-// @Success      200    {object}  ConfigPatchResponse
+// @Param        id      path      string                    true   "Config patch ID"
+// @Param        patch   body      ConfigPatchUpdateRequest  true   "Config patch update request"
+// @Param        dryRun  query     bool                      false  "Validate the request and return the change without applying it"
+// @Success      200    {object}  ConfigPatchResponse  "A DryRunResponse when dryRun is set"
 // @Failure      400    {object}  map[string]string
 // @Failure      404    {object}  map[string]string
 // @Failure      500    {object}  map[string]string
@@ -87,21 +101,21 @@ func (h *ConfigPatchWriteHandler) UpdateConfigPatch(c *gin.Context) {
 		return
 	}
 
-	// Verify config patch exists
-	md := resource.NewMetadata(omniresources.DefaultNamespace, omni.ConfigPatchType, id, resource.VersionUndefined)
-	_, err := h.state.Get(c.Request.Context(), md)
+	// Update config patch using Management service
+	change, err := h.management.UpdateConfigPatch(writeContext(c), id, req.Data)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "config patch not found"})
+		handleManagementError(c, err)
 		return
 	}
-
-	// Use Management service to update config patch
-	log.Printf("Updating config patch %s (Management service integration needed)", id)
+	if isDryRun(c) {
+		respondDryRun(c, change)
+		return
+	}
+	recordPatchRevision(c, h.history, change, patchhistory.ActionUpdate, 0)
 	
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Config patch update initiated",
+		"message": "Config patch updated successfully",
 		"id": id,
-		"note": "Management service integration required for actual update",
 	})
 }
 
@@ -110,7 +124,9 @@ func (h *ConfigPatchWriteHandler) UpdateConfigPatch(c *gin.Context) {
 // @Description  Delete a config patch from Omni
 // @Tags         configpatches
 // @Produce      json
-// @Param        id   path      string  true  "Config patch ID"
+// @Param        id      path      string  true   "Config patch ID"
+// @Param        dryRun  query     bool    false  "Validate the request and return the change without applying it"
+// @Success      200  {object}  DryRunResponse  "Returned instead when dryRun is set"
 // @Success      202  {object}  map[string]string
 // @Failure      404  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
@@ -118,20 +134,20 @@ func (h *ConfigPatchWriteHandler) UpdateConfigPatch(c *gin.Context) {
 func (h *ConfigPatchWriteHandler) DeleteConfigPatch(c *gin.Context) {
 	id := c.Param("id")
 
-	// Verify config patch exists
-	md := resource.NewMetadata(omniresources.DefaultNamespace, omni.ConfigPatchType, id, resource.VersionUndefined)
-	_, err := h.state.Get(c.Request.Context(), md)
+	// Delete config patch using Management service
+	change, err := h.management.DeleteConfigPatch(writeContext(c), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "config patch not found"})
+		handleManagementError(c, err)
 		return
 	}
-
-	// Use Management service to delete config patch
-	log.Printf("Deleting config patch %s (Management service integration needed)", id)
+	if isDryRun(c) {
+		respondDryRun(c, change)
+		return
+	}
+	recordPatchRevision(c, h.history, change, patchhistory.ActionDelete, 0)
 	
 	c.JSON(http.StatusAccepted, gin.H{
 		"message": "Config patch deletion initiated",
 		"id": id,
-		"note": "Management service integration required for actual deletion",
 	})
 }
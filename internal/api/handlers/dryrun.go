@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/jubblin/omni-api/internal/diff"
+	"go.yaml.in/yaml/v4"
+)
+
+// DryRunResponse represents the result of a write request sent with ?dryRun=true
+type DryRunResponse struct {
+	DryRun   bool                   `json:"dry_run"`
+	Action   string                 `json:"action"`
+	Resource map[string]interface{} `json:"resource,omitempty"` // The resource that would be written
+	Diff     string                 `json:"diff,omitempty"`     // Unified diff against the current state
+	Cascade  []string               `json:"cascade,omitempty"`  // Other resources that would be destroyed
+}
+
+// isDryRun reports whether the request asked for a dry run
+func isDryRun(c *gin.Context) bool {
+	dryRun, _ := strconv.ParseBool(c.Query("dryRun"))
+	return dryRun
+}
+
+// writeContext returns the context for write operations, marked as a dry run if the request asked for one
+func writeContext(c *gin.Context) context.Context {
+	if isDryRun(c) {
+		return client.WithDryRun(c.Request.Context())
+	}
+	return c.Request.Context()
+}
+
+// DryRunChangesResponse represents the changes a dry run of a request writing several resources would have made
+type DryRunChangesResponse struct {
+	DryRun  bool             `json:"dry_run"`
+	Changes []DryRunResponse `json:"changes"`
+}
+
+// respondDryRun writes the Change a dry run would have made
+func respondDryRun(c *gin.Context, change *client.Change) {
+	resp, err := dryRunResponse(change)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// respondDryRunChanges writes the Changes a dry run would have made, as a DryRunResponse if there is at most one
+func respondDryRunChanges(c *gin.Context, changes []*client.Change) {
+	if len(changes) <= 1 {
+		var change *client.Change
+		if len(changes) == 1 {
+			change = changes[0]
+		}
+		respondDryRun(c, change)
+		return
+	}
+
+	resp := DryRunChangesResponse{DryRun: true}
+	for _, change := range changes {
+		changeResp, err := dryRunResponse(change)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		resp.Changes = append(resp.Changes, changeResp)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// dryRunResponse renders the Change a dry run would have made
+func dryRunResponse(change *client.Change) (DryRunResponse, error) {
+	resp := DryRunResponse{DryRun: true, Action: string(client.ChangeNone)}
+	if change == nil {
+		return resp, nil
+	}
+	resp.Action = string(change.Action)
+
+	rendered := change.Desired
+	if rendered == nil {
+		rendered = change.Current
+	}
+	if rendered != nil {
+		doc, err := diff.ResourceYAML(rendered)
+		if err != nil {
+			return resp, err
+		}
+		if err := yaml.Unmarshal([]byte(doc), &resp.Resource); err != nil {
+			return resp, err
+		}
+	}
+
+	changeDiff, err := diff.Resources(change.Current, change.Desired)
+	if err != nil {
+		return resp, err
+	}
+	resp.Diff = changeDiff
+
+	for _, ptr := range change.Cascade {
+		resp.Cascade = append(resp.Cascade, pointerName(ptr))
+	}
+
+	return resp, nil
+}
+
+func pointerName(ptr resource.Pointer) string {
+	return ptr.Type() + "/" + ptr.ID()
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRespondDryRun(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	current := omni.NewCluster(resources.DefaultNamespace, "test-cluster")
+	current.TypedSpec().Value.KubernetesVersion = "1.30.1"
+	desired := current.DeepCopy().(*omni.Cluster)
+	desired.TypedSpec().Value.KubernetesVersion = "1.31.0"
+
+	r := gin.New()
+	r.PUT("/clusters/:id", func(c *gin.Context) {
+		assert.True(t, client.IsDryRun(writeContext(c)))
+		respondDryRun(c, &client.Change{Action: client.ChangeUpdate, Current: current, Desired: desired})
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/clusters/test-cluster?dryRun=true", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp DryRunResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.DryRun)
+	assert.Equal(t, "update", resp.Action)
+	assert.Equal(t, "test-cluster", resp.Resource["metadata"].(map[string]interface{})["id"])
+	assert.Contains(t, resp.Diff, "+    kubernetesversion: 1.31.0")
+}
+
+func TestWriteContext_WithoutDryRun(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("DELETE", "/clusters/test-cluster?dryRun=false", nil)
+
+	assert.False(t, client.IsDryRun(writeContext(c)))
+}
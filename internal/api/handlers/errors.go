@@ -18,7 +18,7 @@ func handleManagementError(c *gin.Context, err error) {
 	switch code {
 	case codes.NotFound:
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-	case codes.AlreadyExists:
+	case codes.AlreadyExists, codes.Aborted:
 		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 	case codes.InvalidArgument:
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -32,6 +32,8 @@ func handleManagementError(c *gin.Context, err error) {
 		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "operation timeout"})
 	case codes.FailedPrecondition:
 		c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+	case codes.Unimplemented:
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
 	default:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 	}
@@ -53,6 +55,8 @@ func handleTalosError(c *gin.Context, err error) {
 		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "operation timeout"})
 	case codes.InvalidArgument:
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case codes.FailedPrecondition:
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
 	default:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 	}
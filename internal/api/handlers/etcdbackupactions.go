@@ -1,10 +1,16 @@
 package handlers
 
 import (
-	"log"
 	"net/http"
+	"time"
 
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/state"
 	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/jubblin/omni-api/internal/operations"
+	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
 )
 
 // EtcdBackupCreateRequest represents a request to trigger a manual etcd backup
@@ -12,17 +18,50 @@ type EtcdBackupCreateRequest struct {
 	Cluster string `json:"cluster" binding:"required"`
 }
 
+// EtcdBackupRestoreRequest represents a request to restore an etcd backup into a new cluster
+type EtcdBackupRestoreRequest struct {
+	Cluster           string                        `json:"cluster" binding:"required"`   // ID of the new cluster
+	KubernetesVersion string                        `json:"kubernetes_version,omitempty"` // Defaults to the version of the backed up cluster, required if it is not known
+	TalosVersion      string                        `json:"talos_version,omitempty"`      // Defaults to the version of the backed up cluster, required if it is not known
+	Features          ClusterFeaturesRequest        `json:"features,omitempty"`
+	ControlPlane      EtcdBackupRestoreControlPlane `json:"control_plane"`
+}
+
+// EtcdBackupRestoreControlPlane selects the machines of the restored control plane,
+// either from a machine class or by machine ID
+type EtcdBackupRestoreControlPlane struct {
+	MachineClass string   `json:"machine_class,omitempty"`
+	MachineCount uint32   `json:"machine_count,omitempty"`
+	Machines     []string `json:"machines,omitempty"`
+}
+
+// EtcdBackupRestoreResponse describes the cluster an etcd backup is restored into
+type EtcdBackupRestoreResponse struct {
+	DryRun            bool              `json:"dry_run,omitempty"`
+	Backup            string            `json:"backup"`
+	Cluster           string            `json:"cluster"`
+	KubernetesVersion string            `json:"kubernetes_version"`
+	TalosVersion      string            `json:"talos_version"`
+	SourceClusterUUID string            `json:"source_cluster_uuid"`
+	Snapshot          string            `json:"snapshot"`
+	Resources         []string          `json:"resources"` // Resources created for the restore
+	OperationID       string            `json:"operation_id,omitempty"`
+	Links             map[string]string `json:"_links,omitempty"`
+}
+
 // EtcdBackupActionsHandler handles etcd backup action operations
 type EtcdBackupActionsHandler struct {
-	state      interface{} // State interface
-	management interface{} // Management service client
+	state      state.State
+	management client.ManagementService // Management service client
+	operations *operations.Manager      // Tracks backups and restores
 }
 
 // NewEtcdBackupActionsHandler creates a new EtcdBackupActionsHandler
-func NewEtcdBackupActionsHandler(state, mgmt interface{}) *EtcdBackupActionsHandler {
+func NewEtcdBackupActionsHandler(s state.State, mgmt client.ManagementService, ops *operations.Manager) *EtcdBackupActionsHandler {
 	return &EtcdBackupActionsHandler{
-		state:      state,
+		state:      s,
 		management: mgmt,
+		operations: ops,
 	}
 }
 
@@ -33,8 +72,12 @@ func NewEtcdBackupActionsHandler(state, mgmt interface{}) *EtcdBackupActionsHand
 // @Accept       json
 // @Produce      json
 // @Param        request  body      EtcdBackupCreateRequest  true  "Backup request"
-// @Success      202      {object}  map[string]string
+// @Param        dryRun   query     bool                     false  "Validate the request and return the change without applying it"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200      {object}  DryRunResponse  "Returned instead when dryRun is set"
+// @Success      202      {object}  map[string]string  "Location header points to the operation tracking the backup"
 // @Failure      400      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
 // @Failure      500      {object}  map[string]string
 // @Router       /etcdbackups [post]
 func (h *EtcdBackupActionsHandler) TriggerManualBackup(c *gin.Context) {
@@ -44,13 +87,127 @@ func (h *EtcdBackupActionsHandler) TriggerManualBackup(c *gin.Context) {
 		return
 	}
 
+	// Verify cluster exists
+	md := resource.NewMetadata(omniresources.DefaultNamespace, omni.ClusterType, req.Cluster, resource.VersionUndefined)
+	if _, err := h.state.Get(c.Request.Context(), md); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "cluster not found"})
+		return
+	}
+
+	// Backups that completed before this point do not count towards the operation
+	since := time.Now().Truncate(time.Second)
+
 	// Use Management service to trigger manual backup
-	// Typically: managementClient.CreateEtcdManualBackup(ctx, clusterID)
-	log.Printf("Triggering manual etcd backup for cluster %s (Management service integration needed)", req.Cluster)
-	
-	c.JSON(http.StatusAccepted, gin.H{
+	change, err := h.management.CreateEtcdManualBackup(writeContext(c), req.Cluster)
+	if err != nil {
+		handleManagementError(c, err)
+		return
+	}
+	if isDryRun(c) {
+		respondDryRun(c, change)
+		return
+	}
+
+	resp := gin.H{
 		"message": "Manual etcd backup initiated",
 		"cluster": req.Cluster,
-		"note": "Management service integration required for actual backup",
-	})
+	}
+	startOperation(c, h.operations, operations.Spec{
+		Kind:       "etcd-backup",
+		Target:     req.Cluster,
+		TargetPath: "/api/v1/etcdbackups/" + req.Cluster + "/status",
+		Track:      operations.EtcdBackupTracker(h.state, req.Cluster, since),
+	}, resp)
+
+	c.JSON(http.StatusAccepted, resp)
+}
+
+// RestoreBackup godoc
+// @Summary      Restore an etcd backup into a new cluster
+// @Description  Create a new cluster whose control plane Omni bootstraps from the etcd snapshot of a backup, using the secrets of the backed up cluster.
+// @Description  The control plane allocates machine_count machines from machine_class, or uses the listed machines.
+// @Description  The versions default to those of the backed up cluster; Kubernetes may move one minor version ahead and Talos must not be older.
+// @Description  Both versions are required if the backed up cluster no longer exists or changed since the backup, as Omni does not record its versions.
+// @Tags         etcdbackups
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                    true   "Etcd Backup ID"
+// @Param        request  body      EtcdBackupRestoreRequest  true   "Restore request"
+// @Param        dryRun   query     bool                      false  "Validate the request without creating the cluster"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200      {object}  EtcdBackupRestoreResponse  "Returned instead when dryRun is set"
+// @Success      202      {object}  EtcdBackupRestoreResponse  "Location header points to the operation tracking the bootstrap of the restored cluster"
+// @Failure      400      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      409      {object}  map[string]string  "The cluster or one of the machines already exists or is allocated"
+// @Failure      412      {object}  map[string]string  "The versions are not compatible with the backup, or are required"
+// @Failure      500      {object}  map[string]string
+// @Router       /etcdbackups/{id}/actions/restore [post]
+func (h *EtcdBackupActionsHandler) RestoreBackup(c *gin.Context) {
+	id := c.Param("id")
+	var req EtcdBackupRestoreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	restore := &client.ClusterRestore{
+		ClusterID:         req.Cluster,
+		KubernetesVersion: req.KubernetesVersion,
+		TalosVersion:      req.TalosVersion,
+		Features: &client.ClusterFeatures{
+			WorkloadProxy:  req.Features.WorkloadProxy,
+			DiskEncryption: req.Features.DiskEncryption,
+		},
+		Machines: req.ControlPlane.Machines,
+	}
+	if req.ControlPlane.MachineClass != "" {
+		restore.ControlPlane = &client.MachineSetUpdates{
+			MachineClass: req.ControlPlane.MachineClass,
+			MachineCount: req.ControlPlane.MachineCount,
+		}
+	}
+
+	changes, err := h.management.RestoreEtcdBackup(writeContext(c), id, restore)
+	if err != nil {
+		handleManagementError(c, err)
+		return
+	}
+
+	resp := EtcdBackupRestoreResponse{
+		DryRun:  isDryRun(c),
+		Backup:  id,
+		Cluster: req.Cluster,
+		Links: map[string]string{
+			"backup":  buildURL(c, "/api/v1/etcdbackups/"+id),
+			"cluster": buildURL(c, "/api/v1/clusters/"+req.Cluster),
+		},
+	}
+	for _, change := range changes {
+		switch res := change.Desired.(type) {
+		case *omni.Cluster:
+			resp.KubernetesVersion = res.TypedSpec().Value.KubernetesVersion
+			resp.TalosVersion = res.TypedSpec().Value.TalosVersion
+		case *omni.MachineSet:
+			resp.SourceClusterUUID = res.TypedSpec().Value.GetBootstrapSpec().GetClusterUuid()
+			resp.Snapshot = res.TypedSpec().Value.GetBootstrapSpec().GetSnapshot()
+		}
+		resp.Resources = append(resp.Resources, pointerName(change.Desired.Metadata()))
+	}
+	if resp.DryRun {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	links := gin.H{}
+	startOperation(c, h.operations, operations.Spec{
+		Kind:       "etcd-restore",
+		Target:     req.Cluster,
+		TargetPath: "/api/v1/clusters/" + req.Cluster,
+		Track:      operations.ClusterBootstrapTracker(h.state, req.Cluster),
+	}, links)
+	resp.OperationID, _ = links["operation_id"].(string)
+	resp.Links["operation"] = c.Writer.Header().Get("Location")
+
+	c.JSON(http.StatusAccepted, resp)
 }
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/jubblin/omni-api/internal/operations"
+	"github.com/siderolabs/omni/client/api/omni/specs"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func restoreChanges() []*client.Change {
+	cluster := omni.NewCluster("default", "prod-restored")
+	cluster.TypedSpec().Value.KubernetesVersion = "1.30.1"
+	cluster.TypedSpec().Value.TalosVersion = "1.8.0"
+
+	machineSet := omni.NewMachineSet("default", "prod-restored-control-planes")
+	machineSet.TypedSpec().Value.BootstrapSpec = &specs.MachineSetSpec_BootstrapSpec{ClusterUuid: "uuid-1", Snapshot: "FFFFFFFF9A7F1BFF.snapshot"}
+
+	return []*client.Change{
+		{Action: client.ChangeCreate, Desired: cluster},
+		{Action: client.ChangeCreate, Desired: machineSet},
+	}
+}
+
+func TestEtcdBackupActionsHandler_RestoreBackup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockState := new(MockState)
+	mockState.On("Watch", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errors.New("watch unavailable"))
+
+	mockMgmt := new(MockManagementService)
+	mockMgmt.On("RestoreEtcdBackup", mock.Anything, "prod-1700000000", mock.MatchedBy(func(restore *client.ClusterRestore) bool {
+		return restore.ClusterID == "prod-restored" && restore.ControlPlane.MachineClass == "cp" && restore.ControlPlane.MachineCount == 3
+	})).Return(restoreChanges(), nil)
+
+	ops := operations.NewManager(operations.Config{})
+	handler := NewEtcdBackupActionsHandler(mockState, mockMgmt, ops)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: "prod-1700000000"}}
+	c.Request, _ = http.NewRequest("POST", "/etcdbackups/prod-1700000000/actions/restore", strings.NewReader(`{"cluster":"prod-restored","control_plane":{"machine_class":"cp","machine_count":3}}`))
+
+	handler.RestoreBackup(c)
+
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var resp EtcdBackupRestoreResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "1.30.1", resp.KubernetesVersion)
+	assert.Equal(t, "uuid-1", resp.SourceClusterUUID)
+	assert.Equal(t, "FFFFFFFF9A7F1BFF.snapshot", resp.Snapshot)
+	assert.Len(t, resp.Resources, 2)
+	assert.Equal(t, w.Header().Get("Location"), resp.Links["operation"])
+
+	op, ok := ops.Wait(context.Background(), resp.OperationID)
+	require.True(t, ok)
+	assert.Equal(t, "etcd-restore", op.Kind)
+	assert.Equal(t, "prod-restored", op.Target)
+	mockMgmt.AssertExpectations(t)
+}
+
+func TestEtcdBackupActionsHandler_RestoreBackupDryRun(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockMgmt := new(MockManagementService)
+	mockMgmt.On("RestoreEtcdBackup", mock.MatchedBy(client.IsDryRun), "prod-1700000000", mock.Anything).Return(restoreChanges(), nil)
+
+	ops := operations.NewManager(operations.Config{})
+	handler := NewEtcdBackupActionsHandler(new(MockState), mockMgmt, ops)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: "prod-1700000000"}}
+	c.Request, _ = http.NewRequest("POST", "/etcdbackups/prod-1700000000/actions/restore?dryRun=true", strings.NewReader(`{"cluster":"prod-restored","control_plane":{"machines":["m1"]}}`))
+
+	handler.RestoreBackup(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp EtcdBackupRestoreResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.DryRun)
+	assert.Empty(t, resp.OperationID)
+	assert.Empty(t, ops.List())
+}
+
+func TestEtcdBackupActionsHandler_RestoreBackupErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		body       string
+		err        error
+		wantStatus int
+	}{
+		{"missing cluster", `{"control_plane":{"machines":["m1"]}}`, nil, http.StatusBadRequest},
+		{"unknown backup", `{"cluster":"prod-restored","control_plane":{"machines":["m1"]}}`, status.Error(codes.NotFound, "etcdbackup prod-1 not found"), http.StatusNotFound},
+		{"existing cluster", `{"cluster":"prod","control_plane":{"machines":["m1"]}}`, status.Error(codes.AlreadyExists, "cluster prod already exists"), http.StatusConflict},
+		{"incompatible versions", `{"cluster":"prod-restored","kubernetes_version":"1.29.0","control_plane":{"machines":["m1"]}}`, status.Error(codes.FailedPrecondition, "a backup of Kubernetes 1.30.1 can only be restored into Kubernetes 1.30 or 1.31"), http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockMgmt := new(MockManagementService)
+			mockMgmt.On("RestoreEtcdBackup", mock.Anything, "prod-1", mock.Anything).Return(nil, tt.err)
+			ops := operations.NewManager(operations.Config{})
+			handler := NewEtcdBackupActionsHandler(new(MockState), mockMgmt, ops)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = gin.Params{{Key: "id", Value: "prod-1"}}
+			c.Request, _ = http.NewRequest("POST", "/etcdbackups/prod-1/actions/restore", strings.NewReader(tt.body))
+
+			handler.RestoreBackup(c)
+
+			assert.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			assert.Empty(t, ops.List())
+		})
+	}
+}
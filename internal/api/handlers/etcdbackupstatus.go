@@ -3,10 +3,13 @@ package handlers
 import (
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/safe"
 	"github.com/cosi-project/runtime/pkg/state"
 	"github.com/gin-gonic/gin"
+	"github.com/siderolabs/omni/client/api/omni/specs"
 	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
 	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
 )
@@ -20,6 +23,23 @@ type EtcdBackupStatusResponse struct {
 	Links     map[string]string `json:"_links,omitempty"`
 }
 
+// EtcdBackupStoreStatusResponse represents the state of Omni's etcd backup store across all clusters
+type EtcdBackupStoreStatusResponse struct {
+	Store      string                        `json:"store"` // Configured store: disabled, local or s3
+	Healthy    bool                          `json:"healthy"`
+	Error      string                        `json:"error,omitempty"`
+	LastBackup *EtcdBackupLastStatusResponse `json:"last_backup,omitempty"` // Most recent backup of any cluster
+	Links      map[string]string             `json:"_links,omitempty"`
+}
+
+// EtcdBackupLastStatusResponse represents the outcome of the most recent etcd backup
+type EtcdBackupLastStatusResponse struct {
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+	LastBackup  string `json:"last_backup_time,omitempty"`
+	LastAttempt string `json:"last_backup_attempt,omitempty"`
+}
+
 // EtcdBackupStatusHandler handles etcd backup status requests
 type EtcdBackupStatusHandler struct {
 	state state.State
@@ -81,3 +101,71 @@ func (h *EtcdBackupStatusHandler) GetEtcdBackupStatus(c *gin.Context) {
 
 	c.JSON(http.StatusOK, resp)
 }
+
+// GetEtcdBackupStoreStatus godoc
+// @Summary      Get the etcd backup store status
+// @Description  Get the configured etcd backup store of Omni, whether it is usable, and the outcome of the most recent backup of any cluster.
+// @Description  Combines the EtcdBackupStoreStatus and EtcdBackupOverallStatus resources.
+// @Tags         etcdbackups
+// @Produce      json
+// @Success      200  {object}  EtcdBackupStoreStatusResponse
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /etcdbackups/store-status [get]
+func (h *EtcdBackupStatusHandler) GetEtcdBackupStoreStatus(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	store, err := safe.StateGet[*omni.EtcdBackupStoreStatus](ctx, h.state, omni.NewEtcdBackupStoreStatus().Metadata())
+	if err != nil {
+		if state.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "etcd backup store status not found"})
+			return
+		}
+		log.Printf("Error getting etcd backup store status: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	spec := store.TypedSpec().Value
+	resp := EtcdBackupStoreStatusResponse{
+		Store:   spec.ConfigurationName,
+		Healthy: spec.ConfigurationError == "" && spec.ConfigurationName != "disabled",
+		Error:   spec.ConfigurationError,
+		Links: map[string]string{
+			"self":    buildURL(c, "/api/v1/etcdbackups/store-status"),
+			"backups": buildURL(c, "/api/v1/etcdbackups"),
+		},
+	}
+
+	overall, err := safe.StateGet[*omni.EtcdBackupOverallStatus](ctx, h.state, omni.NewEtcdBackupOverallStatus().Metadata())
+	if err != nil && !state.IsNotFoundError(err) {
+		log.Printf("Error getting etcd backup overall status: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if overall != nil {
+		resp.LastBackup = lastBackupStatus(overall.TypedSpec().Value.GetLastBackupStatus())
+	}
+	if resp.LastBackup != nil && resp.LastBackup.Status == specs.EtcdBackupStatusSpec_Error.String() {
+		resp.Healthy = false
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func lastBackupStatus(last *specs.EtcdBackupStatusSpec) *EtcdBackupLastStatusResponse {
+	if last == nil {
+		return nil
+	}
+	resp := &EtcdBackupLastStatusResponse{
+		Status: last.Status.String(),
+		Error:  last.Error,
+	}
+	if last.LastBackupTime != nil {
+		resp.LastBackup = last.LastBackupTime.AsTime().Format(time.RFC3339)
+	}
+	if last.LastBackupAttempt != nil {
+		resp.LastAttempt = last.LastBackupAttempt.AsTime().Format(time.RFC3339)
+	}
+	return resp
+}
@@ -5,15 +5,18 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/cosi-project/runtime/pkg/resource"
 	"github.com/cosi-project/runtime/pkg/resource/protobuf"
 	"github.com/cosi-project/runtime/pkg/resource/typed"
+	"github.com/cosi-project/runtime/pkg/state/impl/inmem"
 	"github.com/gin-gonic/gin"
 	"github.com/siderolabs/omni/client/api/omni/specs"
 	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func TestEtcdBackupStatusHandler_GetEtcdBackupStatus(t *testing.T) {
@@ -47,3 +50,67 @@ func TestEtcdBackupStatusHandler_GetEtcdBackupStatus(t *testing.T) {
 	assert.Equal(t, "backup-1", resp.ID)
 	assert.Equal(t, "Unknown", resp.Status)
 }
+
+func TestEtcdBackupStatusHandler_GetEtcdBackupStoreStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := omni.NewEtcdBackupStoreStatus()
+	store.TypedSpec().Value.ConfigurationName = "s3"
+	overall := omni.NewEtcdBackupOverallStatus()
+	overall.TypedSpec().Value.LastBackupStatus = &specs.EtcdBackupStatusSpec{
+		Status:         specs.EtcdBackupStatusSpec_Error,
+		Error:          "access denied",
+		LastBackupTime: timestamppb.New(time.Date(2026, 10, 14, 6, 0, 0, 0, time.UTC)),
+	}
+
+	mockState := new(MockState)
+	mockState.On("Get", mock.Anything, ofType(omni.EtcdBackupStoreStatusType), mock.Anything).Return(store, nil)
+	mockState.On("Get", mock.Anything, ofType(omni.EtcdBackupOverallStatusType), mock.Anything).Return(overall, nil)
+
+	handler := NewEtcdBackupStatusHandler(mockState)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/etcdbackups/store-status", nil)
+
+	handler.GetEtcdBackupStoreStatus(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp EtcdBackupStoreStatusResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "s3", resp.Store)
+	assert.False(t, resp.Healthy)
+	if assert.NotNil(t, resp.LastBackup) {
+		assert.Equal(t, "Error", resp.LastBackup.Status)
+		assert.Equal(t, "access denied", resp.LastBackup.Error)
+		assert.Equal(t, "2026-10-14T06:00:00Z", resp.LastBackup.LastBackup)
+	}
+}
+
+func TestEtcdBackupStatusHandler_GetEtcdBackupStoreStatusDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := omni.NewEtcdBackupStoreStatus()
+	store.TypedSpec().Value.ConfigurationName = "disabled"
+
+	mockState := new(MockState)
+	mockState.On("Get", mock.Anything, ofType(omni.EtcdBackupStoreStatusType), mock.Anything).Return(store, nil)
+	mockState.On("Get", mock.Anything, ofType(omni.EtcdBackupOverallStatusType), mock.Anything).Return(nil, inmem.ErrNotFound(omni.NewEtcdBackupOverallStatus().Metadata()))
+
+	handler := NewEtcdBackupStatusHandler(mockState)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/etcdbackups/store-status", nil)
+
+	handler.GetEtcdBackupStoreStatus(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp EtcdBackupStoreStatusResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "disabled", resp.Store)
+	assert.False(t, resp.Healthy)
+	assert.Nil(t, resp.LastBackup)
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/gitops"
+)
+
+// GitOpsStatusResponse represents the result of the latest GitOps sync
+type GitOpsStatusResponse struct {
+	Enabled     bool                  `json:"enabled"`
+	Dir         string                `json:"dir,omitempty"`
+	Mode        string                `json:"mode,omitempty"`
+	Prune       bool                  `json:"prune"`
+	Revision    string                `json:"revision,omitempty"` // Commit checked out in the directory, if it is a git checkout
+	Syncing     bool                  `json:"syncing"`
+	InSync      bool                  `json:"in_sync"` // The latest sync found no errors and left no changes unapplied
+	LastSync    string                `json:"last_sync,omitempty"`
+	LastSuccess string                `json:"last_success,omitempty"`
+	Error       string                `json:"error,omitempty"`
+	Sources     []gitops.SourceStatus `json:"sources"`
+	Pruned      []gitops.Change       `json:"pruned,omitempty"` // Config patches removed from the directory
+	Links       map[string]string     `json:"_links,omitempty"`
+}
+
+// GitOpsHandler handles GitOps reconciler requests
+type GitOpsHandler struct {
+	reconciler *gitops.Reconciler
+}
+
+// NewGitOpsHandler creates a new GitOpsHandler
+func NewGitOpsHandler(reconciler *gitops.Reconciler) *GitOpsHandler {
+	return &GitOpsHandler{reconciler: reconciler}
+}
+
+// GetGitOpsStatus godoc
+// @Summary      Get GitOps sync status
+// @Description  Get the result of the latest sync of the OMNI_API_GITOPS_DIR directory: errors per file and the changes it applied, or would apply in observe mode.
+// @Description  Unapplied changes are drift between the directory and Omni.
+// @Tags         gitops
+// @Produce      json
+// @Success      200  {object}  GitOpsStatusResponse
+// @Router       /gitops/status [get]
+func (h *GitOpsHandler) GetGitOpsStatus(c *gin.Context) {
+	status := h.reconciler.Status()
+
+	resp := GitOpsStatusResponse{
+		Enabled:  status.Enabled,
+		Dir:      status.Dir,
+		Mode:     string(status.Mode),
+		Prune:    status.Prune,
+		Revision: status.Revision,
+		Syncing:  status.Syncing,
+		InSync:   status.InSync(),
+		Error:    status.Error,
+		Sources:  status.Sources,
+		Pruned:   status.Pruned,
+		Links: map[string]string{
+			"self": buildURL(c, "/api/v1/gitops/status"),
+			"sync": buildURL(c, "/api/v1/gitops/sync"),
+		},
+	}
+	if !status.LastSync.IsZero() {
+		resp.LastSync = status.LastSync.UTC().Format(time.RFC3339)
+	}
+	if !status.LastSuccess.IsZero() {
+		resp.LastSuccess = status.LastSuccess.UTC().Format(time.RFC3339)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// SyncGitOps godoc
+// @Summary      Trigger a GitOps sync
+// @Description  Sync the GitOps directory now instead of waiting for OMNI_API_GITOPS_INTERVAL, for example from a git hook after pulling.
+// @Description  The sync runs in the background; follow it with the status endpoint.
+// @Tags         gitops
+// @Produce      json
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      202  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /gitops/sync [post]
+func (h *GitOpsHandler) SyncGitOps(c *gin.Context) {
+	if !h.reconciler.Enabled() {
+		c.JSON(http.StatusConflict, gin.H{"error": "GitOps is not enabled, set OMNI_API_GITOPS_DIR"})
+		return
+	}
+
+	h.reconciler.Trigger()
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "GitOps sync requested",
+		"status":  buildURL(c, "/api/v1/gitops/status"),
+	})
+}
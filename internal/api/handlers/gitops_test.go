@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/gitops"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newGitOpsRouter(reconciler *gitops.Reconciler) *gin.Engine {
+	h := NewGitOpsHandler(reconciler)
+
+	r := gin.New()
+	r.GET("/gitops/status", h.GetGitOpsStatus)
+	r.POST("/gitops/sync", h.SyncGitOps)
+	return r
+}
+
+func TestGitOpsHandler_Disabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := newGitOpsRouter(gitops.New(gitops.Config{}, new(MockState), new(MockTemplateService), new(MockManagementService), nil, nil))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/gitops/status", nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp GitOpsStatusResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.False(t, resp.Enabled)
+	assert.False(t, resp.InSync)
+	assert.Empty(t, resp.LastSync)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/gitops/sync", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestGitOpsHandler_Status(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("metadata: {}\n"), 0o600))
+
+	mockState := new(MockState)
+	mockState.On("List", mock.Anything, ofType(omni.ConfigPatchType), mock.Anything).Return(resource.List{}, nil)
+	reconciler := gitops.New(gitops.Config{Dir: dir, Mode: gitops.ModeObserve}, mockState, new(MockTemplateService), new(MockManagementService), nil, nil)
+	reconciler.Sync(context.Background())
+	r := newGitOpsRouter(reconciler)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/gitops/status", nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp GitOpsStatusResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Enabled)
+	assert.Equal(t, "observe", resp.Mode)
+	assert.NotEmpty(t, resp.LastSync)
+	assert.Empty(t, resp.LastSuccess)
+	assert.False(t, resp.InSync)
+	require.Len(t, resp.Sources, 1)
+	assert.Equal(t, "broken.yaml", resp.Sources[0].File)
+	assert.NotEmpty(t, resp.Sources[0].Error)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/gitops/sync", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+}
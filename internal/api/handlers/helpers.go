@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"fmt"
+	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -33,3 +36,23 @@ func buildURL(c *gin.Context, path string) string {
 	
 	return scheme + "://" + host + path
 }
+
+// wantsRawYAML reports whether the client asked for a raw YAML file rather than JSON,
+// either with ?download=1 or an Accept header preferring application/yaml
+func wantsRawYAML(c *gin.Context) bool {
+	if download, _ := strconv.ParseBool(c.Query("download")); download {
+		return true
+	}
+	switch c.NegotiateFormat(gin.MIMEJSON, gin.MIMEYAML2, gin.MIMEYAML) {
+	case gin.MIMEYAML2, gin.MIMEYAML:
+		return true
+	default:
+		return false
+	}
+}
+
+// respondYAMLFile sends data as a YAML file download
+func respondYAMLFile(c *gin.Context, filename string, data []byte) {
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, gin.MIMEYAML2, data)
+}
@@ -2,74 +2,214 @@ package handlers
 
 import (
 	"encoding/base64"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/cosi-project/runtime/pkg/resource"
 	"github.com/cosi-project/runtime/pkg/state"
 	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/api/middleware"
+	"github.com/jubblin/omni-api/internal/audit"
+	"github.com/jubblin/omni-api/internal/client"
 	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
 	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
 )
 
+// Kubeconfig modes
+const (
+	KubeconfigModeOIDC           = "oidc"            // Each user signs in to Omni
+	KubeconfigModeServiceAccount = "service-account" // Embedded token for a fixed user and groups
+)
+
+// defaultKubeconfigTTL is how long service-account kubeconfigs are valid if no TTL is given
+const defaultKubeconfigTTL = 24 * time.Hour
+
 // KubeconfigResponse represents the kubeconfig information returned by the API
-// WARNING: This contains sensitive cluster credentials
+// WARNING: Service-account kubeconfigs contain credentials
 type KubeconfigResponse struct {
 	ID        string            `json:"id"`
 	Namespace string            `json:"namespace"`
+	Mode      string            `json:"mode"`
+	User      string            `json:"user,omitempty"`
+	Groups    []string          `json:"groups,omitempty"`
+	ExpiresAt *time.Time        `json:"expires_at,omitempty"`
 	Data      string            `json:"data"` // Base64 encoded kubeconfig
 	Links     map[string]string `json:"_links,omitempty"`
 }
 
 // KubeconfigHandler handles kubeconfig requests
 type KubeconfigHandler struct {
-	state state.State
+	state                state.State
+	config               client.ConfigService // Generates kubeconfigs through the Management API
+	audit                *audit.Log
+	serviceAccountUsers  map[string]bool // Authenticated users allowed to generate service-account kubeconfigs
+	serviceAccountGroups map[string]bool // Kubernetes groups service-account kubeconfigs may carry
 }
 
-// NewKubeconfigHandler creates a new KubeconfigHandler
-func NewKubeconfigHandler(s state.State) *KubeconfigHandler {
-	return &KubeconfigHandler{state: s}
+// NewKubeconfigHandler creates a new KubeconfigHandler.
+// serviceAccountUsers lists the authenticated users allowed to generate service-account kubeconfigs,
+// and serviceAccountGroups the Kubernetes groups those kubeconfigs may carry; empty entries are ignored.
+func NewKubeconfigHandler(s state.State, cfg client.ConfigService, auditLog *audit.Log, serviceAccountUsers, serviceAccountGroups []string) *KubeconfigHandler {
+	return &KubeconfigHandler{
+		state:                s,
+		config:               cfg,
+		audit:                auditLog,
+		serviceAccountUsers:  stringSet(serviceAccountUsers),
+		serviceAccountGroups: stringSet(serviceAccountGroups),
+	}
 }
 
 // GetKubeconfig godoc
-// @Summary      Get cluster kubeconfig
-// @Description  Get the Kubernetes kubeconfig for a cluster. WARNING: Contains sensitive credentials.
+// @Summary      Generate cluster kubeconfig
+// @Description  Generate a Kubernetes kubeconfig for a cluster through the Omni Management API.
+// @Description  By default the kubeconfig makes each user sign in to Omni (OIDC); mode=service-account embeds a token for the given user and groups that expires after ttl.
+// @Description  Service-account kubeconfigs are only available to users listed in OMNI_API_SERVICE_ACCOUNT_KUBECONFIG_USERS, authenticated by a proxy in OMNI_API_TRUSTED_PROXIES, and only for the groups listed in OMNI_API_SERVICE_ACCOUNT_GROUPS.
+// @Description  Every generated kubeconfig is recorded in the audit log.
 // @Tags         kubeconfigs
 // @Produce      json
-// @Param        id   path      string  true  "Cluster ID"
+// @Produce      application/yaml
+// @Param        id          path      string  true   "Cluster ID"
+// @Param        mode        query     string  false  "Kubeconfig mode"  Enums(oidc, service-account)  default(oidc)
+// @Param        user        query     string  false  "User the service account authenticates as (service-account mode)"
+// @Param        groups      query     string  false  "Comma-separated Kubernetes groups of the service account (service-account mode)"
+// @Param        ttl         query     string  false  "How long a service-account kubeconfig is valid, e.g. 8h (default 24h)"
+// @Param        grant_type  query     string  false  "OIDC grant type, e.g. authcode-keyboard (oidc mode)"
+// @Param        download    query     bool    false  "Return the raw kubeconfig file instead of JSON; also selected by Accept: application/yaml"
 // @Success      200  {object}  KubeconfigResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
 // @Failure      404  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /clusters/{id}/kubeconfig [get]
 func (h *KubeconfigHandler) GetKubeconfig(c *gin.Context) {
 	id := c.Param("id")
-	st := h.state
 
-	md := resource.NewMetadata(omniresources.DefaultNamespace, omni.KubeconfigType, id, resource.VersionUndefined)
-
-	res, err := st.Get(c.Request.Context(), md)
+	opts, mode, err := kubeconfigOptions(c)
 	if err != nil {
-		log.Printf("Error getting kubeconfig %s: %v", id, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if opts.ServiceAccount {
+		if reason := h.serviceAccountDenied(c, opts); reason != "" {
+			h.audit.Record(audit.Entry{
+				Actor:      audit.ActorFromRequest(c.Request),
+				Action:     "kubeconfig.service-account.denied",
+				Target:     id,
+				RemoteAddr: c.ClientIP(),
+				Details:    map[string]string{"user": opts.User, "groups": strings.Join(opts.Groups, ",")},
+			})
+			c.JSON(http.StatusForbidden, gin.H{"error": reason})
+			return
+		}
+	}
+
+	// Verify cluster exists
+	md := resource.NewMetadata(omniresources.DefaultNamespace, omni.ClusterType, id, resource.VersionUndefined)
+	if _, err := h.state.Get(c.Request.Context(), md); err != nil {
+		if state.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "cluster not found"})
+			return
+		}
+		log.Printf("Error getting cluster %s: %v", id, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	kc, ok := res.(*omni.Kubeconfig)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error: unexpected resource type"})
+	data, err := h.config.Kubeconfig(c.Request.Context(), id, opts)
+	if err != nil {
+		handleManagementError(c, err)
+		return
+	}
+
+	details := map[string]string{"mode": mode}
+	if opts.ServiceAccount {
+		details["user"] = opts.User
+		details["groups"] = strings.Join(opts.Groups, ",")
+		details["ttl"] = opts.TTL.String()
+	}
+	h.audit.Record(audit.Entry{
+		Actor:      audit.ActorFromRequest(c.Request),
+		Action:     "kubeconfig.generate",
+		Target:     id,
+		RemoteAddr: c.ClientIP(),
+		Details:    details,
+	})
+
+	if wantsRawYAML(c) {
+		respondYAMLFile(c, id+"-kubeconfig.yaml", data)
 		return
 	}
 
-	spec := kc.TypedSpec().Value
 	resp := KubeconfigResponse{
-		ID:        kc.Metadata().ID(),
-		Namespace: kc.Metadata().Namespace(),
-		Data:      base64.StdEncoding.EncodeToString(spec.Data),
+		ID:        id,
+		Namespace: omniresources.DefaultNamespace,
+		Mode:      mode,
+		Data:      base64.StdEncoding.EncodeToString(data),
 		Links: map[string]string{
 			"self":    buildURL(c, "/api/v1/clusters/"+id+"/kubeconfig"),
 			"cluster": buildURL(c, "/api/v1/clusters/"+id),
 		},
 	}
+	if opts.ServiceAccount {
+		expiresAt := time.Now().Add(opts.TTL).UTC()
+		resp.User = opts.User
+		resp.Groups = opts.Groups
+		resp.ExpiresAt = &expiresAt
+	}
 
 	c.JSON(http.StatusOK, resp)
 }
+
+// serviceAccountDenied returns why the caller may not generate a service-account kubeconfig with opts, or "" if it may
+func (h *KubeconfigHandler) serviceAccountDenied(c *gin.Context, opts client.KubeconfigOptions) string {
+	// The caller must come from a trusted authenticating proxy; request headers alone can be set by anyone
+	if user, authenticated := middleware.AuthenticatedUser(c); !authenticated || !h.serviceAccountUsers[user] {
+		return "service-account kubeconfigs are restricted to administrators"
+	}
+	for _, group := range opts.Groups {
+		if !h.serviceAccountGroups[group] {
+			return fmt.Sprintf("group %q is not allowed in service-account kubeconfigs", group)
+		}
+	}
+	return ""
+}
+
+// kubeconfigOptions parses the kubeconfig query parameters
+func kubeconfigOptions(c *gin.Context) (client.KubeconfigOptions, string, error) {
+	mode := c.DefaultQuery("mode", KubeconfigModeOIDC)
+
+	switch mode {
+	case KubeconfigModeOIDC:
+		return client.KubeconfigOptions{GrantType: c.Query("grant_type")}, mode, nil
+	case KubeconfigModeServiceAccount:
+	default:
+		return client.KubeconfigOptions{}, "", errors.New("invalid mode, expected oidc or service-account")
+	}
+
+	opts := client.KubeconfigOptions{
+		ServiceAccount: true,
+		User:           c.Query("user"),
+		TTL:            defaultKubeconfigTTL,
+	}
+	if opts.User == "" {
+		return opts, "", errors.New("user is required in service-account mode")
+	}
+	for _, group := range strings.Split(c.Query("groups"), ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			opts.Groups = append(opts.Groups, group)
+		}
+	}
+	if value := c.Query("ttl"); value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err != nil || ttl <= 0 {
+			return opts, "", errors.New("invalid ttl, expected a positive duration such as 8h")
+		}
+		opts.TTL = ttl
+	}
+
+	return opts, mode, nil
+}
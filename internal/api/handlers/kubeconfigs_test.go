@@ -4,38 +4,41 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"testing"
+	"time"
 
-	"github.com/cosi-project/runtime/pkg/resource"
-	"github.com/cosi-project/runtime/pkg/resource/protobuf"
-	"github.com/cosi-project/runtime/pkg/resource/typed"
 	"github.com/gin-gonic/gin"
-	"github.com/siderolabs/omni/client/api/omni/specs"
+	"github.com/jubblin/omni-api/internal/audit"
+	"github.com/jubblin/omni-api/internal/client"
 	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
-func TestKubeconfigHandler_GetKubeconfig(t *testing.T) {
-	gin.SetMode(gin.TestMode)
-
+func newKubeconfigTestHandler(t *testing.T, config *MockConfigService) (*KubeconfigHandler, *audit.Log) {
 	mockState := new(MockState)
+	mockState.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(omni.NewCluster("default", "cluster-1"), nil)
 
-	kc := typed.NewResource[omni.KubeconfigSpec, omni.KubeconfigExtension](
-		resource.NewMetadata("default", omni.KubeconfigType, "cluster-1", resource.VersionUndefined),
-		protobuf.NewResourceSpec(&specs.KubeconfigSpec{
-			Data: []byte("kubeconfig data"),
-		}),
-	)
+	auditLog, err := audit.New(audit.Config{File: filepath.Join(t.TempDir(), "audit.log")})
+	require.NoError(t, err)
 
-	mockState.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(kc, nil)
+	return NewKubeconfigHandler(mockState, config, auditLog, []string{"admin"}, []string{"deployers", "viewers"}), auditLog
+}
 
-	handler := NewKubeconfigHandler(mockState)
+func TestKubeconfigHandler_GetKubeconfig(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	config := new(MockConfigService)
+	config.On("Kubeconfig", mock.Anything, "cluster-1", client.KubeconfigOptions{}).Return([]byte("kubeconfig data"), nil)
+	handler, auditLog := newKubeconfigTestHandler(t, config)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	c.Params = []gin.Param{{Key: "id", Value: "cluster-1"}}
 	c.Request, _ = http.NewRequest("GET", "/clusters/cluster-1/kubeconfig", nil)
+	c.Request.Header.Set("X-Remote-User", "alice")
 
 	handler.GetKubeconfig(c)
 
@@ -45,5 +48,113 @@ func TestKubeconfigHandler_GetKubeconfig(t *testing.T) {
 	err := json.Unmarshal(w.Body.Bytes(), &resp)
 	assert.NoError(t, err)
 	assert.Equal(t, "cluster-1", resp.ID)
+	assert.Equal(t, KubeconfigModeOIDC, resp.Mode)
+	assert.Nil(t, resp.ExpiresAt)
 	assert.Equal(t, "http://localhost:8080/api/v1/clusters/cluster-1/kubeconfig", resp.Links["self"])
+
+	entries := auditLog.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "alice", entries[0].Actor)
+	assert.Equal(t, "kubeconfig.generate", entries[0].Action)
+	assert.Equal(t, "cluster-1", entries[0].Target)
+}
+
+func TestKubeconfigHandler_GetKubeconfig_ServiceAccount(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	config := new(MockConfigService)
+	config.On("Kubeconfig", mock.Anything, "cluster-1", client.KubeconfigOptions{
+		ServiceAccount: true,
+		User:           "ci",
+		Groups:         []string{"deployers", "viewers"},
+		TTL:            8 * time.Hour,
+	}).Return([]byte("apiVersion: v1\n"), nil)
+	handler, _ := newKubeconfigTestHandler(t, config)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "cluster-1"}}
+	c.Request, _ = http.NewRequest("GET", "/clusters/cluster-1/kubeconfig?mode=service-account&user=ci&groups=deployers,viewers&ttl=8h&download=1", nil)
+	c.Request.RemoteAddr = "10.0.0.1:40000"
+	c.Request.Header.Set("X-Remote-User", "admin")
+
+	authenticate(c)
+	handler.GetKubeconfig(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/yaml", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="cluster-1-kubeconfig.yaml"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "apiVersion: v1\n", w.Body.String())
+}
+
+func TestKubeconfigHandler_GetKubeconfig_InvalidParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{name: "unknown mode", query: "mode=admin"},
+		{name: "service account without user", query: "mode=service-account"},
+		{name: "invalid ttl", query: "mode=service-account&user=ci&ttl=-1h"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := new(MockConfigService)
+			handler, _ := newKubeconfigTestHandler(t, config)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = []gin.Param{{Key: "id", Value: "cluster-1"}}
+			c.Request, _ = http.NewRequest("GET", "/clusters/cluster-1/kubeconfig?"+tt.query, nil)
+
+			handler.GetKubeconfig(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			config.AssertNotCalled(t, "Kubeconfig", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestKubeconfigHandler_GetKubeconfig_ServiceAccountDenied(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		actor      string
+		remoteAddr string
+		groups     string
+	}{
+		{name: "other user", actor: "bob", remoteAddr: "10.0.0.1:40000", groups: "deployers"},
+		{name: "anonymous", remoteAddr: "10.0.0.1:40000", groups: "deployers"},
+		{name: "admin header from an untrusted client", actor: "admin", remoteAddr: "192.0.2.1:40000", groups: "deployers"},
+		{name: "group not allowed", actor: "admin", remoteAddr: "10.0.0.1:40000", groups: "deployers,system:masters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := new(MockConfigService)
+			handler, auditLog := newKubeconfigTestHandler(t, config)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = []gin.Param{{Key: "id", Value: "cluster-1"}}
+			c.Request, _ = http.NewRequest("GET", "/clusters/cluster-1/kubeconfig?mode=service-account&user=ci&groups="+tt.groups, nil)
+			c.Request.RemoteAddr = tt.remoteAddr
+			if tt.actor != "" {
+				c.Request.Header.Set("X-Remote-User", tt.actor)
+			}
+
+			authenticate(c)
+			handler.GetKubeconfig(c)
+
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			config.AssertNotCalled(t, "Kubeconfig", mock.Anything, mock.Anything, mock.Anything)
+			entries := auditLog.Entries()
+			require.Len(t, entries, 1)
+			assert.Equal(t, "kubeconfig.service-account.denied", entries[0].Action)
+			assert.Equal(t, tt.groups, entries[0].Details["groups"])
+		})
+	}
 }
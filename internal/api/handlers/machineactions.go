@@ -5,25 +5,42 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/jubblin/omni-api/internal/operations"
 	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
 	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
 	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/safe"
 	"github.com/cosi-project/runtime/pkg/state"
 )
 
+// MachineMaintenanceRequest represents a request to move a machine into or out of maintenance mode
+type MachineMaintenanceRequest struct {
+	Enabled    *bool  `json:"enabled" binding:"required"`
+	MachineSet string `json:"machine_set,omitempty"` // Machine set to add the machine to when leaving maintenance mode, by default the one it was taken out of
+}
+
+// MachineMaintenanceUpgradeRequest represents a request to upgrade a machine in maintenance mode
+type MachineMaintenanceUpgradeRequest struct {
+	Version   string `json:"version" binding:"required"` // Talos version, e.g. 1.9.5
+	Schematic string `json:"schematic,omitempty"`        // Image factory schematic ID, by default the machine's current schematic
+}
+
 // MachineActionsHandler handles machine action operations
 type MachineActionsHandler struct {
 	state      state.State
-	management interface{} // Management service client
-	talos      interface{}  // Talos service client
+	management client.ManagementService // Management service client
+	talos      client.TalosService  // Talos service client
+	operations *operations.Manager  // Tracks reboots, maintenance mode and maintenance upgrades
 }
 
 // NewMachineActionsHandler creates a new MachineActionsHandler
-func NewMachineActionsHandler(s state.State, mgmt, talos interface{}) *MachineActionsHandler {
+func NewMachineActionsHandler(s state.State, mgmt client.ManagementService, talos client.TalosService, ops *operations.Manager) *MachineActionsHandler {
 	return &MachineActionsHandler{
 		state:      s,
 		management: mgmt,
 		talos:      talos,
+		operations: ops,
 	}
 }
 
@@ -32,8 +49,11 @@ func NewMachineActionsHandler(s state.State, mgmt, talos interface{}) *MachineAc
 // @Description  Trigger a reboot of a machine via Talos API
 // @Tags         machines
 // @Produce      json
-// @Param        id   path      string  true  "Machine ID"
-// @Success      202  {object}  map[string]string
+// @Param        id      path      string  true   "Machine ID"
+// @Param        dryRun  query     bool    false  "Validate the request and return the change without applying it"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200  {object}  DryRunResponse  "Returned instead when dryRun is set"
+// @Success      202  {object}  map[string]string  "Location header points to the operation tracking the reboot"
 // @Failure      404  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /machines/{id}/actions/reboot [post]
@@ -49,14 +69,27 @@ func (h *MachineActionsHandler) RebootMachine(c *gin.Context) {
 	}
 
 	// Use Talos service to reboot machine
-	// Typically: talosClient.Reboot(ctx, machineID)
-	log.Printf("Rebooting machine %s (Talos service integration needed)", id)
-	
-	c.JSON(http.StatusAccepted, gin.H{
+	if err := h.talos.RebootMachine(writeContext(c), id); err != nil {
+		handleTalosError(c, err)
+		return
+	}
+	if isDryRun(c) {
+		respondDryRun(c, &client.Change{Action: client.ChangeNone})
+		return
+	}
+
+	resp := gin.H{
 		"message": "Machine reboot initiated",
 		"machine_id": id,
-		"note": "Talos service integration required for actual reboot",
-	})
+	}
+	startOperation(c, h.operations, operations.Spec{
+		Kind:       "machine-reboot",
+		Target:     id,
+		TargetPath: "/api/v1/machines/" + id + "/status",
+		Track:      operations.MachineRebootTracker(h.state, id),
+	}, resp)
+
+	c.JSON(http.StatusAccepted, resp)
 }
 
 // ShutdownMachine godoc
@@ -64,10 +97,14 @@ func (h *MachineActionsHandler) RebootMachine(c *gin.Context) {
 // @Description  Trigger a shutdown of a machine via Talos API
 // @Tags         machines
 // @Produce      json
-// @Param        id   path      string  true  "Machine ID"
+// @Param        id      path      string  true   "Machine ID"
+// @Param        dryRun  query     bool    false  "Validate the request and return the change without applying it"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200  {object}  DryRunResponse  "Returned instead when dryRun is set"
 // @Success      202  {object}  map[string]string
 // @Failure      404  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
+// @Failure      501  {object}  map[string]string  "Shutting down machines is not supported yet"
 // @Router       /machines/{id}/actions/shutdown [post]
 func (h *MachineActionsHandler) ShutdownMachine(c *gin.Context) {
 	id := c.Param("id")
@@ -81,12 +118,18 @@ func (h *MachineActionsHandler) ShutdownMachine(c *gin.Context) {
 	}
 
 	// Use Talos service to shutdown machine
-	log.Printf("Shutting down machine %s (Talos service integration needed)", id)
-	
+	if err := h.talos.ShutdownMachine(writeContext(c), id); err != nil {
+		handleManagementError(c, err)
+		return
+	}
+	if isDryRun(c) {
+		respondDryRun(c, &client.Change{Action: client.ChangeNone})
+		return
+	}
+
 	c.JSON(http.StatusAccepted, gin.H{
-		"message": "Machine shutdown initiated",
+		"message":    "Machine shutdown initiated",
 		"machine_id": id,
-		"note": "Talos service integration required for actual shutdown",
 	})
 }
 
@@ -95,10 +138,14 @@ func (h *MachineActionsHandler) ShutdownMachine(c *gin.Context) {
 // @Description  Trigger a reset of a machine via Talos API
 // @Tags         machines
 // @Produce      json
-// @Param        id   path      string  true  "Machine ID"
+// @Param        id      path      string  true   "Machine ID"
+// @Param        dryRun  query     bool    false  "Validate the request and return the change without applying it"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200  {object}  DryRunResponse  "Returned instead when dryRun is set"
 // @Success      202  {object}  map[string]string
 // @Failure      404  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
+// @Failure      501  {object}  map[string]string  "Resetting machines is not supported yet"
 // @Router       /machines/{id}/actions/reset [post]
 func (h *MachineActionsHandler) ResetMachine(c *gin.Context) {
 	id := c.Param("id")
@@ -112,41 +159,50 @@ func (h *MachineActionsHandler) ResetMachine(c *gin.Context) {
 	}
 
 	// Use Talos service to reset machine
-	log.Printf("Resetting machine %s (Talos service integration needed)", id)
-	
+	if err := h.talos.ResetMachine(writeContext(c), id); err != nil {
+		handleManagementError(c, err)
+		return
+	}
+	if isDryRun(c) {
+		respondDryRun(c, &client.Change{Action: client.ChangeNone})
+		return
+	}
+
 	c.JSON(http.StatusAccepted, gin.H{
-		"message": "Machine reset initiated",
+		"message":    "Machine reset initiated",
 		"machine_id": id,
-		"note": "Talos service integration required for actual reset",
 	})
 }
 
 // ToggleMaintenance godoc
 // @Summary      Toggle machine maintenance mode
-// @Description  Enable or disable maintenance mode for a machine
+// @Description  Move a machine into maintenance mode by taking it out of its machine set, which makes Omni remove it from the cluster and reset it,
+// @Description  or out of maintenance mode by adding it back to the machine set it was taken out of, or to machine_set.
+// @Description  Machines of machine sets that allocate from a machine class cannot be moved; scale the machine set instead.
+// @Description  Control plane machines are refused with 412 if they are the last of their machine set, or if the other healthy control plane machines would not make up an etcd quorum.
+// @Description  maintenance is the current MachineStatus state; the operation completes once it matches enabled.
 // @Tags         machines
 // @Accept       json
 // @Produce      json
-// @Param        id       path      string  true  "Machine ID"
-// @Param        enabled  body      map[string]bool  true  "Maintenance mode enabled"
-// @Success      200      {object}  map[string]string
+// @Param        id       path      string                     true   "Machine ID"
+// @Param        request  body      MachineMaintenanceRequest  true   "Maintenance mode request"
+// @Param        dryRun   query     bool                       false  "Validate the request and return the change without applying it"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200      {object}  map[string]interface{}  "The machine already is in the requested state; a DryRunResponse when dryRun is set"
+// @Success      202      {object}  map[string]interface{}  "Location header points to the operation tracking the maintenance state"
 // @Failure      400      {object}  map[string]string
 // @Failure      404      {object}  map[string]string
+// @Failure      412      {object}  map[string]string
 // @Failure      500      {object}  map[string]string
 // @Router       /machines/{id}/actions/maintenance [post]
 func (h *MachineActionsHandler) ToggleMaintenance(c *gin.Context) {
 	id := c.Param("id")
-	var req map[string]bool
+	var req MachineMaintenanceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	enabled, ok := req["enabled"]
-	if !ok {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "enabled field is required"})
-		return
-	}
+	enabled := *req.Enabled
 
 	// Verify machine exists
 	md := resource.NewMetadata(omniresources.DefaultNamespace, omni.MachineType, id, resource.VersionUndefined)
@@ -157,12 +213,109 @@ func (h *MachineActionsHandler) ToggleMaintenance(c *gin.Context) {
 	}
 
 	// Use Management service to toggle maintenance mode
-	log.Printf("Setting maintenance mode for machine %s to %v (Management service integration needed)", id, enabled)
-	
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Maintenance mode updated",
-		"machine_id": id,
+	change, err := h.management.SetMachineMaintenance(writeContext(c), id, enabled, req.MachineSet)
+	if err != nil {
+		handleManagementError(c, err)
+		return
+	}
+	if isDryRun(c) {
+		respondDryRun(c, change)
+		return
+	}
+
+	maintenance, err := h.maintenance(c, id)
+	if err != nil {
+		log.Printf("Error getting status of machine %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	resp := gin.H{
+		"machine_id":          id,
 		"maintenance_enabled": enabled,
-		"note": "Management service integration required for actual update",
-	})
+		"maintenance":         maintenance,
+	}
+	if node := change.Desired; node != nil || change.Current != nil {
+		if node == nil {
+			node = change.Current
+		}
+		resp["machine_set"], _ = node.Metadata().Labels().Get(omni.LabelMachineSet)
+	}
+
+	if change.Action == client.ChangeNone {
+		resp["message"] = "Maintenance mode unchanged"
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	resp["message"] = "Maintenance mode update initiated"
+	startOperation(c, h.operations, operations.Spec{
+		Kind:       "machine-maintenance",
+		Target:     id,
+		TargetPath: "/api/v1/machines/" + id + "/status",
+		Track:      operations.MachineMaintenanceTracker(h.state, id, enabled),
+	}, resp)
+
+	c.JSON(http.StatusAccepted, resp)
+}
+
+// MaintenanceUpgrade godoc
+// @Summary      Upgrade a machine in maintenance mode
+// @Description  Install a Talos version on a machine that runs in maintenance mode and is not part of a cluster, e.g. to bring new machines to a baseline before allocating them.
+// @Description  The machine keeps its image factory schematic unless schematic is set. The operation completes once the machine reports the new version.
+// @Tags         machines
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                            true   "Machine ID"
+// @Param        request  body      MachineMaintenanceUpgradeRequest  true   "Talos version and schematic"
+// @Param        dryRun   query     bool                              false  "Validate the request without upgrading"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200  {object}  DryRunResponse  "Returned instead when dryRun is set"
+// @Success      202  {object}  map[string]interface{}  "Location header points to the operation tracking the upgrade"
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      412  {object}  map[string]string  "The machine is not in maintenance mode or its schematic is unknown"
+// @Failure      500  {object}  map[string]string
+// @Router       /machines/{id}/actions/maintenance-upgrade [post]
+func (h *MachineActionsHandler) MaintenanceUpgrade(c *gin.Context) {
+	id := c.Param("id")
+	var req MachineMaintenanceUpgradeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.talos.MaintenanceUpgrade(writeContext(c), id, req.Version, req.Schematic); err != nil {
+		handleTalosError(c, err)
+		return
+	}
+	if isDryRun(c) {
+		respondDryRun(c, &client.Change{Action: client.ChangeNone})
+		return
+	}
+
+	resp := gin.H{
+		"message":    "Maintenance upgrade initiated",
+		"machine_id": id,
+		"version":    req.Version,
+	}
+	startOperation(c, h.operations, operations.Spec{
+		Kind:       "machine-maintenance-upgrade",
+		Target:     id,
+		TargetPath: "/api/v1/machines/" + id + "/status",
+		Track:      operations.MachineMaintenanceUpgradeTracker(h.state, id, req.Version),
+	}, resp)
+
+	c.JSON(http.StatusAccepted, resp)
+}
+
+// maintenance returns whether a machine currently runs in maintenance mode
+func (h *MachineActionsHandler) maintenance(c *gin.Context, id string) (bool, error) {
+	machineStatus, err := safe.StateGet[*omni.MachineStatus](c.Request.Context(), h.state, omni.NewMachineStatus(omniresources.DefaultNamespace, id).Metadata())
+	if err != nil {
+		if state.IsNotFoundError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return machineStatus.TypedSpec().Value.Maintenance, nil
 }
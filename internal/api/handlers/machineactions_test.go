@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/jubblin/omni-api/internal/operations"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newMaintenanceState(maintenance bool) *MockState {
+	mockState := new(MockState)
+	mockState.On("Get", mock.Anything, ofType(omni.MachineType), mock.Anything).Return(omni.NewMachine("default", "m1"), nil)
+
+	machineStatus := omni.NewMachineStatus("default", "m1")
+	machineStatus.TypedSpec().Value.Connected = true
+	machineStatus.TypedSpec().Value.Maintenance = maintenance
+	mockState.On("Get", mock.Anything, ofType(omni.MachineStatusType), mock.Anything).Return(machineStatus, nil)
+	mockState.On("Watch", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errors.New("watch unavailable"))
+
+	return mockState
+}
+
+func TestMachineActionsHandler_ToggleMaintenance(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	node := omni.NewMachineSetNode("default", "m1", omni.NewMachineSet("default", "prod-workers"))
+	mockMgmt := new(MockManagementService)
+	mockMgmt.On("SetMachineMaintenance", mock.Anything, "m1", true, "").Return(&client.Change{Action: client.ChangeDestroy, Current: node}, nil)
+
+	ops := operations.NewManager(operations.Config{})
+	handler := NewMachineActionsHandler(newMaintenanceState(false), mockMgmt, new(MockTalosService), ops)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: "m1"}}
+	c.Request, _ = http.NewRequest("POST", "/machines/m1/actions/maintenance", strings.NewReader(`{"enabled":true}`))
+
+	handler.ToggleMaintenance(c)
+
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, true, resp["maintenance_enabled"])
+	assert.Equal(t, false, resp["maintenance"])
+	assert.Equal(t, "prod-workers", resp["machine_set"])
+	assert.NotEmpty(t, w.Header().Get("Location"))
+
+	op, ok := ops.Wait(context.Background(), resp["operation_id"].(string))
+	require.True(t, ok)
+	assert.Equal(t, "machine-maintenance", op.Kind)
+	assert.Equal(t, "m1", op.Target)
+	mockMgmt.AssertExpectations(t)
+}
+
+func TestMachineActionsHandler_ToggleMaintenanceUnchanged(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockMgmt := new(MockManagementService)
+	mockMgmt.On("SetMachineMaintenance", mock.Anything, "m1", true, "").Return(&client.Change{Action: client.ChangeNone}, nil)
+
+	ops := operations.NewManager(operations.Config{})
+	handler := NewMachineActionsHandler(newMaintenanceState(true), mockMgmt, new(MockTalosService), ops)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: "m1"}}
+	c.Request, _ = http.NewRequest("POST", "/machines/m1/actions/maintenance", strings.NewReader(`{"enabled":true}`))
+
+	handler.ToggleMaintenance(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, true, resp["maintenance"])
+	assert.Empty(t, ops.List())
+}
+
+func TestMachineActionsHandler_ToggleMaintenanceErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		body       string
+		err        error
+		wantStatus int
+	}{
+		{"missing enabled", `{}`, nil, http.StatusBadRequest},
+		{"machine class", `{"enabled":true}`, status.Error(codes.FailedPrecondition, "machine set prod-workers allocates machines from a machine class, scale it instead"), http.StatusPreconditionFailed},
+		{"unknown machine set", `{"enabled":false}`, status.Error(codes.InvalidArgument, "machine m1 was not taken out of a machine set, machine_set is required"), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockMgmt := new(MockManagementService)
+			mockMgmt.On("SetMachineMaintenance", mock.Anything, "m1", mock.Anything, "").Return(nil, tt.err)
+			handler := NewMachineActionsHandler(newMaintenanceState(false), mockMgmt, new(MockTalosService), operations.NewManager(operations.Config{}))
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = gin.Params{{Key: "id", Value: "m1"}}
+			c.Request, _ = http.NewRequest("POST", "/machines/m1/actions/maintenance", strings.NewReader(tt.body))
+
+			handler.ToggleMaintenance(c)
+
+			assert.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestMachineActionsHandler_MaintenanceUpgrade(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockTalos := new(MockTalosService)
+	mockTalos.On("MaintenanceUpgrade", mock.Anything, "m1", "1.9.5", "").Return(nil)
+
+	ops := operations.NewManager(operations.Config{})
+	handler := NewMachineActionsHandler(newMaintenanceState(true), new(MockManagementService), mockTalos, ops)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: "m1"}}
+	c.Request, _ = http.NewRequest("POST", "/machines/m1/actions/maintenance-upgrade", strings.NewReader(`{"version":"1.9.5"}`))
+
+	handler.MaintenanceUpgrade(c)
+
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "1.9.5", resp["version"])
+	assert.NotEmpty(t, w.Header().Get("Location"))
+
+	op, ok := ops.Wait(context.Background(), resp["operation_id"].(string))
+	require.True(t, ok)
+	assert.Equal(t, "machine-maintenance-upgrade", op.Kind)
+	mockTalos.AssertExpectations(t)
+}
+
+func TestMachineActionsHandler_MaintenanceUpgradeErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		body       string
+		err        error
+		wantStatus int
+	}{
+		{"missing version", `{}`, nil, http.StatusBadRequest},
+		{"not in maintenance", `{"version":"1.9.5"}`, status.Error(codes.FailedPrecondition, "machine m1 is not in maintenance mode"), http.StatusPreconditionFailed},
+		{"unknown version", `{"version":"0.1.0"}`, status.Error(codes.InvalidArgument, "unknown Talos version 0.1.0"), http.StatusBadRequest},
+		{"unknown machine", `{"version":"1.9.5"}`, status.Error(codes.NotFound, "machine m1 not found"), http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockTalos := new(MockTalosService)
+			mockTalos.On("MaintenanceUpgrade", mock.Anything, "m1", mock.Anything, "").Return(tt.err)
+			ops := operations.NewManager(operations.Config{})
+			handler := NewMachineActionsHandler(newMaintenanceState(true), new(MockManagementService), mockTalos, ops)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = gin.Params{{Key: "id", Value: "m1"}}
+			c.Request, _ = http.NewRequest("POST", "/machines/m1/actions/maintenance-upgrade", strings.NewReader(tt.body))
+
+			handler.MaintenanceUpgrade(c)
+
+			assert.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			assert.Empty(t, ops.List())
+		})
+	}
+}
+
+func TestMachineActionsHandler_ShutdownAndResetUnimplemented(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockTalos := new(MockTalosService)
+	mockTalos.On("ShutdownMachine", mock.Anything, "m1").Return(status.Error(codes.Unimplemented, "shutting down machines is not supported yet"))
+	mockTalos.On("ResetMachine", mock.Anything, "m1").Return(status.Error(codes.Unimplemented, "resetting machines is not supported yet"))
+	handler := NewMachineActionsHandler(newMaintenanceState(false), new(MockManagementService), mockTalos, operations.NewManager(operations.Config{}))
+
+	tests := []struct {
+		name   string
+		path   string
+		action gin.HandlerFunc
+	}{
+		{"shutdown", "/machines/m1/actions/shutdown", handler.ShutdownMachine},
+		{"shutdown dry run", "/machines/m1/actions/shutdown?dryRun=true", handler.ShutdownMachine},
+		{"reset", "/machines/m1/actions/reset", handler.ResetMachine},
+		{"reset dry run", "/machines/m1/actions/reset?dryRun=true", handler.ResetMachine},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = gin.Params{{Key: "id", Value: "m1"}}
+			c.Request, _ = http.NewRequest("POST", tt.path, nil)
+
+			tt.action(c)
+
+			assert.Equal(t, http.StatusNotImplemented, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), "not supported yet")
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/safe"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/allocation"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/siderolabs/omni/client/pkg/cosi/labels"
+	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+// MachineAllocationRequest represents a request to add free machines to a machine set
+type MachineAllocationRequest struct {
+	Count        int    `json:"count" binding:"required,min=1"`
+	MachineClass string `json:"machine_class,omitempty"` // Choose machines matching the selectors of this machine class
+	Selector     string `json:"selector,omitempty"`      // Omni label selector, e.g. "rack=r1, !gpu"
+	Arch         string `json:"arch,omitempty"`          // e.g. amd64
+	Platform     string `json:"platform,omitempty"`      // e.g. metal
+	Ranking      string `json:"ranking,omitempty"`       // lru (default) or spread
+	SpreadLabel  string `json:"spread_label,omitempty"`  // Label the spread ranking balances, default topology.kubernetes.io/zone
+}
+
+// MachineAllocationResponse represents the machines chosen for a machine set
+type MachineAllocationResponse struct {
+	MachineSet string                 `json:"machine_set"`
+	Cluster    string                 `json:"cluster"`
+	Ranking    string                 `json:"ranking"`
+	DryRun     bool                   `json:"dry_run"`
+	Chosen     []allocation.Choice    `json:"chosen"`
+	Rejected   []allocation.Rejection `json:"rejected"`
+	Links      map[string]string      `json:"_links,omitempty"`
+}
+
+// MachineAllocationHandler handles machine allocation requests
+type MachineAllocationHandler struct {
+	state      state.State
+	management client.ManagementService
+	usage      *allocation.Usage
+
+	mu sync.Mutex // Keeps concurrent requests from choosing the same machines
+}
+
+// NewMachineAllocationHandler creates a new MachineAllocationHandler
+func NewMachineAllocationHandler(s state.State, mgmt client.ManagementService, usage *allocation.Usage) *MachineAllocationHandler {
+	return &MachineAllocationHandler{state: s, management: mgmt, usage: usage}
+}
+
+// AllocateMachines godoc
+// @Summary      Allocate machines to a machine set
+// @Description  Choose free machines and add them to a machine set that is not backed by a machine class, creating a MachineSetNode for each.
+// @Description  Machines are eligible if they are connected, not already in a machine set, match the selector or machine class and the arch and platform.
+// @Description  Eligible machines are ranked by "lru" (least recently allocated first) or "spread" (balance the machine set over the values of spread_label, e.g. racks or zones).
+// @Description  Either all chosen machines are allocated or none; the response lists every other machine with the reason it was not chosen.
+// @Tags         machinesets
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                    true   "Machine set ID"
+// @Param        request  body      MachineAllocationRequest  true   "Allocation request"
+// @Param        dryRun   query     bool                      false  "Choose the machines without allocating them"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200  {object}  MachineAllocationResponse  "Returned when dryRun is set"
+// @Success      201  {object}  MachineAllocationResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]interface{}  "Too few eligible machines; rejected lists the reasons"
+// @Failure      412  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /machinesets/{id}/allocate [post]
+func (h *MachineAllocationHandler) AllocateMachines(c *gin.Context) {
+	id := c.Param("id")
+
+	var req MachineAllocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (req.MachineClass == "") == (req.Selector == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "exactly one of machine_class and selector is required"})
+		return
+	}
+	ranker, err := allocation.NewRanker(req.Ranking, req.SpreadLabel)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Ranking == "" {
+		req.Ranking = allocation.DefaultRanking
+	}
+
+	ctx := c.Request.Context()
+	machineSet, err := safe.StateGet[*omni.MachineSet](ctx, h.state, omni.NewMachineSet(omniresources.DefaultNamespace, id).Metadata())
+	if err != nil {
+		if state.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "machine set not found"})
+			return
+		}
+		log.Printf("Error getting machine set %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	cluster, _ := machineSet.Metadata().Labels().Get(omni.LabelCluster)
+
+	filter := allocation.Filter{Arch: req.Arch, Platform: req.Platform}
+	selectors := []string{req.Selector}
+	if req.MachineClass != "" {
+		machineClass, err := safe.StateGet[*omni.MachineClass](ctx, h.state, omni.NewMachineClass(omniresources.DefaultNamespace, req.MachineClass).Metadata())
+		if err != nil {
+			if state.IsNotFoundError(err) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "machine class " + req.MachineClass + " not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		selectors = machineClass.TypedSpec().Value.MatchLabels
+	}
+	if filter.Selectors, err = labels.ParseSelectors(selectors); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid selector: " + err.Error()})
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	candidates, err := h.candidates(c)
+	if err != nil {
+		log.Printf("Error listing machines: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var members []allocation.Candidate
+	for _, candidate := range candidates {
+		if candidate.AllocatedTo == id {
+			members = append(members, candidate)
+		}
+	}
+
+	result, err := allocation.Select(candidates, members, filter, req.Count, ranker)
+	if err != nil {
+		var insufficient *allocation.InsufficientError
+		if errors.As(err, &insufficient) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "rejected": result.Rejected})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	machineIDs := make([]string, 0, len(result.Chosen))
+	for _, choice := range result.Chosen {
+		machineIDs = append(machineIDs, choice.ID)
+	}
+	if _, err := h.management.AllocateMachines(writeContext(c), id, machineIDs); err != nil {
+		handleManagementError(c, err)
+		return
+	}
+
+	resp := MachineAllocationResponse{
+		MachineSet: id,
+		Cluster:    cluster,
+		Ranking:    req.Ranking,
+		DryRun:     isDryRun(c),
+		Chosen:     result.Chosen,
+		Rejected:   result.Rejected,
+		Links: map[string]string{
+			"machineset": buildURL(c, "/api/v1/machinesets/"+id),
+			"nodes":      buildURL(c, "/api/v1/machinesetnodes?machineset="+id),
+		},
+	}
+	if resp.Rejected == nil {
+		resp.Rejected = []allocation.Rejection{}
+	}
+	if isDryRun(c) {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	h.usage.Observe(time.Now(), machineIDs...)
+	c.JSON(http.StatusCreated, resp)
+}
+
+// candidates returns every machine with the machine set it is allocated to.
+// Allocated machines are recorded as used now, for least recently used ranking.
+func (h *MachineAllocationHandler) candidates(c *gin.Context) ([]allocation.Candidate, error) {
+	ctx := c.Request.Context()
+
+	allocated, err := allocatedMachines(ctx, h.state)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	statuses, err := safe.StateListAll[*omni.MachineStatus](ctx, h.state)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]allocation.Candidate, 0, statuses.Len())
+	for status := range statuses.All() {
+		machineID := status.Metadata().ID()
+		if machineSet, ok := allocated[machineID]; ok {
+			h.usage.Observe(now, machineID)
+			candidates = append(candidates, allocation.Candidate{ID: machineID, Labels: status.Metadata().Labels().Raw(), AllocatedTo: machineSet, LastUsed: now})
+			continue
+		}
+
+		candidates = append(candidates, allocation.Candidate{
+			ID:        machineID,
+			Labels:    status.Metadata().Labels().Raw(),
+			Connected: status.TypedSpec().Value.Connected,
+			LastUsed:  h.usage.LastUsed(machineID),
+		})
+	}
+	return candidates, nil
+}
+
+// allocatedMachines returns the machine set of every machine that is part of a cluster or machine set.
+// Machines whose machine set is not known map to "(unknown)".
+func allocatedMachines(ctx context.Context, st state.State) (map[string]string, error) {
+	allocated := map[string]string{}
+	for _, resourceType := range []resource.Type{omni.ClusterMachineType, omni.MachineSetNodeType} {
+		list, err := st.List(ctx, resource.NewMetadata(omniresources.DefaultNamespace, resourceType, "", resource.VersionUndefined))
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range list.Items {
+			machineSet, _ := item.Metadata().Labels().Get(omni.LabelMachineSet)
+			if machineSet == "" {
+				machineSet = "(unknown)"
+			}
+			allocated[item.Metadata().ID()] = machineSet
+		}
+	}
+	return allocated, nil
+}
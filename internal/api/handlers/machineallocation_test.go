@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/allocation"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func ofType(t resource.Type) interface{} {
+	return mock.MatchedBy(func(k resource.Kind) bool { return k.Type() == t })
+}
+
+func newAllocationState() *MockState {
+	mockState := new(MockState)
+
+	machineSet := omni.NewMachineSet("default", "prod-workers")
+	machineSet.Metadata().Labels().Set(omni.LabelCluster, "prod")
+	mockState.On("Get", mock.Anything, ofType(omni.MachineSetType), mock.Anything).Return(machineSet, nil)
+
+	machineClass := omni.NewMachineClass("default", "rack-a")
+	machineClass.TypedSpec().Value.MatchLabels = []string{"rack=a"}
+	mockState.On("Get", mock.Anything, ofType(omni.MachineClassType), mock.Anything).Return(machineClass, nil)
+
+	var statuses []resource.Resource
+	for _, m := range []struct {
+		id, rack  string
+		connected bool
+	}{{"m1", "a", true}, {"m2", "a", true}, {"m3", "b", true}, {"m4", "a", false}, {"m5", "a", true}} {
+		status := omni.NewMachineStatus("default", m.id)
+		status.Metadata().Labels().Set("rack", m.rack)
+		status.TypedSpec().Value.Connected = m.connected
+		statuses = append(statuses, status)
+	}
+	mockState.On("List", mock.Anything, ofType(omni.MachineStatusType), mock.Anything).Return(resource.List{Items: statuses}, nil)
+
+	allocated := omni.NewClusterMachine("default", "m5")
+	allocated.Metadata().Labels().Set(omni.LabelMachineSet, "prod-cp")
+	mockState.On("List", mock.Anything, ofType(omni.ClusterMachineType), mock.Anything).Return(resource.List{Items: []resource.Resource{allocated}}, nil)
+	mockState.On("List", mock.Anything, ofType(omni.MachineSetNodeType), mock.Anything).Return(resource.List{}, nil)
+
+	return mockState
+}
+
+func TestMachineAllocationHandler_AllocateMachines(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockMgmt := new(MockManagementService)
+	mockMgmt.On("AllocateMachines", mock.Anything, "prod-workers", []string{"m1", "m2"}).Return(nil, nil)
+
+	handler := NewMachineAllocationHandler(newAllocationState(), mockMgmt, allocation.NewUsage())
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "prod-workers"}}
+	c.Request, _ = http.NewRequest("POST", "/machinesets/prod-workers/allocate", strings.NewReader(`{"count":2,"machine_class":"rack-a"}`))
+
+	handler.AllocateMachines(c)
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var resp MachineAllocationResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "prod", resp.Cluster)
+	assert.Equal(t, "lru", resp.Ranking)
+	require.Len(t, resp.Chosen, 2)
+	assert.Equal(t, "m1", resp.Chosen[0].ID)
+	assert.Equal(t, "m2", resp.Chosen[1].ID)
+
+	reasons := map[string]string{}
+	for _, r := range resp.Rejected {
+		reasons[r.ID] = r.Reason
+	}
+	assert.Equal(t, map[string]string{
+		"m3": "labels do not match the selector",
+		"m4": "not connected",
+		"m5": "already allocated to machine set prod-cp",
+	}, reasons)
+	mockMgmt.AssertExpectations(t)
+}
+
+func TestMachineAllocationHandler_AllocateMachinesDryRun(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockMgmt := new(MockManagementService)
+	mockMgmt.On("AllocateMachines", mock.Anything, "prod-workers", []string{"m1"}).Return(nil, nil)
+
+	usage := allocation.NewUsage()
+	handler := NewMachineAllocationHandler(newAllocationState(), mockMgmt, usage)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "prod-workers"}}
+	c.Request, _ = http.NewRequest("POST", "/machinesets/prod-workers/allocate?dryRun=true", strings.NewReader(`{"count":1,"selector":"rack=a"}`))
+
+	handler.AllocateMachines(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp MachineAllocationResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.DryRun)
+	assert.True(t, usage.LastUsed("m1").IsZero())
+}
+
+func TestMachineAllocationHandler_AllocateMachinesErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{"no count", `{"selector":"rack=a"}`, http.StatusBadRequest},
+		{"selector and machine class", `{"count":1,"selector":"rack=a","machine_class":"rack-a"}`, http.StatusBadRequest},
+		{"neither selector nor machine class", `{"count":1}`, http.StatusBadRequest},
+		{"unknown ranking", `{"count":1,"selector":"rack=a","ranking":"random"}`, http.StatusBadRequest},
+		{"too few eligible", `{"count":3,"selector":"rack=a"}`, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockMgmt := new(MockManagementService)
+			handler := NewMachineAllocationHandler(newAllocationState(), mockMgmt, allocation.NewUsage())
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = []gin.Param{{Key: "id", Value: "prod-workers"}}
+			c.Request, _ = http.NewRequest("POST", "/machinesets/prod-workers/allocate", strings.NewReader(tt.body))
+
+			handler.AllocateMachines(c)
+
+			assert.Equal(t, tt.expected, w.Code, w.Body.String())
+			mockMgmt.AssertNotCalled(t, "AllocateMachines", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
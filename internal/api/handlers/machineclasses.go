@@ -5,19 +5,44 @@ import (
 	"net/http"
 
 	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/safe"
 	"github.com/cosi-project/runtime/pkg/state"
 	"github.com/gin-gonic/gin"
+	"github.com/siderolabs/omni/client/api/omni/specs"
+	"github.com/siderolabs/omni/client/pkg/cosi/labels"
 	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
 	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
 )
 
 // MachineClassResponse represents the machine class information returned by the API
 type MachineClassResponse struct {
-	ID            string            `json:"id"`
-	Namespace     string            `json:"namespace"`
-	MatchLabels   []string          `json:"match_labels,omitempty"`
-	AutoProvision bool              `json:"auto_provision,omitempty"`
-	Links         map[string]string `json:"_links,omitempty"`
+	ID            string                 `json:"id"`
+	Namespace     string                 `json:"namespace"`
+	MatchLabels   []string               `json:"match_labels,omitempty"`
+	AutoProvision bool                   `json:"auto_provision,omitempty"`
+	Provision     *MachineClassProvision `json:"provision,omitempty"`
+	Links         map[string]string      `json:"_links,omitempty"`
+}
+
+// MachineClassMatch represents a machine selected by a machine class
+type MachineClassMatch struct {
+	ID         string `json:"id"`
+	Hostname   string `json:"hostname,omitempty"`
+	Connected  bool   `json:"connected"`
+	Available  bool   `json:"available"`             // Connected and not part of a cluster or machine set
+	MachineSet string `json:"machine_set,omitempty"` // Machine set the machine is allocated to
+	Cluster    string `json:"cluster,omitempty"`
+}
+
+// MachineClassMatchesResponse represents the machines a machine class selects
+type MachineClassMatchesResponse struct {
+	ID          string              `json:"id"`
+	MatchLabels []string            `json:"match_labels"`
+	Matching    int                 `json:"matching"`
+	Available   int                 `json:"available"`
+	Allocated   int                 `json:"allocated"`
+	Machines    []MachineClassMatch `json:"machines"`
+	Links       map[string]string   `json:"_links,omitempty"`
 }
 
 // MachineClassHandler handles machine class requests
@@ -71,6 +96,7 @@ func (h *MachineClassHandler) ListMachineClasses(c *gin.Context) {
 
 		if spec.AutoProvision != nil {
 			resp.AutoProvision = true
+			resp.Provision = machineClassProvision(spec.AutoProvision)
 		}
 
 		machineClasses = append(machineClasses, resp)
@@ -121,6 +147,104 @@ func (h *MachineClassHandler) GetMachineClass(c *gin.Context) {
 
 	if spec.AutoProvision != nil {
 		resp.AutoProvision = true
+		resp.Provision = machineClassProvision(spec.AutoProvision)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func machineClassProvision(provision *specs.MachineClassSpec_Provision) *MachineClassProvision {
+	return &MachineClassProvision{
+		ProviderID:   provision.ProviderId,
+		ProviderData: provision.ProviderData,
+		KernelArgs:   provision.KernelArgs,
+	}
+}
+
+// GetMachineClassMatches godoc
+// @Summary      Preview the machines of a machine class
+// @Description  Evaluate the selectors of a machine class against all machines and list the matching machines, with how many are available and how many are already allocated.
+// @Description  Machine classes that provision their machines have no selectors to evaluate.
+// @Tags         machineclasses
+// @Produce      json
+// @Param        id   path      string  true  "Machine Class ID"
+// @Success      200  {object}  MachineClassMatchesResponse
+// @Failure      400  {object}  map[string]string  "The machine class provisions its machines"
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /machineclasses/{id}/matches [get]
+func (h *MachineClassHandler) GetMachineClassMatches(c *gin.Context) {
+	id := c.Param("id")
+	ctx := c.Request.Context()
+
+	mc, err := safe.StateGet[*omni.MachineClass](ctx, h.state, omni.NewMachineClass(omniresources.DefaultNamespace, id).Metadata())
+	if err != nil {
+		if state.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "machine class not found"})
+			return
+		}
+		log.Printf("Error getting machine class %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	spec := mc.TypedSpec().Value
+	if spec.AutoProvision != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "machine class " + id + " provisions its machines and has no selectors"})
+		return
+	}
+	selectors, err := labels.ParseSelectors(spec.MatchLabels)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid selectors: " + err.Error()})
+		return
+	}
+
+	allocated, err := allocatedMachines(ctx, h.state)
+	if err != nil {
+		log.Printf("Error listing allocated machines: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	statuses, err := safe.StateListAll[*omni.MachineStatus](ctx, h.state)
+	if err != nil {
+		log.Printf("Error listing machine statuses: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := MachineClassMatchesResponse{
+		ID:          id,
+		MatchLabels: spec.MatchLabels,
+		Machines:    []MachineClassMatch{},
+		Links: map[string]string{
+			"self":         buildURL(c, "/api/v1/machineclasses/"+id+"/matches"),
+			"machineclass": buildURL(c, "/api/v1/machineclasses/"+id),
+		},
+	}
+	for status := range statuses.All() {
+		// An empty selector list matches every machine, which Omni does not do for machine classes
+		if len(selectors) == 0 || !selectors.Matches(*status.Metadata().Labels()) {
+			continue
+		}
+
+		machine := MachineClassMatch{
+			ID:        status.Metadata().ID(),
+			Hostname:  status.TypedSpec().Value.GetNetwork().GetHostname(),
+			Connected: status.TypedSpec().Value.Connected,
+			Cluster:   status.TypedSpec().Value.Cluster,
+		}
+		machineSet, isAllocated := allocated[machine.ID]
+		machine.MachineSet = machineSet
+		machine.Available = machine.Connected && !isAllocated
+
+		resp.Matching++
+		if isAllocated {
+			resp.Allocated++
+		}
+		if machine.Available {
+			resp.Available++
+		}
+		resp.Machines = append(resp.Machines, machine)
 	}
 
 	c.JSON(http.StatusOK, resp)
@@ -81,3 +81,34 @@ func TestMachineClassHandler_GetMachineClass(t *testing.T) {
 	assert.Equal(t, "class-1", resp.ID)
 	assert.Equal(t, "http://localhost:8080/api/v1/machineclasses/class-1", resp.Links["self"])
 }
+
+func TestMachineClassHandler_GetMachineClassMatches(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := NewMachineClassHandler(newAllocationState())
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "rack-a"}}
+	c.Request, _ = http.NewRequest("GET", "/machineclasses/rack-a/matches", nil)
+
+	handler.GetMachineClassMatches(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp MachineClassMatchesResponse
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, resp.Matching)
+	assert.Equal(t, 2, resp.Available)
+	assert.Equal(t, 1, resp.Allocated)
+
+	machines := map[string]MachineClassMatch{}
+	for _, m := range resp.Machines {
+		machines[m.ID] = m
+	}
+	assert.NotContains(t, machines, "m3")
+	assert.True(t, machines["m1"].Available)
+	assert.False(t, machines["m4"].Available)
+	assert.Equal(t, "prod-cp", machines["m5"].MachineSet)
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/client"
+)
+
+// MachineClassProvision represents how an infrastructure provider creates the machines of a class
+type MachineClassProvision struct {
+	ProviderID   string   `json:"provider_id" binding:"required"`
+	ProviderData string   `json:"provider_data,omitempty"` // Provider specific YAML, e.g. "size: t3.large"
+	KernelArgs   []string `json:"kernel_args,omitempty"`
+}
+
+// MachineClassCreateRequest represents a request to create a machine class.
+// Exactly one of match_labels and provision is required.
+type MachineClassCreateRequest struct {
+	ID          string                 `json:"id" binding:"required"`
+	MatchLabels []string               `json:"match_labels,omitempty"` // Omni label selectors, e.g. "rack=a, !gpu"; a machine matching any of them belongs to the class
+	Provision   *MachineClassProvision `json:"provision,omitempty"`
+}
+
+// MachineClassUpdateRequest represents a request to replace the spec of a machine class.
+// Exactly one of match_labels and provision is required.
+type MachineClassUpdateRequest struct {
+	MatchLabels []string               `json:"match_labels,omitempty"`
+	Provision   *MachineClassProvision `json:"provision,omitempty"`
+}
+
+// MachineClassWriteHandler handles machine class write operations
+type MachineClassWriteHandler struct {
+	state      state.State
+	management client.ManagementService
+}
+
+// NewMachineClassWriteHandler creates a new MachineClassWriteHandler
+func NewMachineClassWriteHandler(s state.State, mgmt client.ManagementService) *MachineClassWriteHandler {
+	return &MachineClassWriteHandler{
+		state:      s,
+		management: mgmt,
+	}
+}
+
+func machineClassSpec(matchLabels []string, provision *MachineClassProvision) *client.MachineClassSpec {
+	spec := &client.MachineClassSpec{MatchLabels: matchLabels}
+	if provision != nil {
+		spec.AutoProvision = &client.MachineClassProvision{
+			ProviderID:   provision.ProviderID,
+			ProviderData: provision.ProviderData,
+			KernelArgs:   provision.KernelArgs,
+		}
+	}
+	return spec
+}
+
+// CreateMachineClass godoc
+// @Summary      Create a machine class
+// @Description  Create a machine class that either selects existing machines by label (match_labels) or has an infrastructure provider create them (provision).
+// @Description  Use GET /machineclasses/{id}/matches to check which machines a label-match class selects.
+// @Tags         machineclasses
+// @Accept       json
+// @Produce      json
+// @Param        machineclass  body      MachineClassCreateRequest  true   "Machine class creation request"
+// @Param        dryRun        query     bool                       false  "Validate the request and return the change without applying it"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200  {object}  DryRunResponse  "Returned instead when dryRun is set"
+// @Success      201  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /machineclasses [post]
+func (h *MachineClassWriteHandler) CreateMachineClass(c *gin.Context) {
+	var req MachineClassCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	change, err := h.management.CreateMachineClass(writeContext(c), req.ID, machineClassSpec(req.MatchLabels, req.Provision))
+	if err != nil {
+		handleManagementError(c, err)
+		return
+	}
+	if isDryRun(c) {
+		respondDryRun(c, change)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Machine class created successfully",
+		"id":      req.ID,
+	})
+}
+
+// UpdateMachineClass godoc
+// @Summary      Update a machine class
+// @Description  Replace the selectors or provisioning settings of a machine class. Machine sets using the class pick up the change.
+// @Description  Provisioning meta values and the gRPC tunnel mode set outside the API are kept.
+// @Tags         machineclasses
+// @Accept       json
+// @Produce      json
+// @Param        id            path      string                     true   "Machine class ID"
+// @Param        machineclass  body      MachineClassUpdateRequest  true   "Machine class update request"
+// @Param        dryRun        query     bool                       false  "Validate the request and return the change without applying it"
+// @Success      200  {object}  map[string]string  "A DryRunResponse when dryRun is set"
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /machineclasses/{id} [put]
+func (h *MachineClassWriteHandler) UpdateMachineClass(c *gin.Context) {
+	id := c.Param("id")
+	var req MachineClassUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	change, err := h.management.UpdateMachineClass(writeContext(c), id, machineClassSpec(req.MatchLabels, req.Provision))
+	if err != nil {
+		handleManagementError(c, err)
+		return
+	}
+	if isDryRun(c) {
+		respondDryRun(c, change)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Machine class updated successfully",
+		"id":      id,
+	})
+}
+
+// DeleteMachineClass godoc
+// @Summary      Delete a machine class
+// @Description  Delete a machine class that no machine set allocates machines from
+// @Tags         machineclasses
+// @Produce      json
+// @Param        id      path      string  true   "Machine class ID"
+// @Param        dryRun  query     bool    false  "Validate the request and return the change without applying it"
+// @Success      200  {object}  map[string]string  "A DryRunResponse when dryRun is set"
+// @Failure      404  {object}  map[string]string
+// @Failure      412  {object}  map[string]string  "The machine class is used by machine sets"
+// @Failure      500  {object}  map[string]string
+// @Router       /machineclasses/{id} [delete]
+func (h *MachineClassWriteHandler) DeleteMachineClass(c *gin.Context) {
+	id := c.Param("id")
+
+	change, err := h.management.DeleteMachineClass(writeContext(c), id)
+	if err != nil {
+		handleManagementError(c, err)
+		return
+	}
+	if isDryRun(c) {
+		respondDryRun(c, change)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Machine class deleted successfully",
+		"id":      id,
+	})
+}
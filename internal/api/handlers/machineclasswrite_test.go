@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMachineClassWriteHandler_CreateMachineClass(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockMgmt := new(MockManagementService)
+	mockMgmt.On("CreateMachineClass", mock.Anything, "aws-large", &client.MachineClassSpec{
+		AutoProvision: &client.MachineClassProvision{ProviderID: "aws", ProviderData: "size: t3.large", KernelArgs: []string{"console=ttyS0"}},
+	}).Return(&client.Change{Action: client.ChangeCreate}, nil)
+
+	handler := NewMachineClassWriteHandler(nil, mockMgmt)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/machineclasses", strings.NewReader(
+		`{"id":"aws-large","provision":{"provider_id":"aws","provider_data":"size: t3.large","kernel_args":["console=ttyS0"]}}`))
+
+	handler.CreateMachineClass(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	mockMgmt.AssertExpectations(t)
+}
+
+func TestMachineClassWriteHandler_DeleteMachineClassInUse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockMgmt := new(MockManagementService)
+	mockMgmt.On("DeleteMachineClass", mock.Anything, "rack-a").
+		Return(nil, status.Error(codes.FailedPrecondition, "machine class rack-a is used by machine sets prod-workers"))
+
+	handler := NewMachineClassWriteHandler(nil, mockMgmt)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "rack-a"}}
+	c.Request, _ = http.NewRequest("DELETE", "/machineclasses/rack-a", nil)
+
+	handler.DeleteMachineClass(c)
+
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	assert.Contains(t, w.Body.String(), "prod-workers")
+}
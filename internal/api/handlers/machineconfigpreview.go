@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/safe"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/diff"
+	"github.com/jubblin/omni-api/internal/machineconfig"
+	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+// MachineConfigPreviewRequest represents a request to preview a machine config with a proposed config patch
+type MachineConfigPreviewRequest struct {
+	Cluster string `json:"cluster,omitempty"` // Expected cluster of the machine, checked if set
+	Patch   string `json:"patch,omitempty"`   // Proposed config patch; the current config is returned if empty
+	Level   string `json:"level,omitempty"`   // cluster, machineset or machine (default)
+	ID      string `json:"id,omitempty"`      // Config patch ID, which orders the patch within its level
+}
+
+// MachineConfigPreviewResponse represents a machine config with a proposed config patch applied
+type MachineConfigPreviewResponse struct {
+	ID         string            `json:"id"`
+	Cluster    string            `json:"cluster"`
+	MachineSet string            `json:"machine_set,omitempty"`
+	Config     string            `json:"config"`            // Merged config with secrets redacted
+	Diff       string            `json:"diff,omitempty"`    // Unified diff against the config without the proposed patch
+	Applied    []string          `json:"applied,omitempty"` // Config patches applied to the base config, in order
+	Links      map[string]string `json:"_links,omitempty"`
+}
+
+// MachineConfigPreviewHandler handles machine config preview requests
+type MachineConfigPreviewHandler struct {
+	state state.State
+}
+
+// NewMachineConfigPreviewHandler creates a new MachineConfigPreviewHandler
+func NewMachineConfigPreviewHandler(s state.State) *MachineConfigPreviewHandler {
+	return &MachineConfigPreviewHandler{state: s}
+}
+
+// PreviewMachineConfig godoc
+// @Summary      Preview a machine config
+// @Description  Render the Talos machine config of a cluster machine with a proposed config patch applied, without changing anything.
+// @Description  The config is rendered like Omni does: the base config generated from the cluster secrets gets every patch Omni applies to the machine, in Omni's order (cluster, then machine set, then machine, by ID within a level).
+// @Description  The proposed patch replaces the existing patch with its ID, or is inserted where Omni would apply it.
+// @Description  Secrets are redacted and the response includes a unified diff against the config rendered without the proposed patch.
+// @Tags         machines
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                       true  "Machine ID"
+// @Param        preview  body      MachineConfigPreviewRequest  true  "Proposed config patch"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200  {object}  MachineConfigPreviewResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      412  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /machines/{id}/config-preview [post]
+func (h *MachineConfigPreviewHandler) PreviewMachineConfig(c *gin.Context) {
+	id := c.Param("id")
+	ctx := c.Request.Context()
+
+	var req MachineConfigPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	level, err := machineconfig.ParseLevel(req.Level)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	clusterMachine, err := h.state.Get(ctx, resource.NewMetadata(omniresources.DefaultNamespace, omni.ClusterMachineType, id, resource.VersionUndefined))
+	if err != nil {
+		if state.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "machine is not part of a cluster"})
+			return
+		}
+		log.Printf("Error getting cluster machine %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	cluster, _ := clusterMachine.Metadata().Labels().Get(omni.LabelCluster)
+	machineSet, _ := clusterMachine.Metadata().Labels().Get(omni.LabelMachineSet)
+	if req.Cluster != "" && req.Cluster != cluster {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "machine belongs to cluster " + cluster})
+		return
+	}
+
+	current, err := h.currentConfig(c, id)
+	if err != nil {
+		return
+	}
+
+	preview, applied := current, []string(nil)
+	if req.Patch != "" {
+		patches, err := h.appliedPatches(c, id, cluster, machineSet)
+		if err != nil {
+			log.Printf("Error listing config patches of machine %s: %v", id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		base, err := h.baseConfig(c, clusterMachine, cluster, current)
+		if err != nil {
+			return
+		}
+
+		// Diff against the config rendered the same way, so the diff only shows the effect of the proposed patch
+		if current, err = machineconfig.Render(base, patches); err != nil {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": "failed to render the current config: " + err.Error()})
+			return
+		}
+		if preview, applied, err = machineconfig.Preview(base, patches, machineconfig.Patch{ID: req.ID, Level: level, Data: req.Patch}); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	redactedCurrent, err := machineconfig.Redact(current)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	redactedPreview, err := machineconfig.Redact(preview)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, MachineConfigPreviewResponse{
+		ID:         id,
+		Cluster:    cluster,
+		MachineSet: machineSet,
+		Config:     string(redactedPreview),
+		Diff:       diff.Unified(string(redactedCurrent), string(redactedPreview), "current/"+id, "preview/"+id),
+		Applied:    applied,
+		Links: map[string]string{
+			"machine": buildURL(c, "/api/v1/machines/"+id),
+			"config":  buildURL(c, "/api/v1/clustermachines/"+id+"/config"),
+			"cluster": buildURL(c, "/api/v1/clusters/"+cluster),
+		},
+	})
+}
+
+// currentConfig returns the rendered config of a cluster machine, responding with an error if there is none
+func (h *MachineConfigPreviewHandler) currentConfig(c *gin.Context, id string) ([]byte, error) {
+	res, err := h.state.Get(c.Request.Context(), resource.NewMetadata(omniresources.DefaultNamespace, omni.ClusterMachineConfigType, id, resource.VersionUndefined))
+	if err != nil {
+		if state.IsNotFoundError(err) {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": "machine config has not been generated yet"})
+			return nil, err
+		}
+		log.Printf("Error getting cluster machine config %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, err
+	}
+
+	cmc, ok := res.(*omni.ClusterMachineConfig)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error: unexpected resource type"})
+		return nil, errors.New("unexpected resource type")
+	}
+
+	spec := cmc.TypedSpec().Value
+	if spec.GenerationError != "" {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "machine config generation failed: " + spec.GenerationError})
+		return nil, errors.New(spec.GenerationError)
+	}
+
+	data, err := spec.GetUncompressedData()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, err
+	}
+	defer data.Free()
+
+	if len(data.Data()) == 0 {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "machine config has not been generated yet"})
+		return nil, errors.New("empty machine config")
+	}
+	return append([]byte(nil), data.Data()...), nil
+}
+
+// baseConfig generates the config Omni applies the config patches of a cluster machine to, responding with an error if it cannot.
+// current is the rendered config of the machine, which the install image is taken from.
+func (h *MachineConfigPreviewHandler) baseConfig(c *gin.Context, clusterMachine resource.Resource, clusterID string, current []byte) ([]byte, error) {
+	ctx := c.Request.Context()
+	respondError := func(what string, err error) error {
+		if state.IsNotFoundError(err) {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": what + " of the machine's cluster not found"})
+			return err
+		}
+		log.Printf("Error getting %s of cluster %s: %v", what, clusterID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return err
+	}
+
+	cluster, err := safe.StateGet[*omni.Cluster](ctx, h.state, omni.NewCluster(omniresources.DefaultNamespace, clusterID).Metadata())
+	if err != nil {
+		return nil, respondError("cluster", err)
+	}
+	clusterSecrets, err := safe.StateGet[*omni.ClusterSecrets](ctx, h.state, omni.NewClusterSecrets(omniresources.DefaultNamespace, clusterID).Metadata())
+	if err != nil {
+		return nil, respondError("secrets", err)
+	}
+	loadBalancer, err := safe.StateGet[*omni.LoadBalancerConfig](ctx, h.state, omni.NewLoadBalancerConfig(omniresources.DefaultNamespace, clusterID).Metadata())
+	if err != nil {
+		return nil, respondError("load balancer config", err)
+	}
+
+	// Omni keeps generating configs for the Talos version a cluster was created with
+	talosVersion := cluster.TypedSpec().Value.TalosVersion
+	configVersion, err := safe.StateGet[*omni.ClusterConfigVersion](ctx, h.state, omni.NewClusterConfigVersion(omniresources.DefaultNamespace, clusterID).Metadata())
+	switch {
+	case err == nil && configVersion.TypedSpec().Value.Version != "":
+		talosVersion = configVersion.TypedSpec().Value.Version
+	case err != nil && !state.IsNotFoundError(err):
+		return nil, respondError("config version", err)
+	}
+
+	var installDisk string
+	genOptions, err := safe.StateGet[*omni.MachineConfigGenOptions](ctx, h.state, omni.NewMachineConfigGenOptions(omniresources.DefaultNamespace, clusterMachine.Metadata().ID()).Metadata())
+	switch {
+	case err == nil:
+		installDisk = genOptions.TypedSpec().Value.InstallDisk
+	case !state.IsNotFoundError(err):
+		return nil, respondError("machine config options", err)
+	}
+
+	bundle, err := omni.ToSecretsBundle(clusterSecrets)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, err
+	}
+	installImage, err := machineconfig.InstallImage(current)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, err
+	}
+
+	_, controlPlane := clusterMachine.Metadata().Labels().Get(omni.LabelControlPlaneRole)
+	base, err := machineconfig.Base(machineconfig.BaseInput{
+		Cluster:           clusterID,
+		Machine:           clusterMachine.Metadata().ID(),
+		Secrets:           bundle,
+		TalosVersion:      talosVersion,
+		KubernetesVersion: cluster.TypedSpec().Value.KubernetesVersion,
+		Endpoint:          loadBalancer.TypedSpec().Value.SiderolinkEndpoint,
+		InstallDisk:       installDisk,
+		InstallImage:      installImage,
+		ControlPlane:      controlPlane,
+	})
+	if err != nil {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+		return nil, err
+	}
+	return base, nil
+}
+
+// appliedPatches returns the patches Omni applies to a cluster machine, in order
+func (h *MachineConfigPreviewHandler) appliedPatches(c *gin.Context, id, cluster, machineSet string) ([]machineconfig.Patch, error) {
+	patches, err := h.configPatches(c, id, cluster, machineSet)
+	if err != nil {
+		return nil, err
+	}
+
+	// Omni lists every patch it applies, including its own, in order; without the list the config patches are sorted
+	res, err := safe.StateGet[*omni.ClusterMachineConfigPatches](c.Request.Context(), h.state, omni.NewClusterMachineConfigPatches(omniresources.DefaultNamespace, id).Metadata())
+	if err != nil {
+		if state.IsNotFoundError(err) {
+			return machineconfig.Order(patches, nil), nil
+		}
+		return nil, err
+	}
+	applied, err := res.TypedSpec().Value.GetUncompressedPatches()
+	if err != nil {
+		return nil, err
+	}
+	if applied == nil {
+		applied = []string{}
+	}
+	return machineconfig.Order(patches, applied), nil
+}
+
+// configPatches returns the config patches Omni applies to a cluster machine
+func (h *MachineConfigPreviewHandler) configPatches(c *gin.Context, id, cluster, machineSet string) ([]machineconfig.Patch, error) {
+	items, err := h.state.List(c.Request.Context(), resource.NewMetadata(omniresources.DefaultNamespace, omni.ConfigPatchType, "", resource.VersionUndefined))
+	if err != nil {
+		return nil, err
+	}
+
+	var patches []machineconfig.Patch
+	for _, item := range items.Items {
+		cp, ok := item.(*omni.ConfigPatch)
+		if !ok {
+			continue
+		}
+
+		level, ok := patchLevel(cp.Metadata().Labels(), id, cluster, machineSet)
+		if !ok {
+			continue
+		}
+
+		data, err := cp.TypedSpec().Value.GetUncompressedData()
+		if err != nil {
+			return nil, err
+		}
+		patches = append(patches, machineconfig.Patch{ID: cp.Metadata().ID(), Level: level, Data: string(data.Data())})
+		data.Free()
+	}
+	return patches, nil
+}
+
+// patchLevel returns the level a config patch applies to a cluster machine at, or false if it does not apply
+func patchLevel(labels *resource.Labels, id, cluster, machineSet string) (machineconfig.Level, bool) {
+	if value, ok := labels.Get(omni.LabelClusterMachine); ok {
+		return machineconfig.LevelMachine, value == id
+	}
+	if value, ok := labels.Get(omni.LabelMachine); ok {
+		return machineconfig.LevelMachine, value == id
+	}
+	if value, ok := labels.Get(omni.LabelCluster); !ok || value != cluster {
+		return 0, false
+	}
+	if value, ok := labels.Get(omni.LabelMachineSet); ok {
+		return machineconfig.LevelMachineSet, value == machineSet
+	}
+	return machineconfig.LevelCluster, true
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/gin-gonic/gin"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	talosconfig "github.com/siderolabs/talos/pkg/machinery/config"
+	"github.com/siderolabs/talos/pkg/machinery/config/generate/secrets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const previewMachineConfig = `version: v1alpha1
+machine:
+  type: worker
+  token: abcdef.0123456789abcdef
+  network:
+    hostname: worker-1
+cluster:
+  id: cluster-id
+  secret: cluster-secret
+  controlPlane:
+    endpoint: https://10.5.0.1:6443
+`
+
+func newConfigPatch(t *testing.T, id, data string, labels map[string]string) *omni.ConfigPatch {
+	cp := omni.NewConfigPatch("default", id)
+	for k, v := range labels {
+		cp.Metadata().Labels().Set(k, v)
+	}
+	require.NoError(t, cp.TypedSpec().Value.SetUncompressedData([]byte(data)))
+	return cp
+}
+
+var (
+	previewSecretsOnce sync.Once
+	previewSecretsData []byte
+)
+
+// previewSecrets returns a serialized secrets bundle, generated once since generating keys is slow
+func previewSecrets(t *testing.T) []byte {
+	previewSecretsOnce.Do(func() {
+		bundle, err := secrets.NewBundle(secrets.NewFixedClock(time.Now()), talosconfig.TalosVersionCurrent)
+		require.NoError(t, err)
+		previewSecretsData, err = json.Marshal(bundle)
+		require.NoError(t, err)
+	})
+	return previewSecretsData
+}
+
+func newPreviewState(t *testing.T) *MockState {
+	clusterMachine := omni.NewClusterMachine("default", "machine-1")
+	clusterMachine.Metadata().Labels().Set(omni.LabelCluster, "prod")
+	clusterMachine.Metadata().Labels().Set(omni.LabelMachineSet, "prod-workers")
+
+	config := omni.NewClusterMachineConfig("default", "machine-1")
+	require.NoError(t, config.TypedSpec().Value.SetUncompressedData([]byte(previewMachineConfig)))
+
+	cluster := omni.NewCluster("default", "prod")
+	cluster.TypedSpec().Value.TalosVersion = "1.8.0"
+	cluster.TypedSpec().Value.KubernetesVersion = "1.31.0"
+
+	clusterSecrets := omni.NewClusterSecrets("default", "prod")
+	clusterSecrets.TypedSpec().Value.Data = previewSecrets(t)
+
+	loadBalancer := omni.NewLoadBalancerConfig("default", "prod")
+	loadBalancer.TypedSpec().Value.SiderolinkEndpoint = "https://prod.omni.local:6443"
+
+	resources := map[resource.Type]resource.Resource{
+		omni.ClusterMachineConfigType: config,
+		omni.ClusterType:              cluster,
+		omni.ClusterSecretsType:       clusterSecrets,
+		omni.LoadBalancerConfigType:   loadBalancer,
+	}
+
+	mockState := new(MockState)
+	mockState.On("Get", mock.Anything, mock.MatchedBy(func(md resource.Pointer) bool {
+		return md.Type() == omni.ClusterMachineType && md.ID() == "machine-1"
+	}), mock.Anything).Return(clusterMachine, nil)
+	mockState.On("Get", mock.Anything, mock.MatchedBy(func(md resource.Pointer) bool {
+		return md.Type() == omni.ClusterMachineType && md.ID() != "machine-1"
+	}), mock.Anything).Return(nil, notFoundError{})
+	for resourceType, res := range resources {
+		mockState.On("Get", mock.Anything, mock.MatchedBy(func(md resource.Pointer) bool {
+			return md.Type() == resourceType
+		}), mock.Anything).Return(res, nil)
+	}
+	// Omni's ordered patch list, config options and config version are optional
+	mockState.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(nil, notFoundError{})
+	mockState.On("List", mock.Anything, mock.Anything, mock.Anything).Return(resource.List{Items: []resource.Resource{
+		newConfigPatch(t, "400-hostname", "machine:\n  network:\n    hostname: worker-1\n", map[string]string{
+			omni.LabelCluster: "prod", omni.LabelClusterMachine: "machine-1",
+		}),
+		newConfigPatch(t, "400-other-set", "machine:\n  network:\n    hostname: other\n", map[string]string{
+			omni.LabelCluster: "prod", omni.LabelMachineSet: "prod-control-planes",
+		}),
+		newConfigPatch(t, "400-other-machine", "machine:\n  network:\n    hostname: other\n", map[string]string{
+			omni.LabelCluster: "prod", omni.LabelClusterMachine: "machine-2",
+		}),
+	}}, nil)
+	return mockState
+}
+
+func previewMachineConfigRequest(mockState *MockState, id, body string) *httptest.ResponseRecorder {
+	r := gin.New()
+	r.POST("/machines/:id/config-preview", NewMachineConfigPreviewHandler(mockState).PreviewMachineConfig)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/machines/"+id+"/config-preview", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestMachineConfigPreviewHandler_PreviewMachineConfig(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("machine patch", func(t *testing.T) {
+		w := previewMachineConfigRequest(newPreviewState(t), "machine-1", `{"cluster":"prod","patch":"machine:\n  network:\n    hostname: worker-2\n"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var resp MachineConfigPreviewResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "prod", resp.Cluster)
+		assert.Equal(t, "prod-workers", resp.MachineSet)
+		assert.Contains(t, resp.Config, "hostname: worker-2")
+		assert.NotContains(t, resp.Config, "cluster-secret")
+		assert.Contains(t, resp.Diff, "-        hostname: worker-1\n+        hostname: worker-2")
+		assert.Equal(t, []string{"400-hostname", ""}, resp.Applied)
+	})
+
+	t.Run("replaces the patch with its ID", func(t *testing.T) {
+		w := previewMachineConfigRequest(newPreviewState(t), "machine-1", `{"id":"400-hostname","patch":"machine:\n  kubelet:\n    image: kubelet\n"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var resp MachineConfigPreviewResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, []string{"400-hostname"}, resp.Applied)
+		assert.NotContains(t, resp.Config, "hostname: worker-1")
+		assert.Contains(t, resp.Diff, "-        hostname: worker-1")
+		assert.Contains(t, resp.Diff, "+        image: kubelet")
+	})
+
+	t.Run("machine patches keep precedence over cluster patches", func(t *testing.T) {
+		w := previewMachineConfigRequest(newPreviewState(t), "machine-1", `{"level":"cluster","id":"500-hostname","patch":"machine:\n  network:\n    hostname: renamed\n"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var resp MachineConfigPreviewResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, []string{"500-hostname", "400-hostname"}, resp.Applied)
+		assert.Empty(t, resp.Diff)
+	})
+
+	t.Run("current config", func(t *testing.T) {
+		w := previewMachineConfigRequest(newPreviewState(t), "machine-1", `{}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), "hostname: worker-1")
+		assert.NotContains(t, w.Body.String(), "0123456789abcdef")
+	})
+}
+
+func TestMachineConfigPreviewHandler_Errors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		id         string
+		body       string
+		wantStatus int
+	}{
+		{name: "not in a cluster", id: "machine-2", body: `{}`, wantStatus: http.StatusNotFound},
+		{name: "other cluster", id: "machine-1", body: `{"cluster":"dev"}`, wantStatus: http.StatusBadRequest},
+		{name: "invalid level", id: "machine-1", body: `{"level":"node","patch":"machine: {}"}`, wantStatus: http.StatusBadRequest},
+		{name: "invalid patch", id: "machine-1", body: `{"patch":"machine: ["}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := previewMachineConfigRequest(newPreviewState(t), tt.id, tt.body)
+			assert.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/client"
+	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+// maxLogLineLength bounds the memory a single log line can use
+const maxLogLineLength = 1024 * 1024
+
+var serviceNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// MachineLogsHandler handles machine log streaming
+type MachineLogsHandler struct {
+	state state.State
+	talos client.TalosService // Reads logs through Omni and the Talos proxy
+}
+
+// NewMachineLogsHandler creates a new MachineLogsHandler
+func NewMachineLogsHandler(s state.State, talos client.TalosService) *MachineLogsHandler {
+	return &MachineLogsHandler{state: s, talos: talos}
+}
+
+// StreamMachineLogs godoc
+// @Summary      Stream machine logs
+// @Description  Stream the logs of a machine as chunked plain text, or as Server-Sent Events ("log" events) with format=sse or Accept: text/event-stream.
+// @Description  Without a service the machine console logs collected by Omni are returned; "kernel" reads the kernel ring buffer and any other name reads that Talos service (kubelet, etcd, apid, ...) through the Talos proxy.
+// @Description  The stream ends when the client disconnects.
+// @Tags         machines
+// @Produce      plain
+// @Produce      text/event-stream
+// @Param        id          path      string  true   "Machine ID"
+// @Param        service     query     string  false  "Log source: console (default), kernel or a Talos service such as kubelet, etcd or apid"
+// @Param        follow      query     bool    false  "Keep streaming new log lines"
+// @Param        tail_lines  query     int     false  "Start this many lines from the end (default: all lines)"
+// @Param        format      query     string  false  "Output format"  Enums(text, sse)  default(text)
+// @Success      200  {string}  string
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Failure      503  {object}  map[string]string
+// @Router       /machines/{id}/logs [get]
+func (h *MachineLogsHandler) StreamMachineLogs(c *gin.Context) {
+	id := c.Param("id")
+
+	opts := client.LogOptions{Service: c.Query("service"), TailLines: -1}
+	if opts.Service != "" && !serviceNamePattern.MatchString(opts.Service) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid service name"})
+		return
+	}
+	if value := c.Query("follow"); value != "" {
+		follow, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid follow value"})
+			return
+		}
+		opts.Follow = follow
+	}
+	if value := c.Query("tail_lines"); value != "" {
+		tailLines, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || tailLines < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tail_lines value"})
+			return
+		}
+		opts.TailLines = int32(tailLines)
+	}
+
+	format := c.Query("format")
+	if format != "" && format != "text" && format != "sse" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format, expected text or sse"})
+		return
+	}
+	sse := format == "sse" || (format == "" && c.NegotiateFormat(gin.MIMEPlain, "text/event-stream") == "text/event-stream")
+
+	// Verify machine exists
+	md := resource.NewMetadata(omniresources.DefaultNamespace, omni.MachineType, id, resource.VersionUndefined)
+	if _, err := h.state.Get(c.Request.Context(), md); err != nil {
+		if state.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "machine not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The request context is canceled when the client disconnects, which ends the stream
+	ctx := c.Request.Context()
+	logs, err := h.talos.MachineLogs(ctx, id, opts)
+	if err != nil {
+		handleTalosError(c, err)
+		return
+	}
+	done := make(chan struct{})
+	defer close(done)
+	defer logs.Close()
+
+	// Unblock a pending read once the client goes away
+	go func() {
+		select {
+		case <-ctx.Done():
+			logs.Close()
+		case <-done:
+		}
+	}()
+
+	if sse {
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+	} else {
+		c.Header("Content-Type", "text/plain; charset=utf-8")
+	}
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	scanner := bufio.NewScanner(logs)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLogLineLength)
+	for scanner.Scan() {
+		if sse {
+			c.SSEvent("log", scanner.Text())
+		} else {
+			c.Writer.Write(append(scanner.Bytes(), '\n'))
+		}
+		c.Writer.Flush()
+	}
+
+	if err := scanner.Err(); err != nil && ctx.Err() == nil && !errors.Is(err, io.EOF) {
+		log.Printf("Error streaming logs of machine %s: %v", id, err)
+		if sse {
+			c.SSEvent("error", err.Error())
+		} else {
+			c.Writer.WriteString("error: " + err.Error() + "\n")
+		}
+		c.Writer.Flush()
+	}
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newMachineLogsRouter(mockState *MockState, talos *MockTalosService) *gin.Engine {
+	r := gin.New()
+	r.GET("/machines/:id/logs", NewMachineLogsHandler(mockState, talos).StreamMachineLogs)
+	return r
+}
+
+func TestMachineLogsHandler_StreamMachineLogs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockState := new(MockState)
+	mockState.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(omni.NewMachine("default", "machine-1"), nil)
+
+	talos := new(MockTalosService)
+	talos.On("MachineLogs", mock.Anything, "machine-1", client.LogOptions{Service: "kubelet", TailLines: 2}).
+		Return(io.NopCloser(strings.NewReader("line 1\nline 2\n")), nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/machines/machine-1/logs?service=kubelet&tail_lines=2", nil)
+	newMachineLogsRouter(mockState, talos).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "line 1\nline 2\n", w.Body.String())
+}
+
+func TestMachineLogsHandler_StreamMachineLogs_SSE(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockState := new(MockState)
+	mockState.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(omni.NewMachine("default", "machine-1"), nil)
+
+	talos := new(MockTalosService)
+	talos.On("MachineLogs", mock.Anything, "machine-1", client.LogOptions{Follow: true, TailLines: -1}).
+		Return(io.NopCloser(strings.NewReader("booting\n")), nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/machines/machine-1/logs?follow=true", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	newMachineLogsRouter(mockState, talos).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/event-stream")
+	assert.Equal(t, "event:log\ndata:booting\n\n", w.Body.String())
+}
+
+func TestMachineLogsHandler_StreamMachineLogs_Errors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		query      string
+		getErr     error
+		wantStatus int
+	}{
+		{name: "invalid service", query: "service=../etc", wantStatus: http.StatusBadRequest},
+		{name: "invalid tail lines", query: "tail_lines=-5", wantStatus: http.StatusBadRequest},
+		{name: "invalid format", query: "format=json", wantStatus: http.StatusBadRequest},
+		{name: "machine not found", getErr: notFoundError{}, wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockState := new(MockState)
+			mockState.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(nil, tt.getErr)
+			talos := new(MockTalosService)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/machines/machine-1/logs?"+tt.query, nil)
+			newMachineLogsRouter(mockState, talos).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			talos.AssertNotCalled(t, "MachineLogs", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+// notFoundError implements state.ErrNotFound
+type notFoundError struct{}
+
+func (notFoundError) Error() string  { return "resource not found" }
+func (notFoundError) NotFoundError() {}
@@ -1,27 +1,27 @@
 package handlers
 
 import (
-	"log"
 	"net/http"
 
-	"github.com/cosi-project/runtime/pkg/resource"
 	"github.com/cosi-project/runtime/pkg/state"
 	"github.com/gin-gonic/gin"
-	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
-	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/jubblin/omni-api/internal/operations"
 )
 
 // MachineSetActionsHandler handles machine set action operations
 type MachineSetActionsHandler struct {
 	state      state.State
-	management interface{} // Management service client
+	management client.ManagementService // Management service
+	operations *operations.Manager      // Tracks asynchronous actions
 }
 
 // NewMachineSetActionsHandler creates a new MachineSetActionsHandler
-func NewMachineSetActionsHandler(s state.State, mgmt interface{}) *MachineSetActionsHandler {
+func NewMachineSetActionsHandler(s state.State, mgmt client.ManagementService, ops *operations.Manager) *MachineSetActionsHandler {
 	return &MachineSetActionsHandler{
 		state:      s,
 		management: mgmt,
+		operations: ops,
 	}
 }
 
@@ -30,28 +30,38 @@ func NewMachineSetActionsHandler(s state.State, mgmt interface{}) *MachineSetAct
 // @Description  Trigger destruction/teardown of a machine set
 // @Tags         machinesets
 // @Produce      json
-// @Param        id   path      string  true  "Machine set ID"
-// @Success      202  {object}  map[string]string
+// @Param        id      path      string  true   "Machine set ID"
+// @Param        dryRun  query     bool    false  "Validate the request and return the change without applying it"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200  {object}  DryRunResponse  "Returned instead when dryRun is set"
+// @Success      202  {object}  map[string]string  "Location header points to the operation tracking the destruction"
 // @Failure      404  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /machinesets/{id}/actions/destroy [post]
 func (h *MachineSetActionsHandler) TriggerDestroy(c *gin.Context) {
 	id := c.Param("id")
 
-	// Verify machine set exists
-	md := resource.NewMetadata(omniresources.DefaultNamespace, omni.MachineSetType, id, resource.VersionUndefined)
-	_, err := h.state.Get(c.Request.Context(), md)
+	// Trigger machine set destruction using Management service
+	change, err := h.management.TeardownMachineSet(writeContext(c), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "machine set not found"})
+		handleManagementError(c, err)
+		return
+	}
+	if isDryRun(c) {
+		respondDryRun(c, change)
 		return
 	}
-
-	// Use Management service to trigger machine set destruction
-	log.Printf("Triggering destruction for machine set %s (Management service integration needed)", id)
 	
-	c.JSON(http.StatusAccepted, gin.H{
+	resp := gin.H{
 		"message": "Machine set destruction initiated",
 		"machine_set_id": id,
-		"note": "Management service integration required for actual destruction",
-	})
+	}
+	startOperation(c, h.operations, operations.Spec{
+		Kind:       "machineset-destroy",
+		Target:     id,
+		TargetPath: "/api/v1/machinesets/" + id + "/destroy-status",
+		Track:      operations.MachineSetDestroyTracker(h.state, id),
+	}, resp)
+
+	c.JSON(http.StatusAccepted, resp)
 }
@@ -7,18 +7,40 @@ import (
 	"github.com/cosi-project/runtime/pkg/resource"
 	"github.com/cosi-project/runtime/pkg/state"
 	"github.com/gin-gonic/gin"
+	"github.com/siderolabs/omni/client/api/omni/specs"
 	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
 	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
 )
 
 // MachineSetResponse represents the machine set information returned by the API
 type MachineSetResponse struct {
-	ID          string            `json:"id"`
-	Namespace   string            `json:"namespace"`
-	MachineClass string           `json:"machine_class,omitempty"`
-	UpdateStrategy string         `json:"update_strategy,omitempty"`
-	DeleteStrategy string         `json:"delete_strategy,omitempty"`
-	Links       map[string]string `json:"_links,omitempty"`
+	ID                   string                    `json:"id"`
+	Namespace            string                    `json:"namespace"`
+	MachineClass         string                    `json:"machine_class,omitempty"`
+	UpdateStrategy       string                    `json:"update_strategy,omitempty"`
+	DeleteStrategy       string                    `json:"delete_strategy,omitempty"`
+	UpdateStrategyConfig *MachineSetStrategyConfig `json:"update_strategy_config,omitempty"`
+	DeleteStrategyConfig *MachineSetStrategyConfig `json:"delete_strategy_config,omitempty"`
+	Links                map[string]string         `json:"_links,omitempty"`
+}
+
+// MachineSetStrategyConfig configures the Rolling update or delete strategy of a machine set
+type MachineSetStrategyConfig struct {
+	MaxParallelism uint32 `json:"max_parallelism"` // Machines processed at once; 0 means 1
+}
+
+func (c *MachineSetStrategyConfig) maxParallelism() uint32 {
+	if c == nil {
+		return 0
+	}
+	return c.MaxParallelism
+}
+
+func machineSetStrategyConfig(config *specs.MachineSetSpec_UpdateStrategyConfig) *MachineSetStrategyConfig {
+	if config.GetRolling() == nil {
+		return nil
+	}
+	return &MachineSetStrategyConfig{MaxParallelism: config.GetRolling().GetMaxParallelism()}
 }
 
 // MachineSetHandler handles machine set requests
@@ -77,6 +99,8 @@ func (h *MachineSetHandler) ListMachineSets(c *gin.Context) {
 		}
 		resp.UpdateStrategy = spec.UpdateStrategy.String()
 		resp.DeleteStrategy = spec.DeleteStrategy.String()
+		resp.UpdateStrategyConfig = machineSetStrategyConfig(spec.UpdateStrategyConfig)
+		resp.DeleteStrategyConfig = machineSetStrategyConfig(spec.DeleteStrategyConfig)
 
 		// Try to find cluster ID from labels
 		if clusterID, ok := ms.Metadata().Labels().Get("omni.sidero.dev/cluster"); ok {
@@ -136,6 +160,8 @@ func (h *MachineSetHandler) GetMachineSet(c *gin.Context) {
 	}
 	resp.UpdateStrategy = spec.UpdateStrategy.String()
 	resp.DeleteStrategy = spec.DeleteStrategy.String()
+	resp.UpdateStrategyConfig = machineSetStrategyConfig(spec.UpdateStrategyConfig)
+	resp.DeleteStrategyConfig = machineSetStrategyConfig(spec.DeleteStrategyConfig)
 
 	// Try to find cluster ID from labels
 	if clusterID, ok := ms.Metadata().Labels().Get("omni.sidero.dev/cluster"); ok {
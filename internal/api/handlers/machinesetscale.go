@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/safe"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/siderolabs/omni/client/api/omni/specs"
+	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+// MachineSetScale represents the desired and actual machine counts of a machine set,
+// shaped like the Kubernetes scale subresource
+type MachineSetScale struct {
+	ID              string                `json:"id"`
+	ResourceVersion string                `json:"resource_version"` // Pass back in a PUT to only scale if the machine set did not change
+	Scalable        bool                  `json:"scalable"`         // Only machine sets with a fixed number of machines from a machine class can be scaled
+	MachineClass    string                `json:"machine_class,omitempty"`
+	Spec            MachineSetScaleSpec   `json:"spec"`
+	Status          MachineSetScaleStatus `json:"status"`
+	Links           map[string]string     `json:"_links,omitempty"`
+}
+
+// MachineSetScaleSpec represents the desired machine count
+type MachineSetScaleSpec struct {
+	Replicas uint32 `json:"replicas"`
+}
+
+// MachineSetScaleStatus represents the actual machine counts reported by MachineSetStatus
+type MachineSetScaleStatus struct {
+	Replicas          uint32 `json:"replicas"`           // Machines allocated to the machine set
+	ReadyReplicas     uint32 `json:"ready_replicas"`     // Healthy machines
+	ConnectedReplicas uint32 `json:"connected_replicas"` // Machines connected to Omni
+	RequestedReplicas uint32 `json:"requested_replicas"` // Machines Omni is working towards
+	Ready             bool   `json:"ready"`
+	Phase             string `json:"phase,omitempty"`
+}
+
+// MachineSetScaleRequest represents a request to scale a machine set
+type MachineSetScaleRequest struct {
+	Spec            MachineSetScaleRequestSpec `json:"spec"`
+	ResourceVersion string                     `json:"resource_version,omitempty"`
+}
+
+// MachineSetScaleRequestSpec represents the requested machine count
+type MachineSetScaleRequestSpec struct {
+	Replicas *uint32 `json:"replicas" binding:"required"`
+}
+
+// MachineSetScaleHandler handles the scale subresource of machine sets
+type MachineSetScaleHandler struct {
+	state      state.State
+	management client.ManagementService
+}
+
+// NewMachineSetScaleHandler creates a new MachineSetScaleHandler
+func NewMachineSetScaleHandler(s state.State, mgmt client.ManagementService) *MachineSetScaleHandler {
+	return &MachineSetScaleHandler{
+		state:      s,
+		management: mgmt,
+	}
+}
+
+// GetMachineSetScale godoc
+// @Summary      Get the scale of a machine set
+// @Description  Get the desired machine count of a machine set and the actual counts from its status.
+// @Description  For machine sets with manually allocated machines the desired count is the number of machine set nodes.
+// @Tags         machinesets
+// @Produce      json
+// @Param        id   path      string  true  "Machine Set ID"
+// @Success      200  {object}  MachineSetScale
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /machinesets/{id}/scale [get]
+func (h *MachineSetScaleHandler) GetMachineSetScale(c *gin.Context) {
+	id := c.Param("id")
+
+	scale, err := h.scale(c, id)
+	if err != nil {
+		if state.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "machine set not found"})
+			return
+		}
+		log.Printf("Error getting scale of machine set %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, scale)
+}
+
+// UpdateMachineSetScale godoc
+// @Summary      Scale a machine set
+// @Description  Set the number of machines a machine set takes from its machine class. Omni then adds or removes machines following the update and delete strategies.
+// @Description  If resource_version is set and the machine set changed since, nothing is written and 409 is returned.
+// @Tags         machinesets
+// @Accept       json
+// @Produce      json
+// @Param        id      path      string                  true   "Machine Set ID"
+// @Param        scale   body      MachineSetScaleRequest  true   "Desired machine count"
+// @Param        dryRun  query     bool                    false  "Validate the request and return the change without applying it"
+// @Success      200  {object}  MachineSetScale  "A DryRunResponse when dryRun is set"
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string  "The machine set changed since resource_version"
+// @Failure      412  {object}  map[string]string  "The machine set is not backed by a machine class with a fixed machine count"
+// @Failure      500  {object}  map[string]string
+// @Router       /machinesets/{id}/scale [put]
+func (h *MachineSetScaleHandler) UpdateMachineSetScale(c *gin.Context) {
+	id := c.Param("id")
+	var req MachineSetScaleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	change, err := h.management.ScaleMachineSet(writeContext(c), id, *req.Spec.Replicas, req.ResourceVersion)
+	if err != nil {
+		handleManagementError(c, err)
+		return
+	}
+	if isDryRun(c) {
+		respondDryRun(c, change)
+		return
+	}
+
+	scale, err := h.scale(c, id)
+	if err != nil {
+		log.Printf("Error getting scale of machine set %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, scale)
+}
+
+func (h *MachineSetScaleHandler) scale(c *gin.Context, id string) (*MachineSetScale, error) {
+	ctx := c.Request.Context()
+
+	ms, err := safe.StateGet[*omni.MachineSet](ctx, h.state, omni.NewMachineSet(omniresources.DefaultNamespace, id).Metadata())
+	if err != nil {
+		return nil, err
+	}
+
+	scale := &MachineSetScale{
+		ID:              id,
+		ResourceVersion: ms.Metadata().Version().String(),
+		Links: map[string]string{
+			"self":       buildURL(c, "/api/v1/machinesets/"+id+"/scale"),
+			"machineset": buildURL(c, "/api/v1/machinesets/"+id),
+			"status":     buildURL(c, "/api/v1/machinesets/"+id+"/status"),
+		},
+	}
+
+	if allocation := ms.TypedSpec().Value.MachineAllocation; allocation != nil {
+		scale.MachineClass = allocation.Name
+		scale.Scalable = allocation.AllocationType == specs.MachineSetSpec_MachineAllocation_Static
+		scale.Spec.Replicas = allocation.MachineCount
+	} else {
+		nodes, err := safe.StateListAll[*omni.MachineSetNode](ctx, h.state, state.WithLabelQuery(resource.LabelEqual(omni.LabelMachineSet, id)))
+		if err != nil {
+			return nil, err
+		}
+		scale.Spec.Replicas = uint32(nodes.Len())
+	}
+
+	status, err := safe.StateGet[*omni.MachineSetStatus](ctx, h.state, omni.NewMachineSetStatus(omniresources.DefaultNamespace, id).Metadata())
+	if err != nil && !state.IsNotFoundError(err) {
+		return nil, err
+	}
+	if status != nil {
+		spec := status.TypedSpec().Value
+		scale.Status = MachineSetScaleStatus{
+			Replicas:          spec.GetMachines().GetTotal(),
+			ReadyReplicas:     spec.GetMachines().GetHealthy(),
+			ConnectedReplicas: spec.GetMachines().GetConnected(),
+			RequestedReplicas: spec.GetMachines().GetRequested(),
+			Ready:             spec.Ready,
+			Phase:             spec.Phase.String(),
+		}
+	}
+
+	return scale, nil
+}
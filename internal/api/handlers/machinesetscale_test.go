@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/state/impl/inmem"
+	"github.com/gin-gonic/gin"
+	"github.com/siderolabs/omni/client/api/omni/specs"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMachineSetScaleHandler_GetMachineSetScale(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockState := new(MockState)
+
+	ms := omni.NewMachineSet("default", "prod-workers")
+	ms.TypedSpec().Value.MachineAllocation = &specs.MachineSetSpec_MachineAllocation{
+		Name:           "rack-a",
+		MachineCount:   5,
+		AllocationType: specs.MachineSetSpec_MachineAllocation_Static,
+	}
+	mockState.On("Get", mock.Anything, ofType(omni.MachineSetType), mock.Anything).Return(ms, nil)
+
+	status := omni.NewMachineSetStatus("default", "prod-workers")
+	status.TypedSpec().Value.Phase = specs.MachineSetPhase_ScalingUp
+	status.TypedSpec().Value.Machines = &specs.Machines{Total: 3, Healthy: 2, Connected: 3, Requested: 5}
+	mockState.On("Get", mock.Anything, ofType(omni.MachineSetStatusType), mock.Anything).Return(status, nil)
+
+	handler := NewMachineSetScaleHandler(mockState, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "prod-workers"}}
+	c.Request, _ = http.NewRequest("GET", "/machinesets/prod-workers/scale", nil)
+
+	handler.GetMachineSetScale(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp MachineSetScale
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Scalable)
+	assert.Equal(t, "rack-a", resp.MachineClass)
+	assert.Equal(t, uint32(5), resp.Spec.Replicas)
+	assert.Equal(t, MachineSetScaleStatus{Replicas: 3, ReadyReplicas: 2, ConnectedReplicas: 3, RequestedReplicas: 5, Phase: "ScalingUp"}, resp.Status)
+}
+
+func TestMachineSetScaleHandler_GetMachineSetScaleManual(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockState := new(MockState)
+	mockState.On("Get", mock.Anything, ofType(omni.MachineSetType), mock.Anything).Return(omni.NewMachineSet("default", "prod-workers"), nil)
+	mockState.On("List", mock.Anything, ofType(omni.MachineSetNodeType), mock.Anything).Return(resource.List{Items: []resource.Resource{
+		omni.NewMachineSetNode("default", "m1", omni.NewMachineSet("default", "prod-workers")),
+		omni.NewMachineSetNode("default", "m2", omni.NewMachineSet("default", "prod-workers")),
+	}}, nil)
+	mockState.On("Get", mock.Anything, ofType(omni.MachineSetStatusType), mock.Anything).Return(nil, inmem.ErrNotFound(omni.NewMachineSetStatus("default", "prod-workers").Metadata()))
+
+	handler := NewMachineSetScaleHandler(mockState, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "prod-workers"}}
+	c.Request, _ = http.NewRequest("GET", "/machinesets/prod-workers/scale", nil)
+
+	handler.GetMachineSetScale(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp MachineSetScale
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.False(t, resp.Scalable)
+	assert.Equal(t, uint32(2), resp.Spec.Replicas)
+}
+
+func TestMachineSetScaleHandler_UpdateMachineSetScaleRequiresReplicas(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockMgmt := new(MockManagementService)
+	handler := NewMachineSetScaleHandler(nil, mockMgmt)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "prod-workers"}}
+	c.Request, _ = http.NewRequest("PUT", "/machinesets/prod-workers/scale", strings.NewReader(`{"spec":{}}`))
+
+	handler.UpdateMachineSetScale(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockMgmt.AssertNotCalled(t, "ScaleMachineSet", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -1,46 +1,50 @@
 package handlers
 
 import (
-	"log"
 	"net/http"
 
-	"github.com/cosi-project/runtime/pkg/resource"
 	"github.com/cosi-project/runtime/pkg/state"
 	"github.com/gin-gonic/gin"
-	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
-	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/jubblin/omni-api/internal/operations"
 )
 
 // MachineSetCreateRequest represents a request to create a machine set
 type MachineSetCreateRequest struct {
-	ID              string `json:"id" binding:"required"`
-	Cluster         string `json:"cluster" binding:"required"`
-	MachineClass    string `json:"machine_class" binding:"required"`
-	BootstrapSpec   string `json:"bootstrap_spec,omitempty"`
-	UpdateStrategy  string `json:"update_strategy,omitempty"`
-	DeleteStrategy  string `json:"delete_strategy,omitempty"`
-	MachineCount    uint32 `json:"machine_count,omitempty"`
+	ID                   string                    `json:"id" binding:"required"`
+	Cluster              string                    `json:"cluster" binding:"required"`
+	MachineClass         string                    `json:"machine_class" binding:"required"`
+	BootstrapSpec        string                    `json:"bootstrap_spec,omitempty"`
+	UpdateStrategy       string                    `json:"update_strategy,omitempty"`
+	DeleteStrategy       string                    `json:"delete_strategy,omitempty"`
+	MachineCount         uint32                    `json:"machine_count,omitempty"`
+	UpdateStrategyConfig *MachineSetStrategyConfig `json:"update_strategy_config,omitempty"` // Requires the Rolling update strategy
+	DeleteStrategyConfig *MachineSetStrategyConfig `json:"delete_strategy_config,omitempty"` // Requires the Rolling delete strategy
 }
 
 // MachineSetUpdateRequest represents a request to update a machine set
 type MachineSetUpdateRequest struct {
-	MachineClass    string `json:"machine_class,omitempty"`
-	UpdateStrategy  string `json:"update_strategy,omitempty"`
-	DeleteStrategy  string `json:"delete_strategy,omitempty"`
-	MachineCount    uint32 `json:"machine_count,omitempty"`
+	MachineClass         string                    `json:"machine_class,omitempty"`
+	UpdateStrategy       string                    `json:"update_strategy,omitempty"`
+	DeleteStrategy       string                    `json:"delete_strategy,omitempty"`
+	MachineCount         uint32                    `json:"machine_count,omitempty"`
+	UpdateStrategyConfig *MachineSetStrategyConfig `json:"update_strategy_config,omitempty"`
+	DeleteStrategyConfig *MachineSetStrategyConfig `json:"delete_strategy_config,omitempty"`
 }
 
 // MachineSetWriteHandler handles machine set write operations
 type MachineSetWriteHandler struct {
 	state      state.State
-	management interface{} // Management service client
+	management client.ManagementService // Management service
+	operations *operations.Manager      // Tracks machine set deletion
 }
 
 // NewMachineSetWriteHandler creates a new MachineSetWriteHandler
-func NewMachineSetWriteHandler(s state.State, mgmt interface{}) *MachineSetWriteHandler {
+func NewMachineSetWriteHandler(s state.State, mgmt client.ManagementService, ops *operations.Manager) *MachineSetWriteHandler {
 	return &MachineSetWriteHandler{
 		state:      s,
 		management: mgmt,
+		operations: ops,
 	}
 }
 
@@ -51,8 +55,13 @@ func NewMachineSetWriteHandler(s state.State, mgmt interface{}) *MachineSetWrite
 // @Accept       json
 // @Produce      json
 // @Param        machineset  body      MachineSetCreateRequest  true  "Machine set creation request"
+// @Param        dryRun      query     bool                     false  "Validate the request and return the change without applying it"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200         {object}  DryRunResponse  "Returned instead when dryRun is set"
 // @Success      201         {object}  map[string]string
 // @Failure      400         {object}  map[string]string
+// @Failure      404         {object}  map[string]string
+// @Failure      409         {object}  map[string]string
 // @Failure      500         {object}  map[string]string
 // @Router       /machinesets [post]
 func (h *MachineSetWriteHandler) CreateMachineSet(c *gin.Context) {
@@ -62,16 +71,28 @@ func (h *MachineSetWriteHandler) CreateMachineSet(c *gin.Context) {
 		return
 	}
 
-	// Use Management service to create machine set
-	// The Management service API needs to be integrated here
-	// Typically: managementClient.CreateMachineSet(ctx, req.ID, req.Cluster, req.MachineClass, ...)
-	log.Printf("Creating machine set %s for cluster %s (Management service integration needed)", req.ID, req.Cluster)
+	// Create machine set using Management service
+	change, err := h.management.CreateMachineSet(writeContext(c), req.ID, req.Cluster, &client.MachineSetUpdates{
+		MachineClass:         req.MachineClass,
+		MachineCount:         req.MachineCount,
+		UpdateStrategy:       req.UpdateStrategy,
+		DeleteStrategy:       req.DeleteStrategy,
+		UpdateMaxParallelism: req.UpdateStrategyConfig.maxParallelism(),
+		DeleteMaxParallelism: req.DeleteStrategyConfig.maxParallelism(),
+	})
+	if err != nil {
+		handleManagementError(c, err)
+		return
+	}
+	if isDryRun(c) {
+		respondDryRun(c, change)
+		return
+	}
 	
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "Machine set creation initiated",
+		"message": "Machine set created successfully",
 		"id": req.ID,
 		"cluster": req.Cluster,
-		"note": "Management service integration required for actual creation",
 	})
 }
 
@@ -83,7 +104,8 @@ func (h *MachineSetWriteHandler) CreateMachineSet(c *gin.Context) {
 // @Produce      json
 // @Param        id          path      string                  true  "Machine set ID"
 // @Param        machineset  body      MachineSetUpdateRequest  true  "Machine set update request"
-// @Success      200         {object}  map[string]string
+// @Param        dryRun      query     bool                     false  "Validate the request and return the change without applying it"
+// @Success      200         {object}  map[string]string  "A DryRunResponse when dryRun is set"
 // @Failure      400         {object}  map[string]string
 // @Failure      404         {object}  map[string]string
 // @Failure      500         {object}  map[string]string
@@ -96,21 +118,27 @@ func (h *MachineSetWriteHandler) UpdateMachineSet(c *gin.Context) {
 		return
 	}
 
-	// Verify machine set exists
-	md := resource.NewMetadata(omniresources.DefaultNamespace, omni.MachineSetType, id, resource.VersionUndefined)
-	_, err := h.state.Get(c.Request.Context(), md)
+	// Update machine set using Management service
+	change, err := h.management.UpdateMachineSet(writeContext(c), id, &client.MachineSetUpdates{
+		MachineClass:         req.MachineClass,
+		MachineCount:         req.MachineCount,
+		UpdateStrategy:       req.UpdateStrategy,
+		DeleteStrategy:       req.DeleteStrategy,
+		UpdateMaxParallelism: req.UpdateStrategyConfig.maxParallelism(),
+		DeleteMaxParallelism: req.DeleteStrategyConfig.maxParallelism(),
+	})
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "machine set not found"})
+		handleManagementError(c, err)
+		return
+	}
+	if isDryRun(c) {
+		respondDryRun(c, change)
 		return
 	}
-
-	// Use Management service to update machine set
-	log.Printf("Updating machine set %s (Management service integration needed)", id)
 	
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Machine set update initiated",
+		"message": "Machine set updated successfully",
 		"id": id,
-		"note": "Management service integration required for actual update",
 	})
 }
 
@@ -119,28 +147,37 @@ func (h *MachineSetWriteHandler) UpdateMachineSet(c *gin.Context) {
 // @Description  Delete a machine set from Omni
 // @Tags         machinesets
 // @Produce      json
-// @Param        id   path      string  true  "Machine set ID"
-// @Success      202  {object}  map[string]string
+// @Param        id      path      string  true   "Machine set ID"
+// @Param        dryRun  query     bool    false  "Validate the request and return the change without applying it"
+// @Success      200  {object}  DryRunResponse  "Returned instead when dryRun is set"
+// @Success      202  {object}  map[string]string  "Location header points to the operation tracking the deletion"
 // @Failure      404  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /machinesets/{id} [delete]
 func (h *MachineSetWriteHandler) DeleteMachineSet(c *gin.Context) {
 	id := c.Param("id")
 
-	// Verify machine set exists
-	md := resource.NewMetadata(omniresources.DefaultNamespace, omni.MachineSetType, id, resource.VersionUndefined)
-	_, err := h.state.Get(c.Request.Context(), md)
+	// Delete machine set using Management service
+	change, err := h.management.DeleteMachineSet(writeContext(c), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "machine set not found"})
+		handleManagementError(c, err)
+		return
+	}
+	if isDryRun(c) {
+		respondDryRun(c, change)
 		return
 	}
-
-	// Use Management service to delete/teardown machine set
-	log.Printf("Deleting machine set %s (Management service integration needed)", id)
 	
-	c.JSON(http.StatusAccepted, gin.H{
+	resp := gin.H{
 		"message": "Machine set deletion initiated",
 		"id": id,
-		"note": "Management service integration required for actual deletion",
-	})
+	}
+	startOperation(c, h.operations, operations.Spec{
+		Kind:       "machineset-destroy",
+		Target:     id,
+		TargetPath: "/api/v1/machinesets/" + id + "/destroy-status",
+		Track:      operations.MachineSetDestroyTracker(h.state, id),
+	}, resp)
+
+	c.JSON(http.StatusAccepted, resp)
 }
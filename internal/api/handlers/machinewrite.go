@@ -1,31 +1,65 @@
 package handlers
 
 import (
+	"context"
 	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/siderolabs/omni/client/pkg/cosi/labels"
 	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
 	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
 	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/safe"
 	"github.com/cosi-project/runtime/pkg/state"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // MachineUpdateRequest represents a request to update machine labels or extensions
 type MachineUpdateRequest struct {
-	Labels     map[string]string `json:"labels,omitempty"`
-	Extensions []string           `json:"extensions,omitempty"`
-	Maintenance *bool             `json:"maintenance,omitempty"`
+	Labels      map[string]*string `json:"labels,omitempty"` // Merged into the user labels; null deletes a label
+	Extensions  []string           `json:"extensions,omitempty"`
+	Maintenance *bool              `json:"maintenance,omitempty"`
+}
+
+// MachineLabelsReplaceRequest represents a request to replace the user labels of a machine
+type MachineLabelsReplaceRequest struct {
+	Labels map[string]string `json:"labels" binding:"required"`
+}
+
+// MachineLabelsPatchRequest represents a request to change the user labels of several machines
+type MachineLabelsPatchRequest struct {
+	Labels map[string]*string `json:"labels" binding:"required"` // Merged into the user labels; null deletes a label
+}
+
+// MachineLabelsResult represents the outcome of a label change on one machine
+type MachineLabelsResult struct {
+	ID     string            `json:"id"`
+	Action string            `json:"action,omitempty"` // create, update or none
+	Labels map[string]string `json:"labels,omitempty"` // User labels after the change
+	Error  string            `json:"error,omitempty"`
+}
+
+// MachineLabelsPatchResponse represents the outcome of a bulk label change
+type MachineLabelsPatchResponse struct {
+	Selector string                `json:"selector"`
+	DryRun   bool                  `json:"dry_run"`
+	Matched  int                   `json:"matched"`
+	Changed  int                   `json:"changed"`
+	Failed   int                   `json:"failed"`
+	Machines []MachineLabelsResult `json:"machines"`
 }
 
 // MachineWriteHandler handles machine write operations
 type MachineWriteHandler struct {
 	state      state.State
-	management interface{} // Management service client
+	management client.ManagementService // Management service
 }
 
 // NewMachineWriteHandler creates a new MachineWriteHandler
-func NewMachineWriteHandler(s state.State, mgmt interface{}) *MachineWriteHandler {
+func NewMachineWriteHandler(s state.State, mgmt client.ManagementService) *MachineWriteHandler {
 	return &MachineWriteHandler{
 		state:      s,
 		management: mgmt,
@@ -34,16 +68,20 @@ func NewMachineWriteHandler(s state.State, mgmt interface{}) *MachineWriteHandle
 
 // UpdateMachine godoc
 // @Summary      Update a machine
-// @Description  Update machine labels, extensions, or maintenance mode
+// @Description  Update machine labels, extensions, or maintenance mode.
+// @Description  Labels are merged into the user labels like a JSON merge patch: a null value deletes the label. Labels starting with omni.sidero.dev/ cannot be changed.
+// @Description  All changes are checked before any is applied, so a request that fails changes nothing. Changing extensions is not supported yet and fails with 501.
 // @Tags         machines
 // @Accept       json
 // @Produce      json
 // @Param        id       path      string              true  "Machine ID"
 // @Param        machine  body      MachineUpdateRequest  true  "Machine update request"
-// @Success      200      {object}  MachineResponse
+// @Param        dryRun   query     bool                  false  "Validate the request and return the change without applying it"
+// @Success      200      {object}  MachineResponse  "A DryRunResponse when dryRun is set, or a DryRunChangesResponse if more than one of labels, extensions and maintenance is set"
 // @Failure      400      {object}  map[string]string
 // @Failure      404      {object}  map[string]string
 // @Failure      500      {object}  map[string]string
+// @Failure      501      {object}  map[string]string
 // @Router       /machines/{id} [patch]
 func (h *MachineWriteHandler) UpdateMachine(c *gin.Context) {
 	id := c.Param("id")
@@ -61,15 +99,162 @@ func (h *MachineWriteHandler) UpdateMachine(c *gin.Context) {
 		return
 	}
 
+	// Every part is checked as a dry run before any is applied, so a part that fails leaves the machine unchanged
+	var parts []func(ctx context.Context) (*client.Change, error)
+	if req.Labels != nil {
+		parts = append(parts, func(ctx context.Context) (*client.Change, error) {
+			return h.management.PatchMachineLabels(ctx, id, req.Labels)
+		})
+	}
+	if req.Extensions != nil {
+		parts = append(parts, func(ctx context.Context) (*client.Change, error) {
+			return h.management.UpdateMachineExtensions(ctx, id, req.Extensions)
+		})
+	}
+	if req.Maintenance != nil {
+		parts = append(parts, func(ctx context.Context) (*client.Change, error) {
+			return h.management.SetMachineMaintenance(ctx, id, *req.Maintenance, "")
+		})
+	}
+
+	changes := make([]*client.Change, 0, len(parts))
+	for _, part := range parts {
+		change, err := part(client.WithDryRun(c.Request.Context()))
+		if err != nil {
+			handleManagementError(c, err)
+			return
+		}
+		changes = append(changes, change)
+	}
+	if isDryRun(c) {
+		respondDryRunChanges(c, changes)
+		return
+	}
+
 	// Update machine using Management service
-	// For labels: managementClient.UpdateMachineLabels(ctx, machineID, labels)
-	// For extensions: managementClient.UpdateMachineExtensions(ctx, machineID, extensions)
-	// For maintenance: managementClient.SetMachineMaintenance(ctx, machineID, enabled)
-	log.Printf("Updating machine %s (Management service integration needed)", id)
-	
+	for _, part := range parts {
+		if _, err := part(c.Request.Context()); err != nil {
+			handleManagementError(c, err)
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Machine update initiated",
+		"message": "Machine updated successfully",
 		"id": id,
-		"note": "Management service integration required for actual update",
 	})
 }
+
+// ReplaceMachineLabels godoc
+// @Summary      Replace machine labels
+// @Description  Replace all user labels of a machine. Labels starting with omni.sidero.dev/ are managed by Omni; they cannot be set and are kept.
+// @Tags         machines
+// @Accept       json
+// @Produce      json
+// @Param        id      path      string                       true   "Machine ID"
+// @Param        labels  body      MachineLabelsReplaceRequest  true   "User labels"
+// @Param        dryRun  query     bool                         false  "Validate the request and return the change without applying it"
+// @Success      200  {object}  MachineLabelsResult  "A DryRunResponse when dryRun is set"
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /machines/{id}/labels [put]
+func (h *MachineWriteHandler) ReplaceMachineLabels(c *gin.Context) {
+	id := c.Param("id")
+	var req MachineLabelsReplaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	change, err := h.management.UpdateMachineLabels(writeContext(c), id, req.Labels)
+	if err != nil {
+		handleManagementError(c, err)
+		return
+	}
+	if isDryRun(c) {
+		respondDryRun(c, change)
+		return
+	}
+
+	c.JSON(http.StatusOK, machineLabelsResult(id, change))
+}
+
+// PatchMachinesLabels godoc
+// @Summary      Change the labels of many machines
+// @Description  Merge labels into the user labels of every machine matching an Omni label selector, e.g. "rack=r1" or "rack in (r1, r2), !owner". A null value deletes the label.
+// @Description  Machines are changed one by one; the response lists the outcome per machine and a machine that fails does not stop the others.
+// @Tags         machines
+// @Accept       json
+// @Produce      json
+// @Param        selector  query     string                     true   "Label selector matched against the machine labels"
+// @Param        labels    body      MachineLabelsPatchRequest  true   "Labels to merge"
+// @Param        dryRun    query     bool                       false  "Report the changes without applying them"
+// @Success      200  {object}  MachineLabelsPatchResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /machines/labels [patch]
+func (h *MachineWriteHandler) PatchMachinesLabels(c *gin.Context) {
+	selector := c.Query("selector")
+	if selector == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "selector is required"})
+		return
+	}
+	query, err := labels.ParseSelectors([]string{selector})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid selector: " + err.Error()})
+		return
+	}
+	var req MachineLabelsPatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	statuses, err := safe.StateListAll[*omni.MachineStatus](c.Request.Context(), h.state)
+	if err != nil {
+		log.Printf("Error listing machine statuses: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := MachineLabelsPatchResponse{Selector: selector, DryRun: isDryRun(c), Machines: []MachineLabelsResult{}}
+	ctx := writeContext(c)
+	for machine := range statuses.All() {
+		if !query.Matches(*machine.Metadata().Labels()) {
+			continue
+		}
+		id := machine.Metadata().ID()
+		resp.Matched++
+
+		change, err := h.management.PatchMachineLabels(ctx, id, req.Labels)
+		if status.Code(err) == codes.InvalidArgument {
+			// The labels are invalid, which fails every machine the same way
+			handleManagementError(c, err)
+			return
+		}
+		if err != nil {
+			resp.Failed++
+			resp.Machines = append(resp.Machines, MachineLabelsResult{ID: id, Error: err.Error()})
+			continue
+		}
+
+		result := machineLabelsResult(id, change)
+		if result.Action != string(client.ChangeNone) {
+			resp.Changed++
+		}
+		resp.Machines = append(resp.Machines, result)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func machineLabelsResult(id string, change *client.Change) MachineLabelsResult {
+	result := MachineLabelsResult{ID: id, Action: string(change.Action), Labels: map[string]string{}}
+	if change.Desired != nil {
+		for key, value := range change.Desired.Metadata().Labels().Raw() {
+			result.Labels[key] = value
+		}
+	}
+	return result
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func labelsChange(action client.ChangeAction, id string, labels map[string]string) *client.Change {
+	ml := omni.NewMachineLabels("default", id)
+	for key, value := range labels {
+		ml.Metadata().Labels().Set(key, value)
+	}
+	return &client.Change{Action: action, Desired: ml}
+}
+
+func TestMachineWriteHandler_PatchMachinesLabels(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockState := new(MockState)
+	var statuses []resource.Resource
+	for id, rack := range map[string]string{"m1": "r1", "m2": "r1", "m3": "r2"} {
+		ms := omni.NewMachineStatus("default", id)
+		ms.Metadata().Labels().Set("rack", rack)
+		statuses = append(statuses, ms)
+	}
+	mockState.On("List", mock.Anything, ofType(omni.MachineStatusType), mock.Anything).Return(resource.List{Items: statuses}, nil)
+
+	owner := "team-a"
+	patch := map[string]*string{"owner": &owner, "spare": nil}
+	mockMgmt := new(MockManagementService)
+	mockMgmt.On("PatchMachineLabels", mock.Anything, "m1", patch).Return(labelsChange(client.ChangeUpdate, "m1", map[string]string{"owner": "team-a"}), nil)
+	mockMgmt.On("PatchMachineLabels", mock.Anything, "m2", patch).Return(nil, status.Error(codes.Unavailable, "omni down"))
+
+	handler := NewMachineWriteHandler(mockState, mockMgmt)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("PATCH", "/machines/labels?selector=rack%3Dr1", strings.NewReader(`{"labels":{"owner":"team-a","spare":null}}`))
+
+	handler.PatchMachinesLabels(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp MachineLabelsPatchResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 2, resp.Matched)
+	assert.Equal(t, 1, resp.Changed)
+	assert.Equal(t, 1, resp.Failed)
+	mockMgmt.AssertExpectations(t)
+}
+
+func TestMachineWriteHandler_PatchMachinesLabelsInvalid(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockState := new(MockState)
+	ms := omni.NewMachineStatus("default", "m1")
+	mockState.On("List", mock.Anything, ofType(omni.MachineStatusType), mock.Anything).Return(resource.List{Items: []resource.Resource{ms}}, nil)
+
+	mockMgmt := new(MockManagementService)
+	mockMgmt.On("PatchMachineLabels", mock.Anything, "m1", mock.Anything).
+		Return(nil, status.Error(codes.InvalidArgument, "label omni.sidero.dev/cluster is managed by Omni and cannot be changed"))
+
+	handler := NewMachineWriteHandler(mockState, mockMgmt)
+
+	tests := []struct {
+		name  string
+		query string
+		body  string
+	}{
+		{"no selector", "", `{"labels":{"rack":"r1"}}`},
+		{"invalid selector", "?selector=rack+in+r1", `{"labels":{"rack":"r1"}}`},
+		{"no labels", "?selector=rack", `{}`},
+		{"system label", "?selector=!rack", `{"labels":{"omni.sidero.dev/cluster":"prod"}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest("PATCH", "/machines/labels"+tt.query, strings.NewReader(tt.body))
+
+			handler.PatchMachinesLabels(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestMachineWriteHandler_ReplaceMachineLabels(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	labels := map[string]string{"rack": "r1", "owner": "team-a"}
+	mockMgmt := new(MockManagementService)
+	mockMgmt.On("UpdateMachineLabels", mock.Anything, "m1", labels).Return(labelsChange(client.ChangeUpdate, "m1", labels), nil)
+
+	handler := NewMachineWriteHandler(nil, mockMgmt)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "m1"}}
+	c.Request, _ = http.NewRequest("PUT", "/machines/m1/labels", strings.NewReader(`{"labels":{"rack":"r1","owner":"team-a"}}`))
+
+	handler.ReplaceMachineLabels(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp MachineLabelsResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "update", resp.Action)
+	assert.Equal(t, labels, resp.Labels)
+}
+
+func TestMachineWriteHandler_UpdateMachine(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	rack := "r1"
+	patch := map[string]*string{"rack": &rack}
+
+	tests := []struct {
+		name        string
+		path        string
+		body        string
+		extensions  error
+		wantStatus  int
+		wantChanges int
+		wantApplied bool
+	}{
+		{"labels and maintenance", "/machines/m1", `{"labels":{"rack":"r1"},"maintenance":true}`, nil, http.StatusOK, 0, true},
+		{"labels and maintenance dry run", "/machines/m1?dryRun=true", `{"labels":{"rack":"r1"},"maintenance":true}`, nil, http.StatusOK, 2, false},
+		{"extensions are checked before labels are written", "/machines/m1", `{"labels":{"rack":"r1"},"extensions":["siderolabs/iscsi-tools"]}`,
+			status.Error(codes.Unimplemented, "updating machine extensions is not supported yet"), http.StatusNotImplemented, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockState := new(MockState)
+			mockState.On("Get", mock.Anything, ofType(omni.MachineType), mock.Anything).Return(omni.NewMachine("default", "m1"), nil)
+
+			mockMgmt := new(MockManagementService)
+			mockMgmt.On("PatchMachineLabels", mock.Anything, "m1", patch).Return(labelsChange(client.ChangeUpdate, "m1", map[string]string{"rack": "r1"}), nil)
+			mockMgmt.On("UpdateMachineExtensions", mock.Anything, "m1", mock.Anything).Return(nil, tt.extensions)
+			mockMgmt.On("SetMachineMaintenance", mock.Anything, "m1", true, "").Return(&client.Change{Action: client.ChangeDestroy, Current: omni.NewMachineSetNode("default", "m1", omni.NewMachineSet("default", "prod-workers"))}, nil)
+
+			handler := NewMachineWriteHandler(mockState, mockMgmt)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = []gin.Param{{Key: "id", Value: "m1"}}
+			c.Request, _ = http.NewRequest("PATCH", tt.path, strings.NewReader(tt.body))
+
+			handler.UpdateMachine(c)
+
+			require.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			if tt.wantChanges > 0 {
+				var resp DryRunChangesResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				require.Len(t, resp.Changes, tt.wantChanges)
+				assert.Equal(t, "update", resp.Changes[0].Action)
+				assert.Equal(t, "destroy", resp.Changes[1].Action)
+			}
+
+			applied := false
+			for _, call := range mockMgmt.Calls {
+				if ctx, ok := call.Arguments.Get(0).(context.Context); ok && !client.IsDryRun(ctx) {
+					applied = true
+				}
+			}
+			assert.Equal(t, tt.wantApplied, applied)
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/client"
 )
 
 var (
@@ -26,17 +27,26 @@ type MetricsResponse struct {
 	RequestCounts       map[string]uint64   `json:"request_counts,omitempty"`
 	ErrorCounts         map[string]uint64   `json:"error_counts,omitempty"`
 	AverageResponseTime map[string]float64  `json:"average_response_time_seconds,omitempty"`
+	CircuitBreaker      *CircuitBreakerStatus `json:"circuit_breaker,omitempty"`
 	Links               map[string]string   `json:"_links,omitempty"`
 }
 
 // MetricsHandler handles metrics requests
-type MetricsHandler struct{}
+type MetricsHandler struct {
+	breaker *client.Breaker
+}
 
 // NewMetricsHandler creates a new MetricsHandler
 func NewMetricsHandler() *MetricsHandler {
 	return &MetricsHandler{}
 }
 
+// WithCircuitBreaker includes the state of the Omni circuit breaker in the metrics
+func (h *MetricsHandler) WithCircuitBreaker(b *client.Breaker) *MetricsHandler {
+	h.breaker = b
+	return h
+}
+
 // RecordRequest records a request for metrics
 func RecordRequest(endpoint string, duration time.Duration, statusCode int) {
 	metricsMutex.Lock()
@@ -104,5 +114,10 @@ func (h *MetricsHandler) GetMetrics(c *gin.Context) {
 		},
 	}
 
+	if h.breaker != nil {
+		breakerStatus := circuitBreakerStatus(h.breaker.Snapshot())
+		resp.CircuitBreaker = &breakerStatus
+	}
+
 	c.JSON(http.StatusOK, resp)
 }
@@ -2,9 +2,12 @@ package handlers
 
 import (
 	"context"
+	"io"
+	"iter"
 
 	"github.com/cosi-project/runtime/pkg/resource"
 	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/jubblin/omni-api/internal/client"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -25,3 +28,224 @@ func (m *MockState) List(ctx context.Context, k resource.Kind, opts ...state.Lis
 	args := m.Called(ctx, k, opts)
 	return args.Get(0).(resource.List), args.Error(1)
 }
+
+func (m *MockState) Watch(ctx context.Context, p resource.Pointer, ch chan<- state.Event, opts ...state.WatchOption) error {
+	return m.Called(ctx, p, ch, opts).Error(0)
+}
+
+// MockManagementService is a mock implementation of the config patch, machine label, machine class, machine set scale, allocation, maintenance, cluster backup, etcd restore and upgrade methods of client.ManagementService
+type MockManagementService struct {
+	mock.Mock
+	client.ManagementService
+}
+
+func (m *MockManagementService) CreateConfigPatch(ctx context.Context, id, cluster, data string) (*client.Change, error) {
+	args := m.Called(ctx, id, cluster, data)
+	change, _ := args.Get(0).(*client.Change)
+	return change, args.Error(1)
+}
+
+func (m *MockManagementService) UpdateConfigPatch(ctx context.Context, id, data string) (*client.Change, error) {
+	args := m.Called(ctx, id, data)
+	change, _ := args.Get(0).(*client.Change)
+	return change, args.Error(1)
+}
+
+func (m *MockManagementService) DeleteConfigPatch(ctx context.Context, id string) (*client.Change, error) {
+	args := m.Called(ctx, id)
+	change, _ := args.Get(0).(*client.Change)
+	return change, args.Error(1)
+}
+
+func (m *MockManagementService) AllocateMachines(ctx context.Context, machineSetID string, machineIDs []string) ([]*client.Change, error) {
+	args := m.Called(ctx, machineSetID, machineIDs)
+	changes, _ := args.Get(0).([]*client.Change)
+	return changes, args.Error(1)
+}
+
+func (m *MockManagementService) CreateMachineClass(ctx context.Context, id string, spec *client.MachineClassSpec) (*client.Change, error) {
+	args := m.Called(ctx, id, spec)
+	change, _ := args.Get(0).(*client.Change)
+	return change, args.Error(1)
+}
+
+func (m *MockManagementService) DeleteMachineClass(ctx context.Context, id string) (*client.Change, error) {
+	args := m.Called(ctx, id)
+	change, _ := args.Get(0).(*client.Change)
+	return change, args.Error(1)
+}
+
+func (m *MockManagementService) ScaleMachineSet(ctx context.Context, id string, replicas uint32, version string) (*client.Change, error) {
+	args := m.Called(ctx, id, replicas, version)
+	change, _ := args.Get(0).(*client.Change)
+	return change, args.Error(1)
+}
+
+func (m *MockManagementService) UpdateMachineLabels(ctx context.Context, machineID string, labels map[string]string) (*client.Change, error) {
+	args := m.Called(ctx, machineID, labels)
+	change, _ := args.Get(0).(*client.Change)
+	return change, args.Error(1)
+}
+
+func (m *MockManagementService) PatchMachineLabels(ctx context.Context, machineID string, patch map[string]*string) (*client.Change, error) {
+	args := m.Called(ctx, machineID, patch)
+	change, _ := args.Get(0).(*client.Change)
+	return change, args.Error(1)
+}
+
+func (m *MockManagementService) UpdateMachineExtensions(ctx context.Context, machineID string, extensions []string) (*client.Change, error) {
+	args := m.Called(ctx, machineID, extensions)
+	change, _ := args.Get(0).(*client.Change)
+	return change, args.Error(1)
+}
+
+func (m *MockManagementService) SetClusterBackup(ctx context.Context, id string, backup *client.ClusterBackup) (*client.Change, error) {
+	args := m.Called(ctx, id, backup)
+	change, _ := args.Get(0).(*client.Change)
+	return change, args.Error(1)
+}
+
+func (m *MockManagementService) UpgradeKubernetes(ctx context.Context, clusterID, version string) (*client.Change, error) {
+	args := m.Called(ctx, clusterID, version)
+	change, _ := args.Get(0).(*client.Change)
+	return change, args.Error(1)
+}
+
+func (m *MockManagementService) UpgradeTalos(ctx context.Context, clusterID, version string) (*client.Change, error) {
+	args := m.Called(ctx, clusterID, version)
+	change, _ := args.Get(0).(*client.Change)
+	return change, args.Error(1)
+}
+
+func (m *MockManagementService) RevertKubernetesUpgrade(ctx context.Context, clusterID, version string) (*client.Change, error) {
+	args := m.Called(ctx, clusterID, version)
+	change, _ := args.Get(0).(*client.Change)
+	return change, args.Error(1)
+}
+
+func (m *MockManagementService) RevertTalosUpgrade(ctx context.Context, clusterID, version string) (*client.Change, error) {
+	args := m.Called(ctx, clusterID, version)
+	change, _ := args.Get(0).(*client.Change)
+	return change, args.Error(1)
+}
+
+func (m *MockManagementService) KubernetesUpgradePreChecks(ctx context.Context, clusterID, version string) (string, error) {
+	args := m.Called(ctx, clusterID, version)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockManagementService) RestoreEtcdBackup(ctx context.Context, backupID string, restore *client.ClusterRestore) ([]*client.Change, error) {
+	args := m.Called(ctx, backupID, restore)
+	changes, _ := args.Get(0).([]*client.Change)
+	return changes, args.Error(1)
+}
+
+func (m *MockManagementService) SetMachineMaintenance(ctx context.Context, machineID string, enabled bool, machineSetID string) (*client.Change, error) {
+	args := m.Called(ctx, machineID, enabled, machineSetID)
+	change, _ := args.Get(0).(*client.Change)
+	return change, args.Error(1)
+}
+
+// MockConfigService is a mock implementation of client.ConfigService
+type MockConfigService struct {
+	mock.Mock
+}
+
+func (m *MockConfigService) Kubeconfig(ctx context.Context, clusterID string, opts client.KubeconfigOptions) ([]byte, error) {
+	args := m.Called(ctx, clusterID, opts)
+	data, _ := args.Get(0).([]byte)
+	return data, args.Error(1)
+}
+
+func (m *MockConfigService) Talosconfig(ctx context.Context, clusterID string, opts client.TalosconfigOptions) ([]byte, error) {
+	args := m.Called(ctx, clusterID, opts)
+	data, _ := args.Get(0).([]byte)
+	return data, args.Error(1)
+}
+
+func (m *MockConfigService) Omniconfig(ctx context.Context) ([]byte, error) {
+	args := m.Called(ctx)
+	data, _ := args.Get(0).([]byte)
+	return data, args.Error(1)
+}
+
+// MockAuditLogService is a mock implementation of client.AuditLogService
+type MockAuditLogService struct {
+	mock.Mock
+}
+
+// ReadAuditLog yields the lines returned by the mock, followed by its error if set
+func (m *MockAuditLogService) ReadAuditLog(ctx context.Context, from, to string) iter.Seq2[[]byte, error] {
+	args := m.Called(ctx, from, to)
+	lines, _ := args.Get(0).([]string)
+	return func(yield func([]byte, error) bool) {
+		for _, line := range lines {
+			if !yield([]byte(line), nil) {
+				return
+			}
+		}
+		if err := args.Error(1); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// MockTemplateService is a mock implementation of client.TemplateService
+type MockTemplateService struct {
+	mock.Mock
+}
+
+func (m *MockTemplateService) Validate(tmpl []byte) (string, error) {
+	args := m.Called(string(tmpl))
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockTemplateService) Plan(ctx context.Context, tmpl []byte) (*client.TemplatePlan, error) {
+	args := m.Called(ctx, string(tmpl))
+	plan, _ := args.Get(0).(*client.TemplatePlan)
+	return plan, args.Error(1)
+}
+
+func (m *MockTemplateService) Apply(ctx context.Context, plan *client.TemplatePlan) error {
+	return m.Called(ctx, plan).Error(0)
+}
+
+func (m *MockTemplateService) Prune(ctx context.Context, plan *client.TemplatePlan, report func(phase, total int)) error {
+	for i := range plan.Prune {
+		report(i+1, len(plan.Prune))
+	}
+	return m.Called(ctx, plan).Error(0)
+}
+
+func (m *MockTemplateService) Export(ctx context.Context, clusterID string) ([]byte, error) {
+	args := m.Called(ctx, clusterID)
+	data, _ := args.Get(0).([]byte)
+	return data, args.Error(1)
+}
+
+// MockTalosService is a mock implementation of client.TalosService
+type MockTalosService struct {
+	mock.Mock
+}
+
+func (m *MockTalosService) RebootMachine(ctx context.Context, machineID string) error {
+	return m.Called(ctx, machineID).Error(0)
+}
+
+func (m *MockTalosService) ShutdownMachine(ctx context.Context, machineID string) error {
+	return m.Called(ctx, machineID).Error(0)
+}
+
+func (m *MockTalosService) ResetMachine(ctx context.Context, machineID string) error {
+	return m.Called(ctx, machineID).Error(0)
+}
+
+func (m *MockTalosService) MaintenanceUpgrade(ctx context.Context, machineID, version, schematicID string) error {
+	return m.Called(ctx, machineID, version, schematicID).Error(0)
+}
+
+func (m *MockTalosService) MachineLogs(ctx context.Context, machineID string, opts client.LogOptions) (io.ReadCloser, error) {
+	args := m.Called(ctx, machineID, opts)
+	logs, _ := args.Get(0).(io.ReadCloser)
+	return logs, args.Error(1)
+}
@@ -86,6 +86,7 @@ type CreateOIDCProviderRequest struct {
 // @Accept       json
 // @Produce      json
 // @Param        provider  body      CreateOIDCProviderRequest  true  "OIDC provider creation request"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
 // @Success      201       {object}  OIDCProviderResponse
 // @Failure      400       {object}  map[string]string
 // @Failure      500       {object}  map[string]string
@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/audit"
+	"github.com/jubblin/omni-api/internal/client"
+)
+
+// Audit record sources
+const (
+	AuditSourceOmni = "omni"     // Omni's own audit log
+	AuditSourceAPI  = "omni-api" // This API's audit log
+)
+
+// mimeNDJSON is the content type of newline-delimited JSON
+const mimeNDJSON = "application/x-ndjson"
+
+// auditCSVHeader lists the CSV columns, matching the AuditRecord fields
+var auditCSVHeader = []string{"source", "time", "actor", "event_type", "resource_type", "resource_id", "data"}
+
+// AuditRecord is an audit log entry from Omni or this API in a common shape
+type AuditRecord struct {
+	Source       string          `json:"source"`
+	Time         time.Time       `json:"time"`
+	Actor        string          `json:"actor,omitempty"`
+	EventType    string          `json:"event_type"`
+	ResourceType string          `json:"resource_type,omitempty"`
+	ResourceID   string          `json:"resource_id,omitempty"`
+	Data         json.RawMessage `json:"data,omitempty" swaggertype:"object"` // The original entry
+}
+
+// omniAuditEntry holds the fields of an Omni audit log entry used for filtering
+type omniAuditEntry struct {
+	EventType    string `json:"event_type"`
+	ResourceType string `json:"resource_type"`
+	ResourceID   string `json:"resource_id"`
+	EventTS      int64  `json:"event_ts"` // Unix milliseconds
+	EventData    struct {
+		Session struct {
+			UserID string `json:"user_id"`
+			Email  string `json:"email"`
+		} `json:"session"`
+	} `json:"event_data"`
+}
+
+// auditFilter selects the audit records returned
+type auditFilter struct {
+	from, to      time.Time // Inclusive start, exclusive end
+	actors        map[string]bool
+	resourceTypes map[string]bool
+	eventTypes    map[string]bool
+}
+
+func (f auditFilter) match(r AuditRecord) bool {
+	if r.Time.Before(f.from) || !r.Time.Before(f.to) {
+		return false
+	}
+	if len(f.actors) > 0 && !f.actors[r.Actor] {
+		return false
+	}
+	if len(f.resourceTypes) > 0 && !f.resourceTypes[r.ResourceType] {
+		return false
+	}
+	if len(f.eventTypes) > 0 && !f.eventTypes[r.EventType] {
+		return false
+	}
+	return true
+}
+
+// OmniAuditLogHandler handles audit log export requests
+type OmniAuditLogHandler struct {
+	omni  client.AuditLogService // Reads Omni's audit log through the Management API
+	audit *audit.Log             // This API's audit log
+}
+
+// NewOmniAuditLogHandler creates a new OmniAuditLogHandler
+func NewOmniAuditLogHandler(omniAuditLog client.AuditLogService, auditLog *audit.Log) *OmniAuditLogHandler {
+	return &OmniAuditLogHandler{omni: omniAuditLog, audit: auditLog}
+}
+
+// ExportAuditLog godoc
+// @Summary      Export the audit log
+// @Description  Stream Omni's audit log between two dates (inclusive, UTC) as newline-delimited JSON, or as CSV with format=csv or Accept: text/csv.
+// @Description  Every record has the same shape whatever its source; data holds the original entry.
+// @Description  source=all appends the entries this API recorded itself (credential downloads and the like) after Omni's, so a single request covers both.
+// @Description  This API's entries are served from memory and only cover the most recent OMNI_API_AUDIT_LOG_MAX_ENTRIES since the last restart.
+// @Tags         audit
+// @Produce      application/x-ndjson
+// @Produce      text/csv
+// @Param        from           query     string  false  "First day to export, YYYY-MM-DD (default: today)"
+// @Param        to             query     string  false  "Last day to export, YYYY-MM-DD (default: from)"
+// @Param        actor          query     string  false  "Comma-separated actors (Omni user e-mail or ID, or this API's actor) to include"
+// @Param        resource_type  query     string  false  "Comma-separated resource types to include, e.g. Clusters.omni.sidero.dev or kubeconfig"
+// @Param        event_type     query     string  false  "Comma-separated event types to include, e.g. create, update, destroy or kubeconfig.generate"
+// @Param        source         query     string  false  "Audit logs to read"  Enums(omni, omni-api, all)  default(omni)
+// @Param        format         query     string  false  "Output format"  Enums(ndjson, csv)  default(ndjson)
+// @Success      200  {object}  AuditRecord  "One record per line"
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Failure      503  {object}  map[string]string
+// @Router       /omni-audit-log [get]
+func (h *OmniAuditLogHandler) ExportAuditLog(c *gin.Context) {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	source := c.DefaultQuery("source", AuditSourceOmni)
+	if source != AuditSourceOmni && source != AuditSourceAPI && source != "all" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid source, expected omni, omni-api or all"})
+		return
+	}
+
+	format := c.Query("format")
+	if format != "" && format != "ndjson" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format, expected ndjson or csv"})
+		return
+	}
+	if format == "" {
+		format = "ndjson"
+		if c.NegotiateFormat(mimeNDJSON, gin.MIMEJSON, "text/csv") == "text/csv" {
+			format = "csv"
+		}
+	}
+
+	from := filter.from.Format(client.AuditLogDateFormat)
+	to := filter.to.AddDate(0, 0, -1).Format(client.AuditLogDateFormat)
+	w := &auditRecordWriter{c: c, csv: format == "csv", filename: fmt.Sprintf("audit-log-%s-%s.csv", from, to)}
+
+	if source != AuditSourceAPI {
+		for line, err := range h.omni.ReadAuditLog(c.Request.Context(), from, to) {
+			if err != nil {
+				w.fail(err)
+				return
+			}
+
+			record, err := omniAuditRecord(line)
+			if err != nil {
+				log.Printf("Skipping malformed Omni audit log entry: %v", err)
+				continue
+			}
+			if filter.match(record) {
+				w.write(record)
+			}
+		}
+	}
+
+	if source != AuditSourceOmni {
+		for _, entry := range h.audit.Entries() {
+			if record := apiAuditRecord(entry); filter.match(record) {
+				w.write(record)
+			}
+		}
+	}
+
+	w.finish()
+}
+
+// parseAuditFilter parses the audit log query parameters
+func parseAuditFilter(c *gin.Context) (auditFilter, error) {
+	filter := auditFilter{
+		actors:        parseSet(c.Query("actor")),
+		resourceTypes: parseSet(c.Query("resource_type")),
+		eventTypes:    parseSet(c.Query("event_type")),
+	}
+
+	filter.from = time.Now().UTC().Truncate(24 * time.Hour)
+	if value := c.Query("from"); value != "" {
+		from, err := time.Parse(client.AuditLogDateFormat, value)
+		if err != nil {
+			return filter, errors.New("invalid from, expected YYYY-MM-DD")
+		}
+		filter.from = from
+	}
+
+	to := filter.from
+	if value := c.Query("to"); value != "" {
+		var err error
+		if to, err = time.Parse(client.AuditLogDateFormat, value); err != nil {
+			return filter, errors.New("invalid to, expected YYYY-MM-DD")
+		}
+	}
+	if to.Before(filter.from) {
+		return filter, errors.New("to must not be before from")
+	}
+	filter.to = to.AddDate(0, 0, 1)
+
+	return filter, nil
+}
+
+// parseSet splits a comma-separated query value into a set
+func parseSet(value string) map[string]bool {
+	set := map[string]bool{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			set[item] = true
+		}
+	}
+	return set
+}
+
+// omniAuditRecord converts an Omni audit log entry
+func omniAuditRecord(line []byte) (AuditRecord, error) {
+	var entry omniAuditEntry
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return AuditRecord{}, err
+	}
+
+	actor := entry.EventData.Session.Email
+	if actor == "" {
+		actor = entry.EventData.Session.UserID
+	}
+
+	return AuditRecord{
+		Source:       AuditSourceOmni,
+		Time:         time.UnixMilli(entry.EventTS).UTC(),
+		Actor:        actor,
+		EventType:    entry.EventType,
+		ResourceType: entry.ResourceType,
+		ResourceID:   entry.ResourceID,
+		Data:         json.RawMessage(line),
+	}, nil
+}
+
+// apiAuditRecord converts an entry of this API's audit log.
+// Actions are named <resource type>.<event>, so the resource type is the first part of the action.
+func apiAuditRecord(entry audit.Entry) AuditRecord {
+	resourceType, _, _ := strings.Cut(entry.Action, ".")
+	data, _ := json.Marshal(entry)
+
+	return AuditRecord{
+		Source:       AuditSourceAPI,
+		Time:         entry.Time,
+		Actor:        entry.Actor,
+		EventType:    entry.Action,
+		ResourceType: resourceType,
+		ResourceID:   entry.Target,
+		Data:         data,
+	}
+}
+
+// auditRecordWriter streams audit records as NDJSON or CSV.
+// The response starts with the first record, so errors before it still get a proper status.
+type auditRecordWriter struct {
+	c        *gin.Context
+	csv      bool
+	filename string
+	out      *csv.Writer
+	started  bool
+}
+
+func (w *auditRecordWriter) start() {
+	if w.started {
+		return
+	}
+	w.started = true
+
+	if w.csv {
+		w.c.Header("Content-Type", "text/csv; charset=utf-8")
+		w.c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", w.filename))
+		w.out = csv.NewWriter(w.c.Writer)
+		w.out.Write(auditCSVHeader)
+	} else {
+		w.c.Header("Content-Type", mimeNDJSON)
+	}
+	w.c.Header("X-Accel-Buffering", "no")
+	w.c.Status(http.StatusOK)
+}
+
+func (w *auditRecordWriter) write(r AuditRecord) {
+	w.start()
+
+	if w.csv {
+		w.out.Write([]string{r.Source, r.Time.Format(time.RFC3339Nano), r.Actor, r.EventType, r.ResourceType, r.ResourceID, string(r.Data)})
+		w.out.Flush()
+	} else {
+		line, err := json.Marshal(r)
+		if err != nil {
+			log.Printf("Failed to marshal audit record: %v", err)
+			return
+		}
+		w.c.Writer.Write(append(line, '\n'))
+	}
+	w.c.Writer.Flush()
+}
+
+// fail reports an error, in the body if records were already sent
+func (w *auditRecordWriter) fail(err error) {
+	if !w.started {
+		handleManagementError(w.c, err)
+		return
+	}
+
+	log.Printf("Error streaming audit log: %v", err)
+	if w.csv {
+		// CSV has no room for errors; the truncated file is all the client gets
+		return
+	}
+	line, _ := json.Marshal(gin.H{"error": err.Error()})
+	w.c.Writer.Write(append(line, '\n'))
+	w.c.Writer.Flush()
+}
+
+// finish completes the response, which is empty apart from the CSV header if nothing matched
+func (w *auditRecordWriter) finish() {
+	w.start()
+	if w.out != nil {
+		w.out.Flush()
+	}
+}
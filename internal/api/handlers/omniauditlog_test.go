@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 2026-03-01T10:00:00Z and 2026-03-01T11:00:00Z
+var omniAuditLines = []string{
+	`{"event_type":"create","resource_type":"Clusters.omni.sidero.dev","event_ts":1772359200000,"event_data":{"session":{"email":"alice@example.com"}}}`,
+	`{"event_type":"destroy","resource_type":"MachineSets.omni.sidero.dev","event_ts":1772362800000,"event_data":{"session":{"user_id":"sa-1"}}}`,
+}
+
+func exportAuditLog(t *testing.T, handler *OmniAuditLogHandler, query string, header http.Header) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/omni-audit-log?"+query, nil)
+	if header != nil {
+		c.Request.Header = header
+	}
+
+	handler.ExportAuditLog(c)
+	return w
+}
+
+func decodeAuditRecords(t *testing.T, body string) []AuditRecord {
+	var records []AuditRecord
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		var r AuditRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &r))
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestOmniAuditLogHandler_ExportAuditLog_NDJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	omniAuditLog := new(MockAuditLogService)
+	omniAuditLog.On("ReadAuditLog", mock.Anything, "2026-03-01", "2026-03-02").Return(omniAuditLines, nil)
+	handler := NewOmniAuditLogHandler(omniAuditLog, nil)
+
+	w := exportAuditLog(t, handler, "from=2026-03-01&to=2026-03-02", nil)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, mimeNDJSON, w.Header().Get("Content-Type"))
+
+	records := decodeAuditRecords(t, w.Body.String())
+	require.Len(t, records, 2)
+	assert.Equal(t, AuditSourceOmni, records[0].Source)
+	assert.Equal(t, "alice@example.com", records[0].Actor)
+	assert.Equal(t, "create", records[0].EventType)
+	assert.Equal(t, time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC), records[0].Time)
+	assert.JSONEq(t, omniAuditLines[0], string(records[0].Data))
+	assert.Equal(t, "sa-1", records[1].Actor)
+}
+
+func TestOmniAuditLogHandler_ExportAuditLog_Filters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	omniAuditLog := new(MockAuditLogService)
+	omniAuditLog.On("ReadAuditLog", mock.Anything, "2026-03-01", "2026-03-01").Return(omniAuditLines, nil)
+
+	auditLog, err := audit.New(audit.Config{})
+	require.NoError(t, err)
+	auditLog.Record(audit.Entry{Time: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), Actor: "alice@example.com", Action: "kubeconfig.generate", Target: "prod"})
+	auditLog.Record(audit.Entry{Time: time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC), Actor: "alice@example.com", Action: "kubeconfig.generate", Target: "staging"})
+	handler := NewOmniAuditLogHandler(omniAuditLog, auditLog)
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string // Event types in order
+	}{
+		{name: "omni only", query: "", want: []string{"create", "destroy"}},
+		{name: "all sources", query: "&source=all", want: []string{"create", "destroy", "kubeconfig.generate"}},
+		{name: "api only", query: "&source=omni-api", want: []string{"kubeconfig.generate"}},
+		{name: "actor", query: "&source=all&actor=alice@example.com", want: []string{"create", "kubeconfig.generate"}},
+		{name: "resource type", query: "&source=all&resource_type=kubeconfig,MachineSets.omni.sidero.dev", want: []string{"destroy", "kubeconfig.generate"}},
+		{name: "event type", query: "&event_type=destroy", want: []string{"destroy"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := exportAuditLog(t, handler, "from=2026-03-01"+tt.query, nil)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var eventTypes []string
+			for _, r := range decodeAuditRecords(t, w.Body.String()) {
+				eventTypes = append(eventTypes, r.EventType)
+			}
+			assert.Equal(t, tt.want, eventTypes)
+		})
+	}
+}
+
+func TestOmniAuditLogHandler_ExportAuditLog_CSV(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	omniAuditLog := new(MockAuditLogService)
+	omniAuditLog.On("ReadAuditLog", mock.Anything, "2026-03-01", "2026-03-01").Return(omniAuditLines[:1], nil)
+	handler := NewOmniAuditLogHandler(omniAuditLog, nil)
+
+	w := exportAuditLog(t, handler, "from=2026-03-01", http.Header{"Accept": {"text/csv"}})
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="audit-log-2026-03-01-2026-03-01.csv"`, w.Header().Get("Content-Disposition"))
+
+	rows, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, auditCSVHeader, rows[0])
+	assert.Equal(t, []string{"omni", "2026-03-01T10:00:00Z", "alice@example.com", "create", "Clusters.omni.sidero.dev", "", omniAuditLines[0]}, rows[1])
+}
+
+func TestOmniAuditLogHandler_ExportAuditLog_InvalidParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := NewOmniAuditLogHandler(new(MockAuditLogService), nil)
+
+	for _, query := range []string{"from=yesterday", "from=2026-03-02&to=2026-03-01", "source=everything", "format=xml"} {
+		w := exportAuditLog(t, handler, query, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestOmniAuditLogHandler_ExportAuditLog_Error(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("before the first record", func(t *testing.T) {
+		omniAuditLog := new(MockAuditLogService)
+		omniAuditLog.On("ReadAuditLog", mock.Anything, mock.Anything, mock.Anything).Return(nil, status.Error(codes.Unavailable, "omni down"))
+		handler := NewOmniAuditLogHandler(omniAuditLog, nil)
+
+		w := exportAuditLog(t, handler, "", nil)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	})
+
+	t.Run("mid-stream", func(t *testing.T) {
+		omniAuditLog := new(MockAuditLogService)
+		omniAuditLog.On("ReadAuditLog", mock.Anything, mock.Anything, mock.Anything).Return(omniAuditLines[:1], status.Error(codes.Unavailable, "omni down"))
+		handler := NewOmniAuditLogHandler(omniAuditLog, nil)
+
+		w := exportAuditLog(t, handler, "from=2026-03-01", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		require.Len(t, lines, 2)
+		assert.Contains(t, lines[1], "omni down")
+	})
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/audit"
+	"github.com/jubblin/omni-api/internal/client"
+)
+
+// OmniconfigResponse represents the omniconfig returned by the API
+type OmniconfigResponse struct {
+	Data  string            `json:"data"` // Base64 encoded omniconfig
+	Links map[string]string `json:"_links,omitempty"`
+}
+
+// OmniconfigHandler handles omniconfig requests
+type OmniconfigHandler struct {
+	config client.ConfigService // Generates omniconfigs through the Management API
+	audit  *audit.Log
+}
+
+// NewOmniconfigHandler creates a new OmniconfigHandler
+func NewOmniconfigHandler(cfg client.ConfigService, auditLog *audit.Log) *OmniconfigHandler {
+	return &OmniconfigHandler{config: cfg, audit: auditLog}
+}
+
+// GetOmniconfig godoc
+// @Summary      Generate omniconfig
+// @Description  Generate an omniconfig for omnictl through the Omni Management API. Users authenticate against Omni when they first use it.
+// @Tags         omniconfig
+// @Produce      json
+// @Produce      application/yaml
+// @Param        download  query     bool  false  "Return the raw omniconfig file instead of JSON; also selected by Accept: application/yaml"
+// @Success      200  {object}  OmniconfigResponse
+// @Failure      500  {object}  map[string]string
+// @Router       /omniconfig [get]
+func (h *OmniconfigHandler) GetOmniconfig(c *gin.Context) {
+	data, err := h.config.Omniconfig(c.Request.Context())
+	if err != nil {
+		handleManagementError(c, err)
+		return
+	}
+
+	h.audit.Record(audit.Entry{
+		Actor:      audit.ActorFromRequest(c.Request),
+		Action:     "omniconfig.generate",
+		RemoteAddr: c.ClientIP(),
+	})
+
+	if wantsRawYAML(c) {
+		respondYAMLFile(c, "omniconfig.yaml", data)
+		return
+	}
+
+	c.JSON(http.StatusOK, OmniconfigResponse{
+		Data: base64.StdEncoding.EncodeToString(data),
+		Links: map[string]string{
+			"self": buildURL(c, "/api/v1/omniconfig"),
+		},
+	})
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestOmniconfigHandler_GetOmniconfig_YAML(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	config := new(MockConfigService)
+	config.On("Omniconfig", mock.Anything).Return([]byte("contexts: {}\n"), nil)
+	handler := NewOmniconfigHandler(config, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/omniconfig", nil)
+	c.Request.Header.Set("Accept", "application/yaml")
+
+	handler.GetOmniconfig(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `attachment; filename="omniconfig.yaml"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "contexts: {}\n", w.Body.String())
+}
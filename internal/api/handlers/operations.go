@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/operations"
+)
+
+// maxOperationWait caps the ?wait= long-poll duration
+const maxOperationWait = 5 * time.Minute
+
+// OperationResponse represents a long-running operation returned by the API
+type OperationResponse struct {
+	ID          string            `json:"id"`
+	Kind        string            `json:"kind"`
+	Target      string            `json:"target"`
+	Status      string            `json:"status"`
+	Done        bool              `json:"done"`
+	Phase       string            `json:"phase,omitempty"`
+	Message     string            `json:"message,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	Result      interface{}       `json:"result,omitempty"` // Kind specific details, e.g. the per-item report of a bulk operation
+	Cancelable  bool              `json:"cancelable"`
+	CreatedAt   string            `json:"created_at"`
+	UpdatedAt   string            `json:"updated_at"`
+	CompletedAt string            `json:"completed_at,omitempty"`
+	Links       map[string]string `json:"_links,omitempty"`
+}
+
+// OperationHandler handles long-running operation requests
+type OperationHandler struct {
+	operations *operations.Manager
+}
+
+// NewOperationHandler creates a new OperationHandler
+func NewOperationHandler(ops *operations.Manager) *OperationHandler {
+	return &OperationHandler{operations: ops}
+}
+
+// ListOperations godoc
+// @Summary      List operations
+// @Description  Get a list of long-running operations started by asynchronous actions, newest first
+// @Tags         operations
+// @Produce      json
+// @Success      200  {array}   OperationResponse
+// @Router       /operations [get]
+func (h *OperationHandler) ListOperations(c *gin.Context) {
+	ops := h.operations.List()
+
+	resp := make([]OperationResponse, 0, len(ops))
+	for _, op := range ops {
+		resp = append(resp, operationResponse(c, op))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetOperation godoc
+// @Summary      Get an operation
+// @Description  Get the progress of a long-running operation. Use wait (e.g. 30s, at most 5m) to long-poll until the operation completes.
+// @Tags         operations
+// @Produce      json
+// @Param        id    path      string  true   "Operation ID"
+// @Param        wait  query     string  false  "Maximum time to wait for completion, e.g. 30s"
+// @Success      200   {object}  OperationResponse
+// @Failure      400   {object}  map[string]string
+// @Failure      404   {object}  map[string]string
+// @Router       /operations/{id} [get]
+func (h *OperationHandler) GetOperation(c *gin.Context) {
+	id := c.Param("id")
+
+	var wait time.Duration
+	if value := c.Query("wait"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wait duration"})
+			return
+		}
+		wait = min(d, maxOperationWait)
+	}
+
+	op, ok := h.operations.Get(id)
+	if ok && wait > 0 && !op.Done() {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), wait)
+		defer cancel()
+		op, ok = h.operations.Wait(ctx, id)
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "operation not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, operationResponse(c, op))
+}
+
+// CancelOperation godoc
+// @Summary      Cancel an operation
+// @Description  Cancel a running operation, e.g. by reverting an upgrade to the previous version. Not all operations can be canceled.
+// @Tags         operations
+// @Produce      json
+// @Param        id   path      string  true  "Operation ID"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200  {object}  OperationResponse
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /operations/{id}/cancel [post]
+func (h *OperationHandler) CancelOperation(c *gin.Context) {
+	id := c.Param("id")
+
+	op, err := h.operations.Cancel(c.Request.Context(), id)
+	switch {
+	case errors.Is(err, operations.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, operations.ErrNotCancelable), errors.Is(err, operations.ErrCompleted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		handleManagementError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, operationResponse(c, op))
+}
+
+// startOperation starts tracking an asynchronous action, points the Location header
+// at the operation and adds the operation to the action's response body
+func startOperation(c *gin.Context, ops *operations.Manager, spec operations.Spec, resp gin.H) {
+	op := ops.Start(spec)
+	location := buildURL(c, "/api/v1/operations/"+op.ID)
+
+	c.Header("Location", location)
+	resp["operation_id"] = op.ID
+	resp["_links"] = map[string]string{"operation": location}
+}
+
+func operationResponse(c *gin.Context, op operations.Operation) OperationResponse {
+	resp := OperationResponse{
+		ID:         op.ID,
+		Kind:       op.Kind,
+		Target:     op.Target,
+		Status:     string(op.Status),
+		Done:       op.Done(),
+		Phase:      op.Phase,
+		Message:    op.Message,
+		Error:      op.Error,
+		Result:     op.Result,
+		Cancelable: op.Cancelable,
+		CreatedAt:  op.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt:  op.UpdatedAt.UTC().Format(time.RFC3339),
+		Links: map[string]string{
+			"self": buildURL(c, "/api/v1/operations/"+op.ID),
+		},
+	}
+	if op.Done() {
+		resp.CompletedAt = op.CompletedAt.UTC().Format(time.RFC3339)
+	}
+	if op.TargetPath != "" {
+		resp.Links["target"] = buildURL(c, op.TargetPath)
+	}
+	if op.Cancelable {
+		resp.Links["cancel"] = buildURL(c, "/api/v1/operations/"+op.ID+"/cancel")
+	}
+	return resp
+}
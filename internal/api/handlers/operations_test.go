@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/operations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOperationHandler_GetOperation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ops := operations.NewManager(operations.Config{})
+	op := ops.Start(operations.Spec{
+		Kind:       "kubernetes-upgrade",
+		Target:     "test-cluster",
+		TargetPath: "/api/v1/clusters/test-cluster/kubernetes-upgrade",
+		Track: func(ctx context.Context, report func(operations.Progress)) error {
+			report(operations.Progress{Phase: "Done"})
+			return nil
+		},
+	})
+	handler := NewOperationHandler(ops)
+
+	t.Run("Wait for completion", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "id", Value: op.ID}}
+		c.Request, _ = http.NewRequest("GET", "/api/v1/operations/"+op.ID+"?wait=30s", nil)
+
+		handler.GetOperation(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var resp OperationResponse
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, op.ID, resp.ID)
+		assert.Equal(t, "succeeded", resp.Status)
+		assert.True(t, resp.Done)
+		assert.Equal(t, "Done", resp.Phase)
+		assert.NotEmpty(t, resp.CompletedAt)
+		assert.Contains(t, resp.Links["target"], "/api/v1/clusters/test-cluster/kubernetes-upgrade")
+	})
+
+	t.Run("Invalid wait", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "id", Value: op.ID}}
+		c.Request, _ = http.NewRequest("GET", "/api/v1/operations/"+op.ID+"?wait=soon", nil)
+
+		handler.GetOperation(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "id", Value: "op-missing"}}
+		c.Request, _ = http.NewRequest("GET", "/api/v1/operations/op-missing", nil)
+
+		handler.GetOperation(c)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestOperationHandler_CancelOperation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ops := operations.NewManager(operations.Config{})
+	running := func(ctx context.Context, report func(operations.Progress)) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	handler := NewOperationHandler(ops)
+
+	t.Run("Cancelable", func(t *testing.T) {
+		op := ops.Start(operations.Spec{
+			Kind:   "talos-upgrade",
+			Track:  running,
+			Cancel: func(ctx context.Context) error { return nil },
+		})
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "id", Value: op.ID}}
+		c.Request, _ = http.NewRequest("POST", "/api/v1/operations/"+op.ID+"/cancel", nil)
+
+		handler.CancelOperation(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var resp OperationResponse
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, "canceled", resp.Status)
+	})
+
+	t.Run("Not cancelable", func(t *testing.T) {
+		op := ops.Start(operations.Spec{Kind: "bootstrap", Track: running})
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "id", Value: op.ID}}
+		c.Request, _ = http.NewRequest("POST", "/api/v1/operations/"+op.ID+"/cancel", nil)
+
+		handler.CancelOperation(c)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+}
+
+func TestOperationHandler_ListOperations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ops := operations.NewManager(operations.Config{})
+	ops.Start(operations.Spec{
+		Kind:  "etcd-backup",
+		Track: func(ctx context.Context, report func(operations.Progress)) error { return nil },
+	})
+	handler := NewOperationHandler(ops)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/api/v1/operations", nil)
+
+	handler.ListOperations(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp []OperationResponse
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	require.NoError(t, err)
+	assert.Len(t, resp, 1)
+	assert.Equal(t, "etcd-backup", resp[0].Kind)
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/client"
+	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+// CircuitBreakerStatus represents the state of the Omni circuit breaker
+type CircuitBreakerStatus struct {
+	State               string `json:"state"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	Trips               uint64 `json:"trips"`
+	OpenedAt            string `json:"opened_at,omitempty"`
+	RetryAfterSeconds   int    `json:"retry_after_seconds,omitempty"`
+	LastError           string `json:"last_error,omitempty"`
+}
+
+// ReadinessResponse represents whether the API is ready to serve requests
+type ReadinessResponse struct {
+	Ready          bool                 `json:"ready"`
+	Timestamp      string               `json:"timestamp"`
+	Error          string               `json:"error,omitempty"`
+	CircuitBreaker CircuitBreakerStatus `json:"circuit_breaker"`
+	Links          map[string]string    `json:"_links,omitempty"`
+}
+
+// ReadinessHandler handles readiness probe requests
+type ReadinessHandler struct {
+	state   state.State
+	breaker *client.Breaker
+}
+
+// NewReadinessHandler creates a new ReadinessHandler
+func NewReadinessHandler(s state.State, b *client.Breaker) *ReadinessHandler {
+	return &ReadinessHandler{state: s, breaker: b}
+}
+
+// GetReadiness godoc
+// @Summary      Get API readiness
+// @Description  Report whether the API can currently reach Omni, including the circuit breaker state. Returns 503 while Omni is unavailable.
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  ReadinessResponse
+// @Failure      503  {object}  ReadinessResponse
+// @Router       /readyz [get]
+func (h *ReadinessHandler) GetReadiness(c *gin.Context) {
+	resp := ReadinessResponse{
+		Ready:     true,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Links: map[string]string{
+			"self":    buildURL(c, "/readyz"),
+			"health":  buildURL(c, "/health"),
+			"metrics": buildURL(c, "/metrics"),
+		},
+	}
+
+	// Don't probe Omni while the breaker is open, the probe would be rejected anyway
+	if h.breaker.State() != client.BreakerOpen {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+		defer cancel()
+
+		md := resource.NewMetadata(omniresources.DefaultNamespace, omni.ClusterType, "", resource.VersionUndefined)
+		if _, err := h.state.List(ctx, md); err != nil {
+			resp.Ready = false
+			resp.Error = err.Error()
+		}
+	} else {
+		resp.Ready = false
+		resp.Error = client.ErrCircuitOpen.Error()
+	}
+
+	snapshot := h.breaker.Snapshot()
+	resp.CircuitBreaker = circuitBreakerStatus(snapshot)
+
+	if !resp.Ready {
+		if snapshot.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(snapshot.RetryAfterSeconds()))
+		}
+		c.JSON(http.StatusServiceUnavailable, resp)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// circuitBreakerStatus converts a breaker snapshot into its API representation
+func circuitBreakerStatus(snapshot client.BreakerSnapshot) CircuitBreakerStatus {
+	status := CircuitBreakerStatus{
+		State:               string(snapshot.State),
+		ConsecutiveFailures: snapshot.ConsecutiveFailures,
+		Trips:               snapshot.Trips,
+		RetryAfterSeconds:   snapshot.RetryAfterSeconds(),
+		LastError:           snapshot.LastError,
+	}
+	if !snapshot.OpenedAt.IsZero() {
+		status.OpenedAt = snapshot.OpenedAt.UTC().Format(time.RFC3339)
+	}
+	return status
+}
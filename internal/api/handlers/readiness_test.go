@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestReadinessHandler_GetReadiness(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Ready", func(t *testing.T) {
+		mockState := new(MockState)
+		mockState.On("List", mock.Anything, mock.Anything, mock.Anything).Return(resource.List{}, nil)
+
+		handler := NewReadinessHandler(mockState, client.NewBreaker(client.BreakerConfig{}))
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/readyz", nil)
+
+		handler.GetReadiness(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var resp ReadinessResponse
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+		assert.True(t, resp.Ready)
+		assert.Equal(t, "closed", resp.CircuitBreaker.State)
+		assert.NotEmpty(t, resp.Links["self"])
+	})
+
+	t.Run("Circuit open", func(t *testing.T) {
+		mockState := new(MockState)
+		breaker := client.NewBreaker(client.BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute})
+		breaker.Record(status.Error(codes.Unavailable, "connection refused"))
+
+		handler := NewReadinessHandler(mockState, breaker)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/readyz", nil)
+
+		handler.GetReadiness(c)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+		var resp ReadinessResponse
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+		assert.False(t, resp.Ready)
+		assert.Equal(t, "open", resp.CircuitBreaker.State)
+		assert.Equal(t, uint64(1), resp.CircuitBreaker.Trips)
+
+		// Omni must not be probed while the breaker is open
+		mockState.AssertNotCalled(t, "List", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/audit"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/jubblin/omni-api/internal/supportbundle"
+	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+// SupportBundleResponse represents a support bundle returned by the API
+type SupportBundleResponse struct {
+	ID          string                         `json:"id"`
+	Cluster     string                         `json:"cluster"`
+	Status      string                         `json:"status"`
+	Done        bool                           `json:"done"`
+	Error       string                         `json:"error,omitempty"`
+	Progress    []client.SupportBundleProgress `json:"progress"`
+	Size        int64                          `json:"size,omitempty"`
+	CreatedAt   string                         `json:"created_at"`
+	CompletedAt string                         `json:"completed_at,omitempty"`
+	ExpiresAt   string                         `json:"expires_at,omitempty"`
+	Links       map[string]string              `json:"_links,omitempty"`
+}
+
+// SupportBundleHandler handles support bundle requests
+type SupportBundleHandler struct {
+	state   state.State
+	bundles *supportbundle.Manager
+	audit   *audit.Log
+}
+
+// NewSupportBundleHandler creates a new SupportBundleHandler
+func NewSupportBundleHandler(s state.State, bundles *supportbundle.Manager, auditLog *audit.Log) *SupportBundleHandler {
+	return &SupportBundleHandler{state: s, bundles: bundles, audit: auditLog}
+}
+
+// CreateSupportBundle godoc
+// @Summary      Generate a cluster support bundle
+// @Description  Start collecting Talos and Kubernetes diagnostics from every machine of a cluster through the Omni Management API, like omnictl support.
+// @Description  Follow per-node progress with the events link (Server-Sent Events) and download the zip once the bundle is ready; it is deleted after OMNI_API_SUPPORT_BUNDLE_TTL.
+// @Tags         clusters
+// @Produce      json
+// @Param        id   path      string  true  "Cluster ID"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      202  {object}  SupportBundleResponse
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /clusters/{id}/support-bundle [post]
+func (h *SupportBundleHandler) CreateSupportBundle(c *gin.Context) {
+	id := c.Param("id")
+
+	// Verify cluster exists
+	md := resource.NewMetadata(omniresources.DefaultNamespace, omni.ClusterType, id, resource.VersionUndefined)
+	if _, err := h.state.Get(c.Request.Context(), md); err != nil {
+		if state.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "cluster not found"})
+			return
+		}
+		log.Printf("Error getting cluster %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	bundle := h.bundles.Start(id)
+
+	// Bundles contain machine logs and configuration
+	h.audit.Record(audit.Entry{
+		Actor:      audit.ActorFromRequest(c.Request),
+		Action:     "support-bundle.generate",
+		Target:     id,
+		RemoteAddr: c.ClientIP(),
+		Details:    map[string]string{"bundle": bundle.ID},
+	})
+
+	resp := supportBundleResponse(c, bundle)
+	c.Header("Location", resp.Links["self"])
+	c.JSON(http.StatusAccepted, resp)
+}
+
+// GetSupportBundle godoc
+// @Summary      Get a support bundle
+// @Description  Get the status and per-node progress of a support bundle
+// @Tags         support-bundles
+// @Produce      json
+// @Param        id   path      string  true  "Support bundle ID"
+// @Success      200  {object}  SupportBundleResponse
+// @Failure      404  {object}  map[string]string
+// @Router       /support-bundles/{id} [get]
+func (h *SupportBundleHandler) GetSupportBundle(c *gin.Context) {
+	bundle, ok := h.bundles.Get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": supportbundle.ErrNotFound.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, supportBundleResponse(c, bundle))
+}
+
+// StreamSupportBundleEvents godoc
+// @Summary      Stream support bundle progress
+// @Description  Stream the progress of a support bundle as Server-Sent Events.
+// @Description  A "progress" event with the source (node), state and value/total is sent whenever the progress of a node changes, followed by a final "done" event carrying the SupportBundleResponse.
+// @Tags         support-bundles
+// @Produce      text/event-stream
+// @Param        id   path      string  true  "Support bundle ID"
+// @Success      200  {string}  string
+// @Failure      404  {object}  map[string]string
+// @Router       /support-bundles/{id}/events [get]
+func (h *SupportBundleHandler) StreamSupportBundleEvents(c *gin.Context) {
+	id := c.Param("id")
+
+	bundle, changed, ok := h.bundles.Watch(id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": supportbundle.ErrNotFound.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	sent := map[string]client.SupportBundleProgress{}
+	for {
+		for _, p := range bundle.Progress {
+			if sent[p.Source] != p {
+				c.SSEvent("progress", p)
+				sent[p.Source] = p
+			}
+		}
+
+		if bundle.Done() {
+			c.SSEvent("done", supportBundleResponse(c, bundle))
+			c.Writer.Flush()
+			return
+		}
+		c.Writer.Flush()
+
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-changed:
+		}
+
+		if bundle, changed, ok = h.bundles.Watch(id); !ok {
+			c.SSEvent("error", supportbundle.ErrNotFound.Error())
+			c.Writer.Flush()
+			return
+		}
+	}
+}
+
+// DownloadSupportBundle godoc
+// @Summary      Download a support bundle
+// @Description  Download the zip archive of a ready support bundle
+// @Tags         support-bundles
+// @Produce      application/zip
+// @Param        id   path      string  true  "Support bundle ID"
+// @Success      200  {file}    file
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /support-bundles/{id}/download [get]
+func (h *SupportBundleHandler) DownloadSupportBundle(c *gin.Context) {
+	f, bundle, err := h.bundles.Open(c.Param("id"))
+	switch {
+	case errors.Is(err, supportbundle.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, supportbundle.ErrNotReady):
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("%s: status is %s", err, bundle.Status)})
+		return
+	case err != nil:
+		log.Printf("Error opening support bundle %s: %v", bundle.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer f.Close()
+
+	filename := fmt.Sprintf("support-bundle-%s-%s.zip", bundle.Cluster, bundle.CompletedAt.UTC().Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Content-Length", strconv.FormatInt(bundle.Size, 10))
+	c.Header("Content-Type", "application/zip")
+	c.Status(http.StatusOK)
+	if _, err := io.Copy(c.Writer, f); err != nil {
+		log.Printf("Error sending support bundle %s: %v", bundle.ID, err)
+	}
+}
+
+func supportBundleResponse(c *gin.Context, bundle supportbundle.Bundle) SupportBundleResponse {
+	self := "/api/v1/support-bundles/" + bundle.ID
+	resp := SupportBundleResponse{
+		ID:        bundle.ID,
+		Cluster:   bundle.Cluster,
+		Status:    string(bundle.Status),
+		Done:      bundle.Done(),
+		Error:     bundle.Error,
+		Progress:  bundle.Progress,
+		Size:      bundle.Size,
+		CreatedAt: bundle.CreatedAt.UTC().Format(time.RFC3339),
+		Links: map[string]string{
+			"self":    buildURL(c, self),
+			"events":  buildURL(c, self+"/events"),
+			"cluster": buildURL(c, "/api/v1/clusters/"+bundle.Cluster),
+		},
+	}
+	if bundle.Done() {
+		resp.CompletedAt = bundle.CompletedAt.UTC().Format(time.RFC3339)
+		resp.ExpiresAt = bundle.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	if bundle.Status == supportbundle.StatusReady {
+		resp.Links["download"] = buildURL(c, self+"/download")
+	}
+	return resp
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/audit"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/jubblin/omni-api/internal/supportbundle"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// stubSupportService reports progress for two nodes, then returns data or err
+type stubSupportService struct {
+	data []byte
+	err  error
+}
+
+func (s *stubSupportService) SupportBundle(ctx context.Context, clusterID string, report func(client.SupportBundleProgress)) ([]byte, error) {
+	report(client.SupportBundleProgress{Source: "node-1", State: "collecting", Total: 2, Value: 1})
+	report(client.SupportBundleProgress{Source: "node-2", State: "done", Total: 2, Value: 2})
+	report(client.SupportBundleProgress{Source: "node-1", State: "done", Total: 2, Value: 2})
+	return s.data, s.err
+}
+
+func newSupportBundleRouter(t *testing.T, mockState *MockState, support client.SupportService) (*gin.Engine, *supportbundle.Manager, *audit.Log) {
+	bundles, err := supportbundle.NewManager(supportbundle.Config{Dir: t.TempDir()}, support)
+	require.NoError(t, err)
+	auditLog, err := audit.New(audit.Config{File: filepath.Join(t.TempDir(), "audit.log")})
+	require.NoError(t, err)
+
+	handler := NewSupportBundleHandler(mockState, bundles, auditLog)
+	r := gin.New()
+	r.POST("/clusters/:id/support-bundle", handler.CreateSupportBundle)
+	r.GET("/support-bundles/:id", handler.GetSupportBundle)
+	r.GET("/support-bundles/:id/events", handler.StreamSupportBundleEvents)
+	r.GET("/support-bundles/:id/download", handler.DownloadSupportBundle)
+	return r, bundles, auditLog
+}
+
+func serveSupportBundle(r *gin.Engine, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, nil)
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestSupportBundleHandler_CreateAndDownload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockState := new(MockState)
+	mockState.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(omni.NewCluster("default", "prod"), nil)
+	r, _, auditLog := newSupportBundleRouter(t, mockState, &stubSupportService{data: []byte("PK bundle")})
+
+	w := serveSupportBundle(r, "POST", "/clusters/prod/support-bundle")
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	var created SupportBundleResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "prod", created.Cluster)
+	assert.Equal(t, "http://localhost:8080/api/v1/support-bundles/"+created.ID, w.Header().Get("Location"))
+	require.Len(t, auditLog.Entries(), 1)
+	assert.Equal(t, "support-bundle.generate", auditLog.Entries()[0].Action)
+
+	// The event stream ends once the bundle is ready
+	w = serveSupportBundle(r, "GET", "/support-bundles/"+created.ID+"/events")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "event:progress\ndata:{\"source\":\"node-2\"")
+	assert.Contains(t, w.Body.String(), "event:done\n")
+
+	w = serveSupportBundle(r, "GET", "/support-bundles/"+created.ID)
+	var bundle SupportBundleResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &bundle))
+	assert.Equal(t, "ready", bundle.Status)
+	assert.NotEmpty(t, bundle.ExpiresAt)
+	require.Len(t, bundle.Progress, 2)
+	assert.Equal(t, client.SupportBundleProgress{Source: "node-1", State: "done", Total: 2, Value: 2}, bundle.Progress[0])
+	assert.Contains(t, bundle.Links, "download")
+
+	w = serveSupportBundle(r, "GET", "/support-bundles/"+created.ID+"/download")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "support-bundle-prod-")
+	assert.Equal(t, "PK bundle", w.Body.String())
+}
+
+func TestSupportBundleHandler_Failed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockState := new(MockState)
+	mockState.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(omni.NewCluster("default", "prod"), nil)
+	r, bundles, _ := newSupportBundleRouter(t, mockState, &stubSupportService{err: errors.New("no machines")})
+
+	w := serveSupportBundle(r, "POST", "/clusters/prod/support-bundle")
+	var created SupportBundleResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+
+	assert.Eventually(t, func() bool {
+		bundle, _ := bundles.Get(created.ID)
+		return bundle.Done()
+	}, 5*time.Second, 5*time.Millisecond)
+
+	w = serveSupportBundle(r, "GET", "/support-bundles/"+created.ID+"/download")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = serveSupportBundle(r, "GET", "/support-bundles/"+created.ID)
+	assert.Contains(t, w.Body.String(), "no machines")
+}
+
+func TestSupportBundleHandler_NotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockState := new(MockState)
+	mockState.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(nil, notFoundError{})
+	r, _, _ := newSupportBundleRouter(t, mockState, &stubSupportService{})
+
+	assert.Equal(t, http.StatusNotFound, serveSupportBundle(r, "POST", "/clusters/missing/support-bundle").Code)
+	assert.Equal(t, http.StatusNotFound, serveSupportBundle(r, "GET", "/support-bundles/sb-missing").Code)
+	assert.Equal(t, http.StatusNotFound, serveSupportBundle(r, "GET", "/support-bundles/sb-missing/events").Code)
+	assert.Equal(t, http.StatusNotFound, serveSupportBundle(r, "GET", "/support-bundles/sb-missing/download").Code)
+}
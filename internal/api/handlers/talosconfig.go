@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/api/middleware"
+	"github.com/jubblin/omni-api/internal/audit"
+	"github.com/jubblin/omni-api/internal/client"
+	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+// TalosconfigResponse represents the talosconfig information returned by the API
+// WARNING: Break-glass talosconfigs contain credentials
+type TalosconfigResponse struct {
+	ID         string            `json:"id"`
+	BreakGlass bool              `json:"break_glass"`
+	Data       string            `json:"data"` // Base64 encoded talosconfig
+	Links      map[string]string `json:"_links,omitempty"`
+}
+
+// TalosconfigHandler handles talosconfig requests
+type TalosconfigHandler struct {
+	state           state.State
+	config          client.ConfigService // Generates talosconfigs through the Management API
+	audit           *audit.Log
+	breakGlassUsers map[string]bool // Authenticated users allowed to download break-glass talosconfigs
+}
+
+// NewTalosconfigHandler creates a new TalosconfigHandler.
+// breakGlassUsers lists the authenticated users allowed to download break-glass talosconfigs; empty entries are ignored.
+func NewTalosconfigHandler(s state.State, cfg client.ConfigService, auditLog *audit.Log, breakGlassUsers []string) *TalosconfigHandler {
+	return &TalosconfigHandler{state: s, config: cfg, audit: auditLog, breakGlassUsers: stringSet(breakGlassUsers)}
+}
+
+// stringSet returns the non-empty values, trimmed of spaces, as a set
+func stringSet(values []string) map[string]bool {
+	set := make(map[string]bool)
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			set[value] = true
+		}
+	}
+	return set
+}
+
+// GetTalosconfig godoc
+// @Summary      Generate cluster talosconfig
+// @Description  Generate a talosconfig for a cluster through the Omni Management API. The talosconfig reaches the machines through Omni and authenticates each user against Omni.
+// @Description  break_glass=true returns an operator talosconfig that talks to the machines directly; it is only available to users listed in OMNI_API_BREAK_GLASS_USERS, authenticated by a proxy in OMNI_API_TRUSTED_PROXIES.
+// @Description  Every generated talosconfig is recorded in the audit log.
+// @Tags         talosconfigs
+// @Produce      json
+// @Produce      application/yaml
+// @Param        id           path      string  true   "Cluster ID"
+// @Param        break_glass  query     bool    false  "Generate an operator talosconfig that bypasses Omni"
+// @Param        download     query     bool    false  "Return the raw talosconfig file instead of JSON; also selected by Accept: application/yaml"
+// @Success      200  {object}  TalosconfigResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /clusters/{id}/talosconfig [get]
+func (h *TalosconfigHandler) GetTalosconfig(c *gin.Context) {
+	id := c.Param("id")
+	actor := audit.ActorFromRequest(c.Request)
+
+	breakGlass := false
+	if value := c.Query("break_glass"); value != "" {
+		var err error
+		if breakGlass, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid break_glass value"})
+			return
+		}
+	}
+	// The user must come from a trusted authenticating proxy; request headers alone can be set by anyone
+	if user, authenticated := middleware.AuthenticatedUser(c); breakGlass && (!authenticated || !h.breakGlassUsers[user]) {
+		h.audit.Record(audit.Entry{
+			Actor:      actor,
+			Action:     "talosconfig.break-glass.denied",
+			Target:     id,
+			RemoteAddr: c.ClientIP(),
+		})
+		c.JSON(http.StatusForbidden, gin.H{"error": "break-glass talosconfigs are restricted to administrators"})
+		return
+	}
+
+	// Verify cluster exists
+	md := resource.NewMetadata(omniresources.DefaultNamespace, omni.ClusterType, id, resource.VersionUndefined)
+	if _, err := h.state.Get(c.Request.Context(), md); err != nil {
+		if state.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "cluster not found"})
+			return
+		}
+		log.Printf("Error getting cluster %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, err := h.config.Talosconfig(c.Request.Context(), id, client.TalosconfigOptions{BreakGlass: breakGlass})
+	if err != nil {
+		handleManagementError(c, err)
+		return
+	}
+
+	h.audit.Record(audit.Entry{
+		Actor:      actor,
+		Action:     "talosconfig.generate",
+		Target:     id,
+		RemoteAddr: c.ClientIP(),
+		Details:    map[string]string{"break_glass": strconv.FormatBool(breakGlass)},
+	})
+
+	if wantsRawYAML(c) {
+		respondYAMLFile(c, id+"-talosconfig.yaml", data)
+		return
+	}
+
+	c.JSON(http.StatusOK, TalosconfigResponse{
+		ID:         id,
+		BreakGlass: breakGlass,
+		Data:       base64.StdEncoding.EncodeToString(data),
+		Links: map[string]string{
+			"self":    buildURL(c, "/api/v1/clusters/"+id+"/talosconfig"),
+			"cluster": buildURL(c, "/api/v1/clusters/"+id),
+		},
+	})
+}
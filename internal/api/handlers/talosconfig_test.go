@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/api/middleware"
+	"github.com/jubblin/omni-api/internal/audit"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTalosconfigTestHandler(t *testing.T, config *MockConfigService) (*TalosconfigHandler, *audit.Log) {
+	mockState := new(MockState)
+	mockState.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(omni.NewCluster("default", "cluster-1"), nil)
+
+	auditLog, err := audit.New(audit.Config{File: filepath.Join(t.TempDir(), "audit.log")})
+	require.NoError(t, err)
+
+	return NewTalosconfigHandler(mockState, config, auditLog, []string{"admin", ""}), auditLog
+}
+
+// authenticate runs the Identity middleware with 10.0.0.0/8 as the trusted proxies
+func authenticate(c *gin.Context) {
+	middleware.Identity(middleware.IdentityConfig{TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}})(c)
+}
+
+func TestTalosconfigHandler_GetTalosconfig(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	config := new(MockConfigService)
+	config.On("Talosconfig", mock.Anything, "cluster-1", client.TalosconfigOptions{}).Return([]byte("context: cluster-1\n"), nil)
+	handler, auditLog := newTalosconfigTestHandler(t, config)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "cluster-1"}}
+	c.Request, _ = http.NewRequest("GET", "/clusters/cluster-1/talosconfig", nil)
+
+	handler.GetTalosconfig(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp TalosconfigResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "cluster-1", resp.ID)
+	assert.False(t, resp.BreakGlass)
+	assert.Equal(t, "http://localhost:8080/api/v1/clusters/cluster-1/talosconfig", resp.Links["self"])
+	assert.Len(t, auditLog.Entries(), 1)
+}
+
+func TestTalosconfigHandler_GetTalosconfig_BreakGlass(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		actor      string
+		remoteAddr string
+		wantStatus int
+		wantAction string
+	}{
+		{name: "admin", actor: "admin", remoteAddr: "10.0.0.1:40000", wantStatus: http.StatusOK, wantAction: "talosconfig.generate"},
+		{name: "other user", actor: "bob", remoteAddr: "10.0.0.1:40000", wantStatus: http.StatusForbidden, wantAction: "talosconfig.break-glass.denied"},
+		{name: "anonymous", remoteAddr: "10.0.0.1:40000", wantStatus: http.StatusForbidden, wantAction: "talosconfig.break-glass.denied"},
+		{name: "admin header from an untrusted client", actor: "admin", remoteAddr: "192.0.2.1:40000", wantStatus: http.StatusForbidden, wantAction: "talosconfig.break-glass.denied"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := new(MockConfigService)
+			config.On("Talosconfig", mock.Anything, "cluster-1", client.TalosconfigOptions{BreakGlass: true}).Return([]byte("context: cluster-1\n"), nil)
+			handler, auditLog := newTalosconfigTestHandler(t, config)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = []gin.Param{{Key: "id", Value: "cluster-1"}}
+			c.Request, _ = http.NewRequest("GET", "/clusters/cluster-1/talosconfig?break_glass=true&download=1", nil)
+			c.Request.RemoteAddr = tt.remoteAddr
+			if tt.actor != "" {
+				c.Request.Header.Set("X-Remote-User", tt.actor)
+			}
+
+			authenticate(c)
+			handler.GetTalosconfig(c)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			entries := auditLog.Entries()
+			require.Len(t, entries, 1)
+			assert.Equal(t, tt.wantAction, entries[0].Action)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, "context: cluster-1\n", w.Body.String())
+			} else {
+				config.AssertNotCalled(t, "Talosconfig", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
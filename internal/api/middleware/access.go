@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Cross-cutting route groups. Every route also belongs to the group named after
+// its first path segment, e.g. "clusters" or "machinesets".
+const (
+	GroupActions        = "actions"         // POST .../actions/* routes
+	GroupKubeconfig     = "kubeconfig"      // Kubeconfig download routes
+	GroupTalosconfig    = "talosconfig"     // Talosconfig download routes
+	GroupSupportBundles = "support-bundles" // Support bundle routes, including POST /clusters/:id/support-bundle
+	GroupWrite          = "write"           // All mutating routes
+)
+
+// readOnlyRoutes are POST routes that only compute a result, such as previews and diffs.
+// They are served in read-only mode and do not belong to GroupWrite.
+var readOnlyRoutes = map[string]bool{
+	"POST /machines/:id/config-preview": true,
+	"POST /cluster-templates:validate":  true,
+	"POST /cluster-templates:diff":      true,
+}
+
+// AccessConfig controls which API routes are served
+type AccessConfig struct {
+	ReadOnly       bool            // Reject every mutating route
+	EnabledGroups  map[string]bool // If set, only routes whose groups are all listed here are served
+	DisabledGroups map[string]bool // Routes in any of these groups are not served
+}
+
+// AccessConfigFromEnv builds an AccessConfig from environment variables
+func AccessConfigFromEnv() AccessConfig {
+	return AccessConfig{
+		ReadOnly:       os.Getenv("OMNI_API_READ_ONLY") == "true",
+		EnabledGroups:  parseGroups(os.Getenv("OMNI_API_ENABLED_GROUPS")),
+		DisabledGroups: parseGroups(os.Getenv("OMNI_API_DISABLED_GROUPS")),
+	}
+}
+
+// RouteGroups returns the groups a route belongs to.
+// The path is relative to the API base path and may use gin (:id) or Swagger ({id}) parameters.
+func RouteGroups(method, path string) []string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) == 0 || segments[0] == "" {
+		return nil
+	}
+
+	groups := []string{resourceGroup(segments[0])}
+	for i, segment := range segments {
+		if segment == "actions" && i > 0 {
+			groups = append(groups, GroupActions)
+		}
+		if segment == "kubeconfig" {
+			groups = append(groups, GroupKubeconfig)
+		}
+		if segment == "talosconfig" && i > 0 {
+			groups = append(groups, GroupTalosconfig)
+		}
+		if segment == "support-bundle" && i > 0 {
+			groups = append(groups, GroupSupportBundles)
+		}
+	}
+	if mutates(method, path) {
+		groups = append(groups, GroupWrite)
+	}
+
+	return groups
+}
+
+// Allowed reports whether a route is served, returning the reason if not
+func (cfg AccessConfig) Allowed(method, path string) (bool, string) {
+	if cfg.ReadOnly && mutates(method, path) {
+		return false, "the API is running in read-only mode"
+	}
+
+	for _, group := range RouteGroups(method, path) {
+		if cfg.DisabledGroups[group] {
+			return false, fmt.Sprintf("route group %q is disabled", group)
+		}
+		if len(cfg.EnabledGroups) > 0 && !cfg.EnabledGroups[group] && group != GroupWrite {
+			return false, fmt.Sprintf("route group %q is not enabled", group)
+		}
+	}
+
+	return true, ""
+}
+
+// Access rejects requests to routes disabled by the AccessConfig.
+// Mutating routes in read-only mode get 405, routes in disabled groups get 404.
+// basePath is stripped from the matched route before it is classified.
+func Access(cfg AccessConfig, basePath string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := routePath(c)
+		if route == "" {
+			c.Next()
+			return
+		}
+
+		path := strings.TrimPrefix(route, basePath)
+		if ok, reason := cfg.Allowed(c.Request.Method, path); !ok {
+			if cfg.ReadOnly && mutates(c.Request.Method, path) {
+				c.Header("Allow", "GET, HEAD, OPTIONS")
+				c.AbortWithStatusJSON(http.StatusMethodNotAllowed, gin.H{"error": reason})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": reason})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// resourceGroup maps the first path segment to its group name
+func resourceGroup(segment string) string {
+	// Custom methods such as "cluster-templates:apply" belong to their collection
+	if i := strings.Index(segment, ":"); i > 0 {
+		segment = segment[:i]
+	}
+	return segment
+}
+
+// mutates reports whether a route may change state; path may use gin (:id) or Swagger ({id}) parameters
+func mutates(method, path string) bool {
+	return isMutating(method) && !readOnlyRoutes[method+" "+swaggerParams.ReplaceAllString(path, ":$1")]
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	default:
+		return true
+	}
+}
+
+var swaggerParams = regexp.MustCompile(`\{([^}/]+)\}`)
+
+func parseGroups(value string) map[string]bool {
+	if value == "" {
+		return nil
+	}
+
+	groups := make(map[string]bool)
+	for _, group := range strings.Split(value, ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			groups[group] = true
+		}
+	}
+	return groups
+}
+
+// routePath returns the route matched by a request. Custom method routes are registered with an escaped colon,
+// e.g. "/cluster-templates\\:apply", which gin only unescapes when the server is started with Run.
+func routePath(c *gin.Context) string {
+	return strings.ReplaceAll(c.FullPath(), "\\:", ":")
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteGroups(t *testing.T) {
+	assert.Equal(t, []string{"clusters"}, RouteGroups("GET", "/clusters/:id"))
+	assert.Equal(t, []string{"clusters", "kubeconfig"}, RouteGroups("GET", "/clusters/{id}/kubeconfig"))
+	assert.Equal(t, []string{"clusters", "talosconfig"}, RouteGroups("GET", "/clusters/{id}/talosconfig"))
+	assert.Equal(t, []string{"clusters", "support-bundles", "write"}, RouteGroups("POST", "/clusters/{id}/support-bundle"))
+	assert.Equal(t, []string{"support-bundles"}, RouteGroups("GET", "/support-bundles/{id}/download"))
+	assert.Equal(t, []string{"machines", "actions", "write"}, RouteGroups("POST", "/machines/:id/actions/reboot"))
+	assert.Equal(t, []string{"auth", "write"}, RouteGroups("DELETE", "/auth/service-accounts/:id"))
+	assert.Equal(t, []string{"machines"}, RouteGroups("POST", "/machines/{id}/config-preview"))
+	assert.Equal(t, []string{"cluster-templates"}, RouteGroups("POST", "/cluster-templates:diff"))
+	assert.Equal(t, []string{"cluster-templates", "write"}, RouteGroups("POST", "/cluster-templates:apply"))
+}
+
+func TestAccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		config   AccessConfig
+		method   string
+		path     string
+		expected int
+	}{
+		{"read allowed in read-only mode", AccessConfig{ReadOnly: true}, "GET", "/api/v1/clusters/c1", http.StatusOK},
+		{"write rejected in read-only mode", AccessConfig{ReadOnly: true}, "POST", "/api/v1/clusters/c1/actions/destroy", http.StatusMethodNotAllowed},
+		{"preview allowed in read-only mode", AccessConfig{ReadOnly: true}, "POST", "/api/v1/machines/m1/config-preview", http.StatusOK},
+		// Without Run, gin matches the escaped colon of custom method routes literally
+		{"template diff allowed in read-only mode", AccessConfig{ReadOnly: true}, "POST", "/api/v1/cluster-templates\\:diff", http.StatusOK},
+		{"template apply rejected in read-only mode", AccessConfig{ReadOnly: true}, "POST", "/api/v1/cluster-templates\\:apply", http.StatusMethodNotAllowed},
+		{"disabled group", AccessConfig{DisabledGroups: map[string]bool{"kubeconfig": true}}, "GET", "/api/v1/clusters/c1/kubeconfig", http.StatusNotFound},
+		{"other routes unaffected by disabled group", AccessConfig{DisabledGroups: map[string]bool{"kubeconfig": true}}, "GET", "/api/v1/clusters/c1", http.StatusOK},
+		{"group not enabled", AccessConfig{EnabledGroups: map[string]bool{"clusters": true}}, "POST", "/api/v1/clusters/c1/actions/destroy", http.StatusNotFound},
+		{"enabled group", AccessConfig{EnabledGroups: map[string]bool{"clusters": true}}, "DELETE", "/api/v1/clusters/c1", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			v1 := r.Group("/api/v1")
+			v1.Use(Access(tt.config, "/api/v1"))
+			ok := func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{}) }
+			v1.GET("/clusters/:id", ok)
+			v1.DELETE("/clusters/:id", ok)
+			v1.GET("/clusters/:id/kubeconfig", ok)
+			v1.POST("/clusters/:id/actions/destroy", ok)
+			v1.POST("/machines/:id/config-preview", ok)
+			v1.POST("/cluster-templates\\:diff", ok)
+			v1.POST("/cluster-templates\\:apply", ok)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tt.method, tt.path, nil)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+}
+
+func TestFilterSwagger(t *testing.T) {
+	doc := `{"paths": {
+		"/health": {"get": {}},
+		"/clusters/{id}": {"get": {}, "delete": {}},
+		"/clusters/{id}/actions/destroy": {"post": {}}
+	}}`
+
+	filtered := FilterSwagger(doc, AccessConfig{ReadOnly: true}, "/health")
+
+	var spec struct {
+		Paths map[string]map[string]interface{} `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(filtered.ReadDoc()), &spec))
+	assert.Contains(t, spec.Paths, "/health")
+	assert.Contains(t, spec.Paths["/clusters/{id}"], "get")
+	assert.NotContains(t, spec.Paths["/clusters/{id}"], "delete")
+	assert.NotContains(t, spec.Paths, "/clusters/{id}/actions/destroy")
+}
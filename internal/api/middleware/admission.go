@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/admission"
+	"github.com/jubblin/omni-api/internal/audit"
+)
+
+// maxAdmissionBodySize bounds the request body buffered for admission policies
+const maxAdmissionBodySize = 4 << 20
+
+// Admission evaluates the admission policies matching a write request before its handler runs.
+// Denied requests get 403 with the name and message of the policy.
+func Admission(controller *admission.Controller, basePath string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := routePath(c)
+		if controller == nil || route == "" || !isMutating(c.Request.Method) {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxAdmissionBodySize))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("request body exceeds %d bytes", maxAdmissionBodySize)})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		req := admission.Request{
+			Method: c.Request.Method,
+			Route:  strings.TrimPrefix(route, basePath),
+			Path:   strings.TrimPrefix(c.Request.URL.Path, basePath),
+			Params: map[string]string{},
+			Query:  map[string]string{},
+			User:   audit.ActorFromRequest(c.Request),
+			Body:   body,
+		}
+		for _, p := range c.Params {
+			req.Params[p.Key] = p.Value
+		}
+		for name := range c.Request.URL.Query() {
+			req.Query[name] = c.Query(name)
+		}
+
+		denial, err := controller.Admit(c.Request.Context(), req)
+		if err != nil {
+			log.Printf("Error evaluating admission policies: %v", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to evaluate admission policies"})
+			return
+		}
+		if denial != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": denial.Message, "policy": denial.Policy})
+			return
+		}
+
+		c.Next()
+	}
+}
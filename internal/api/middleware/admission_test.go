@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/admission"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	controller, err := admission.New([]admission.Policy{
+		{
+			Name:       "max-machines",
+			Message:    "machine sets are limited to 5 machines",
+			Routes:     []string{"/machinesets/{id}"},
+			Expression: "request.params.id == 'small' ? request.body.machine_count <= 5 : true",
+		},
+		{
+			Name:       "no-template-apply",
+			Message:    "apply templates through GitOps",
+			Routes:     []string{"/cluster-templates:apply"},
+			Expression: "request.user == 'gitops-bot'",
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	r := gin.New()
+	v1 := r.Group("/api/v1")
+	v1.Use(Admission(controller, "/api/v1"))
+	echo := func(c *gin.Context) {
+		// Handlers still see the body
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(body))
+	}
+	v1.GET("/machinesets/:id", echo)
+	v1.PUT("/machinesets/:id", echo)
+	v1.POST("/cluster-templates\\:apply", echo)
+	v1.POST("/cluster-templates\\:diff", echo)
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		user     string
+		expected int
+		policy   string
+	}{
+		{"admitted", "PUT", "/api/v1/machinesets/small", `{"machine_count":3}`, "", http.StatusOK, ""},
+		{"denied", "PUT", "/api/v1/machinesets/small", `{"machine_count":7}`, "", http.StatusForbidden, "max-machines"},
+		{"reads are not evaluated", "GET", "/api/v1/machinesets/small", "", "", http.StatusOK, ""},
+		// Without Run, gin matches the escaped colon of custom method routes literally
+		{"custom method denied", "POST", "/api/v1/cluster-templates\\:apply", "kind: Cluster", "alice", http.StatusForbidden, "no-template-apply"},
+		{"custom method admitted", "POST", "/api/v1/cluster-templates\\:apply", "kind: Cluster", "gitops-bot", http.StatusOK, ""},
+		{"other custom method", "POST", "/api/v1/cluster-templates\\:diff", "kind: Cluster", "alice", http.StatusOK, ""},
+		{"body too large", "PUT", "/api/v1/machinesets/small", strings.Repeat(" ", maxAdmissionBodySize+1), "", http.StatusRequestEntityTooLarge, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.user != "" {
+				req.Header.Set("X-Remote-User", tt.user)
+			}
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expected, w.Code, w.Body.String())
+			if tt.expected == http.StatusRequestEntityTooLarge {
+				assert.Contains(t, w.Body.String(), "request body exceeds")
+				return
+			}
+			if tt.policy == "" {
+				assert.Equal(t, tt.body, w.Body.String())
+				return
+			}
+			var resp map[string]string
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, tt.policy, resp["policy"])
+			assert.NotEmpty(t, resp["error"])
+		})
+	}
+}
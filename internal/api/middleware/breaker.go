@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/client"
+)
+
+// CircuitBreaker fails fast with 503 and a Retry-After header while the Omni circuit breaker is open,
+// instead of letting every request wait for Omni calls to time out
+func CircuitBreaker(b *client.Breaker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		snapshot := b.Snapshot()
+		if snapshot.State == client.BreakerOpen && snapshot.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(snapshot.RetryAfterSeconds()))
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Omni is unavailable, retry later"})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,265 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader is the request header that identifies retries of the same POST request
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength bounds the memory a single key can use
+const maxIdempotencyKeyLength = 255
+
+// maxIdempotentBodySize bounds the request body buffered to fingerprint a request
+const maxIdempotentBodySize = 4 << 20
+
+// replayedHeaders are copied from the original response when it is replayed
+var replayedHeaders = []string{"Content-Type", "Location"}
+
+// IdempotencyConfig configures the idempotency store
+type IdempotencyConfig struct {
+	TTL time.Duration // How long keys and their responses are kept
+}
+
+// IdempotencyConfigFromEnv builds an IdempotencyConfig from environment variables
+func IdempotencyConfigFromEnv() IdempotencyConfig {
+	cfg := IdempotencyConfig{TTL: 24 * time.Hour}
+
+	if value := os.Getenv("OMNI_API_IDEMPOTENCY_TTL"); value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err != nil || ttl <= 0 {
+			log.Printf("Warning: invalid duration %q for OMNI_API_IDEMPOTENCY_TTL, using %s", value, cfg.TTL)
+		} else {
+			cfg.TTL = ttl
+		}
+	}
+
+	return cfg
+}
+
+type idempotencyEntry struct {
+	fingerprint string
+	done        bool
+	status      int
+	header      http.Header
+	body        []byte
+	expires     time.Time
+}
+
+// IdempotencyStore keeps the responses of requests sent with an Idempotency-Key in memory
+type IdempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+// NewIdempotencyStore creates a new IdempotencyStore
+func NewIdempotencyStore(cfg IdempotencyConfig) *IdempotencyStore {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+
+	return &IdempotencyStore{
+		entries: make(map[string]*idempotencyEntry),
+		ttl:     cfg.TTL,
+		now:     time.Now,
+	}
+}
+
+// begin reserves key for a request with the given fingerprint.
+// It returns the stored entry if the key was used before, or nil if the request should run.
+func (s *IdempotencyStore) begin(key, fingerprint string) *idempotencyEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune()
+
+	if e, ok := s.entries[key]; ok {
+		copied := *e
+		return &copied
+	}
+
+	s.entries[key] = &idempotencyEntry{
+		fingerprint: fingerprint,
+		expires:     s.now().Add(s.ttl),
+	}
+	return nil
+}
+
+// complete stores the response for key
+func (s *IdempotencyStore) complete(key string, status int, header http.Header, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return
+	}
+	e.done = true
+	e.status = status
+	e.header = header
+	e.body = body
+	e.expires = s.now().Add(s.ttl)
+}
+
+// release forgets key so the request can be retried
+func (s *IdempotencyStore) release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+}
+
+// prune drops expired keys. Callers must hold s.mu.
+func (s *IdempotencyStore) prune() {
+	now := s.now()
+	for key, e := range s.entries {
+		if now.After(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// Idempotency makes POST requests that carry an Idempotency-Key header safe to retry.
+// Keys are scoped to the caller, method and path, so clients that pick the same key do not collide.
+// The first response for a key is stored and replayed for later requests with the same key and body;
+// reusing a key for a different request gets 422. Server errors are not stored, so they can be retried.
+func Idempotency(store *IdempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if c.Request.Method != http.MethodPost || key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must be at most 255 characters"})
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBodySize))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("request body exceeds %d bytes", maxIdempotentBodySize)})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		key = scopedKey(c, key)
+		fingerprint := requestFingerprint(c.Request, body)
+
+		if stored := store.begin(key, fingerprint); stored != nil {
+			switch {
+			case stored.fingerprint != fingerprint:
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used with a different request"})
+			case !stored.done:
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "a request with this Idempotency-Key is still in progress"})
+			default:
+				replay(c, stored)
+			}
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		// Release the key if a handler panics, so the request can be retried
+		completed := false
+		defer func() {
+			if !completed {
+				store.release(key)
+			}
+		}()
+
+		c.Next()
+
+		status := c.Writer.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+
+		header := make(http.Header)
+		for _, name := range replayedHeaders {
+			if value := c.Writer.Header().Get(name); value != "" {
+				header.Set(name, value)
+			}
+		}
+		store.complete(key, status, header, recorder.body.Bytes())
+		completed = true
+	}
+}
+
+// scopedKey qualifies an Idempotency-Key with the caller, method and path of the request.
+// The caller is the authenticated user, or the connecting address for anonymous requests.
+func scopedKey(c *gin.Context, key string) string {
+	caller, ok := AuthenticatedUser(c)
+	if !ok {
+		caller = "addr:" + c.RemoteIP()
+	} else {
+		caller = "user:" + caller
+	}
+	return strings.Join([]string{caller, c.Request.Method, c.Request.URL.Path, key}, "\x00")
+}
+
+func replay(c *gin.Context, stored *idempotencyEntry) {
+	for name, values := range stored.header {
+		for _, value := range values {
+			c.Writer.Header().Add(name, value)
+		}
+	}
+	c.Header("Idempotent-Replayed", "true")
+	c.Status(stored.status)
+	_, _ = c.Writer.Write(stored.body)
+	c.Abort()
+}
+
+// requestFingerprint identifies a request by method, URL and body.
+// JSON bodies are compacted first so formatting differences do not count as a different request.
+func requestFingerprint(r *http.Request, body []byte) string {
+	var compacted bytes.Buffer
+	if json.Compact(&compacted, body) == nil {
+		body = compacted.Bytes()
+	}
+
+	h := sha256.New()
+	h.Write([]byte(r.Method))
+	h.Write([]byte{0})
+	h.Write([]byte(r.URL.RequestURI()))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder copies the response body while it is written to the client
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newIdempotencyRouter(store *IdempotencyStore, calls *int, status int) *gin.Engine {
+	r := gin.New()
+	r.Use(Idempotency(store))
+	r.POST("/etcdbackups", func(c *gin.Context) {
+		*calls++
+		c.Header("Location", "/api/v1/operations/op-1")
+		c.JSON(status, gin.H{"call": *calls})
+	})
+	return r
+}
+
+func postWithKey(r *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/etcdbackups", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency_ReplaysDuplicates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var calls int
+	r := newIdempotencyRouter(NewIdempotencyStore(IdempotencyConfig{}), &calls, http.StatusAccepted)
+
+	first := postWithKey(r, "key-1", `{"cluster": "test-cluster"}`)
+	assert.Equal(t, http.StatusAccepted, first.Code)
+	assert.Empty(t, first.Header().Get("Idempotent-Replayed"))
+
+	// Formatting differences do not make it a different request
+	second := postWithKey(r, "key-1", `{"cluster":"test-cluster"}`)
+	assert.Equal(t, http.StatusAccepted, second.Code)
+	assert.Equal(t, "true", second.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, "/api/v1/operations/op-1", second.Header().Get("Location"))
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotency_RejectsDifferentBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var calls int
+	r := newIdempotencyRouter(NewIdempotencyStore(IdempotencyConfig{}), &calls, http.StatusAccepted)
+
+	postWithKey(r, "key-1", `{"cluster": "test-cluster"}`)
+	w := postWithKey(r, "key-1", `{"cluster": "other-cluster"}`)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotency_WithoutKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var calls int
+	r := newIdempotencyRouter(NewIdempotencyStore(IdempotencyConfig{}), &calls, http.StatusAccepted)
+
+	postWithKey(r, "", `{"cluster": "test-cluster"}`)
+	postWithKey(r, "", `{"cluster": "test-cluster"}`)
+
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotency_ServerErrorsAreRetried(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var calls int
+	r := newIdempotencyRouter(NewIdempotencyStore(IdempotencyConfig{}), &calls, http.StatusServiceUnavailable)
+
+	postWithKey(r, "key-1", `{"cluster": "test-cluster"}`)
+	w := postWithKey(r, "key-1", `{"cluster": "test-cluster"}`)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotency_KeysExpire(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	now := time.Now()
+	store := NewIdempotencyStore(IdempotencyConfig{TTL: time.Hour})
+	store.now = func() time.Time { return now }
+
+	var calls int
+	r := newIdempotencyRouter(store, &calls, http.StatusAccepted)
+
+	postWithKey(r, "key-1", `{"cluster": "test-cluster"}`)
+	now = now.Add(2 * time.Hour)
+	w := postWithKey(r, "key-1", `{"cluster": "other-cluster"}`)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotency_KeysAreScopedToCallerAndPath(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var calls int
+	r := newIdempotencyRouter(NewIdempotencyStore(IdempotencyConfig{}), &calls, http.StatusAccepted)
+	r.POST("/restores", func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusAccepted, gin.H{"call": calls})
+	})
+
+	post := func(path, remoteAddr string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, strings.NewReader(`{"cluster": "test-cluster"}`))
+		req.RemoteAddr = remoteAddr
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	post("/etcdbackups", "192.0.2.1:40000")
+	assert.Empty(t, post("/etcdbackups", "192.0.2.2:40000").Header().Get("Idempotent-Replayed"), "another caller")
+	assert.Empty(t, post("/restores", "192.0.2.1:40000").Header().Get("Idempotent-Replayed"), "another route")
+	assert.Equal(t, "true", post("/etcdbackups", "192.0.2.1:40000").Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, 3, calls)
+}
+
+func TestIdempotency_BodyTooLarge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var calls int
+	r := newIdempotencyRouter(NewIdempotencyStore(IdempotencyConfig{}), &calls, http.StatusAccepted)
+
+	w := postWithKey(r, "key-1", strings.Repeat("a", maxIdempotentBodySize+1))
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, 0, calls)
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"log"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jubblin/omni-api/internal/audit"
+)
+
+// authenticatedUserKey is the gin context key Identity stores the authenticated user under
+const authenticatedUserKey = "omni-api/authenticated-user"
+
+// IdentityConfig configures which peers may pass the authenticated user of a request
+type IdentityConfig struct {
+	TrustedProxies []netip.Prefix // Authenticating proxies allowed to set audit.ActorHeaders
+}
+
+// IdentityConfigFromEnv builds an IdentityConfig from environment variables
+func IdentityConfigFromEnv() IdentityConfig {
+	var cfg IdentityConfig
+
+	for _, value := range strings.Split(os.Getenv("OMNI_API_TRUSTED_PROXIES"), ",") {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		prefix, err := parsePrefix(value)
+		if err != nil {
+			log.Printf("Warning: ignoring invalid address %q in OMNI_API_TRUSTED_PROXIES: %v", value, err)
+			continue
+		}
+		cfg.TrustedProxies = append(cfg.TrustedProxies, prefix)
+	}
+
+	return cfg
+}
+
+// Identity authenticates the user of a request. Only a trusted authenticating proxy, identified by the
+// address the connection comes from, may pass the user in audit.ActorHeaders. The headers are removed from
+// requests of any other peer, so handlers, admission policies and the audit log never see a user the client chose.
+func Identity(cfg IdentityConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cfg.trusted(c.Request.RemoteAddr) {
+			if user := audit.ActorFromRequest(c.Request); user != audit.UnknownActor {
+				c.Set(authenticatedUserKey, user)
+			}
+		} else {
+			for _, header := range audit.ActorHeaders {
+				c.Request.Header.Del(header)
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// AuthenticatedUser returns the user a trusted authenticating proxy passed with the request
+func AuthenticatedUser(c *gin.Context) (string, bool) {
+	user := c.GetString(authenticatedUserKey)
+	return user, user != ""
+}
+
+// trusted reports whether a connection from remoteAddr (host:port) comes from a trusted proxy
+func (cfg IdentityConfig) trusted(remoteAddr string) bool {
+	addrPort, err := netip.ParseAddrPort(remoteAddr)
+	if err != nil {
+		return false
+	}
+
+	addr := addrPort.Addr().Unmap()
+	for _, prefix := range cfg.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePrefix parses a CIDR or a single address
+func parsePrefix(value string) (netip.Prefix, error) {
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		return prefix.Masked(), err
+	}
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := IdentityConfig{TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("::1/128")}}
+	r := gin.New()
+	r.Use(Identity(cfg))
+	r.GET("/whoami", func(c *gin.Context) {
+		user, ok := AuthenticatedUser(c)
+		c.JSON(http.StatusOK, gin.H{"user": user, "authenticated": ok, "header": c.GetHeader("X-Remote-User")})
+	})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		user       string
+		expected   string
+	}{
+		{"trusted proxy", "10.1.2.3:40000", "alice", `{"user":"alice","authenticated":true,"header":"alice"}`},
+		{"trusted IPv6 proxy", "[::1]:40000", "alice", `{"user":"alice","authenticated":true,"header":"alice"}`},
+		{"trusted proxy without user", "10.1.2.3:40000", "", `{"user":"","authenticated":false,"header":""}`},
+		{"untrusted peer", "192.0.2.1:40000", "alice", `{"user":"","authenticated":false,"header":""}`},
+		{"IPv4-mapped untrusted peer", "[::ffff:192.0.2.1]:40000", "alice", `{"user":"","authenticated":false,"header":""}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/whoami", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.user != "" {
+				req.Header.Set("X-Remote-User", tt.user)
+			}
+			r.ServeHTTP(w, req)
+
+			assert.JSONEq(t, tt.expected, w.Body.String())
+		})
+	}
+}
+
+func TestIdentityConfigFromEnv(t *testing.T) {
+	t.Setenv("OMNI_API_TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.10,not-an-ip, fd00::1")
+
+	cfg := IdentityConfigFromEnv()
+	require.Len(t, cfg.TrustedProxies, 3)
+	assert.True(t, cfg.trusted("192.168.1.10:1234"))
+	assert.False(t, cfg.trusted("192.168.1.11:1234"))
+	assert.True(t, cfg.trusted("[fd00::1]:1234"))
+	assert.False(t, cfg.trusted("not an address"))
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"encoding/json"
+	"log"
+	"slices"
+	"strings"
+)
+
+// SwaggerDoc serves a fixed, pre-rendered Swagger document.
+// It implements swag.Swagger so it can be registered as a swag instance.
+type SwaggerDoc struct {
+	doc string
+}
+
+// ReadDoc returns the Swagger document
+func (d *SwaggerDoc) ReadDoc() string {
+	return d.doc
+}
+
+// FilterSwagger removes the operations that the AccessConfig does not serve from a Swagger document,
+// so the served spec matches the routes that are actually available.
+// Paths listed in unmanaged are served outside the API group and are always kept.
+func FilterSwagger(doc string, cfg AccessConfig, unmanaged ...string) *SwaggerDoc {
+	var spec map[string]interface{}
+	if err := json.Unmarshal([]byte(doc), &spec); err != nil {
+		log.Printf("Warning: failed to parse Swagger document, serving it unfiltered: %v", err)
+		return &SwaggerDoc{doc: doc}
+	}
+
+	paths, _ := spec["paths"].(map[string]interface{})
+	for path, item := range paths {
+		operations, ok := item.(map[string]interface{})
+		if !ok || slices.Contains(unmanaged, path) {
+			continue
+		}
+
+		for method := range operations {
+			if allowed, _ := cfg.Allowed(strings.ToUpper(method), path); !allowed {
+				delete(operations, method)
+			}
+		}
+
+		if len(operations) == 0 {
+			delete(paths, path)
+		}
+	}
+
+	filtered, err := json.MarshalIndent(spec, "", "    ")
+	if err != nil {
+		log.Printf("Warning: failed to render filtered Swagger document, serving it unfiltered: %v", err)
+		return &SwaggerDoc{doc: doc}
+	}
+
+	return &SwaggerDoc{doc: string(filtered)}
+}
@@ -0,0 +1,139 @@
+// Package audit records security-relevant API actions, such as credential downloads,
+// as JSON lines and keeps the most recent entries in memory.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ActorHeaders are the request headers an authenticating proxy uses to pass the user, in order of preference
+var ActorHeaders = []string{"X-Remote-User", "X-Forwarded-User", "X-Auth-Request-User"}
+
+// UnknownActor is recorded when no actor header is present
+const UnknownActor = "anonymous"
+
+// Entry is a single audit log entry
+type Entry struct {
+	Time       time.Time         `json:"time"`
+	Actor      string            `json:"actor"`
+	Action     string            `json:"action"`
+	Target     string            `json:"target,omitempty"`
+	RemoteAddr string            `json:"remote_addr,omitempty"`
+	Details    map[string]string `json:"details,omitempty"`
+}
+
+// Config configures the audit log
+type Config struct {
+	File       string // Path entries are appended to; empty logs them through the standard logger
+	MaxEntries int    // Number of recent entries kept in memory
+}
+
+// ConfigFromEnv builds a Config from environment variables
+func ConfigFromEnv() Config {
+	cfg := Config{
+		File:       os.Getenv("OMNI_API_AUDIT_LOG_FILE"),
+		MaxEntries: 10000,
+	}
+
+	if value := os.Getenv("OMNI_API_AUDIT_LOG_MAX_ENTRIES"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			log.Printf("Warning: invalid value %q for OMNI_API_AUDIT_LOG_MAX_ENTRIES, using %d", value, cfg.MaxEntries)
+		} else {
+			cfg.MaxEntries = n
+		}
+	}
+
+	return cfg
+}
+
+// Log records audit entries
+type Log struct {
+	mu      sync.Mutex
+	out     io.Writer // Nil logs entries through the standard logger
+	entries []Entry
+	max     int
+	now     func() time.Time
+}
+
+// New creates a Log, opening cfg.File for appending if it is set
+func New(cfg Config) (*Log, error) {
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 10000
+	}
+
+	l := &Log{max: cfg.MaxEntries, now: time.Now}
+	if cfg.File != "" {
+		f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+		l.out = f
+	}
+	return l, nil
+}
+
+// Record adds an entry, setting its time if it is zero
+func (l *Log) Record(e Entry) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e.Time.IsZero() {
+		e.Time = l.now().UTC()
+	}
+	if e.Actor == "" {
+		e.Actor = UnknownActor
+	}
+
+	l.entries = append(l.entries, e)
+	if len(l.entries) > l.max {
+		l.entries = l.entries[len(l.entries)-l.max:]
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("Failed to marshal audit entry: %v", err)
+		return
+	}
+	if l.out == nil {
+		log.Printf("audit: %s", line)
+		return
+	}
+	if _, err := l.out.Write(append(line, '\n')); err != nil {
+		log.Printf("Failed to write audit entry: %v", err)
+	}
+}
+
+// Entries returns the entries kept in memory, oldest first
+func (l *Log) Entries() []Entry {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]Entry(nil), l.entries...)
+}
+
+// ActorFromRequest returns the user an authenticating proxy passed with the request
+func ActorFromRequest(r *http.Request) string {
+	for _, header := range ActorHeaders {
+		if actor := r.Header.Get(header); actor != "" {
+			return actor
+		}
+	}
+	return UnknownActor
+}
@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLog_WritesJSONLines(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "audit.log")
+	l, err := New(Config{File: file})
+	require.NoError(t, err)
+
+	l.Record(Entry{Actor: "alice", Action: "kubeconfig.generate", Target: "prod"})
+	l.Record(Entry{Action: "kubeconfig.generate", Target: "staging"})
+
+	f, err := os.Open(file)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Entry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+		entries = append(entries, e)
+	}
+
+	require.Len(t, entries, 2)
+	assert.Equal(t, "alice", entries[0].Actor)
+	assert.False(t, entries[0].Time.IsZero())
+	assert.Equal(t, UnknownActor, entries[1].Actor)
+}
+
+func TestLog_KeepsRecentEntries(t *testing.T) {
+	l, err := New(Config{File: filepath.Join(t.TempDir(), "audit.log"), MaxEntries: 2})
+	require.NoError(t, err)
+
+	l.Record(Entry{Action: "first"})
+	l.Record(Entry{Action: "second"})
+	l.Record(Entry{Action: "third"})
+
+	entries := l.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "second", entries[0].Action)
+	assert.Equal(t, "third", entries[1].Action)
+}
+
+func TestActorFromRequest(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/", nil)
+	assert.Equal(t, UnknownActor, ActorFromRequest(req))
+
+	req.Header.Set("X-Forwarded-User", "bob")
+	assert.Equal(t, "bob", ActorFromRequest(req))
+
+	req.Header.Set("X-Remote-User", "alice")
+	assert.Equal(t, "alice", ActorFromRequest(req))
+}
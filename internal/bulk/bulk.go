@@ -0,0 +1,213 @@
+// Package bulk runs the same kind of action against many targets with bounded
+// concurrency and reports the outcome of every item.
+package bulk
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Mode decides what happens to the remaining items once an item failed
+type Mode string
+
+const (
+	// ModeContinue runs every item regardless of failures
+	ModeContinue Mode = "continue"
+	// ModeFailFast skips the items that did not start yet after the first failure
+	ModeFailFast Mode = "fail_fast"
+)
+
+// Concurrency limits
+const (
+	DefaultConcurrency = 4
+	MaxConcurrency     = 32
+)
+
+// ItemStatus is the status of a single item
+type ItemStatus string
+
+// Item statuses; skipped items were not run because of an earlier failure or cancellation
+const (
+	ItemPending   ItemStatus = "pending"
+	ItemRunning   ItemStatus = "running"
+	ItemSucceeded ItemStatus = "succeeded"
+	ItemFailed    ItemStatus = "failed"
+	ItemSkipped   ItemStatus = "skipped"
+)
+
+// Item is a single action against a single target
+type Item struct {
+	Action string
+	Target string
+	Run    func(ctx context.Context) (string, error) // Returns a short description of the result, e.g. "update"
+}
+
+// ItemResult is the outcome of an item
+type ItemResult struct {
+	Index  int        `json:"index"`
+	Action string     `json:"action"`
+	Target string     `json:"target"`
+	Status ItemStatus `json:"status"`
+	Result string     `json:"result,omitempty"`
+	Error  string     `json:"error,omitempty"`
+}
+
+// Report is a point-in-time view of a bulk run
+type Report struct {
+	Mode        Mode         `json:"mode"`
+	Concurrency int          `json:"concurrency"`
+	Total       int          `json:"total"`
+	Succeeded   int          `json:"succeeded"`
+	Failed      int          `json:"failed"`
+	Skipped     int          `json:"skipped"`
+	Items       []ItemResult `json:"items"`
+}
+
+// Done returns the number of items that reached a final status
+func (r Report) Done() int {
+	return r.Succeeded + r.Failed + r.Skipped
+}
+
+// Options configures a run
+type Options struct {
+	Mode        Mode // ModeContinue if empty
+	Concurrency int  // DefaultConcurrency if zero, capped at MaxConcurrency
+}
+
+// ParseMode validates a mode, defaulting to ModeContinue
+func ParseMode(value string) (Mode, error) {
+	switch Mode(value) {
+	case "", ModeContinue:
+		return ModeContinue, nil
+	case ModeFailFast:
+		return ModeFailFast, nil
+	default:
+		return "", fmt.Errorf("unknown mode %q, expected %s or %s", value, ModeContinue, ModeFailFast)
+	}
+}
+
+// Run runs the items, calling report with a copy of the report whenever an item changes status.
+// Items left over when ctx is done are skipped. Run returns an error if any item failed or was skipped.
+func Run(ctx context.Context, items []Item, opts Options, report func(Report)) (Report, error) {
+	if opts.Mode == "" {
+		opts.Mode = ModeContinue
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+	opts.Concurrency = min(opts.Concurrency, MaxConcurrency)
+
+	r := &run{
+		report: Report{
+			Mode:        opts.Mode,
+			Concurrency: opts.Concurrency,
+			Total:       len(items),
+			Items:       make([]ItemResult, len(items)),
+		},
+		notify: report,
+	}
+	for i, item := range items {
+		r.report.Items[i] = ItemResult{Index: i, Action: item.Action, Target: item.Target, Status: ItemPending}
+	}
+
+	// Items already running when stop is canceled are allowed to finish
+	stop, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(opts.Concurrency, len(items)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				r.run(ctx, stop, i, items[i], func() {
+					if opts.Mode == ModeFailFast {
+						cancel()
+					}
+				})
+			}
+		}()
+	}
+
+	for i := range items {
+		if stop.Err() != nil {
+			r.set(i, ItemSkipped, "", "")
+			continue
+		}
+		select {
+		case next <- i:
+		case <-stop.Done():
+			r.set(i, ItemSkipped, "", "")
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	final := r.snapshot()
+	switch {
+	case final.Failed > 0:
+		return final, fmt.Errorf("%d of %d items failed", final.Failed, final.Total)
+	case final.Skipped > 0:
+		return final, fmt.Errorf("%d of %d items were skipped", final.Skipped, final.Total)
+	}
+	return final, nil
+}
+
+type run struct {
+	mu     sync.Mutex
+	report Report
+	notify func(Report)
+}
+
+func (r *run) run(ctx, stop context.Context, i int, item Item, failed func()) {
+	if stop.Err() != nil {
+		r.set(i, ItemSkipped, "", "")
+		return
+	}
+
+	r.set(i, ItemRunning, "", "")
+	result, err := item.Run(ctx)
+	if err != nil {
+		r.set(i, ItemFailed, "", err.Error())
+		failed()
+		return
+	}
+	r.set(i, ItemSucceeded, result, "")
+}
+
+func (r *run) set(i int, status ItemStatus, result, errMsg string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	item := &r.report.Items[i]
+	item.Status = status
+	item.Result = result
+	item.Error = errMsg
+	switch status {
+	case ItemSucceeded:
+		r.report.Succeeded++
+	case ItemFailed:
+		r.report.Failed++
+	case ItemSkipped:
+		r.report.Skipped++
+	}
+
+	// Notify while holding the lock so reports arrive in order
+	if r.notify != nil {
+		r.notify(r.copyLocked())
+	}
+}
+
+func (r *run) snapshot() Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.copyLocked()
+}
+
+func (r *run) copyLocked() Report {
+	report := r.report
+	report.Items = append([]ItemResult(nil), r.report.Items...)
+	return report
+}
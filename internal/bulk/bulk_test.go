@@ -0,0 +1,102 @@
+package bulk
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func item(target string, err error) Item {
+	return Item{
+		Action: "test",
+		Target: target,
+		Run: func(ctx context.Context) (string, error) {
+			if err != nil {
+				return "", err
+			}
+			return "done", nil
+		},
+	}
+}
+
+func statuses(report Report) []ItemStatus {
+	result := make([]ItemStatus, 0, len(report.Items))
+	for _, item := range report.Items {
+		result = append(result, item.Status)
+	}
+	return result
+}
+
+func TestRun_Continue(t *testing.T) {
+	var reports []Report
+	report, err := Run(context.Background(), []Item{item("a", nil), item("b", errors.New("boom")), item("c", nil)}, Options{}, func(r Report) {
+		reports = append(reports, r)
+	})
+	require.EqualError(t, err, "1 of 3 items failed")
+
+	assert.Equal(t, ModeContinue, report.Mode)
+	assert.Equal(t, DefaultConcurrency, report.Concurrency)
+	assert.Equal(t, []ItemStatus{ItemSucceeded, ItemFailed, ItemSucceeded}, statuses(report))
+	assert.Equal(t, 2, report.Succeeded)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, 3, report.Done())
+	assert.Equal(t, "done", report.Items[0].Result)
+	assert.Equal(t, "boom", report.Items[1].Error)
+	assert.Equal(t, report, reports[len(reports)-1])
+}
+
+func TestRun_FailFast(t *testing.T) {
+	report, err := Run(context.Background(), []Item{item("a", errors.New("boom")), item("b", nil), item("c", nil)}, Options{Mode: ModeFailFast, Concurrency: 1}, nil)
+	require.EqualError(t, err, "1 of 3 items failed")
+	assert.Equal(t, []ItemStatus{ItemFailed, ItemSkipped, ItemSkipped}, statuses(report))
+	assert.Equal(t, 2, report.Skipped)
+}
+
+func TestRun_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report, err := Run(ctx, []Item{item("a", nil)}, Options{}, nil)
+	require.EqualError(t, err, "1 of 1 items were skipped")
+	assert.Equal(t, []ItemStatus{ItemSkipped}, statuses(report))
+}
+
+func TestRun_BoundsConcurrency(t *testing.T) {
+	var running, peak atomic.Int32
+	items := make([]Item, 20)
+	for i := range items {
+		items[i] = Item{Run: func(ctx context.Context) (string, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			return "", nil
+		}}
+	}
+
+	report, err := Run(context.Background(), items, Options{Concurrency: 3}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 20, report.Succeeded)
+	assert.LessOrEqual(t, peak.Load(), int32(3))
+}
+
+func TestParseMode(t *testing.T) {
+	mode, err := ParseMode("")
+	require.NoError(t, err)
+	assert.Equal(t, ModeContinue, mode)
+
+	mode, err = ParseMode("fail_fast")
+	require.NoError(t, err)
+	assert.Equal(t, ModeFailFast, mode)
+
+	_, err = ParseMode("stop")
+	assert.Error(t, err)
+}
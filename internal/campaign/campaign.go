@@ -0,0 +1,768 @@
+// Package campaign upgrades fleets of clusters to target Talos and Kubernetes versions in waves:
+// a canary cluster first, then a share of the fleet, then the rest. Every cluster follows its upgrade
+// plan one step at a time and has to be healthy before each step and after the last one.
+// Campaigns are kept in a file, so running campaigns continue after a restart; without one campaigns cannot be created.
+package campaign
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/safe"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/jubblin/omni-api/internal/env"
+	"github.com/jubblin/omni-api/internal/operations"
+	"github.com/jubblin/omni-api/internal/upgradeplan"
+	"github.com/siderolabs/omni/client/api/omni/specs"
+	"github.com/siderolabs/omni/client/pkg/cosi/labels"
+	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+// Status is the status of a campaign
+type Status string
+
+const (
+	// StatusRunning means clusters are being upgraded
+	StatusRunning Status = "running"
+	// StatusPaused means no further steps start until the campaign is resumed; a failed cluster pauses its campaign
+	StatusPaused Status = "paused"
+	// StatusSucceeded means every cluster runs the target versions
+	StatusSucceeded Status = "succeeded"
+	// StatusAborted means the campaign was stopped for good
+	StatusAborted Status = "aborted"
+)
+
+// ClusterStatus is the status of a cluster within a campaign
+type ClusterStatus string
+
+const (
+	// ClusterPending means the cluster waits for its wave, or for the campaign to be resumed
+	ClusterPending ClusterStatus = "pending"
+	// ClusterUpgrading means the cluster is being checked or upgraded
+	ClusterUpgrading ClusterStatus = "upgrading"
+	// ClusterSucceeded means the cluster runs the target versions and is healthy
+	ClusterSucceeded ClusterStatus = "succeeded"
+	// ClusterFailed means an upgrade step or health gate of the cluster failed
+	ClusterFailed ClusterStatus = "failed"
+)
+
+var (
+	// ErrNotFound is returned for unknown campaign IDs
+	ErrNotFound = errors.New("campaign not found")
+	// ErrInvalid is returned for campaign specs that cannot run
+	ErrInvalid = errors.New("invalid campaign")
+	// ErrConflict is returned when a campaign cannot change to the requested status
+	ErrConflict = errors.New("campaign status conflict")
+	// ErrDisabled is returned when a campaign is created without a file to store it in
+	ErrDisabled = errors.New("upgrade campaigns are not enabled, set OMNI_API_CAMPAIGN_FILE")
+)
+
+// Spec defines what a campaign upgrades
+type Spec struct {
+	TalosVersion      string `json:"talos_version,omitempty"`      // Target Talos version; unset keeps the clusters' versions
+	KubernetesVersion string `json:"kubernetes_version,omitempty"` // Target Kubernetes version; unset keeps the clusters' versions
+	Selector          string `json:"selector,omitempty"`           // Omni label selector over cluster labels, e.g. env=prod; empty selects every cluster
+	Canary            string `json:"canary,omitempty"`             // Cluster upgraded first, alone; defaults to the first selected cluster by ID
+	Waves             []int  `json:"waves,omitempty"`              // Share of the selected clusters upgraded in each wave after the canary, in percent; the rest follow in a last wave
+}
+
+// Cluster is the progress of a single cluster
+type Cluster struct {
+	ID          string             `json:"id"`
+	Wave        int                `json:"wave"`
+	Status      ClusterStatus      `json:"status"`
+	Step        *upgradeplan.Step  `json:"step,omitempty"` // Step being run; kept when the campaign is paused or the step failed, and run again on resume
+	Completed   []upgradeplan.Step `json:"completed,omitempty"`
+	Message     string             `json:"message,omitempty"` // Progress of the current step or health gate
+	Error       string             `json:"error,omitempty"`
+	StartedAt   time.Time          `json:"started_at,omitzero"`
+	CompletedAt time.Time          `json:"completed_at,omitzero"`
+}
+
+// Campaign is a point-in-time view of a campaign
+type Campaign struct {
+	ID          string    `json:"id"`
+	Spec        Spec      `json:"spec"`
+	Status      Status    `json:"status"`
+	Error       string    `json:"error,omitempty"` // Why the campaign paused
+	Wave        int       `json:"wave"`            // Current wave, starting with the canary at 0
+	Waves       int       `json:"waves"`
+	Clusters    []Cluster `json:"clusters"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	CompletedAt time.Time `json:"completed_at,omitzero"`
+}
+
+// Done reports whether the campaign reached a final status
+func (c Campaign) Done() bool {
+	return c.Status == StatusSucceeded || c.Status == StatusAborted
+}
+
+// Config configures a Manager
+type Config struct {
+	File          string        // Campaigns are stored in and loaded from this file; empty disables creating campaigns
+	HealthTimeout time.Duration // Time a cluster may take to become healthy before or after a step
+	StepTimeout   time.Duration // Time a single upgrade step may take
+	PollInterval  time.Duration // Time between health checks
+}
+
+// ConfigFromEnv builds a Config from environment variables
+func ConfigFromEnv() Config {
+	return Config{
+		File:          os.Getenv("OMNI_API_CAMPAIGN_FILE"),
+		HealthTimeout: env.Duration("OMNI_API_CAMPAIGN_HEALTH_TIMEOUT", 15*time.Minute),
+		StepTimeout:   env.Duration("OMNI_API_CAMPAIGN_STEP_TIMEOUT", time.Hour),
+	}
+}
+
+type entry struct {
+	campaign Campaign
+	cancel   context.CancelFunc // Stops the runner; nil while none runs
+	done     chan struct{}      // Closed once the runner stopped
+}
+
+// Manager runs upgrade campaigns and stores them in a file
+type Manager struct {
+	mu         sync.Mutex
+	campaigns  map[string]*entry
+	ctx        context.Context // Parent of the runners, set by Start
+	state      state.State
+	management client.ManagementService
+	cfg        Config
+	now        func() time.Time
+}
+
+// NewManager creates a Manager, loading the campaigns stored in cfg.File
+func NewManager(cfg Config, st state.State, mgmt client.ManagementService) (*Manager, error) {
+	if cfg.HealthTimeout <= 0 {
+		cfg.HealthTimeout = 15 * time.Minute
+	}
+	if cfg.StepTimeout <= 0 {
+		cfg.StepTimeout = time.Hour
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 10 * time.Second
+	}
+
+	m := &Manager{
+		campaigns:  map[string]*entry{},
+		ctx:        context.Background(),
+		state:      st,
+		management: mgmt,
+		cfg:        cfg,
+		now:        time.Now,
+	}
+	if !m.Enabled() {
+		return m, nil
+	}
+
+	data, err := os.ReadFile(cfg.File)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read campaigns: %w", err)
+	default:
+		var campaigns []Campaign
+		if err := json.Unmarshal(data, &campaigns); err != nil {
+			return nil, fmt.Errorf("failed to load campaigns from %s: %w", cfg.File, err)
+		}
+		for _, c := range campaigns {
+			m.campaigns[c.ID] = &entry{campaign: c}
+		}
+	}
+
+	return m, nil
+}
+
+// Enabled reports whether a file to store campaigns in is configured
+func (m *Manager) Enabled() bool {
+	return m.cfg.File != ""
+}
+
+// Start continues the campaigns that were running when they were stored; runners stop when ctx is done
+func (m *Manager) Start(ctx context.Context) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ctx = ctx
+	for _, e := range m.campaigns {
+		if e.campaign.Status == StatusRunning {
+			log.Printf("Continuing upgrade campaign %s", e.campaign.ID)
+			m.startRunner(e)
+		}
+	}
+}
+
+// Plan selects the clusters of a campaign and assigns them to waves without starting it
+func (m *Manager) Plan(ctx context.Context, spec Spec) (Campaign, error) {
+	spec.TalosVersion = strings.TrimPrefix(spec.TalosVersion, "v")
+	spec.KubernetesVersion = strings.TrimPrefix(spec.KubernetesVersion, "v")
+	if spec.TalosVersion == "" && spec.KubernetesVersion == "" {
+		return Campaign{}, fmt.Errorf("%w: talos_version or kubernetes_version is required", ErrInvalid)
+	}
+	for _, version := range []string{spec.TalosVersion, spec.KubernetesVersion} {
+		if version != "" && !upgradeplan.Valid(version) {
+			return Campaign{}, fmt.Errorf("%w: invalid version %q", ErrInvalid, version)
+		}
+	}
+	if len(spec.Waves) == 0 {
+		spec.Waves = []int{10}
+	}
+	for _, percent := range spec.Waves {
+		if percent < 1 || percent > 100 {
+			return Campaign{}, fmt.Errorf("%w: wave sizes must be between 1 and 100 percent", ErrInvalid)
+		}
+	}
+	query, err := labels.ParseSelectors([]string{spec.Selector})
+	if err != nil {
+		return Campaign{}, fmt.Errorf("%w: invalid selector: %w", ErrInvalid, err)
+	}
+
+	clusters, err := safe.StateListAll[*omni.Cluster](ctx, m.state)
+	if err != nil {
+		return Campaign{}, err
+	}
+	catalog, err := upgradeplan.LoadCatalog(ctx, m.state)
+	if err != nil {
+		return Campaign{}, err
+	}
+	var ids, unplannable []string
+	for cluster := range clusters.All() {
+		if !query.Matches(*cluster.Metadata().Labels()) {
+			continue
+		}
+		ids = append(ids, cluster.Metadata().ID())
+
+		// Clusters without an upgrade path would only fail once their wave starts
+		current := cluster.TypedSpec().Value
+		talos, kubernetes := targetVersions(current, spec)
+		if _, err := catalog.Plan(current.TalosVersion, current.KubernetesVersion, talos, kubernetes); err != nil {
+			unplannable = append(unplannable, fmt.Sprintf("%s: %v", cluster.Metadata().ID(), err))
+		}
+	}
+	if len(ids) == 0 {
+		return Campaign{}, fmt.Errorf("%w: no cluster matches the selector", ErrInvalid)
+	}
+	if len(unplannable) > 0 {
+		slices.Sort(unplannable)
+		return Campaign{}, fmt.Errorf("%w: no upgrade path for %s", ErrInvalid, strings.Join(unplannable, "; "))
+	}
+	slices.Sort(ids)
+	total := len(ids)
+
+	if spec.Canary == "" {
+		spec.Canary = ids[0]
+	}
+	if !slices.Contains(ids, spec.Canary) {
+		return Campaign{}, fmt.Errorf("%w: canary cluster %s does not match the selector", ErrInvalid, spec.Canary)
+	}
+
+	campaign := Campaign{Spec: spec, Clusters: []Cluster{{ID: spec.Canary, Status: ClusterPending}}}
+	rest := slices.DeleteFunc(ids, func(id string) bool { return id == spec.Canary })
+	for _, percent := range spec.Waves {
+		if len(rest) == 0 {
+			break
+		}
+		n := min(len(rest), max(1, int(math.Ceil(float64(percent)*float64(total)/100))))
+		campaign.Waves++
+		for _, id := range rest[:n] {
+			campaign.Clusters = append(campaign.Clusters, Cluster{ID: id, Wave: campaign.Waves, Status: ClusterPending})
+		}
+		rest = rest[n:]
+	}
+	if len(rest) > 0 {
+		campaign.Waves++
+		for _, id := range rest {
+			campaign.Clusters = append(campaign.Clusters, Cluster{ID: id, Wave: campaign.Waves, Status: ClusterPending})
+		}
+	}
+	campaign.Waves++ // The canary wave
+
+	return campaign, nil
+}
+
+// Create plans a campaign, stores it and starts running it
+func (m *Manager) Create(ctx context.Context, spec Spec) (Campaign, error) {
+	if !m.Enabled() {
+		return Campaign{}, ErrDisabled
+	}
+	campaign, err := m.Plan(ctx, spec)
+	if err != nil {
+		return Campaign{}, err
+	}
+
+	now := m.now().UTC()
+	campaign.ID = newID()
+	campaign.Status = StatusRunning
+	campaign.CreatedAt = now
+	campaign.UpdatedAt = now
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := &entry{campaign: campaign}
+	m.campaigns[campaign.ID] = e
+	if err := m.persist(); err != nil {
+		delete(m.campaigns, campaign.ID)
+		return Campaign{}, err
+	}
+	m.startRunner(e)
+
+	return e.snapshot(), nil
+}
+
+// List returns all campaigns, newest first
+func (m *Manager) List() []Campaign {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	campaigns := make([]Campaign, 0, len(m.campaigns))
+	for _, e := range m.campaigns {
+		campaigns = append(campaigns, e.snapshot())
+	}
+	slices.SortFunc(campaigns, func(a, b Campaign) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return campaigns
+}
+
+// Get returns a campaign by ID
+func (m *Manager) Get(id string) (Campaign, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.campaigns[id]
+	if !ok {
+		return Campaign{}, false
+	}
+	return e.snapshot(), true
+}
+
+// Pause stops a running campaign from starting further steps. Steps already running in Omni complete
+// on their own, and are followed again when the campaign is resumed.
+func (m *Manager) Pause(id string) (Campaign, error) {
+	return m.stop(id, StatusPaused, StatusRunning)
+}
+
+// Abort stops a running or paused campaign for good. Steps already running in Omni complete on their own.
+func (m *Manager) Abort(id string) (Campaign, error) {
+	return m.stop(id, StatusAborted, StatusRunning, StatusPaused)
+}
+
+// Resume continues a paused campaign, retrying its failed clusters
+func (m *Manager) Resume(id string) (Campaign, error) {
+	m.mu.Lock()
+	e, ok := m.campaigns[id]
+	if !ok {
+		m.mu.Unlock()
+		return Campaign{}, ErrNotFound
+	}
+	if e.campaign.Status != StatusPaused {
+		status := e.campaign.Status
+		m.mu.Unlock()
+		return Campaign{}, fmt.Errorf("%w: only paused campaigns can be resumed, campaign is %s", ErrConflict, status)
+	}
+	done := e.done
+	m.mu.Unlock()
+
+	// The runner of a paused campaign may still be winding down
+	if done != nil {
+		<-done
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e.campaign.Status != StatusPaused {
+		return Campaign{}, fmt.Errorf("%w: only paused campaigns can be resumed, campaign is %s", ErrConflict, e.campaign.Status)
+	}
+	e.campaign.Status = StatusRunning
+	e.campaign.Error = ""
+	for i := range e.campaign.Clusters {
+		if cluster := &e.campaign.Clusters[i]; cluster.Status == ClusterFailed {
+			cluster.Status = ClusterPending
+			cluster.Error = ""
+		}
+	}
+	m.changed(e)
+	m.startRunner(e)
+
+	return e.snapshot(), nil
+}
+
+// stop moves a campaign in one of the from statuses to status and stops its runner
+func (m *Manager) stop(id string, status Status, from ...Status) (Campaign, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.campaigns[id]
+	if !ok {
+		return Campaign{}, ErrNotFound
+	}
+	if !slices.Contains(from, e.campaign.Status) {
+		return Campaign{}, fmt.Errorf("%w: campaign is %s", ErrConflict, e.campaign.Status)
+	}
+
+	e.campaign.Status = status
+	if status == StatusAborted {
+		e.campaign.CompletedAt = m.now().UTC()
+	}
+	if e.cancel != nil {
+		e.cancel()
+	}
+	m.changed(e)
+
+	return e.snapshot(), nil
+}
+
+// startRunner runs a campaign in the background. Callers must hold m.mu.
+func (m *Manager) startRunner(e *entry) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	e.cancel = cancel
+	e.done = make(chan struct{})
+
+	go m.run(ctx, e, e.done)
+}
+
+// run upgrades the clusters of a campaign wave by wave until all succeeded, a cluster failed or ctx is done
+func (m *Manager) run(ctx context.Context, e *entry, done chan struct{}) {
+	defer close(done)
+
+	m.mu.Lock()
+	waves, wave := e.campaign.Waves, e.campaign.Wave
+	m.mu.Unlock()
+
+	for ; wave < waves; wave++ {
+		m.update(e, func(c *Campaign) { c.Wave = wave })
+
+		var wg sync.WaitGroup
+		for _, id := range m.clusters(e, wave) {
+			wg.Go(func() { m.upgradeCluster(ctx, e, id) })
+		}
+		wg.Wait()
+
+		if ctx.Err() != nil {
+			return // Paused, aborted or shutting down; the status was set by whoever stopped the campaign
+		}
+
+		var failed []string
+		m.update(e, func(c *Campaign) {
+			for _, cluster := range c.Clusters {
+				if cluster.Wave == wave && cluster.Status == ClusterFailed {
+					failed = append(failed, cluster.ID)
+				}
+			}
+			if len(failed) > 0 && c.Status == StatusRunning {
+				c.Status = StatusPaused
+				c.Error = fmt.Sprintf("wave %d: upgrading %s failed", wave, strings.Join(failed, ", "))
+			}
+		})
+		if len(failed) > 0 {
+			log.Printf("Upgrade campaign %s paused: upgrading %s failed", e.campaign.ID, strings.Join(failed, ", "))
+			return
+		}
+	}
+
+	m.update(e, func(c *Campaign) {
+		if c.Status == StatusRunning {
+			c.Status = StatusSucceeded
+			c.CompletedAt = m.now().UTC()
+		}
+	})
+}
+
+// clusters returns the clusters of a wave that did not succeed yet
+func (m *Manager) clusters(e *entry, wave int) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ids []string
+	for _, cluster := range e.campaign.Clusters {
+		if cluster.Wave == wave && cluster.Status != ClusterSucceeded {
+			ids = append(ids, cluster.ID)
+		}
+	}
+	return ids
+}
+
+// upgradeCluster runs the upgrade steps of a cluster, recording its progress
+func (m *Manager) upgradeCluster(ctx context.Context, e *entry, id string) {
+	m.updateCluster(e, id, func(c *Cluster) {
+		c.Status = ClusterUpgrading
+		c.Error = ""
+		if c.StartedAt.IsZero() {
+			c.StartedAt = m.now().UTC()
+		}
+	})
+
+	err := m.upgradeSteps(ctx, e, id)
+
+	m.updateCluster(e, id, func(c *Cluster) {
+		c.Message = ""
+		switch {
+		case ctx.Err() != nil:
+			c.Status = ClusterPending
+		case err != nil:
+			c.Status = ClusterFailed
+			c.Error = err.Error()
+		default:
+			c.Status = ClusterSucceeded
+			c.CompletedAt = m.now().UTC()
+		}
+	})
+	if err != nil && ctx.Err() == nil {
+		log.Printf("Upgrade campaign %s: upgrading cluster %s failed: %v", e.campaign.ID, id, err)
+	}
+}
+
+// upgradeSteps waits for the cluster to be healthy and runs the next step of its upgrade plan until it runs the target versions
+func (m *Manager) upgradeSteps(ctx context.Context, e *entry, id string) error {
+	m.mu.Lock()
+	spec := e.campaign.Spec
+	m.mu.Unlock()
+
+	if step := m.currentStep(e, id); step != nil {
+		// The cluster may have been upgraded by other means while the campaign was stopped
+		applies, err := m.stepApplies(ctx, id, *step)
+		if err != nil {
+			return err
+		}
+		if !applies {
+			m.updateCluster(e, id, func(c *Cluster) { c.Step = nil })
+		}
+	}
+
+	for {
+		step := m.currentStep(e, id)
+		if step == nil {
+			if err := m.waitHealthy(ctx, e, id); err != nil {
+				return err
+			}
+
+			next, err := m.nextStep(ctx, id, spec)
+			if err != nil || next == nil {
+				return err
+			}
+			step = next
+			m.updateCluster(e, id, func(c *Cluster) { c.Step = step })
+		}
+
+		if err := m.runStep(ctx, e, id, *step); err != nil {
+			return err
+		}
+		m.updateCluster(e, id, func(c *Cluster) {
+			c.Completed = append(c.Completed, *step)
+			c.Step = nil
+		})
+	}
+}
+
+// nextStep returns the first step of the cluster's upgrade plan, or nil if it runs the target versions
+func (m *Manager) nextStep(ctx context.Context, id string, spec Spec) (*upgradeplan.Step, error) {
+	cluster, err := safe.StateGet[*omni.Cluster](ctx, m.state, omni.NewCluster(omniresources.DefaultNamespace, id).Metadata())
+	if err != nil {
+		return nil, err
+	}
+
+	current := cluster.TypedSpec().Value
+	talos, kubernetes := targetVersions(current, spec)
+
+	catalog, err := upgradeplan.LoadCatalog(ctx, m.state)
+	if err != nil {
+		return nil, err
+	}
+	steps, err := catalog.Plan(current.TalosVersion, current.KubernetesVersion, talos, kubernetes)
+	if err != nil || len(steps) == 0 {
+		return nil, err
+	}
+	return &steps[0], nil
+}
+
+// targetVersions returns the versions a campaign upgrades a cluster to; unset targets keep the cluster's versions
+func targetVersions(current *specs.ClusterSpec, spec Spec) (talos, kubernetes string) {
+	talos, kubernetes = spec.TalosVersion, spec.KubernetesVersion
+	if talos == "" {
+		talos = current.TalosVersion
+	}
+	if kubernetes == "" {
+		kubernetes = current.KubernetesVersion
+	}
+	return talos, kubernetes
+}
+
+// stepApplies reports whether a cluster still runs the version a step upgrades from or to
+func (m *Manager) stepApplies(ctx context.Context, id string, step upgradeplan.Step) (bool, error) {
+	cluster, err := safe.StateGet[*omni.Cluster](ctx, m.state, omni.NewCluster(omniresources.DefaultNamespace, id).Metadata())
+	if err != nil {
+		return false, err
+	}
+
+	version := cluster.TypedSpec().Value.TalosVersion
+	if step.Component == upgradeplan.ComponentKubernetes {
+		version = cluster.TypedSpec().Value.KubernetesVersion
+	}
+	return version == step.From || version == step.To, nil
+}
+
+// runStep starts an upgrade step through the Management service and follows it until the cluster runs the new version.
+// Starting a step again is harmless, so steps interrupted by a pause or restart are simply run again.
+func (m *Manager) runStep(ctx context.Context, e *entry, id string, step upgradeplan.Step) error {
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.StepTimeout)
+	defer cancel()
+
+	var track operations.Tracker
+	switch step.Component {
+	case upgradeplan.ComponentTalos:
+		if _, err := m.management.UpgradeTalos(ctx, id, step.To); err != nil {
+			return fmt.Errorf("failed to upgrade Talos to %s: %w", step.To, err)
+		}
+		track = operations.TalosUpgradeTracker(m.state, id, step.To)
+	case upgradeplan.ComponentKubernetes:
+		if _, err := m.management.UpgradeKubernetes(ctx, id, step.To); err != nil {
+			return fmt.Errorf("failed to upgrade Kubernetes to %s: %w", step.To, err)
+		}
+		track = operations.KubernetesUpgradeTracker(m.state, id, step.To)
+	default:
+		return fmt.Errorf("unknown upgrade component %q", step.Component)
+	}
+
+	err := track(ctx, func(p operations.Progress) {
+		m.updateCluster(e, id, func(c *Cluster) {
+			c.Message = fmt.Sprintf("%s %s: %s", step.Component, step.To, p.Phase)
+			if p.Message != "" {
+				c.Message += ": " + p.Message
+			}
+		})
+	})
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() != nil {
+		return fmt.Errorf("%s upgrade to %s did not complete within %s", step.Component, step.To, m.cfg.StepTimeout)
+	}
+	return err
+}
+
+// waitHealthy polls the health of a cluster until it is healthy or the health timeout passes
+func (m *Manager) waitHealthy(ctx context.Context, e *entry, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.HealthTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(m.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		reason, err := Health(ctx, m.state, id)
+		if err == nil && reason == "" {
+			return nil
+		}
+		if err != nil {
+			reason = err.Error()
+		}
+		m.updateCluster(e, id, func(c *Cluster) { c.Message = "waiting for the cluster to be healthy: " + reason })
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("cluster did not become healthy within %s: %s", m.cfg.HealthTimeout, reason)
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// currentStep returns the step a cluster is running, if any
+func (m *Manager) currentStep(e *entry, id string) *upgradeplan.Step {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, cluster := range e.campaign.Clusters {
+		if cluster.ID == id {
+			return cluster.Step
+		}
+	}
+	return nil
+}
+
+// updateCluster changes a cluster of a campaign and stores the campaigns
+func (m *Manager) updateCluster(e *entry, id string, fn func(*Cluster)) {
+	m.update(e, func(c *Campaign) {
+		for i := range c.Clusters {
+			if c.Clusters[i].ID == id {
+				fn(&c.Clusters[i])
+			}
+		}
+	})
+}
+
+// update changes a campaign and stores the campaigns
+func (m *Manager) update(e *entry, fn func(*Campaign)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fn(&e.campaign)
+	m.changed(e)
+}
+
+// changed stores the campaigns after e changed, logging failures. Callers must hold m.mu.
+func (m *Manager) changed(e *entry) {
+	e.campaign.UpdatedAt = m.now().UTC()
+	if err := m.persist(); err != nil {
+		log.Printf("Failed to store upgrade campaigns: %v", err)
+	}
+}
+
+// persist writes all campaigns to the file, replacing it atomically. Callers must hold m.mu.
+func (m *Manager) persist() error {
+	campaigns := make([]Campaign, 0, len(m.campaigns))
+	for _, e := range m.campaigns {
+		campaigns = append(campaigns, e.campaign)
+	}
+	slices.SortFunc(campaigns, func(a, b Campaign) int { return a.CreatedAt.Compare(b.CreatedAt) })
+
+	data, err := json.MarshalIndent(campaigns, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := m.cfg.File + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to store campaigns: %w", err)
+	}
+	if err := os.Rename(tmp, m.cfg.File); err != nil {
+		return fmt.Errorf("failed to store campaigns: %w", err)
+	}
+	return nil
+}
+
+// snapshot returns a deep copy of the campaign. Callers must hold m.mu.
+func (e *entry) snapshot() Campaign {
+	campaign := e.campaign
+	campaign.Spec.Waves = slices.Clone(campaign.Spec.Waves)
+	campaign.Clusters = slices.Clone(campaign.Clusters)
+	for i := range campaign.Clusters {
+		cluster := &campaign.Clusters[i]
+		cluster.Completed = slices.Clone(cluster.Completed)
+		if cluster.Step != nil {
+			step := *cluster.Step
+			cluster.Step = &step
+		}
+	}
+	return campaign
+}
+
+func newID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to generate campaign ID: %v", err))
+	}
+	return "uc-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,373 @@
+package campaign
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/safe"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/cosi-project/runtime/pkg/state/impl/inmem"
+	"github.com/cosi-project/runtime/pkg/state/impl/namespaced"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/jubblin/omni-api/internal/upgradeplan"
+	"github.com/siderolabs/omni/client/api/omni/specs"
+	"github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeManagement upgrades clusters by updating their spec and reporting the upgrade as done right away
+type fakeManagement struct {
+	client.ManagementService
+	st state.State
+
+	mu    sync.Mutex
+	calls []string
+	fail  map[string]error
+}
+
+func (f *fakeManagement) UpgradeTalos(ctx context.Context, clusterID, version string) (*client.Change, error) {
+	if err := f.record(clusterID, "talos", version); err != nil {
+		return nil, err
+	}
+	return nil, f.upgrade(ctx, clusterID, func(spec *specs.ClusterSpec) { spec.TalosVersion = version },
+		omni.NewTalosUpgradeStatus(resources.DefaultNamespace, clusterID), func(res resource.Resource) {
+			res.(*omni.TalosUpgradeStatus).TypedSpec().Value.LastUpgradeVersion = version //nolint:forcetypeassert
+			res.(*omni.TalosUpgradeStatus).TypedSpec().Value.Phase = specs.TalosUpgradeStatusSpec_Done
+		})
+}
+
+func (f *fakeManagement) UpgradeKubernetes(ctx context.Context, clusterID, version string) (*client.Change, error) {
+	if err := f.record(clusterID, "kubernetes", version); err != nil {
+		return nil, err
+	}
+	return nil, f.upgrade(ctx, clusterID, func(spec *specs.ClusterSpec) { spec.KubernetesVersion = version },
+		omni.NewKubernetesUpgradeStatus(resources.DefaultNamespace, clusterID), func(res resource.Resource) {
+			res.(*omni.KubernetesUpgradeStatus).TypedSpec().Value.LastUpgradeVersion = version //nolint:forcetypeassert
+			res.(*omni.KubernetesUpgradeStatus).TypedSpec().Value.Phase = specs.KubernetesUpgradeStatusSpec_Done
+		})
+}
+
+func (f *fakeManagement) record(clusterID, component, version string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, clusterID+" "+component+" "+version)
+	return f.fail[clusterID]
+}
+
+func (f *fakeManagement) upgrade(ctx context.Context, clusterID string, setVersion func(*specs.ClusterSpec), status resource.Resource, done func(resource.Resource)) error {
+	if _, err := safe.StateUpdateWithConflicts(ctx, f.st, omni.NewCluster(resources.DefaultNamespace, clusterID).Metadata(), func(cluster *omni.Cluster) error {
+		setVersion(cluster.TypedSpec().Value)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	current, err := f.st.Get(ctx, status.Metadata())
+	if state.IsNotFoundError(err) {
+		done(status)
+		return f.st.Create(ctx, status)
+	}
+	if err != nil {
+		return err
+	}
+	res := current.DeepCopy()
+	done(res)
+	return f.st.Update(ctx, res)
+}
+
+func (f *fakeManagement) setFail(clusterID string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.fail[clusterID] = err
+}
+
+func (f *fakeManagement) callsOf(clusterID string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var calls []string
+	for _, call := range f.calls {
+		if id, _, _ := strings.Cut(call, " "); id == clusterID {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// newTestFleet creates healthy clusters running Talos 1.8.0 and Kubernetes 1.30.1, labeled env=prod
+func newTestFleet(t *testing.T, ids ...string) state.State {
+	ctx := context.Background()
+	st := state.WrapCore(namespaced.NewState(inmem.Build))
+
+	for version, compatible := range map[string][]string{"1.8.0": {"1.30.1", "1.31.0"}, "1.9.1": {"1.30.1", "1.31.0"}} {
+		tv := omni.NewTalosVersion(resources.DefaultNamespace, version)
+		tv.TypedSpec().Value.CompatibleKubernetesVersions = compatible
+		require.NoError(t, st.Create(ctx, tv))
+	}
+	for _, version := range []string{"1.30.1", "1.31.0"} {
+		kv := omni.NewKubernetesVersion(resources.DefaultNamespace, version)
+		kv.TypedSpec().Value.Version = version
+		require.NoError(t, st.Create(ctx, kv))
+	}
+
+	for _, id := range ids {
+		cluster := omni.NewCluster(resources.DefaultNamespace, id)
+		cluster.Metadata().Labels().Set("env", "prod")
+		cluster.TypedSpec().Value.TalosVersion = "1.8.0"
+		cluster.TypedSpec().Value.KubernetesVersion = "1.30.1"
+		require.NoError(t, st.Create(ctx, cluster))
+
+		clusterStatus := omni.NewClusterStatus(resources.DefaultNamespace, id)
+		clusterStatus.TypedSpec().Value.Ready = true
+		require.NoError(t, st.Create(ctx, clusterStatus))
+
+		kubernetesStatus := omni.NewKubernetesStatus(resources.DefaultNamespace, id)
+		kubernetesStatus.TypedSpec().Value.Nodes = []*specs.KubernetesStatusSpec_NodeStatus{{Nodename: id + "-node", Ready: true}}
+		require.NoError(t, st.Create(ctx, kubernetesStatus))
+	}
+
+	return st
+}
+
+func newTestManager(t *testing.T, st state.State, file string) (*Manager, *fakeManagement) {
+	mgmt := &fakeManagement{st: st, fail: map[string]error{}}
+	m, err := NewManager(Config{File: file, HealthTimeout: 5 * time.Second, PollInterval: 10 * time.Millisecond}, st, mgmt)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.Start(ctx)
+	t.Cleanup(func() {
+		// Let the runners stop before the file's directory is removed
+		cancel()
+		m.mu.Lock()
+		var done []chan struct{}
+		for _, e := range m.campaigns {
+			if e.done != nil {
+				done = append(done, e.done)
+			}
+		}
+		m.mu.Unlock()
+		for _, ch := range done {
+			<-ch
+		}
+	})
+
+	return m, mgmt
+}
+
+func waitFor(t *testing.T, m *Manager, id string, condition func(Campaign) bool) Campaign {
+	var campaign Campaign
+	require.Eventually(t, func() bool {
+		campaign, _ = m.Get(id)
+		return condition(campaign)
+	}, 5*time.Second, 10*time.Millisecond)
+	return campaign
+}
+
+func setReady(t *testing.T, st state.State, id string, ready bool) {
+	_, err := safe.StateUpdateWithConflicts(context.Background(), st, omni.NewClusterStatus(resources.DefaultNamespace, id).Metadata(), func(status *omni.ClusterStatus) error {
+		status.TypedSpec().Value.Ready = ready
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestManager_Plan(t *testing.T) {
+	ids := make([]string, 12)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("c%02d", i)
+	}
+	st := newTestFleet(t, ids...)
+	staging := omni.NewCluster(resources.DefaultNamespace, "staging")
+	staging.TypedSpec().Value.TalosVersion = "1.9.1"
+	staging.TypedSpec().Value.KubernetesVersion = "1.31.0"
+	require.NoError(t, st.Create(context.Background(), staging))
+
+	m, _ := newTestManager(t, st, filepath.Join(t.TempDir(), "campaigns.json"))
+	ctx := context.Background()
+
+	campaign, err := m.Plan(ctx, Spec{TalosVersion: "v1.9.1", Selector: "env=prod", Canary: "c05"})
+	require.NoError(t, err)
+	assert.Equal(t, "1.9.1", campaign.Spec.TalosVersion)
+	assert.Equal(t, []int{10}, campaign.Spec.Waves)
+	assert.Equal(t, 3, campaign.Waves)
+
+	waves := map[int][]string{}
+	for _, cluster := range campaign.Clusters {
+		waves[cluster.Wave] = append(waves[cluster.Wave], cluster.ID)
+		assert.Equal(t, ClusterPending, cluster.Status)
+	}
+	assert.Equal(t, []string{"c05"}, waves[0])
+	assert.Equal(t, []string{"c00", "c01"}, waves[1], "10% of 12 clusters, rounded up")
+	assert.Len(t, waves[2], 9)
+	assert.NotContains(t, waves[2], "staging")
+
+	campaign, err = m.Plan(ctx, Spec{KubernetesVersion: "1.31.0", Waves: []int{25, 50}})
+	require.NoError(t, err)
+	assert.Equal(t, "c00", campaign.Clusters[0].ID, "the first cluster is the default canary")
+	assert.Equal(t, 4, campaign.Waves, "the canary, 25% and 50% of 13 clusters, then the rest")
+	assert.Empty(t, m.List(), "planning does not store the campaign")
+
+	for name, spec := range map[string]Spec{
+		"no version":       {Selector: "env=prod"},
+		"invalid version":  {TalosVersion: "latest"},
+		"invalid selector": {TalosVersion: "1.9.1", Selector: "env in (prod"},
+		"no match":         {TalosVersion: "1.9.1", Selector: "env=dev"},
+		"foreign canary":   {TalosVersion: "1.9.1", Selector: "env=prod", Canary: "staging"},
+		"empty wave":       {TalosVersion: "1.9.1", Waves: []int{0}},
+	} {
+		_, err := m.Plan(ctx, spec)
+		assert.ErrorIs(t, err, ErrInvalid, name)
+	}
+
+	// Kubernetes cannot be downgraded on staging, so the whole campaign is rejected
+	_, err = m.Plan(ctx, Spec{KubernetesVersion: "1.30.1"})
+	assert.ErrorIs(t, err, ErrInvalid)
+	assert.ErrorContains(t, err, "no upgrade path for staging")
+}
+
+func TestManager_Disabled(t *testing.T) {
+	st := newTestFleet(t, "a")
+	m, err := NewManager(Config{}, st, &fakeManagement{st: st, fail: map[string]error{}})
+	require.NoError(t, err)
+	assert.False(t, m.Enabled())
+
+	_, err = m.Plan(context.Background(), Spec{TalosVersion: "1.9.1"})
+	require.NoError(t, err, "campaigns can be planned without a file")
+	_, err = m.Create(context.Background(), Spec{TalosVersion: "1.9.1"})
+	assert.ErrorIs(t, err, ErrDisabled)
+}
+
+func TestManager_Run(t *testing.T) {
+	st := newTestFleet(t, "a", "b", "c")
+	file := filepath.Join(t.TempDir(), "campaigns.json")
+	m, mgmt := newTestManager(t, st, file)
+
+	created, err := m.Create(context.Background(), Spec{TalosVersion: "1.9.1", KubernetesVersion: "1.31.0", Canary: "b"})
+	require.NoError(t, err)
+	assert.Equal(t, StatusRunning, created.Status)
+
+	campaign := waitFor(t, m, created.ID, Campaign.Done)
+	assert.Equal(t, StatusSucceeded, campaign.Status)
+	for _, cluster := range campaign.Clusters {
+		assert.Equal(t, ClusterSucceeded, cluster.Status, cluster.ID)
+		assert.Equal(t, []upgradeplan.Step{
+			{Component: upgradeplan.ComponentTalos, From: "1.8.0", To: "1.9.1", TalosVersion: "1.9.1", KubernetesVersion: "1.30.1"},
+			{Component: upgradeplan.ComponentKubernetes, From: "1.30.1", To: "1.31.0", TalosVersion: "1.9.1", KubernetesVersion: "1.31.0"},
+		}, cluster.Completed, cluster.ID)
+		assert.Equal(t, []string{cluster.ID + " talos 1.9.1", cluster.ID + " kubernetes 1.31.0"}, mgmt.callsOf(cluster.ID))
+	}
+	assert.Equal(t, "b talos 1.9.1", mgmt.calls[0], "the canary goes first")
+	assert.True(t, slices.Index(mgmt.calls, "b kubernetes 1.31.0") < slices.Index(mgmt.calls, "a talos 1.9.1"), "the canary completes before the next wave")
+
+	// Campaigns are loaded from the file
+	reloaded, err := NewManager(Config{File: file}, st, mgmt)
+	require.NoError(t, err)
+	stored, ok := reloaded.Get(created.ID)
+	require.True(t, ok)
+	assert.Equal(t, campaign, stored)
+}
+
+func TestManager_FailurePausesCampaign(t *testing.T) {
+	st := newTestFleet(t, "a", "b")
+	m, mgmt := newTestManager(t, st, filepath.Join(t.TempDir(), "campaigns.json"))
+	mgmt.setFail("a", fmt.Errorf("cannot upgrade Talos"))
+
+	created, err := m.Create(context.Background(), Spec{TalosVersion: "1.9.1"})
+	require.NoError(t, err)
+
+	campaign := waitFor(t, m, created.ID, func(c Campaign) bool { return c.Status == StatusPaused })
+	assert.Contains(t, campaign.Error, "wave 0: upgrading a failed")
+	assert.Equal(t, ClusterFailed, campaign.Clusters[0].Status)
+	assert.Contains(t, campaign.Clusters[0].Error, "cannot upgrade Talos")
+	assert.NotNil(t, campaign.Clusters[0].Step, "the failed step is retried on resume")
+	assert.Equal(t, ClusterPending, campaign.Clusters[1].Status, "the next wave does not start")
+	assert.Empty(t, mgmt.callsOf("b"))
+
+	mgmt.setFail("a", nil)
+	_, err = m.Resume(created.ID)
+	require.NoError(t, err)
+
+	campaign = waitFor(t, m, created.ID, Campaign.Done)
+	assert.Equal(t, StatusSucceeded, campaign.Status)
+	assert.Empty(t, campaign.Error)
+}
+
+func TestManager_PauseResumeAbort(t *testing.T) {
+	st := newTestFleet(t, "a", "b")
+	setReady(t, st, "a", false)
+	m, mgmt := newTestManager(t, st, filepath.Join(t.TempDir(), "campaigns.json"))
+
+	created, err := m.Create(context.Background(), Spec{TalosVersion: "1.9.1"})
+	require.NoError(t, err)
+
+	// The canary waits for its health gate
+	waitFor(t, m, created.ID, func(c Campaign) bool {
+		return c.Clusters[0].Message == "waiting for the cluster to be healthy: cluster is not ready"
+	})
+	_, err = m.Resume(created.ID)
+	assert.ErrorIs(t, err, ErrConflict)
+
+	paused, err := m.Pause(created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusPaused, paused.Status)
+	waitFor(t, m, created.ID, func(c Campaign) bool { return c.Clusters[0].Status == ClusterPending })
+	_, err = m.Pause(created.ID)
+	assert.ErrorIs(t, err, ErrConflict)
+
+	setReady(t, st, "a", true)
+	_, err = m.Resume(created.ID)
+	require.NoError(t, err)
+	campaign := waitFor(t, m, created.ID, Campaign.Done)
+	assert.Equal(t, StatusSucceeded, campaign.Status)
+	assert.Len(t, mgmt.callsOf("a"), 1)
+
+	_, err = m.Abort(created.ID)
+	assert.ErrorIs(t, err, ErrConflict, "completed campaigns cannot be aborted")
+
+	setReady(t, st, "b", false)
+	created, err = m.Create(context.Background(), Spec{KubernetesVersion: "1.31.0", Canary: "b"})
+	require.NoError(t, err)
+	aborted, err := m.Abort(created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusAborted, aborted.Status)
+	assert.False(t, aborted.CompletedAt.IsZero())
+
+	_, err = m.Pause("uc-unknown")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestHealth(t *testing.T) {
+	ctx := context.Background()
+	st := newTestFleet(t, "a")
+
+	reason, err := Health(ctx, st, "a")
+	require.NoError(t, err)
+	assert.Empty(t, reason)
+
+	controlPlane := omni.NewControlPlaneStatus(resources.DefaultNamespace, "a-control-planes")
+	controlPlane.Metadata().Labels().Set(omni.LabelCluster, "a")
+	controlPlane.TypedSpec().Value.Conditions = []*specs.ControlPlaneStatusSpec_Condition{
+		{Type: specs.ConditionType_Etcd, Status: specs.ControlPlaneStatusSpec_Condition_NotReady, Reason: "etcd member is down"},
+	}
+	require.NoError(t, st.Create(ctx, controlPlane))
+
+	reason, err = Health(ctx, st, "a")
+	require.NoError(t, err)
+	assert.Contains(t, reason, "etcd member is down")
+
+	reason, err = Health(ctx, st, "missing")
+	require.NoError(t, err)
+	assert.Equal(t, "cluster status is not reported yet", reason)
+}
@@ -0,0 +1,60 @@
+package campaign
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/safe"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/siderolabs/omni/client/api/omni/specs"
+	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+// Health checks the health gates of a cluster: ClusterStatus is ready, the control plane reports no
+// condition that is not ready, and every Kubernetes node is ready. It returns why the cluster is not
+// healthy, or "" if it is.
+func Health(ctx context.Context, st state.State, id string) (string, error) {
+	clusterStatus, err := safe.StateGet[*omni.ClusterStatus](ctx, st, omni.NewClusterStatus(omniresources.DefaultNamespace, id).Metadata())
+	if state.IsNotFoundError(err) {
+		return "cluster status is not reported yet", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if !clusterStatus.TypedSpec().Value.Ready {
+		return "cluster is not ready", nil
+	}
+
+	controlPlanes, err := safe.StateListAll[*omni.ControlPlaneStatus](ctx, st, state.WithLabelQuery(resource.LabelEqual(omni.LabelCluster, id)))
+	if err != nil {
+		return "", err
+	}
+	for controlPlane := range controlPlanes.All() {
+		for _, condition := range controlPlane.TypedSpec().Value.Conditions {
+			if condition.Status != specs.ControlPlaneStatusSpec_Condition_Ready {
+				return fmt.Sprintf("control plane condition %s is %s: %s", condition.Type, condition.Status, condition.Reason), nil
+			}
+		}
+	}
+
+	kubernetesStatus, err := safe.StateGet[*omni.KubernetesStatus](ctx, st, omni.NewKubernetesStatus(omniresources.DefaultNamespace, id).Metadata())
+	if state.IsNotFoundError(err) {
+		return "Kubernetes node status is not reported yet", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	var notReady []string
+	for _, node := range kubernetesStatus.TypedSpec().Value.Nodes {
+		if !node.Ready {
+			notReady = append(notReady, node.Nodename)
+		}
+	}
+	if len(notReady) > 0 {
+		return "nodes are not ready: " + strings.Join(notReady, ", "), nil
+	}
+	return "", nil
+}
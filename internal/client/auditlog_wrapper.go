@@ -0,0 +1,67 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"iter"
+)
+
+// AuditLogDateFormat is the date format Omni expects for audit log ranges
+const AuditLogDateFormat = "2006-01-02"
+
+// AuditLogService reads Omni's own audit log through the Management API
+type AuditLogService interface {
+	// ReadAuditLog yields the raw JSON entries Omni logged from the start of day from
+	// to the end of day to, both formatted as AuditLogDateFormat
+	ReadAuditLog(ctx context.Context, from, to string) iter.Seq2[[]byte, error]
+}
+
+// auditLogService implements AuditLogService
+type auditLogService struct {
+	source ClientSource // Resolved on every call so rebuilt clients are picked up
+}
+
+// NewAuditLogService creates a new AuditLogService wrapper
+func NewAuditLogService(src ClientSource) AuditLogService {
+	return &auditLogService{
+		source: src,
+	}
+}
+
+func (s *auditLogService) ReadAuditLog(ctx context.Context, from, to string) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		if err := allow(s.source); err != nil {
+			yield(nil, err)
+			return
+		}
+		var streamErr error
+		defer func() { record(s.source, streamErr) }()
+
+		// Omni streams the log files in chunks that don't necessarily end on a line boundary
+		var pending []byte
+		for resp, err := range s.source.Client().Management().ReadAuditLog(ctx, from, to) {
+			if err != nil {
+				streamErr = err
+				yield(nil, err)
+				return
+			}
+
+			pending = append(pending, resp.GetAuditLog()...)
+			for {
+				i := bytes.IndexByte(pending, '\n')
+				if i < 0 {
+					break
+				}
+				line := bytes.TrimSpace(pending[:i])
+				pending = pending[i+1:]
+				if len(line) > 0 && !yield(line, nil) {
+					return
+				}
+			}
+		}
+
+		if line := bytes.TrimSpace(pending); len(line) > 0 {
+			yield(line, nil)
+		}
+	}
+}
@@ -3,8 +3,6 @@ package client
 import (
 	"context"
 	"fmt"
-
-	"github.com/siderolabs/omni/client/pkg/client"
 )
 
 // ServiceAccount represents a service account
@@ -32,13 +30,13 @@ type AuthService interface {
 
 // authService implements AuthService
 type authService struct {
-	client interface{} // Will be *auth.Client once we know the type
+	source ClientSource // Resolved on every call so rebuilt clients are picked up
 }
 
 // NewAuthService creates a new AuthService wrapper
-func NewAuthService(c *client.Client) AuthService {
+func NewAuthService(src ClientSource) AuthService {
 	return &authService{
-		client: c.Auth(),
+		source: src,
 	}
 }
 
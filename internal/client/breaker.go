@@ -0,0 +1,222 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrCircuitOpen is returned when a call is rejected because Omni is considered unavailable
+var ErrCircuitOpen = status.Error(codes.Unavailable, "omni is unavailable: circuit breaker is open")
+
+// BreakerState is the state of a circuit breaker
+type BreakerState string
+
+const (
+	// BreakerClosed lets all calls through
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen rejects all calls until the open timeout elapses
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets a single probe call through to test whether Omni recovered
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// BreakerConfig configures a circuit breaker
+type BreakerConfig struct {
+	FailureThreshold int           // Consecutive failures before the breaker opens
+	OpenTimeout      time.Duration // Time the breaker stays open before probing again
+}
+
+// BreakerSnapshot is a point-in-time view of a circuit breaker
+type BreakerSnapshot struct {
+	State               BreakerState
+	ConsecutiveFailures int
+	Trips               uint64
+	OpenedAt            time.Time
+	RetryAfter          time.Duration
+	LastError           string
+}
+
+// RetryAfterSeconds returns RetryAfter rounded up to whole seconds, as used by the Retry-After header
+func (s BreakerSnapshot) RetryAfterSeconds() int {
+	return int((s.RetryAfter + time.Second - 1) / time.Second)
+}
+
+// Breaker is a circuit breaker guarding calls to Omni
+type Breaker struct {
+	mu       sync.Mutex
+	config   BreakerConfig
+	state    BreakerState
+	failures int
+	trips    uint64
+	openedAt time.Time
+	probing  bool
+	lastErr  string
+	onTrip   func()
+	now      func() time.Time
+}
+
+// NewBreaker creates a new Breaker
+func NewBreaker(config BreakerConfig) *Breaker {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 5
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = 30 * time.Second
+	}
+
+	return &Breaker{
+		config: config,
+		state:  BreakerClosed,
+		now:    time.Now,
+	}
+}
+
+// OnTrip registers a callback invoked every time the breaker opens
+func (b *Breaker) OnTrip(fn func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.onTrip = fn
+}
+
+// Allow reports whether a call may proceed, returning ErrCircuitOpen if not
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.config.OpenTimeout {
+			return ErrCircuitOpen
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return nil
+	case BreakerHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Record records the outcome of a call that was allowed by Allow
+func (b *Breaker) Record(err error) {
+	var onTrip func()
+
+	b.mu.Lock()
+	if !IsUnavailable(err) {
+		b.state = BreakerClosed
+		b.failures = 0
+		b.probing = false
+		b.mu.Unlock()
+		return
+	}
+
+	b.failures++
+	b.lastErr = err.Error()
+	if b.state == BreakerHalfOpen || b.failures >= b.config.FailureThreshold {
+		if b.state != BreakerOpen {
+			b.trips++
+			onTrip = b.onTrip
+		}
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+		b.probing = false
+	}
+	b.mu.Unlock()
+
+	if onTrip != nil {
+		onTrip()
+	}
+}
+
+// State returns the current breaker state
+func (b *Breaker) State() BreakerState {
+	return b.Snapshot().State
+}
+
+// RetryAfter returns how long callers should wait before retrying, or zero if calls are allowed
+func (b *Breaker) RetryAfter() time.Duration {
+	return b.Snapshot().RetryAfter
+}
+
+// Snapshot returns a point-in-time view of the breaker
+func (b *Breaker) Snapshot() BreakerSnapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	snapshot := BreakerSnapshot{
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+		Trips:               b.trips,
+		OpenedAt:            b.openedAt,
+		LastError:           b.lastErr,
+	}
+
+	if b.state == BreakerOpen {
+		if remaining := b.config.OpenTimeout - b.now().Sub(b.openedAt); remaining > 0 {
+			snapshot.RetryAfter = remaining
+		}
+	}
+
+	return snapshot
+}
+
+// IsUnavailable reports whether an error means Omni could not be reached.
+// Errors such as NotFound or PermissionDenied prove Omni is up and do not count.
+func IsUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
+
+// breakerSource is implemented by client sources that guard Omni calls with a circuit breaker, such as Holder
+type breakerSource interface {
+	Breaker() *Breaker
+}
+
+// allow asks the circuit breaker of src, if it has one, whether an Omni call may proceed
+func allow(src ClientSource) error {
+	if b, ok := src.(breakerSource); ok {
+		return b.Breaker().Allow()
+	}
+	return nil
+}
+
+// record records the outcome of an Omni call allowed by allow
+func record(src ClientSource, err error) {
+	if b, ok := src.(breakerSource); ok {
+		b.Breaker().Record(err)
+	}
+}
+
+// guard runs an Omni call that does not go through the guarded state, such as a Management API call,
+// through the circuit breaker of src
+func guard[T any](src ClientSource, call func() (T, error)) (T, error) {
+	if err := allow(src); err != nil {
+		var zero T
+		return zero, err
+	}
+	result, err := call()
+	record(src, err)
+	return result, err
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBreaker_OpensAfterThreshold(t *testing.T) {
+	b := NewBreaker(BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute})
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+
+	assert.NoError(t, b.Allow())
+	b.Record(unavailable)
+	assert.Equal(t, BreakerClosed, b.State())
+
+	assert.NoError(t, b.Allow())
+	b.Record(unavailable)
+	assert.Equal(t, BreakerOpen, b.State())
+	assert.Equal(t, uint64(1), b.Snapshot().Trips)
+	assert.ErrorIs(t, b.Allow(), ErrCircuitOpen)
+	assert.Greater(t, b.RetryAfter(), time.Duration(0))
+}
+
+func TestBreaker_IgnoresNonAvailabilityErrors(t *testing.T) {
+	b := NewBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute})
+
+	assert.NoError(t, b.Allow())
+	b.Record(status.Error(codes.NotFound, "cluster not found"))
+	assert.Equal(t, BreakerClosed, b.State())
+
+	assert.NoError(t, b.Allow())
+	b.Record(context.Canceled)
+	assert.Equal(t, BreakerClosed, b.State())
+}
+
+func TestBreaker_HalfOpenProbe(t *testing.T) {
+	now := time.Now()
+	b := NewBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: 10 * time.Second})
+	b.now = func() time.Time { return now }
+
+	var trips int
+	b.OnTrip(func() { trips++ })
+
+	assert.NoError(t, b.Allow())
+	b.Record(context.DeadlineExceeded)
+	assert.Equal(t, BreakerOpen, b.State())
+	assert.Equal(t, 10, b.Snapshot().RetryAfterSeconds())
+
+	// After the open timeout a single probe is let through
+	now = now.Add(11 * time.Second)
+	assert.NoError(t, b.Allow())
+	assert.Equal(t, BreakerHalfOpen, b.State())
+	assert.ErrorIs(t, b.Allow(), ErrCircuitOpen)
+
+	// A failed probe reopens the breaker
+	b.Record(status.Error(codes.Unavailable, "still down"))
+	assert.Equal(t, BreakerOpen, b.State())
+	assert.Equal(t, 2, trips)
+
+	// A successful probe closes it
+	now = now.Add(11 * time.Second)
+	assert.NoError(t, b.Allow())
+	b.Record(nil)
+	assert.Equal(t, BreakerClosed, b.State())
+	assert.Equal(t, 0, b.Snapshot().ConsecutiveFailures)
+}
+
+// breakerTestSource is a ClientSource guarded by a circuit breaker that never builds a client
+type breakerTestSource struct {
+	StaticSource
+	breaker *Breaker
+}
+
+func (s breakerTestSource) Breaker() *Breaker {
+	return s.breaker
+}
+
+func TestGuard(t *testing.T) {
+	src := breakerTestSource{breaker: NewBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute})}
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+
+	_, err := guard(src, func() ([]byte, error) { return nil, unavailable })
+	assert.ErrorIs(t, err, unavailable)
+	assert.Equal(t, BreakerOpen, src.breaker.State())
+
+	called := false
+	_, err = guard(src, func() ([]byte, error) {
+		called = true
+		return nil, nil
+	})
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.False(t, called)
+
+	// Sources without a breaker call through
+	data, err := guard(StaticSource{}, func() ([]byte, error) { return []byte("ok"), nil })
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("ok"), data)
+}
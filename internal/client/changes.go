@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+)
+
+// ChangeAction is the kind of change a write operation makes
+type ChangeAction string
+
+const (
+	// ChangeCreate creates a new resource
+	ChangeCreate ChangeAction = "create"
+	// ChangeUpdate updates an existing resource
+	ChangeUpdate ChangeAction = "update"
+	// ChangeDestroy tears down and destroys an existing resource
+	ChangeDestroy ChangeAction = "destroy"
+	// ChangeNone means the operation does not write a resource, e.g. a reboot
+	ChangeNone ChangeAction = "none"
+)
+
+// Change describes what a write operation writes, or would write in dry-run mode
+type Change struct {
+	Action  ChangeAction
+	Current resource.Resource // Nil for creations; set for ChangeNone if the resource is left as it is
+	Desired resource.Resource // Nil for deletions; set for ChangeNone if the resource is left as it is
+	// Cascade lists other resources that are destroyed together with Current
+	Cascade []resource.Pointer
+}
+
+type dryRunKey struct{}
+
+// WithDryRun marks ctx so that write operations run their validation and
+// return the Change they would make without writing anything
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+// IsDryRun reports whether ctx was marked with WithDryRun
+func IsDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return dryRun
+}
+
+type managedByKey struct{}
+
+// WithManagedBy marks ctx so that config patch writes set ManagedByLabel to owner,
+// letting the owner find the patches it wrote, e.g. to prune them after a restart
+func WithManagedBy(ctx context.Context, owner string) context.Context {
+	return context.WithValue(ctx, managedByKey{}, owner)
+}
+
+// ManagedBy returns the owner ctx was marked with by WithManagedBy
+func ManagedBy(ctx context.Context) string {
+	owner, _ := ctx.Value(managedByKey{}).(string)
+	return owner
+}
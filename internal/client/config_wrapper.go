@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/siderolabs/omni/client/pkg/client/management"
+)
+
+// KubeconfigOptions selects the kind of kubeconfig Omni generates
+type KubeconfigOptions struct {
+	// ServiceAccount generates a kubeconfig with an embedded token for User and Groups, valid for TTL.
+	// Otherwise the kubeconfig authenticates each user against Omni through OIDC.
+	ServiceAccount bool
+	User           string
+	Groups         []string
+	TTL            time.Duration
+	GrantType      string // OIDC grant type, e.g. "authcode-keyboard"; Omni's default if empty
+}
+
+// TalosconfigOptions selects the kind of talosconfig Omni generates
+type TalosconfigOptions struct {
+	// BreakGlass generates an operator talosconfig that talks to the machines directly, bypassing Omni
+	BreakGlass bool
+}
+
+// ConfigService generates client configuration through the Omni Management API
+type ConfigService interface {
+	Kubeconfig(ctx context.Context, clusterID string, opts KubeconfigOptions) ([]byte, error)
+	Talosconfig(ctx context.Context, clusterID string, opts TalosconfigOptions) ([]byte, error)
+	Omniconfig(ctx context.Context) ([]byte, error)
+}
+
+// configService implements ConfigService
+type configService struct {
+	source ClientSource // Resolved on every call so rebuilt clients are picked up
+}
+
+// NewConfigService creates a new ConfigService wrapper
+func NewConfigService(src ClientSource) ConfigService {
+	return &configService{
+		source: src,
+	}
+}
+
+func (s *configService) Kubeconfig(ctx context.Context, clusterID string, opts KubeconfigOptions) ([]byte, error) {
+	var kubeconfigOpts []management.KubeconfigOption
+	if opts.ServiceAccount {
+		kubeconfigOpts = append(kubeconfigOpts, management.WithServiceAccount(opts.TTL, opts.User, opts.Groups...))
+	}
+	if opts.GrantType != "" {
+		kubeconfigOpts = append(kubeconfigOpts, management.WithGrantType(opts.GrantType))
+	}
+
+	return guard(s.source, func() ([]byte, error) {
+		return s.source.Client().Management().WithCluster(clusterID).Kubeconfig(ctx, kubeconfigOpts...)
+	})
+}
+
+func (s *configService) Talosconfig(ctx context.Context, clusterID string, opts TalosconfigOptions) ([]byte, error) {
+	var talosconfigOpts []management.TalosconfigOption
+	if opts.BreakGlass {
+		talosconfigOpts = append(talosconfigOpts, management.WithBreakGlassTalosconfig(true))
+	}
+
+	return guard(s.source, func() ([]byte, error) {
+		return s.source.Client().Management().WithCluster(clusterID).Talosconfig(ctx, talosconfigOpts...)
+	})
+}
+
+func (s *configService) Omniconfig(ctx context.Context) ([]byte, error) {
+	return guard(s.source, func() ([]byte, error) {
+		return s.source.Client().Management().Omniconfig(ctx)
+	})
+}
@@ -0,0 +1,238 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/jubblin/omni-api/internal/env"
+	"github.com/siderolabs/omni/client/pkg/client"
+)
+
+// ClientSource provides the Omni client that should be used for the next call
+type ClientSource interface {
+	Client() *client.Client
+	// State returns the COSI state to read and write Omni resources through
+	State() state.State
+}
+
+// StaticSource is a ClientSource that always returns the same client
+type StaticSource struct {
+	C *client.Client
+}
+
+// Client returns the wrapped client
+func (s StaticSource) Client() *client.Client {
+	return s.C
+}
+
+// State returns the state of the wrapped client
+func (s StaticSource) State() state.State {
+	return s.C.Omni().State()
+}
+
+// HolderConfig configures a Holder
+type HolderConfig struct {
+	CredentialsFile string        // Service account key file to watch, e.g. a mounted secret
+	ReloadInterval  time.Duration // How often the credentials file is checked for changes
+	DrainTimeout    time.Duration // How long a replaced client is kept open for in-flight requests
+	Breaker         BreakerConfig
+}
+
+// HolderConfigFromEnv builds a HolderConfig from environment variables
+func HolderConfigFromEnv() HolderConfig {
+	return HolderConfig{
+		CredentialsFile: os.Getenv("OMNI_SERVICE_ACCOUNT_KEY_FILE"),
+		ReloadInterval:  env.Duration("OMNI_CREDENTIALS_RELOAD_INTERVAL", 30*time.Second),
+		DrainTimeout:    env.Duration("OMNI_CLIENT_DRAIN_TIMEOUT", time.Minute),
+		Breaker: BreakerConfig{
+			FailureThreshold: env.Int("OMNI_CIRCUIT_FAILURE_THRESHOLD", 5),
+			OpenTimeout:      env.Duration("OMNI_CIRCUIT_OPEN_TIMEOUT", 30*time.Second),
+		},
+	}
+}
+
+type holderEntry struct {
+	client *client.Client
+	state  state.State
+}
+
+// Holder owns the Omni client and rebuilds it when credentials change or
+// when Omni becomes unreachable. Replaced clients are closed only after a
+// drain period, so requests in flight during a swap are not dropped.
+// Long-running watches fail once their client is closed and are expected to be
+// opened again through State, which then targets the new client.
+type Holder struct {
+	mu        sync.RWMutex
+	entry     *holderEntry
+	config    HolderConfig
+	breaker   *Breaker
+	state     state.State
+	keyDigest [sha256.Size]byte
+	reconnect chan struct{}
+	newClient func(serviceAccount string) (*client.Client, error)
+}
+
+// NewHolder creates a Holder and its initial Omni client
+func NewHolder(config HolderConfig) (*Holder, error) {
+	h := &Holder{
+		config:    config,
+		breaker:   NewBreaker(config.Breaker),
+		reconnect: make(chan struct{}, 1),
+		newClient: newOmniClient,
+	}
+	h.state = state.WrapCore(&holderState{holder: h})
+
+	if config.CredentialsFile == "" {
+		c, err := NewOmniClient()
+		if err != nil {
+			return nil, err
+		}
+		h.swap(c)
+	} else if err := h.reloadCredentials(); err != nil {
+		return nil, err
+	}
+
+	h.breaker.OnTrip(func() {
+		select {
+		case h.reconnect <- struct{}{}:
+		default:
+		}
+	})
+
+	return h, nil
+}
+
+// Client returns the current Omni client
+func (h *Holder) Client() *client.Client {
+	return h.current().client
+}
+
+// State returns a state.State that always targets the current client and is guarded by the circuit breaker
+func (h *Holder) State() state.State {
+	return h.state
+}
+
+// Breaker returns the circuit breaker guarding Omni calls
+func (h *Holder) Breaker() *Breaker {
+	return h.breaker
+}
+
+// Run watches the credentials file and reconnects after the breaker trips until ctx is canceled
+func (h *Holder) Run(ctx context.Context) {
+	interval := h.config.ReloadInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if h.config.CredentialsFile == "" {
+				continue
+			}
+			if err := h.reloadCredentials(); err != nil {
+				log.Printf("Error reloading Omni credentials: %v", err)
+			}
+		case <-h.reconnect:
+			log.Println("Omni circuit breaker opened, re-establishing Omni client")
+			if err := h.Reconnect(); err != nil {
+				log.Printf("Error re-establishing Omni client: %v", err)
+			}
+		}
+	}
+}
+
+// Reconnect rebuilds the Omni client with the current credentials
+func (h *Holder) Reconnect() error {
+	if h.config.CredentialsFile != "" {
+		key, err := readServiceAccountKey(h.config.CredentialsFile)
+		if err != nil {
+			return err
+		}
+		return h.rebuild(key)
+	}
+
+	c, err := NewOmniClient()
+	if err != nil {
+		return err
+	}
+	h.swap(c)
+	return nil
+}
+
+// Close closes the current Omni client
+func (h *Holder) Close() error {
+	return h.current().client.Close()
+}
+
+func (h *Holder) current() *holderEntry {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.entry
+}
+
+// reloadCredentials rebuilds the client if the credentials file changed since the last load
+func (h *Holder) reloadCredentials() error {
+	key, err := readServiceAccountKey(h.config.CredentialsFile)
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		return fmt.Errorf("service account key file %s is empty", h.config.CredentialsFile)
+	}
+
+	h.mu.RLock()
+	unchanged := h.entry != nil && h.keyDigest == sha256.Sum256([]byte(key))
+	h.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	if h.current() != nil {
+		log.Printf("Omni credentials in %s changed, rebuilding client", h.config.CredentialsFile)
+	}
+	return h.rebuild(key)
+}
+
+func (h *Holder) rebuild(key string) error {
+	c, err := h.newClient(key)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	h.keyDigest = sha256.Sum256([]byte(key))
+	h.mu.Unlock()
+
+	h.swap(c)
+	return nil
+}
+
+// swap installs a new client and closes the previous one after the drain timeout
+func (h *Holder) swap(c *client.Client) {
+	h.mu.Lock()
+	old := h.entry
+	h.entry = &holderEntry{client: c, state: c.Omni().State()}
+	h.mu.Unlock()
+
+	if old == nil {
+		return
+	}
+
+	time.AfterFunc(h.config.DrainTimeout, func() {
+		if err := old.client.Close(); err != nil {
+			log.Printf("Error closing replaced Omni client: %v", err)
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/siderolabs/omni/client/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHolder_ReloadsChangedCredentials(t *testing.T) {
+	os.Setenv("OMNI_ENDPOINT", "http://localhost:8080")
+	keyFile := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(keyFile, []byte("eyJmb28iOiJiYXIifQ==\n"), 0o600))
+
+	var keys []string
+	h := &Holder{
+		config:    HolderConfig{CredentialsFile: keyFile},
+		breaker:   NewBreaker(BreakerConfig{}),
+		reconnect: make(chan struct{}, 1),
+		newClient: func(serviceAccount string) (*client.Client, error) {
+			keys = append(keys, serviceAccount)
+			return client.New("http://localhost:8080")
+		},
+	}
+
+	require.NoError(t, h.reloadCredentials())
+	first := h.Client()
+	assert.NotNil(t, first)
+
+	// Unchanged key does not rebuild the client
+	require.NoError(t, h.reloadCredentials())
+	assert.Same(t, first, h.Client())
+
+	require.NoError(t, os.WriteFile(keyFile, []byte("eyJiYXoiOiJxdXgifQ=="), 0o600))
+	require.NoError(t, h.reloadCredentials())
+	assert.NotSame(t, first, h.Client())
+	assert.Equal(t, []string{"eyJmb28iOiJiYXIifQ==", "eyJiYXoiOiJxdXgifQ=="}, keys)
+
+	h.Close()
+}
+
+func TestHolder_EmptyCredentialsFile(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(keyFile, nil, 0o600))
+
+	_, err := NewHolder(HolderConfig{CredentialsFile: keyFile})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "is empty")
+}
@@ -3,142 +3,1439 @@ package client
 import (
 	"context"
 	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+	"time"
 
-	"github.com/siderolabs/omni/client/pkg/client"
+	"github.com/blang/semver/v4"
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/safe"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/jubblin/omni-api/internal/retention"
+	"github.com/siderolabs/omni/client/api/omni/management"
+	"github.com/siderolabs/omni/client/api/omni/specs"
+	"github.com/siderolabs/omni/client/pkg/cosi/labels"
+	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/infra"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"go.yaml.in/yaml/v4"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// destroyTimeout bounds how long torn down resources are waited on before they are destroyed
+const destroyTimeout = time.Hour
+
 // ClusterFeatures represents cluster feature flags
 type ClusterFeatures struct {
-	WorkloadProxy bool
+	WorkloadProxy  bool
 	DiskEncryption bool
 }
 
+// BackupRetentionAnnotation records the retention policy of a cluster's etcd backups on the cluster.
+// Omni does not know about retention; the policy is evaluated by this API.
+const BackupRetentionAnnotation = "omni-api/backup-retention"
+
+// ClusterBackup configures the etcd backups Omni takes of a cluster
+type ClusterBackup struct {
+	Enabled   bool
+	Interval  time.Duration     // Required when enabled
+	Retention *retention.Policy // Left unchanged when nil; a zero policy removes it
+}
+
 // MachineSetUpdates represents updates to a machine set
 type MachineSetUpdates struct {
-	MachineClass   string
-	MachineCount   uint32
-	UpdateStrategy string
-	DeleteStrategy string
+	MachineClass         string
+	MachineCount         uint32
+	UpdateStrategy       string
+	DeleteStrategy       string
+	UpdateMaxParallelism uint32 // Machines updated at once by the Rolling update strategy
+	DeleteMaxParallelism uint32 // Machines deleted at once by the Rolling delete strategy
 }
 
-// ManagementService defines the interface for Management operations
-// This interface abstracts the actual Management client API
+// MachineClassSpec represents the desired state of a machine class.
+// Exactly one of MatchLabels and AutoProvision must be set.
+type MachineClassSpec struct {
+	MatchLabels   []string               // Omni label selectors; a machine matching any of them belongs to the class
+	AutoProvision *MachineClassProvision // Machines are requested from an infrastructure provider instead
+}
+
+// MachineClassProvision configures how an infrastructure provider creates the machines of a class
+type MachineClassProvision struct {
+	ProviderID   string
+	ProviderData string // Provider specific YAML, e.g. the size of the machines
+	KernelArgs   []string
+}
+
+// ClusterRestore describes the cluster an etcd backup is restored into.
+// The control plane either allocates from a machine class or uses the listed machines.
+type ClusterRestore struct {
+	ClusterID         string
+	KubernetesVersion string // Defaults to the version of the backed up cluster, required if it is not known
+	TalosVersion      string // Defaults to the version of the backed up cluster, required if it is not known
+	Features          *ClusterFeatures
+	ControlPlane      *MachineSetUpdates
+	Machines          []string
+}
+
+// ManagementService defines the interface for Management operations.
+// Write operations return the Change they made; with a WithDryRun context they
+// run all validation and return the Change without writing anything.
 type ManagementService interface {
 	// Cluster operations
-	CreateCluster(ctx context.Context, id, k8sVersion, talosVersion string, features *ClusterFeatures) error
-	UpdateCluster(ctx context.Context, id, k8sVersion, talosVersion string, features *ClusterFeatures) error
-	DeleteCluster(ctx context.Context, id string) error
-	
+	CreateCluster(ctx context.Context, id, k8sVersion, talosVersion string, features *ClusterFeatures, backup *ClusterBackup) (*Change, error)
+	UpdateCluster(ctx context.Context, id, k8sVersion, talosVersion string, features *ClusterFeatures) (*Change, error)
+	DeleteCluster(ctx context.Context, id string) (*Change, error)
+	// SetClusterBackup writes the etcd backup configuration of a cluster
+	SetClusterBackup(ctx context.Context, id string, backup *ClusterBackup) (*Change, error)
+
 	// MachineSet operations
-	CreateMachineSet(ctx context.Context, id, cluster, machineClass string, count uint32, updateStrategy, deleteStrategy string) error
-	UpdateMachineSet(ctx context.Context, id string, updates *MachineSetUpdates) error
-	DeleteMachineSet(ctx context.Context, id string) error
-	
+	CreateMachineSet(ctx context.Context, id, cluster string, spec *MachineSetUpdates) (*Change, error)
+	UpdateMachineSet(ctx context.Context, id string, updates *MachineSetUpdates) (*Change, error)
+	DeleteMachineSet(ctx context.Context, id string) (*Change, error)
+
+	// ScaleMachineSet sets the machine count of a machine set backed by a machine class.
+	// If version is not empty, it must match the current resource version of the machine set.
+	ScaleMachineSet(ctx context.Context, id string, replicas uint32, version string) (*Change, error)
+
+	// AllocateMachines adds machines to a machine set that is not backed by a machine class, all or none
+	AllocateMachines(ctx context.Context, machineSetID string, machineIDs []string) ([]*Change, error)
+
+	// MachineClass operations
+	CreateMachineClass(ctx context.Context, id string, spec *MachineClassSpec) (*Change, error)
+	UpdateMachineClass(ctx context.Context, id string, spec *MachineClassSpec) (*Change, error)
+	DeleteMachineClass(ctx context.Context, id string) (*Change, error)
+
 	// ConfigPatch operations
-	CreateConfigPatch(ctx context.Context, id, cluster, data string) error
-	UpdateConfigPatch(ctx context.Context, id, data string) error
-	DeleteConfigPatch(ctx context.Context, id string) error
-	
+	CreateConfigPatch(ctx context.Context, id, cluster, data string) (*Change, error)
+	UpdateConfigPatch(ctx context.Context, id, data string) (*Change, error)
+	DeleteConfigPatch(ctx context.Context, id string) (*Change, error)
+
 	// Machine operations
-	UpdateMachineLabels(ctx context.Context, machineID string, labels map[string]string) error
-	UpdateMachineExtensions(ctx context.Context, machineID string, extensions []string) error
-	SetMachineMaintenance(ctx context.Context, machineID string, enabled bool) error
-	
+	// UpdateMachineLabels replaces the user labels of a machine; PatchMachineLabels merges them, a nil value deletes the label.
+	// Labels with the omni.sidero.dev/ prefix are reserved for Omni and cannot be written.
+	UpdateMachineLabels(ctx context.Context, machineID string, labels map[string]string) (*Change, error)
+	PatchMachineLabels(ctx context.Context, machineID string, patch map[string]*string) (*Change, error)
+	UpdateMachineExtensions(ctx context.Context, machineID string, extensions []string) (*Change, error)
+	// SetMachineMaintenance takes a machine out of its machine set, which makes Omni reset it into maintenance mode,
+	// or adds it back. machineSetID is the machine set to add it to; if empty, the one it was taken out of.
+	SetMachineMaintenance(ctx context.Context, machineID string, enabled bool, machineSetID string) (*Change, error)
+
 	// Action operations
-	UpgradeKubernetes(ctx context.Context, clusterID, version string) error
-	UpgradeTalos(ctx context.Context, clusterID, version string) error
-	BootstrapCluster(ctx context.Context, clusterID string) error
-	CreateEtcdManualBackup(ctx context.Context, clusterID string) error
-	TeardownMachineSet(ctx context.Context, machineSetID string) error
+	UpgradeKubernetes(ctx context.Context, clusterID, version string) (*Change, error)
+	UpgradeTalos(ctx context.Context, clusterID, version string) (*Change, error)
+	// RevertKubernetesUpgrade and RevertTalosUpgrade set a cluster back to the version it ran before an upgrade.
+	// Omni only offers newer versions as upgrades, so the version is not checked against them.
+	RevertKubernetesUpgrade(ctx context.Context, clusterID, version string) (*Change, error)
+	RevertTalosUpgrade(ctx context.Context, clusterID, version string) (*Change, error)
+	// KubernetesUpgradePreChecks runs Omni's checks for upgrading a cluster to a Kubernetes version,
+	// e.g. for resources using APIs the version removes. It returns why the upgrade is unsafe, or "" if it passes.
+	KubernetesUpgradePreChecks(ctx context.Context, clusterID, version string) (string, error)
+	BootstrapCluster(ctx context.Context, clusterID string) (*Change, error)
+	CreateEtcdManualBackup(ctx context.Context, clusterID string) (*Change, error)
+	// RestoreEtcdBackup creates a cluster whose control plane Omni bootstraps from an etcd backup,
+	// with the secrets of the backed up cluster. The changes are the cluster, its control plane machine set and machine set nodes.
+	RestoreEtcdBackup(ctx context.Context, backupID string, restore *ClusterRestore) ([]*Change, error)
+	TeardownMachineSet(ctx context.Context, machineSetID string) (*Change, error)
 }
 
-// managementService implements ManagementService
-// This wraps the actual Management client and will be implemented once API is known
+// managementService implements ManagementService by writing Omni resources through COSI state
 type managementService struct {
-	client interface{} // Will be *management.Client once we know the type
+	source ClientSource // Resolved on every call so rebuilt clients are picked up
 }
 
 // NewManagementService creates a new ManagementService wrapper
-func NewManagementService(c *client.Client) ManagementService {
+func NewManagementService(src ClientSource) ManagementService {
 	return &managementService{
-		client: c.Management(),
+		source: src,
 	}
 }
 
-// Implementation methods - these will call the actual Management client API
-// TODO: Implement once actual API methods are known
+func (m *managementService) state() state.State {
+	return m.source.State()
+}
+
+func (m *managementService) CreateCluster(ctx context.Context, id, k8sVersion, talosVersion string, features *ClusterFeatures, backup *ClusterBackup) (*Change, error) {
+	st := m.state()
+
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "cluster ID is required")
+	}
+	if err := ensureAbsent(ctx, st, omni.NewCluster(omniresources.DefaultNamespace, id).Metadata()); err != nil {
+		return nil, err
+	}
+	if err := validateVersions(ctx, st, talosVersion, k8sVersion); err != nil {
+		return nil, err
+	}
+
+	desired := newCluster(id, k8sVersion, talosVersion, features)
+	if backup != nil {
+		if err := applyClusterBackup(ctx, st, desired, backup); err != nil {
+			return nil, err
+		}
+	}
 
-func (m *managementService) CreateCluster(ctx context.Context, id, k8sVersion, talosVersion string, features *ClusterFeatures) error {
-	// TODO: Implement actual API call
-	// Example:
-	// mgmtClient := m.client.(*management.Client)
-	// req := &management.CreateClusterRequest{...}
-	// _, err := mgmtClient.CreateCluster(ctx, req)
-	// return err
-	
-	return fmt.Errorf("CreateCluster not yet implemented - Management API integration needed")
+	return m.apply(ctx, st, &Change{Action: ChangeCreate, Desired: desired})
 }
 
-func (m *managementService) UpdateCluster(ctx context.Context, id, k8sVersion, talosVersion string, features *ClusterFeatures) error {
-	return fmt.Errorf("UpdateCluster not yet implemented - Management API integration needed")
+func newCluster(id, k8sVersion, talosVersion string, features *ClusterFeatures) *omni.Cluster {
+	cluster := omni.NewCluster(omniresources.DefaultNamespace, id)
+	spec := cluster.TypedSpec().Value
+	spec.KubernetesVersion = trimVersion(k8sVersion)
+	spec.TalosVersion = trimVersion(talosVersion)
+	if features != nil {
+		spec.Features = &specs.ClusterSpec_Features{
+			EnableWorkloadProxy: features.WorkloadProxy,
+			DiskEncryption:      features.DiskEncryption,
+		}
+	}
+	return cluster
 }
 
-func (m *managementService) DeleteCluster(ctx context.Context, id string) error {
-	return fmt.Errorf("DeleteCluster not yet implemented - Management API integration needed")
+func (m *managementService) UpdateCluster(ctx context.Context, id, k8sVersion, talosVersion string, features *ClusterFeatures) (*Change, error) {
+	st := m.state()
+
+	current, err := getResource[*omni.Cluster](ctx, st, omni.NewCluster(omniresources.DefaultNamespace, id).Metadata())
+	if err != nil {
+		return nil, err
+	}
+
+	desired := current.DeepCopy().(*omni.Cluster) //nolint:forcetypeassert
+	spec := desired.TypedSpec().Value
+	if k8sVersion != "" {
+		spec.KubernetesVersion = trimVersion(k8sVersion)
+	}
+	if talosVersion != "" {
+		spec.TalosVersion = trimVersion(talosVersion)
+	}
+	if features != nil {
+		if features.DiskEncryption != spec.GetFeatures().GetDiskEncryption() {
+			return nil, status.Error(codes.InvalidArgument, "disk encryption can only be set when the cluster is created")
+		}
+		if spec.Features == nil {
+			spec.Features = &specs.ClusterSpec_Features{}
+		}
+		spec.Features.EnableWorkloadProxy = features.WorkloadProxy
+	}
+
+	if err := validateVersions(ctx, st, spec.TalosVersion, spec.KubernetesVersion); err != nil {
+		return nil, err
+	}
+
+	return m.apply(ctx, st, &Change{Action: ChangeUpdate, Current: current, Desired: desired})
 }
 
-func (m *managementService) CreateMachineSet(ctx context.Context, id, cluster, machineClass string, count uint32, updateStrategy, deleteStrategy string) error {
-	return fmt.Errorf("CreateMachineSet not yet implemented - Management API integration needed")
+func (m *managementService) SetClusterBackup(ctx context.Context, id string, backup *ClusterBackup) (*Change, error) {
+	st := m.state()
+
+	current, err := getResource[*omni.Cluster](ctx, st, omni.NewCluster(omniresources.DefaultNamespace, id).Metadata())
+	if err != nil {
+		return nil, err
+	}
+	if backup == nil {
+		return nil, status.Error(codes.InvalidArgument, "backup configuration is required")
+	}
+
+	desired := current.DeepCopy().(*omni.Cluster) //nolint:forcetypeassert
+	if err := applyClusterBackup(ctx, st, desired, backup); err != nil {
+		return nil, err
+	}
+
+	return m.apply(ctx, st, &Change{Action: ChangeUpdate, Current: current, Desired: desired})
 }
 
-func (m *managementService) UpdateMachineSet(ctx context.Context, id string, updates *MachineSetUpdates) error {
-	return fmt.Errorf("UpdateMachineSet not yet implemented - Management API integration needed")
+// applyClusterBackup validates a backup configuration and writes it to the cluster spec and retention annotation
+func applyClusterBackup(ctx context.Context, st state.State, cluster *omni.Cluster, backup *ClusterBackup) error {
+	if backup.Interval < 0 {
+		return status.Error(codes.InvalidArgument, "backup interval must not be negative")
+	}
+	if backup.Enabled {
+		if backup.Interval == 0 {
+			return status.Error(codes.InvalidArgument, "backup interval is required when backups are enabled")
+		}
+		if err := ensureBackupStore(ctx, st); err != nil {
+			return err
+		}
+	}
+
+	conf := &specs.EtcdBackupConf{Enabled: backup.Enabled}
+	if backup.Interval > 0 {
+		conf.Interval = durationpb.New(backup.Interval)
+	}
+	cluster.TypedSpec().Value.BackupConfiguration = conf
+
+	switch {
+	case backup.Retention == nil:
+	case backup.Retention.IsZero():
+		cluster.Metadata().Annotations().Delete(BackupRetentionAnnotation)
+	default:
+		if err := backup.Retention.Validate(); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		cluster.Metadata().Annotations().Set(BackupRetentionAnnotation, backup.Retention.String())
+	}
+	return nil
 }
 
-func (m *managementService) DeleteMachineSet(ctx context.Context, id string) error {
-	return fmt.Errorf("DeleteMachineSet not yet implemented - Management API integration needed")
+// ensureBackupStore returns a FailedPrecondition status error if Omni has no usable etcd backup store
+func ensureBackupStore(ctx context.Context, st state.State) error {
+	store, err := safe.StateGet[*omni.EtcdBackupStoreStatus](ctx, st, omni.NewEtcdBackupStoreStatus().Metadata())
+	if state.IsNotFoundError(err) {
+		return nil
+	}
+	if err != nil {
+		return stateError(err)
+	}
+
+	spec := store.TypedSpec().Value
+	switch {
+	case spec.ConfigurationError != "":
+		return status.Errorf(codes.FailedPrecondition, "the etcd backup store of Omni is misconfigured: %s", spec.ConfigurationError)
+	case spec.ConfigurationName == "disabled":
+		return status.Error(codes.FailedPrecondition, "etcd backups are disabled in Omni, no backup store is configured")
+	}
+	return nil
+}
+
+// ClusterBackupRetention returns the backup retention policy recorded on a cluster, if any
+func ClusterBackupRetention(cluster *omni.Cluster) (retention.Policy, bool, error) {
+	value, ok := cluster.Metadata().Annotations().Get(BackupRetentionAnnotation)
+	if !ok {
+		return retention.Policy{}, false, nil
+	}
+	policy, err := retention.ParsePolicy(value)
+	if err != nil {
+		return retention.Policy{}, false, fmt.Errorf("invalid %s annotation on cluster %s: %w", BackupRetentionAnnotation, cluster.Metadata().ID(), err)
+	}
+	return policy, true, nil
 }
 
-func (m *managementService) CreateConfigPatch(ctx context.Context, id, cluster, data string) error {
-	return fmt.Errorf("CreateConfigPatch not yet implemented - Management API integration needed")
+func (m *managementService) DeleteCluster(ctx context.Context, id string) (*Change, error) {
+	st := m.state()
+
+	current, err := getResource[*omni.Cluster](ctx, st, omni.NewCluster(omniresources.DefaultNamespace, id).Metadata())
+	if err != nil {
+		return nil, err
+	}
+
+	// The cluster's machine sets and config patches go with it, machine sets first
+	clusterLabel := state.WithLabelQuery(resource.LabelEqual(omni.LabelCluster, id))
+
+	var cascade []resource.Pointer
+	for _, resourceType := range []resource.Type{omni.MachineSetNodeType, omni.MachineSetType, omni.ConfigPatchType} {
+		pointers, err := listPointers(ctx, st, resource.NewMetadata(omniresources.DefaultNamespace, resourceType, "", resource.VersionUndefined), clusterLabel)
+		if err != nil {
+			return nil, err
+		}
+		cascade = append(cascade, pointers...)
+	}
+
+	return m.apply(ctx, st, &Change{Action: ChangeDestroy, Current: current, Cascade: cascade})
 }
 
-func (m *managementService) UpdateConfigPatch(ctx context.Context, id, data string) error {
-	return fmt.Errorf("UpdateConfigPatch not yet implemented - Management API integration needed")
+func (m *managementService) CreateMachineSet(ctx context.Context, id, cluster string, spec *MachineSetUpdates) (*Change, error) {
+	st := m.state()
+
+	if _, err := getResource[*omni.Cluster](ctx, st, omni.NewCluster(omniresources.DefaultNamespace, cluster).Metadata()); err != nil {
+		return nil, err
+	}
+	// Omni derives the machine set role and ownership from its ID
+	if !strings.HasPrefix(id, cluster+"-") {
+		return nil, status.Errorf(codes.InvalidArgument, "machine set ID must start with %q", cluster+"-")
+	}
+	if err := ensureAbsent(ctx, st, omni.NewMachineSet(omniresources.DefaultNamespace, id).Metadata()); err != nil {
+		return nil, err
+	}
+
+	desired := newMachineSet(id, cluster)
+	if err := m.applyMachineSetUpdates(ctx, st, desired, spec); err != nil {
+		return nil, err
+	}
+
+	return m.apply(ctx, st, &Change{Action: ChangeCreate, Desired: desired})
 }
 
-func (m *managementService) DeleteConfigPatch(ctx context.Context, id string) error {
-	return fmt.Errorf("DeleteConfigPatch not yet implemented - Management API integration needed")
+func newMachineSet(id, cluster string) *omni.MachineSet {
+	ms := omni.NewMachineSet(omniresources.DefaultNamespace, id)
+	ms.Metadata().Labels().Set(omni.LabelCluster, cluster)
+	if id == omni.ControlPlanesResourceID(cluster) {
+		ms.Metadata().Labels().Set(omni.LabelControlPlaneRole, "")
+	} else {
+		ms.Metadata().Labels().Set(omni.LabelWorkerRole, "")
+	}
+	return ms
 }
 
-func (m *managementService) UpdateMachineLabels(ctx context.Context, machineID string, labels map[string]string) error {
-	return fmt.Errorf("UpdateMachineLabels not yet implemented - Management API integration needed")
+func (m *managementService) UpdateMachineSet(ctx context.Context, id string, updates *MachineSetUpdates) (*Change, error) {
+	st := m.state()
+
+	current, err := getResource[*omni.MachineSet](ctx, st, omni.NewMachineSet(omniresources.DefaultNamespace, id).Metadata())
+	if err != nil {
+		return nil, err
+	}
+
+	desired := current.DeepCopy().(*omni.MachineSet) //nolint:forcetypeassert
+	if err := m.applyMachineSetUpdates(ctx, st, desired, updates); err != nil {
+		return nil, err
+	}
+
+	return m.apply(ctx, st, &Change{Action: ChangeUpdate, Current: current, Desired: desired})
 }
 
-func (m *managementService) UpdateMachineExtensions(ctx context.Context, machineID string, extensions []string) error {
-	return fmt.Errorf("UpdateMachineExtensions not yet implemented - Management API integration needed")
+// applyMachineSetUpdates validates updates and applies the non-empty fields to ms
+func (m *managementService) applyMachineSetUpdates(ctx context.Context, st state.State, ms *omni.MachineSet, updates *MachineSetUpdates) error {
+	if updates == nil {
+		return nil
+	}
+	spec := ms.TypedSpec().Value
+
+	if updates.MachineClass != "" {
+		if _, err := getResource[*omni.MachineClass](ctx, st, omni.NewMachineClass(omniresources.DefaultNamespace, updates.MachineClass).Metadata()); err != nil {
+			return err
+		}
+		spec.MachineAllocation = &specs.MachineSetSpec_MachineAllocation{Name: updates.MachineClass}
+	}
+	if updates.MachineCount > 0 {
+		if spec.MachineAllocation == nil {
+			return status.Error(codes.InvalidArgument, "machine_count requires a machine class")
+		}
+		spec.MachineAllocation.MachineCount = updates.MachineCount
+		spec.MachineAllocation.AllocationType = specs.MachineSetSpec_MachineAllocation_Static
+	}
+
+	if updates.UpdateStrategy != "" {
+		strategy, err := parseStrategy(updates.UpdateStrategy)
+		if err != nil {
+			return err
+		}
+		spec.UpdateStrategy = strategy
+	}
+	if updates.DeleteStrategy != "" {
+		strategy, err := parseStrategy(updates.DeleteStrategy)
+		if err != nil {
+			return err
+		}
+		spec.DeleteStrategy = strategy
+	}
+
+	var err error
+	if spec.UpdateStrategyConfig, err = strategyConfig("update", spec.UpdateStrategy, spec.UpdateStrategyConfig, updates.UpdateMaxParallelism); err != nil {
+		return err
+	}
+	if spec.DeleteStrategyConfig, err = strategyConfig("delete", spec.DeleteStrategy, spec.DeleteStrategyConfig, updates.DeleteMaxParallelism); err != nil {
+		return err
+	}
+
+	return nil
 }
 
-func (m *managementService) SetMachineMaintenance(ctx context.Context, machineID string, enabled bool) error {
-	return fmt.Errorf("SetMachineMaintenance not yet implemented - Management API integration needed")
+// strategyConfig returns the strategy config for a strategy after maxParallelism is applied.
+// Only the Rolling strategy is configurable, so the config of other strategies is dropped.
+func strategyConfig(kind string, strategy specs.MachineSetSpec_UpdateStrategy, config *specs.MachineSetSpec_UpdateStrategyConfig, maxParallelism uint32) (*specs.MachineSetSpec_UpdateStrategyConfig, error) {
+	if strategy != specs.MachineSetSpec_Rolling {
+		if maxParallelism > 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "%s_max_parallelism requires the Rolling %s strategy", kind, kind)
+		}
+		return nil, nil
+	}
+	if maxParallelism == 0 {
+		return config, nil
+	}
+	return &specs.MachineSetSpec_UpdateStrategyConfig{
+		Rolling: &specs.MachineSetSpec_RollingUpdateStrategyConfig{MaxParallelism: maxParallelism},
+	}, nil
 }
 
-func (m *managementService) UpgradeKubernetes(ctx context.Context, clusterID, version string) error {
-	return fmt.Errorf("UpgradeKubernetes not yet implemented - Management API integration needed")
+func (m *managementService) DeleteMachineSet(ctx context.Context, id string) (*Change, error) {
+	st := m.state()
+
+	current, err := getResource[*omni.MachineSet](ctx, st, omni.NewMachineSet(omniresources.DefaultNamespace, id).Metadata())
+	if err != nil {
+		return nil, err
+	}
+
+	cascade, err := listPointers(ctx, st,
+		resource.NewMetadata(omniresources.DefaultNamespace, omni.MachineSetNodeType, "", resource.VersionUndefined),
+		state.WithLabelQuery(resource.LabelEqual(omni.LabelMachineSet, id)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.apply(ctx, st, &Change{Action: ChangeDestroy, Current: current, Cascade: cascade})
 }
 
-func (m *managementService) UpgradeTalos(ctx context.Context, clusterID, version string) error {
-	return fmt.Errorf("UpgradeTalos not yet implemented - Management API integration needed")
+func (m *managementService) ScaleMachineSet(ctx context.Context, id string, replicas uint32, version string) (*Change, error) {
+	st := m.state()
+
+	current, err := getResource[*omni.MachineSet](ctx, st, omni.NewMachineSet(omniresources.DefaultNamespace, id).Metadata())
+	if err != nil {
+		return nil, err
+	}
+	desired := current.DeepCopy().(*omni.MachineSet) //nolint:forcetypeassert
+	if err := scaleMachineSet(desired, replicas, version); err != nil {
+		return nil, err
+	}
+	if desired.TypedSpec().Value.MachineAllocation.MachineCount == current.TypedSpec().Value.MachineAllocation.MachineCount {
+		return &Change{Action: ChangeNone, Current: current, Desired: desired}, nil
+	}
+
+	return m.apply(ctx, st, &Change{Action: ChangeUpdate, Current: current, Desired: desired})
 }
 
-func (m *managementService) BootstrapCluster(ctx context.Context, clusterID string) error {
-	return fmt.Errorf("BootstrapCluster not yet implemented - Management API integration needed")
+// scaleMachineSet sets the machine count of ms after checking that it can be scaled
+func scaleMachineSet(ms *omni.MachineSet, replicas uint32, version string) error {
+	if version != "" && version != ms.Metadata().Version().String() {
+		return status.Errorf(codes.Aborted, "machine set %s is at version %s, not %s", ms.Metadata().ID(), ms.Metadata().Version(), version)
+	}
+
+	allocation := ms.TypedSpec().Value.MachineAllocation
+	switch {
+	case allocation == nil:
+		return status.Errorf(codes.FailedPrecondition, "machine set %s has manually allocated machines, add them with the allocate endpoint instead", ms.Metadata().ID())
+	case allocation.AllocationType == specs.MachineSetSpec_MachineAllocation_Unlimited:
+		return status.Errorf(codes.FailedPrecondition, "machine set %s takes every machine of machine class %s and cannot be scaled", ms.Metadata().ID(), allocation.Name)
+	}
+	if _, isControlPlane := ms.Metadata().Labels().Get(omni.LabelControlPlaneRole); isControlPlane && replicas == 0 {
+		return status.Error(codes.InvalidArgument, "control plane machine sets need at least one machine")
+	}
+
+	allocation.MachineCount = replicas
+	return nil
+}
+
+func (m *managementService) AllocateMachines(ctx context.Context, machineSetID string, machineIDs []string) ([]*Change, error) {
+	return allocateMachines(ctx, m.state(), machineSetID, machineIDs)
+}
+
+// allocateMachines creates a MachineSetNode for every machine. If a node cannot be created,
+// the nodes created before it are destroyed again, so either all machines are allocated or none.
+func allocateMachines(ctx context.Context, st state.State, machineSetID string, machineIDs []string) ([]*Change, error) {
+	if len(machineIDs) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no machines to allocate")
+	}
+
+	machineSet, err := getResource[*omni.MachineSet](ctx, st, omni.NewMachineSet(omniresources.DefaultNamespace, machineSetID).Metadata())
+	if err != nil {
+		return nil, err
+	}
+	if allocation := machineSet.TypedSpec().Value.MachineAllocation; allocation != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "machine set %s allocates machines from machine class %s, change its machine count instead", machineSetID, allocation.Name)
+	}
+
+	changes, err := machineSetNodeChanges(ctx, st, machineSet, machineIDs)
+	if err != nil {
+		return nil, err
+	}
+	if IsDryRun(ctx) {
+		return changes, nil
+	}
+	if err := createAll(ctx, st, changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// machineSetNodeChanges validates that the machines exist and are free, and returns the MachineSetNodes adding them to machineSet
+func machineSetNodeChanges(ctx context.Context, st state.State, machineSet *omni.MachineSet, machineIDs []string) ([]*Change, error) {
+	changes := make([]*Change, 0, len(machineIDs))
+	for _, id := range machineIDs {
+		if _, err := getResource[*omni.Machine](ctx, st, omni.NewMachine(omniresources.DefaultNamespace, id).Metadata()); err != nil {
+			return nil, err
+		}
+		if err := ensureAbsent(ctx, st, omni.NewClusterMachine(omniresources.DefaultNamespace, id).Metadata()); err != nil {
+			if status.Code(err) == codes.AlreadyExists {
+				return nil, status.Errorf(codes.FailedPrecondition, "machine %s is already allocated", id)
+			}
+			return nil, err
+		}
+		if err := ensureAbsent(ctx, st, omni.NewMachineSetNode(omniresources.DefaultNamespace, id, machineSet).Metadata()); err != nil {
+			return nil, err
+		}
+		changes = append(changes, &Change{Action: ChangeCreate, Desired: omni.NewMachineSetNode(omniresources.DefaultNamespace, id, machineSet)})
+	}
+	return changes, nil
+}
+
+// createAll creates the desired resources of changes in order. If one cannot be created,
+// the ones created before it are destroyed again, so either all resources are created or none.
+func createAll(ctx context.Context, st state.State, changes []*Change) error {
+	for i, change := range changes {
+		if err := st.Create(ctx, change.Desired); err != nil {
+			for _, created := range slices.Backward(changes[:i]) {
+				if err := st.TeardownAndDestroy(ctx, created.Desired.Metadata()); err != nil && !state.IsNotFoundError(err) {
+					log.Printf("Failed to roll back creation of %s %s: %v", resourceKind(created.Desired.Metadata()), created.Desired.Metadata().ID(), err)
+				}
+			}
+			return stateError(err)
+		}
+	}
+	return nil
+}
+
+func (m *managementService) CreateMachineClass(ctx context.Context, id string, spec *MachineClassSpec) (*Change, error) {
+	st := m.state()
+
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "machine class ID is required")
+	}
+	if err := ensureAbsent(ctx, st, omni.NewMachineClass(omniresources.DefaultNamespace, id).Metadata()); err != nil {
+		return nil, err
+	}
+
+	desired := omni.NewMachineClass(omniresources.DefaultNamespace, id)
+	if err := applyMachineClassSpec(ctx, st, desired, spec); err != nil {
+		return nil, err
+	}
+
+	return m.apply(ctx, st, &Change{Action: ChangeCreate, Desired: desired})
+}
+
+func (m *managementService) UpdateMachineClass(ctx context.Context, id string, spec *MachineClassSpec) (*Change, error) {
+	st := m.state()
+
+	current, err := getResource[*omni.MachineClass](ctx, st, omni.NewMachineClass(omniresources.DefaultNamespace, id).Metadata())
+	if err != nil {
+		return nil, err
+	}
+
+	desired := current.DeepCopy().(*omni.MachineClass) //nolint:forcetypeassert
+	if err := applyMachineClassSpec(ctx, st, desired, spec); err != nil {
+		return nil, err
+	}
+
+	return m.apply(ctx, st, &Change{Action: ChangeUpdate, Current: current, Desired: desired})
+}
+
+func (m *managementService) DeleteMachineClass(ctx context.Context, id string) (*Change, error) {
+	st := m.state()
+
+	current, err := getResource[*omni.MachineClass](ctx, st, omni.NewMachineClass(omniresources.DefaultNamespace, id).Metadata())
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureMachineClassUnused(ctx, st, id); err != nil {
+		return nil, err
+	}
+
+	return m.apply(ctx, st, &Change{Action: ChangeDestroy, Current: current})
+}
+
+// applyMachineClassSpec validates spec and replaces the spec of mc with it.
+// The auto provision settings the API does not expose, meta values and the gRPC tunnel mode, are kept.
+func applyMachineClassSpec(ctx context.Context, st state.State, mc *omni.MachineClass, spec *MachineClassSpec) error {
+	if spec == nil || (len(spec.MatchLabels) == 0) == (spec.AutoProvision == nil) {
+		return status.Error(codes.InvalidArgument, "exactly one of match_labels and auto_provision is required")
+	}
+
+	value := mc.TypedSpec().Value
+	previous := value.AutoProvision
+	value.MatchLabels, value.AutoProvision = nil, nil
+
+	if len(spec.MatchLabels) > 0 {
+		if _, err := labels.ParseSelectors(spec.MatchLabels); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid match_labels: %v", err)
+		}
+		value.MatchLabels = slices.Clone(spec.MatchLabels)
+		return nil
+	}
+
+	provision := spec.AutoProvision
+	if provision.ProviderID == "" {
+		return status.Error(codes.InvalidArgument, "auto_provision.provider_id is required")
+	}
+	if _, err := getResource[*infra.Provider](ctx, st, infra.NewProvider(provision.ProviderID).Metadata()); err != nil {
+		if status.Code(err) == codes.NotFound {
+			return status.Errorf(codes.InvalidArgument, "unknown infrastructure provider %s", provision.ProviderID)
+		}
+		return err
+	}
+	var providerData map[string]any
+	if err := yaml.Unmarshal([]byte(provision.ProviderData), &providerData); err != nil {
+		return status.Errorf(codes.InvalidArgument, "auto_provision.provider_data is not a YAML object: %v", err)
+	}
+
+	value.AutoProvision = &specs.MachineClassSpec_Provision{
+		ProviderId:   provision.ProviderID,
+		ProviderData: provision.ProviderData,
+		KernelArgs:   slices.Clone(provision.KernelArgs),
+		MetaValues:   previous.GetMetaValues(),
+		GrpcTunnel:   previous.GetGrpcTunnel(),
+	}
+	return nil
+}
+
+// ensureMachineClassUnused returns a FailedPrecondition status error if machine sets allocate machines from the class
+func ensureMachineClassUnused(ctx context.Context, st state.State, id string) error {
+	machineSets, err := safe.StateListAll[*omni.MachineSet](ctx, st)
+	if err != nil {
+		return stateError(err)
+	}
+
+	var users []string
+	for ms := range machineSets.All() {
+		if ms.TypedSpec().Value.GetMachineAllocation().GetName() == id {
+			users = append(users, ms.Metadata().ID())
+		}
+	}
+	if len(users) > 0 {
+		return status.Errorf(codes.FailedPrecondition, "machine class %s is used by machine sets %s", id, strings.Join(users, ", "))
+	}
+	return nil
+}
+
+// ManagedByLabel on config patches records the writer that manages them, set by writes with WithManagedBy
+const ManagedByLabel = "omni-api/managed-by"
+
+func (m *managementService) CreateConfigPatch(ctx context.Context, id, cluster, data string) (*Change, error) {
+	st := m.state()
+
+	if _, err := getResource[*omni.Cluster](ctx, st, omni.NewCluster(omniresources.DefaultNamespace, cluster).Metadata()); err != nil {
+		return nil, err
+	}
+	if err := ensureAbsent(ctx, st, omni.NewConfigPatch(omniresources.DefaultNamespace, id).Metadata()); err != nil {
+		return nil, err
+	}
+	if err := m.validateConfig(ctx, data); err != nil {
+		return nil, err
+	}
+
+	desired := omni.NewConfigPatch(omniresources.DefaultNamespace, id)
+	desired.Metadata().Labels().Set(omni.LabelCluster, cluster)
+	if owner := ManagedBy(ctx); owner != "" {
+		desired.Metadata().Labels().Set(ManagedByLabel, owner)
+	}
+	if err := desired.TypedSpec().Value.SetUncompressedData([]byte(data)); err != nil {
+		return nil, fmt.Errorf("failed to set config patch data: %w", err)
+	}
+
+	return m.apply(ctx, st, &Change{Action: ChangeCreate, Desired: desired})
+}
+
+func (m *managementService) UpdateConfigPatch(ctx context.Context, id, data string) (*Change, error) {
+	st := m.state()
+
+	current, err := getResource[*omni.ConfigPatch](ctx, st, omni.NewConfigPatch(omniresources.DefaultNamespace, id).Metadata())
+	if err != nil {
+		return nil, err
+	}
+	if err := m.validateConfig(ctx, data); err != nil {
+		return nil, err
+	}
+
+	desired := current.DeepCopy().(*omni.ConfigPatch) //nolint:forcetypeassert
+	if owner := ManagedBy(ctx); owner != "" {
+		desired.Metadata().Labels().Set(ManagedByLabel, owner)
+	}
+	if err := desired.TypedSpec().Value.SetUncompressedData([]byte(data)); err != nil {
+		return nil, fmt.Errorf("failed to set config patch data: %w", err)
+	}
+
+	return m.apply(ctx, st, &Change{Action: ChangeUpdate, Current: current, Desired: desired})
+}
+
+func (m *managementService) DeleteConfigPatch(ctx context.Context, id string) (*Change, error) {
+	st := m.state()
+
+	current, err := getResource[*omni.ConfigPatch](ctx, st, omni.NewConfigPatch(omniresources.DefaultNamespace, id).Metadata())
+	if err != nil {
+		return nil, err
+	}
+
+	return m.apply(ctx, st, &Change{Action: ChangeDestroy, Current: current})
+}
+
+func (m *managementService) UpdateMachineLabels(ctx context.Context, machineID string, labels map[string]string) (*Change, error) {
+	patch := make(map[string]*string, len(labels))
+	for key, value := range labels {
+		patch[key] = &value
+	}
+	if err := validateUserLabels(patch); err != nil {
+		return nil, err
+	}
+
+	return m.writeMachineLabels(ctx, m.state(), machineID, replaceLabels(labels))
+}
+
+func (m *managementService) PatchMachineLabels(ctx context.Context, machineID string, patch map[string]*string) (*Change, error) {
+	if err := validateUserLabels(patch); err != nil {
+		return nil, err
+	}
+
+	return m.writeMachineLabels(ctx, m.state(), machineID, mergeLabels(patch))
 }
 
-func (m *managementService) CreateEtcdManualBackup(ctx context.Context, clusterID string) error {
-	return fmt.Errorf("CreateEtcdManualBackup not yet implemented - Management API integration needed")
+// replaceLabels replaces all labels except the ones reserved for Omni
+func replaceLabels(labels map[string]string) func(*resource.Labels) {
+	return func(current *resource.Labels) {
+		for key := range current.Raw() {
+			if !strings.HasPrefix(key, omni.SystemLabelPrefix) {
+				current.Delete(key)
+			}
+		}
+		for _, key := range slices.Sorted(maps.Keys(labels)) {
+			current.Set(key, labels[key])
+		}
+	}
+}
+
+// mergeLabels applies patch like a JSON merge patch: nil values delete labels, others are set
+func mergeLabels(patch map[string]*string) func(*resource.Labels) {
+	return func(current *resource.Labels) {
+		for _, key := range slices.Sorted(maps.Keys(patch)) {
+			if value := patch[key]; value != nil {
+				current.Set(key, *value)
+			} else {
+				current.Delete(key)
+			}
+		}
+	}
+}
+
+// validateUserLabels rejects empty keys and the labels Omni reserves for itself
+func validateUserLabels(labels map[string]*string) error {
+	for key := range labels {
+		if key == "" {
+			return status.Error(codes.InvalidArgument, "label keys cannot be empty")
+		}
+		if strings.HasPrefix(key, omni.SystemLabelPrefix) {
+			return status.Errorf(codes.InvalidArgument, "label %s is managed by Omni and cannot be changed", key)
+		}
+	}
+	return nil
+}
+
+// writeMachineLabels applies update to the user labels of a machine, which live on the
+// MachineLabels resource Omni copies to MachineStatus. The resource is created if it does not exist yet.
+func (m *managementService) writeMachineLabels(ctx context.Context, st state.State, machineID string, update func(*resource.Labels)) (*Change, error) {
+	if _, err := getResource[*omni.Machine](ctx, st, omni.NewMachine(omniresources.DefaultNamespace, machineID).Metadata()); err != nil {
+		return nil, err
+	}
+
+	current, err := safe.StateGet[*omni.MachineLabels](ctx, st, omni.NewMachineLabels(omniresources.DefaultNamespace, machineID).Metadata())
+	if err != nil && !state.IsNotFoundError(err) {
+		return nil, stateError(err)
+	}
+
+	var desired *omni.MachineLabels
+	change := &Change{Action: ChangeCreate}
+	if current != nil {
+		desired = current.DeepCopy().(*omni.MachineLabels) //nolint:forcetypeassert
+		change = &Change{Action: ChangeUpdate, Current: current}
+	} else {
+		desired = omni.NewMachineLabels(omniresources.DefaultNamespace, machineID)
+	}
+	update(desired.Metadata().Labels())
+	change.Desired = desired
+
+	unchanged := desired.Metadata().Labels().Empty()
+	if current != nil {
+		unchanged = current.Metadata().Labels().Equal(*desired.Metadata().Labels())
+	}
+	if unchanged {
+		change.Action = ChangeNone
+		return change, nil
+	}
+
+	return m.apply(ctx, st, change)
+}
+
+func (m *managementService) UpdateMachineExtensions(ctx context.Context, machineID string, extensions []string) (*Change, error) {
+	return nil, status.Error(codes.Unimplemented, "updating machine extensions is not supported yet")
+}
+
+// MaintenanceMachineSetAnnotation on MachineLabels records the machine set a machine was taken out of for maintenance
+const MaintenanceMachineSetAnnotation = "omni-api/maintenance-machine-set"
+
+func (m *managementService) SetMachineMaintenance(ctx context.Context, machineID string, enabled bool, machineSetID string) (*Change, error) {
+	return m.setMachineMaintenance(ctx, m.state(), machineID, enabled, machineSetID)
+}
+
+func (m *managementService) setMachineMaintenance(ctx context.Context, st state.State, machineID string, enabled bool, machineSetID string) (*Change, error) {
+	if _, err := getResource[*omni.Machine](ctx, st, omni.NewMachine(omniresources.DefaultNamespace, machineID).Metadata()); err != nil {
+		return nil, err
+	}
+	node, err := safe.StateGet[*omni.MachineSetNode](ctx, st, resource.NewMetadata(omniresources.DefaultNamespace, omni.MachineSetNodeType, machineID, resource.VersionUndefined))
+	if err != nil && !state.IsNotFoundError(err) {
+		return nil, stateError(err)
+	}
+	machineLabels, err := safe.StateGet[*omni.MachineLabels](ctx, st, omni.NewMachineLabels(omniresources.DefaultNamespace, machineID).Metadata())
+	if err != nil && !state.IsNotFoundError(err) {
+		return nil, stateError(err)
+	}
+
+	if enabled {
+		if machineSetID != "" {
+			return nil, status.Error(codes.InvalidArgument, "a machine set can only be given when leaving maintenance mode")
+		}
+		if node == nil {
+			// Machines outside of machine sets already run in maintenance mode
+			return &Change{Action: ChangeNone}, nil
+		}
+
+		current, _ := node.Metadata().Labels().Get(omni.LabelMachineSet)
+		if node.Metadata().Owner() != "" {
+			return nil, status.Errorf(codes.FailedPrecondition, "machine set %s allocates its machines from a machine class, scale it instead", current)
+		}
+		if err := ensureControlPlaneQuorum(ctx, st, node); err != nil {
+			return nil, err
+		}
+
+		change, err := m.apply(ctx, st, &Change{Action: ChangeDestroy, Current: node})
+		if err != nil || IsDryRun(ctx) {
+			return change, err
+		}
+		// Recorded once the machine left its machine set, so a failed teardown leaves no record behind
+		if err := setMaintenanceMachineSet(ctx, st, machineID, machineLabels, current); err != nil {
+			return nil, fmt.Errorf("machine %s left machine set %s, but recording it failed, pass the machine set when leaving maintenance mode: %w", machineID, current, err)
+		}
+		return change, nil
+	}
+
+	if node != nil {
+		if current, _ := node.Metadata().Labels().Get(omni.LabelMachineSet); machineSetID != "" && machineSetID != current {
+			return nil, status.Errorf(codes.FailedPrecondition, "machine %s is already in machine set %s", machineID, current)
+		}
+		return &Change{Action: ChangeNone, Current: node}, nil
+	}
+	if machineSetID == "" && machineLabels != nil {
+		machineSetID, _ = machineLabels.Metadata().Annotations().Get(MaintenanceMachineSetAnnotation)
+	}
+	if machineSetID == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "machine %s was not taken out of a machine set by this API, a machine set is required", machineID)
+	}
+
+	changes, err := allocateMachines(ctx, st, machineSetID, []string{machineID})
+	if err != nil {
+		return nil, err
+	}
+	if !IsDryRun(ctx) {
+		if err := setMaintenanceMachineSet(ctx, st, machineID, machineLabels, ""); err != nil {
+			return nil, err
+		}
+	}
+	return changes[0], nil
+}
+
+// ensureControlPlaneQuorum returns a FailedPrecondition status error if a machine set node is the last control plane
+// machine of its machine set, or if the other control plane machines that are healthy do not make up an etcd quorum
+func ensureControlPlaneQuorum(ctx context.Context, st state.State, node *omni.MachineSetNode) error {
+	if _, isControlPlane := node.Metadata().Labels().Get(omni.LabelControlPlaneRole); !isControlPlane {
+		return nil
+	}
+	machineSet, _ := node.Metadata().Labels().Get(omni.LabelMachineSet)
+
+	nodes, err := safe.StateListAll[*omni.MachineSetNode](ctx, st, state.WithLabelQuery(resource.LabelEqual(omni.LabelMachineSet, machineSet)))
+	if err != nil {
+		return stateError(err)
+	}
+	if nodes.Len() <= 1 {
+		return status.Errorf(codes.FailedPrecondition, "machine %s is the last control plane machine of machine set %s", node.Metadata().ID(), machineSet)
+	}
+
+	// etcd keeps every member until the machine has left, so the others must make up a quorum of all of them
+	quorum := nodes.Len()/2 + 1
+	var healthy int
+	for other := range nodes.All() {
+		if other.Metadata().ID() == node.Metadata().ID() {
+			continue
+		}
+		machine, err := safe.StateGet[*omni.ClusterMachineStatus](ctx, st, omni.NewClusterMachineStatus(omniresources.DefaultNamespace, other.Metadata().ID()).Metadata())
+		if state.IsNotFoundError(err) {
+			continue
+		}
+		if err != nil {
+			return stateError(err)
+		}
+		if spec := machine.TypedSpec().Value; spec.Ready && spec.Stage == specs.ClusterMachineStatusSpec_RUNNING {
+			healthy++
+		}
+	}
+	if healthy < quorum {
+		return status.Errorf(codes.FailedPrecondition, "etcd quorum of machine set %s needs %d healthy control plane machines besides %s, %d are healthy", machineSet, quorum, node.Metadata().ID(), healthy)
+	}
+	return nil
+}
+
+// setMaintenanceMachineSet records the machine set a machine was taken out of, or forgets it if machineSetID is empty
+func setMaintenanceMachineSet(ctx context.Context, st state.State, machineID string, current *omni.MachineLabels, machineSetID string) error {
+	if current == nil {
+		if machineSetID == "" {
+			return nil
+		}
+		desired := omni.NewMachineLabels(omniresources.DefaultNamespace, machineID)
+		desired.Metadata().Annotations().Set(MaintenanceMachineSetAnnotation, machineSetID)
+		return stateError(st.Create(ctx, desired))
+	}
+
+	desired := current.DeepCopy().(*omni.MachineLabels) //nolint:forcetypeassert
+	if machineSetID == "" {
+		desired.Metadata().Annotations().Delete(MaintenanceMachineSetAnnotation)
+	} else {
+		desired.Metadata().Annotations().Set(MaintenanceMachineSetAnnotation, machineSetID)
+	}
+	if desired.Metadata().Annotations().Equal(*current.Metadata().Annotations()) {
+		return nil
+	}
+	return stateError(st.Update(ctx, desired))
+}
+
+func (m *managementService) UpgradeKubernetes(ctx context.Context, clusterID, version string) (*Change, error) {
+	return m.upgradeKubernetes(ctx, m.state(), clusterID, version)
+}
+
+func (m *managementService) upgradeKubernetes(ctx context.Context, st state.State, clusterID, version string) (*Change, error) {
+	current, err := getResource[*omni.Cluster](ctx, st, omni.NewCluster(omniresources.DefaultNamespace, clusterID).Metadata())
+	if err != nil {
+		return nil, err
+	}
+
+	version = trimVersion(version)
+	if version != current.TypedSpec().Value.KubernetesVersion {
+		upgrade, err := getResource[*omni.KubernetesUpgradeStatus](ctx, st, omni.NewKubernetesUpgradeStatus(omniresources.DefaultNamespace, clusterID).Metadata())
+		if err != nil {
+			return nil, err
+		}
+		if available := upgrade.TypedSpec().Value.UpgradeVersions; !slices.Contains(available, version) {
+			return nil, status.Errorf(codes.InvalidArgument, "cannot upgrade Kubernetes to %s, available versions: %s", version, strings.Join(available, ", "))
+		}
+		if err := validateVersions(ctx, st, current.TypedSpec().Value.TalosVersion, version); err != nil {
+			return nil, err
+		}
+	}
+
+	desired := current.DeepCopy().(*omni.Cluster) //nolint:forcetypeassert
+	desired.TypedSpec().Value.KubernetesVersion = version
+
+	return m.apply(ctx, st, &Change{Action: ChangeUpdate, Current: current, Desired: desired})
+}
+
+func (m *managementService) UpgradeTalos(ctx context.Context, clusterID, version string) (*Change, error) {
+	st := m.state()
+
+	current, err := getResource[*omni.Cluster](ctx, st, omni.NewCluster(omniresources.DefaultNamespace, clusterID).Metadata())
+	if err != nil {
+		return nil, err
+	}
+
+	version = trimVersion(version)
+	if version != current.TypedSpec().Value.TalosVersion {
+		upgrade, err := getResource[*omni.TalosUpgradeStatus](ctx, st, omni.NewTalosUpgradeStatus(omniresources.DefaultNamespace, clusterID).Metadata())
+		if err != nil {
+			return nil, err
+		}
+		if available := upgrade.TypedSpec().Value.UpgradeVersions; !slices.Contains(available, version) {
+			return nil, status.Errorf(codes.InvalidArgument, "cannot upgrade Talos to %s, available versions: %s", version, strings.Join(available, ", "))
+		}
+	}
+	if err := validateVersions(ctx, st, version, current.TypedSpec().Value.KubernetesVersion); err != nil {
+		return nil, err
+	}
+
+	desired := current.DeepCopy().(*omni.Cluster) //nolint:forcetypeassert
+	desired.TypedSpec().Value.TalosVersion = version
+
+	return m.apply(ctx, st, &Change{Action: ChangeUpdate, Current: current, Desired: desired})
+}
+
+func (m *managementService) RevertKubernetesUpgrade(ctx context.Context, clusterID, version string) (*Change, error) {
+	return m.revertUpgrade(ctx, m.state(), clusterID, "Kubernetes", version)
+}
+
+func (m *managementService) RevertTalosUpgrade(ctx context.Context, clusterID, version string) (*Change, error) {
+	return m.revertUpgrade(ctx, m.state(), clusterID, "Talos", version)
+}
+
+// revertUpgrade writes the previous Talos or Kubernetes version back to the cluster spec, which makes Omni roll the upgrade back
+func (m *managementService) revertUpgrade(ctx context.Context, st state.State, clusterID, component, version string) (*Change, error) {
+	if version == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "the %s version to revert to is required", component)
+	}
+
+	current, err := getResource[*omni.Cluster](ctx, st, omni.NewCluster(omniresources.DefaultNamespace, clusterID).Metadata())
+	if err != nil {
+		return nil, err
+	}
+
+	desired := current.DeepCopy().(*omni.Cluster) //nolint:forcetypeassert
+	if component == "Talos" {
+		desired.TypedSpec().Value.TalosVersion = trimVersion(version)
+	} else {
+		desired.TypedSpec().Value.KubernetesVersion = trimVersion(version)
+	}
+
+	return m.apply(ctx, st, &Change{Action: ChangeUpdate, Current: current, Desired: desired})
+}
+
+func (m *managementService) KubernetesUpgradePreChecks(ctx context.Context, clusterID, version string) (string, error) {
+	client := management.NewManagementServiceClient(m.source.Client().Omni())
+
+	// The Management API takes the cluster from the request context
+	ctx = metadata.AppendToOutgoingContext(ctx, "context", clusterID)
+	resp, err := guard(m.source, func() (*management.KubernetesUpgradePreChecksResponse, error) {
+		return client.KubernetesUpgradePreChecks(ctx, &management.KubernetesUpgradePreChecksRequest{NewVersion: trimVersion(version)})
+	})
+	if err != nil {
+		return "", err
+	}
+	if resp.Ok {
+		return "", nil
+	}
+	return resp.Reason, nil
+}
+
+func (m *managementService) BootstrapCluster(ctx context.Context, clusterID string) (*Change, error) {
+	// Omni bootstraps etcd on its own once the first control plane machine is ready,
+	// so there is nothing to write; callers follow ClusterBootstrapStatus instead
+	if _, err := getResource[*omni.Cluster](ctx, m.state(), omni.NewCluster(omniresources.DefaultNamespace, clusterID).Metadata()); err != nil {
+		return nil, err
+	}
+
+	return &Change{Action: ChangeNone}, nil
+}
+
+func (m *managementService) CreateEtcdManualBackup(ctx context.Context, clusterID string) (*Change, error) {
+	st := m.state()
+
+	if _, err := getResource[*omni.Cluster](ctx, st, omni.NewCluster(omniresources.DefaultNamespace, clusterID).Metadata()); err != nil {
+		return nil, err
+	}
+
+	current, err := safe.StateGet[*omni.EtcdManualBackup](ctx, st, omni.NewEtcdManualBackup(clusterID).Metadata())
+	if err != nil && !state.IsNotFoundError(err) {
+		return nil, stateError(err)
+	}
+
+	// Omni takes a backup whenever BackupAt moves forward
+	var desired *omni.EtcdManualBackup
+	change := &Change{Action: ChangeCreate}
+	if current != nil {
+		desired = current.DeepCopy().(*omni.EtcdManualBackup) //nolint:forcetypeassert
+		change = &Change{Action: ChangeUpdate, Current: current}
+	} else {
+		desired = omni.NewEtcdManualBackup(clusterID)
+	}
+	desired.TypedSpec().Value.BackupAt = timestamppb.New(time.Now())
+	change.Desired = desired
+
+	return m.apply(ctx, st, change)
+}
+
+func (m *managementService) RestoreEtcdBackup(ctx context.Context, backupID string, restore *ClusterRestore) ([]*Change, error) {
+	return m.restoreEtcdBackup(ctx, m.state(), backupID, restore)
+}
+
+func (m *managementService) restoreEtcdBackup(ctx context.Context, st state.State, backupID string, restore *ClusterRestore) ([]*Change, error) {
+	if restore == nil || restore.ClusterID == "" {
+		return nil, status.Error(codes.InvalidArgument, "cluster ID is required")
+	}
+	if (restore.ControlPlane != nil && restore.ControlPlane.MachineClass != "") == (len(restore.Machines) > 0) {
+		return nil, status.Error(codes.InvalidArgument, "the control plane needs either a machine class or machines")
+	}
+
+	backup, err := getResource[*omni.EtcdBackup](ctx, st, resource.NewMetadata(omniresources.ExternalNamespace, omni.EtcdBackupType, backupID, resource.VersionUndefined))
+	if err != nil {
+		return nil, err
+	}
+	snapshot := backup.TypedSpec().Value.Snapshot
+	if snapshot == "" {
+		return nil, status.Errorf(codes.FailedPrecondition, "etcd backup %s has no snapshot", backupID)
+	}
+	clusterUUID, source, err := backupSource(ctx, st, backup)
+	if err != nil {
+		return nil, err
+	}
+
+	k8sVersion, talosVersion := trimVersion(restore.KubernetesVersion), trimVersion(restore.TalosVersion)
+	if sourceK8s, sourceTalos, ok := backupVersions(backup, source); ok {
+		if k8sVersion == "" {
+			k8sVersion = sourceK8s
+		}
+		if talosVersion == "" {
+			talosVersion = sourceTalos
+		}
+		if err := checkRestoreVersions(sourceK8s, sourceTalos, k8sVersion, talosVersion); err != nil {
+			return nil, err
+		}
+	} else if k8sVersion == "" || talosVersion == "" {
+		return nil, status.Errorf(codes.FailedPrecondition,
+			"the versions of the cluster etcd backup %s was taken of are not known, both the Kubernetes and the Talos version are required", backupID)
+	}
+
+	if err := ensureAbsent(ctx, st, omni.NewCluster(omniresources.DefaultNamespace, restore.ClusterID).Metadata()); err != nil {
+		return nil, err
+	}
+	if err := validateVersions(ctx, st, talosVersion, k8sVersion); err != nil {
+		return nil, err
+	}
+
+	// Omni restores the snapshot, and reads the secrets of the backed up cluster, when it bootstraps a control plane with a bootstrap spec
+	machineSet := newMachineSet(omni.ControlPlanesResourceID(restore.ClusterID), restore.ClusterID)
+	if err := m.applyMachineSetUpdates(ctx, st, machineSet, restore.ControlPlane); err != nil {
+		return nil, err
+	}
+	machineSet.TypedSpec().Value.BootstrapSpec = &specs.MachineSetSpec_BootstrapSpec{
+		ClusterUuid: clusterUUID,
+		Snapshot:    snapshot,
+	}
+
+	changes := []*Change{
+		{Action: ChangeCreate, Desired: newCluster(restore.ClusterID, k8sVersion, talosVersion, restore.Features)},
+		{Action: ChangeCreate, Desired: machineSet},
+	}
+	if len(restore.Machines) > 0 {
+		nodes, err := machineSetNodeChanges(ctx, st, machineSet, restore.Machines)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, nodes...)
+	}
+
+	if IsDryRun(ctx) {
+		return changes, nil
+	}
+	if err := createAll(ctx, st, changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// backupSource returns the UUID of the cluster an etcd backup was taken of, and the cluster if it still exists.
+// A cluster that was recreated with the same name since has another UUID and is not returned.
+func backupSource(ctx context.Context, st state.State, backup *omni.EtcdBackup) (string, *omni.Cluster, error) {
+	clusterID, _ := backup.Metadata().Labels().Get(omni.LabelCluster)
+	clusterUUID, _ := backup.Metadata().Labels().Get(omni.LabelClusterUUID)
+
+	var source *omni.Cluster
+	if clusterID != "" {
+		cluster, err := safe.StateGet[*omni.Cluster](ctx, st, omni.NewCluster(omniresources.DefaultNamespace, clusterID).Metadata())
+		if err != nil && !state.IsNotFoundError(err) {
+			return "", nil, stateError(err)
+		}
+		uuid, err := safe.StateGet[*omni.ClusterUUID](ctx, st, omni.NewClusterUUID(clusterID).Metadata())
+		if err != nil && !state.IsNotFoundError(err) {
+			return "", nil, stateError(err)
+		}
+
+		switch {
+		case uuid == nil:
+			source = cluster
+		case clusterUUID == "":
+			source, clusterUUID = cluster, uuid.TypedSpec().Value.Uuid
+		case clusterUUID == uuid.TypedSpec().Value.Uuid:
+			source = cluster
+		}
+	}
+
+	if clusterUUID == "" {
+		return "", nil, status.Errorf(codes.FailedPrecondition, "the cluster etcd backup %s was taken of is not known", backup.Metadata().ID())
+	}
+	return clusterUUID, source, nil
+}
+
+// backupVersions returns the Kubernetes and Talos versions of the cluster an etcd backup was taken of.
+// Omni does not record them with the backup, so they are only known if the backed up cluster still exists
+// and its spec did not change since the backup was created.
+func backupVersions(backup *omni.EtcdBackup, source *omni.Cluster) (k8s, talos string, ok bool) {
+	createdAt := backup.TypedSpec().Value.CreatedAt
+	if source == nil || createdAt == nil || source.Metadata().Updated().After(createdAt.AsTime()) {
+		return "", "", false
+	}
+
+	spec := source.TypedSpec().Value
+	return spec.KubernetesVersion, spec.TalosVersion, spec.KubernetesVersion != "" && spec.TalosVersion != ""
+}
+
+// checkRestoreVersions checks that a snapshot of a cluster running the source versions can be restored into the target versions.
+// Kubernetes may stay on the same minor version or move one minor version ahead, as in an upgrade; Talos must not be older.
+func checkRestoreVersions(sourceK8s, sourceTalos, targetK8s, targetTalos string) error {
+	source, err := semver.ParseTolerant(sourceK8s)
+	if err != nil {
+		return nil //nolint:nilerr // Unknown source versions cannot be compared
+	}
+	target, err := semver.ParseTolerant(targetK8s)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid Kubernetes version %q", targetK8s)
+	}
+	if target.Major != source.Major || target.Minor < source.Minor || target.Minor > source.Minor+1 {
+		return status.Errorf(codes.FailedPrecondition, "a backup of Kubernetes %s can only be restored into Kubernetes %d.%d or %d.%d", sourceK8s, source.Major, source.Minor, source.Major, source.Minor+1)
+	}
+
+	source, err = semver.ParseTolerant(sourceTalos)
+	if err != nil {
+		return nil //nolint:nilerr // Unknown source versions cannot be compared
+	}
+	target, err = semver.ParseTolerant(targetTalos)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid Talos version %q", targetTalos)
+	}
+	if target.LT(source) {
+		return status.Errorf(codes.FailedPrecondition, "a backup of a cluster running Talos %s cannot be restored into the older Talos %s", sourceTalos, targetTalos)
+	}
+	return nil
+}
+
+func (m *managementService) TeardownMachineSet(ctx context.Context, machineSetID string) (*Change, error) {
+	return m.DeleteMachineSet(ctx, machineSetID)
+}
+
+// apply writes a Change unless ctx is a dry run.
+// Destroyed resources are torn down right away and destroyed in the background once their finalizers are released.
+func (m *managementService) apply(ctx context.Context, st state.State, change *Change) (*Change, error) {
+	if IsDryRun(ctx) {
+		return change, nil
+	}
+
+	switch change.Action {
+	case ChangeCreate:
+		if err := st.Create(ctx, change.Desired); err != nil {
+			return nil, stateError(err)
+		}
+	case ChangeUpdate:
+		if err := st.Update(ctx, change.Desired); err != nil {
+			return nil, stateError(err)
+		}
+	case ChangeDestroy:
+		pointers := append(slices.Clone(change.Cascade), change.Current.Metadata())
+		for _, ptr := range pointers {
+			if _, err := st.Teardown(ctx, ptr); err != nil && !state.IsNotFoundError(err) {
+				return nil, stateError(err)
+			}
+		}
+		go m.destroy(pointers)
+	case ChangeNone:
+	}
+
+	return change, nil
+}
+
+// destroy waits for torn down resources to release their finalizers and destroys them in order
+func (m *managementService) destroy(pointers []resource.Pointer) {
+	ctx, cancel := context.WithTimeout(context.Background(), destroyTimeout)
+	defer cancel()
+
+	for _, ptr := range pointers {
+		if err := m.state().TeardownAndDestroy(ctx, ptr); err != nil && !state.IsNotFoundError(err) {
+			log.Printf("Failed to destroy %s %s: %v", ptr.Type(), ptr.ID(), err)
+		}
+	}
+}
+
+// validateConfig validates a Talos config patch using the Omni Management API
+func (m *managementService) validateConfig(ctx context.Context, data string) error {
+	client := management.NewManagementServiceClient(m.source.Client().Omni())
+
+	_, err := guard(m.source, func() (*emptypb.Empty, error) {
+		return client.ValidateConfig(ctx, &management.ValidateConfigRequest{Config: data})
+	})
+	if err != nil {
+		if status.Code(err) == codes.InvalidArgument {
+			return status.Errorf(codes.InvalidArgument, "invalid config patch: %s", status.Convert(err).Message())
+		}
+		return err
+	}
+	return nil
+}
+
+// validateVersions checks that the Talos version is known to Omni and supports the Kubernetes version
+func validateVersions(ctx context.Context, st state.State, talosVersion, k8sVersion string) error {
+	if talosVersion == "" {
+		return status.Error(codes.InvalidArgument, "talos_version is required")
+	}
+	if k8sVersion == "" {
+		return status.Error(codes.InvalidArgument, "kubernetes_version is required")
+	}
+
+	talosVersion, k8sVersion = trimVersion(talosVersion), trimVersion(k8sVersion)
+
+	tv, err := safe.StateGet[*omni.TalosVersion](ctx, st, omni.NewTalosVersion(omniresources.DefaultNamespace, talosVersion).Metadata())
+	if state.IsNotFoundError(err) {
+		return status.Errorf(codes.InvalidArgument, "unknown Talos version %s", talosVersion)
+	}
+	if err != nil {
+		return stateError(err)
+	}
+
+	if !slices.Contains(tv.TypedSpec().Value.CompatibleKubernetesVersions, k8sVersion) {
+		return status.Errorf(codes.InvalidArgument, "Kubernetes %s is not supported by Talos %s", k8sVersion, talosVersion)
+	}
+	return nil
+}
+
+// getResource fetches a resource, returning a NotFound status error if it does not exist
+func getResource[T resource.Resource](ctx context.Context, st state.State, ptr resource.Pointer) (T, error) {
+	res, err := safe.StateGet[T](ctx, st, ptr)
+	if state.IsNotFoundError(err) {
+		return res, status.Errorf(codes.NotFound, "%s %s not found", resourceKind(ptr), ptr.ID())
+	}
+	return res, stateError(err)
+}
+
+// ensureAbsent returns an AlreadyExists status error if the resource exists
+func ensureAbsent(ctx context.Context, st state.State, ptr resource.Pointer) error {
+	_, err := st.Get(ctx, ptr)
+	switch {
+	case err == nil:
+		return status.Errorf(codes.AlreadyExists, "%s %s already exists", resourceKind(ptr), ptr.ID())
+	case state.IsNotFoundError(err):
+		return nil
+	default:
+		return stateError(err)
+	}
+}
+
+func listPointers(ctx context.Context, st state.State, kind resource.Kind, opts ...state.ListOption) ([]resource.Pointer, error) {
+	list, err := st.List(ctx, kind, opts...)
+	if err != nil {
+		return nil, stateError(err)
+	}
+
+	pointers := make([]resource.Pointer, 0, len(list.Items))
+	for _, item := range list.Items {
+		pointers = append(pointers, item.Metadata())
+	}
+	return pointers, nil
+}
+
+// stateError converts COSI state errors into gRPC status errors for the handlers
+func stateError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case state.IsNotFoundError(err):
+		return status.Error(codes.NotFound, err.Error())
+	case state.IsConflictError(err):
+		return status.Error(codes.AlreadyExists, err.Error())
+	default:
+		return err
+	}
+}
+
+func parseStrategy(value string) (specs.MachineSetSpec_UpdateStrategy, error) {
+	for name, v := range specs.MachineSetSpec_UpdateStrategy_value {
+		if strings.EqualFold(name, value) {
+			return specs.MachineSetSpec_UpdateStrategy(v), nil
+		}
+	}
+	return 0, status.Errorf(codes.InvalidArgument, "unknown strategy %q, expected Rolling or Unset", value)
+}
+
+// resourceKind turns a resource type such as "Clusters.omni.sidero.dev" into "cluster"
+func resourceKind(ptr resource.Pointer) string {
+	kind, _, _ := strings.Cut(ptr.Type(), ".")
+	if strings.HasSuffix(kind, "sses") {
+		kind = strings.TrimSuffix(kind, "es")
+	}
+	return strings.ToLower(strings.TrimSuffix(kind, "s"))
 }
 
-func (m *managementService) TeardownMachineSet(ctx context.Context, machineSetID string) error {
-	return fmt.Errorf("TeardownMachineSet not yet implemented - Management API integration needed")
+// trimVersion drops the leading "v" Omni does not use in version IDs
+func trimVersion(version string) string {
+	return strings.TrimPrefix(version, "v")
 }
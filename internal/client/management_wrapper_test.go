@@ -0,0 +1,696 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/safe"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/cosi-project/runtime/pkg/state/impl/inmem"
+	"github.com/cosi-project/runtime/pkg/state/impl/namespaced"
+	"github.com/jubblin/omni-api/internal/retention"
+	"github.com/siderolabs/omni/client/api/omni/specs"
+	"github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/infra"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newTestState(t *testing.T) state.State {
+	st := state.WrapCore(namespaced.NewState(inmem.Build))
+
+	tv := omni.NewTalosVersion(resources.DefaultNamespace, "1.8.0")
+	tv.TypedSpec().Value.CompatibleKubernetesVersions = []string{"1.30.1", "1.31.0"}
+	require.NoError(t, st.Create(context.Background(), tv))
+
+	return st
+}
+
+func TestApply_DryRunDoesNotWrite(t *testing.T) {
+	st := newTestState(t)
+	m := &managementService{}
+	desired := omni.NewCluster(resources.DefaultNamespace, "test-cluster")
+
+	change, err := m.apply(WithDryRun(context.Background()), st, &Change{Action: ChangeCreate, Desired: desired})
+	require.NoError(t, err)
+	assert.Equal(t, ChangeCreate, change.Action)
+
+	_, err = st.Get(context.Background(), desired.Metadata())
+	assert.True(t, state.IsNotFoundError(err))
+
+	_, err = m.apply(context.Background(), st, &Change{Action: ChangeCreate, Desired: desired})
+	require.NoError(t, err)
+
+	_, err = st.Get(context.Background(), desired.Metadata())
+	assert.NoError(t, err)
+}
+
+func TestApply_ConflictIsAlreadyExists(t *testing.T) {
+	st := newTestState(t)
+	m := &managementService{}
+	desired := omni.NewCluster(resources.DefaultNamespace, "test-cluster")
+	require.NoError(t, st.Create(context.Background(), desired))
+
+	_, err := m.apply(context.Background(), st, &Change{Action: ChangeCreate, Desired: desired})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+}
+
+func TestValidateVersions(t *testing.T) {
+	st := newTestState(t)
+
+	tests := []struct {
+		name         string
+		talosVersion string
+		k8sVersion   string
+		wantErr      bool
+	}{
+		{name: "compatible", talosVersion: "v1.8.0", k8sVersion: "v1.31.0"},
+		{name: "unknown Talos version", talosVersion: "1.2.0", k8sVersion: "1.31.0", wantErr: true},
+		{name: "incompatible Kubernetes version", talosVersion: "1.8.0", k8sVersion: "1.25.0", wantErr: true},
+		{name: "missing Talos version", k8sVersion: "1.31.0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateVersions(context.Background(), st, tt.talosVersion, tt.k8sVersion)
+			if tt.wantErr {
+				assert.Equal(t, codes.InvalidArgument, status.Code(err))
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAllocateMachines(t *testing.T) {
+	ctx := context.Background()
+	st := newTestState(t)
+
+	machineSet := omni.NewMachineSet(resources.DefaultNamespace, "prod-workers")
+	machineSet.Metadata().Labels().Set(omni.LabelCluster, "prod")
+	require.NoError(t, st.Create(ctx, machineSet))
+
+	classBacked := omni.NewMachineSet(resources.DefaultNamespace, "prod-pool")
+	classBacked.TypedSpec().Value.MachineAllocation = &specs.MachineSetSpec_MachineAllocation{Name: "rack-a"}
+	require.NoError(t, st.Create(ctx, classBacked))
+
+	for _, id := range []string{"m1", "m2", "m3"} {
+		require.NoError(t, st.Create(ctx, omni.NewMachine(resources.DefaultNamespace, id)))
+	}
+	require.NoError(t, st.Create(ctx, omni.NewClusterMachine(resources.DefaultNamespace, "m3")))
+
+	changes, err := allocateMachines(WithDryRun(ctx), st, "prod-workers", []string{"m1", "m2"})
+	require.NoError(t, err)
+	assert.Len(t, changes, 2)
+	_, err = st.Get(ctx, omni.NewMachineSetNode(resources.DefaultNamespace, "m1", machineSet).Metadata())
+	assert.True(t, state.IsNotFoundError(err))
+
+	_, err = allocateMachines(ctx, st, "prod-workers", []string{"m1", "m2"})
+	require.NoError(t, err)
+	node, err := st.Get(ctx, omni.NewMachineSetNode(resources.DefaultNamespace, "m2", machineSet).Metadata())
+	require.NoError(t, err)
+	machineSetLabel, _ := node.Metadata().Labels().Get(omni.LabelMachineSet)
+	assert.Equal(t, "prod-workers", machineSetLabel)
+
+	tests := []struct {
+		name       string
+		machineSet string
+		machines   []string
+		code       codes.Code
+	}{
+		{name: "no machines", machineSet: "prod-workers", code: codes.InvalidArgument},
+		{name: "unknown machine set", machineSet: "missing", machines: []string{"m1"}, code: codes.NotFound},
+		{name: "machine class", machineSet: "prod-pool", machines: []string{"m1"}, code: codes.FailedPrecondition},
+		{name: "unknown machine", machineSet: "prod-workers", machines: []string{"m9"}, code: codes.NotFound},
+		{name: "in a cluster", machineSet: "prod-workers", machines: []string{"m3"}, code: codes.FailedPrecondition},
+		{name: "already in the machine set", machineSet: "prod-workers", machines: []string{"m1"}, code: codes.AlreadyExists},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := allocateMachines(ctx, st, tt.machineSet, tt.machines)
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func TestApplyMachineClassSpec(t *testing.T) {
+	ctx := context.Background()
+	st := newTestState(t)
+	require.NoError(t, st.Create(ctx, infra.NewProvider("aws")))
+
+	tests := []struct {
+		name    string
+		spec    *MachineClassSpec
+		wantErr bool
+	}{
+		{name: "match labels", spec: &MachineClassSpec{MatchLabels: []string{"rack=a, !gpu"}}},
+		{name: "auto provision", spec: &MachineClassSpec{AutoProvision: &MachineClassProvision{ProviderID: "aws", ProviderData: "size: t3.large\n", KernelArgs: []string{"console=ttyS0"}}}},
+		{name: "neither", spec: &MachineClassSpec{}, wantErr: true},
+		{name: "both", spec: &MachineClassSpec{MatchLabels: []string{"rack=a"}, AutoProvision: &MachineClassProvision{ProviderID: "aws"}}, wantErr: true},
+		{name: "invalid selector", spec: &MachineClassSpec{MatchLabels: []string{"rack in a"}}, wantErr: true},
+		{name: "unknown provider", spec: &MachineClassSpec{AutoProvision: &MachineClassProvision{ProviderID: "gcp"}}, wantErr: true},
+		{name: "invalid provider data", spec: &MachineClassSpec{AutoProvision: &MachineClassProvision{ProviderID: "aws", ProviderData: "- size"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mc := omni.NewMachineClass(resources.DefaultNamespace, "class")
+			err := applyMachineClassSpec(ctx, st, mc, tt.spec)
+			if tt.wantErr {
+				assert.Equal(t, codes.InvalidArgument, status.Code(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.spec.MatchLabels, mc.TypedSpec().Value.MatchLabels)
+			assert.Equal(t, tt.spec.AutoProvision != nil, mc.TypedSpec().Value.AutoProvision != nil)
+		})
+	}
+}
+
+func TestManagementService_UpdateMachineClassKeepsProvisionSettings(t *testing.T) {
+	ctx := context.Background()
+	st := newTestState(t)
+	require.NoError(t, st.Create(ctx, infra.NewProvider("aws")))
+
+	mc := omni.NewMachineClass(resources.DefaultNamespace, "class")
+	mc.TypedSpec().Value.AutoProvision = &specs.MachineClassSpec_Provision{
+		ProviderId: "aws",
+		MetaValues: []*specs.MetaValue{{Key: 12, Value: "rack-a"}},
+		GrpcTunnel: specs.GrpcTunnelMode_ENABLED,
+	}
+	require.NoError(t, st.Create(ctx, mc))
+
+	h := &Holder{breaker: NewBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Hour})}
+	h.state = state.WrapCore(&holderState{holder: h})
+	h.entry = &holderEntry{state: st}
+	m := NewManagementService(h)
+
+	_, err := m.UpdateMachineClass(ctx, "class", &MachineClassSpec{AutoProvision: &MachineClassProvision{ProviderID: "aws", ProviderData: "size: t3.large\n"}})
+	require.NoError(t, err)
+
+	updated, err := safe.StateGet[*omni.MachineClass](ctx, st, mc.Metadata())
+	require.NoError(t, err)
+	provision := updated.TypedSpec().Value.AutoProvision
+	assert.Equal(t, "size: t3.large\n", provision.ProviderData)
+	require.Len(t, provision.MetaValues, 1)
+	assert.Equal(t, uint32(12), provision.MetaValues[0].Key)
+	assert.Equal(t, "rack-a", provision.MetaValues[0].Value)
+	assert.Equal(t, specs.GrpcTunnelMode_ENABLED, provision.GrpcTunnel)
+}
+
+func TestEnsureMachineClassUnused(t *testing.T) {
+	ctx := context.Background()
+	st := newTestState(t)
+
+	ms := omni.NewMachineSet(resources.DefaultNamespace, "prod-workers")
+	ms.TypedSpec().Value.MachineAllocation = &specs.MachineSetSpec_MachineAllocation{Name: "rack-a"}
+	require.NoError(t, st.Create(ctx, ms))
+
+	assert.Equal(t, codes.FailedPrecondition, status.Code(ensureMachineClassUnused(ctx, st, "rack-a")))
+	assert.NoError(t, ensureMachineClassUnused(ctx, st, "rack-b"))
+}
+
+func TestScaleMachineSet(t *testing.T) {
+	newMachineSet := func(allocation *specs.MachineSetSpec_MachineAllocation, controlPlane bool) *omni.MachineSet {
+		ms := omni.NewMachineSet(resources.DefaultNamespace, "prod-workers")
+		ms.TypedSpec().Value.MachineAllocation = allocation
+		if controlPlane {
+			ms.Metadata().Labels().Set(omni.LabelControlPlaneRole, "")
+		}
+		return ms
+	}
+	static := func() *specs.MachineSetSpec_MachineAllocation {
+		return &specs.MachineSetSpec_MachineAllocation{Name: "rack-a", MachineCount: 3, AllocationType: specs.MachineSetSpec_MachineAllocation_Static}
+	}
+
+	ms := newMachineSet(static(), false)
+	require.NoError(t, scaleMachineSet(ms, 5, ms.Metadata().Version().String()))
+	assert.Equal(t, uint32(5), ms.TypedSpec().Value.MachineAllocation.MachineCount)
+
+	tests := []struct {
+		name     string
+		ms       *omni.MachineSet
+		replicas uint32
+		version  string
+		code     codes.Code
+	}{
+		{name: "stale version", ms: newMachineSet(static(), false), replicas: 5, version: "42", code: codes.Aborted},
+		{name: "manual allocation", ms: newMachineSet(nil, false), replicas: 5, code: codes.FailedPrecondition},
+		{name: "unlimited", ms: newMachineSet(&specs.MachineSetSpec_MachineAllocation{Name: "rack-a", AllocationType: specs.MachineSetSpec_MachineAllocation_Unlimited}, false), replicas: 5, code: codes.FailedPrecondition},
+		{name: "empty control plane", ms: newMachineSet(static(), true), code: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.code, status.Code(scaleMachineSet(tt.ms, tt.replicas, tt.version)))
+		})
+	}
+}
+
+func TestStrategyConfig(t *testing.T) {
+	rolling := &specs.MachineSetSpec_UpdateStrategyConfig{Rolling: &specs.MachineSetSpec_RollingUpdateStrategyConfig{MaxParallelism: 2}}
+
+	config, err := strategyConfig("update", specs.MachineSetSpec_Rolling, nil, 3)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(3), config.GetRolling().GetMaxParallelism())
+
+	config, err = strategyConfig("update", specs.MachineSetSpec_Rolling, rolling, 0)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2), config.GetRolling().GetMaxParallelism())
+
+	config, err = strategyConfig("delete", specs.MachineSetSpec_Unset, rolling, 0)
+	require.NoError(t, err)
+	assert.Nil(t, config)
+
+	_, err = strategyConfig("delete", specs.MachineSetSpec_Unset, nil, 2)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestWriteMachineLabels(t *testing.T) {
+	ctx := context.Background()
+	st := newTestState(t)
+	m := &managementService{}
+	require.NoError(t, st.Create(ctx, omni.NewMachine(resources.DefaultNamespace, "m1")))
+
+	userLabels := func() map[string]string {
+		ml, err := safe.StateGet[*omni.MachineLabels](ctx, st, omni.NewMachineLabels(resources.DefaultNamespace, "m1").Metadata())
+		require.NoError(t, err)
+		return ml.Metadata().Labels().Raw()
+	}
+	value := func(s string) *string { return &s }
+
+	change, err := m.writeMachineLabels(ctx, st, "m1", mergeLabels(map[string]*string{"rack": value("r1"), "owner": value("alice")}))
+	require.NoError(t, err)
+	assert.Equal(t, ChangeCreate, change.Action)
+	assert.Equal(t, map[string]string{"rack": "r1", "owner": "alice"}, userLabels())
+
+	change, err = m.writeMachineLabels(ctx, st, "m1", mergeLabels(map[string]*string{"rack": value("r2"), "owner": nil}))
+	require.NoError(t, err)
+	assert.Equal(t, ChangeUpdate, change.Action)
+	assert.Equal(t, map[string]string{"rack": "r2"}, userLabels())
+
+	change, err = m.writeMachineLabels(ctx, st, "m1", mergeLabels(map[string]*string{"rack": value("r2")}))
+	require.NoError(t, err)
+	assert.Equal(t, ChangeNone, change.Action)
+
+	// Labels reserved for Omni survive a replace
+	ml, err := safe.StateGet[*omni.MachineLabels](ctx, st, omni.NewMachineLabels(resources.DefaultNamespace, "m1").Metadata())
+	require.NoError(t, err)
+	ml.Metadata().Labels().Set(omni.SystemLabelPrefix+"owned", "")
+	require.NoError(t, st.Update(ctx, ml))
+
+	_, err = m.writeMachineLabels(ctx, st, "m1", replaceLabels(map[string]string{"owner": "bob"}))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"owner": "bob", omni.SystemLabelPrefix + "owned": ""}, userLabels())
+
+	_, err = m.writeMachineLabels(ctx, st, "m2", mergeLabels(map[string]*string{"rack": value("r1")}))
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(validateUserLabels(map[string]*string{omni.LabelCluster: value("prod")})))
+	assert.Equal(t, codes.InvalidArgument, status.Code(validateUserLabels(map[string]*string{"": nil})))
+	assert.NoError(t, validateUserLabels(map[string]*string{"rack": nil}))
+}
+
+func TestSetMachineMaintenance(t *testing.T) {
+	ctx := context.Background()
+	st := newTestState(t)
+	m := &managementService{}
+
+	machineSet := omni.NewMachineSet(resources.DefaultNamespace, "prod-workers")
+	require.NoError(t, st.Create(ctx, machineSet))
+	for _, id := range []string{"m1", "m2", "m3"} {
+		require.NoError(t, st.Create(ctx, omni.NewMachine(resources.DefaultNamespace, id)))
+	}
+	require.NoError(t, st.Create(ctx, omni.NewMachineSetNode(resources.DefaultNamespace, "m1", machineSet)))
+	classNode := omni.NewMachineSetNode(resources.DefaultNamespace, "m3", machineSet)
+	require.NoError(t, st.Create(ctx, classNode, state.WithCreateOwner("MachineSetNodeController")))
+
+	// Entering maintenance tears down the machine set node; a dry run writes nothing
+	change, err := m.setMachineMaintenance(WithDryRun(ctx), st, "m1", true, "")
+	require.NoError(t, err)
+	assert.Equal(t, ChangeDestroy, change.Action)
+	_, err = st.Get(ctx, omni.NewMachineLabels(resources.DefaultNamespace, "m1").Metadata())
+	assert.True(t, state.IsNotFoundError(err))
+
+	change, err = m.setMachineMaintenance(ctx, st, "m2", true, "")
+	require.NoError(t, err)
+	assert.Equal(t, ChangeNone, change.Action)
+
+	_, err = m.setMachineMaintenance(ctx, st, "m3", true, "")
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = m.setMachineMaintenance(ctx, st, "m1", false, "other")
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// Leaving maintenance returns the machine to the machine set it was taken out of
+	_, err = m.setMachineMaintenance(ctx, st, "m2", false, "")
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	require.NoError(t, setMaintenanceMachineSet(ctx, st, "m2", nil, "prod-workers"))
+
+	change, err = m.setMachineMaintenance(ctx, st, "m2", false, "")
+	require.NoError(t, err)
+	assert.Equal(t, ChangeCreate, change.Action)
+	_, err = st.Get(ctx, omni.NewMachineSetNode(resources.DefaultNamespace, "m2", machineSet).Metadata())
+	require.NoError(t, err)
+
+	machineLabels, err := safe.StateGet[*omni.MachineLabels](ctx, st, omni.NewMachineLabels(resources.DefaultNamespace, "m2").Metadata())
+	require.NoError(t, err)
+	_, ok := machineLabels.Metadata().Annotations().Get(MaintenanceMachineSetAnnotation)
+	assert.False(t, ok)
+
+	_, err = m.setMachineMaintenance(ctx, st, "m9", true, "")
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestSetMachineMaintenanceControlPlane(t *testing.T) {
+	ctx := context.Background()
+	st := newTestState(t)
+	h := &Holder{breaker: NewBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Hour})}
+	h.state = state.WrapCore(&holderState{holder: h})
+	h.entry = &holderEntry{state: st}
+	m := NewManagementService(h)
+
+	newControlPlane := func(cluster string, machines ...string) {
+		machineSet := omni.NewMachineSet(resources.DefaultNamespace, omni.ControlPlanesResourceID(cluster))
+		machineSet.Metadata().Labels().Set(omni.LabelCluster, cluster)
+		machineSet.Metadata().Labels().Set(omni.LabelControlPlaneRole, "")
+		require.NoError(t, st.Create(ctx, machineSet))
+		for _, id := range machines {
+			require.NoError(t, st.Create(ctx, omni.NewMachine(resources.DefaultNamespace, id)))
+			require.NoError(t, st.Create(ctx, omni.NewMachineSetNode(resources.DefaultNamespace, id, machineSet)))
+		}
+	}
+	setHealthy := func(id string, healthy bool) {
+		machine := omni.NewClusterMachineStatus(resources.DefaultNamespace, id)
+		machine.TypedSpec().Value.Ready = healthy
+		machine.TypedSpec().Value.Stage = specs.ClusterMachineStatusSpec_RUNNING
+		require.NoError(t, st.Create(ctx, machine))
+	}
+	newControlPlane("solo", "s1")
+	newControlPlane("prod", "cp1", "cp2", "cp3")
+	setHealthy("cp2", true)
+	setHealthy("cp3", false)
+
+	_, err := m.SetMachineMaintenance(ctx, "s1", true, "")
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err), "the last control plane machine stays")
+
+	// cp1 and the unhealthy cp3 would leave cp2 without a quorum of the three members
+	_, err = m.SetMachineMaintenance(ctx, "cp1", true, "")
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = st.Get(ctx, omni.NewMachineLabels(resources.DefaultNamespace, "cp1").Metadata())
+	assert.True(t, state.IsNotFoundError(err), "refused requests record nothing")
+
+	cp3, err := safe.StateGet[*omni.ClusterMachineStatus](ctx, st, omni.NewClusterMachineStatus(resources.DefaultNamespace, "cp3").Metadata())
+	require.NoError(t, err)
+	cp3.TypedSpec().Value.Ready = true
+	require.NoError(t, st.Update(ctx, cp3))
+
+	change, err := m.SetMachineMaintenance(ctx, "cp1", true, "")
+	require.NoError(t, err)
+	assert.Equal(t, ChangeDestroy, change.Action)
+	machineLabels, err := safe.StateGet[*omni.MachineLabels](ctx, st, omni.NewMachineLabels(resources.DefaultNamespace, "cp1").Metadata())
+	require.NoError(t, err)
+	machineSet, _ := machineLabels.Metadata().Annotations().Get(MaintenanceMachineSetAnnotation)
+	assert.Equal(t, omni.ControlPlanesResourceID("prod"), machineSet)
+}
+
+func TestMaintenanceUpgradeImage(t *testing.T) {
+	ctx := context.Background()
+	st := newTestState(t)
+
+	schematic := "376567988ad370138ad8b2698212367b8edcb69b5fd68c80be1f2ec7d603b4ba"
+	for _, m := range []struct {
+		id          string
+		maintenance bool
+		cluster     string
+		secureBoot  bool
+	}{{"fresh", true, "", false}, {"secure", true, "", true}, {"running", false, "", false}, {"joining", true, "prod", false}} {
+		machineStatus := omni.NewMachineStatus(resources.DefaultNamespace, m.id)
+		machineStatus.TypedSpec().Value.Maintenance = m.maintenance
+		machineStatus.TypedSpec().Value.Cluster = m.cluster
+		machineStatus.TypedSpec().Value.Schematic = &specs.MachineStatusSpec_Schematic{FullId: schematic}
+		machineStatus.TypedSpec().Value.SecurityState = &specs.SecurityState{SecureBoot: m.secureBoot}
+		require.NoError(t, st.Create(ctx, machineStatus))
+	}
+
+	image, err := maintenanceUpgradeImage(ctx, st, "fresh", "v1.8.0", "")
+	require.NoError(t, err)
+	assert.Equal(t, "factory.talos.dev/installer/"+schematic+":v1.8.0", image)
+
+	features := omni.NewFeaturesConfig(resources.DefaultNamespace, omni.FeaturesConfigID)
+	features.TypedSpec().Value.ImageFactoryBaseUrl = "https://factory.example.com/"
+	require.NoError(t, st.Create(ctx, features))
+
+	other := "a" + schematic[1:]
+	image, err = maintenanceUpgradeImage(ctx, st, "secure", "1.8.0", other)
+	require.NoError(t, err)
+	assert.Equal(t, "factory.example.com/installer-secureboot/"+other+":v1.8.0", image)
+
+	tests := []struct {
+		name      string
+		machine   string
+		version   string
+		schematic string
+		code      codes.Code
+	}{
+		{name: "unknown machine", machine: "missing", version: "1.8.0", code: codes.NotFound},
+		{name: "not in maintenance", machine: "running", version: "1.8.0", code: codes.FailedPrecondition},
+		{name: "allocated", machine: "joining", version: "1.8.0", code: codes.FailedPrecondition},
+		{name: "no version", machine: "fresh", code: codes.InvalidArgument},
+		{name: "unknown version", machine: "fresh", version: "1.2.0", code: codes.InvalidArgument},
+		{name: "invalid schematic", machine: "fresh", version: "1.8.0", schematic: "abc", code: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := maintenanceUpgradeImage(ctx, st, tt.machine, tt.version, tt.schematic)
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func TestRestoreEtcdBackup(t *testing.T) {
+	ctx := context.Background()
+	st := newTestState(t)
+	m := &managementService{}
+
+	source := omni.NewCluster(resources.DefaultNamespace, "prod")
+	source.TypedSpec().Value.KubernetesVersion = "1.30.1"
+	source.TypedSpec().Value.TalosVersion = "1.8.0"
+	require.NoError(t, st.Create(ctx, source))
+	clusterUUID := omni.NewClusterUUID("prod")
+	clusterUUID.TypedSpec().Value.Uuid = "5f1a3c1e-0000-4000-8000-000000000001"
+	require.NoError(t, st.Create(ctx, clusterUUID))
+
+	backup := omni.NewEtcdBackup("prod", time.Unix(1700000000, 0))
+	backup.Metadata().Labels().Set(omni.LabelCluster, "prod")
+	backup.TypedSpec().Value.Snapshot = "FFFFFFFF9A7F1BFF.snapshot"
+	backup.TypedSpec().Value.CreatedAt = timestamppb.New(time.Now().Add(time.Minute))
+	require.NoError(t, st.Create(ctx, backup))
+
+	// The versions of backups taken before the cluster last changed, or of another cluster of the same name, are not known
+	older := omni.NewEtcdBackup("prod", time.Unix(1600000000, 0))
+	older.Metadata().Labels().Set(omni.LabelCluster, "prod")
+	older.TypedSpec().Value.Snapshot = "FFFFFFFF9A7F1B00.snapshot"
+	older.TypedSpec().Value.CreatedAt = timestamppb.New(time.Unix(1600000000, 0))
+	require.NoError(t, st.Create(ctx, older))
+	recreated := omni.NewEtcdBackup("prod", time.Unix(1650000000, 0))
+	recreated.Metadata().Labels().Set(omni.LabelCluster, "prod")
+	recreated.Metadata().Labels().Set(omni.LabelClusterUUID, "5f1a3c1e-0000-4000-8000-000000000002")
+	recreated.TypedSpec().Value.Snapshot = "FFFFFFFF9A7F1B01.snapshot"
+	recreated.TypedSpec().Value.CreatedAt = timestamppb.New(time.Now().Add(time.Minute))
+	require.NoError(t, st.Create(ctx, recreated))
+	require.NoError(t, st.Create(ctx, omni.NewMachine(resources.DefaultNamespace, "m1")))
+
+	restore := &ClusterRestore{ClusterID: "prod-restored", Machines: []string{"m1"}}
+
+	// A dry run validates without writing
+	changes, err := m.restoreEtcdBackup(WithDryRun(ctx), st, "prod-1700000000", restore)
+	require.NoError(t, err)
+	require.Len(t, changes, 3)
+	_, err = st.Get(ctx, omni.NewCluster(resources.DefaultNamespace, "prod-restored").Metadata())
+	assert.True(t, state.IsNotFoundError(err))
+
+	changes, err = m.restoreEtcdBackup(ctx, st, "prod-1700000000", restore)
+	require.NoError(t, err)
+	require.Len(t, changes, 3)
+
+	cluster, err := safe.StateGet[*omni.Cluster](ctx, st, omni.NewCluster(resources.DefaultNamespace, "prod-restored").Metadata())
+	require.NoError(t, err)
+	assert.Equal(t, "1.30.1", cluster.TypedSpec().Value.KubernetesVersion)
+	assert.Equal(t, "1.8.0", cluster.TypedSpec().Value.TalosVersion)
+
+	machineSet, err := safe.StateGet[*omni.MachineSet](ctx, st, omni.NewMachineSet(resources.DefaultNamespace, "prod-restored-control-planes").Metadata())
+	require.NoError(t, err)
+	assert.Equal(t, "5f1a3c1e-0000-4000-8000-000000000001", machineSet.TypedSpec().Value.BootstrapSpec.ClusterUuid)
+	assert.Equal(t, "FFFFFFFF9A7F1BFF.snapshot", machineSet.TypedSpec().Value.BootstrapSpec.Snapshot)
+	_, err = st.Get(ctx, omni.NewMachineSetNode(resources.DefaultNamespace, "m1", machineSet).Metadata())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		backupID string
+		restore  *ClusterRestore
+		wantCode codes.Code
+	}{
+		{"missing cluster ID", "prod-1700000000", &ClusterRestore{Machines: []string{"m1"}}, codes.InvalidArgument},
+		{"no control plane", "prod-1700000000", &ClusterRestore{ClusterID: "other"}, codes.InvalidArgument},
+		{"unknown backup", "prod-1", &ClusterRestore{ClusterID: "other", Machines: []string{"m1"}}, codes.NotFound},
+		{"existing cluster", "prod-1700000000", &ClusterRestore{ClusterID: "prod", Machines: []string{"m1"}}, codes.AlreadyExists},
+		{"allocated machine", "prod-1700000000", &ClusterRestore{ClusterID: "other", Machines: []string{"m1"}}, codes.AlreadyExists},
+		{"older Kubernetes", "prod-1700000000", &ClusterRestore{ClusterID: "other", KubernetesVersion: "1.29.0", Machines: []string{"m1"}}, codes.FailedPrecondition},
+		{"unknown versions", "prod-1600000000", &ClusterRestore{ClusterID: "other", ControlPlane: &MachineSetUpdates{MachineClass: "none", MachineCount: 3}}, codes.FailedPrecondition},
+		{"unknown Talos version", "prod-1600000000", &ClusterRestore{ClusterID: "other", KubernetesVersion: "1.30.1", ControlPlane: &MachineSetUpdates{MachineClass: "none", MachineCount: 3}}, codes.FailedPrecondition},
+		{"recreated cluster", "prod-1650000000", &ClusterRestore{ClusterID: "other", ControlPlane: &MachineSetUpdates{MachineClass: "none", MachineCount: 3}}, codes.FailedPrecondition},
+		{"explicit versions", "prod-1600000000", &ClusterRestore{ClusterID: "other", KubernetesVersion: "1.30.1", TalosVersion: "1.8.0", ControlPlane: &MachineSetUpdates{MachineClass: "none", MachineCount: 3}}, codes.NotFound},
+		{"unknown machine class", "prod-1700000000", &ClusterRestore{ClusterID: "other", ControlPlane: &MachineSetUpdates{MachineClass: "none", MachineCount: 3}}, codes.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := m.restoreEtcdBackup(ctx, st, tt.backupID, tt.restore)
+			assert.Equal(t, tt.wantCode, status.Code(err), err)
+		})
+	}
+}
+
+func TestCheckRestoreVersions(t *testing.T) {
+	tests := []struct {
+		name                   string
+		targetK8s, targetTalos string
+		wantCode               codes.Code
+	}{
+		{"same versions", "1.30.1", "1.8.0", codes.OK},
+		{"next Kubernetes minor", "v1.31.0", "1.9.0", codes.OK},
+		{"older Kubernetes", "1.29.5", "1.8.0", codes.FailedPrecondition},
+		{"Kubernetes two minors ahead", "1.32.0", "1.8.0", codes.FailedPrecondition},
+		{"older Talos", "1.30.1", "1.7.6", codes.FailedPrecondition},
+		{"invalid version", "latest", "1.8.0", codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkRestoreVersions("1.30.1", "1.8.0", tt.targetK8s, tt.targetTalos)
+			assert.Equal(t, tt.wantCode, status.Code(err), err)
+		})
+	}
+}
+
+func TestApplyClusterBackup(t *testing.T) {
+	ctx := context.Background()
+	st := newTestState(t)
+
+	cluster := newCluster("prod", "1.31.0", "1.8.0", nil)
+	cluster.Metadata().Annotations().Set(BackupRetentionAnnotation, "last=3")
+
+	err := applyClusterBackup(ctx, st, cluster, &ClusterBackup{Enabled: true})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	err = applyClusterBackup(ctx, st, cluster, &ClusterBackup{Interval: -time.Hour})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	err = applyClusterBackup(ctx, st, cluster, &ClusterBackup{Retention: &retention.Policy{KeepLast: -1}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Without a retention policy the annotation is left alone
+	require.NoError(t, applyClusterBackup(ctx, st, cluster, &ClusterBackup{Enabled: true, Interval: 6 * time.Hour}))
+	conf := cluster.TypedSpec().Value.BackupConfiguration
+	assert.True(t, conf.Enabled)
+	assert.Equal(t, 6*time.Hour, conf.Interval.AsDuration())
+	policy, ok, err := ClusterBackupRetention(cluster)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, retention.Policy{KeepLast: 3}, policy)
+
+	require.NoError(t, applyClusterBackup(ctx, st, cluster, &ClusterBackup{Enabled: true, Interval: time.Hour, Retention: &retention.Policy{KeepDaily: 7, KeepWeekly: 4}}))
+	value, _ := cluster.Metadata().Annotations().Get(BackupRetentionAnnotation)
+	assert.Equal(t, "daily=7,weekly=4", value)
+
+	require.NoError(t, applyClusterBackup(ctx, st, cluster, &ClusterBackup{Retention: &retention.Policy{}}))
+	assert.False(t, cluster.TypedSpec().Value.BackupConfiguration.Enabled)
+	_, ok = cluster.Metadata().Annotations().Get(BackupRetentionAnnotation)
+	assert.False(t, ok)
+
+	store := omni.NewEtcdBackupStoreStatus()
+	store.TypedSpec().Value.ConfigurationName = "disabled"
+	require.NoError(t, st.Create(ctx, store))
+
+	err = applyClusterBackup(ctx, st, cluster, &ClusterBackup{Enabled: true, Interval: time.Hour})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.NoError(t, applyClusterBackup(ctx, st, cluster, &ClusterBackup{}), "disabling backups needs no store")
+}
+
+func TestUpgradeKubernetes(t *testing.T) {
+	ctx := context.Background()
+	st := newTestState(t)
+	m := &managementService{}
+
+	require.NoError(t, st.Create(ctx, newCluster("prod", "1.30.1", "1.8.0", nil)))
+	upgrade := omni.NewKubernetesUpgradeStatus(resources.DefaultNamespace, "prod")
+	upgrade.TypedSpec().Value.UpgradeVersions = []string{"1.31.0", "1.32.0"}
+	require.NoError(t, st.Create(ctx, upgrade))
+
+	_, err := m.upgradeKubernetes(ctx, st, "prod", "1.33.0")
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "not offered by Omni")
+	_, err = m.upgradeKubernetes(ctx, st, "prod", "1.32.0")
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "not supported by Talos 1.8.0")
+
+	change, err := m.upgradeKubernetes(ctx, st, "prod", "v1.31.0")
+	require.NoError(t, err)
+	assert.Equal(t, "1.31.0", change.Desired.(*omni.Cluster).TypedSpec().Value.KubernetesVersion)
+}
+
+func TestRevertUpgrade(t *testing.T) {
+	ctx := context.Background()
+	st := newTestState(t)
+	m := &managementService{}
+
+	require.NoError(t, st.Create(ctx, newCluster("prod", "1.31.0", "1.9.0", nil)))
+	upgrade := omni.NewTalosUpgradeStatus(resources.DefaultNamespace, "prod")
+	upgrade.TypedSpec().Value.UpgradeVersions = []string{"1.9.1"}
+	require.NoError(t, st.Create(ctx, upgrade))
+
+	// Older versions are not offered as upgrades, but can be reverted to
+	change, err := m.revertUpgrade(ctx, st, "prod", "Talos", "v1.8.0")
+	require.NoError(t, err)
+	assert.Equal(t, ChangeUpdate, change.Action)
+	change, err = m.revertUpgrade(ctx, st, "prod", "Kubernetes", "1.30.1")
+	require.NoError(t, err)
+
+	cluster, err := safe.StateGet[*omni.Cluster](ctx, st, omni.NewCluster(resources.DefaultNamespace, "prod").Metadata())
+	require.NoError(t, err)
+	assert.Equal(t, "1.8.0", cluster.TypedSpec().Value.TalosVersion)
+	assert.Equal(t, "1.30.1", cluster.TypedSpec().Value.KubernetesVersion)
+	assert.Equal(t, "1.30.1", change.Desired.(*omni.Cluster).TypedSpec().Value.KubernetesVersion)
+
+	_, err = m.revertUpgrade(ctx, st, "prod", "Talos", "")
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = m.revertUpgrade(ctx, st, "missing", "Talos", "1.8.0")
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestManagementService_WritesAreGuardedByBreaker(t *testing.T) {
+	ctx := context.Background()
+	st := newTestState(t)
+	require.NoError(t, st.Create(ctx, newCluster("prod", "1.30.1", "1.8.0", nil)))
+
+	h := &Holder{breaker: NewBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Hour})}
+	h.state = state.WrapCore(&holderState{holder: h})
+	h.entry = &holderEntry{state: st}
+	m := NewManagementService(h)
+
+	_, err := m.RevertKubernetesUpgrade(ctx, "prod", "1.30.1")
+	require.NoError(t, err)
+
+	h.breaker.Record(status.Error(codes.Unavailable, "omni is down"))
+	_, err = m.RevertKubernetesUpgrade(ctx, "prod", "1.31.0")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	cluster, err := safe.StateGet[*omni.Cluster](ctx, st, omni.NewCluster(resources.DefaultNamespace, "prod").Metadata())
+	require.NoError(t, err)
+	assert.Equal(t, "1.30.1", cluster.TypedSpec().Value.KubernetesVersion, "writes are short-circuited while the breaker is open")
+}
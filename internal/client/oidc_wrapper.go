@@ -3,8 +3,6 @@ package client
 import (
 	"context"
 	"fmt"
-
-	"github.com/siderolabs/omni/client/pkg/client"
 )
 
 // OIDCProvider represents an OIDC provider configuration
@@ -46,13 +44,13 @@ type OIDCService interface {
 
 // oidcService implements OIDCService
 type oidcService struct {
-	client interface{} // Will be *oidc.Client once we know the type
+	source ClientSource // Resolved on every call so rebuilt clients are picked up
 }
 
 // NewOIDCService creates a new OIDCService wrapper
-func NewOIDCService(c *client.Client) OIDCService {
+func NewOIDCService(src ClientSource) OIDCService {
 	return &oidcService{
-		client: c.OIDC(),
+		source: src,
 	}
 }
 
@@ -4,27 +4,42 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/siderolabs/omni/client/pkg/client"
 )
 
 // NewOmniClient creates a new Omni client using environment variables.
 func NewOmniClient() (*client.Client, error) {
+	serviceAccount := os.Getenv("OMNI_SERVICE_ACCOUNT")
+	if serviceAccount == "" {
+		serviceAccount = os.Getenv("OMNI_SERVICE_ACCOUNT_KEY")
+	}
+	if keyFile := os.Getenv("OMNI_SERVICE_ACCOUNT_KEY_FILE"); keyFile != "" && serviceAccount == "" {
+		key, err := readServiceAccountKey(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		serviceAccount = key
+	}
+
+	return newOmniClient(serviceAccount)
+}
+
+// newOmniClient creates a new Omni client using the given service account key
+// and the remaining settings from environment variables.
+func newOmniClient(serviceAccount string) (*client.Client, error) {
 	endpoint := os.Getenv("OMNI_ENDPOINT")
 	if endpoint == "" {
 		return nil, fmt.Errorf("OMNI_ENDPOINT environment variable is not set")
 	}
 
-	serviceAccount := os.Getenv("OMNI_SERVICE_ACCOUNT")
-	if serviceAccount == "" {
-		serviceAccount = os.Getenv("OMNI_SERVICE_ACCOUNT_KEY")
-	}
 	contextName := os.Getenv("OMNI_CONTEXT")
 	identity := os.Getenv("OMNI_IDENTITY")
 	keysDir := os.Getenv("OMNI_KEYS_DIR")
-	
+
 	log.Printf("Initializing Omni client with endpoint: %s\n", endpoint)
-	
+
 	var opts []client.Option
 
 	if serviceAccount != "" {
@@ -49,3 +64,12 @@ func NewOmniClient() (*client.Client, error) {
 	return client.New(endpoint, opts...)
 }
 
+// readServiceAccountKey reads a service account key from a file, such as a mounted secret
+func readServiceAccountKey(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read service account key file: %w", err)
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
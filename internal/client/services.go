@@ -2,6 +2,7 @@ package client
 
 import (
 	"github.com/siderolabs/omni/client/pkg/client"
+	"github.com/siderolabs/omni/client/pkg/client/management"
 )
 
 // Services wraps all available Omni client services
@@ -19,8 +20,8 @@ func (s *Services) State() interface{} {
 	return s.client.Omni().State()
 }
 
-// Management returns the Management service client for write operations
-func (s *Services) Management() interface{} {
+// Management returns the Management service client
+func (s *Services) Management() *management.Client {
 	return s.client.Management()
 }
 
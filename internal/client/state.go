@@ -0,0 +1,91 @@
+package client
+
+import (
+	"context"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/state"
+)
+
+// holderState implements state.CoreState on top of whichever client the Holder
+// currently owns, so handlers keep working across client rebuilds.
+// Every call is guarded by the Holder's circuit breaker.
+type holderState struct {
+	holder *Holder
+}
+
+func (s *holderState) current() state.State {
+	return s.holder.current().state
+}
+
+func (s *holderState) Get(ctx context.Context, ptr resource.Pointer, opts ...state.GetOption) (resource.Resource, error) {
+	if err := s.holder.breaker.Allow(); err != nil {
+		return nil, err
+	}
+	res, err := s.current().Get(ctx, ptr, opts...)
+	s.holder.breaker.Record(err)
+	return res, err
+}
+
+func (s *holderState) List(ctx context.Context, kind resource.Kind, opts ...state.ListOption) (resource.List, error) {
+	if err := s.holder.breaker.Allow(); err != nil {
+		return resource.List{}, err
+	}
+	list, err := s.current().List(ctx, kind, opts...)
+	s.holder.breaker.Record(err)
+	return list, err
+}
+
+func (s *holderState) Create(ctx context.Context, res resource.Resource, opts ...state.CreateOption) error {
+	if err := s.holder.breaker.Allow(); err != nil {
+		return err
+	}
+	err := s.current().Create(ctx, res, opts...)
+	s.holder.breaker.Record(err)
+	return err
+}
+
+func (s *holderState) Update(ctx context.Context, res resource.Resource, opts ...state.UpdateOption) error {
+	if err := s.holder.breaker.Allow(); err != nil {
+		return err
+	}
+	err := s.current().Update(ctx, res, opts...)
+	s.holder.breaker.Record(err)
+	return err
+}
+
+func (s *holderState) Destroy(ctx context.Context, ptr resource.Pointer, opts ...state.DestroyOption) error {
+	if err := s.holder.breaker.Allow(); err != nil {
+		return err
+	}
+	err := s.current().Destroy(ctx, ptr, opts...)
+	s.holder.breaker.Record(err)
+	return err
+}
+
+func (s *holderState) Watch(ctx context.Context, ptr resource.Pointer, ch chan<- state.Event, opts ...state.WatchOption) error {
+	if err := s.holder.breaker.Allow(); err != nil {
+		return err
+	}
+	err := s.current().Watch(ctx, ptr, ch, opts...)
+	s.holder.breaker.Record(err)
+	return err
+}
+
+func (s *holderState) WatchKind(ctx context.Context, kind resource.Kind, ch chan<- state.Event, opts ...state.WatchKindOption) error {
+	if err := s.holder.breaker.Allow(); err != nil {
+		return err
+	}
+	err := s.current().WatchKind(ctx, kind, ch, opts...)
+	s.holder.breaker.Record(err)
+	return err
+}
+
+func (s *holderState) WatchKindAggregated(ctx context.Context, kind resource.Kind, ch chan<- []state.Event, opts ...state.WatchKindOption) error {
+	if err := s.holder.breaker.Allow(); err != nil {
+		return err
+	}
+	err := s.current().WatchKindAggregated(ctx, kind, ch, opts...)
+	s.holder.breaker.Record(err)
+	return err
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+
+	"github.com/siderolabs/omni/client/api/omni/management"
+)
+
+// SupportBundleProgress is reported for each source (a machine or Omni itself) while a support bundle is collected
+type SupportBundleProgress struct {
+	Source string `json:"source"`
+	State  string `json:"state,omitempty"`
+	Error  string `json:"error,omitempty"`
+	Total  int    `json:"total"`
+	Value  int    `json:"value"`
+}
+
+// SupportService collects diagnostics through the Omni Management API
+type SupportService interface {
+	// SupportBundle collects Talos and Kubernetes diagnostics from every machine of a cluster and returns them as a zip archive
+	SupportBundle(ctx context.Context, clusterID string, report func(SupportBundleProgress)) ([]byte, error)
+}
+
+// supportService implements SupportService
+type supportService struct {
+	source ClientSource // Resolved on every call so rebuilt clients are picked up
+}
+
+// NewSupportService creates a new SupportService wrapper
+func NewSupportService(src ClientSource) SupportService {
+	return &supportService{
+		source: src,
+	}
+}
+
+func (s *supportService) SupportBundle(ctx context.Context, clusterID string, report func(SupportBundleProgress)) ([]byte, error) {
+	if err := allow(s.source); err != nil {
+		return nil, err
+	}
+
+	// The Management client closes the channel when it returns
+	progress := make(chan *management.GetSupportBundleResponse_Progress)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for p := range progress {
+			if p == nil {
+				continue
+			}
+			report(SupportBundleProgress{
+				Source: p.GetSource(),
+				State:  p.GetState(),
+				Error:  p.GetError(),
+				Total:  int(p.GetTotal()),
+				Value:  int(p.GetValue()),
+			})
+		}
+	}()
+
+	data, err := s.source.Client().Management().GetSupportBundle(ctx, clusterID, progress)
+	record(s.source, err)
+	<-done
+	return data, err
+}
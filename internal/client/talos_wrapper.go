@@ -3,8 +3,22 @@ package client
 import (
 	"context"
 	"fmt"
+	"io"
+	"regexp"
+	"strings"
 
-	"github.com/siderolabs/omni/client/pkg/client"
+	"github.com/cosi-project/runtime/pkg/safe"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/siderolabs/omni/client/api/omni/management"
+	"github.com/siderolabs/omni/client/pkg/client/talos"
+	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/siderolabs/talos/pkg/machinery/api/common"
+	"github.com/siderolabs/talos/pkg/machinery/api/machine"
+	talosclient "github.com/siderolabs/talos/pkg/machinery/client"
+	"github.com/siderolabs/talos/pkg/machinery/constants"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // TalosService defines the interface for Talos operations
@@ -12,37 +26,195 @@ type TalosService interface {
 	RebootMachine(ctx context.Context, machineID string) error
 	ShutdownMachine(ctx context.Context, machineID string) error
 	ResetMachine(ctx context.Context, machineID string) error
+	MachineLogs(ctx context.Context, machineID string, opts LogOptions) (io.ReadCloser, error)
+
+	// MaintenanceUpgrade installs a Talos version on a machine running in maintenance mode, using
+	// the machine's current schematic or schematicID if set. It does not wait for the upgrade to finish.
+	MaintenanceUpgrade(ctx context.Context, machineID, version, schematicID string) error
+}
+
+// defaultImageFactory is used when Omni does not report its image factory
+const defaultImageFactory = "factory.talos.dev"
+
+var schematicIDPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Log sources besides Talos service names
+const (
+	LogSourceConsole = "console" // Machine console logs collected by Omni
+	LogSourceKernel  = "kernel"  // Kernel ring buffer
+)
+
+// LogOptions selects the machine logs to read
+type LogOptions struct {
+	Service   string // Talos service such as "kubelet", LogSourceKernel, or LogSourceConsole if empty
+	Follow    bool   // Keep streaming new lines until ctx is canceled
+	TailLines int32  // Number of lines from the end to start at; negative reads all lines
 }
 
 // talosService implements TalosService
 type talosService struct {
-	client interface{} // Will be *talos.Client once we know the type
+	source ClientSource // Resolved on every call so rebuilt clients are picked up
 }
 
 // NewTalosService creates a new TalosService wrapper
-func NewTalosService(c *client.Client) TalosService {
+func NewTalosService(src ClientSource) TalosService {
 	return &talosService{
-		client: c.Talos(),
+		source: src,
 	}
 }
 
-// Implementation methods - these will call the actual Talos client API
-// TODO: Implement once actual API methods are known
-
 func (t *talosService) RebootMachine(ctx context.Context, machineID string) error {
-	// TODO: Implement actual API call
-	// Example:
-	// talosClient := t.client.(*talos.Client)
-	// err := talosClient.Reboot(ctx, machineID)
-	// return err
-	
-	return fmt.Errorf("RebootMachine not yet implemented - Talos API integration needed")
+	talosClient, err := t.machineClient(ctx, machineID)
+	if err != nil {
+		return err
+	}
+	if IsDryRun(ctx) {
+		return nil
+	}
+
+	_, err = talosClient.Reboot(ctx, &machine.RebootRequest{})
+	return err
 }
 
 func (t *talosService) ShutdownMachine(ctx context.Context, machineID string) error {
-	return fmt.Errorf("ShutdownMachine not yet implemented - Talos API integration needed")
+	return status.Error(codes.Unimplemented, "shutting down machines is not supported yet")
 }
 
 func (t *talosService) ResetMachine(ctx context.Context, machineID string) error {
-	return fmt.Errorf("ResetMachine not yet implemented - Talos API integration needed")
+	return status.Error(codes.Unimplemented, "resetting machines is not supported yet")
+}
+
+// MaintenanceUpgrade uses Omni's maintenance upgrade, which keeps the machine's schematic.
+// To switch schematics, the installer image is resolved here and the upgrade is sent through the Omni Talos proxy.
+func (t *talosService) MaintenanceUpgrade(ctx context.Context, machineID, version, schematicID string) error {
+	image, err := maintenanceUpgradeImage(ctx, t.source.State(), machineID, version, schematicID)
+	if err != nil {
+		return err
+	}
+	if IsDryRun(ctx) {
+		return nil
+	}
+
+	if schematicID == "" {
+		_, err = guard(t.source, func() (*management.MaintenanceUpgradeResponse, error) {
+			return management.NewManagementServiceClient(t.source.Client().Omni()).MaintenanceUpgrade(ctx, &management.MaintenanceUpgradeRequest{
+				MachineId: machineID,
+				Version:   trimVersion(version),
+			})
+		})
+		return err
+	}
+
+	talosClient, err := t.machineClient(ctx, machineID)
+	if err != nil {
+		return err
+	}
+	_, err = talosClient.Upgrade(ctx, &machine.UpgradeRequest{Image: image})
+	return err
+}
+
+// maintenanceUpgradeImage validates a maintenance upgrade and returns the installer image to upgrade to
+func maintenanceUpgradeImage(ctx context.Context, st state.State, machineID, version, schematicID string) (string, error) {
+	machineStatus, err := getResource[*omni.MachineStatus](ctx, st, omni.NewMachineStatus(omniresources.DefaultNamespace, machineID).Metadata())
+	if err != nil {
+		return "", err
+	}
+	spec := machineStatus.TypedSpec().Value
+	if !spec.Maintenance {
+		return "", status.Errorf(codes.FailedPrecondition, "machine %s is not in maintenance mode", machineID)
+	}
+	if spec.Cluster != "" {
+		return "", status.Errorf(codes.FailedPrecondition, "machine %s is allocated to cluster %s, upgrade the cluster instead", machineID, spec.Cluster)
+	}
+
+	version = trimVersion(version)
+	if version == "" {
+		return "", status.Error(codes.InvalidArgument, "version is required")
+	}
+	if _, err := getResource[*omni.TalosVersion](ctx, st, omni.NewTalosVersion(omniresources.DefaultNamespace, version).Metadata()); err != nil {
+		if status.Code(err) == codes.NotFound {
+			return "", status.Errorf(codes.InvalidArgument, "unknown Talos version %s", version)
+		}
+		return "", err
+	}
+
+	switch {
+	case schematicID != "":
+		if !schematicIDPattern.MatchString(schematicID) {
+			return "", status.Errorf(codes.InvalidArgument, "invalid schematic ID %q", schematicID)
+		}
+	case spec.GetSchematic().GetInvalid() || spec.GetSchematic().GetFullId() == "":
+		return "", status.Errorf(codes.FailedPrecondition, "the schematic of machine %s is not known, pass a schematic ID", machineID)
+	default:
+		schematicID = spec.GetSchematic().GetFullId()
+	}
+
+	factory := defaultImageFactory
+	features, err := safe.StateGet[*omni.FeaturesConfig](ctx, st, omni.NewFeaturesConfig(omniresources.DefaultNamespace, omni.FeaturesConfigID).Metadata())
+	if err != nil && !state.IsNotFoundError(err) {
+		return "", stateError(err)
+	}
+	if features != nil {
+		if url := features.TypedSpec().Value.GetImageFactoryBaseUrl(); url != "" {
+			factory = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(url, "https://"), "http://"), "/")
+		}
+	}
+
+	installer := "installer"
+	if spec.GetSecurityState().GetSecureBoot() {
+		installer = "installer-secureboot"
+	}
+	return fmt.Sprintf("%s/%s/%s:v%s", factory, installer, schematicID, version), nil
+}
+
+// MachineLogs streams machine logs. Console logs come from Omni, everything else
+// is read from the machine through the Omni Talos proxy. Closing the reader or canceling ctx stops the stream.
+func (t *talosService) MachineLogs(ctx context.Context, machineID string, opts LogOptions) (io.ReadCloser, error) {
+	if opts.Service == "" || opts.Service == LogSourceConsole {
+		r, err := guard(t.source, func() (io.Reader, error) {
+			return t.source.Client().Management().LogsReader(ctx, machineID, opts.Follow, opts.TailLines)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(r), nil
+	}
+
+	talosClient, err := t.machineClient(ctx, machineID)
+	if err != nil {
+		return nil, err
+	}
+
+	var stream talosclient.MachineStream
+	if opts.Service == LogSourceKernel {
+		stream, err = talosClient.Dmesg(ctx, &machine.DmesgRequest{Follow: opts.Follow})
+	} else {
+		stream, err = talosClient.Logs(ctx, &machine.LogsRequest{
+			Namespace: constants.SystemContainerdNamespace,
+			Driver:    common.ContainerDriver_CONTAINERD,
+			Id:        opts.Service,
+			Follow:    opts.Follow,
+			TailLines: opts.TailLines,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return talosclient.ReadStream(stream)
+}
+
+// machineClient returns a Talos client that targets a single machine through the Omni Talos proxy
+func (t *talosService) machineClient(ctx context.Context, machineID string) (*talos.Client, error) {
+	status, err := getResource[*omni.MachineStatus](ctx, t.source.State(), omni.NewMachineStatus(omniresources.DefaultNamespace, machineID).Metadata())
+	if err != nil {
+		return nil, err
+	}
+
+	// Machines allocated to a cluster are addressed through the cluster context
+	talosClient := t.source.Client().Talos().WithNodes(machineID)
+	if cluster := status.TypedSpec().Value.Cluster; cluster != "" {
+		talosClient = talosClient.WithCluster(cluster)
+	}
+	return talosClient, nil
 }
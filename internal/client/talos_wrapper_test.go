@@ -0,0 +1,23 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestTalosService_MachineLogsUnknownMachine(t *testing.T) {
+	h := &Holder{breaker: NewBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Hour})}
+	h.state = state.WrapCore(&holderState{holder: h})
+	h.entry = &holderEntry{state: newTestState(t)}
+
+	_, err := NewTalosService(h).MachineLogs(context.Background(), "missing", LogOptions{Service: "kubelet"})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
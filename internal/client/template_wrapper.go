@@ -0,0 +1,262 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/siderolabs/omni/client/pkg/template"
+	templateops "github.com/siderolabs/omni/client/pkg/template/operations"
+	"go.yaml.in/yaml/v4"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TemplateError is returned for templates that cannot be loaded or fail validation.
+// Its gRPC status is InvalidArgument.
+type TemplateError struct {
+	Errors []string
+}
+
+func (e *TemplateError) Error() string {
+	if len(e.Errors) == 1 {
+		return "invalid cluster template: " + e.Errors[0]
+	}
+	return fmt.Sprintf("invalid cluster template: %d errors", len(e.Errors))
+}
+
+// GRPCStatus maps template errors to InvalidArgument
+func (e *TemplateError) GRPCStatus() *status.Status {
+	return status.New(codes.InvalidArgument, e.Error())
+}
+
+// TemplatePlan lists the changes that reconcile a cluster with a template
+type TemplatePlan struct {
+	Cluster string
+	Changes []*Change   // Creations and updates, applied in order
+	Prune   [][]*Change // Destructions of resources missing from the template, applied phase by phase
+}
+
+// TemplateService reconciles Omni resources with omnictl cluster templates
+type TemplateService interface {
+	// Validate loads and validates a template without looking at Omni, returning its cluster name
+	Validate(tmpl []byte) (string, error)
+	// Plan computes the changes that reconcile the cluster with a template
+	Plan(ctx context.Context, tmpl []byte) (*TemplatePlan, error)
+	// Apply creates and updates the resources of a plan
+	Apply(ctx context.Context, plan *TemplatePlan) error
+	// Prune tears down and destroys the resources a plan removes, waiting for each phase to finish
+	Prune(ctx context.Context, plan *TemplatePlan, report func(phase, total int)) error
+	// Export renders an existing cluster as a template
+	Export(ctx context.Context, clusterID string) ([]byte, error)
+}
+
+// templateService implements TemplateService
+type templateService struct {
+	source ClientSource // Resolved on every call so rebuilt clients are picked up
+}
+
+// NewTemplateService creates a new TemplateService wrapper
+func NewTemplateService(src ClientSource) TemplateService {
+	return &templateService{
+		source: src,
+	}
+}
+
+func (s *templateService) state() state.State {
+	return s.source.State()
+}
+
+func (s *templateService) Validate(tmpl []byte) (string, error) {
+	t, err := loadTemplate(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	cluster, err := t.ClusterName()
+	if err != nil {
+		return "", &TemplateError{Errors: []string{err.Error()}}
+	}
+	return cluster, nil
+}
+
+func (s *templateService) Plan(ctx context.Context, tmpl []byte) (*TemplatePlan, error) {
+	return planTemplate(ctx, s.state(), tmpl)
+}
+
+func (s *templateService) Apply(ctx context.Context, plan *TemplatePlan) error {
+	return applyPlan(ctx, s.state(), plan)
+}
+
+func (s *templateService) Prune(ctx context.Context, plan *TemplatePlan, report func(phase, total int)) error {
+	return prunePlan(ctx, s.state(), plan, report)
+}
+
+func (s *templateService) Export(ctx context.Context, clusterID string) ([]byte, error) {
+	var out bytes.Buffer
+	if _, err := templateops.ExportTemplate(ctx, s.state(), clusterID, &out); err != nil {
+		return nil, stateError(err)
+	}
+	return out.Bytes(), nil
+}
+
+// applyPlan creates and updates the resources of a plan
+func applyPlan(ctx context.Context, st state.State, plan *TemplatePlan) error {
+	for _, change := range plan.Changes {
+		var err error
+		switch change.Action {
+		case ChangeCreate:
+			err = st.Create(ctx, change.Desired)
+		case ChangeUpdate:
+			err = st.Update(ctx, change.Desired)
+		}
+		if err != nil {
+			return stateError(err)
+		}
+	}
+	return nil
+}
+
+// prunePlan destroys the resources a plan removes phase by phase
+func prunePlan(ctx context.Context, st state.State, plan *TemplatePlan, report func(phase, total int)) error {
+	for i, phase := range plan.Prune {
+		report(i+1, len(plan.Prune))
+
+		// Tear the whole phase down first so the resources are released in parallel
+		for _, change := range phase {
+			if _, err := st.Teardown(ctx, change.Current.Metadata()); err != nil && !state.IsNotFoundError(err) {
+				return stateError(err)
+			}
+		}
+		for _, change := range phase {
+			if err := st.TeardownAndDestroy(ctx, change.Current.Metadata()); err != nil && !state.IsNotFoundError(err) {
+				return fmt.Errorf("failed to destroy %s: %w", resource.String(change.Current), err)
+			}
+		}
+	}
+	return nil
+}
+
+// planTemplate loads a template and compares its resources with the state
+func planTemplate(ctx context.Context, st state.State, tmpl []byte) (*TemplatePlan, error) {
+	t, err := loadTemplate(tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	cluster, err := t.ClusterName()
+	if err != nil {
+		return nil, &TemplateError{Errors: []string{err.Error()}}
+	}
+
+	sync, err := t.Sync(ctx, st)
+	if err != nil {
+		// Sync fails for resources owned by other clusters
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	plan := &TemplatePlan{Cluster: cluster}
+	for _, r := range sync.Create {
+		plan.Changes = append(plan.Changes, &Change{Action: ChangeCreate, Desired: r})
+	}
+	for _, u := range sync.Update {
+		plan.Changes = append(plan.Changes, &Change{Action: ChangeUpdate, Current: u.Old, Desired: u.New})
+	}
+	for _, phase := range sync.Destroy {
+		if len(phase) == 0 {
+			continue
+		}
+		changes := make([]*Change, 0, len(phase))
+		for _, r := range phase {
+			changes = append(changes, &Change{Action: ChangeDestroy, Current: r})
+		}
+		plan.Prune = append(plan.Prune, changes)
+	}
+	return plan, nil
+}
+
+// loadTemplate parses and validates a template
+func loadTemplate(tmpl []byte) (*template.Template, error) {
+	// Patch files would be read from the server's file system
+	if err := rejectPatchFiles(tmpl); err != nil {
+		return nil, err
+	}
+
+	t, err := template.Load(bytes.NewReader(tmpl))
+	if err != nil {
+		return nil, &TemplateError{Errors: []string{err.Error()}}
+	}
+	if err := t.Validate(); err != nil {
+		return nil, &TemplateError{Errors: flattenErrors(err)}
+	}
+	return t, nil
+}
+
+// rejectPatchFiles fails for templates with patches that reference files instead of inlining them
+func rejectPatchFiles(tmpl []byte) error {
+	dec := yaml.NewDecoder(bytes.NewReader(tmpl))
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return &TemplateError{Errors: []string{fmt.Sprintf("error decoding template: %v", err)}}
+		}
+		if line := findPatchFile(&doc); line > 0 {
+			return &TemplateError{Errors: []string{fmt.Sprintf("line %d: patch files are not supported, use inline patches", line)}}
+		}
+	}
+}
+
+// findPatchFile returns the line of the first "file" key of a patch, or 0 if there is none
+func findPatchFile(node *yaml.Node) int {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value != "patches" || value.Kind != yaml.SequenceNode {
+				continue
+			}
+			for _, patch := range value.Content {
+				if patch.Kind != yaml.MappingNode {
+					continue
+				}
+				for j := 0; j+1 < len(patch.Content); j += 2 {
+					if patch.Content[j].Value == "file" {
+						return patch.Content[j].Line
+					}
+				}
+			}
+		}
+	}
+
+	for _, child := range node.Content {
+		if line := findPatchFile(child); line > 0 {
+			return line
+		}
+	}
+	return 0
+}
+
+// flattenErrors splits joined and multi-errors into their messages
+func flattenErrors(err error) []string {
+	var errs []error
+	switch e := err.(type) {
+	case interface{ WrappedErrors() []error }:
+		errs = e.WrappedErrors()
+	case interface{ Unwrap() []error }:
+		errs = e.Unwrap()
+	default:
+		return []string{err.Error()}
+	}
+
+	var messages []string
+	for _, e := range errs {
+		messages = append(messages, flattenErrors(e)...)
+	}
+	return messages
+}
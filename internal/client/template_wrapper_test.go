@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const testTemplate = `kind: Cluster
+name: prod
+kubernetes:
+  version: v1.31.0
+talos:
+  version: v1.8.0
+---
+kind: ControlPlane
+machines:
+  - 430d882a-51a8-48b3-ae00-90c5b0b5b0b0
+---
+kind: Workers
+machines:
+  - 430d882a-51a8-48b3-ab00-d4b5b0b5b0b0
+`
+
+// countActions counts the changes of a plan by action
+func countActions(plan *TemplatePlan) map[ChangeAction]int {
+	counts := map[ChangeAction]int{}
+	for _, change := range plan.Changes {
+		counts[change.Action]++
+	}
+	for _, phase := range plan.Prune {
+		counts[ChangeDestroy] += len(phase)
+	}
+	return counts
+}
+
+func TestPlanTemplate_ApplyAndPrune(t *testing.T) {
+	ctx := context.Background()
+	st := newTestState(t)
+
+	plan, err := planTemplate(ctx, st, []byte(testTemplate))
+	require.NoError(t, err)
+	assert.Equal(t, "prod", plan.Cluster)
+	assert.Empty(t, plan.Prune)
+
+	counts := countActions(plan)
+	assert.Zero(t, counts[ChangeUpdate])
+	assert.Positive(t, counts[ChangeCreate])
+
+	require.NoError(t, applyPlan(ctx, st, plan))
+
+	_, err = st.Get(ctx, omni.NewCluster(resources.DefaultNamespace, "prod").Metadata())
+	require.NoError(t, err)
+
+	// Applying the same template again changes nothing
+	plan, err = planTemplate(ctx, st, []byte(testTemplate))
+	require.NoError(t, err)
+	assert.Empty(t, plan.Changes)
+	assert.Empty(t, plan.Prune)
+
+	// Dropping the workers prunes their machine set; Omni removes its nodes with it
+	withoutWorkers := testTemplate[:strings.Index(testTemplate, "---\nkind: Workers")]
+	plan, err = planTemplate(ctx, st, []byte(withoutWorkers))
+	require.NoError(t, err)
+	require.Len(t, plan.Prune, 1)
+	require.Len(t, plan.Prune[0], 1)
+	assert.Equal(t, "prod-workers", plan.Prune[0][0].Current.Metadata().ID())
+
+	var phases []int
+	require.NoError(t, prunePlan(ctx, st, plan, func(phase, total int) { phases = append(phases, phase) }))
+	assert.Equal(t, []int{1}, phases)
+
+	_, err = st.Get(ctx, omni.NewMachineSet(resources.DefaultNamespace, "prod-workers").Metadata())
+	assert.True(t, state.IsNotFoundError(err))
+}
+
+func TestLoadTemplate_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		tmpl string
+		want string
+	}{
+		{name: "not yaml", tmpl: "kind: [", want: "error decoding template"},
+		{name: "unknown kind", tmpl: "kind: Nodes\n", want: "unknown"},
+		{name: "patch file", tmpl: "kind: Cluster\nname: prod\npatches:\n  - file: /etc/passwd\n", want: "line 4: patch files are not supported"},
+		{name: "missing control plane", tmpl: "kind: Cluster\nname: prod\nkubernetes:\n  version: v1.31.0\ntalos:\n  version: v1.8.0\n", want: "controlplane"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadTemplate([]byte(tt.tmpl))
+			require.Error(t, err)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+			var templateErr *TemplateError
+			require.ErrorAs(t, err, &templateErr)
+			assert.Contains(t, strings.ToLower(strings.Join(templateErr.Errors, "\n")), strings.ToLower(tt.want))
+		})
+	}
+}
@@ -0,0 +1,77 @@
+// Package diff renders Omni resources and the differences between them
+// in a form suitable for review, e.g. before a change is applied.
+package diff
+
+import (
+	"fmt"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/pmezard/go-difflib/difflib"
+	"go.yaml.in/yaml/v4"
+)
+
+// ResourceYAML renders a resource the way omnictl does. A nil resource renders as an empty document.
+func ResourceYAML(r resource.Resource) (string, error) {
+	if r == nil {
+		return "", nil
+	}
+
+	doc, err := resource.MarshalYAML(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal %s: %w", resource.String(r), err)
+	}
+
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal %s: %w", resource.String(r), err)
+	}
+	return string(out), nil
+}
+
+// Unified returns a unified diff between two documents, or an empty string if they are equal
+func Unified(from, to, fromName, toName string) string {
+	if from == to {
+		return ""
+	}
+
+	out, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(from),
+		B:        difflib.SplitLines(to),
+		FromFile: fromName,
+		ToFile:   toName,
+		Context:  3,
+	})
+	if err != nil {
+		// Writing to a string buffer does not fail
+		return ""
+	}
+	return out
+}
+
+// Resources returns a unified diff between the current and the desired state of a resource.
+// Either side may be nil for creations and deletions.
+func Resources(current, desired resource.Resource) (string, error) {
+	from, err := ResourceYAML(current)
+	if err != nil {
+		return "", err
+	}
+
+	to, err := ResourceYAML(desired)
+	if err != nil {
+		return "", err
+	}
+
+	fromName, toName := "/dev/null", "/dev/null"
+	if current != nil {
+		fromName = "current/" + resourceName(current)
+	}
+	if desired != nil {
+		toName = "desired/" + resourceName(desired)
+	}
+
+	return Unified(from, to, fromName, toName), nil
+}
+
+func resourceName(r resource.Resource) string {
+	return string(r.Metadata().Type()) + "/" + r.Metadata().ID()
+}
@@ -0,0 +1,41 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnified_Equal(t *testing.T) {
+	assert.Empty(t, Unified("a: 1\n", "a: 1\n", "from", "to"))
+}
+
+func TestResources_Create(t *testing.T) {
+	desired := omni.NewCluster(resources.DefaultNamespace, "test-cluster")
+	desired.TypedSpec().Value.KubernetesVersion = "1.30.1"
+
+	out, err := Resources(nil, desired)
+	require.NoError(t, err)
+
+	assert.Contains(t, out, "--- /dev/null")
+	assert.Contains(t, out, "+++ desired/Clusters.omni.sidero.dev/test-cluster")
+	assert.Contains(t, out, "+    kubernetesversion: 1.30.1")
+}
+
+func TestResources_Update(t *testing.T) {
+	current := omni.NewCluster(resources.DefaultNamespace, "test-cluster")
+	current.TypedSpec().Value.KubernetesVersion = "1.30.1"
+
+	desired := current.DeepCopy().(*omni.Cluster)
+	desired.TypedSpec().Value.KubernetesVersion = "1.31.0"
+
+	out, err := Resources(current, desired)
+	require.NoError(t, err)
+
+	assert.Contains(t, out, "-    kubernetesversion: 1.30.1")
+	assert.Contains(t, out, "+    kubernetesversion: 1.31.0")
+	assert.NotContains(t, out, "-    talosversion")
+}
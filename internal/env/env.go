@@ -0,0 +1,38 @@
+// Package env reads typed settings from environment variables.
+// An unset variable yields the default; an invalid value is logged and also yields the default.
+package env
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Duration returns the duration in the named variable, e.g. "30s", or def
+func Duration(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Warning: invalid duration %q for %s, using %s", value, name, def)
+		return def
+	}
+	return d
+}
+
+// Int returns the integer in the named variable, or def
+func Int(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Warning: invalid integer %q for %s, using %d", value, name, def)
+		return def
+	}
+	return n
+}
@@ -0,0 +1,30 @@
+package env
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDuration(t *testing.T) {
+	t.Setenv("TEST_ENV_DURATION", "")
+	assert.Equal(t, time.Minute, Duration("TEST_ENV_DURATION", time.Minute))
+
+	t.Setenv("TEST_ENV_DURATION", "90s")
+	assert.Equal(t, 90*time.Second, Duration("TEST_ENV_DURATION", time.Minute))
+
+	t.Setenv("TEST_ENV_DURATION", "soon")
+	assert.Equal(t, time.Minute, Duration("TEST_ENV_DURATION", time.Minute))
+}
+
+func TestInt(t *testing.T) {
+	t.Setenv("TEST_ENV_INT", "")
+	assert.Equal(t, 5, Int("TEST_ENV_INT", 5))
+
+	t.Setenv("TEST_ENV_INT", "3")
+	assert.Equal(t, 3, Int("TEST_ENV_INT", 5))
+
+	t.Setenv("TEST_ENV_INT", "three")
+	assert.Equal(t, 5, Int("TEST_ENV_INT", 5))
+}
@@ -0,0 +1,589 @@
+// Package gitops reconciles Omni with cluster templates and config patches kept in a directory,
+// such as a git checkout, applying them through the same services as the HTTP handlers.
+package gitops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/safe"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/jubblin/omni-api/internal/admission"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/jubblin/omni-api/internal/diff"
+	"github.com/jubblin/omni-api/internal/patchhistory"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Author is recorded in the config patch history for patches written by the reconciler,
+// set as client.ManagedByLabel on the config patches it writes, and the user admission policies see
+const Author = "gitops"
+
+// Mode selects whether the reconciler changes Omni
+type Mode string
+
+const (
+	// ModeApply applies the desired state
+	ModeApply Mode = "apply"
+	// ModeObserve only reports the changes applying the desired state would make
+	ModeObserve Mode = "observe"
+)
+
+// Source kinds
+const (
+	KindClusterTemplate = "cluster-template"
+	KindConfigPatch     = "config-patch"
+)
+
+// Config configures the reconciler
+type Config struct {
+	Dir      string        // Directory with the desired state; empty disables the reconciler
+	Interval time.Duration // Time between syncs
+	Timeout  time.Duration // Time a sync may take, including waiting for pruned resources to be destroyed
+	Mode     Mode
+	Prune    bool // Destroy resources that were removed from the desired state
+}
+
+// ConfigFromEnv builds a Config from environment variables
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Dir:      os.Getenv("OMNI_API_GITOPS_DIR"),
+		Interval: time.Minute,
+		Timeout:  10 * time.Minute,
+		Mode:     ModeApply,
+		Prune:    os.Getenv("OMNI_API_GITOPS_PRUNE") == "true",
+	}
+
+	for name, target := range map[string]*time.Duration{
+		"OMNI_API_GITOPS_INTERVAL": &cfg.Interval,
+		"OMNI_API_GITOPS_TIMEOUT":  &cfg.Timeout,
+	} {
+		if value := os.Getenv(name); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				log.Printf("Warning: invalid value %q for %s, using %s", value, name, *target)
+				continue
+			}
+			*target = d
+		}
+	}
+
+	switch mode := Mode(os.Getenv("OMNI_API_GITOPS_MODE")); mode {
+	case "":
+	case ModeApply, ModeObserve:
+		cfg.Mode = mode
+	default:
+		log.Printf("Warning: invalid value %q for OMNI_API_GITOPS_MODE, using %s", mode, cfg.Mode)
+	}
+
+	return cfg
+}
+
+// Change is a difference between the desired state and Omni
+type Change struct {
+	Action  string `json:"action"`
+	Type    string `json:"type"`
+	ID      string `json:"id"`
+	Diff    string `json:"diff,omitempty"`
+	Applied bool   `json:"applied"`
+}
+
+// SourceStatus is the result of syncing a file of the desired state
+type SourceStatus struct {
+	File    string   `json:"file"` // Relative to the directory
+	Kind    string   `json:"kind,omitempty"`
+	Name    string   `json:"name,omitempty"` // Cluster of a template, or ID of a config patch
+	Error   string   `json:"error,omitempty"`
+	Changes []Change `json:"changes,omitempty"`
+}
+
+// Status is the result of the latest sync
+type Status struct {
+	Enabled     bool           `json:"enabled"`
+	Dir         string         `json:"dir,omitempty"`
+	Mode        Mode           `json:"mode,omitempty"`
+	Prune       bool           `json:"prune"`
+	Revision    string         `json:"revision,omitempty"` // Commit checked out in the directory, if it is a git checkout
+	Syncing     bool           `json:"syncing"`
+	LastSync    time.Time      `json:"last_sync"`
+	LastSuccess time.Time      `json:"last_success"`
+	Error       string         `json:"error,omitempty"`
+	Sources     []SourceStatus `json:"sources"`
+	Pruned      []Change       `json:"pruned,omitempty"` // Config patches removed from the directory
+}
+
+// InSync reports whether the latest sync found no errors and left no changes unapplied
+func (s Status) InSync() bool {
+	if s.LastSync.IsZero() || s.Error != "" {
+		return false
+	}
+	for _, src := range s.Sources {
+		if src.Error != "" {
+			return false
+		}
+		for _, change := range src.Changes {
+			if !change.Applied {
+				return false
+			}
+		}
+	}
+	for _, change := range s.Pruned {
+		if !change.Applied {
+			return false
+		}
+	}
+	return true
+}
+
+// Reconciler syncs the desired state in a directory to Omni
+type Reconciler struct {
+	cfg        Config
+	state      state.State
+	templates  client.TemplateService
+	management client.ManagementService
+	history    *patchhistory.History
+	admission  *admission.Controller // Every write must pass the policies of its equivalent HTTP request
+
+	trigger chan struct{}
+
+	mu     sync.Mutex
+	status Status
+}
+
+// New creates a Reconciler
+func New(cfg Config, st state.State, templates client.TemplateService, mgmt client.ManagementService, history *patchhistory.History, admissionController *admission.Controller) *Reconciler {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Minute
+	}
+	if cfg.Mode == "" {
+		cfg.Mode = ModeApply
+	}
+
+	return &Reconciler{
+		cfg:        cfg,
+		state:      st,
+		templates:  templates,
+		management: mgmt,
+		history:    history,
+		admission:  admissionController,
+		trigger:    make(chan struct{}, 1),
+		status:     Status{Enabled: cfg.Dir != "", Dir: cfg.Dir, Mode: cfg.Mode, Prune: cfg.Prune, Sources: []SourceStatus{}},
+	}
+}
+
+// Enabled reports whether a directory is configured
+func (r *Reconciler) Enabled() bool {
+	return r.cfg.Dir != ""
+}
+
+// Run syncs every interval, or when triggered, until ctx is done
+func (r *Reconciler) Run(ctx context.Context) {
+	if !r.Enabled() {
+		return
+	}
+
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		r.Sync(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.trigger:
+		}
+	}
+}
+
+// Trigger requests a sync without waiting for the interval
+func (r *Reconciler) Trigger() {
+	select {
+	case r.trigger <- struct{}{}:
+	default:
+		// A sync is already pending
+	}
+}
+
+// Status returns the result of the latest sync
+func (r *Reconciler) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := r.status
+	status.Sources = slices.Clone(r.status.Sources)
+	status.Pruned = slices.Clone(r.status.Pruned)
+	return status
+}
+
+// Sync reconciles Omni with the directory once and returns the result
+func (r *Reconciler) Sync(ctx context.Context) Status {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+
+	r.mu.Lock()
+	r.status.Syncing = true
+	r.mu.Unlock()
+
+	result := Status{Enabled: true, Dir: r.cfg.Dir, Mode: r.cfg.Mode, Prune: r.cfg.Prune, Sources: []SourceStatus{}}
+	result.Revision = gitRevision(r.cfg.Dir)
+
+	sources, err := loadSources(r.cfg.Dir)
+	if err != nil {
+		result.Error = err.Error()
+	}
+	r.resolveNames(sources)
+
+	desiredPatches := map[string]bool{}
+	var unparsed []string
+	for _, src := range sources {
+		status := SourceStatus{File: src.file, Kind: src.kind, Name: src.name}
+		switch {
+		case src.kind == KindConfigPatch && src.name != "":
+			// Patches that fail to sync are kept, as their file may only be broken for now
+			desiredPatches[src.name] = true
+		case src.kind != KindClusterTemplate && src.err != nil:
+			// The patches a broken file defines are not known, so none may be pruned
+			unparsed = append(unparsed, src.file)
+		}
+
+		var err error
+		switch {
+		case src.err != nil:
+			err = src.err
+		case src.kind == KindClusterTemplate:
+			status.Changes, err = r.syncTemplate(ctx, src.data)
+		case src.kind == KindConfigPatch:
+			status.Changes, err = r.syncPatch(ctx, src.name, src.cluster, src.patch)
+		}
+		if err != nil {
+			status.Error = err.Error()
+		}
+		result.Sources = append(result.Sources, status)
+	}
+
+	// A directory that cannot be read must not prune everything
+	switch {
+	case result.Error != "":
+	case len(unparsed) > 0:
+		result.Error = fmt.Sprintf("config patches are not pruned while %s cannot be parsed", strings.Join(unparsed, ", "))
+	default:
+		if result.Pruned, err = r.prunePatches(ctx, desiredPatches); err != nil {
+			result.Error = err.Error()
+		}
+	}
+
+	result.LastSync = time.Now().UTC()
+	failed := result.Error != ""
+	for _, src := range result.Sources {
+		failed = failed || src.Error != ""
+	}
+	if failed {
+		log.Printf("GitOps sync of %s failed, see the status endpoint for details", r.cfg.Dir)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result.LastSuccess = r.status.LastSuccess
+	if !failed {
+		result.LastSuccess = result.LastSync
+	}
+	r.status = result
+	return result
+}
+
+// resolveNames sets the cluster names of templates and fails sources that define the same cluster or config patch
+func (r *Reconciler) resolveNames(sources []*source) {
+	owners := map[string][]*source{}
+	for _, src := range sources {
+		if src.err != nil {
+			continue
+		}
+		if src.kind == KindClusterTemplate {
+			if src.name, src.err = r.templates.Validate(src.data); src.err != nil {
+				continue
+			}
+		}
+		key := src.kind + "/" + src.name
+		owners[key] = append(owners[key], src)
+	}
+
+	for _, srcs := range owners {
+		if len(srcs) < 2 {
+			continue
+		}
+		for _, src := range srcs {
+			others := make([]string, 0, len(srcs)-1)
+			for _, other := range srcs {
+				if other != src {
+					others = append(others, other.file)
+				}
+			}
+			src.err = fmt.Errorf("%s %s is also defined in %s", src.kind, src.name, strings.Join(others, ", "))
+		}
+	}
+}
+
+// syncTemplate plans a cluster template and applies it in apply mode
+func (r *Reconciler) syncTemplate(ctx context.Context, data []byte) ([]Change, error) {
+	plan, err := r.templates.Plan(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for _, change := range plan.Changes {
+		changes = append(changes, newChange(change))
+	}
+	var prune []Change
+	for _, phase := range plan.Prune {
+		for _, change := range phase {
+			prune = append(prune, newChange(change))
+		}
+	}
+
+	if r.cfg.Mode != ModeApply {
+		return append(changes, prune...), nil
+	}
+	if len(plan.Changes) > 0 || (r.cfg.Prune && len(plan.Prune) > 0) {
+		req := admission.Request{Method: http.MethodPost, Route: "/cluster-templates:apply", Query: map[string]string{"prune": strconv.FormatBool(r.cfg.Prune)}, Body: data}
+		if err := r.admit(ctx, req); err != nil {
+			return append(changes, prune...), err
+		}
+	}
+
+	if len(plan.Changes) > 0 {
+		if err := r.templates.Apply(ctx, plan); err != nil {
+			return append(changes, prune...), err
+		}
+		r.recordChanges(plan.Changes)
+		markApplied(changes)
+	}
+	if r.cfg.Prune && len(plan.Prune) > 0 {
+		var started int
+		err := r.templates.Prune(ctx, plan, func(phase, _ int) { started = phase })
+
+		// Pruning stops at the first failing phase; only the phases before it are recorded
+		done := started
+		if err != nil && done > 0 {
+			done--
+		}
+		for _, phase := range plan.Prune[:done] {
+			r.recordChanges(phase)
+		}
+		if err != nil {
+			return append(changes, prune...), err
+		}
+		markApplied(prune)
+	}
+	return append(changes, prune...), nil
+}
+
+// syncPatch compares a config patch with Omni and writes it in apply mode, marking it as managed by the reconciler
+func (r *Reconciler) syncPatch(ctx context.Context, id, cluster, data string) ([]Change, error) {
+	ctx = client.WithManagedBy(ctx, Author)
+
+	action := patchhistory.ActionUpdate
+	planned, err := r.management.UpdateConfigPatch(client.WithDryRun(ctx), id, data)
+	if status.Code(err) == codes.NotFound {
+		action = patchhistory.ActionCreate
+		planned, err = r.management.CreateConfigPatch(client.WithDryRun(ctx), id, cluster, data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if planned.Current != nil {
+		if current, _ := planned.Current.Metadata().Labels().Get(omni.LabelCluster); current != cluster {
+			return nil, fmt.Errorf("config patch %s belongs to cluster %q", id, current)
+		}
+		owner, _ := planned.Current.Metadata().Labels().Get(client.ManagedByLabel)
+		if patchData(planned.Current) == data && owner == Author {
+			return nil, nil
+		}
+	}
+
+	change := newChange(planned)
+	if r.cfg.Mode != ModeApply {
+		return []Change{change}, nil
+	}
+
+	req := admission.Request{Method: http.MethodPut, Route: "/configpatches/:id", Params: map[string]string{"id": id}}
+	body := map[string]string{"data": data}
+	if action == patchhistory.ActionCreate {
+		req = admission.Request{Method: http.MethodPost, Route: "/configpatches"}
+		body = map[string]string{"id": id, "cluster": cluster, "data": data}
+	}
+	if req.Body, err = json.Marshal(body); err != nil {
+		return []Change{change}, err
+	}
+	if err := r.admit(ctx, req); err != nil {
+		return []Change{change}, err
+	}
+
+	var written *client.Change
+	if action == patchhistory.ActionCreate {
+		written, err = r.management.CreateConfigPatch(ctx, id, cluster, data)
+	} else {
+		written, err = r.management.UpdateConfigPatch(ctx, id, data)
+	}
+	if err != nil {
+		return []Change{change}, err
+	}
+	r.record(written, action)
+
+	change.Applied = true
+	return []Change{change}, nil
+}
+
+// prunePatches deletes the config patches the reconciler wrote whose files were removed since, if pruning is enabled.
+// The patches are found by their client.ManagedByLabel, so patches removed while the server was down are pruned too.
+// Deletions denied by an admission policy are returned as an error after the other patches were pruned.
+func (r *Reconciler) prunePatches(ctx context.Context, desired map[string]bool) ([]Change, error) {
+	managed, err := safe.StateListAll[*omni.ConfigPatch](ctx, r.state, state.WithLabelQuery(resource.LabelEqual(client.ManagedByLabel, Author)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list config patches managed by GitOps: %w", err)
+	}
+
+	var removed []string
+	for cp := range managed.All() {
+		if !desired[cp.Metadata().ID()] {
+			removed = append(removed, cp.Metadata().ID())
+		}
+	}
+	slices.Sort(removed)
+
+	var changes []Change
+	var denied []error
+	for _, id := range removed {
+		change := Change{Action: string(client.ChangeDestroy), Type: omni.ConfigPatchType, ID: id}
+		if r.cfg.Mode != ModeApply || !r.cfg.Prune {
+			changes = append(changes, change)
+			continue
+		}
+		if err := r.admit(ctx, admission.Request{Method: http.MethodDelete, Route: "/configpatches/:id", Params: map[string]string{"id": id}}); err != nil {
+			denied = append(denied, fmt.Errorf("config patch %s: %w", id, err))
+			changes = append(changes, change)
+			continue
+		}
+
+		written, err := r.management.DeleteConfigPatch(ctx, id)
+		if err != nil && status.Code(err) != codes.NotFound {
+			log.Printf("GitOps failed to prune config patch %s: %v", id, err)
+			changes = append(changes, change)
+			continue
+		}
+		if written != nil {
+			r.record(written, patchhistory.ActionDelete)
+		}
+
+		change.Applied = true
+		changes = append(changes, change)
+	}
+	return changes, errors.Join(denied...)
+}
+
+// admit evaluates the admission policies against the HTTP request equivalent to a write of the reconciler.
+// The route and its parameters make up the path; the user is Author.
+func (r *Reconciler) admit(ctx context.Context, req admission.Request) error {
+	if r.admission == nil {
+		return nil
+	}
+
+	req.Path = req.Route
+	for name, value := range req.Params {
+		req.Path = strings.Replace(req.Path, ":"+name, value, 1)
+	}
+	if req.Params == nil {
+		req.Params = map[string]string{}
+	}
+	if req.Query == nil {
+		req.Query = map[string]string{}
+	}
+	req.User = Author
+
+	denial, err := r.admission.Admit(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to evaluate admission policies: %w", err)
+	}
+	if denial != nil {
+		return fmt.Errorf("denied by admission policy %s: %s", denial.Policy, denial.Message)
+	}
+	return nil
+}
+
+func (r *Reconciler) record(change *client.Change, action patchhistory.Action) {
+	if r.history == nil {
+		return
+	}
+	if _, err := r.history.RecordChange(change, action, Author, 0); err != nil {
+		log.Printf("Error recording config patch history: %v", err)
+	}
+}
+
+// recordChanges records the config patches a cluster template wrote
+func (r *Reconciler) recordChanges(changes []*client.Change) {
+	if r.history == nil {
+		return
+	}
+	if err := r.history.RecordChanges(changes, Author); err != nil {
+		log.Printf("Error recording config patch history: %v", err)
+	}
+}
+
+func newChange(change *client.Change) Change {
+	res := change.Desired
+	if res == nil {
+		res = change.Current
+	}
+
+	// Rendering resources does not fail for Omni resources
+	changeDiff, _ := diff.Resources(change.Current, change.Desired) //nolint:errcheck
+
+	return Change{
+		Action: string(change.Action),
+		Type:   res.Metadata().Type(),
+		ID:     res.Metadata().ID(),
+		Diff:   changeDiff,
+	}
+}
+
+func markApplied(changes []Change) {
+	for i := range changes {
+		changes[i].Applied = true
+	}
+}
+
+// patchData returns the content of a config patch resource
+func patchData(r resource.Resource) string {
+	cp, ok := r.(*omni.ConfigPatch)
+	if !ok {
+		return ""
+	}
+
+	data, err := cp.TypedSpec().Value.GetUncompressedData()
+	if err != nil {
+		return ""
+	}
+	defer data.Free()
+
+	return string(data.Data())
+}
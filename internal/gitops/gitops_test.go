@@ -0,0 +1,399 @@
+package gitops
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cosi-project/runtime/pkg/safe"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/cosi-project/runtime/pkg/state/impl/inmem"
+	"github.com/cosi-project/runtime/pkg/state/impl/namespaced"
+	"github.com/jubblin/omni-api/internal/admission"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/jubblin/omni-api/internal/patchhistory"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.yaml.in/yaml/v4"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeTemplates plans a cluster and a config patch for every template and records the templates it applied
+type fakeTemplates struct {
+	client.TemplateService
+	applied []string
+}
+
+func (f *fakeTemplates) Validate(tmpl []byte) (string, error) {
+	var doc struct {
+		Name string `yaml:"name"`
+	}
+	if err := yaml.Unmarshal(tmpl, &doc); err != nil || doc.Name == "" {
+		return "", &client.TemplateError{Errors: []string{"cluster name is required"}}
+	}
+	return doc.Name, nil
+}
+
+func (f *fakeTemplates) Plan(_ context.Context, tmpl []byte) (*client.TemplatePlan, error) {
+	name, err := f.Validate(tmpl)
+	if err != nil {
+		return nil, err
+	}
+	patch := omni.NewConfigPatch("default", "400-"+name+"-template")
+	patch.Metadata().Labels().Set(omni.LabelCluster, name)
+	if err := patch.TypedSpec().Value.SetUncompressedData([]byte("machine: {}\n")); err != nil {
+		return nil, err
+	}
+	return &client.TemplatePlan{
+		Cluster: name,
+		Changes: []*client.Change{
+			{Action: client.ChangeCreate, Desired: omni.NewCluster("default", name)},
+			{Action: client.ChangeCreate, Desired: patch},
+		},
+	}, nil
+}
+
+func (f *fakeTemplates) Apply(_ context.Context, plan *client.TemplatePlan) error {
+	f.applied = append(f.applied, plan.Cluster)
+	return nil
+}
+
+// fakeManagement writes config patches to an in-memory state
+type fakeManagement struct {
+	client.ManagementService
+	st state.State
+}
+
+func newFakeManagement() *fakeManagement {
+	return &fakeManagement{st: state.WrapCore(namespaced.NewState(inmem.Build))}
+}
+
+func (f *fakeManagement) put(t *testing.T, id, cluster, data string) {
+	cp := omni.NewConfigPatch("default", id)
+	cp.Metadata().Labels().Set(omni.LabelCluster, cluster)
+	require.NoError(t, cp.TypedSpec().Value.SetUncompressedData([]byte(data)))
+	require.NoError(t, f.st.Create(context.Background(), cp))
+}
+
+func (f *fakeManagement) get(id string) (*omni.ConfigPatch, error) {
+	return safe.StateGet[*omni.ConfigPatch](context.Background(), f.st, omni.NewConfigPatch("default", id).Metadata())
+}
+
+func (f *fakeManagement) CreateConfigPatch(ctx context.Context, id, cluster, data string) (*client.Change, error) {
+	if _, err := f.get(id); err == nil {
+		return nil, status.Errorf(codes.AlreadyExists, "config patch %s already exists", id)
+	}
+	desired := omni.NewConfigPatch("default", id)
+	desired.Metadata().Labels().Set(omni.LabelCluster, cluster)
+	if owner := client.ManagedBy(ctx); owner != "" {
+		desired.Metadata().Labels().Set(client.ManagedByLabel, owner)
+	}
+	if err := desired.TypedSpec().Value.SetUncompressedData([]byte(data)); err != nil {
+		return nil, err
+	}
+	if !client.IsDryRun(ctx) {
+		if err := f.st.Create(ctx, desired); err != nil {
+			return nil, err
+		}
+	}
+	return &client.Change{Action: client.ChangeCreate, Desired: desired}, nil
+}
+
+func (f *fakeManagement) UpdateConfigPatch(ctx context.Context, id, data string) (*client.Change, error) {
+	current, err := f.get(id)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "config patch %s not found", id)
+	}
+	desired := current.DeepCopy().(*omni.ConfigPatch) //nolint:forcetypeassert
+	if owner := client.ManagedBy(ctx); owner != "" {
+		desired.Metadata().Labels().Set(client.ManagedByLabel, owner)
+	}
+	if err := desired.TypedSpec().Value.SetUncompressedData([]byte(data)); err != nil {
+		return nil, err
+	}
+	if !client.IsDryRun(ctx) {
+		if err := f.st.Update(ctx, desired); err != nil {
+			return nil, err
+		}
+	}
+	return &client.Change{Action: client.ChangeUpdate, Current: current, Desired: desired}, nil
+}
+
+func (f *fakeManagement) DeleteConfigPatch(ctx context.Context, id string) (*client.Change, error) {
+	current, err := f.get(id)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "config patch %s not found", id)
+	}
+	if !client.IsDryRun(ctx) {
+		if err := f.st.Destroy(ctx, current.Metadata()); err != nil {
+			return nil, err
+		}
+	}
+	return &client.Change{Action: client.ChangeDestroy, Current: current}, nil
+}
+
+const hostnamePatch = `metadata:
+  namespace: default
+  type: ConfigPatches.omni.sidero.dev
+  id: 400-hostname
+  labels:
+    omni.sidero.dev/cluster: prod
+spec:
+  data: |
+    machine:
+      network:
+        hostname: worker-1
+`
+
+func writeFile(t *testing.T, dir, name, content string) {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func newTestReconciler(t *testing.T, cfg Config) (*Reconciler, *fakeTemplates, *fakeManagement, *patchhistory.History) {
+	history, err := patchhistory.New(patchhistory.Config{})
+	require.NoError(t, err)
+
+	templates := &fakeTemplates{}
+	mgmt := newFakeManagement()
+	return New(cfg, mgmt.st, templates, mgmt, history, nil), templates, mgmt, history
+}
+
+func TestReconciler_SyncApply(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "clusters/prod.yaml", "kind: Cluster\nname: prod\n")
+	writeFile(t, dir, "patches/hostname.yaml", hostnamePatch)
+	writeFile(t, dir, "README.md", "not synced")
+	writeFile(t, dir, ".git/config.yaml", "ignored: true")
+
+	r, templates, mgmt, history := newTestReconciler(t, Config{Dir: dir, Mode: ModeApply, Prune: true})
+	mgmt.put(t, "400-hostname", "prod", "machine:\n  network:\n    hostname: old\n")
+
+	result := r.Sync(context.Background())
+	require.Empty(t, result.Error)
+	require.Len(t, result.Sources, 2)
+	assert.Equal(t, SourceStatus{File: "clusters/prod.yaml", Kind: KindClusterTemplate, Name: "prod", Changes: result.Sources[0].Changes}, result.Sources[0])
+	assert.Equal(t, []string{"prod"}, templates.applied)
+
+	patch := result.Sources[1]
+	assert.Equal(t, "patches/hostname.yaml", patch.File)
+	assert.Empty(t, patch.Error)
+	require.Len(t, patch.Changes, 1)
+	assert.True(t, patch.Changes[0].Applied)
+	assert.Contains(t, patch.Changes[0].Diff, "hostname: worker-1")
+	assert.True(t, result.InSync())
+	assert.Equal(t, result.LastSync, result.LastSuccess)
+
+	// Config patches written by templates are recorded too
+	revisions := history.Revisions("400-prod-template")
+	require.Len(t, revisions, 1)
+	assert.Equal(t, patchhistory.ActionCreate, revisions[0].Action)
+	assert.Equal(t, Author, revisions[0].Author)
+
+	revisions = history.Revisions("400-hostname")
+	require.Len(t, revisions, 2)
+	assert.Equal(t, patchhistory.ActionObserved, revisions[0].Action)
+	assert.Equal(t, Author, revisions[1].Author)
+
+	// Unchanged patches are left alone
+	result = r.Sync(context.Background())
+	assert.Empty(t, result.Sources[1].Changes)
+	assert.Len(t, history.Revisions("400-hostname"), 2)
+
+	// Patches removed from the directory are pruned
+	require.NoError(t, os.Remove(filepath.Join(dir, "patches/hostname.yaml")))
+	result = r.Sync(context.Background())
+	require.Len(t, result.Pruned, 1)
+	assert.True(t, result.Pruned[0].Applied)
+	_, err := mgmt.get("400-hostname")
+	assert.True(t, state.IsNotFoundError(err))
+	assert.Equal(t, patchhistory.ActionDelete, history.Revisions("400-hostname")[2].Action)
+}
+
+func TestReconciler_PrunesAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "hostname.yaml", hostnamePatch)
+
+	r, _, mgmt, _ := newTestReconciler(t, Config{Dir: dir, Mode: ModeApply, Prune: true})
+	mgmt.put(t, "500-manual", "prod", "machine: {}\n")
+	result := r.Sync(context.Background())
+	require.True(t, result.InSync(), result)
+
+	cp, err := mgmt.get("400-hostname")
+	require.NoError(t, err)
+	owner, _ := cp.Metadata().Labels().Get(client.ManagedByLabel)
+	assert.Equal(t, Author, owner)
+
+	// A new reconciler finds the patches the previous one wrote by their label
+	require.NoError(t, os.Remove(filepath.Join(dir, "hostname.yaml")))
+	history, err := patchhistory.New(patchhistory.Config{})
+	require.NoError(t, err)
+	r = New(Config{Dir: dir, Mode: ModeApply, Prune: true}, mgmt.st, &fakeTemplates{}, mgmt, history, nil)
+
+	result = r.Sync(context.Background())
+	require.Len(t, result.Pruned, 1)
+	assert.Equal(t, "400-hostname", result.Pruned[0].ID)
+	assert.True(t, result.Pruned[0].Applied)
+	_, err = mgmt.get("400-hostname")
+	assert.True(t, state.IsNotFoundError(err))
+
+	// Patches the reconciler did not write are left alone
+	_, err = mgmt.get("500-manual")
+	assert.NoError(t, err)
+}
+
+func TestReconciler_KeepsPatchesOfBrokenFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "hostname.yaml", hostnamePatch)
+
+	r, _, mgmt, _ := newTestReconciler(t, Config{Dir: dir, Mode: ModeApply, Prune: true})
+	result := r.Sync(context.Background())
+	require.True(t, result.InSync(), result)
+
+	for _, broken := range []string{
+		"metadata: [\n",
+		"metadata:\n  type: Clusters.omni.sidero.dev\n  id: 400-hostname\n",
+		"metadata:\n  type: ConfigPatches.omni.sidero.dev\n",
+	} {
+		writeFile(t, dir, "hostname.yaml", broken)
+
+		result = r.Sync(context.Background())
+		assert.NotEmpty(t, result.Sources[0].Error)
+		assert.Contains(t, result.Error, "config patches are not pruned while hostname.yaml cannot be parsed")
+		assert.Empty(t, result.Pruned)
+		_, err := mgmt.get("400-hostname")
+		assert.NoError(t, err, "the patch of a broken file is kept")
+	}
+}
+
+func TestReconciler_Admission(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "prod.yaml", "kind: Cluster\nname: prod\n")
+	writeFile(t, dir, "hostname.yaml", hostnamePatch)
+
+	history, err := patchhistory.New(patchhistory.Config{})
+	require.NoError(t, err)
+	templates := &fakeTemplates{}
+	mgmt := newFakeManagement()
+	mgmt.put(t, "400-removed", "prod", "machine: {}\n")
+	cp, err := mgmt.get("400-removed")
+	require.NoError(t, err)
+	cp.Metadata().Labels().Set(client.ManagedByLabel, Author)
+	require.NoError(t, mgmt.st.Update(context.Background(), cp))
+
+	controller, err := admission.New([]admission.Policy{
+		{Name: "no-templates", Routes: []string{"/cluster-templates:apply"}, Expression: `request.user != 'gitops'`},
+		{Name: "no-hostnames", Methods: []string{"POST", "PUT"}, Routes: []string{"/configpatches*"}, Expression: `!request.body.data.contains('hostname')`},
+		{Name: "keep-patches", Methods: []string{"DELETE"}, Expression: `object.metadata.labels['omni.sidero.dev/cluster'] != 'prod'`},
+	}, mgmt.st)
+	require.NoError(t, err)
+	r := New(Config{Dir: dir, Mode: ModeApply, Prune: true}, mgmt.st, templates, mgmt, history, controller)
+
+	result := r.Sync(context.Background())
+	require.Len(t, result.Sources, 2)
+	assert.Contains(t, result.Sources[0].Error, "denied by admission policy no-hostnames")
+	assert.Contains(t, result.Sources[1].Error, "denied by admission policy no-templates")
+	assert.Empty(t, templates.applied)
+	_, err = mgmt.get("400-hostname")
+	assert.True(t, state.IsNotFoundError(err))
+
+	assert.Contains(t, result.Error, "config patch 400-removed: denied by admission policy keep-patches")
+	require.Len(t, result.Pruned, 1)
+	assert.False(t, result.Pruned[0].Applied)
+	_, err = mgmt.get("400-removed")
+	assert.NoError(t, err)
+	assert.False(t, result.InSync())
+}
+
+func TestReconciler_SyncObserve(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "prod.yaml", "kind: Cluster\nname: prod\n")
+	writeFile(t, dir, "hostname.yaml", hostnamePatch)
+
+	r, templates, mgmt, history := newTestReconciler(t, Config{Dir: dir, Mode: ModeObserve})
+
+	result := r.Sync(context.Background())
+	require.Len(t, result.Sources, 2)
+	assert.Equal(t, "create", result.Sources[0].Changes[0].Action)
+	assert.False(t, result.Sources[0].Changes[0].Applied)
+	assert.Equal(t, "create", result.Sources[1].Changes[0].Action)
+	assert.False(t, result.InSync(), "unapplied changes are drift")
+
+	assert.Empty(t, templates.applied)
+	_, err := mgmt.get("400-hostname")
+	assert.True(t, state.IsNotFoundError(err))
+	assert.Empty(t, history.Revisions("400-hostname"))
+	assert.Equal(t, result, r.Status())
+}
+
+func TestReconciler_SyncErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.yaml", "kind: Cluster\nname: prod\n")
+	writeFile(t, dir, "b.yaml", "kind: Cluster\nname: prod\n")
+	writeFile(t, dir, "invalid.yaml", "kind: Cluster\n")
+	writeFile(t, dir, "other.yaml", "metadata:\n  type: Clusters.omni.sidero.dev\n")
+	writeFile(t, dir, "broken.yaml", "metadata: [\n")
+	writeFile(t, dir, "wrong-cluster.yaml", hostnamePatch)
+
+	r, templates, mgmt, _ := newTestReconciler(t, Config{Dir: dir, Mode: ModeApply})
+	mgmt.put(t, "400-hostname", "staging", "machine: {}\n")
+
+	result := r.Sync(context.Background())
+	require.Len(t, result.Sources, 6)
+	for _, src := range result.Sources {
+		assert.NotEmpty(t, src.Error, src.File)
+	}
+	assert.Contains(t, result.Sources[0].Error, "also defined in b.yaml")
+	assert.Contains(t, result.Sources[5].Error, `belongs to cluster "staging"`)
+	assert.Empty(t, templates.applied, "duplicate templates are not applied")
+	assert.False(t, result.InSync())
+	assert.True(t, result.LastSuccess.IsZero())
+}
+
+func TestReconciler_Disabled(t *testing.T) {
+	r, _, _, _ := newTestReconciler(t, Config{})
+	assert.False(t, r.Enabled())
+	assert.False(t, r.Status().Enabled)
+
+	// Run returns at once
+	r.Run(context.Background())
+}
+
+func TestParseFile_MultiplePatches(t *testing.T) {
+	second := `metadata:
+  type: ConfigPatches.omni.sidero.dev
+  id: 500-machine
+  labels:
+    omni.sidero.dev/cluster: prod
+    omni.sidero.dev/cluster-machine: machine-1
+spec:
+  data: "machine: {}"
+`
+	sources := parseFile("patches.yaml", []byte(hostnamePatch+"---\n"+second))
+	require.Len(t, sources, 2)
+	assert.Equal(t, "patches.yaml#1", sources[0].file)
+	assert.Equal(t, "400-hostname", sources[0].name)
+	assert.Equal(t, "prod", sources[0].cluster)
+	assert.Equal(t, "machine:\n  network:\n    hostname: worker-1\n", sources[0].patch)
+	assert.NoError(t, sources[0].err)
+	assert.ErrorContains(t, sources[1].err, "only cluster-wide patches are supported")
+}
+
+func TestGitRevision(t *testing.T) {
+	dir := t.TempDir()
+	assert.Empty(t, gitRevision(dir))
+
+	writeFile(t, dir, ".git/HEAD", "ref: refs/heads/main\n")
+	writeFile(t, dir, ".git/packed-refs", "# pack-refs with: peeled\nabc123 refs/heads/main\n")
+	assert.Equal(t, "abc123", gitRevision(dir))
+
+	writeFile(t, dir, ".git/refs/heads/main", "def456\n")
+	assert.Equal(t, "def456", gitRevision(dir))
+
+	writeFile(t, dir, ".git/HEAD", "0123abcd\n")
+	assert.Equal(t, "0123abcd", gitRevision(dir))
+}
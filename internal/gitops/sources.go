@@ -0,0 +1,179 @@
+package gitops
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"go.yaml.in/yaml/v4"
+)
+
+// source is a cluster template or config patch read from the directory
+type source struct {
+	file    string // Relative to the directory
+	kind    string
+	name    string // Cluster of a template, or ID of a config patch
+	cluster string // Cluster of a config patch
+	data    []byte // Template content
+	patch   string // Config patch content
+	err     error
+}
+
+// patchDocument is a config patch in the format written by omnictl
+type patchDocument struct {
+	Metadata struct {
+		Type   string            `yaml:"type"`
+		ID     string            `yaml:"id"`
+		Labels map[string]string `yaml:"labels"`
+	} `yaml:"metadata"`
+	Spec struct {
+		Data string `yaml:"data"`
+	} `yaml:"spec"`
+}
+
+// loadSources reads the YAML files below dir, sorted by path. Hidden directories such as .git are skipped.
+// A file is either a whole cluster template, or one or more config patches.
+func loadSources(dir string) ([]*source, error) {
+	var sources []*source
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ext := filepath.Ext(path); ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			sources = append(sources, &source{file: filepath.ToSlash(rel), err: err})
+			return nil
+		}
+		sources = append(sources, parseFile(filepath.ToSlash(rel), data)...)
+		return nil
+	})
+	if err != nil {
+		return sources, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	return sources, nil
+}
+
+// parseFile splits a file into its sources
+func parseFile(file string, data []byte) []*source {
+	docs, err := decodeDocuments(data)
+	if err != nil {
+		return []*source{{file: file, err: err}}
+	}
+	if len(docs) == 0 {
+		return nil
+	}
+
+	// Cluster templates start with a document that has a kind
+	var first map[string]any
+	if err := docs[0].Decode(&first); err == nil && first["kind"] != nil {
+		return []*source{{file: file, kind: KindClusterTemplate, data: data}}
+	}
+
+	sources := make([]*source, 0, len(docs))
+	for i, doc := range docs {
+		src := &source{file: file, kind: KindConfigPatch}
+		if len(docs) > 1 {
+			src.file = fmt.Sprintf("%s#%d", file, i+1)
+		}
+		src.name, src.cluster, src.patch, src.err = parsePatch(doc)
+		sources = append(sources, src)
+	}
+	return sources
+}
+
+// parsePatch reads a config patch document
+func parsePatch(doc *yaml.Node) (id, cluster, data string, err error) {
+	var patch patchDocument
+	if err := doc.Decode(&patch); err != nil {
+		return "", "", "", fmt.Errorf("line %d: %w", doc.Line, err)
+	}
+
+	switch {
+	case patch.Metadata.Type != omni.ConfigPatchType:
+		return "", "", "", fmt.Errorf("line %d: expected a cluster template or a %s resource", doc.Line, omni.ConfigPatchType)
+	case patch.Metadata.ID == "":
+		return "", "", "", fmt.Errorf("line %d: config patch has no metadata.id", doc.Line)
+	case patch.Spec.Data == "":
+		return patch.Metadata.ID, "", "", fmt.Errorf("config patch %s has no spec.data", patch.Metadata.ID)
+	}
+
+	cluster = patch.Metadata.Labels[omni.LabelCluster]
+	if cluster == "" {
+		return patch.Metadata.ID, "", "", fmt.Errorf("config patch %s has no %s label", patch.Metadata.ID, omni.LabelCluster)
+	}
+	// Machine set and machine patches belong in cluster templates
+	for _, label := range []string{omni.LabelMachineSet, omni.LabelClusterMachine, omni.LabelMachine} {
+		if _, ok := patch.Metadata.Labels[label]; ok {
+			return patch.Metadata.ID, cluster, "", fmt.Errorf("config patch %s: only cluster-wide patches are supported, found label %s", patch.Metadata.ID, label)
+		}
+	}
+
+	return patch.Metadata.ID, cluster, patch.Spec.Data, nil
+}
+
+// decodeDocuments returns the non-empty documents of a YAML stream
+func decodeDocuments(data []byte) ([]*yaml.Node, error) {
+	var docs []*yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				return docs, nil
+			}
+			return nil, fmt.Errorf("error decoding YAML: %w", err)
+		}
+		if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 && doc.Content[0].Kind != yaml.ScalarNode {
+			docs = append(docs, &doc)
+		}
+	}
+}
+
+// gitRevision returns the commit checked out in dir, or an empty string if it is not a git checkout
+func gitRevision(dir string) string {
+	gitDir := filepath.Join(dir, ".git")
+	head, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return ""
+	}
+
+	ref, ok := strings.CutPrefix(strings.TrimSpace(string(head)), "ref: ")
+	if !ok {
+		// Detached HEAD
+		return ref
+	}
+	if commit, err := os.ReadFile(filepath.Join(gitDir, filepath.FromSlash(ref))); err == nil {
+		return strings.TrimSpace(string(commit))
+	}
+
+	packed, err := os.ReadFile(filepath.Join(gitDir, "packed-refs"))
+	if err != nil {
+		return ""
+	}
+	for line := range strings.Lines(string(packed)) {
+		if commit, name, ok := strings.Cut(strings.TrimSpace(line), " "); ok && name == ref {
+			return commit
+		}
+	}
+	return ""
+}
@@ -0,0 +1,219 @@
+// Package machineconfig previews the effect of config patches on Talos machine configs rendered by Omni.
+package machineconfig
+
+import (
+	"fmt"
+	"sort"
+
+	omnimachineconfig "github.com/siderolabs/omni/client/pkg/machineconfig"
+	"github.com/siderolabs/talos/pkg/machinery/config/configloader"
+	"github.com/siderolabs/talos/pkg/machinery/config/configpatcher"
+	"github.com/siderolabs/talos/pkg/machinery/config/encoder"
+	"github.com/siderolabs/talos/pkg/machinery/config/generate/secrets"
+)
+
+// Redacted replaces secrets in redacted configs
+const Redacted = "******"
+
+// Level is the scope a config patch applies to. Omni applies cluster patches first, then machine set patches, then machine patches.
+type Level int
+
+// Patch levels in the order Omni applies them
+const (
+	LevelCluster Level = iota
+	LevelMachineSet
+	LevelMachine
+)
+
+// ParseLevel parses a level name, "machine" if empty
+func ParseLevel(name string) (Level, error) {
+	switch name {
+	case "cluster":
+		return LevelCluster, nil
+	case "machineset":
+		return LevelMachineSet, nil
+	case "machine", "":
+		return LevelMachine, nil
+	default:
+		return 0, fmt.Errorf("invalid patch level %q, expected cluster, machineset or machine", name)
+	}
+}
+
+func (l Level) String() string {
+	switch l {
+	case LevelCluster:
+		return "cluster"
+	case LevelMachineSet:
+		return "machineset"
+	default:
+		return "machine"
+	}
+}
+
+// Patch is a config patch with the scope it applies to
+type Patch struct {
+	ID    string
+	Level Level
+	Data  string
+}
+
+// Sort orders patches the way Omni applies them: by level, then by ID within a level
+func Sort(patches []Patch) {
+	sort.SliceStable(patches, func(i, j int) bool {
+		if patches[i].Level != patches[j].Level {
+			return patches[i].Level < patches[j].Level
+		}
+		return patches[i].ID < patches[j].ID
+	})
+}
+
+// BaseInput describes a machine to generate the config for that Omni applies config patches to
+type BaseInput struct {
+	Cluster           string
+	Machine           string
+	Secrets           *secrets.Bundle
+	TalosVersion      string // Talos version the cluster config was first generated for
+	KubernetesVersion string
+	Endpoint          string // SideroLink endpoint of the cluster load balancer
+	InstallDisk       string
+	InstallImage      string
+	ControlPlane      bool
+}
+
+// Base generates the config of a machine before any config patch is applied, the way Omni does
+func Base(in BaseInput) ([]byte, error) {
+	out, err := omnimachineconfig.Generate(omnimachineconfig.GenerateInput{
+		Secrets:                  in.Secrets,
+		ClusterID:                in.Cluster,
+		MachineID:                in.Machine,
+		InitialTalosVersion:      in.TalosVersion,
+		InitialKubernetesVersion: in.KubernetesVersion,
+		SiderolinkEndpoint:       in.Endpoint,
+		InstallDisk:              in.InstallDisk,
+		InstallImage:             in.InstallImage,
+		IsControlPlane:           in.ControlPlane,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate base config: %w", err)
+	}
+
+	return out.Config.EncodeBytes(encoder.WithComments(encoder.CommentsDisabled))
+}
+
+// Order returns the patches Omni applies to a machine in the order it applies them.
+// applied is the content of the patches from the ClusterMachineConfigPatches of the machine; config patches are
+// identified by their content, and patches of applied that match none of them are kept without an ID.
+// If applied is nil, the config patches are sorted instead.
+func Order(patches []Patch, applied []string) []Patch {
+	if applied == nil {
+		sorted := append([]Patch(nil), patches...)
+		Sort(sorted)
+		return sorted
+	}
+
+	byData := make(map[string][]Patch)
+	for _, patch := range patches {
+		byData[patch.Data] = append(byData[patch.Data], patch)
+	}
+
+	ordered := make([]Patch, 0, len(applied))
+	for _, data := range applied {
+		if matches := byData[data]; len(matches) > 0 {
+			ordered = append(ordered, matches[0])
+			byData[data] = matches[1:]
+			continue
+		}
+		ordered = append(ordered, Patch{Data: data})
+	}
+	return ordered
+}
+
+// Render applies patches, in order, to a base config
+func Render(base []byte, patches []Patch) ([]byte, error) {
+	loaded := make([]configpatcher.Patch, 0, len(patches))
+	for _, patch := range patches {
+		p, err := configpatcher.LoadPatch([]byte(patch.Data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid patch %q: %w", patch.ID, err)
+		}
+		loaded = append(loaded, p)
+	}
+
+	out, err := configpatcher.Apply(configpatcher.WithBytes(base), loaded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply patches: %w", err)
+	}
+
+	cfg, err := out.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	return cfg, nil
+}
+
+// Preview renders a base config with the patches Omni applies, in Omni's order (see Order), and a proposed patch.
+// The proposed patch replaces the patch with the same ID, or is inserted where Omni would apply it;
+// one without an ID goes after the other patches of its level.
+// It returns the rendered config and the IDs of the config patches applied, in order.
+func Preview(base []byte, patches []Patch, proposed Patch) ([]byte, []string, error) {
+	applied := make([]Patch, 0, len(patches)+1)
+	for _, patch := range patches {
+		// The proposed patch replaces the patch with its ID
+		if proposed.ID == "" || patch.ID != proposed.ID {
+			applied = append(applied, patch)
+		}
+	}
+
+	index := len(applied)
+	for i, patch := range applied {
+		// Omni's own patches have no ID and keep their place
+		if patch.ID != "" && after(patch, proposed) {
+			index = i
+			break
+		}
+	}
+	applied = append(applied[:index], append([]Patch{proposed}, applied[index:]...)...)
+
+	cfg, err := Render(base, applied)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ids := make([]string, 0, len(applied))
+	for i, patch := range applied {
+		if patch.ID != "" || i == index {
+			ids = append(ids, patch.ID)
+		}
+	}
+	return cfg, ids, nil
+}
+
+// InstallImage returns the installer image of a config
+func InstallImage(cfg []byte) (string, error) {
+	provider, err := configloader.NewFromBytes(cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to load config: %w", err)
+	}
+	if provider.Machine() == nil {
+		return "", nil
+	}
+	return provider.Machine().Install().Image(), nil
+}
+
+// Redact replaces the secrets of a config with Redacted
+func Redact(cfg []byte) ([]byte, error) {
+	provider, err := configloader.NewFromBytes(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	return provider.RedactSecrets(Redacted).EncodeBytes(encoder.WithComments(encoder.CommentsDisabled))
+}
+
+// after reports whether Omni applies an existing patch after the proposed one
+func after(patch, proposed Patch) bool {
+	if patch.Level != proposed.Level {
+		return patch.Level > proposed.Level
+	}
+	return proposed.ID != "" && patch.ID > proposed.ID
+}
@@ -0,0 +1,139 @@
+package machineconfig
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/siderolabs/talos/pkg/machinery/config"
+	"github.com/siderolabs/talos/pkg/machinery/config/generate/secrets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `version: v1alpha1
+machine:
+  type: worker
+  token: abcdef.0123456789abcdef
+  ca:
+    crt: Y2VydA==
+    key: ""
+  network:
+    hostname: worker-1
+cluster:
+  id: cluster-id
+  secret: cluster-secret
+  controlPlane:
+    endpoint: https://10.5.0.1:6443
+  token: abcdef.fedcba9876543210
+`
+
+func TestPreview(t *testing.T) {
+	// In Omni's order
+	patches := []Patch{
+		{ID: "100-cluster", Level: LevelCluster, Data: "cluster:\n  allowSchedulingOnControlPlanes: true\n"},
+		{ID: "400-workers", Level: LevelMachineSet, Data: "machine:\n  sysctls:\n    vm.max_map_count: \"262144\"\n"},
+		{Data: "machine:\n  certSANs:\n    - omni.local\n"},
+		{ID: "200-hostname", Level: LevelMachine, Data: "machine:\n  network:\n    hostname: worker-1\n  certSANs:\n    - worker-1.local\n"},
+	}
+
+	t.Run("machine patch goes last", func(t *testing.T) {
+		cfg, ids, err := Preview([]byte(testConfig), patches, Patch{Level: LevelMachine, Data: "machine:\n  network:\n    hostname: worker-2\n"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"100-cluster", "400-workers", "200-hostname", ""}, ids)
+		assert.Contains(t, string(cfg), "hostname: worker-2")
+	})
+
+	t.Run("later patches keep precedence", func(t *testing.T) {
+		cfg, ids, err := Preview([]byte(testConfig), patches, Patch{ID: "500-cluster", Level: LevelCluster, Data: "machine:\n  network:\n    hostname: renamed\n"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"100-cluster", "500-cluster", "400-workers", "200-hostname"}, ids)
+		assert.Contains(t, string(cfg), "hostname: worker-1")
+	})
+
+	t.Run("ordered by ID within the level", func(t *testing.T) {
+		_, ids, err := Preview([]byte(testConfig), patches, Patch{ID: "100-first", Level: LevelMachine, Data: "machine:\n  kubelet:\n    image: kubelet\n"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"100-cluster", "400-workers", "100-first", "200-hostname"}, ids)
+	})
+
+	t.Run("replaced patch no longer applies", func(t *testing.T) {
+		cfg, ids, err := Preview([]byte(testConfig), patches, Patch{ID: "400-workers", Level: LevelMachineSet, Data: "machine:\n  kubelet:\n    image: kubelet\n"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"100-cluster", "400-workers", "200-hostname"}, ids)
+		assert.NotContains(t, string(cfg), "vm.max_map_count")
+		assert.Contains(t, string(cfg), "image: kubelet")
+	})
+
+	t.Run("patches are applied once", func(t *testing.T) {
+		cfg, _, err := Preview([]byte(testConfig), patches, Patch{ID: "100-first", Level: LevelCluster, Data: "machine:\n  certSANs:\n    - first.local\n"})
+		require.NoError(t, err)
+		assert.Equal(t, 1, strings.Count(string(cfg), "worker-1.local"))
+		assert.Equal(t, 1, strings.Count(string(cfg), "omni.local"))
+		assert.Equal(t, 1, strings.Count(string(cfg), "first.local"))
+	})
+
+	t.Run("invalid patch", func(t *testing.T) {
+		_, _, err := Preview([]byte(testConfig), nil, Patch{ID: "bad", Data: "machine: [\n"})
+		assert.ErrorContains(t, err, `invalid patch "bad"`)
+	})
+}
+
+func TestOrder(t *testing.T) {
+	patches := []Patch{
+		{ID: "200-hostname", Level: LevelMachine, Data: "hostname"},
+		{ID: "100-cluster", Level: LevelCluster, Data: "cluster"},
+		{ID: "101-cluster", Level: LevelCluster, Data: "cluster"},
+	}
+
+	assert.Equal(t, []Patch{patches[1], patches[2], patches[0]}, Order(patches, nil))
+	assert.Equal(t, []Patch{{Data: "omni"}, patches[1], patches[2], patches[0]}, Order(patches, []string{"omni", "cluster", "cluster", "hostname"}))
+}
+
+func TestBase(t *testing.T) {
+	bundle, err := secrets.NewBundle(secrets.NewFixedClock(time.Now()), config.TalosVersionCurrent)
+	require.NoError(t, err)
+
+	in := BaseInput{
+		Cluster:           "prod",
+		Machine:           "machine-1",
+		Secrets:           bundle,
+		TalosVersion:      "1.8.0",
+		KubernetesVersion: "1.31.0",
+		Endpoint:          "https://prod.omni.local:6443",
+		InstallDisk:       "/dev/vda",
+		InstallImage:      "ghcr.io/siderolabs/installer:v1.8.0",
+	}
+	cfg, err := Base(in)
+	require.NoError(t, err)
+	assert.Contains(t, string(cfg), "type: worker")
+	assert.Contains(t, string(cfg), "endpoint: https://prod.omni.local:6443")
+	assert.Contains(t, string(cfg), "disk: /dev/vda")
+
+	in.ControlPlane = true
+	cfg, err = Base(in)
+	require.NoError(t, err)
+	assert.Contains(t, string(cfg), "type: controlplane")
+}
+
+func TestRedact(t *testing.T) {
+	cfg, err := Redact([]byte(testConfig))
+	require.NoError(t, err)
+
+	assert.NotContains(t, string(cfg), "cluster-secret")
+	assert.NotContains(t, string(cfg), "0123456789abcdef")
+	assert.Contains(t, string(cfg), "hostname: worker-1")
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("")
+	require.NoError(t, err)
+	assert.Equal(t, LevelMachine, level)
+
+	level, err = ParseLevel("machineset")
+	require.NoError(t, err)
+	assert.Equal(t, "machineset", level.String())
+
+	_, err = ParseLevel("node")
+	assert.Error(t, err)
+}
@@ -0,0 +1,306 @@
+// Package operations tracks long-running asynchronous actions, such as upgrades
+// and backups, by following the Omni status resources they affect.
+package operations
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jubblin/omni-api/internal/env"
+)
+
+// Status is the status of an operation
+type Status string
+
+const (
+	// StatusRunning means the operation is still being tracked
+	StatusRunning Status = "running"
+	// StatusSucceeded means the operation completed successfully
+	StatusSucceeded Status = "succeeded"
+	// StatusFailed means the operation failed or timed out
+	StatusFailed Status = "failed"
+	// StatusCanceled means the operation was canceled
+	StatusCanceled Status = "canceled"
+)
+
+var (
+	// ErrNotFound is returned for unknown operation IDs
+	ErrNotFound = errors.New("operation not found")
+	// ErrNotCancelable is returned when an operation does not support cancellation
+	ErrNotCancelable = errors.New("operation cannot be canceled")
+	// ErrCompleted is returned when canceling an operation that already completed
+	ErrCompleted = errors.New("operation already completed")
+)
+
+// Progress is reported by a Tracker while an operation runs
+type Progress struct {
+	Phase   string
+	Message string
+	Result  any // Optional structured progress, e.g. per-item results; kept if nil
+}
+
+// Tracker follows an operation until it completes, reporting progress along the way.
+// It returns nil when the operation succeeded.
+type Tracker func(ctx context.Context, report func(Progress)) error
+
+// Canceler stops an operation on the Omni side, e.g. by reverting an upgrade
+type Canceler func(ctx context.Context) error
+
+// Spec describes an operation to start
+type Spec struct {
+	Kind       string // Action kind, e.g. "kubernetes-upgrade"
+	Target     string // ID of the affected resource
+	TargetPath string // API path of the affected resource, used for links
+	Track      Tracker
+	Cancel     Canceler // Optional
+}
+
+// Operation is a point-in-time view of an operation
+type Operation struct {
+	ID          string
+	Kind        string
+	Target      string
+	TargetPath  string
+	Status      Status
+	Phase       string
+	Message     string
+	Error       string
+	Result      any // Last Result reported by the Tracker
+	Cancelable  bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	CompletedAt time.Time
+}
+
+// Done reports whether the operation reached a final status
+func (o Operation) Done() bool {
+	return o.Status != StatusRunning
+}
+
+type entry struct {
+	op       Operation
+	stop     context.CancelFunc
+	canceler Canceler
+	done     chan struct{}
+}
+
+// Config configures a Manager
+type Config struct {
+	Timeout   time.Duration // Operations still running after Timeout fail
+	Retention time.Duration // Completed operations are kept for Retention
+}
+
+// ConfigFromEnv builds a Config from environment variables
+func ConfigFromEnv() Config {
+	return Config{
+		Timeout:   env.Duration("OMNI_API_OPERATION_TIMEOUT", 2*time.Hour),
+		Retention: env.Duration("OMNI_API_OPERATION_RETENTION", 24*time.Hour),
+	}
+}
+
+// Manager runs and stores operations in memory
+type Manager struct {
+	mu        sync.Mutex
+	ops       map[string]*entry
+	timeout   time.Duration
+	retention time.Duration
+	now       func() time.Time
+}
+
+// NewManager creates a new Manager
+func NewManager(cfg Config) *Manager {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 2 * time.Hour
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = 24 * time.Hour
+	}
+
+	return &Manager{
+		ops:       make(map[string]*entry),
+		timeout:   cfg.Timeout,
+		retention: cfg.Retention,
+		now:       time.Now,
+	}
+}
+
+// Start starts tracking a new operation in the background
+func (m *Manager) Start(spec Spec) Operation {
+	ctx, stop := context.WithTimeout(context.Background(), m.timeout)
+
+	now := m.now()
+	e := &entry{
+		op: Operation{
+			ID:         newID(),
+			Kind:       spec.Kind,
+			Target:     spec.Target,
+			TargetPath: spec.TargetPath,
+			Status:     StatusRunning,
+			Cancelable: spec.Cancel != nil,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		},
+		stop:     stop,
+		canceler: spec.Cancel,
+		done:     make(chan struct{}),
+	}
+
+	m.mu.Lock()
+	m.prune()
+	m.ops[e.op.ID] = e
+	op := e.op
+	m.mu.Unlock()
+
+	go m.run(ctx, e, spec.Track)
+
+	return op
+}
+
+// Get returns an operation by ID
+func (m *Manager) Get(id string) (Operation, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.ops[id]
+	if !ok {
+		return Operation{}, false
+	}
+	return e.op, true
+}
+
+// List returns all known operations, newest first
+func (m *Manager) List() []Operation {
+	m.mu.Lock()
+	m.prune()
+	ops := make([]Operation, 0, len(m.ops))
+	for _, e := range m.ops {
+		ops = append(ops, e.op)
+	}
+	m.mu.Unlock()
+
+	sort.Slice(ops, func(i, j int) bool {
+		return ops[i].CreatedAt.After(ops[j].CreatedAt)
+	})
+	return ops
+}
+
+// Wait blocks until the operation completes or ctx is done, then returns its current state
+func (m *Manager) Wait(ctx context.Context, id string) (Operation, bool) {
+	m.mu.Lock()
+	e, ok := m.ops[id]
+	m.mu.Unlock()
+	if !ok {
+		return Operation{}, false
+	}
+
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+	}
+
+	return m.Get(id)
+}
+
+// Cancel cancels a running operation using its Canceler and stops tracking it
+func (m *Manager) Cancel(ctx context.Context, id string) (Operation, error) {
+	m.mu.Lock()
+	e, ok := m.ops[id]
+	if !ok {
+		m.mu.Unlock()
+		return Operation{}, ErrNotFound
+	}
+	op := e.op
+	m.mu.Unlock()
+
+	if op.Done() {
+		return op, ErrCompleted
+	}
+	if e.canceler == nil {
+		return op, ErrNotCancelable
+	}
+
+	if err := e.canceler(ctx); err != nil {
+		return op, err
+	}
+
+	m.finish(e, StatusCanceled, "")
+	e.stop()
+
+	op, _ = m.Get(id)
+	return op, nil
+}
+
+func (m *Manager) run(ctx context.Context, e *entry, track Tracker) {
+	defer e.stop()
+
+	err := track(ctx, func(p Progress) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		if e.op.Done() {
+			return
+		}
+		e.op.Phase = p.Phase
+		e.op.Message = p.Message
+		if p.Result != nil {
+			e.op.Result = p.Result
+		}
+		e.op.UpdatedAt = m.now()
+	})
+
+	switch {
+	case err == nil:
+		m.finish(e, StatusSucceeded, "")
+	case errors.Is(err, context.DeadlineExceeded) && ctx.Err() != nil:
+		m.finish(e, StatusFailed, fmt.Sprintf("operation did not complete within %s", m.timeout))
+	default:
+		m.finish(e, StatusFailed, err.Error())
+	}
+}
+
+// finish moves an operation to a final status; it is a no-op if the operation already completed
+func (m *Manager) finish(e *entry, status Status, errMsg string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e.op.Done() {
+		return
+	}
+
+	now := m.now()
+	e.op.Status = status
+	e.op.Error = errMsg
+	e.op.Cancelable = false
+	e.op.UpdatedAt = now
+	e.op.CompletedAt = now
+	close(e.done)
+
+	if status == StatusFailed {
+		log.Printf("Operation %s (%s %s) failed: %s", e.op.ID, e.op.Kind, e.op.Target, errMsg)
+	}
+}
+
+// prune drops completed operations older than the retention period. Callers must hold m.mu.
+func (m *Manager) prune() {
+	cutoff := m.now().Add(-m.retention)
+	for id, e := range m.ops {
+		if e.op.Done() && e.op.CompletedAt.Before(cutoff) {
+			delete(m.ops, id)
+		}
+	}
+}
+
+func newID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to generate operation ID: %v", err))
+	}
+	return "op-" + hex.EncodeToString(b)
+}
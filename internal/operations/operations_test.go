@@ -0,0 +1,176 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_Succeeds(t *testing.T) {
+	m := NewManager(Config{})
+
+	op := m.Start(Spec{
+		Kind:   "kubernetes-upgrade",
+		Target: "test-cluster",
+		Track: func(ctx context.Context, report func(Progress)) error {
+			report(Progress{Phase: "Upgrading", Message: "step 1"})
+			return nil
+		},
+	})
+	assert.Equal(t, StatusRunning, op.Status)
+	assert.False(t, op.Cancelable)
+
+	op, ok := m.Wait(context.Background(), op.ID)
+	require.True(t, ok)
+	assert.Equal(t, StatusSucceeded, op.Status)
+	assert.Equal(t, "Upgrading", op.Phase)
+	assert.True(t, op.Done())
+	assert.False(t, op.CompletedAt.IsZero())
+}
+
+func TestManager_KeepsResult(t *testing.T) {
+	m := NewManager(Config{})
+
+	op := m.Start(Spec{
+		Kind:   "bulk",
+		Target: "2 items",
+		Track: func(ctx context.Context, report func(Progress)) error {
+			report(Progress{Phase: "Running", Result: []string{"done"}})
+			report(Progress{Phase: "Finishing"})
+			return nil
+		},
+	})
+
+	op, ok := m.Wait(context.Background(), op.ID)
+	require.True(t, ok)
+	assert.Equal(t, "Finishing", op.Phase)
+	assert.Equal(t, []string{"done"}, op.Result)
+}
+
+func TestManager_Fails(t *testing.T) {
+	m := NewManager(Config{})
+
+	op := m.Start(Spec{
+		Kind: "etcd-backup",
+		Track: func(ctx context.Context, report func(Progress)) error {
+			return errors.New("etcd backup failed: disk full")
+		},
+	})
+
+	op, ok := m.Wait(context.Background(), op.ID)
+	require.True(t, ok)
+	assert.Equal(t, StatusFailed, op.Status)
+	assert.Equal(t, "etcd backup failed: disk full", op.Error)
+}
+
+func TestManager_Timeout(t *testing.T) {
+	m := NewManager(Config{Timeout: 10 * time.Millisecond})
+
+	op := m.Start(Spec{
+		Kind: "machine-reboot",
+		Track: func(ctx context.Context, report func(Progress)) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+
+	op, ok := m.Wait(context.Background(), op.ID)
+	require.True(t, ok)
+	assert.Equal(t, StatusFailed, op.Status)
+	assert.Contains(t, op.Error, "did not complete within")
+}
+
+func TestManager_WaitReturnsWhenContextDone(t *testing.T) {
+	m := NewManager(Config{})
+	release := make(chan struct{})
+	defer close(release)
+
+	op := m.Start(Spec{
+		Kind: "talos-upgrade",
+		Track: func(ctx context.Context, report func(Progress)) error {
+			<-release
+			return nil
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	op, ok := m.Wait(ctx, op.ID)
+	require.True(t, ok)
+	assert.Equal(t, StatusRunning, op.Status)
+
+	_, ok = m.Wait(ctx, "op-missing")
+	assert.False(t, ok)
+}
+
+func TestManager_Cancel(t *testing.T) {
+	m := NewManager(Config{})
+
+	var reverted bool
+	op := m.Start(Spec{
+		Kind: "kubernetes-upgrade",
+		Track: func(ctx context.Context, report func(Progress)) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+		Cancel: func(ctx context.Context) error {
+			reverted = true
+			return nil
+		},
+	})
+	assert.True(t, op.Cancelable)
+
+	op, err := m.Cancel(context.Background(), op.ID)
+	require.NoError(t, err)
+	assert.True(t, reverted)
+	assert.Equal(t, StatusCanceled, op.Status)
+	assert.False(t, op.Cancelable)
+
+	_, err = m.Cancel(context.Background(), op.ID)
+	assert.ErrorIs(t, err, ErrCompleted)
+
+	_, err = m.Cancel(context.Background(), "op-missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestManager_CancelNotSupported(t *testing.T) {
+	m := NewManager(Config{})
+	release := make(chan struct{})
+	defer close(release)
+
+	op := m.Start(Spec{
+		Kind: "bootstrap",
+		Track: func(ctx context.Context, report func(Progress)) error {
+			<-release
+			return nil
+		},
+	})
+
+	_, err := m.Cancel(context.Background(), op.ID)
+	assert.ErrorIs(t, err, ErrNotCancelable)
+}
+
+func TestManager_ListPrunesExpired(t *testing.T) {
+	m := NewManager(Config{Retention: time.Hour})
+	now := time.Now()
+	m.now = func() time.Time { return now }
+
+	first := m.Start(Spec{Kind: "etcd-backup", Track: func(ctx context.Context, report func(Progress)) error { return nil }})
+	_, _ = m.Wait(context.Background(), first.ID)
+
+	now = now.Add(time.Minute)
+	second := m.Start(Spec{Kind: "etcd-backup", Track: func(ctx context.Context, report func(Progress)) error { return nil }})
+	_, _ = m.Wait(context.Background(), second.ID)
+
+	ops := m.List()
+	require.Len(t, ops, 2)
+	assert.Equal(t, second.ID, ops[0].ID)
+
+	now = now.Add(2 * time.Hour)
+	assert.Empty(t, m.List())
+}
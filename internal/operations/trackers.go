@@ -0,0 +1,352 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/siderolabs/omni/client/api/omni/specs"
+	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+// watchFunc inspects a watch event. It returns true once the operation is complete.
+type watchFunc func(res resource.Resource, eventType state.EventType, report func(Progress)) (bool, error)
+
+// maxWatchRestarts is how many times in a row a failed watch is opened again.
+// A watch fails when the Omni client it runs on is replaced, e.g. after a credential rotation;
+// opening it again through the Holder's state continues on the new client.
+const maxWatchRestarts = 3
+
+// watchRestartDelay is the pause before a failed watch is opened again
+var watchRestartDelay = time.Second
+
+// watch follows a single resource until fn reports completion, ctx is done, or the watch keeps failing
+func watch(ctx context.Context, st state.State, ptr resource.Pointer, report func(Progress), fn watchFunc) error {
+	for restarts := 0; ; restarts++ {
+		received, err := watchOnce(ctx, st, ptr, report, fn)
+		if received {
+			restarts = 0
+		}
+
+		var failed *watchError
+		if !errors.As(err, &failed) || restarts >= maxWatchRestarts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(watchRestartDelay):
+		}
+	}
+}
+
+// watchError is a failure of an open watch, as opposed to an error of the tracked operation
+type watchError struct {
+	err error
+}
+
+func (e *watchError) Error() string {
+	return fmt.Sprintf("watch failed: %v", e.err)
+}
+
+func (e *watchError) Unwrap() error {
+	return e.err
+}
+
+// watchOnce opens a watch and follows it until fn reports completion, ctx is done, or the watch fails.
+// It reports whether any resource event was received.
+func watchOnce(ctx context.Context, st state.State, ptr resource.Pointer, report func(Progress), fn watchFunc) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events := make(chan state.Event)
+	if err := st.Watch(ctx, ptr, events); err != nil {
+		return false, fmt.Errorf("failed to watch %s %s: %w", ptr.Type(), ptr.ID(), err)
+	}
+
+	received := false
+	for {
+		select {
+		case <-ctx.Done():
+			return received, ctx.Err()
+		case event := <-events:
+			if event.Type == state.Errored {
+				return received, &watchError{err: event.Error}
+			}
+			received = true
+
+			done, err := fn(event.Resource, event.Type, report)
+			if err != nil || done {
+				return received, err
+			}
+		}
+	}
+}
+
+// KubernetesUpgradeTracker follows KubernetesUpgradeStatus until the cluster runs the given version
+func KubernetesUpgradeTracker(st state.State, clusterID, version string) Tracker {
+	return func(ctx context.Context, report func(Progress)) error {
+		ptr := resource.NewMetadata(omniresources.DefaultNamespace, omni.KubernetesUpgradeStatusType, clusterID, resource.VersionUndefined)
+
+		return watch(ctx, st, ptr, report, func(res resource.Resource, eventType state.EventType, report func(Progress)) (bool, error) {
+			status, ok := res.(*omni.KubernetesUpgradeStatus)
+			if !ok || eventType == state.Destroyed {
+				return false, nil
+			}
+
+			spec := status.TypedSpec().Value
+			report(Progress{Phase: spec.Phase.String(), Message: progressMessage(spec.Step, spec.Status)})
+
+			switch spec.Phase {
+			case specs.KubernetesUpgradeStatusSpec_Failed:
+				return false, fmt.Errorf("kubernetes upgrade failed: %s", spec.Error)
+			case specs.KubernetesUpgradeStatusSpec_Done:
+				return sameVersion(spec.LastUpgradeVersion, version), nil
+			default:
+				return false, nil
+			}
+		})
+	}
+}
+
+// TalosUpgradeTracker follows TalosUpgradeStatus until the cluster runs the given version
+func TalosUpgradeTracker(st state.State, clusterID, version string) Tracker {
+	return func(ctx context.Context, report func(Progress)) error {
+		ptr := resource.NewMetadata(omniresources.DefaultNamespace, omni.TalosUpgradeStatusType, clusterID, resource.VersionUndefined)
+
+		return watch(ctx, st, ptr, report, func(res resource.Resource, eventType state.EventType, report func(Progress)) (bool, error) {
+			status, ok := res.(*omni.TalosUpgradeStatus)
+			if !ok || eventType == state.Destroyed {
+				return false, nil
+			}
+
+			spec := status.TypedSpec().Value
+			report(Progress{Phase: spec.Phase.String(), Message: progressMessage(spec.Step, spec.Status)})
+
+			switch spec.Phase {
+			case specs.TalosUpgradeStatusSpec_Failed:
+				return false, fmt.Errorf("talos upgrade failed: %s", spec.Error)
+			case specs.TalosUpgradeStatusSpec_Done:
+				return sameVersion(spec.LastUpgradeVersion, version), nil
+			default:
+				return false, nil
+			}
+		})
+	}
+}
+
+// ClusterDestroyTracker follows ClusterDestroyStatus until the cluster is gone
+func ClusterDestroyTracker(st state.State, clusterID string) Tracker {
+	return destroyTracker(st,
+		resource.NewMetadata(omniresources.DefaultNamespace, omni.ClusterDestroyStatusType, clusterID, resource.VersionUndefined),
+		resource.NewMetadata(omniresources.DefaultNamespace, omni.ClusterType, clusterID, resource.VersionUndefined),
+		func(res resource.Resource) string {
+			if status, ok := res.(*omni.ClusterDestroyStatus); ok {
+				return status.TypedSpec().Value.Phase
+			}
+			return ""
+		},
+	)
+}
+
+// MachineSetDestroyTracker follows MachineSetDestroyStatus until the machine set is gone
+func MachineSetDestroyTracker(st state.State, machineSetID string) Tracker {
+	return destroyTracker(st,
+		resource.NewMetadata(omniresources.DefaultNamespace, omni.MachineSetDestroyStatusType, machineSetID, resource.VersionUndefined),
+		resource.NewMetadata(omniresources.DefaultNamespace, omni.MachineSetType, machineSetID, resource.VersionUndefined),
+		func(res resource.Resource) string {
+			if status, ok := res.(*omni.MachineSetDestroyStatus); ok {
+				return status.TypedSpec().Value.Phase
+			}
+			return ""
+		},
+	)
+}
+
+// destroyTracker reports the destroy status phase and completes once the destroyed resource no longer exists
+func destroyTracker(st state.State, statusPtr, targetPtr resource.Pointer, phase func(resource.Resource) string) Tracker {
+	return func(ctx context.Context, report func(Progress)) error {
+		return watch(ctx, st, statusPtr, report, func(res resource.Resource, eventType state.EventType, report func(Progress)) (bool, error) {
+			if eventType != state.Destroyed {
+				report(Progress{Phase: "Destroying", Message: phase(res)})
+				return false, nil
+			}
+
+			// The destroy status goes away together with the resource
+			_, err := st.Get(ctx, targetPtr)
+			if state.IsNotFoundError(err) {
+				report(Progress{Phase: "Destroyed"})
+				return true, nil
+			}
+			return false, nil
+		})
+	}
+}
+
+// EtcdBackupTracker follows EtcdBackupStatus until a backup newer than since completes
+func EtcdBackupTracker(st state.State, clusterID string, since time.Time) Tracker {
+	return func(ctx context.Context, report func(Progress)) error {
+		ptr := resource.NewMetadata(omniresources.DefaultNamespace, omni.EtcdBackupStatusType, clusterID, resource.VersionUndefined)
+
+		return watch(ctx, st, ptr, report, func(res resource.Resource, eventType state.EventType, report func(Progress)) (bool, error) {
+			status, ok := res.(*omni.EtcdBackupStatus)
+			if !ok || eventType == state.Destroyed {
+				return false, nil
+			}
+
+			spec := status.TypedSpec().Value
+			attempted := spec.LastBackupAttempt != nil && !spec.LastBackupAttempt.AsTime().Before(since)
+			if !attempted {
+				report(Progress{Phase: "Pending", Message: "waiting for the backup to start"})
+				return false, nil
+			}
+
+			report(Progress{Phase: spec.Status.String()})
+
+			switch spec.Status {
+			case specs.EtcdBackupStatusSpec_Error:
+				return false, fmt.Errorf("etcd backup failed: %s", spec.Error)
+			case specs.EtcdBackupStatusSpec_Ok:
+				return spec.LastBackupTime != nil && !spec.LastBackupTime.AsTime().Before(since), nil
+			default:
+				return false, nil
+			}
+		})
+	}
+}
+
+// ClusterBootstrapTracker follows ClusterBootstrapStatus until the cluster is bootstrapped
+func ClusterBootstrapTracker(st state.State, clusterID string) Tracker {
+	return func(ctx context.Context, report func(Progress)) error {
+		ptr := resource.NewMetadata(omniresources.DefaultNamespace, omni.ClusterBootstrapStatusType, clusterID, resource.VersionUndefined)
+
+		return watch(ctx, st, ptr, report, func(res resource.Resource, eventType state.EventType, report func(Progress)) (bool, error) {
+			status, ok := res.(*omni.ClusterBootstrapStatus)
+			if !ok || eventType == state.Destroyed {
+				return false, nil
+			}
+
+			if status.TypedSpec().Value.Bootstrapped {
+				report(Progress{Phase: "Bootstrapped"})
+				return true, nil
+			}
+			report(Progress{Phase: "Bootstrapping"})
+			return false, nil
+		})
+	}
+}
+
+// MachineRebootTracker follows MachineStatus until the machine disconnects and connects again
+func MachineRebootTracker(st state.State, machineID string) Tracker {
+	return func(ctx context.Context, report func(Progress)) error {
+		ptr := resource.NewMetadata(omniresources.DefaultNamespace, omni.MachineStatusType, machineID, resource.VersionUndefined)
+		wentDown := false
+
+		return watch(ctx, st, ptr, report, func(res resource.Resource, eventType state.EventType, report func(Progress)) (bool, error) {
+			status, ok := res.(*omni.MachineStatus)
+			if !ok || eventType == state.Destroyed {
+				return false, nil
+			}
+
+			connected := status.TypedSpec().Value.Connected
+			switch {
+			case !connected:
+				wentDown = true
+				report(Progress{Phase: "Rebooting", Message: "machine disconnected"})
+				return false, nil
+			case wentDown:
+				report(Progress{Phase: "Running", Message: "machine reconnected"})
+				return true, nil
+			default:
+				report(Progress{Phase: "Pending", Message: "waiting for the machine to go down"})
+				return false, nil
+			}
+		})
+	}
+}
+
+// MachineMaintenanceTracker follows MachineStatus until the machine is connected and in or out of maintenance mode.
+// The Result reports the machine's maintenance state.
+func MachineMaintenanceTracker(st state.State, machineID string, maintenance bool) Tracker {
+	return func(ctx context.Context, report func(Progress)) error {
+		ptr := resource.NewMetadata(omniresources.DefaultNamespace, omni.MachineStatusType, machineID, resource.VersionUndefined)
+
+		return watch(ctx, st, ptr, report, func(res resource.Resource, eventType state.EventType, report func(Progress)) (bool, error) {
+			status, ok := res.(*omni.MachineStatus)
+			if !ok || eventType == state.Destroyed {
+				return false, nil
+			}
+
+			spec := status.TypedSpec().Value
+			result := map[string]any{"maintenance": spec.Maintenance, "connected": spec.Connected}
+			switch {
+			case !spec.Connected:
+				report(Progress{Phase: "Rebooting", Message: "machine disconnected", Result: result})
+				return false, nil
+			case spec.Maintenance == maintenance:
+				report(Progress{Phase: "Running", Message: maintenanceMessage(spec.Maintenance), Result: result})
+				return true, nil
+			default:
+				report(Progress{Phase: "Pending", Message: maintenanceMessage(spec.Maintenance), Result: result})
+				return false, nil
+			}
+		})
+	}
+}
+
+// MachineMaintenanceUpgradeTracker follows MachineStatus until the machine is connected running the given Talos version
+func MachineMaintenanceUpgradeTracker(st state.State, machineID, version string) Tracker {
+	return func(ctx context.Context, report func(Progress)) error {
+		ptr := resource.NewMetadata(omniresources.DefaultNamespace, omni.MachineStatusType, machineID, resource.VersionUndefined)
+
+		return watch(ctx, st, ptr, report, func(res resource.Resource, eventType state.EventType, report func(Progress)) (bool, error) {
+			status, ok := res.(*omni.MachineStatus)
+			if !ok || eventType == state.Destroyed {
+				return false, nil
+			}
+
+			spec := status.TypedSpec().Value
+			result := map[string]any{"maintenance": spec.Maintenance, "talos_version": spec.TalosVersion}
+			switch {
+			case !spec.Connected:
+				report(Progress{Phase: "Upgrading", Message: "machine disconnected", Result: result})
+				return false, nil
+			case sameVersion(spec.TalosVersion, version):
+				report(Progress{Phase: "Running", Message: "machine runs Talos " + spec.TalosVersion, Result: result})
+				return true, nil
+			default:
+				report(Progress{Phase: "Pending", Message: "machine runs Talos " + spec.TalosVersion, Result: result})
+				return false, nil
+			}
+		})
+	}
+}
+
+func maintenanceMessage(maintenance bool) string {
+	if maintenance {
+		return "machine is in maintenance mode"
+	}
+	return "machine is not in maintenance mode"
+}
+
+func progressMessage(step, status string) string {
+	switch {
+	case step != "" && status != "":
+		return step + ": " + status
+	case step != "":
+		return step
+	default:
+		return status
+	}
+}
+
+// sameVersion compares versions ignoring a leading "v"
+func sameVersion(a, b string) bool {
+	return strings.TrimPrefix(a, "v") == strings.TrimPrefix(b, "v")
+}
@@ -0,0 +1,76 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/cosi-project/runtime/pkg/state/impl/inmem"
+	"github.com/cosi-project/runtime/pkg/state/impl/namespaced"
+	"github.com/siderolabs/omni/client/api/omni/specs"
+	omniresources "github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingWatchState fails the first watches it opens, like watches on an Omni client that was replaced
+type failingWatchState struct {
+	state.State
+	failures int
+	watches  int
+}
+
+func (s *failingWatchState) Watch(ctx context.Context, ptr resource.Pointer, ch chan<- state.Event, opts ...state.WatchOption) error {
+	s.watches++
+	if s.watches <= s.failures {
+		go func() {
+			select {
+			case ch <- state.Event{Type: state.Errored, Error: errors.New("client closed")}:
+			case <-ctx.Done():
+			}
+		}()
+		return nil
+	}
+	return s.State.Watch(ctx, ptr, ch, opts...)
+}
+
+func newTalosUpgradeState(t *testing.T, failures int) *failingWatchState {
+	t.Helper()
+
+	st := state.WrapCore(namespaced.NewState(inmem.Build))
+	status := omni.NewTalosUpgradeStatus(omniresources.DefaultNamespace, "prod")
+	status.TypedSpec().Value.Phase = specs.TalosUpgradeStatusSpec_Done
+	status.TypedSpec().Value.LastUpgradeVersion = "1.9.0"
+	require.NoError(t, st.Create(context.Background(), status))
+
+	return &failingWatchState{State: st, failures: failures}
+}
+
+func TestWatch_RestartsFailedWatch(t *testing.T) {
+	restoreDelay := watchRestartDelay
+	watchRestartDelay = time.Millisecond
+	t.Cleanup(func() { watchRestartDelay = restoreDelay })
+
+	st := newTalosUpgradeState(t, 1)
+
+	err := TalosUpgradeTracker(st, "prod", "1.9.0")(context.Background(), func(Progress) {})
+	require.NoError(t, err)
+	assert.Equal(t, 2, st.watches)
+}
+
+func TestWatch_GivesUpOnRepeatedFailures(t *testing.T) {
+	restoreDelay := watchRestartDelay
+	watchRestartDelay = time.Millisecond
+	t.Cleanup(func() { watchRestartDelay = restoreDelay })
+
+	st := newTalosUpgradeState(t, maxWatchRestarts+1)
+
+	err := TalosUpgradeTracker(st, "prod", "1.9.0")(context.Background(), func(Progress) {})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "watch failed: client closed")
+	assert.Equal(t, maxWatchRestarts+1, st.watches)
+}
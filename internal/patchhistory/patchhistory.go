@@ -0,0 +1,339 @@
+// Package patchhistory keeps the revisions of config patches written through the API,
+// so they can be compared and rolled back. Revisions are appended to a file as JSON lines,
+// which is rewritten with the kept revisions once trimmed ones make up half of it.
+package patchhistory
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+// Action is the kind of write that produced a revision
+type Action string
+
+const (
+	// ActionObserved records the content found in Omni before the API first changed the patch
+	ActionObserved Action = "observed"
+	// ActionCreate records a created patch
+	ActionCreate Action = "create"
+	// ActionUpdate records an updated patch
+	ActionUpdate Action = "update"
+	// ActionDelete records a deleted patch; its revision has no data
+	ActionDelete Action = "delete"
+	// ActionRollback records a patch restored to an earlier revision
+	ActionRollback Action = "rollback"
+)
+
+// Revision is a single version of a config patch
+type Revision struct {
+	Patch           string    `json:"patch"`
+	Revision        int       `json:"revision"`
+	Action          Action    `json:"action"`
+	Cluster         string    `json:"cluster,omitempty"`
+	Data            string    `json:"data"`
+	Author          string    `json:"author,omitempty"` // User the authenticating proxy passed; informational, not verified by the API
+	Time            time.Time `json:"time"`
+	ResourceVersion string    `json:"resource_version,omitempty"` // Omni resource version after the write
+	RollbackOf      int       `json:"rollback_of,omitempty"`      // Revision restored by a rollback
+}
+
+// Config configures the history
+type Config struct {
+	File         string // Path revisions are appended to and loaded from; empty keeps them in memory only
+	MaxRevisions int    // Number of revisions kept per patch
+}
+
+// ConfigFromEnv builds a Config from environment variables
+func ConfigFromEnv() Config {
+	cfg := Config{
+		File:         os.Getenv("OMNI_API_PATCH_HISTORY_FILE"),
+		MaxRevisions: 100,
+	}
+
+	if value := os.Getenv("OMNI_API_PATCH_HISTORY_MAX_REVISIONS"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			log.Printf("Warning: invalid value %q for OMNI_API_PATCH_HISTORY_MAX_REVISIONS, using %d", value, cfg.MaxRevisions)
+		} else {
+			cfg.MaxRevisions = n
+		}
+	}
+
+	return cfg
+}
+
+// History records config patch revisions
+type History struct {
+	mu        sync.Mutex
+	file      string   // Empty keeps revisions in memory only
+	out       *os.File // Opened for appending to file
+	lines     int      // Revisions in the file, including trimmed ones
+	kept      int      // Revisions kept in memory
+	revisions map[string][]Revision
+	max       int
+	now       func() time.Time
+}
+
+// New creates a History, loading the revisions in cfg.File and opening it for appending if it is set
+func New(cfg Config) (*History, error) {
+	if cfg.MaxRevisions <= 0 {
+		cfg.MaxRevisions = 100
+	}
+
+	h := &History{revisions: map[string][]Revision{}, max: cfg.MaxRevisions, now: time.Now}
+	if cfg.File == "" {
+		return h, nil
+	}
+
+	f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config patch history: %w", err)
+	}
+	if err := h.load(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to load config patch history: %w", err)
+	}
+	h.file, h.out = cfg.File, f
+
+	// Drop revisions trimmed while loading, e.g. after MaxRevisions was lowered
+	if h.lines > h.kept {
+		if err := h.compact(); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+
+	return h, nil
+}
+
+// load reads the revisions previously written to r
+func (h *History) load(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		h.lines++
+
+		var rev Revision
+		if err := json.Unmarshal(scanner.Bytes(), &rev); err != nil || rev.Patch == "" {
+			log.Printf("Warning: skipping invalid config patch history line %d", line)
+			continue
+		}
+		h.add(rev)
+	}
+	return scanner.Err()
+}
+
+// Record adds a revision, numbering it after the latest revision of its patch and setting its time if it is zero
+func (h *History) Record(rev Revision) (Revision, error) {
+	if rev.Patch == "" {
+		return Revision{}, errors.New("revision has no patch ID")
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if revisions := h.revisions[rev.Patch]; len(revisions) > 0 {
+		rev.Revision = revisions[len(revisions)-1].Revision + 1
+	} else {
+		rev.Revision = 1
+	}
+	if rev.Time.IsZero() {
+		rev.Time = h.now().UTC()
+	}
+
+	if h.out != nil {
+		line, err := json.Marshal(rev)
+		if err != nil {
+			return Revision{}, fmt.Errorf("failed to marshal revision: %w", err)
+		}
+		if _, err := h.out.Write(append(line, '\n')); err != nil {
+			return Revision{}, fmt.Errorf("failed to write revision: %w", err)
+		}
+		h.lines++
+	}
+
+	h.add(rev)
+
+	// Compacting once trimmed revisions make up half of the file keeps rewrites rare
+	if h.out != nil && h.lines > 2*h.kept {
+		if err := h.compact(); err != nil {
+			// The revision is recorded; the file is compacted on a later write
+			log.Printf("Error compacting config patch history: %v", err)
+		}
+	}
+	return rev, nil
+}
+
+// compact rewrites the file with the revisions kept in memory. Callers must hold h.mu.
+func (h *History) compact() error {
+	tmp, err := os.CreateTemp(filepath.Dir(h.file), filepath.Base(h.file)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to compact config patch history: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	patches := make([]string, 0, len(h.revisions))
+	for patch := range h.revisions {
+		patches = append(patches, patch)
+	}
+	sort.Strings(patches)
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, patch := range patches {
+		for _, rev := range h.revisions[patch] {
+			if err := enc.Encode(rev); err != nil {
+				tmp.Close()
+				return fmt.Errorf("failed to compact config patch history: %w", err)
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to compact config patch history: %w", err)
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to compact config patch history: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to compact config patch history: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), h.file); err != nil {
+		return fmt.Errorf("failed to compact config patch history: %w", err)
+	}
+
+	f, err := os.OpenFile(h.file, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to reopen config patch history: %w", err)
+	}
+	h.out.Close()
+	h.out, h.lines = f, h.kept
+	return nil
+}
+
+// RecordChange records the config patch a write operation wrote.
+// The content of patches without revisions is recorded first as an observed revision, so it can be restored.
+func (h *History) RecordChange(change *client.Change, action Action, author string, rollbackOf int) (Revision, error) {
+	written := change.Desired
+	if written == nil {
+		written = change.Current
+	}
+
+	if _, ok := h.Latest(written.Metadata().ID()); !ok && change.Current != nil {
+		if _, err := h.Record(fromResource(change.Current, ActionObserved)); err != nil {
+			return Revision{}, err
+		}
+	}
+
+	rev := fromResource(written, action)
+	rev.Author = author
+	rev.RollbackOf = rollbackOf
+	if action == ActionDelete {
+		rev.Data = ""
+		rev.ResourceVersion = ""
+	}
+	return h.Record(rev)
+}
+
+// RecordChanges records the config patches among the changes of a bulk write, such as a cluster template apply.
+// Changes to other resources are skipped.
+func (h *History) RecordChanges(changes []*client.Change, author string) error {
+	for _, change := range changes {
+		written := change.Desired
+		if written == nil {
+			written = change.Current
+		}
+		if written == nil || written.Metadata().Type() != omni.ConfigPatchType {
+			continue
+		}
+
+		action := ActionUpdate
+		switch change.Action {
+		case client.ChangeCreate:
+			action = ActionCreate
+		case client.ChangeDestroy:
+			action = ActionDelete
+		}
+		if _, err := h.RecordChange(change, action, author, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Revisions returns the revisions kept for a patch, oldest first
+func (h *History) Revisions(patch string) []Revision {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]Revision(nil), h.revisions[patch]...)
+}
+
+// Get returns a revision of a patch
+func (h *History) Get(patch string, revision int) (Revision, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, rev := range h.revisions[patch] {
+		if rev.Revision == revision {
+			return rev, true
+		}
+	}
+	return Revision{}, false
+}
+
+// Latest returns the newest revision of a patch
+func (h *History) Latest(patch string) (Revision, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	revisions := h.revisions[patch]
+	if len(revisions) == 0 {
+		return Revision{}, false
+	}
+	return revisions[len(revisions)-1], true
+}
+
+// add keeps a revision in memory, dropping the oldest revisions of its patch beyond the limit
+func (h *History) add(rev Revision) {
+	revisions := append(h.revisions[rev.Patch], rev)
+	h.kept++
+	if len(revisions) > h.max {
+		h.kept -= len(revisions) - h.max
+		revisions = revisions[len(revisions)-h.max:]
+	}
+	h.revisions[rev.Patch] = revisions
+}
+
+// fromResource builds a revision from a config patch resource
+func fromResource(r resource.Resource, action Action) Revision {
+	rev := Revision{
+		Patch:           r.Metadata().ID(),
+		Action:          action,
+		ResourceVersion: r.Metadata().Version().String(),
+	}
+	rev.Cluster, _ = r.Metadata().Labels().Get(omni.LabelCluster)
+
+	if cp, ok := r.(*omni.ConfigPatch); ok {
+		if data, err := cp.TypedSpec().Value.GetUncompressedData(); err == nil {
+			rev.Data = string(data.Data())
+			data.Free()
+		}
+	}
+	return rev
+}
@@ -0,0 +1,127 @@
+package patchhistory
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/siderolabs/omni/client/pkg/omni/resources"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory_Record(t *testing.T) {
+	h, err := New(Config{MaxRevisions: 2})
+	require.NoError(t, err)
+
+	for _, data := range []string{"a", "b", "c"} {
+		_, err := h.Record(Revision{Patch: "400-network", Action: ActionUpdate, Data: data})
+		require.NoError(t, err)
+	}
+	_, err = h.Record(Revision{Patch: "500-other", Action: ActionCreate, Data: "x"})
+	require.NoError(t, err)
+
+	revisions := h.Revisions("400-network")
+	require.Len(t, revisions, 2)
+	assert.Equal(t, 2, revisions[0].Revision)
+	assert.Equal(t, "c", revisions[1].Data)
+	assert.False(t, revisions[1].Time.IsZero())
+
+	_, ok := h.Get("400-network", 1)
+	assert.False(t, ok, "trimmed revisions are dropped")
+
+	latest, ok := h.Latest("500-other")
+	require.True(t, ok)
+	assert.Equal(t, 1, latest.Revision)
+
+	_, err = h.Record(Revision{Data: "no patch"})
+	assert.Error(t, err)
+}
+
+func TestHistory_Persists(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "history.jsonl")
+
+	h, err := New(Config{File: file})
+	require.NoError(t, err)
+	_, err = h.Record(Revision{Patch: "400-network", Action: ActionCreate, Cluster: "prod", Data: "machine: {}", Author: "alice"})
+	require.NoError(t, err)
+	_, err = h.Record(Revision{Patch: "400-network", Action: ActionDelete})
+	require.NoError(t, err)
+
+	// Invalid lines are skipped
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = f.WriteString("not json\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	h, err = New(Config{File: file})
+	require.NoError(t, err)
+
+	revisions := h.Revisions("400-network")
+	require.Len(t, revisions, 2)
+	assert.Equal(t, "alice", revisions[0].Author)
+	assert.Equal(t, ActionDelete, revisions[1].Action)
+
+	rev, err := h.Record(Revision{Patch: "400-network", Action: ActionRollback, RollbackOf: 1, Data: "machine: {}"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, rev.Revision)
+}
+
+func TestHistory_RecordChanges(t *testing.T) {
+	h, err := New(Config{})
+	require.NoError(t, err)
+
+	created := omni.NewConfigPatch(resources.DefaultNamespace, "400-prod-network")
+	created.Metadata().Labels().Set(omni.LabelCluster, "prod")
+	require.NoError(t, created.TypedSpec().Value.SetUncompressedData([]byte("machine: {}")))
+
+	require.NoError(t, h.RecordChanges([]*client.Change{
+		{Action: client.ChangeCreate, Desired: omni.NewCluster(resources.DefaultNamespace, "prod")},
+		{Action: client.ChangeCreate, Desired: created},
+		{Action: client.ChangeDestroy, Current: omni.NewConfigPatch(resources.DefaultNamespace, "400-prod-old")},
+	}, "gitops"))
+
+	assert.Empty(t, h.Revisions("prod"), "only config patches are recorded")
+
+	rev, ok := h.Latest("400-prod-network")
+	require.True(t, ok)
+	assert.Equal(t, ActionCreate, rev.Action)
+	assert.Equal(t, "prod", rev.Cluster)
+	assert.Equal(t, "machine: {}", rev.Data)
+	assert.Equal(t, "gitops", rev.Author)
+
+	revisions := h.Revisions("400-prod-old")
+	require.Len(t, revisions, 2)
+	assert.Equal(t, ActionObserved, revisions[0].Action)
+	assert.Equal(t, ActionDelete, revisions[1].Action)
+}
+
+func TestHistory_CompactsFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "history.jsonl")
+
+	h, err := New(Config{File: file, MaxRevisions: 2})
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		_, err = h.Record(Revision{Patch: "400-network", Action: ActionUpdate, Data: "machine: {}"})
+		require.NoError(t, err)
+	}
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, strings.Count(string(data), "\n"), 4)
+
+	h, err = New(Config{File: file, MaxRevisions: 2})
+	require.NoError(t, err)
+	revisions := h.Revisions("400-network")
+	require.Len(t, revisions, 2)
+	assert.Equal(t, 9, revisions[0].Revision)
+	assert.Equal(t, 10, revisions[1].Revision)
+
+	rev, err := h.Record(Revision{Patch: "400-network", Action: ActionDelete})
+	require.NoError(t, err)
+	assert.Equal(t, 11, rev.Revision)
+}
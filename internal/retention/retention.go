@@ -0,0 +1,178 @@
+// Package retention evaluates etcd backups against a retention policy.
+// Omni keeps every backup it takes; the policy decides which of them are worth keeping,
+// so that the rest can be reported and pruned from the backup store.
+package retention
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy keeps the newest backups, and the newest backup of recent days and weeks.
+// A backup is kept if any rule keeps it. Days and weeks are in UTC; weeks are ISO weeks.
+type Policy struct {
+	KeepLast   int `json:"keep_last,omitempty"`
+	KeepDaily  int `json:"keep_daily,omitempty"`
+	KeepWeekly int `json:"keep_weekly,omitempty"`
+}
+
+// Rules that keep a backup
+const (
+	RuleLast   = "last"
+	RuleDaily  = "daily"
+	RuleWeekly = "weekly"
+)
+
+// IsZero reports whether the policy has no rules
+func (p Policy) IsZero() bool {
+	return p == Policy{}
+}
+
+// Validate checks that the policy has at least one rule and no negative counts
+func (p Policy) Validate() error {
+	if p.KeepLast < 0 || p.KeepDaily < 0 || p.KeepWeekly < 0 {
+		return fmt.Errorf("retention counts must not be negative")
+	}
+	if p.IsZero() {
+		return fmt.Errorf("retention policy needs at least one of keep_last, keep_daily and keep_weekly")
+	}
+	return nil
+}
+
+// String formats the policy as "last=7,daily=7,weekly=4", omitting unset rules
+func (p Policy) String() string {
+	var parts []string
+	for _, rule := range []struct {
+		name  string
+		count int
+	}{{RuleLast, p.KeepLast}, {RuleDaily, p.KeepDaily}, {RuleWeekly, p.KeepWeekly}} {
+		if rule.count > 0 {
+			parts = append(parts, rule.name+"="+strconv.Itoa(rule.count))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParsePolicy parses the format written by Policy.String
+func ParsePolicy(value string) (Policy, error) {
+	var p Policy
+	for _, part := range strings.Split(value, ",") {
+		name, count, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return Policy{}, fmt.Errorf("invalid retention rule %q, expected name=count", part)
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			return Policy{}, fmt.Errorf("invalid count in retention rule %q", part)
+		}
+		switch name {
+		case RuleLast:
+			p.KeepLast = n
+		case RuleDaily:
+			p.KeepDaily = n
+		case RuleWeekly:
+			p.KeepWeekly = n
+		default:
+			return Policy{}, fmt.Errorf("unknown retention rule %q, expected %s, %s or %s", name, RuleLast, RuleDaily, RuleWeekly)
+		}
+	}
+	return p, p.Validate()
+}
+
+// Backup is a backup to evaluate
+type Backup struct {
+	ID        string
+	CreatedAt time.Time
+}
+
+// Decision is the outcome for a single backup
+type Decision struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Keep      bool      `json:"keep"`
+	Rules     []string  `json:"rules,omitempty"` // Rules keeping the backup
+}
+
+// Report is the outcome of an evaluation
+type Report struct {
+	Policy     Policy     `json:"policy"`
+	Total      int        `json:"total"`
+	Kept       int        `json:"kept"`
+	Violations int        `json:"violations"`     // Backups no rule keeps
+	Backups    []Decision `json:"backups"`        // Newest first
+	Gaps       []string   `json:"gaps,omitempty"` // Days and weeks covered by the policy without a backup
+}
+
+// Evaluate decides for every backup whether the policy keeps it.
+// Gaps lists the days and weeks up to now that the daily and weekly rules cover but no backup exists for.
+func (p Policy) Evaluate(backups []Backup, now time.Time) Report {
+	sorted := slices.Clone(backups)
+	slices.SortStableFunc(sorted, func(a, b Backup) int { return b.CreatedAt.Compare(a.CreatedAt) })
+
+	report := Report{Policy: p, Total: len(sorted), Backups: make([]Decision, len(sorted))}
+	for i, backup := range sorted {
+		report.Backups[i] = Decision{ID: backup.ID, CreatedAt: backup.CreatedAt.UTC()}
+	}
+
+	for i := range min(p.KeepLast, len(sorted)) {
+		report.Backups[i].Rules = append(report.Backups[i].Rules, RuleLast)
+	}
+	keepNewestPer(report.Backups, RuleDaily, p.KeepDaily, day)
+	keepNewestPer(report.Backups, RuleWeekly, p.KeepWeekly, week)
+
+	for i := range report.Backups {
+		if report.Backups[i].Keep = len(report.Backups[i].Rules) > 0; report.Backups[i].Keep {
+			report.Kept++
+		} else {
+			report.Violations++
+		}
+	}
+
+	report.Gaps = append(gaps(report.Backups, now, p.KeepDaily, day, 24*time.Hour), gaps(report.Backups, now, p.KeepWeekly, week, 7*24*time.Hour)...)
+	return report
+}
+
+// keepNewestPer keeps the newest backup of each of the count most recent periods that have a backup
+func keepNewestPer(decisions []Decision, rule string, count int, period func(time.Time) string) {
+	seen := map[string]bool{}
+	for i := range decisions {
+		if len(seen) == count {
+			return
+		}
+		key := period(decisions[i].CreatedAt)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		decisions[i].Rules = append(decisions[i].Rules, rule)
+	}
+}
+
+// gaps returns the last count periods up to now without a backup
+func gaps(decisions []Decision, now time.Time, count int, period func(time.Time) string, length time.Duration) []string {
+	covered := map[string]bool{}
+	for _, decision := range decisions {
+		covered[period(decision.CreatedAt)] = true
+	}
+
+	var result []string
+	for i := range count {
+		key := period(now.Add(-time.Duration(i) * length))
+		if !covered[key] {
+			result = append(result, key)
+		}
+	}
+	return result
+}
+
+func day(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
+
+func week(t time.Time) string {
+	year, w := t.UTC().ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, w)
+}
@@ -0,0 +1,69 @@
+package retention
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy_Evaluate(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC) // Wednesday
+
+	backups := []Backup{
+		{ID: "tue-late", CreatedAt: time.Date(2026, 10, 13, 18, 0, 0, 0, time.UTC)},
+		{ID: "wed", CreatedAt: time.Date(2026, 10, 14, 6, 0, 0, 0, time.UTC)},
+		{ID: "tue-early", CreatedAt: time.Date(2026, 10, 13, 6, 0, 0, 0, time.UTC)},
+		{ID: "mon", CreatedAt: time.Date(2026, 10, 12, 6, 0, 0, 0, time.UTC)},
+		{ID: "last-week", CreatedAt: time.Date(2026, 10, 8, 6, 0, 0, 0, time.UTC)},
+		{ID: "two-weeks-ago", CreatedAt: time.Date(2026, 10, 1, 6, 0, 0, 0, time.UTC)},
+	}
+
+	report := Policy{KeepLast: 1, KeepDaily: 4, KeepWeekly: 2}.Evaluate(backups, now)
+
+	ids := make([]string, 0, len(report.Backups))
+	kept := map[string][]string{}
+	for _, decision := range report.Backups {
+		ids = append(ids, decision.ID)
+		if decision.Keep {
+			kept[decision.ID] = decision.Rules
+		}
+	}
+	assert.Equal(t, []string{"wed", "tue-late", "tue-early", "mon", "last-week", "two-weeks-ago"}, ids)
+	assert.Equal(t, map[string][]string{
+		"wed":       {RuleLast, RuleDaily, RuleWeekly},
+		"tue-late":  {RuleDaily},
+		"mon":       {RuleDaily},
+		"last-week": {RuleDaily, RuleWeekly},
+	}, kept)
+	assert.Equal(t, 6, report.Total)
+	assert.Equal(t, 4, report.Kept)
+	assert.Equal(t, 2, report.Violations)
+	assert.Equal(t, []string{"2026-10-11"}, report.Gaps)
+}
+
+func TestPolicy_EvaluateEmpty(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+
+	report := Policy{KeepLast: 3, KeepWeekly: 2}.Evaluate(nil, now)
+	assert.Zero(t, report.Total)
+	assert.Empty(t, report.Backups)
+	assert.Equal(t, []string{"2026-W42", "2026-W41"}, report.Gaps)
+}
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := ParsePolicy("last=7, daily=7,weekly=4")
+	require.NoError(t, err)
+	assert.Equal(t, Policy{KeepLast: 7, KeepDaily: 7, KeepWeekly: 4}, policy)
+	assert.Equal(t, "last=7,daily=7,weekly=4", policy.String())
+
+	policy, err = ParsePolicy(Policy{KeepWeekly: 2}.String())
+	require.NoError(t, err)
+	assert.Equal(t, Policy{KeepWeekly: 2}, policy)
+
+	for _, value := range []string{"", "last", "last=x", "monthly=3", "last=0", "daily=-1"} {
+		_, err := ParsePolicy(value)
+		assert.Error(t, err, value)
+	}
+}
@@ -0,0 +1,267 @@
+// Package supportbundle collects cluster support bundles in the background and keeps
+// the resulting zip archives on disk until they expire.
+package supportbundle
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/jubblin/omni-api/internal/env"
+)
+
+// Status is the status of a support bundle
+type Status string
+
+const (
+	// StatusRunning means the bundle is still being collected
+	StatusRunning Status = "running"
+	// StatusReady means the bundle can be downloaded
+	StatusReady Status = "ready"
+	// StatusFailed means collecting the bundle failed or timed out
+	StatusFailed Status = "failed"
+)
+
+var (
+	// ErrNotFound is returned for unknown or expired bundle IDs
+	ErrNotFound = errors.New("support bundle not found")
+	// ErrNotReady is returned when downloading a bundle that is not ready
+	ErrNotReady = errors.New("support bundle is not ready")
+)
+
+// Bundle is a point-in-time view of a support bundle
+type Bundle struct {
+	ID          string
+	Cluster     string
+	Status      Status
+	Error       string
+	Progress    []client.SupportBundleProgress // One entry per source, sorted by source
+	Size        int64
+	CreatedAt   time.Time
+	CompletedAt time.Time
+	ExpiresAt   time.Time // Set once the bundle completed
+}
+
+// Done reports whether the bundle reached a final status
+func (b Bundle) Done() bool {
+	return b.Status != StatusRunning
+}
+
+type entry struct {
+	bundle   Bundle
+	progress map[string]client.SupportBundleProgress
+	path     string
+	changed  chan struct{} // Closed and replaced on every change
+}
+
+// Config configures a Manager
+type Config struct {
+	Dir     string        // Directory the archives are stored in
+	TTL     time.Duration // Completed bundles are deleted after TTL
+	Timeout time.Duration // Bundles still being collected after Timeout fail
+}
+
+// ConfigFromEnv builds a Config from environment variables
+func ConfigFromEnv() Config {
+	return Config{
+		Dir:     os.Getenv("OMNI_API_SUPPORT_BUNDLE_DIR"),
+		TTL:     env.Duration("OMNI_API_SUPPORT_BUNDLE_TTL", time.Hour),
+		Timeout: env.Duration("OMNI_API_SUPPORT_BUNDLE_TIMEOUT", 30*time.Minute),
+	}
+}
+
+// Manager collects support bundles and stores them until they expire
+type Manager struct {
+	mu      sync.Mutex
+	bundles map[string]*entry
+	support client.SupportService
+	dir     string
+	ttl     time.Duration
+	timeout time.Duration
+	now     func() time.Time
+}
+
+// NewManager creates a Manager, removing archives left behind by a previous run
+func NewManager(cfg Config, support client.SupportService) (*Manager, error) {
+	if cfg.Dir == "" {
+		cfg.Dir = filepath.Join(os.TempDir(), "omni-api-support-bundles")
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = time.Hour
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Minute
+	}
+
+	if err := os.MkdirAll(cfg.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create support bundle directory: %w", err)
+	}
+	stale, err := filepath.Glob(filepath.Join(cfg.Dir, "sb-*.zip"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range stale {
+		os.Remove(path)
+	}
+
+	return &Manager{
+		bundles: make(map[string]*entry),
+		support: support,
+		dir:     cfg.Dir,
+		ttl:     cfg.TTL,
+		timeout: cfg.Timeout,
+		now:     time.Now,
+	}, nil
+}
+
+// Start starts collecting a support bundle for a cluster in the background
+func (m *Manager) Start(clusterID string) Bundle {
+	id := newID()
+	e := &entry{
+		bundle: Bundle{
+			ID:        id,
+			Cluster:   clusterID,
+			Status:    StatusRunning,
+			CreatedAt: m.now(),
+		},
+		progress: make(map[string]client.SupportBundleProgress),
+		path:     filepath.Join(m.dir, id+".zip"),
+		changed:  make(chan struct{}),
+	}
+
+	m.mu.Lock()
+	m.bundles[id] = e
+	bundle := e.snapshot()
+	m.mu.Unlock()
+
+	go m.collect(e)
+
+	return bundle
+}
+
+// Get returns a bundle by ID
+func (m *Manager) Get(id string) (Bundle, bool) {
+	bundle, _, ok := m.Watch(id)
+	return bundle, ok
+}
+
+// Watch returns a bundle by ID and a channel that is closed on its next change
+func (m *Manager) Watch(id string) (Bundle, <-chan struct{}, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.bundles[id]
+	if !ok {
+		return Bundle{}, nil, false
+	}
+	return e.snapshot(), e.changed, true
+}
+
+// Open opens the archive of a ready bundle. Callers must close the file.
+func (m *Manager) Open(id string) (*os.File, Bundle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.bundles[id]
+	if !ok {
+		return nil, Bundle{}, ErrNotFound
+	}
+	if e.bundle.Status != StatusReady {
+		return nil, e.snapshot(), ErrNotReady
+	}
+
+	// The file stays readable if the bundle expires while it is being downloaded
+	f, err := os.Open(e.path)
+	return f, e.snapshot(), err
+}
+
+func (m *Manager) collect(e *entry) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	data, err := m.support.SupportBundle(ctx, e.bundle.Cluster, func(p client.SupportBundleProgress) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		e.progress[p.Source] = p
+		e.notify()
+	})
+	if err == nil {
+		err = os.WriteFile(e.path, data, 0o600)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	e.bundle.CompletedAt = now
+	e.bundle.ExpiresAt = now.Add(m.ttl)
+	switch {
+	case err == nil:
+		e.bundle.Status = StatusReady
+		e.bundle.Size = int64(len(data))
+	case errors.Is(err, context.DeadlineExceeded) && ctx.Err() != nil:
+		e.bundle.Status = StatusFailed
+		e.bundle.Error = fmt.Sprintf("support bundle was not collected within %s", m.timeout)
+	default:
+		e.bundle.Status = StatusFailed
+		e.bundle.Error = err.Error()
+	}
+	e.notify()
+
+	if e.bundle.Status == StatusFailed {
+		log.Printf("Support bundle %s for cluster %s failed: %s", e.bundle.ID, e.bundle.Cluster, e.bundle.Error)
+	}
+
+	time.AfterFunc(m.ttl, func() { m.expire(e.bundle.ID) })
+}
+
+// expire deletes a bundle and its archive
+func (m *Manager) expire(id string) {
+	m.mu.Lock()
+	e, ok := m.bundles[id]
+	delete(m.bundles, id)
+	m.mu.Unlock()
+
+	if ok {
+		if err := os.Remove(e.path); err != nil && !os.IsNotExist(err) {
+			log.Printf("Failed to remove support bundle %s: %v", id, err)
+		}
+	}
+}
+
+// snapshot returns a copy of the bundle with its progress. Callers must hold m.mu.
+func (e *entry) snapshot() Bundle {
+	bundle := e.bundle
+	bundle.Progress = make([]client.SupportBundleProgress, 0, len(e.progress))
+	for _, p := range e.progress {
+		bundle.Progress = append(bundle.Progress, p)
+	}
+	sort.Slice(bundle.Progress, func(i, j int) bool {
+		return bundle.Progress[i].Source < bundle.Progress[j].Source
+	})
+	return bundle
+}
+
+// notify wakes up watchers. Callers must hold m.mu.
+func (e *entry) notify() {
+	close(e.changed)
+	e.changed = make(chan struct{})
+}
+
+func newID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to generate support bundle ID: %v", err))
+	}
+	return "sb-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,123 @@
+package supportbundle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/jubblin/omni-api/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSupport reports the given progress, then returns data or err
+type fakeSupport struct {
+	progress []client.SupportBundleProgress
+	data     []byte
+	err      error
+	block    chan struct{} // If set, collection waits until it is closed or the context is done
+}
+
+func (f *fakeSupport) SupportBundle(ctx context.Context, clusterID string, report func(client.SupportBundleProgress)) ([]byte, error) {
+	for _, p := range f.progress {
+		report(p)
+	}
+	if f.block != nil {
+		select {
+		case <-f.block:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return f.data, f.err
+}
+
+// waitDone watches a bundle until it completes
+func waitDone(t *testing.T, m *Manager, id string) Bundle {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		bundle, changed, ok := m.Watch(id)
+		require.True(t, ok)
+		if bundle.Done() {
+			return bundle
+		}
+		select {
+		case <-changed:
+		case <-timeout:
+			t.Fatal("support bundle did not complete")
+		}
+	}
+}
+
+func TestManager_Ready(t *testing.T) {
+	support := &fakeSupport{
+		progress: []client.SupportBundleProgress{
+			{Source: "node-2", State: "collecting", Total: 4, Value: 1},
+			{Source: "node-1", State: "collecting", Total: 4, Value: 1},
+			{Source: "node-1", State: "done", Total: 4, Value: 4},
+		},
+		data: []byte("PK zip"),
+	}
+	m, err := NewManager(Config{Dir: t.TempDir()}, support)
+	require.NoError(t, err)
+
+	bundle := m.Start("prod")
+	assert.Equal(t, StatusRunning, bundle.Status)
+	assert.Equal(t, "prod", bundle.Cluster)
+
+	bundle = waitDone(t, m, bundle.ID)
+	assert.Equal(t, StatusReady, bundle.Status)
+	assert.Equal(t, int64(6), bundle.Size)
+	assert.Equal(t, bundle.CompletedAt.Add(time.Hour), bundle.ExpiresAt)
+	require.Len(t, bundle.Progress, 2)
+	assert.Equal(t, "node-1", bundle.Progress[0].Source)
+	assert.Equal(t, 4, bundle.Progress[0].Value)
+
+	f, _, err := m.Open(bundle.ID)
+	require.NoError(t, err)
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, "PK zip", string(data))
+}
+
+func TestManager_Failed(t *testing.T) {
+	m, err := NewManager(Config{Dir: t.TempDir()}, &fakeSupport{err: errors.New("cluster unreachable")})
+	require.NoError(t, err)
+
+	bundle := waitDone(t, m, m.Start("prod").ID)
+	assert.Equal(t, StatusFailed, bundle.Status)
+	assert.Equal(t, "cluster unreachable", bundle.Error)
+
+	_, _, err = m.Open(bundle.ID)
+	assert.ErrorIs(t, err, ErrNotReady)
+}
+
+func TestManager_Timeout(t *testing.T) {
+	m, err := NewManager(Config{Dir: t.TempDir(), Timeout: 10 * time.Millisecond}, &fakeSupport{block: make(chan struct{})})
+	require.NoError(t, err)
+
+	bundle := waitDone(t, m, m.Start("prod").ID)
+	assert.Equal(t, StatusFailed, bundle.Status)
+	assert.Contains(t, bundle.Error, "not collected within")
+}
+
+func TestManager_Expires(t *testing.T) {
+	m, err := NewManager(Config{Dir: t.TempDir(), TTL: 10 * time.Millisecond}, &fakeSupport{data: []byte("PK")})
+	require.NoError(t, err)
+
+	bundle := waitDone(t, m, m.Start("prod").ID)
+	assert.Equal(t, StatusReady, bundle.Status)
+
+	assert.Eventually(t, func() bool {
+		_, ok := m.Get(bundle.ID)
+		return !ok
+	}, 5*time.Second, 5*time.Millisecond)
+
+	_, _, err = m.Open(bundle.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
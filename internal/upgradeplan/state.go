@@ -0,0 +1,36 @@
+package upgradeplan
+
+import (
+	"context"
+
+	"github.com/cosi-project/runtime/pkg/safe"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/siderolabs/omni/client/pkg/omni/resources/omni"
+)
+
+// LoadCatalog collects the Talos versions Omni knows, with the Kubernetes versions they support
+func LoadCatalog(ctx context.Context, st state.State) (Catalog, error) {
+	var catalog Catalog
+
+	talosVersions, err := safe.StateListAll[*omni.TalosVersion](ctx, st)
+	if err != nil {
+		return catalog, err
+	}
+	for tv := range talosVersions.All() {
+		spec := tv.TypedSpec().Value
+		catalog.Talos = append(catalog.Talos, TalosVersion{
+			Version:              tv.Metadata().ID(),
+			CompatibleKubernetes: spec.CompatibleKubernetesVersions,
+			Deprecated:           spec.Deprecated,
+		})
+	}
+
+	kubernetesVersions, err := safe.StateListAll[*omni.KubernetesVersion](ctx, st)
+	if err != nil {
+		return catalog, err
+	}
+	for kv := range kubernetesVersions.All() {
+		catalog.Kubernetes = append(catalog.Kubernetes, kv.TypedSpec().Value.Version)
+	}
+	return catalog, nil
+}
@@ -0,0 +1,207 @@
+// Package upgradeplan computes the ordered steps that move a cluster to newer Talos and Kubernetes versions.
+// Every step changes one component, and the cluster runs a Talos version that supports its Kubernetes
+// version after each of them.
+package upgradeplan
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/blang/semver/v4"
+)
+
+// Components a step upgrades
+const (
+	ComponentTalos      = "talos"
+	ComponentKubernetes = "kubernetes"
+)
+
+// ErrNoPath is returned when the catalog has no sequence of compatible versions leading to the target
+var ErrNoPath = errors.New("no upgrade path")
+
+// TalosVersion is a Talos version known to Omni
+type TalosVersion struct {
+	Version              string
+	CompatibleKubernetes []string
+	Deprecated           bool // Not chosen as a default target or an intermediate step
+}
+
+// Catalog holds the versions a cluster can be upgraded to
+type Catalog struct {
+	Talos      []TalosVersion
+	Kubernetes []string
+}
+
+// Step is a single upgrade of one component
+type Step struct {
+	Component string `json:"component"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	// Versions the cluster runs once the step is done
+	TalosVersion      string `json:"talos_version"`
+	KubernetesVersion string `json:"kubernetes_version"`
+}
+
+// Latest returns the newest Talos version that is not deprecated, and the newest Kubernetes version it supports.
+// Either is empty if the catalog has none.
+func (c Catalog) Latest() (talos, kubernetes string) {
+	for _, tv := range c.Talos {
+		if tv.Deprecated || !Valid(tv.Version) {
+			continue
+		}
+		if talos == "" || Compare(tv.Version, talos) > 0 {
+			talos = tv.Version
+		}
+	}
+	if talos == "" {
+		return "", ""
+	}
+	return talos, c.LatestKubernetes(talos)
+}
+
+// LatestKubernetes returns the newest Kubernetes version a Talos version supports, or "" if there is none
+func (c Catalog) LatestKubernetes(talos string) string {
+	var latest string
+	for _, version := range c.Kubernetes {
+		if !Valid(version) || !c.supports(talos, version) {
+			continue
+		}
+		if latest == "" || Compare(version, latest) > 0 {
+			latest = version
+		}
+	}
+	return latest
+}
+
+// Plan returns the steps from the current to the target versions.
+// Talos moves to the newest patch of each following minor version and Kubernetes one minor version at a time,
+// as both projects support. Talos is upgraded first whenever the next Talos version supports the running
+// Kubernetes version. The error wraps ErrNoPath if no sequence of compatible versions reaches the target.
+func (c Catalog) Plan(talos, kubernetes, targetTalos, targetKubernetes string) ([]Step, error) {
+	for _, version := range []string{talos, kubernetes, targetTalos, targetKubernetes} {
+		if !Valid(version) {
+			return nil, fmt.Errorf("invalid version %q", version)
+		}
+	}
+	if Compare(targetTalos, talos) < 0 {
+		return nil, fmt.Errorf("cannot downgrade Talos from %s to %s", talos, targetTalos)
+	}
+	if Compare(targetKubernetes, kubernetes) < 0 {
+		return nil, fmt.Errorf("cannot downgrade Kubernetes from %s to %s", kubernetes, targetKubernetes)
+	}
+	if !c.supports(targetTalos, targetKubernetes) {
+		return nil, fmt.Errorf("%w: Talos %s does not support Kubernetes %s", ErrNoPath, targetTalos, targetKubernetes)
+	}
+
+	var steps []Step
+	for talos != targetTalos || kubernetes != targetKubernetes {
+		if talos != targetTalos {
+			if next, ok := nextVersion(talos, targetTalos, c.talosVersions(targetTalos), false); ok && c.supports(next, kubernetes) {
+				steps = append(steps, Step{Component: ComponentTalos, From: talos, To: next, TalosVersion: next, KubernetesVersion: kubernetes})
+				talos = next
+				continue
+			}
+		}
+		if kubernetes != targetKubernetes {
+			if next, ok := nextVersion(kubernetes, targetKubernetes, c.kubernetesVersions(talos, targetKubernetes), true); ok {
+				steps = append(steps, Step{Component: ComponentKubernetes, From: kubernetes, To: next, TalosVersion: talos, KubernetesVersion: next})
+				kubernetes = next
+				continue
+			}
+		}
+		return steps, fmt.Errorf("%w: Talos %s and Kubernetes %s have no compatible next version on the way to Talos %s and Kubernetes %s",
+			ErrNoPath, talos, kubernetes, targetTalos, targetKubernetes)
+	}
+	return steps, nil
+}
+
+// supports reports whether a Talos version in the catalog supports a Kubernetes version
+func (c Catalog) supports(talos, kubernetes string) bool {
+	for _, tv := range c.Talos {
+		if tv.Version == talos {
+			return slices.Contains(tv.CompatibleKubernetes, kubernetes)
+		}
+	}
+	return false
+}
+
+// talosVersions returns the Talos versions usable as steps; deprecated versions only as the target
+func (c Catalog) talosVersions(target string) []string {
+	var versions []string
+	for _, tv := range c.Talos {
+		if (!tv.Deprecated || tv.Version == target) && Valid(tv.Version) {
+			versions = append(versions, tv.Version)
+		}
+	}
+	return versions
+}
+
+// kubernetesVersions returns the Kubernetes versions a Talos version supports, with the target even if it is not listed
+func (c Catalog) kubernetesVersions(talos, target string) []string {
+	var versions []string
+	for _, version := range append(slices.Clone(c.Kubernetes), target) {
+		if Valid(version) && c.supports(talos, version) && !slices.Contains(versions, version) {
+			versions = append(versions, version)
+		}
+	}
+	return versions
+}
+
+// nextVersion returns the version following current on the way to target: the newest patch of the next
+// minor version present in known, or the target itself once it is in that minor version.
+// With adjacent set, the next minor version must directly follow the current one.
+func nextVersion(current, target string, known []string, adjacent bool) (string, bool) {
+	cur, tgt := parse(current), parse(target)
+	if compareMinor(cur, tgt) == 0 {
+		return target, slices.Contains(known, target)
+	}
+
+	var next string
+	for _, version := range known {
+		v := parse(version)
+		if compareMinor(v, cur) <= 0 || compareMinor(v, tgt) > 0 {
+			continue
+		}
+		if next == "" || compareMinor(v, parse(next)) < 0 || (compareMinor(v, parse(next)) == 0 && v.GT(parse(next))) {
+			next = version
+		}
+	}
+	if next == "" {
+		return "", false
+	}
+
+	n := parse(next)
+	if adjacent && (n.Major != cur.Major || n.Minor != cur.Minor+1) {
+		return "", false
+	}
+	if compareMinor(n, tgt) == 0 {
+		return target, slices.Contains(known, target)
+	}
+	return next, true
+}
+
+func compareMinor(a, b semver.Version) int {
+	if a.Major != b.Major {
+		return int(a.Major) - int(b.Major)
+	}
+	return int(a.Minor) - int(b.Minor)
+}
+
+// Compare compares two versions, with or without a leading "v", returning -1, 0 or 1.
+// Invalid versions compare as 0.0.0.
+func Compare(a, b string) int {
+	return parse(a).Compare(parse(b))
+}
+
+// Valid reports whether a version, with or without a leading "v", is a semantic version
+func Valid(version string) bool {
+	_, err := semver.Parse(strings.TrimPrefix(version, "v"))
+	return err == nil
+}
+
+func parse(version string) semver.Version {
+	v, _ := semver.Parse(strings.TrimPrefix(version, "v")) //nolint:errcheck
+	return v
+}
@@ -0,0 +1,75 @@
+package upgradeplan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var catalog = Catalog{
+	Talos: []TalosVersion{
+		{Version: "1.7.6", CompatibleKubernetes: []string{"1.29.3", "1.30.1"}, Deprecated: true},
+		{Version: "1.8.0", CompatibleKubernetes: []string{"1.29.3", "1.30.1", "1.31.0"}},
+		{Version: "1.8.3", CompatibleKubernetes: []string{"1.29.3", "1.30.1", "1.31.0"}},
+		{Version: "1.9.0", CompatibleKubernetes: []string{"1.30.1", "1.31.0", "1.32.0"}},
+		{Version: "1.9.2", CompatibleKubernetes: []string{"1.30.1", "1.31.0", "1.32.0"}},
+		{Version: "1.10.1", CompatibleKubernetes: []string{"1.31.0", "1.32.0", "1.33.0"}},
+	},
+	Kubernetes: []string{"1.29.3", "1.30.1", "1.31.0", "1.32.0", "1.33.0"},
+}
+
+func TestCatalog_Latest(t *testing.T) {
+	talos, kubernetes := catalog.Latest()
+	assert.Equal(t, "1.10.1", talos)
+	assert.Equal(t, "1.33.0", kubernetes)
+
+	assert.Equal(t, "1.31.0", catalog.LatestKubernetes("1.8.3"))
+	assert.Empty(t, catalog.LatestKubernetes("1.6.0"))
+
+	talos, kubernetes = Catalog{}.Latest()
+	assert.Empty(t, talos)
+	assert.Empty(t, kubernetes)
+}
+
+func TestCatalog_Plan(t *testing.T) {
+	steps, err := catalog.Plan("1.8.0", "1.29.3", "1.10.1", "1.33.0")
+	require.NoError(t, err)
+	assert.Equal(t, []Step{
+		{Component: ComponentKubernetes, From: "1.29.3", To: "1.30.1", TalosVersion: "1.8.0", KubernetesVersion: "1.30.1"},
+		{Component: ComponentTalos, From: "1.8.0", To: "1.9.2", TalosVersion: "1.9.2", KubernetesVersion: "1.30.1"},
+		{Component: ComponentKubernetes, From: "1.30.1", To: "1.31.0", TalosVersion: "1.9.2", KubernetesVersion: "1.31.0"},
+		{Component: ComponentTalos, From: "1.9.2", To: "1.10.1", TalosVersion: "1.10.1", KubernetesVersion: "1.31.0"},
+		{Component: ComponentKubernetes, From: "1.31.0", To: "1.32.0", TalosVersion: "1.10.1", KubernetesVersion: "1.32.0"},
+		{Component: ComponentKubernetes, From: "1.32.0", To: "1.33.0", TalosVersion: "1.10.1", KubernetesVersion: "1.33.0"},
+	}, steps)
+
+	steps, err = catalog.Plan("1.9.2", "1.31.0", "1.9.2", "1.31.0")
+	require.NoError(t, err)
+	assert.Empty(t, steps, "already at the target")
+
+	steps, err = catalog.Plan("1.9.0", "1.31.0", "1.9.2", "1.31.0")
+	require.NoError(t, err)
+	assert.Equal(t, []Step{{Component: ComponentTalos, From: "1.9.0", To: "1.9.2", TalosVersion: "1.9.2", KubernetesVersion: "1.31.0"}}, steps)
+}
+
+func TestCatalog_PlanErrors(t *testing.T) {
+	_, err := catalog.Plan("1.9.2", "1.31.0", "1.8.3", "1.31.0")
+	assert.EqualError(t, err, "cannot downgrade Talos from 1.9.2 to 1.8.3")
+	assert.NotErrorIs(t, err, ErrNoPath)
+
+	_, err = catalog.Plan("1.9.2", "1.31.0", "1.9.2", "1.30.1")
+	assert.NotErrorIs(t, err, ErrNoPath)
+
+	_, err = catalog.Plan("1.9.2", "latest", "1.9.2", "1.31.0")
+	assert.Error(t, err)
+
+	_, err = catalog.Plan("1.8.0", "1.30.1", "1.8.3", "1.32.0")
+	assert.ErrorIs(t, err, ErrNoPath, "the target Talos version does not support the target Kubernetes version")
+
+	// Talos 1.9 no longer supports Kubernetes 1.29, and Kubernetes cannot skip 1.30
+	broken := Catalog{Talos: catalog.Talos, Kubernetes: []string{"1.29.3", "1.31.0", "1.32.0", "1.33.0"}}
+	steps, err := broken.Plan("1.8.0", "1.29.3", "1.10.1", "1.33.0")
+	assert.ErrorIs(t, err, ErrNoPath)
+	assert.Empty(t, steps)
+}
@@ -1,19 +1,31 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"github.com/swaggo/swag"
 
-	_ "github.com/jubblin/omni-api/docs"
+	"github.com/jubblin/omni-api/docs"
+	"github.com/jubblin/omni-api/internal/admission"
+	"github.com/jubblin/omni-api/internal/allocation"
 	"github.com/jubblin/omni-api/internal/api/handlers"
+	"github.com/jubblin/omni-api/internal/api/middleware"
+	"github.com/jubblin/omni-api/internal/audit"
+	"github.com/jubblin/omni-api/internal/campaign"
 	omniclient "github.com/jubblin/omni-api/internal/client"
+	"github.com/jubblin/omni-api/internal/gitops"
+	"github.com/jubblin/omni-api/internal/operations"
+	"github.com/jubblin/omni-api/internal/patchhistory"
+	"github.com/jubblin/omni-api/internal/supportbundle"
 )
 
 // Version is set at build time via ldflags
@@ -36,12 +48,25 @@ var Version = "dev"
 // @BasePath  /api/v1
 
 func main() {
-	// Initialize Omni client
-	client, err := omniclient.NewOmniClient()
+	// Initialize Omni client holder. It rebuilds the client when the service account
+	// key file changes or when the circuit breaker trips.
+	holder, err := omniclient.NewHolder(omniclient.HolderConfigFromEnv())
 	if err != nil {
 		log.Fatalf("Failed to create Omni client: %v", err)
 	}
-	defer client.Close()
+	defer holder.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go holder.Run(ctx)
+
+	omniState := holder.State()
+
+	// Audit log for credential downloads
+	auditLog, err := audit.New(audit.ConfigFromEnv())
+	if err != nil {
+		log.Fatalf("Failed to open audit log: %v", err)
+	}
 
 	r := gin.Default()
 
@@ -49,12 +74,19 @@ func main() {
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization", "Idempotency-Key"},
+		ExposeHeaders:    []string{"Content-Length", "Content-Disposition", "Location", "Idempotent-Replayed"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
+	// Users are only taken from the headers of trusted authenticating proxies
+	identity := middleware.IdentityConfigFromEnv()
+	if len(identity.TrustedProxies) == 0 {
+		log.Println("No trusted proxies configured, requests are not authenticated and user headers are ignored")
+	}
+	r.Use(middleware.Identity(identity))
+
 	// Middleware to record metrics
 	r.Use(func(c *gin.Context) {
 		start := time.Now()
@@ -64,72 +96,130 @@ func main() {
 	})
 
 	// Handlers
-	clusterHandler := handlers.NewClusterHandler(client.Omni().State())
-	machineHandler := handlers.NewMachineHandler(client.Omni().State())
-	machineStatusHandler := handlers.NewMachineStatusHandler(client.Omni().State())
-	machineLabelsHandler := handlers.NewMachineLabelsHandler(client.Omni().State())
-	machineExtensionsHandler := handlers.NewMachineExtensionsHandler(client.Omni().State())
-	machineUpgradeStatusHandler := handlers.NewMachineUpgradeStatusHandler(client.Omni().State())
-	machineStatusMetricsHandler := handlers.NewMachineStatusMetricsHandler(client.Omni().State())
-	machineSetHandler := handlers.NewMachineSetHandler(client.Omni().State())
-	machineSetStatusHandler := handlers.NewMachineSetStatusHandler(client.Omni().State())
-	configPatchHandler := handlers.NewConfigPatchHandler(client.Omni().State())
-	clusterMachineHandler := handlers.NewClusterMachineHandler(client.Omni().State())
-	clusterMachineStatusHandler := handlers.NewClusterMachineStatusHandler(client.Omni().State())
-	clusterMachineConfigStatusHandler := handlers.NewClusterMachineConfigStatusHandler(client.Omni().State())
-	clusterMachineTalosVersionHandler := handlers.NewClusterMachineTalosVersionHandler(client.Omni().State())
-	kubeconfigHandler := handlers.NewKubeconfigHandler(client.Omni().State())
-	kubernetesUpgradeHandler := handlers.NewKubernetesUpgradeHandler(client.Omni().State())
-	clusterEndpointHandler := handlers.NewClusterEndpointHandler(client.Omni().State())
-	etcdBackupHandler := handlers.NewEtcdBackupHandler(client.Omni().State())
-	machineClassHandler := handlers.NewMachineClassHandler(client.Omni().State())
-	machineSetNodeHandler := handlers.NewMachineSetNodeHandler(client.Omni().State())
-	talosUpgradeHandler := handlers.NewTalosUpgradeHandler(client.Omni().State())
-	schematicHandler := handlers.NewSchematicHandler(client.Omni().State())
-	ongoingTaskHandler := handlers.NewOngoingTaskHandler(client.Omni().State())
-	kubernetesStatusHandler := handlers.NewKubernetesStatusHandler(client.Omni().State())
-	clusterKubernetesNodesHandler := handlers.NewClusterKubernetesNodesHandler(client.Omni().State())
-	kubernetesVersionHandler := handlers.NewKubernetesVersionHandler(client.Omni().State())
-	clusterMachineConfigHandler := handlers.NewClusterMachineConfigHandler(client.Omni().State())
-	controlPlaneStatusHandler := handlers.NewControlPlaneStatusHandler(client.Omni().State())
-	etcdBackupStatusHandler := handlers.NewEtcdBackupStatusHandler(client.Omni().State())
-	etcdManualBackupHandler := handlers.NewEtcdManualBackupHandler(client.Omni().State())
-	schematicConfigurationHandler := handlers.NewSchematicConfigurationHandler(client.Omni().State())
-	extensionsConfigurationHandler := handlers.NewExtensionsConfigurationHandler(client.Omni().State())
-	kernelArgsHandler := handlers.NewKernelArgsHandler(client.Omni().State())
-	loadBalancerConfigHandler := handlers.NewLoadBalancerConfigHandler(client.Omni().State())
-	loadBalancerStatusHandler := handlers.NewLoadBalancerStatusHandler(client.Omni().State())
-	exposedServiceHandler := handlers.NewExposedServiceHandler(client.Omni().State())
-	machineRequestSetHandler := handlers.NewMachineRequestSetHandler(client.Omni().State())
-	clusterDiagnosticsHandler := handlers.NewClusterDiagnosticsHandler(client.Omni().State())
-	clusterDestroyStatusHandler := handlers.NewClusterDestroyStatusHandler(client.Omni().State())
-	machineSetDestroyStatusHandler := handlers.NewMachineSetDestroyStatusHandler(client.Omni().State())
-	clusterWorkloadProxyStatusHandler := handlers.NewClusterWorkloadProxyStatusHandler(client.Omni().State())
-	imagePullRequestHandler := handlers.NewImagePullRequestHandler(client.Omni().State())
-	imagePullStatusHandler := handlers.NewImagePullStatusHandler(client.Omni().State())
-	installationMediaHandler := handlers.NewInstallationMediaHandler(client.Omni().State())
-	infraMachineConfigHandler := handlers.NewInfraMachineConfigHandler(client.Omni().State())
-	machineConfigDiffHandler := handlers.NewMachineConfigDiffHandler(client.Omni().State())
-	healthHandler := handlers.NewHealthHandler(client.Omni().State())
-	metricsHandler := handlers.NewMetricsHandler()
+	clusterHandler := handlers.NewClusterHandler(omniState)
+	machineHandler := handlers.NewMachineHandler(omniState)
+	machineStatusHandler := handlers.NewMachineStatusHandler(omniState)
+	machineLabelsHandler := handlers.NewMachineLabelsHandler(omniState)
+	machineExtensionsHandler := handlers.NewMachineExtensionsHandler(omniState)
+	machineUpgradeStatusHandler := handlers.NewMachineUpgradeStatusHandler(omniState)
+	machineStatusMetricsHandler := handlers.NewMachineStatusMetricsHandler(omniState)
+	machineSetHandler := handlers.NewMachineSetHandler(omniState)
+	machineSetStatusHandler := handlers.NewMachineSetStatusHandler(omniState)
+	configPatchHandler := handlers.NewConfigPatchHandler(omniState)
+	clusterMachineHandler := handlers.NewClusterMachineHandler(omniState)
+	clusterMachineStatusHandler := handlers.NewClusterMachineStatusHandler(omniState)
+	clusterMachineConfigStatusHandler := handlers.NewClusterMachineConfigStatusHandler(omniState)
+	clusterMachineTalosVersionHandler := handlers.NewClusterMachineTalosVersionHandler(omniState)
+	configService := omniclient.NewConfigService(holder)
+	kubeconfigHandler := handlers.NewKubeconfigHandler(omniState, configService, auditLog,
+		strings.Split(os.Getenv("OMNI_API_SERVICE_ACCOUNT_KUBECONFIG_USERS"), ","), strings.Split(os.Getenv("OMNI_API_SERVICE_ACCOUNT_GROUPS"), ","))
+	talosconfigHandler := handlers.NewTalosconfigHandler(omniState, configService, auditLog, strings.Split(os.Getenv("OMNI_API_BREAK_GLASS_USERS"), ","))
+	omniconfigHandler := handlers.NewOmniconfigHandler(configService, auditLog)
+	omniAuditLogHandler := handlers.NewOmniAuditLogHandler(omniclient.NewAuditLogService(holder), auditLog)
+	kubernetesUpgradeHandler := handlers.NewKubernetesUpgradeHandler(omniState)
+	clusterEndpointHandler := handlers.NewClusterEndpointHandler(omniState)
+	etcdBackupHandler := handlers.NewEtcdBackupHandler(omniState)
+	machineClassHandler := handlers.NewMachineClassHandler(omniState)
+	machineSetNodeHandler := handlers.NewMachineSetNodeHandler(omniState)
+	talosUpgradeHandler := handlers.NewTalosUpgradeHandler(omniState)
+	schematicHandler := handlers.NewSchematicHandler(omniState)
+	ongoingTaskHandler := handlers.NewOngoingTaskHandler(omniState)
+	kubernetesStatusHandler := handlers.NewKubernetesStatusHandler(omniState)
+	clusterKubernetesNodesHandler := handlers.NewClusterKubernetesNodesHandler(omniState)
+	kubernetesVersionHandler := handlers.NewKubernetesVersionHandler(omniState)
+	clusterMachineConfigHandler := handlers.NewClusterMachineConfigHandler(omniState)
+	controlPlaneStatusHandler := handlers.NewControlPlaneStatusHandler(omniState)
+	etcdBackupStatusHandler := handlers.NewEtcdBackupStatusHandler(omniState)
+	etcdManualBackupHandler := handlers.NewEtcdManualBackupHandler(omniState)
+	schematicConfigurationHandler := handlers.NewSchematicConfigurationHandler(omniState)
+	extensionsConfigurationHandler := handlers.NewExtensionsConfigurationHandler(omniState)
+	kernelArgsHandler := handlers.NewKernelArgsHandler(omniState)
+	loadBalancerConfigHandler := handlers.NewLoadBalancerConfigHandler(omniState)
+	loadBalancerStatusHandler := handlers.NewLoadBalancerStatusHandler(omniState)
+	exposedServiceHandler := handlers.NewExposedServiceHandler(omniState)
+	machineRequestSetHandler := handlers.NewMachineRequestSetHandler(omniState)
+	clusterDiagnosticsHandler := handlers.NewClusterDiagnosticsHandler(omniState)
+	clusterDestroyStatusHandler := handlers.NewClusterDestroyStatusHandler(omniState)
+	machineSetDestroyStatusHandler := handlers.NewMachineSetDestroyStatusHandler(omniState)
+	clusterWorkloadProxyStatusHandler := handlers.NewClusterWorkloadProxyStatusHandler(omniState)
+	imagePullRequestHandler := handlers.NewImagePullRequestHandler(omniState)
+	imagePullStatusHandler := handlers.NewImagePullStatusHandler(omniState)
+	installationMediaHandler := handlers.NewInstallationMediaHandler(omniState)
+	infraMachineConfigHandler := handlers.NewInfraMachineConfigHandler(omniState)
+	machineConfigDiffHandler := handlers.NewMachineConfigDiffHandler(omniState)
+	machineConfigPreviewHandler := handlers.NewMachineConfigPreviewHandler(omniState)
+	healthHandler := handlers.NewHealthHandler(omniState)
+	readinessHandler := handlers.NewReadinessHandler(omniState, holder.Breaker())
+	metricsHandler := handlers.NewMetricsHandler().WithCircuitBreaker(holder.Breaker())
 
 	// Create service wrappers
-	mgmtService := omniclient.NewManagementService(client)
-	talosService := omniclient.NewTalosService(client)
-	authService := omniclient.NewAuthService(client)
-	oidcService := omniclient.NewOIDCService(client)
+	mgmtService := omniclient.NewManagementService(holder)
+	talosService := omniclient.NewTalosService(holder)
+	machineLogsHandler := handlers.NewMachineLogsHandler(omniState, talosService)
+	authService := omniclient.NewAuthService(holder)
+	oidcService := omniclient.NewOIDCService(holder)
+
+	// Long-running operations started by asynchronous actions
+	opsManager := operations.NewManager(operations.ConfigFromEnv())
+	operationHandler := handlers.NewOperationHandler(opsManager)
+
+	// Versions of the config patches written through the API
+	patchHistory, err := patchhistory.New(patchhistory.ConfigFromEnv())
+	if err != nil {
+		log.Fatalf("Failed to set up config patch history: %v", err)
+	}
+
+	// Support bundles collected in the background and kept until they expire
+	bundleManager, err := supportbundle.NewManager(supportbundle.ConfigFromEnv(), omniclient.NewSupportService(holder))
+	if err != nil {
+		log.Fatalf("Failed to set up support bundles: %v", err)
+	}
+	supportBundleHandler := handlers.NewSupportBundleHandler(omniState, bundleManager, auditLog)
+	templateService := omniclient.NewTemplateService(holder)
+	clusterTemplateHandler := handlers.NewClusterTemplateHandler(templateService, opsManager, patchHistory)
+
+	// CEL admission policies checked against write requests, bulk items and GitOps writes
+	admissionController, err := admission.Load(admission.ConfigFromEnv(), omniState)
+	if err != nil {
+		log.Fatalf("Failed to load admission policies: %v", err)
+	}
+	if policies := admissionController.Policies(); len(policies) > 0 {
+		log.Printf("Loaded %d admission policies", len(policies))
+	}
+
+	// Cluster templates and config patches synced from a directory in the background
+	reconciler := gitops.New(gitops.ConfigFromEnv(), omniState, templateService, mgmtService, patchHistory, admissionController)
+	if reconciler.Enabled() {
+		log.Printf("Syncing cluster definitions from %s", reconciler.Status().Dir)
+		go reconciler.Run(ctx)
+	}
+	gitOpsHandler := handlers.NewGitOpsHandler(reconciler)
+
+	// Fleet upgrade campaigns stored in OMNI_API_CAMPAIGN_FILE; running campaigns continue after a restart
+	campaigns, err := campaign.NewManager(campaign.ConfigFromEnv(), omniState, mgmtService)
+	if err != nil {
+		log.Fatalf("Failed to set up upgrade campaigns: %v", err)
+	}
+	campaigns.Start(ctx)
+	campaignHandler := handlers.NewCampaignHandler(campaigns)
 
 	// Write operation handlers (using Management service)
-	clusterWriteHandler := handlers.NewClusterWriteHandler(client.Omni().State(), mgmtService)
-	machineWriteHandler := handlers.NewMachineWriteHandler(client.Omni().State(), mgmtService)
-	machineSetWriteHandler := handlers.NewMachineSetWriteHandler(client.Omni().State(), mgmtService)
-	configPatchWriteHandler := handlers.NewConfigPatchWriteHandler(client.Omni().State(), mgmtService)
+	clusterWriteHandler := handlers.NewClusterWriteHandler(omniState, mgmtService, opsManager)
+	clusterBackupHandler := handlers.NewClusterBackupHandler(omniState, mgmtService)
+	clusterUpgradePlanHandler := handlers.NewClusterUpgradePlanHandler(omniState, mgmtService)
+	machineWriteHandler := handlers.NewMachineWriteHandler(omniState, mgmtService)
+	machineSetWriteHandler := handlers.NewMachineSetWriteHandler(omniState, mgmtService, opsManager)
+	machineAllocationHandler := handlers.NewMachineAllocationHandler(omniState, mgmtService, allocation.NewUsage())
+	machineClassWriteHandler := handlers.NewMachineClassWriteHandler(omniState, mgmtService)
+	machineSetScaleHandler := handlers.NewMachineSetScaleHandler(omniState, mgmtService)
+	configPatchWriteHandler := handlers.NewConfigPatchWriteHandler(omniState, mgmtService, patchHistory)
+	configPatchHistoryHandler := handlers.NewConfigPatchHistoryHandler(mgmtService, patchHistory)
 
 	// Action handlers
-	clusterActionsHandler := handlers.NewClusterActionsHandler(client.Omni().State(), mgmtService, talosService)
-	machineActionsHandler := handlers.NewMachineActionsHandler(client.Omni().State(), mgmtService, talosService)
-	machineSetActionsHandler := handlers.NewMachineSetActionsHandler(client.Omni().State(), mgmtService)
-	etcdBackupActionsHandler := handlers.NewEtcdBackupActionsHandler(client.Omni().State(), mgmtService)
+	clusterActionsHandler := handlers.NewClusterActionsHandler(omniState, mgmtService, talosService, opsManager)
+	machineActionsHandler := handlers.NewMachineActionsHandler(omniState, mgmtService, talosService, opsManager)
+	machineSetActionsHandler := handlers.NewMachineSetActionsHandler(omniState, mgmtService, opsManager)
+	etcdBackupActionsHandler := handlers.NewEtcdBackupActionsHandler(omniState, mgmtService, opsManager)
 
 	// Auth and OIDC handlers
 	authHandler := handlers.NewAuthHandler(authService)
@@ -137,10 +227,26 @@ func main() {
 
 	// Health and Metrics routes (outside v1 group for easier access)
 	r.GET("/health", healthHandler.GetHealth)
+	r.GET("/readyz", readinessHandler.GetReadiness)
 	r.GET("/metrics", metricsHandler.GetMetrics)
 
 	// API Routes
+	// Read-only mode and per-group route enablement
+	access := middleware.AccessConfigFromEnv()
+	if access.ReadOnly {
+		log.Println("Running in read-only mode, mutating routes are disabled")
+	}
+
+	// Bulk items are checked against the same route groups and policies as single requests
+	bulkHandler := handlers.NewBulkHandler(omniState, mgmtService, talosService, opsManager, access, admissionController, patchHistory)
+
 	v1 := r.Group("/api/v1")
+	v1.Use(middleware.Access(access, "/api/v1"))
+	v1.Use(middleware.CircuitBreaker(holder.Breaker()))
+	// Write requests must pass the configured CEL admission policies
+	v1.Use(middleware.Admission(admissionController, "/api/v1"))
+	// Retried POST requests with the same Idempotency-Key get the stored response
+	v1.Use(middleware.Idempotency(middleware.NewIdempotencyStore(middleware.IdempotencyConfigFromEnv())))
 	{
 		// Cluster routes
 		v1.GET("/clusters", clusterHandler.ListClusters)
@@ -149,6 +255,7 @@ func main() {
 		v1.GET("/clusters/:id/metrics", clusterHandler.GetClusterMetrics)
 		v1.GET("/clusters/:id/bootstrap", clusterHandler.GetClusterBootstrap)
 		v1.GET("/clusters/:id/kubeconfig", kubeconfigHandler.GetKubeconfig)
+		v1.GET("/clusters/:id/talosconfig", talosconfigHandler.GetTalosconfig)
 		v1.GET("/clusters/:id/kubernetes-upgrade", kubernetesUpgradeHandler.GetKubernetesUpgradeStatus)
 		v1.GET("/clusters/:id/talos-upgrade", talosUpgradeHandler.GetTalosUpgradeStatus)
 		v1.GET("/clusters/:id/endpoints", clusterEndpointHandler.GetClusterEndpoints)
@@ -159,11 +266,15 @@ func main() {
 		v1.GET("/clusters/:id/diagnostics", clusterDiagnosticsHandler.GetClusterDiagnostics)
 		v1.GET("/clusters/:id/destroy-status", clusterDestroyStatusHandler.GetClusterDestroyStatus)
 		v1.GET("/clusters/:id/workload-proxy-status", clusterWorkloadProxyStatusHandler.GetClusterWorkloadProxyStatus)
+		v1.GET("/clusters/:id/backup", clusterBackupHandler.GetClusterBackup)
+		v1.GET("/clusters/:id/backup/retention", clusterBackupHandler.GetClusterBackupRetention)
+		v1.GET("/clusters/:id/upgrade-plan", clusterUpgradePlanHandler.GetClusterUpgradePlan)
 		
 		// Cluster write operations
 		v1.POST("/clusters", clusterWriteHandler.CreateCluster)
 		v1.PUT("/clusters/:id", clusterWriteHandler.UpdateCluster)
 		v1.DELETE("/clusters/:id", clusterWriteHandler.DeleteCluster)
+		v1.PUT("/clusters/:id/backup", clusterBackupHandler.SetClusterBackup)
 		
 		// Cluster actions
 		v1.POST("/clusters/:id/actions/kubernetes-upgrade", clusterActionsHandler.TriggerKubernetesUpgrade)
@@ -180,29 +291,37 @@ func main() {
 		v1.GET("/machines/:id/upgrade-status", machineUpgradeStatusHandler.GetMachineUpgradeStatus)
 		v1.GET("/machines/:id/metrics", machineStatusMetricsHandler.GetMachineStatusMetrics)
 		v1.GET("/machines/:id/config-diff", machineConfigDiffHandler.GetMachineConfigDiff)
+		v1.POST("/machines/:id/config-preview", machineConfigPreviewHandler.PreviewMachineConfig)
+		v1.GET("/machines/:id/logs", machineLogsHandler.StreamMachineLogs)
 		
 		// Machine write operations
 		v1.PATCH("/machines/:id", machineWriteHandler.UpdateMachine)
+		v1.PUT("/machines/:id/labels", machineWriteHandler.ReplaceMachineLabels)
+		v1.PATCH("/machines/labels", machineWriteHandler.PatchMachinesLabels)
 		
 		// Machine actions
 		v1.POST("/machines/:id/actions/reboot", machineActionsHandler.RebootMachine)
 		v1.POST("/machines/:id/actions/shutdown", machineActionsHandler.ShutdownMachine)
 		v1.POST("/machines/:id/actions/reset", machineActionsHandler.ResetMachine)
 		v1.POST("/machines/:id/actions/maintenance", machineActionsHandler.ToggleMaintenance)
+		v1.POST("/machines/:id/actions/maintenance-upgrade", machineActionsHandler.MaintenanceUpgrade)
 		
 		// MachineSet routes
 		v1.GET("/machinesets", machineSetHandler.ListMachineSets)
 		v1.GET("/machinesets/:id", machineSetHandler.GetMachineSet)
 		v1.GET("/machinesets/:id/status", machineSetStatusHandler.GetMachineSetStatus)
 		v1.GET("/machinesets/:id/destroy-status", machineSetDestroyStatusHandler.GetMachineSetDestroyStatus)
+		v1.GET("/machinesets/:id/scale", machineSetScaleHandler.GetMachineSetScale)
 		
 		// MachineSet write operations
 		v1.POST("/machinesets", machineSetWriteHandler.CreateMachineSet)
 		v1.PUT("/machinesets/:id", machineSetWriteHandler.UpdateMachineSet)
 		v1.DELETE("/machinesets/:id", machineSetWriteHandler.DeleteMachineSet)
+		v1.PUT("/machinesets/:id/scale", machineSetScaleHandler.UpdateMachineSetScale)
 		
 		// MachineSet actions
 		v1.POST("/machinesets/:id/actions/destroy", machineSetActionsHandler.TriggerDestroy)
+		v1.POST("/machinesets/:id/allocate", machineAllocationHandler.AllocateMachines)
 		
 		// MachineSetNode routes
 		v1.GET("/machinesetnodes", machineSetNodeHandler.ListMachineSetNodes)
@@ -216,6 +335,9 @@ func main() {
 		v1.POST("/configpatches", configPatchWriteHandler.CreateConfigPatch)
 		v1.PUT("/configpatches/:id", configPatchWriteHandler.UpdateConfigPatch)
 		v1.DELETE("/configpatches/:id", configPatchWriteHandler.DeleteConfigPatch)
+		v1.GET("/configpatches/:id/history", configPatchHistoryHandler.GetConfigPatchHistory)
+		v1.GET("/configpatches/:id/history/diff", configPatchHistoryHandler.DiffConfigPatchRevisions)
+		v1.POST("/configpatches/:id/rollback", configPatchHistoryHandler.RollbackConfigPatch)
 		
 		// ClusterMachine routes
 		v1.GET("/clustermachines", clusterMachineHandler.ListClusterMachines)
@@ -228,9 +350,16 @@ func main() {
 		// MachineClass routes
 		v1.GET("/machineclasses", machineClassHandler.ListMachineClasses)
 		v1.GET("/machineclasses/:id", machineClassHandler.GetMachineClass)
+		v1.GET("/machineclasses/:id/matches", machineClassHandler.GetMachineClassMatches)
+
+		// MachineClass write operations
+		v1.POST("/machineclasses", machineClassWriteHandler.CreateMachineClass)
+		v1.PUT("/machineclasses/:id", machineClassWriteHandler.UpdateMachineClass)
+		v1.DELETE("/machineclasses/:id", machineClassWriteHandler.DeleteMachineClass)
 		
 		// EtcdBackup routes
 		v1.GET("/etcdbackups", etcdBackupHandler.ListEtcdBackups)
+		v1.GET("/etcdbackups/store-status", etcdBackupStatusHandler.GetEtcdBackupStoreStatus)
 		v1.GET("/etcdbackups/:id", etcdBackupHandler.GetEtcdBackup)
 		v1.GET("/etcdbackups/:id/status", etcdBackupStatusHandler.GetEtcdBackupStatus)
 		v1.GET("/etcd-manual-backups", etcdManualBackupHandler.ListEtcdManualBackups)
@@ -238,6 +367,7 @@ func main() {
 		
 		// EtcdBackup actions
 		v1.POST("/etcdbackups", etcdBackupActionsHandler.TriggerManualBackup)
+		v1.POST("/etcdbackups/:id/actions/restore", etcdBackupActionsHandler.RestoreBackup)
 		
 		// Schematic routes
 		v1.GET("/schematics", schematicHandler.ListSchematics)
@@ -287,6 +417,45 @@ func main() {
 		v1.GET("/infra-machine-configs", infraMachineConfigHandler.ListInfraMachineConfigs)
 		v1.GET("/infra-machine-configs/:id", infraMachineConfigHandler.GetInfraMachineConfig)
 		
+		// Operation routes
+		v1.GET("/operations", operationHandler.ListOperations)
+		v1.GET("/operations/:id", operationHandler.GetOperation)
+		v1.POST("/operations/:id/cancel", operationHandler.CancelOperation)
+
+		// Bulk operation routes
+		v1.POST("/bulk", bulkHandler.RunBulk)
+
+		// Client configuration
+		v1.GET("/omniconfig", omniconfigHandler.GetOmniconfig)
+
+		// Audit log routes
+		v1.GET("/omni-audit-log", omniAuditLogHandler.ExportAuditLog)
+
+		// Support bundle routes
+		v1.POST("/clusters/:id/support-bundle", supportBundleHandler.CreateSupportBundle)
+		v1.GET("/support-bundles/:id", supportBundleHandler.GetSupportBundle)
+		v1.GET("/support-bundles/:id/events", supportBundleHandler.StreamSupportBundleEvents)
+		v1.GET("/support-bundles/:id/download", supportBundleHandler.DownloadSupportBundle)
+
+		// Cluster template routes. The colons are escaped so they are not parsed as route parameters;
+		// gin unescapes them when the server is started with Run.
+		v1.POST("/cluster-templates\\:validate", clusterTemplateHandler.ValidateTemplate)
+		v1.POST("/cluster-templates\\:diff", clusterTemplateHandler.DiffTemplate)
+		v1.POST("/cluster-templates\\:apply", clusterTemplateHandler.ApplyTemplate)
+		v1.GET("/clusters/:id/template", clusterTemplateHandler.ExportTemplate)
+
+		// GitOps routes
+		v1.GET("/gitops/status", gitOpsHandler.GetGitOpsStatus)
+		v1.POST("/gitops/sync", gitOpsHandler.SyncGitOps)
+
+		// Fleet upgrade campaign routes
+		v1.GET("/campaigns", campaignHandler.ListCampaigns)
+		v1.POST("/campaigns", campaignHandler.CreateCampaign)
+		v1.GET("/campaigns/:id", campaignHandler.GetCampaign)
+		v1.POST("/campaigns/:id/actions/pause", campaignHandler.PauseCampaign)
+		v1.POST("/campaigns/:id/actions/resume", campaignHandler.ResumeCampaign)
+		v1.POST("/campaigns/:id/actions/abort", campaignHandler.AbortCampaign)
+		
 		// Auth service routes
 		v1.GET("/auth/service-accounts", authHandler.ListServiceAccounts)
 		v1.GET("/auth/service-accounts/:id", authHandler.GetServiceAccount)
@@ -306,8 +475,9 @@ func main() {
 		c.Redirect(http.StatusMovedPermanently, "/swagger/index.html")
 	})
 
-	// Swagger UI
-	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	// Swagger UI, serving only the operations enabled by the access configuration
+	swag.Register("filtered", middleware.FilterSwagger(docs.SwaggerInfo.ReadDoc(), access, "/health", "/readyz", "/metrics"))
+	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.InstanceName("filtered")))
 
 	port := os.Getenv("PORT")
 	if port == "" {